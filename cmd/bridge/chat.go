@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/backend"
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+)
+
+const chatHelp = `Type a message and press Enter. Commands:
+  /reset   clear the session's history
+  /exit    quit (or Ctrl+D)
+Ctrl+C stops a reply in progress.`
+
+// cmdChat is a terminal REPL talking to the configured backends with the
+// session keys the bridge would use, so agents can be tried without Feishu
+func cmdChat(args []string) {
+	fs := flag.NewFlagSet("chat", flag.ExitOnError)
+	chatID := fs.String("chat", "terminal", "chat ID whose session and route to use, e.g. an oc_xxx group")
+	backendName := fs.String("backend", "", "backend to ask instead of the chat's route")
+	agentID := fs.String("agent", "", "gateway agent to address")
+	verbose := fs.Bool("verbose", false, "show logs")
+	fs.Parse(args)
+
+	log.SetOutput(redact.Writer(os.Stderr))
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config error: %v\n", err)
+		os.Exit(1)
+	}
+	redact.Register(cfg.Secrets()...)
+	router, err := backend.NewRouter(cfg, nil, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create backends: %v\n", err)
+		os.Exit(1)
+	}
+	if *backendName != "" {
+		if _, ok := router.Backend(*backendName); !ok {
+			fmt.Fprintf(os.Stderr, "Unknown backend %s (have %s)\n", *backendName, strings.Join(router.Names(), ", "))
+			os.Exit(1)
+		}
+	}
+
+	// The same scheme as the bridge: one session per chat, and one per
+	// agent within it
+	sessionKey := cfg.Clawdbot.SessionKey
+	if sessionKey == "" {
+		sessionKey = "feishu:" + *chatID
+	}
+	if *agentID != "" {
+		sessionKey += ":agent:" + *agentID
+	}
+
+	fmt.Printf("Session %s. %s\n\n", sessionKey, chatHelp)
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	lines := readLines(os.Stdin)
+	for {
+		fmt.Print("> ")
+		var line string
+		var ok bool
+		select {
+		case line, ok = <-lines:
+		case <-sigChan:
+			ok = false
+		}
+		if !ok {
+			fmt.Println()
+			return
+		}
+		text := strings.TrimSpace(line)
+		switch text {
+		case "":
+			continue
+		case "/exit", "/quit":
+			return
+		case "/help":
+			fmt.Println(chatHelp)
+			continue
+		}
+
+		name, agent, rest := router.Route(*chatID, text)
+		if *backendName != "" {
+			name, rest = *backendName, text
+			agent, _ = router.Backend(name)
+		}
+		if agent == nil {
+			fmt.Printf("No backend named %s\n", name)
+			continue
+		}
+		if text == "/reset" {
+			if err := agent.ResetSession(sessionKey); err != nil {
+				fmt.Printf("Failed to reset: %v\n", err)
+			} else {
+				fmt.Println("Session cleared")
+			}
+			continue
+		}
+		chatTurn(backend.WithAgent(agent, *agentID), rest, sessionKey, sigChan)
+	}
+}
+
+// readLines delivers stdin line by line so a prompt can also wait for Ctrl+C
+func readLines(r io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+// chatTurn asks agent and prints the reply as it streams, with tool calls
+// on their own lines. Ctrl+C cancels the run.
+func chatTurn(agent backend.Backend, text, sessionKey string, sigChan <-chan os.Signal) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-sigChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var mu sync.Mutex
+	var printed strings.Builder
+	// show prints the reply so far, or all of it again when it was rewritten
+	show := func(full string) {
+		if strings.HasPrefix(full, printed.String()) {
+			fmt.Print(full[printed.Len():])
+		} else {
+			fmt.Print("\n" + full)
+		}
+		printed.Reset()
+		printed.WriteString(full)
+	}
+
+	reply, err := agent.Ask(ctx, text, sessionKey, func(stream, data string) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch stream {
+		case backend.StreamAssistant:
+			var d struct {
+				Text  string `json:"text"`
+				Delta string `json:"delta"`
+			}
+			if json.Unmarshal([]byte(data), &d) != nil {
+				return
+			}
+			if d.Text != "" {
+				show(d.Text)
+			} else if d.Delta != "" {
+				show(printed.String() + d.Delta)
+			}
+		case backend.StreamToolCall:
+			var call struct {
+				Name string `json:"name"`
+			}
+			if json.Unmarshal([]byte(data), &call) == nil && call.Name != "" {
+				if printed.Len() > 0 && !strings.HasSuffix(printed.String(), "\n") {
+					fmt.Println()
+				}
+				fmt.Printf("[tool] %s\n", call.Name)
+				printed.Reset()
+			}
+		}
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	switch {
+	case ctx.Err() != nil:
+		fmt.Println("\n[stopped]")
+	case err != nil:
+		fmt.Printf("\n[error] %v\n", err)
+	default:
+		if printed.Len() == 0 || !strings.HasSuffix(reply, printed.String()) {
+			show(reply)
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/deadletter"
+	"github.com/wy51ai/moltbotCNAPP/internal/seal"
+	"github.com/wy51ai/moltbotCNAPP/internal/store"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+const deadletterUsage = `Usage:
+  clawdbot-bridge deadletter list [--json]
+  clawdbot-bridge deadletter retry <id>|--all
+  clawdbot-bridge deadletter drop <id>|--all`
+
+// cmdDeadletter lists, retries and drops replies the bridge couldn't deliver
+func cmdDeadletter(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, deadletterUsage)
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	keys, err := seal.FromConfig(cfg.Encryption)
+	if err != nil {
+		log.Fatal(err)
+	}
+	st, closeStore := openStateStore(cfg)
+	defer closeStore()
+
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("deadletter list", flag.ExitOnError)
+		asJSON := fs.Bool("json", false, "print the letters, with their full text, as JSON lines")
+		fs.Parse(args[1:])
+		deadletterList(st, keys, *asJSON)
+	case "retry", "drop":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, deadletterUsage)
+			os.Exit(1)
+		}
+		letters := pickLetters(st, keys, args[1])
+		auditLog, err := audit.Open(cfg.Audit.Dir)
+		if err != nil {
+			log.Printf("Audit log disabled: %v", err)
+		}
+		if args[0] == "retry" {
+			deadletterRetry(cfg, st, keys, auditLog, letters)
+		} else {
+			deadletterDrop(st, auditLog, letters)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, deadletterUsage)
+		os.Exit(1)
+	}
+}
+
+// pickLetters returns the letter with id, or all of them for --all
+func pickLetters(st store.KV, keys *seal.Keyring, id string) []deadletter.Letter {
+	if id == "--all" {
+		letters, err := deadletter.List(st, keys)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return letters
+	}
+	l, err := deadletter.Get(st, keys, strings.TrimSpace(id))
+	if errors.Is(err, deadletter.ErrNotFound) {
+		log.Fatalf("No dead letter %s", id)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	return []deadletter.Letter{l}
+}
+
+func deadletterList(st store.KV, keys *seal.Keyring, asJSON bool) {
+	letters, err := deadletter.List(st, keys)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, l := range letters {
+			enc.Encode(l)
+		}
+		return
+	}
+	if len(letters) == 0 {
+		fmt.Println("No dead letters")
+		return
+	}
+
+	fmt.Printf("%-9s %-17s %-22s %-8s %-30s %s\n", "ID", "FAILED", "CHAT", "RETRIES", "TEXT", "ERROR")
+	for _, l := range letters {
+		fmt.Printf("%-9s %-17s %-22s %-8d %-30s %s\n", l.ID, l.Failed.Format("2006-01-02 15:04"), l.ChatID, l.Attempts,
+			preview(l.Text, 30), preview(l.Error, 60))
+	}
+}
+
+// preview shortens text to one line of at most n characters
+func preview(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	return string([]rune(text)[:n-1]) + "…"
+}
+
+// deadletterRetry sends letters again, dropping the ones that go out
+func deadletterRetry(cfg *config.Config, st store.KV, keys *seal.Keyring, auditLog *audit.Log, letters []deadletter.Letter) {
+	client := feishu.NewClient(cfg.Feishu.AppID, cfg.Feishu.AppSecret, nil, feishuNetwork(cfg)...)
+	failed := 0
+	for _, l := range letters {
+		var err error
+		if l.Post {
+			_, err = client.SendPost(l.ChatID, l.Text)
+		} else {
+			_, err = client.SendMessage(l.ChatID, l.Text)
+		}
+		if err != nil {
+			failed++
+			fmt.Printf("%s: failed again: %v\n", l.ID, err)
+			if err := deadletter.RetryFailed(st, keys, l, err, time.Now()); err != nil {
+				log.Printf("Failed to update %s: %v", l.ID, err)
+			}
+			continue
+		}
+		if err := deadletter.Drop(st, l.ID); err != nil {
+			log.Printf("Failed to drop delivered %s: %v", l.ID, err)
+		}
+		auditLog.Record(context.Background(), audit.Event{Action: "deadletter.retry", Actor: "cli", Target: l.ID, Detail: l.ChatID})
+		fmt.Printf("%s: delivered to %s\n", l.ID, l.ChatID)
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// deadletterDrop deletes letters without sending them
+func deadletterDrop(st store.KV, auditLog *audit.Log, letters []deadletter.Letter) {
+	for _, l := range letters {
+		if err := deadletter.Drop(st, l.ID); err != nil {
+			log.Fatalf("Failed to drop %s: %v", l.ID, err)
+		}
+		auditLog.Record(context.Background(), audit.Event{Action: "deadletter.drop", Actor: "cli", Target: l.ID, Detail: l.ChatID})
+		fmt.Printf("Dropped %s\n", l.ID)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// diagCheck is the outcome of one diagnostic step
+type diagCheck struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"` // pass, fail or skip
+	DurationMs int64  `json:"duration_ms"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// cmdDiag runs connectivity diagnostics against the configured services
+func cmdDiag(args []string) {
+	if len(args) == 0 || args[0] != "feishu" {
+		fmt.Fprintln(os.Stderr, "Usage: clawdbot-bridge diag feishu --chat oc_xxx [--keep] [--json]")
+		os.Exit(1)
+	}
+	cmdDiagFeishu(args[1:])
+}
+
+// cmdDiagFeishu sends, edits and deletes one message of each kind the
+// bridge uses in a test chat with the configured app, and reports which
+// calls Feishu accepted
+func cmdDiagFeishu(args []string) {
+	fs := flag.NewFlagSet("diag feishu", flag.ExitOnError)
+	chatID := fs.String("chat", "", "chat_id to send the test messages to; the bot must be a member")
+	domain := fs.String("domain", "", "open platform URL, e.g. https://open.larksuite.com for Lark")
+	keep := fs.Bool("keep", false, "leave the test messages in the chat")
+	asJSON := fs.Bool("json", false, "print results as JSON lines")
+	fs.Parse(args)
+	if *chatID == "" {
+		fmt.Fprintln(os.Stderr, "Usage: clawdbot-bridge diag feishu --chat oc_xxx [--keep] [--json]")
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	redact.Register(cfg.Secrets()...)
+	// The client logs each call; the results say all that's needed
+	log.SetOutput(io.Discard)
+
+	opts := feishuNetwork(cfg)
+	if *domain != "" {
+		opts = append(opts, feishu.WithDomain(*domain))
+	}
+	client := feishu.NewClient(cfg.Feishu.AppID, cfg.Feishu.AppSecret, nil, opts...)
+	if !*asJSON {
+		fmt.Printf("Feishu diagnostics for app %s in %s\n\n", cfg.Feishu.AppID, *chatID)
+	}
+
+	var checks []diagCheck
+	var sent []string
+	// check runs one step unless the step it depends on failed, and
+	// prints the result right away
+	check := func(name string, needs bool, step func() (string, error)) bool {
+		c := diagCheck{Name: name, Status: "skip"}
+		if needs {
+			start := time.Now()
+			detail, err := step()
+			c.DurationMs = time.Since(start).Milliseconds()
+			c.Detail = detail
+			if err != nil {
+				c.Status, c.Error = "fail", redact.String(err.Error())
+			} else {
+				c.Status = "pass"
+			}
+		}
+		checks = append(checks, c)
+		printCheck(c, *asJSON)
+		return c.Status == "pass"
+	}
+	send := func(id string, err error) (string, error) {
+		if err == nil {
+			sent = append(sent, id)
+		}
+		return id, err
+	}
+
+	var textID, postID, cardID, imageKey string
+	textOK := check("send text", true, func() (string, error) {
+		var err error
+		textID, err = client.SendMessage(*chatID, "clawdbot-bridge 诊断：文本消息")
+		return send(textID, err)
+	})
+	check("update text", textOK, func() (string, error) {
+		return "", client.UpdateMessage(textID, "clawdbot-bridge 诊断：文本消息（已编辑）")
+	})
+
+	postOK := check("send post", true, func() (string, error) {
+		var err error
+		postID, err = client.SendPost(*chatID, "clawdbot-bridge 诊断：**富文本**\n```go\nfmt.Println(\"ok\")\n```")
+		return send(postID, err)
+	})
+	check("update post", postOK, func() (string, error) {
+		return "", client.UpdatePost(postID, "clawdbot-bridge 诊断：**富文本**（已编辑）\n```go\nfmt.Println(\"ok\")\n```")
+	})
+
+	cardOK := check("send card", true, func() (string, error) {
+		var err error
+		cardID, err = client.SendCard(*chatID, feishu.NewCard("clawdbot-bridge 诊断", "blue").AddMarkdown("卡片消息"))
+		return send(cardID, err)
+	})
+	check("update card", cardOK, func() (string, error) {
+		return "", client.UpdateCard(cardID, feishu.NewCard("clawdbot-bridge 诊断", "green").AddMarkdown("卡片消息（已编辑）"))
+	})
+
+	uploadOK := check("upload image", true, func() (string, error) {
+		var err error
+		imageKey, err = client.UploadImage(diagImage())
+		return imageKey, err
+	})
+	check("send image", uploadOK, func() (string, error) {
+		return send(client.SendImage(*chatID, diagImage()))
+	})
+	check("send file", true, func() (string, error) {
+		return send(client.SendFile(*chatID, "clawdbot-bridge-diag.txt", []byte("clawdbot-bridge 诊断文件\n")))
+	})
+
+	if !*keep {
+		for _, id := range sent {
+			check("delete "+id, true, func() (string, error) {
+				return "", client.DeleteMessage(id)
+			})
+		}
+	}
+
+	var failed int
+	for _, c := range checks {
+		if c.Status == "fail" {
+			failed++
+		}
+	}
+	if !*asJSON {
+		fmt.Printf("\n%d checks, %d failed\n", len(checks), failed)
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// printCheck prints one result as a table row or a JSON line
+func printCheck(c diagCheck, asJSON bool) {
+	if asJSON {
+		line, _ := json.Marshal(c)
+		fmt.Println(string(line))
+		return
+	}
+	status := map[string]string{"pass": "PASS", "fail": "FAIL", "skip": "SKIP"}[c.Status]
+	detail := c.Detail
+	if c.Error != "" {
+		detail = c.Error
+	}
+	fmt.Printf("%-4s  %-28s %6dms  %s\n", status, c.Name, c.DurationMs, detail)
+}
+
+// diagImage is a small PNG to upload
+func diagImage() []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 4), B: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
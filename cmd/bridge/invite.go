@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/invite"
+	"github.com/wy51ai/moltbotCNAPP/internal/store"
+)
+
+const inviteUsage = `Usage:
+  clawdbot-bridge invite create [--uses 1] [--days 0] [--count 1] [--note text]
+  clawdbot-bridge invite list
+  clawdbot-bridge invite revoke <code>`
+
+// cmdInvite creates, lists and revokes invite codes
+func cmdInvite(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, inviteUsage)
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	st, closeStore := openStateStore(cfg)
+	defer closeStore()
+	auditLog, err := audit.Open(cfg.Audit.Dir)
+	if err != nil {
+		log.Printf("Audit log disabled: %v", err)
+	}
+
+	switch args[0] {
+	case "create":
+		inviteCreate(st, auditLog, args[1:])
+	case "list":
+		inviteList(st)
+	case "revoke":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, inviteUsage)
+			os.Exit(1)
+		}
+		code := strings.ToUpper(strings.TrimSpace(args[1]))
+		users, err := invite.Revoke(st, code)
+		if err != nil {
+			log.Fatalf("Failed to revoke %s: %v", code, err)
+		}
+		auditLog.Record(context.Background(), audit.Event{Action: "invite.revoke", Actor: "cli", Target: code,
+			Detail: fmt.Sprintf("%d users deactivated", users)})
+		fmt.Printf("Revoked %s; %d users who redeemed it must activate again\n", code, users)
+	default:
+		fmt.Fprintln(os.Stderr, inviteUsage)
+		os.Exit(1)
+	}
+}
+
+// openStateStore opens the state store invite codes and dead letters are
+// kept in. The running bridge keeps parts of the local state file cached
+// in memory, so it can't be edited under a running bridge; Redis can.
+func openStateStore(cfg *config.Config) (store.KV, func()) {
+	if cfg.Redis.Addr != "" {
+		rs, err := store.OpenRedis(cfg.Redis)
+		if err != nil {
+			log.Fatalf("Failed to open shared state: %v", err)
+		}
+		return rs, func() { rs.Close() }
+	}
+
+	dir, err := config.Dir()
+	if err != nil {
+		log.Fatalf("Failed to get config dir: %v", err)
+	}
+	if isRunning(filepath.Join(dir, "bridge.pid")) {
+		log.Fatal("Bridge is running with a local state file; stop it first, or share state via redis")
+	}
+	fileStore, err := store.Open(filepath.Join(dir, "bridge-state.json"))
+	if err != nil {
+		log.Fatalf("Failed to open state store: %v", err)
+	}
+	return fileStore, func() {}
+}
+
+func inviteCreate(st store.KV, auditLog *audit.Log, args []string) {
+	fs := flag.NewFlagSet("invite create", flag.ExitOnError)
+	uses := fs.Int("uses", 1, "how many users may redeem each code (0 = unlimited)")
+	days := fs.Int("days", 0, "days until the codes expire (0 = never)")
+	count := fs.Int("count", 1, "number of codes to create")
+	note := fs.String("note", "", "note shown in invite list, e.g. who the codes are for")
+	fs.Parse(args)
+	if *uses < 0 || *days < 0 || *count < 1 {
+		log.Fatal("--uses and --days must not be negative, --count must be at least 1")
+	}
+
+	for i := 0; i < *count; i++ {
+		c, err := invite.Create(st, *uses, time.Duration(*days)*24*time.Hour, *note)
+		if err != nil {
+			log.Fatal(err)
+		}
+		auditLog.Record(context.Background(), audit.Event{Action: "invite.create", Actor: "cli", Target: c.Code,
+			Detail: fmt.Sprintf("uses=%d days=%d", *uses, *days)})
+		fmt.Println(c.Code)
+	}
+}
+
+func inviteList(st store.KV) {
+	codes, err := invite.List(st)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(codes) == 0 {
+		fmt.Println("No invite codes")
+		return
+	}
+
+	now := time.Now()
+	fmt.Printf("%-10s %-9s %-17s %-17s %s\n", "CODE", "USES", "CREATED", "EXPIRES", "NOTE")
+	for _, c := range codes {
+		uses := fmt.Sprintf("%d/%d", c.Uses, c.MaxUses)
+		if c.MaxUses == 0 {
+			uses = fmt.Sprintf("%d/-", c.Uses)
+		}
+		expires := "never"
+		if !c.Expires.IsZero() {
+			expires = c.Expires.Format("2006-01-02 15:04")
+			if now.After(c.Expires) {
+				expires += " (expired)"
+			}
+		}
+		fmt.Printf("%-10s %-9s %-17s %-17s %s\n", c.Code, uses, c.Created.Format("2006-01-02 15:04"), expires, c.Note)
+	}
+}
@@ -3,22 +3,43 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/wy51ai/moltbotCNAPP/internal/analytics"
+	"github.com/wy51ai/moltbotCNAPP/internal/backend"
 	"github.com/wy51ai/moltbotCNAPP/internal/bridge"
-	"github.com/wy51ai/moltbotCNAPP/internal/clawdbot"
+	"github.com/wy51ai/moltbotCNAPP/internal/cluster"
 	"github.com/wy51ai/moltbotCNAPP/internal/config"
-	"github.com/wy51ai/moltbotCNAPP/internal/feishu"
+	"github.com/wy51ai/moltbotCNAPP/internal/deadletter"
+	"github.com/wy51ai/moltbotCNAPP/internal/errreport"
+	"github.com/wy51ai/moltbotCNAPP/internal/events"
+	"github.com/wy51ai/moltbotCNAPP/internal/jsonl"
+	"github.com/wy51ai/moltbotCNAPP/internal/leader"
+	"github.com/wy51ai/moltbotCNAPP/internal/metrics"
+	"github.com/wy51ai/moltbotCNAPP/internal/netdial"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/internal/retention"
+	"github.com/wy51ai/moltbotCNAPP/internal/seal"
+	"github.com/wy51ai/moltbotCNAPP/internal/store"
+	"github.com/wy51ai/moltbotCNAPP/internal/tracing"
+	"github.com/wy51ai/moltbotCNAPP/internal/transcript"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
 )
 
+// Version is set at build time via -ldflags "-X main.Version=..."
+var Version = "dev"
+
 func main() {
 	cmd := "run"
 	if len(os.Args) > 1 {
@@ -32,29 +53,41 @@ func main() {
 	case "stop":
 		cmdStop()
 	case "status":
-		cmdStatus()
+		cmdStatus(os.Args[2:])
 	case "restart":
 		applyConfigArgs(os.Args[2:])
-		dir, _ := config.Dir()
-		pidPath := filepath.Join(dir, "bridge.pid")
-		if pid, err := readPID(pidPath); err == nil {
-			stopProcess(pid)
-			for i := 0; i < 10; i++ {
-				time.Sleep(200 * time.Millisecond)
-				if !isProcessRunning(pid) {
-					break
-				}
-			}
-			os.Remove(pidPath)
-		}
-		cmdStart()
+		cmdRestart()
+	case "analytics":
+		cmdAnalytics(os.Args[2:])
+	case "events":
+		cmdEvents(os.Args[2:])
+	case "purge":
+		cmdPurge(os.Args[2:])
+	case "forget":
+		cmdForget(os.Args[2:])
+	case "rekey":
+		cmdRekey()
+	case "invite":
+		cmdInvite(os.Args[2:])
+	case "deadletter":
+		cmdDeadletter(os.Args[2:])
+	case "chat":
+		cmdChat(os.Args[2:])
+	case "simulate":
+		cmdSimulate(os.Args[2:])
+	case "diag":
+		cmdDiag(os.Args[2:])
+	case "session":
+		cmdSession(os.Args[2:])
+	case "migrate":
+		cmdMigrate(os.Args[2:])
 	case "run":
 		if len(os.Args) > 2 {
 			applyConfigArgs(os.Args[2:])
 		}
 		cmdRun()
 	default:
-		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\nUsage:\n  clawdbot-bridge start [fs_app_id=xxx fs_app_secret=yyy]\n  clawdbot-bridge stop\n  clawdbot-bridge status\n  clawdbot-bridge restart\n  clawdbot-bridge run\n")
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\nUsage:\n  clawdbot-bridge start [fs_app_id=xxx fs_app_secret=yyy]\n  clawdbot-bridge stop\n  clawdbot-bridge status [--json]\n  clawdbot-bridge restart\n  clawdbot-bridge run\n  clawdbot-bridge analytics [--days 7]\n  clawdbot-bridge events [--chat oc_xxx] [--since 2h] [--type error] [--cid xxx] [--json]\n  clawdbot-bridge purge [--dry-run]\n  clawdbot-bridge forget <open_id>\n  clawdbot-bridge rekey\n  clawdbot-bridge invite create|list|revoke\n  clawdbot-bridge deadletter list|retry|drop\n  clawdbot-bridge chat [--chat oc_xxx] [--backend name] [--agent id]\n  clawdbot-bridge simulate --chat oc_xxx [--type group] [--mention] --text xxx\n  clawdbot-bridge diag feishu --chat oc_xxx [--keep] [--json]\n  clawdbot-bridge session snapshot|restore\n  clawdbot-bridge migrate [--dry-run]\n", cmd)
 		os.Exit(1)
 	}
 }
@@ -79,6 +112,22 @@ func cmdStart() {
 		log.Fatalf("Config error: %v", err)
 	}
 
+	p := spawnDaemon(logPath, nil)
+	pid := p.Pid
+
+	// Write PID file
+	if err := os.WriteFile(pidPath, []byte(strconv.Itoa(pid)), 0644); err != nil {
+		p.Kill()
+		log.Fatalf("Failed to write PID file: %v", err)
+	}
+
+	p.Release()
+	fmt.Printf("Started (PID %d), log: %s\n", pid, logPath)
+}
+
+// spawnDaemon re-execs this binary as a background "run" process logging
+// to logPath, with env appended to the current environment
+func spawnDaemon(logPath string, env []string) *os.Process {
 	// Open log file
 	logFile, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
@@ -100,23 +149,92 @@ func cmdStart() {
 	}
 
 	p, err := os.StartProcess(exe, []string{exe, "run"}, &os.ProcAttr{
+		Env:   append(os.Environ(), env...),
 		Files: []*os.File{devNull, logFile, logFile},
 		Sys:   daemonSysProcAttr(),
 	})
 	if err != nil {
 		log.Fatalf("Failed to start daemon: %v", err)
 	}
+	return p
+}
 
-	pid := p.Pid
+// readyFileEnv names the file a process started by restart writes its PID
+// to once its Feishu connection is up
+const readyFileEnv = "CLAWDBOT_BRIDGE_READY_FILE"
 
-	// Write PID file
-	if err := os.WriteFile(pidPath, []byte(strconv.Itoa(pid)), 0644); err != nil {
-		p.Kill()
-		log.Fatalf("Failed to write PID file: %v", err)
+// cmdRestart starts the new process alongside the old one and only stops
+// the old process once the new one is connected to Feishu. The old process
+// then finishes its in-flight runs before exiting, so no message is dropped.
+func cmdRestart() {
+	dir, err := config.Dir()
+	if err != nil {
+		log.Fatal(err)
+	}
+	pidPath := filepath.Join(dir, "bridge.pid")
+	logPath := filepath.Join(dir, "bridge.log")
+
+	oldPID, err := readPID(pidPath)
+	if err != nil || !isProcessRunning(oldPID) {
+		os.Remove(pidPath)
+		cmdStart()
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	if cfg.Failover.Enabled {
+		// The new process would sit as standby behind the old one's lease;
+		// the standby instance covers the gap instead
+		stopAndWait(oldPID, cfg.DrainTimeout)
+		os.Remove(pidPath)
+		cmdStart()
+		return
 	}
 
+	readyPath := filepath.Join(dir, "bridge.ready")
+	os.Remove(readyPath)
+	p := spawnDaemon(logPath, []string{readyFileEnv + "=" + readyPath})
+	newPID := p.Pid
 	p.Release()
-	fmt.Printf("Started (PID %d), log: %s\n", pid, logPath)
+
+	if !waitReady(readyPath, newPID, 30*time.Second) {
+		stopProcess(newPID)
+		log.Fatalf("New process (PID %d) did not connect to Feishu in time; PID %d keeps running, see %s", newPID, oldPID, logPath)
+	}
+	os.Remove(readyPath)
+
+	if err := os.WriteFile(pidPath, []byte(strconv.Itoa(newPID)), 0644); err != nil {
+		log.Fatalf("Failed to write PID file: %v", err)
+	}
+	stopProcess(oldPID)
+	fmt.Printf("Restarted (PID %d -> %d), old process is finishing in-flight runs; log: %s\n", oldPID, newPID, logPath)
+}
+
+// waitReady waits for pid to report readiness through path
+func waitReady(path string, pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if got, err := readPID(path); err == nil && got == pid {
+			return true
+		}
+		if !isProcessRunning(pid) {
+			return false
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return false
+}
+
+// stopAndWait signals pid to stop and waits up to drain for it to exit
+func stopAndWait(pid int, drain time.Duration) {
+	stopProcess(pid)
+	deadline := time.Now().Add(drain + 5*time.Second)
+	for time.Now().Before(deadline) && isProcessRunning(pid) {
+		time.Sleep(200 * time.Millisecond)
+	}
 }
 
 func cmdStop() {
@@ -132,73 +250,362 @@ func cmdStop() {
 		os.Exit(1)
 	}
 
-	if err := stopProcess(pid); err != nil {
+	if !isProcessRunning(pid) {
 		fmt.Println("Not running")
 		os.Remove(pidPath)
 		os.Exit(1)
 	}
 
-	for i := 0; i < 10; i++ {
-		time.Sleep(200 * time.Millisecond)
-		if !isProcessRunning(pid) {
-			break
-		}
+	// The process finishes in-flight runs before exiting
+	drainTimeout := 2 * time.Minute
+	if cfg, err := config.Load(); err == nil {
+		drainTimeout = cfg.DrainTimeout
 	}
+	fmt.Println("Stopping, waiting for in-flight runs...")
+	stopAndWait(pid, drainTimeout)
 
 	os.Remove(pidPath)
 	fmt.Println("Stopped")
 }
 
-func cmdStatus() {
+// statusJSON is the output of `status --json`
+type statusJSON struct {
+	Running bool `json:"running"`
+	PID     int  `json:"pid,omitempty"`
+	// Status is the last snapshot the bridge wrote, if it's recent
+	Status *bridge.Status `json:"status,omitempty"`
+}
+
+func cmdStatus(args []string) {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the status as JSON")
+	fs.Parse(args)
+
 	dir, err := config.Dir()
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	pidPath := filepath.Join(dir, "bridge.pid")
-	if isRunning(pidPath) {
+	running := isRunning(pidPath)
+	if *asJSON {
+		out := statusJSON{Running: running}
+		if running {
+			out.PID, _ = readPID(pidPath)
+			if st, err := bridge.ReadStatus(dir); err == nil && time.Since(st.UpdatedAt) <= 5*time.Minute {
+				out.Status = st
+			}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(out)
+		if !running {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if running {
 		pid, _ := readPID(pidPath)
 		fmt.Printf("Running (PID %d)\n", pid)
+		printStatus(dir)
 	} else {
 		fmt.Println("Not running")
 		os.Exit(1)
 	}
 }
 
+func cmdAnalytics(args []string) {
+	fs := flag.NewFlagSet("analytics", flag.ExitOnError)
+	days := fs.Int("days", 7, "number of days to summarize")
+	fs.Parse(args)
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	keys, err := seal.FromConfig(cfg.Encryption)
+	if err != nil {
+		log.Fatal(err)
+	}
+	st, err := transcript.Open(cfg.Transcripts.Dir, false, keys)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	now := time.Now()
+	report, err := analytics.Build(st, now.AddDate(0, 0, -*days), now)
+	if err != nil {
+		log.Fatalf("Failed to build report: %v", err)
+	}
+	fmt.Println(report.Markdown())
+}
+
+func cmdEvents(args []string) {
+	fs := flag.NewFlagSet("events", flag.ExitOnError)
+	chat := fs.String("chat", "", "only events for this chat_id")
+	since := fs.String("since", "24h", "how far back to look, e.g. 30m, 2h, 7d")
+	types := fs.String("type", "", "comma-separated event types, e.g. error,run_end")
+	cid := fs.String("cid", "", "only events with this correlation ID")
+	asJSON := fs.Bool("json", false, "print raw JSON lines")
+	fs.Parse(args)
+
+	window, err := parseSince(*since)
+	if err != nil {
+		log.Fatalf("Invalid --since: %v", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+
+	filter := events.Filter{
+		Since:         time.Now().Add(-window),
+		ChatID:        *chat,
+		CorrelationID: *cid,
+	}
+	if *types != "" {
+		filter.Types = strings.Split(*types, ",")
+	}
+
+	err = events.Query(cfg.Events.Dir, filter, func(ev events.Event) {
+		if *asJSON {
+			line, _ := json.Marshal(ev)
+			fmt.Println(string(line))
+			return
+		}
+		fmt.Println(ev.Format())
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parseSince accepts a Go duration or a whole number of days ("7d")
+func parseSince(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid day count %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// printStatus shows the latency and active-run snapshot of a running bridge
+func printStatus(dir string) {
+	st, err := bridge.ReadStatus(dir)
+	if err != nil || time.Since(st.UpdatedAt) > 5*time.Minute {
+		return
+	}
+
+	if u := st.Uptime; !u.StartedAt.IsZero() {
+		fmt.Printf("\nUp since %s (%s), %d messages served, %d gateway errors\n",
+			u.StartedAt.Format("2006-01-02 15:04:05"), (time.Duration(u.Seconds) * time.Second).String(), u.MessagesServed, u.GatewayErrors)
+	}
+	if c := st.Connection; c != nil && !c.ConnectedAt.IsZero() {
+		fmt.Printf("Feishu long connection up since %s, %d connects\n", c.ConnectedAt.Format("2006-01-02 15:04:05"), c.Connects)
+	}
+
+	row := func(name string, l bridge.LatencyStats) {
+		fmt.Printf("  %-24s %6d %8.1fs %8.1fs %8.1fs\n", name, l.Samples,
+			float64(l.P50Ms)/1000, float64(l.P95Ms)/1000, float64(l.P99Ms)/1000)
+	}
+	fmt.Printf("\nResponse latency (last %s):\n", st.Window)
+	fmt.Printf("  %-24s %6s %9s %9s %9s\n", "", "count", "p50", "p95", "p99")
+	row("overall", st.Overall)
+	for _, name := range sortedKeys(st.Backends) {
+		row("backend "+name, st.Backends[name])
+	}
+	for _, chatID := range sortedKeys(st.Chats) {
+		row(chatID, st.Chats[chatID])
+	}
+
+	if c := st.Feishu; c != nil && c.State != feishu.CircuitClosed {
+		fmt.Printf("\nFeishu API: %s since %s, %d replies pending\n", c.State, c.Since.Format("15:04:05"), c.Pending)
+	}
+	if c := st.Connection; c != nil && (c.Silent || c.Reconnects > 0) {
+		state := "receiving"
+		if c.Silent {
+			state = "silent, reconnecting"
+		}
+		fmt.Printf("\nFeishu long connection: %s, last activity %s, %d watchdog reconnects\n", state, c.LastActivity.Format("15:04:05"), c.Reconnects)
+	}
+	if len(st.GatewayDown) > 0 {
+		fmt.Printf("\nGateway unavailable for: %s\n", strings.Join(st.GatewayDown, ", "))
+	}
+
+	if len(st.ActiveRuns) > 0 {
+		fmt.Printf("\nActive runs (%d):\n", len(st.ActiveRuns))
+		for _, run := range st.ActiveRuns {
+			fmt.Printf("  %-14s %-24s %-12s %-24s %s\n", run.ID, run.ChatID, run.Backend, run.Instance,
+				time.Since(run.Started).Round(time.Second))
+		}
+	}
+}
+
+func sortedKeys(m map[string]bridge.LatencyStats) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func cmdRun() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	// Gateway frames and API errors can echo credentials
+	log.SetOutput(redact.Writer(os.Stderr))
 	log.Println("[Main] Starting ClawdBot Bridge...")
 
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("[Main] Failed to load config: %v", err)
 	}
+	redact.Register(cfg.Secrets()...)
 
-	log.Printf("[Main] Loaded config: AppID=%s, Gateway=127.0.0.1:%d, AgentID=%s", "SessionKey=%s",
-		cfg.Feishu.AppID, cfg.Clawdbot.GatewayPort, cfg.Clawdbot.AgentID, cfg.Clawdbot.SessionKey)
+	log.Printf("[Main] Loaded config: AppID=%s, Backend=%s, Gateway=%s, AgentID=%s, SessionKey=%s",
+		cfg.Feishu.AppID, cfg.Backend.Type, cfg.Clawdbot.WebSocketAddr(), cfg.Clawdbot.AgentID, cfg.Clawdbot.SessionKey)
+
+	dir, err := config.Dir()
+	if err != nil {
+		log.Fatalf("[Main] Failed to get config dir: %v", err)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if m, err := config.PlanMigration(home); err == nil && !m.Empty() {
+			log.Printf("[Main] Both %s and %s exist; reading %s. Run 'clawdbot-bridge migrate' to merge them into %s",
+				m.Dir, m.From, dir, m.Dir)
+		}
+	}
+	var st store.KV
+	var shared store.Shared
+	var rs *store.Redis
+	if cfg.Redis.Addr != "" {
+		rs, err = store.OpenRedis(cfg.Redis)
+		if err != nil {
+			log.Fatalf("[Main] Failed to open shared state: %v", err)
+		}
+		defer rs.Close()
+		st, shared = rs, rs
+		log.Printf("[Main] Sharing state via Redis at %s (prefix %s)", cfg.Redis.Addr, cfg.Redis.Prefix)
+	} else {
+		fileStore, err := store.Open(filepath.Join(dir, "bridge-state.json"))
+		if err != nil {
+			log.Fatalf("[Main] Failed to open state store: %v", err)
+		}
+		local := store.NewLocal()
+		defer local.Close()
+		st, shared = fileStore, local
+	}
 
-	clawdbotClient := clawdbot.NewClient(
-		cfg.Clawdbot.GatewayPort,
-		cfg.Clawdbot.GatewayToken,
-		cfg.Clawdbot.AgentID,
-			)
+	// In a failover pair only the primary connects to Feishu; the standby
+	// blocks here until the primary stops renewing its lease
+	var elector *leader.Elector
+	if cfg.Failover.Enabled {
+		elector = waitForLeadership(cfg, rs)
+		if elector == nil {
+			return
+		}
+		defer elector.Release()
+	}
 
-	bridgeInstance := bridge.NewBridge(nil, clawdbotClient, cfg.Feishu.ThinkingThresholdMs, cfg.Clawdbot.SessionKey)
+	router, err := backend.NewRouter(cfg, st, nil)
+	if err != nil {
+		log.Fatalf("[Main] Failed to create backends: %v", err)
+	}
+	log.Printf("[Main] Backends: %v, default route: %s", router.Names(), cfg.Routes.Default)
 
+	// Don't take messages before the gateway can answer them
+	if !waitForGateway(router) {
+		return
+	}
+
+	bridgeInstance := bridge.NewBridge(nil, router, st, shared, cfg)
+	bridgeInstance.SetVersion(Version)
+
+	feishuOpts := append(feishuNetwork(cfg), feishu.WithSilenceTimeout(cfg.Feishu.SilenceTimeout))
+	if cfg.Helpdesk.Enabled {
+		feishuOpts = append(feishuOpts, feishu.WithHelpdesk(cfg.Helpdesk.ID, cfg.Helpdesk.Token))
+	}
 	feishuClient := feishu.NewClient(
 		cfg.Feishu.AppID,
 		cfg.Feishu.AppSecret,
 		bridgeInstance.HandleMessage,
+		feishuOpts...,
 	)
 
+	feishuClient.SetCardActionHandler(bridgeInstance.HandleCardAction)
+	feishuClient.SetBotAddedHandler(bridgeInstance.HandleBotAdded)
+	feishuClient.SetTaskUpdateHandler(bridgeInstance.HandleTaskUpdate)
 	bridgeInstance.SetFeishuClient(feishuClient)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	if cfg.Cluster.Enabled {
+		bridgeInstance.JoinCluster(cluster.New(cfg.Cluster.Instance, rs, cfg.Cluster.Heartbeat))
+	}
+	bridgeInstance.Start(ctx)
+	defer bridgeInstance.Close()
+
+	if addr := cfg.Admin.Listen; addr != "" {
+		adminServer := &http.Server{Addr: addr, Handler: bridgeInstance.AdminHandler(), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			log.Printf("[Main] Admin API listening on %s", addr)
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("[Main] Admin API stopped: %v", err)
+			}
+		}()
+		defer adminServer.Close()
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Observability.Tracing, Version)
+	if err != nil {
+		log.Fatalf("[Main] Failed to set up tracing: %v", err)
+	}
+	defer func() {
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer flushCancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			log.Printf("[Main] Failed to flush traces: %v", err)
+		}
+	}()
+
+	flushErrors, err := errreport.Setup(cfg.Observability.Sentry, Version)
+	if err != nil {
+		log.Fatalf("[Main] Failed to set up error reporting: %v", err)
+	}
+	defer flushErrors()
+
+	// Push metrics for push-based monitoring stacks
+	obs := cfg.Observability
+	var sinks []metrics.Sink
+	if obs.StatsD.Addr != "" {
+		sinks = append(sinks, metrics.NewStatsD(obs.StatsD.Addr, obs.MetricPrefix, obs.StatsD.DogStatsD))
+	}
+	if obs.InfluxDB.URL != "" {
+		sinks = append(sinks, metrics.NewInfluxDB(obs.InfluxDB.URL, obs.InfluxDB.Token, obs.MetricPrefix))
+	}
+	for _, sink := range sinks {
+		log.Printf("[Main] Pushing metrics to %s every %s", sink.Name(), obs.PushInterval)
+	}
+	go metrics.Push(ctx, metrics.Default(), obs.PushInterval, sinks...)
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
+	// Follow gateway port/token, app secret rotation and alias changes
+	// without a restart
+	rot := newRotator(cfg, feishuClient, router, bridgeInstance)
+	if path := cfg.Clawdbot.ConfigPath; path != "" {
+		current := config.GatewaySettings{Port: cfg.Clawdbot.GatewayPort, Token: cfg.Clawdbot.GatewayToken}
+		go config.WatchGateway(ctx, path, 5*time.Second, current, rot.gatewayChanged)
+	}
+	go rot.Run(ctx, cfg.CredentialFiles())
+
 	errChan := make(chan error, 1)
 	go func() {
 		if err := feishuClient.Start(ctx); err != nil {
@@ -206,21 +613,318 @@ func cmdRun() {
 		}
 	}()
 
+	var leaseLost <-chan struct{}
+	if elector != nil {
+		leaseLost = elector.Keep(ctx)
+	}
+
+	// Tell a restarting parent we're connected so it can stop the old process
+	if path := os.Getenv(readyFileEnv); path != "" {
+		go func() {
+			select {
+			case <-feishuClient.Connected():
+			case <-ctx.Done():
+				return
+			}
+			if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+				log.Printf("[Main] Failed to report readiness: %v", err)
+			}
+		}()
+	}
+
 	log.Println("[Main] ClawdBot Bridge started successfully")
 	log.Println("[Main] Press Ctrl+C to stop")
 
 	select {
 	case <-sigChan:
 		log.Println("[Main] Received shutdown signal, stopping...")
+		drain(bridgeInstance, cfg.DrainTimeout, sigChan)
 		cancel()
 	case err := <-errChan:
 		log.Printf("[Main] Error: %v", err)
+		bridgeInstance.Alert("feishu_connection", fmt.Sprintf("飞书长连接异常退出：%v", err))
+		cancel()
+	case <-leaseLost:
+		// The Feishu connection can't be closed in place, so step down by
+		// exiting; a supervisor restart brings this instance back as standby
+		log.Println("[Main] Lost primary lease, stopping so the standby can take over")
+		bridgeInstance.Alert("failover", "主实例租约丢失，已停止，由备用实例接管")
 		cancel()
 	}
 
 	log.Println("[Main] ClawdBot Bridge stopped")
 }
 
+// drain lets in-flight runs finish before shutdown. Messages still
+// arriving on the Feishu connection meanwhile are handled too. A second
+// signal skips the wait.
+func drain(b *bridge.Bridge, timeout time.Duration, sigChan <-chan os.Signal) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-sigChan:
+			log.Println("[Main] Received second signal, not waiting for in-flight runs")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	log.Printf("[Main] Waiting up to %s for in-flight runs", timeout)
+	if err := b.Drain(ctx); err != nil {
+		log.Printf("[Main] Stopping with %v", err)
+		return
+	}
+	log.Println("[Main] All runs finished")
+}
+
+// cmdPurge deletes data files past their retention right away instead of
+// waiting for the running bridge's hourly purge
+func cmdPurge(args []string) {
+	fs := flag.NewFlagSet("purge", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only list the files that would be deleted")
+	fs.Parse(args)
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+
+	targets := retention.Targets(cfg)
+	for _, t := range targets {
+		if t.Days > 0 {
+			fmt.Printf("%-12s keep %d days (%s)\n", t.Name, t.Days, t.Dir)
+		} else {
+			fmt.Printf("%-12s kept forever (%s)\n", t.Name, t.Dir)
+		}
+	}
+
+	removed, err := retention.Purge(targets, time.Now(), *dryRun)
+	var total int64
+	for _, r := range removed {
+		total += r.Size
+		fmt.Printf("  %s\n", r.Path)
+	}
+	verb := "Deleted"
+	if *dryRun {
+		verb = "Would delete"
+	}
+	fmt.Printf("%s %d files (%.1f MB)\n", verb, len(removed), float64(total)/(1<<20))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	days := cfg.DeadLetters.RetentionDays
+	if days <= 0 {
+		fmt.Printf("%-12s kept until retried or dropped\n", "dead letters")
+		return
+	}
+	fmt.Printf("%-12s keep %d days\n", "dead letters", days)
+	if cfg.Redis.Addr == "" && isRunning(filepath.Join(cfg.Dir, "bridge.pid")) {
+		// The running bridge owns the local state file and expires them itself
+		fmt.Println("  skipped: the running bridge removes them")
+		return
+	}
+	kv, closeStore := openStateStore(cfg)
+	defer closeStore()
+	n, err := deadletter.Expire(kv, time.Now().AddDate(0, 0, -days), *dryRun)
+	fmt.Printf("%s %d dead letters\n", verb, n)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// feishuNetwork returns the options sending Feishu traffic through the
+// proxies and dial settings in bridge.json, if any are set
+func feishuNetwork(cfg *config.Config) []feishu.Option {
+	var opts []feishu.Option
+	if cfg.Feishu.Proxy != "" || cfg.Feishu.ConnectionProxy != "" {
+		opts = append(opts, feishu.WithProxy(cfg.Feishu.Proxies()))
+	}
+	if dial := netdial.New(cfg.Network); dial != nil {
+		opts = append(opts, feishu.WithDialer(dial))
+	}
+	return opts
+}
+
+// cmdForget deletes one user's data, like the user sending /忘记我. The
+// bridge must be stopped so its writes can't race the rewrite.
+func cmdForget(args []string) {
+	if len(args) != 1 || !strings.HasPrefix(args[0], "ou_") {
+		fmt.Fprintln(os.Stderr, "Usage: clawdbot-bridge forget <open_id>")
+		os.Exit(1)
+	}
+	userID := args[0]
+
+	dir, err := config.Dir()
+	if err != nil {
+		log.Fatalf("Failed to get config dir: %v", err)
+	}
+	if isRunning(filepath.Join(dir, "bridge.pid")) {
+		log.Fatal("Bridge is running; stop it first, or ask the user to send /忘记我")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+
+	var st store.KV
+	var shared store.Shared
+	if cfg.Redis.Addr != "" {
+		rs, err := store.OpenRedis(cfg.Redis)
+		if err != nil {
+			log.Fatalf("Failed to open shared state: %v", err)
+		}
+		defer rs.Close()
+		st, shared = rs, rs
+	} else {
+		fileStore, err := store.Open(filepath.Join(dir, "bridge-state.json"))
+		if err != nil {
+			log.Fatalf("Failed to open state store: %v", err)
+		}
+		local := store.NewLocal()
+		defer local.Close()
+		st, shared = fileStore, local
+	}
+
+	router, err := backend.NewRouter(cfg, st, nil)
+	if err != nil {
+		log.Fatalf("Failed to create backends: %v", err)
+	}
+
+	b := bridge.NewBridge(nil, router, st, shared, cfg)
+	report := b.ForgetUser(context.Background(), "cli", userID)
+	fmt.Printf("User %s\n%s\n", userID, report.Text())
+	if len(report.Failures) > 0 {
+		os.Exit(1)
+	}
+}
+
+// cmdRekey re-encrypts stored transcripts, shadow records, gateway run
+// recordings and dead letters with the active key so retired keys can be removed from the keyring. Like forget, it needs the bridge
+// stopped.
+func cmdRekey() {
+	dir, err := config.Dir()
+	if err != nil {
+		log.Fatalf("Failed to get config dir: %v", err)
+	}
+	if isRunning(filepath.Join(dir, "bridge.pid")) {
+		log.Fatal("Bridge is running; stop it first")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	keys, err := seal.FromConfig(cfg.Encryption)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if keys == nil {
+		log.Fatal("Encryption is not configured (encryption.keyring_file in bridge.json)")
+	}
+	st, err := transcript.Open(cfg.Transcripts.Dir, false, keys)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	n, err := st.Rekey()
+	fmt.Printf("Re-encrypted %d records with key %s\n", n, keys.Active())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	files, err := bridge.ShadowFiles(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	n = 0
+	for _, path := range files {
+		var m int
+		m, err = jsonl.Rewrite(path, keys.Reseal)
+		n += m
+		if err != nil {
+			break
+		}
+	}
+	fmt.Printf("Re-encrypted %d shadow records with key %s\n", n, keys.Active())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if dir := cfg.Clawdbot.RecordDir; dir != "" {
+		n, err = backend.RekeyRecordings(dir, keys)
+		fmt.Printf("Re-encrypted %d gateway run recordings with key %s\n", n, keys.Active())
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	kv, closeStore := openStateStore(cfg)
+	defer closeStore()
+	n, err = deadletter.Rekey(kv, keys)
+	fmt.Printf("Re-encrypted %d dead letters with key %s\n", n, keys.Active())
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// waitForLeadership blocks until this instance holds the failover lease.
+// It returns nil if interrupted while still standby.
+func waitForLeadership(cfg *config.Config, rs *store.Redis) *leader.Elector {
+	var lease leader.Lease
+	if cfg.Failover.LockFile != "" {
+		fl, err := leader.NewFileLease(cfg.Failover.LockFile)
+		if err != nil {
+			log.Fatalf("[Main] Failed to set up failover: %v", err)
+		}
+		lease = fl
+	} else {
+		lease = rs.Lease("primary")
+	}
+
+	host, _ := os.Hostname()
+	elector := leader.New(lease, fmt.Sprintf("%s:%d", host, os.Getpid()), cfg.Failover.Lease)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := elector.Acquire(ctx); err != nil {
+		log.Println("[Main] Stopped while standby")
+		return nil
+	}
+	return elector
+}
+
+// waitForGateway blocks until every gateway backend accepts connections,
+// retrying with backoff. It returns false if interrupted while waiting.
+func waitForGateway(router *backend.Router) bool {
+	err := router.PingGateways()
+	if err == nil {
+		return true
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	delay := time.Second
+	for attempt := 1; err != nil; attempt++ {
+		log.Printf("[Main] Waiting for gateway (attempt %d, retry in %s): %v", attempt, delay, err)
+		select {
+		case <-ctx.Done():
+			log.Println("[Main] Stopped while waiting for gateway")
+			return false
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > 30*time.Second {
+			delay = 30 * time.Second
+		}
+		err = router.PingGateways()
+	}
+	log.Println("[Main] Gateway is reachable")
+	return true
+}
+
 func isRunning(pidPath string) bool {
 	pid, err := readPID(pidPath)
 	if err != nil {
@@ -253,9 +957,12 @@ func applyConfigArgs(args []string) {
 	}
 
 	// Read existing config if present (try bridge.json first)
+	// Sections not managed here (backends, routes, ...) are kept as-is
 	var cfg bridgeConfigJSON
+	raw := make(map[string]json.RawMessage)
 	if data, err := os.ReadFile(filepath.Join(dir, "bridge.json")); err == nil {
 		json.Unmarshal(data, &cfg)
+		json.Unmarshal(data, &raw)
 	}
 
 	if appID != "" {
@@ -276,7 +983,13 @@ func applyConfigArgs(args []string) {
 		}
 	}
 
-	data, _ := json.MarshalIndent(cfg, "", "  ")
+	for _, key := range []string{"feishu", "thinking_threshold_ms", "agent_id", "session_key"} {
+		delete(raw, key)
+	}
+	managed, _ := json.Marshal(cfg)
+	json.Unmarshal(managed, &raw)
+
+	data, _ := json.MarshalIndent(raw, "", "  ")
 	path := filepath.Join(dir, "bridge.json")
 	if err := os.WriteFile(path, data, 0600); err != nil {
 		log.Fatalf("Failed to save config: %v", err)
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+)
+
+// cmdMigrate merges ~/.clawdbot and ~/.openclaw into the directory next to
+// the gateway's current config, printing every change it makes
+func cmdMigrate(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only print what would change")
+	fs.Parse(args)
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	m, err := config.PlanMigration(home)
+	if err != nil {
+		log.Fatal(err)
+	}
+	printMigration(m)
+	// Only paths are rewritten; no setting has been renamed yet
+	fmt.Println("Legacy field names: none to convert, no bridge.json setting has been renamed")
+	if m.Empty() {
+		fmt.Println("Nothing to migrate")
+		return
+	}
+	if *dryRun {
+		return
+	}
+
+	for _, dir := range []string{m.Dir, m.From} {
+		if isRunning(filepath.Join(dir, "bridge.pid")) {
+			log.Fatal("Bridge is running; stop it first")
+		}
+	}
+	backups, err := m.Apply(time.Now())
+	for _, b := range backups {
+		fmt.Printf("Backup: %s\n", b)
+	}
+	if err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+
+	if _, err := config.LoadDir(m.Dir); err != nil {
+		fmt.Printf("Migrated, but the config doesn't load yet: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Migrated; config is now read from %s\n", m.Dir)
+	if len(m.Skipped) > 0 {
+		fmt.Printf("%s is left in place with %d entries that aren't the bridge's, such as the gateway's config\n", m.From, len(m.Skipped))
+	}
+}
+
+// printMigration lists what m changes, and the unknown bridge.json keys it
+// leaves alone
+func printMigration(m *config.Migration) {
+	fmt.Printf("Config directory: %s\n", m.Dir)
+	if m.From != "" {
+		fmt.Printf("Moving the bridge's files out of %s (%s):\n", m.From, m.Reason)
+		for _, name := range m.Moves {
+			fmt.Printf("  copy      %s\n", name)
+		}
+		for _, name := range m.Conflicts {
+			fmt.Printf("  conflict  %s: %s already has one, the old copy stays in the backup\n", name, m.Dir)
+		}
+		for _, name := range m.Skipped {
+			fmt.Printf("  keep      %s: not the bridge's, stays in %s\n", name, m.From)
+		}
+	}
+	if len(m.Rewrites) > 0 {
+		fmt.Println("bridge.json changes:")
+		for _, c := range m.Rewrites {
+			fmt.Printf("  %s: %s -> %s\n", c.Key, c.Old, c.New)
+		}
+	}
+	if len(m.Unknown) > 0 {
+		fmt.Println("bridge.json keys the bridge doesn't read (left as they are):")
+		for _, key := range m.Unknown {
+			fmt.Printf("  %s\n", key)
+		}
+	}
+}
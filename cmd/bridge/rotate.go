@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/backend"
+	"github.com/wy51ai/moltbotCNAPP/internal/bridge"
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// rotator applies rotated credentials to the running clients, and the
+// command aliases to the bridge. Secret values are never logged, only
+// which one changed.
+type rotator struct {
+	mu    sync.Mutex
+	appID string
+	creds config.Credentials
+	// gateway holds the settings last read from the gateway config file
+	gateway config.GatewaySettings
+	feishu  *feishu.Client
+	router  *backend.Router
+	bridge  *bridge.Bridge
+}
+
+func newRotator(cfg *config.Config, feishuClient *feishu.Client, router *backend.Router, b *bridge.Bridge) *rotator {
+	return &rotator{
+		appID:   cfg.Feishu.AppID,
+		creds:   cfg.Credentials(),
+		gateway: config.GatewaySettings{Port: cfg.Clawdbot.GatewayPort, Token: cfg.Clawdbot.GatewayToken},
+		feishu:  feishuClient,
+		router:  router,
+		bridge:  b,
+	}
+}
+
+// Run reloads credentials on SIGHUP and whenever the files holding them
+// change, until ctx is cancelled
+func (r *rotator) Run(ctx context.Context, files []string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	changed := make(chan struct{}, 1)
+	go config.WatchFiles(ctx, files, 5*time.Second, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.reload("SIGHUP")
+		case <-changed:
+			r.reload("config change")
+		}
+	}
+}
+
+// reload re-reads the config and applies any rotated secret
+func (r *rotator) reload(reason string) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Printf("[Main] Credential reload (%s) failed, keeping current credentials: %v", reason, err)
+		return
+	}
+
+	redact.Register(cfg.Secrets()...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cfg.Feishu.AppID != r.appID {
+		log.Printf("[Main] feishu.app_id changed; restart the bridge to switch apps")
+	}
+	r.bridge.SetAliases(cfg.Aliases)
+
+	next := cfg.Credentials()
+	if next == r.creds {
+		log.Printf("[Main] Credential reload (%s): nothing rotated", reason)
+		return
+	}
+	if next.AppSecret != r.creds.AppSecret {
+		log.Printf("[Main] Feishu app secret rotated (%s), reopening the long connection with it", reason)
+		r.feishu.UpdateSecret(next.AppSecret)
+	}
+	if next.GatewayToken != r.creds.GatewayToken {
+		log.Printf("[Main] Gateway token rotated (%s)", reason)
+		if !cfg.Clawdbot.TokenFromBridge {
+			// The override was removed; fall back to the gateway config file
+			r.gateway = config.GatewaySettings{Port: cfg.Clawdbot.GatewayPort, Token: cfg.Clawdbot.GatewayToken}
+		}
+		r.creds.GatewayToken = next.GatewayToken
+		r.applyGateway()
+	}
+	r.creds = next
+}
+
+// gatewayChanged takes settings from the gateway config file watcher
+func (r *rotator) gatewayChanged(gw config.GatewaySettings) {
+	redact.Register(gw.Token)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.gateway = gw
+	r.applyGateway()
+}
+
+// applyGateway points the gateway backends at the current port and token;
+// a token set in bridge.json wins over the gateway config file. Callers
+// must hold mu.
+func (r *rotator) applyGateway() {
+	token := r.gateway.Token
+	if r.creds.GatewayToken != "" {
+		token = r.creds.GatewayToken
+	}
+	r.router.UpdateGateway(r.gateway.Port, token)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/bridge"
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+)
+
+const sessionUsage = `Usage:
+  clawdbot-bridge session snapshot --chat oc_xxx [--out file]
+  clawdbot-bridge session restore --file file [--chat oc_yyy]`
+
+// cmdSession saves a chat's session state from the running bridge to a
+// file, or restores one, through the admin API
+func cmdSession(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, sessionUsage)
+		os.Exit(1)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+
+	switch args[0] {
+	case "snapshot":
+		sessionSnapshot(cfg, args[1:])
+	case "restore":
+		sessionRestore(cfg, args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, sessionUsage)
+		os.Exit(1)
+	}
+}
+
+func sessionSnapshot(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("session snapshot", flag.ExitOnError)
+	chatID := fs.String("chat", "", "chat_id to save")
+	out := fs.String("out", "", "file to write (default <chat>-<time>.json)")
+	fs.Parse(args)
+	if *chatID == "" {
+		fmt.Fprintln(os.Stderr, sessionUsage)
+		os.Exit(1)
+	}
+
+	var snap bridge.SessionSnapshot
+	adminRequest(cfg, http.MethodGet, "/v1/sessions/snapshot?chat_id="+url.QueryEscape(*chatID), nil, &snap)
+	path := *out
+	if path == "" {
+		path = fmt.Sprintf("%s-%s.json", *chatID, snap.TakenAt.Format("20060102-150405"))
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode snapshot: %v", err)
+	}
+	// The history is conversation content
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		log.Fatalf("Failed to write %s: %v", path, err)
+	}
+	fmt.Printf("Saved %s (session %s, backend %s, %d history messages) to %s\n",
+		*chatID, snap.SessionKey, snap.ServedBy, len(snap.Session.History), path)
+}
+
+func sessionRestore(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("session restore", flag.ExitOnError)
+	file := fs.String("file", "", "snapshot written by session snapshot")
+	chatID := fs.String("chat", "", "chat_id to restore onto (default the chat it was taken from)")
+	fs.Parse(args)
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, sessionUsage)
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *file, err)
+	}
+	var snap bridge.SessionSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		log.Fatalf("Failed to parse %s: %v", *file, err)
+	}
+	var resp bridge.RestoreResponse
+	adminRequest(cfg, http.MethodPost, "/v1/sessions/restore?chat_id="+url.QueryEscape(*chatID), snap, &resp)
+	fmt.Printf("Restored %s onto %s (session %s)\n", snap.ChatID, resp.ChatID, resp.SessionKey)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/bridge"
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+)
+
+// cmdSimulate sends a fabricated message to the running bridge through
+// its admin API, to exercise triggers, commands and streaming
+func cmdSimulate(args []string) {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	chatID := fs.String("chat", "", "chat_id the message appears in; replies are sent there")
+	chatType := fs.String("type", "p2p", "chat type: p2p or group")
+	sender := fs.String("sender", "", "sender open_id (default ou_simulated)")
+	text := fs.String("text", "", "message text")
+	mention := fs.Bool("mention", false, "@-mention the bot")
+	fs.Parse(args)
+	if *chatID == "" || *text == "" {
+		fmt.Fprintln(os.Stderr, "Usage: clawdbot-bridge simulate --chat oc_xxx [--type group] [--sender ou_xxx] [--mention] --text xxx")
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	var out bridge.SimulateResponse
+	adminRequest(cfg, http.MethodPost, "/v1/simulate", bridge.SimulateRequest{
+		ChatID:   *chatID,
+		ChatType: *chatType,
+		SenderID: *sender,
+		Text:     *text,
+		Mention:  *mention,
+	}, &out)
+	fmt.Printf("Injected %s into %s (cid %s)\n", out.MessageID, *chatID, out.CorrelationID)
+	fmt.Printf("Follow it with: clawdbot-bridge events --cid %s\n", out.CorrelationID)
+}
+
+// adminRequest calls the running bridge's admin API with body as JSON
+// and decodes the JSON response into out, exiting on failure
+func adminRequest(cfg *config.Config, method, path string, body, out interface{}) {
+	if cfg.Admin.Listen == "" {
+		log.Fatal("The admin API is disabled; set admin.listen and admin.token in bridge.json and restart the bridge")
+	}
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			log.Fatalf("Failed to encode request: %v", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, adminURL(cfg.Admin.Listen)+path, reqBody)
+	if err != nil {
+		log.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cfg.Admin.Token)
+
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		log.Fatalf("Failed to reach the bridge (is it running?): %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		log.Fatalf("Bridge refused the request: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		log.Fatalf("Failed to parse response: %v", err)
+	}
+}
+
+// adminURL returns the URL to reach the admin API listening on addr,
+// using loopback for wildcard addresses
+func adminURL(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "http://" + addr
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
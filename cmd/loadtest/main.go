@@ -0,0 +1,343 @@
+// Command loadtest drives concurrent synthetic conversations through the
+// bridge, with a fake Feishu server on one side and a mock gateway on the
+// other, and reports throughput and reply latency. Each comma-separated
+// value of -updates-per-second gets a run of its own, so the effect of the
+// streaming edit budget can be compared side by side.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/testutil"
+	"github.com/wy51ai/moltbotCNAPP/pkg/bridge"
+	"github.com/wy51ai/moltbotCNAPP/pkg/connector"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// options describe the load and the environment it runs against
+type options struct {
+	conversations int
+	turns         int
+	replyChars    int
+	chunkChars    int
+	chunkInterval time.Duration
+	firstToken    time.Duration
+	feishuLatency time.Duration
+	updateLimit   int
+	minInterval   time.Duration
+	maxInterval   time.Duration
+	timeout       time.Duration
+}
+
+func main() {
+	var o options
+	fs := flag.NewFlagSet("loadtest", flag.ExitOnError)
+	fs.IntVar(&o.conversations, "conversations", 20, "concurrent conversations, each in its own chat")
+	fs.IntVar(&o.turns, "turns", 5, "messages per conversation, each sent once the previous reply is complete")
+	fs.IntVar(&o.replyChars, "reply-chars", 600, "length of each reply")
+	fs.IntVar(&o.chunkChars, "chunk-chars", 20, "characters per streamed delta")
+	fs.DurationVar(&o.chunkInterval, "chunk-interval", 50*time.Millisecond, "gap between streamed deltas")
+	fs.DurationVar(&o.firstToken, "first-token", 500*time.Millisecond, "gateway delay before the first delta")
+	fs.DurationVar(&o.feishuLatency, "feishu-latency", 50*time.Millisecond, "delay of each Feishu message API call")
+	fs.IntVar(&o.updateLimit, "update-limit", 0, "message edits per second Feishu accepts before rate limiting; 0 for no limit")
+	fs.DurationVar(&o.minInterval, "min-interval", 300*time.Millisecond, "streaming.min_interval_ms")
+	fs.DurationVar(&o.maxInterval, "max-interval", 5*time.Second, "streaming.max_interval_ms")
+	fs.DurationVar(&o.timeout, "timeout", time.Minute, "how long to wait for a reply before counting it failed")
+	budgets := fs.String("updates-per-second", "20", "streaming.updates_per_second values to compare, comma-separated")
+	verbose := fs.Bool("verbose", false, "show bridge logs")
+	fs.Parse(os.Args[1:])
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+	var rates []float64
+	for _, s := range strings.Split(*budgets, ",") {
+		ups, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || ups <= 0 {
+			fmt.Fprintf(os.Stderr, "Invalid updates per second %q\n", s)
+			os.Exit(2)
+		}
+		rates = append(rates, ups)
+	}
+
+	fmt.Printf("%d conversations x %d turns, %d-char replies: first delta after %v, %d chars every %v\n",
+		o.conversations, o.turns, o.replyChars, o.firstToken, o.chunkChars, o.chunkInterval)
+	fmt.Printf("Feishu latency %v, edit limit %d/s, streaming interval %v-%v\n\n",
+		o.feishuLatency, o.updateLimit, o.minInterval, o.maxInterval)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "updates/s\treplies\tfailed\ttime\treplies/s\tfirst p50\tfirst p99\tdone p50\tdone p90\tdone p99\tdone max\tedits\tlimited\tpeak\t")
+	for _, ups := range rates {
+		fmt.Fprintf(os.Stderr, "Running with %g updates per second...\n", ups)
+		r, err := run(o, ups)
+		if err != nil {
+			tw.Flush()
+			fmt.Fprintf(os.Stderr, "Run failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(tw, "%g\t%d\t%d\t%v\t%.1f\t%v\t%v\t%v\t%v\t%v\t%v\t%d\t%d\t%d\t\n",
+			ups, len(r.done), r.failed, r.elapsed.Round(10*time.Millisecond),
+			float64(len(r.done))/r.elapsed.Seconds(),
+			percentile(r.first, 0.5), percentile(r.first, 0.99),
+			percentile(r.done, 0.5), percentile(r.done, 0.9), percentile(r.done, 0.99), percentile(r.done, 1),
+			r.edits, r.limited, r.peak)
+	}
+	tw.Flush()
+	fmt.Println("\nfirst: until the first message in the chat; done: until the complete reply shows")
+}
+
+// result is the outcome of one run
+type result struct {
+	first   []time.Duration
+	done    []time.Duration
+	failed  int
+	elapsed time.Duration
+	edits   int
+	limited int
+	peak    int32
+}
+
+// run starts a bridge with the given edit budget against a fresh fake
+// Feishu and plays the conversations through it
+func run(o options, ups float64) (*result, error) {
+	dir, err := os.MkdirTemp("", "bridge-loadtest")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	bridgeJSON, _ := json.Marshal(map[string]interface{}{
+		"feishu":  map[string]string{"app_id": "cli_loadtest", "app_secret": "loadtest"},
+		"backend": map[string]string{"type": "embedded"},
+		"streaming": map[string]interface{}{
+			"min_interval_ms":    o.minInterval.Milliseconds(),
+			"max_interval_ms":    o.maxInterval.Milliseconds(),
+			"updates_per_second": ups,
+		},
+	})
+	if err := os.WriteFile(filepath.Join(dir, "bridge.json"), bridgeJSON, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write bridge.json: %w", err)
+	}
+
+	server := testutil.NewFeishuServer()
+	defer server.Close()
+	server.SetLatency(o.feishuLatency)
+	server.SetUpdateLimit(o.updateLimit)
+	t := newTracker()
+	server.OnChange(t.observe)
+
+	b, err := bridge.New(bridge.Options{
+		ConfigDir:     dir,
+		Connectors:    map[string]connector.Connector{"default": &gateway{o: o}},
+		FeishuOptions: []feishu.Option{feishu.WithDomain(server.URL)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer b.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		b.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	select {
+	case <-server.Connected():
+	case <-time.After(10 * time.Second):
+		return nil, fmt.Errorf("bridge didn't connect to the fake Feishu server")
+	}
+
+	r := &result{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	start := time.Now()
+	for c := 0; c < o.conversations; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			chatID := fmt.Sprintf("oc_load_%d", c)
+			for turn := 0; turn < o.turns; turn++ {
+				first, done, err := t.converse(server, chatID, fmt.Sprintf("c%dt%d", c, turn), o.timeout)
+				mu.Lock()
+				if err != nil {
+					r.failed++
+				} else {
+					r.first = append(r.first, first)
+					r.done = append(r.done, done)
+				}
+				mu.Unlock()
+				if err != nil {
+					return
+				}
+			}
+		}(c)
+	}
+	wg.Wait()
+	r.elapsed = time.Since(start)
+
+	for _, m := range server.Messages() {
+		r.edits += m.Updates
+	}
+	r.limited = server.RateLimited()
+	r.peak = t.peak.Load()
+	return r, nil
+}
+
+// tracker follows the turn in progress in each chat through the messages
+// the bridge sends
+type tracker struct {
+	mu      sync.Mutex
+	turns   map[string]*turn
+	pending atomic.Int32
+	peak    atomic.Int32
+}
+
+// turn is one message waiting for its reply
+type turn struct {
+	marker string
+	sent   time.Time
+	first  time.Duration
+	done   chan time.Duration
+}
+
+func newTracker() *tracker {
+	return &tracker{turns: make(map[string]*turn)}
+}
+
+// converse sends text to chatID and waits for the complete reply,
+// returning the time to the first message and to the full reply
+func (t *tracker) converse(server *testutil.FeishuServer, chatID, text string, timeout time.Duration) (time.Duration, time.Duration, error) {
+	tr := &turn{marker: replyMarker(text), sent: time.Now(), done: make(chan time.Duration, 1)}
+	t.mu.Lock()
+	t.turns[chatID] = tr
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.turns, chatID)
+		t.mu.Unlock()
+	}()
+
+	n := t.pending.Add(1)
+	defer t.pending.Add(-1)
+	for peak := t.peak.Load(); n > peak && !t.peak.CompareAndSwap(peak, n); peak = t.peak.Load() {
+	}
+
+	if err := server.PushText(chatID, "p2p", "ou_load_"+chatID, text); err != nil {
+		return 0, 0, err
+	}
+	select {
+	case done := <-tr.done:
+		t.mu.Lock()
+		first := tr.first
+		t.mu.Unlock()
+		return first, done, nil
+	case <-time.After(timeout):
+		return 0, 0, fmt.Errorf("no reply to %s within %v", text, timeout)
+	}
+}
+
+// observe notes a message the bridge sent or edited
+func (t *tracker) observe(m testutil.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tr := t.turns[m.ChatID]
+	if tr == nil {
+		return
+	}
+	elapsed := time.Since(tr.sent)
+	if tr.first == 0 {
+		tr.first = elapsed
+	}
+	// Partial replies end with the streaming cursor
+	if !m.Deleted && strings.Contains(m.Text, tr.marker) && !strings.Contains(m.Text, "▍") {
+		select {
+		case tr.done <- elapsed:
+		default:
+		}
+	}
+}
+
+// replyMarker ends the reply to text, so the harness can tell it's whole
+func replyMarker(text string) string {
+	return "[end " + text + "]"
+}
+
+// gateway is the mock agent: it streams a reply of fixed length in
+// chunks, at the configured pace
+type gateway struct {
+	o options
+}
+
+func (g *gateway) Ask(ctx context.Context, text, sessionKey string, onProgress connector.ProgressFunc) (string, error) {
+	filler := strings.Repeat("load test reply ", g.o.replyChars/16+1)
+	reply := filler[:g.o.replyChars] + " " + replyMarker(text)
+
+	if err := sleep(ctx, g.o.firstToken); err != nil {
+		return "", err
+	}
+	chunk := g.o.chunkChars
+	if chunk <= 0 {
+		chunk = len(reply)
+	}
+	for i := 0; i < len(reply); i += chunk {
+		end := i + chunk
+		if end > len(reply) {
+			end = len(reply)
+		}
+		if onProgress != nil {
+			delta, _ := json.Marshal(map[string]string{"delta": reply[i:end]})
+			onProgress(connector.StreamAssistant, string(delta))
+		}
+		if err := sleep(ctx, g.o.chunkInterval); err != nil {
+			return "", err
+		}
+	}
+	return reply, nil
+}
+
+func (g *gateway) ResetSession(sessionKey string) error {
+	return nil
+}
+
+// sleep waits for d unless ctx ends first
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// percentile returns the q-th quantile of ds, rounded for display
+func percentile(ds []time.Duration, q float64) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(q*float64(len(sorted)-1) + 0.5)
+	return sorted[i].Round(time.Millisecond)
+}
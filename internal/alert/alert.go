@@ -0,0 +1,140 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/internal/store"
+)
+
+// Sender posts text to a Feishu chat
+type Sender func(chatID, text string) (string, error)
+
+// Alerter posts operational alerts to the configured alert chat and/or
+// webhook. Repeats of the same alert key are suppressed for the cooldown,
+// and the total number of alerts per clock hour is capped. Both limits
+// are kept in shared state so several instances don't repeat each other.
+type Alerter struct {
+	cfg        config.AlertConfig
+	send       Sender
+	shared     store.Shared
+	httpClient *http.Client
+
+	mu         sync.Mutex
+	suppressed map[string]int
+}
+
+// New returns an Alerter, or nil when no alert destination is configured.
+// A nil Alerter ignores Notify.
+func New(cfg config.AlertConfig, send Sender, shared store.Shared) *Alerter {
+	if !cfg.Enabled() {
+		return nil
+	}
+	return &Alerter{
+		cfg:        cfg,
+		send:       send,
+		shared:     shared,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		suppressed: make(map[string]int),
+	}
+}
+
+// Notify posts an alert unless the same key fired within the cooldown or
+// the hourly cap is reached. It blocks while sending; call it with go
+// from latency-sensitive paths.
+func (a *Alerter) Notify(key, text string) {
+	if a == nil {
+		return
+	}
+
+	text = redact.String(text)
+	msg, ok := a.admit(key, text, time.Now())
+	if !ok {
+		return
+	}
+	log.Printf("[Alert] %s: %s", key, text)
+
+	if a.cfg.ChatID != "" && a.send != nil {
+		if _, err := a.send(a.cfg.ChatID, msg); err != nil {
+			log.Printf("[Alert] Failed to post to alert chat: %v", err)
+		}
+	}
+	if a.cfg.Webhook != "" {
+		if err := a.postWebhook(msg); err != nil {
+			log.Printf("[Alert] Failed to post to webhook: %v", err)
+		}
+	}
+}
+
+// admit applies dedupe and rate limiting and returns the text to send
+func (a *Alerter) admit(key, text string, now time.Time) (string, bool) {
+	ctx := context.Background()
+	if a.cfg.Cooldown > 0 {
+		first, err := a.shared.Claim(ctx, "alert:"+key, a.cfg.Cooldown)
+		if err != nil {
+			// Without shared state, alerting loudly beats staying silent
+			log.Printf("[Alert] Failed to check cooldown: %v", err)
+		} else if !first {
+			a.suppress(key)
+			return "", false
+		}
+	}
+
+	if a.cfg.MaxPerHour > 0 {
+		hour := now.Format("2006010215")
+		n, err := a.shared.Incr(ctx, "alert_count:"+hour, time.Hour)
+		if err != nil {
+			log.Printf("[Alert] Failed to check hourly cap: %v", err)
+		} else if n > int64(a.cfg.MaxPerHour) {
+			a.suppress(key)
+			return "", false
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	msg := "【ClawdBot Bridge 告警】" + text
+	if n := a.suppressed[key]; n > 0 {
+		msg += fmt.Sprintf("\n（此前 %d 次同类告警已合并）", n)
+		delete(a.suppressed, key)
+	}
+	return msg, true
+}
+
+// suppress counts an alert merged into the next one of the same key
+func (a *Alerter) suppress(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.suppressed[key]++
+}
+
+// postWebhook sends text to a Feishu custom-bot webhook
+func (a *Alerter) postWebhook(text string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": text},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook body: %w", err)
+	}
+
+	resp, err := a.httpClient.Post(a.cfg.Webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
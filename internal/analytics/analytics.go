@@ -0,0 +1,212 @@
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/transcript"
+)
+
+// ChatStats aggregates one chat's messages
+type ChatStats struct {
+	ChatID       string
+	Messages     int
+	Users        int
+	Errors       int
+	InputTokens  int
+	OutputTokens int
+	AvgLatencyMs int64
+	// Cost is estimated from configured prices; only scheduled reports
+	// fill it in
+	Cost float64
+}
+
+// Count is a name with an occurrence count
+type Count struct {
+	Name  string
+	Count int
+}
+
+// Report summarizes usage over a period
+type Report struct {
+	Since, Until time.Time
+	Messages     int
+	Commands     int
+	Errors       int
+	InputTokens  int
+	OutputTokens int
+	AvgLatencyMs int64
+	P95LatencyMs int64
+	Cost         float64
+	Chats        []ChatStats
+	Users        []Count
+	TopCommands  []Count
+	// Tags counts messages by conversation tag, when classification is on
+	Tags []Count
+	// Surveys counts satisfaction survey answers, Helpful the positive ones
+	Surveys, Helpful int
+}
+
+// Build aggregates the transcript records in [since, until)
+func Build(st *transcript.Store, since, until time.Time) (*Report, error) {
+	r := &Report{Since: since, Until: until}
+
+	chats := make(map[string]*ChatStats)
+	chatUsers := make(map[string]map[string]bool)
+	chatLatency := make(map[string]int64)
+	users := make(map[string]int)
+	commands := make(map[string]int)
+	tags := make(map[string]int)
+	var latencies []int64
+
+	err := st.Query(since, until, func(rec transcript.Record) bool {
+		switch rec.Kind {
+		case transcript.KindCommand:
+			r.Commands++
+			commands["/"+rec.Command]++
+			return true
+		case transcript.KindSurvey:
+			r.Surveys++
+			if rec.Helpful {
+				r.Helpful++
+			}
+			return true
+		}
+
+		c, ok := chats[rec.ChatID]
+		if !ok {
+			c = &ChatStats{ChatID: rec.ChatID}
+			chats[rec.ChatID] = c
+			chatUsers[rec.ChatID] = make(map[string]bool)
+		}
+		c.Messages++
+		c.InputTokens += rec.InputTokens
+		c.OutputTokens += rec.OutputTokens
+		chatLatency[rec.ChatID] += rec.LatencyMs
+		if rec.UserID != "" {
+			chatUsers[rec.ChatID][rec.UserID] = true
+			users[rec.UserID]++
+		}
+		if rec.Error != "" {
+			c.Errors++
+			r.Errors++
+		}
+		if rec.Tag != "" {
+			tags[rec.Tag]++
+		}
+
+		r.Messages++
+		r.InputTokens += rec.InputTokens
+		r.OutputTokens += rec.OutputTokens
+		latencies = append(latencies, rec.LatencyMs)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for id, c := range chats {
+		c.Users = len(chatUsers[id])
+		c.AvgLatencyMs = chatLatency[id] / int64(c.Messages)
+		r.Chats = append(r.Chats, *c)
+	}
+	sort.Slice(r.Chats, func(i, j int) bool { return r.Chats[i].Messages > r.Chats[j].Messages })
+
+	r.Users = sortedCounts(users)
+	r.TopCommands = sortedCounts(commands)
+	r.Tags = sortedCounts(tags)
+
+	if len(latencies) > 0 {
+		var total int64
+		for _, l := range latencies {
+			total += l
+		}
+		r.AvgLatencyMs = total / int64(len(latencies))
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		r.P95LatencyMs = latencies[(len(latencies)*95-1)/100]
+	}
+	return r, nil
+}
+
+// SatisfactionRate is the share of survey answers that found the replies
+// helpful, in percent
+func (r *Report) SatisfactionRate() float64 {
+	if r.Surveys == 0 {
+		return 0
+	}
+	return float64(r.Helpful) * 100 / float64(r.Surveys)
+}
+
+// TagsText lists the message counts by tag, e.g. "howto 12 条，incident 3 条"
+func (r *Report) TagsText() string {
+	parts := make([]string, len(r.Tags))
+	for i, t := range r.Tags {
+		parts[i] = fmt.Sprintf("%s %d 条", t.Name, t.Count)
+	}
+	return strings.Join(parts, "，")
+}
+
+// sortedCounts orders a count map by count, then name
+func sortedCounts(m map[string]int) []Count {
+	counts := make([]Count, 0, len(m))
+	for name, n := range m {
+		counts = append(counts, Count{Name: name, Count: n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	return counts
+}
+
+// Markdown renders the report for a Feishu card or a terminal
+func (r *Report) Markdown() string {
+	const top = 10
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**统计周期**：%s ~ %s\n", r.Since.Format("2006-01-02 15:04"), r.Until.Format("2006-01-02 15:04"))
+	fmt.Fprintf(&sb, "**消息**：%d 条（失败 %d），命令 %d 次，活跃用户 %d 人，活跃会话 %d 个\n",
+		r.Messages, r.Errors, r.Commands, len(r.Users), len(r.Chats))
+	fmt.Fprintf(&sb, "**耗时**：平均 %.1fs，P95 %.1fs\n", float64(r.AvgLatencyMs)/1000, float64(r.P95LatencyMs)/1000)
+	fmt.Fprintf(&sb, "**Token**：输入 %d，输出 %d\n", r.InputTokens, r.OutputTokens)
+	if len(r.Tags) > 0 {
+		fmt.Fprintf(&sb, "**对话类型**：%s\n", r.TagsText())
+	}
+	if r.Surveys > 0 {
+		fmt.Fprintf(&sb, "**满意度**：%.0f%% 认为有帮助（%d 份反馈）\n", r.SatisfactionRate(), r.Surveys)
+	}
+
+	if len(r.Chats) > 0 {
+		sb.WriteString("\n**会话排行**\n")
+		for i, c := range r.Chats {
+			if i == top {
+				break
+			}
+			fmt.Fprintf(&sb, "%d. %s：%d 条，%d 人，平均 %.1fs，Token %d/%d\n",
+				i+1, c.ChatID, c.Messages, c.Users, float64(c.AvgLatencyMs)/1000, c.InputTokens, c.OutputTokens)
+		}
+	}
+	if len(r.Users) > 0 {
+		sb.WriteString("\n**用户排行**\n")
+		for i, u := range r.Users {
+			if i == top {
+				break
+			}
+			fmt.Fprintf(&sb, "%d. %s：%d 条\n", i+1, u.Name, u.Count)
+		}
+	}
+	if len(r.TopCommands) > 0 {
+		sb.WriteString("\n**常用命令**\n")
+		for i, c := range r.TopCommands {
+			if i == top {
+				break
+			}
+			fmt.Fprintf(&sb, "%d. %s：%d 次\n", i+1, c.Name, c.Count)
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,138 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/transcript"
+)
+
+// PeriodReport is a scheduled usage report: one period's usage and cost,
+// compared with the period before it
+type PeriodReport struct {
+	Current  *Report
+	Previous *Report
+}
+
+// Period builds the report for [since, until), with prev as the start of
+// the period before it. Costs are estimated from prices.
+func Period(st *transcript.Store, prev, since, until time.Time, prices map[string]config.Price) (*PeriodReport, error) {
+	cur, err := buildPriced(st, since, until, prices)
+	if err != nil {
+		return nil, err
+	}
+	before, err := buildPriced(st, prev, since, prices)
+	if err != nil {
+		return nil, err
+	}
+	return &PeriodReport{Current: cur, Previous: before}, nil
+}
+
+// buildPriced is Build with each chat's estimated cost filled in
+func buildPriced(st *transcript.Store, since, until time.Time, prices map[string]config.Price) (*Report, error) {
+	r, err := Build(st, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	costs := make(map[string]float64)
+	err = st.Query(since, until, func(rec transcript.Record) bool {
+		if rec.Kind != transcript.KindMessage {
+			return true
+		}
+		if p, ok := prices[rec.Backend]; ok {
+			costs[rec.ChatID] += float64(rec.InputTokens)/1e6*p.InputPerMTok + float64(rec.OutputTokens)/1e6*p.OutputPerMTok
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range r.Chats {
+		r.Chats[i].Cost = costs[r.Chats[i].ChatID]
+		r.Cost += r.Chats[i].Cost
+	}
+	return r, nil
+}
+
+// ErrorRate is the share of failed messages, in percent
+func (r *Report) ErrorRate() float64 {
+	if r.Messages == 0 {
+		return 0
+	}
+	return float64(r.Errors) * 100 / float64(r.Messages)
+}
+
+// Markdown renders the report for a Feishu card
+func (p *PeriodReport) Markdown() string {
+	const top = 10
+	cur, prev := p.Current, p.Previous
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**统计周期**：%s ~ %s\n", cur.Since.Format("2006-01-02 15:04"), cur.Until.Format("2006-01-02 15:04"))
+	fmt.Fprintf(&sb, "**消息**：%d 条%s，活跃用户 %d 人，活跃会话 %d 个\n",
+		cur.Messages, change(float64(cur.Messages), float64(prev.Messages)), len(cur.Users), len(cur.Chats))
+	fmt.Fprintf(&sb, "**估算费用**：$%.4f%s\n", cur.Cost, change(cur.Cost, prev.Cost))
+	fmt.Fprintf(&sb, "**Token**：输入 %d，输出 %d\n", cur.InputTokens, cur.OutputTokens)
+	if len(cur.Tags) > 0 {
+		fmt.Fprintf(&sb, "**对话类型**：%s\n", cur.TagsText())
+	}
+	if cur.Surveys > 0 || prev.Surveys > 0 {
+		fmt.Fprintf(&sb, "**满意度**：%.0f%%（%d 份反馈，上期 %.0f%%）\n", cur.SatisfactionRate(), cur.Surveys, prev.SatisfactionRate())
+	}
+	fmt.Fprintf(&sb, "**错误率**：%.1f%%（%d 条，上期 %.1f%%）\n", cur.ErrorRate(), cur.Errors, prev.ErrorRate())
+	fmt.Fprintf(&sb, "**耗时**：平均 %.1fs（上期 %.1fs），P95 %.1fs（上期 %.1fs）\n",
+		float64(cur.AvgLatencyMs)/1000, float64(prev.AvgLatencyMs)/1000,
+		float64(cur.P95LatencyMs)/1000, float64(prev.P95LatencyMs)/1000)
+
+	if len(cur.Chats) > 0 {
+		sb.WriteString("\n**会话排行**\n")
+		for i, c := range cur.Chats {
+			if i == top {
+				break
+			}
+			fmt.Fprintf(&sb, "%d. %s：%d 条，失败 %d，平均 %.1fs，$%.4f\n",
+				i+1, c.ChatID, c.Messages, c.Errors, float64(c.AvgLatencyMs)/1000, c.Cost)
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// change describes the change from prev to cur, e.g. "（较上期 +12%）"
+func change(cur, prev float64) string {
+	if prev == 0 {
+		if cur == 0 {
+			return ""
+		}
+		return "（上期无数据）"
+	}
+	return fmt.Sprintf("（较上期 %+.0f%%）", (cur-prev)*100/prev)
+}
+
+// CSV renders the period's per-chat statistics, one row per chat
+func (p *PeriodReport) CSV() []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"chat_id", "messages", "users", "errors", "error_rate_percent",
+		"input_tokens", "output_tokens", "cost_usd", "avg_latency_ms"})
+	for _, c := range p.Current.Chats {
+		w.Write([]string{
+			c.ChatID,
+			strconv.Itoa(c.Messages),
+			strconv.Itoa(c.Users),
+			strconv.Itoa(c.Errors),
+			strconv.FormatFloat(float64(c.Errors)*100/float64(c.Messages), 'f', 1, 64),
+			strconv.Itoa(c.InputTokens),
+			strconv.Itoa(c.OutputTokens),
+			strconv.FormatFloat(c.Cost, 'f', 4, 64),
+			strconv.FormatInt(c.AvgLatencyMs, 10),
+		})
+	}
+	w.Flush()
+	return buf.Bytes()
+}
@@ -0,0 +1,90 @@
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/transcript"
+)
+
+// BackendUsage is the token usage of one backend
+type BackendUsage struct {
+	Backend      string
+	Messages     int
+	InputTokens  int
+	OutputTokens int
+	// Cost is estimated from configured prices; Priced is false when the
+	// backend has no price
+	Cost   float64
+	Priced bool
+}
+
+// UsageSummary totals messages, tokens and cost over a period
+type UsageSummary struct {
+	Since, Until time.Time
+	Messages     int
+	InputTokens  int
+	OutputTokens int
+	Cost         float64
+	Backends     []BackendUsage
+}
+
+// Usage totals the message records in [since, until). An empty chatID
+// covers all chats.
+func Usage(st *transcript.Store, since, until time.Time, chatID string, prices map[string]config.Price) (*UsageSummary, error) {
+	u := &UsageSummary{Since: since, Until: until}
+	backends := make(map[string]*BackendUsage)
+
+	err := st.Query(since, until, func(rec transcript.Record) bool {
+		if rec.Kind != transcript.KindMessage || (chatID != "" && rec.ChatID != chatID) {
+			return true
+		}
+		b, ok := backends[rec.Backend]
+		if !ok {
+			b = &BackendUsage{Backend: rec.Backend}
+			backends[rec.Backend] = b
+		}
+		b.Messages++
+		b.InputTokens += rec.InputTokens
+		b.OutputTokens += rec.OutputTokens
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range backends {
+		if p, ok := prices[b.Backend]; ok {
+			b.Priced = true
+			b.Cost = float64(b.InputTokens)/1e6*p.InputPerMTok + float64(b.OutputTokens)/1e6*p.OutputPerMTok
+		}
+		u.Messages += b.Messages
+		u.InputTokens += b.InputTokens
+		u.OutputTokens += b.OutputTokens
+		u.Cost += b.Cost
+		u.Backends = append(u.Backends, *b)
+	}
+	sort.Slice(u.Backends, func(i, j int) bool { return u.Backends[i].Messages > u.Backends[j].Messages })
+	return u, nil
+}
+
+// Text renders the summary as a chat reply
+func (u *UsageSummary) Text() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "消息 %d 条，Token 输入 %d / 输出 %d", u.Messages, u.InputTokens, u.OutputTokens)
+	if u.Cost > 0 {
+		fmt.Fprintf(&sb, "，估算费用 $%.4f", u.Cost)
+	}
+	if len(u.Backends) > 1 {
+		for _, b := range u.Backends {
+			fmt.Fprintf(&sb, "\n- %s：%d 条，%d / %d", b.Backend, b.Messages, b.InputTokens, b.OutputTokens)
+			if b.Priced {
+				fmt.Fprintf(&sb, "，$%.4f", b.Cost)
+			}
+		}
+	}
+	return sb.String()
+}
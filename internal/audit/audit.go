@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+)
+
+// Outcomes recorded on events
+const (
+	Allowed = "allowed"
+	Denied  = "denied"
+	// Pending marks an action waiting for a second admin's confirmation
+	Pending = "pending"
+)
+
+// Event is one line of the audit log
+type Event struct {
+	Time time.Time `json:"time"`
+	// Action names what was attempted, e.g. "backend.switch"
+	Action string `json:"action"`
+	// Actor is the open_id of the user who attempted it
+	Actor   string `json:"actor,omitempty"`
+	ChatID  string `json:"chat_id,omitempty"`
+	Target  string `json:"target,omitempty"`
+	Outcome string `json:"outcome"`
+	Detail  string `json:"detail,omitempty"`
+	// CorrelationID links the event to the debug log (cid=...)
+	CorrelationID string `json:"cid,omitempty"`
+}
+
+// Log appends security-relevant events to daily JSONL files, kept apart
+// from the debug log. Files are only ever appended to; whole days are
+// removed by the retention purger once they fall outside the window.
+type Log struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// Open prepares dir for audit files
+func Open(dir string) (*Log, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit dir: %w", err)
+	}
+	return &Log{dir: dir}, nil
+}
+
+// Record appends ev, filling in the time and correlation ID.
+// Failures are logged rather than returned so auditing never blocks a command.
+func (l *Log) Record(ctx context.Context, ev Event) {
+	if l == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	if ev.CorrelationID == "" {
+		ev.CorrelationID = logging.CorrelationID(ctx)
+	}
+	if ev.Outcome == "" {
+		ev.Outcome = Allowed
+	}
+
+	line, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("[Audit] Failed to encode event: %v", err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	day := ev.Time.Format("2006-01-02")
+	path := filepath.Join(l.dir, "audit-"+day+".jsonl")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Printf("[Audit] Failed to open %s: %v", path, err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Printf("[Audit] Failed to write event: %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/sse"
+)
+
+// requestTimeout bounds a single streamed completion, matching the gateway client
+const requestTimeout = 15 * time.Minute
+
+// AnthropicClient talks to the Anthropic Messages API directly
+type AnthropicClient struct {
+	baseURL      string
+	apiKey       string
+	systemPrompt string
+	maxTokens    int
+	httpClient   *http.Client
+	history      *history
+	models       *modelOverrides
+}
+
+// NewAnthropicClient creates a new Anthropic Messages API client
+func NewAnthropicClient(baseURL, apiKey, model, systemPrompt string, maxTokens int) *AnthropicClient {
+	return &AnthropicClient{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		apiKey:       apiKey,
+		systemPrompt: systemPrompt,
+		maxTokens:    maxTokens,
+		httpClient:   &http.Client{},
+		history:      newHistory(),
+		models:       newModelOverrides(model),
+	}
+}
+
+// anthropicRequest is the body of POST /v1/messages
+type anthropicRequest struct {
+	Model     string        `json:"model"`
+	MaxTokens int           `json:"max_tokens"`
+	System    string        `json:"system,omitempty"`
+	Messages  []chatMessage `json:"messages"`
+	Stream    bool          `json:"stream"`
+}
+
+// anthropicEvent covers the fields we use from streamed events
+type anthropicEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage Usage `json:"usage"`
+	} `json:"message"`
+	Usage        Usage `json:"usage"`
+	ContentBlock struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	} `json:"content_block"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		Thinking   string `json:"thinking"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Ask sends text to the Messages API and streams the reply
+func (c *AnthropicClient) Ask(ctx context.Context, text, sessionKey string, onProgress ProgressFunc) (string, error) {
+	body, err := json.Marshal(anthropicRequest{
+		Model:     c.models.get(sessionKey),
+		MaxTokens: c.maxTokens,
+		System:    c.systemPrompt,
+		Messages:  c.history.with(sessionKey, text),
+		Stream:    true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	stream, err := sse.Do(c.httpClient, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call anthropic api: %w", err)
+	}
+	defer stream.Close()
+
+	var reply strings.Builder
+	var usage Usage
+	for {
+		ev, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read anthropic stream: %w", err)
+		}
+
+		done, err := c.handleEvent(ctx, ev.Data, &reply, &usage, onProgress)
+		if err != nil {
+			return "", err
+		}
+		if done {
+			break
+		}
+	}
+	emit(onProgress, StreamUsage, usage)
+
+	c.history.append(sessionKey, text, reply.String())
+	return reply.String(), nil
+}
+
+// handleEvent maps one streamed Messages API event onto the bridge streams.
+// done is true once the message is complete.
+func (c *AnthropicClient) handleEvent(ctx context.Context, data string, reply *strings.Builder, usage *Usage, onProgress ProgressFunc) (done bool, err error) {
+	var ev anthropicEvent
+	if err := json.Unmarshal([]byte(data), &ev); err != nil {
+		logging.Printf(ctx, "[Anthropic] Failed to parse event: %v", err)
+		return false, nil
+	}
+
+	switch ev.Type {
+	case "message_start":
+		usage.InputTokens = ev.Message.Usage.InputTokens
+	case "message_delta":
+		if ev.Usage.OutputTokens > 0 {
+			usage.OutputTokens = ev.Usage.OutputTokens
+		}
+		if ev.Delta.StopReason == "refusal" && reply.Len() == 0 {
+			return false, ErrContentBlocked
+		}
+	case "content_block_start":
+		if ev.ContentBlock.Type == "tool_use" || ev.ContentBlock.Type == "server_tool_use" {
+			emit(onProgress, StreamToolCall, map[string]string{"name": ev.ContentBlock.Name})
+		}
+	case "content_block_delta":
+		switch ev.Delta.Type {
+		case "text_delta":
+			reply.WriteString(ev.Delta.Text)
+			emit(onProgress, StreamAssistant, map[string]string{"delta": ev.Delta.Text})
+		case "thinking_delta":
+			emit(onProgress, StreamThought, map[string]string{"delta": ev.Delta.Thinking})
+		}
+	case "message_stop":
+		return true, nil
+	case "error":
+		if ev.Error != nil {
+			return false, fmt.Errorf("anthropic api error: %s: %s", ev.Error.Type, ev.Error.Message)
+		}
+		return false, fmt.Errorf("anthropic api error")
+	}
+	return false, nil
+}
+
+// Model returns the model used for a session
+func (c *AnthropicClient) Model(sessionKey string) string {
+	return c.models.get(sessionKey)
+}
+
+// SetModel selects the model for a session; empty restores the default
+func (c *AnthropicClient) SetModel(sessionKey, model string) {
+	c.models.set(sessionKey, model)
+}
+
+// ResetSession clears the in-memory history of a session
+func (c *AnthropicClient) ResetSession(sessionKey string) error {
+	c.history.reset(sessionKey)
+	return nil
+}
+
+// emit encodes data and forwards it to onProgress if set
+func emit(onProgress ProgressFunc, stream string, data interface{}) {
+	if onProgress == nil {
+		return
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	onProgress(stream, string(b))
+}
+
+// SessionState returns a session's model override and history
+func (c *AnthropicClient) SessionState(sessionKey string) SessionState {
+	return SessionState{Model: c.models.override(sessionKey), History: c.history.snapshot(sessionKey)}
+}
+
+// RestoreSession replaces a session's model override and history
+func (c *AnthropicClient) RestoreSession(sessionKey string, state SessionState) {
+	c.models.set(sessionKey, state.Model)
+	c.history.restore(sessionKey, state.History)
+}
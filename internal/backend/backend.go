@@ -0,0 +1,261 @@
+package backend
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/netdial"
+	"github.com/wy51ai/moltbotCNAPP/internal/seal"
+	"github.com/wy51ai/moltbotCNAPP/internal/sshtunnel"
+	"github.com/wy51ai/moltbotCNAPP/pkg/clawdbot"
+	"github.com/wy51ai/moltbotCNAPP/pkg/connector"
+)
+
+// Stream names passed to ProgressFunc; see the connector package
+const (
+	StreamAssistant  = connector.StreamAssistant
+	StreamThought    = connector.StreamThought
+	StreamToolCall   = connector.StreamToolCall
+	StreamToolResult = connector.StreamToolResult
+	StreamUsage      = connector.StreamUsage
+	StreamRun        = connector.StreamRun
+)
+
+// ErrGatewayUnreachable is wrapped by errors from clawdbot backends when
+// the gateway can't be reached at all
+var ErrGatewayUnreachable = clawdbot.ErrUnreachable
+
+// Usage is the token accounting reported on the usage stream
+type Usage = connector.Usage
+
+// ProgressFunc receives streaming updates during a run
+type ProgressFunc = connector.ProgressFunc
+
+// Backend answers chat messages on behalf of the bridge. It is the
+// public connector interface, so embedded connectors route like any other.
+type Backend = connector.Connector
+
+// AgentAsker is implemented by backends hosting several agents
+// (e.g. the ClawdBot gateway) that can be addressed per message
+type AgentAsker interface {
+	AskAgent(ctx context.Context, agentID, text, sessionKey string, onProgress ProgressFunc) (string, error)
+}
+
+// AgentInfo describes an agent available on a backend
+type AgentInfo struct {
+	ID          string
+	Name        string
+	Description string
+	Tools       []string
+}
+
+// AgentLister is implemented by backends that can discover their agents
+type AgentLister interface {
+	ListAgents() ([]AgentInfo, error)
+}
+
+// GatewayUpdater is implemented by backends connected to the ClawdBot
+// gateway so rotated ports and tokens can be applied without a restart
+type GatewayUpdater interface {
+	UpdateGateway(port int, token string)
+}
+
+// WithAgent returns a Backend that addresses agentID on b.
+// Backends that don't host multiple agents are returned unchanged.
+func WithAgent(b Backend, agentID string) Backend {
+	asker, ok := b.(AgentAsker)
+	if !ok || agentID == "" {
+		return b
+	}
+	return &agentBackend{Backend: b, asker: asker, agentID: agentID}
+}
+
+// tunnels holds the SSH tunnels to gateways by settings, so backends
+// reaching the same gateway share one SSH connection
+var (
+	tunnelsMu sync.Mutex
+	tunnels   = make(map[config.SSHTunnelConfig]*sshtunnel.Tunnel)
+)
+
+// gatewayOptions returns the client options for reaching the gateway
+// described by gw, dialing with dial if set
+func gatewayOptions(gw config.ClawdbotConfig, dial netdial.DialFunc) ([]clawdbot.Option, error) {
+	opts := []clawdbot.Option{clawdbot.WithDeliver(gw.Deliver)}
+	if gw.Transport == "grpc" {
+		opts = append(opts, clawdbot.WithGRPC(gw.GRPCAddr, gw.GRPCTLS))
+	}
+	tlsConfig, err := gw.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if gw.TLS && tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if tlsConfig != nil {
+		opts = append(opts, clawdbot.WithTLS(tlsConfig))
+	}
+	if gw.Host != "" {
+		opts = append(opts, clawdbot.WithHost(gw.Host))
+	}
+	if proxy := gw.ProxyFunc(); proxy != nil {
+		opts = append(opts, clawdbot.WithProxy(proxy))
+	}
+	if gw.SSH.Addr == "" {
+		if dial != nil {
+			opts = append(opts, clawdbot.WithNetDial(dial))
+		}
+		return opts, nil
+	}
+
+	tunnelsMu.Lock()
+	defer tunnelsMu.Unlock()
+	t, ok := tunnels[gw.SSH]
+	if !ok {
+		var err error
+		if t, err = sshtunnel.New(gw.SSH.Addr, gw.SSH.User, gw.SSH.KeyFile, gw.SSH.KnownHosts, dial); err != nil {
+			return nil, err
+		}
+		tunnels[gw.SSH] = t
+	}
+	return append(opts, clawdbot.WithNetDial(t.DialContext)), nil
+}
+
+// agentBackend pins Ask calls to one agent
+type agentBackend struct {
+	Backend
+	asker   AgentAsker
+	agentID string
+}
+
+func (a *agentBackend) Ask(ctx context.Context, text, sessionKey string, onProgress ProgressFunc) (string, error) {
+	return a.asker.AskAgent(ctx, a.agentID, text, sessionKey, onProgress)
+}
+
+// New creates the backend described by b.
+// gw supplies the gateway settings for "clawdbot" backends, and dial, if
+// not nil, opens their connections. keys, if not nil, seals recordings
+// and opens sealed ones for "replay".
+func New(b config.BackendConfig, gw config.ClawdbotConfig, dial netdial.DialFunc, keys *seal.Keyring) (Backend, error) {
+	switch b.Type {
+	case "", "clawdbot":
+		agentID := gw.AgentID
+		if b.AgentID != "" {
+			agentID = b.AgentID
+		}
+		opts, err := gatewayOptions(gw, dial)
+		if err != nil {
+			return nil, err
+		}
+		g := &gatewayBackend{client: clawdbot.NewClient(
+			gw.GatewayPort,
+			gw.GatewayToken,
+			agentID,
+			opts...,
+		)}
+		if gw.RecordDir != "" {
+			g.recorder = newRecorder(gw.RecordDir, keys)
+		}
+		return g, nil
+	case "anthropic":
+		return NewAnthropicClient(b.BaseURL, b.APIKey, b.Model, b.SystemPrompt, b.MaxTokens), nil
+	case "openai":
+		return NewOpenAIClient(b.BaseURL, b.APIKey, b.Model, b.SystemPrompt, b.MaxTokens), nil
+	case "ollama":
+		return NewOllamaClient(b.BaseURL, b.Model, b.SystemPrompt), nil
+	case "sse":
+		return NewSSEGatewayClient(b.BaseURL, b.APIKey, b.Model), nil
+	case "replay":
+		return NewReplayClient(b.Recordings, 1, keys)
+	case "embedded":
+		return nil, fmt.Errorf("no connector was supplied for this embedded backend")
+	default:
+		return nil, fmt.Errorf("unknown backend type: %s", b.Type)
+	}
+}
+
+// gatewayBackend adapts clawdbot.Client to the Backend interface
+type gatewayBackend struct {
+	client  *clawdbot.Client
+	breaker gatewayBreaker
+	// recorder, when set, saves every run for replay
+	recorder *recorder
+}
+
+func (g *gatewayBackend) Ask(ctx context.Context, text, sessionKey string, onProgress ProgressFunc) (string, error) {
+	return g.AskAgent(ctx, "", text, sessionKey, onProgress)
+}
+
+// AskAgent runs through the breaker; an empty agentID addresses the
+// client's default agent
+func (g *gatewayBackend) AskAgent(ctx context.Context, agentID, text, sessionKey string, onProgress ProgressFunc) (string, error) {
+	if err := g.breaker.allow(g.client.Ping); err != nil {
+		return "", err
+	}
+
+	var run *recordingRun
+	if g.recorder != nil {
+		run, onProgress = g.recorder.start(agentID, text, sessionKey, onProgress)
+	}
+
+	var reply string
+	var err error
+	if agentID == "" {
+		reply, err = g.client.AskClawdbot(ctx, text, sessionKey, onProgress)
+	} else {
+		reply, err = g.client.AskAgent(ctx, agentID, text, sessionKey, onProgress)
+	}
+	g.breaker.record(err)
+	if run != nil {
+		run.finish(reply, err)
+	}
+	return reply, err
+}
+
+func (g *gatewayBackend) Ping() error {
+	return g.client.Ping()
+}
+
+// CircuitOpen reports whether the breaker is refusing runs
+func (g *gatewayBackend) CircuitOpen() bool {
+	return g.breaker.isOpen()
+}
+
+func (g *gatewayBackend) ListAgents() ([]AgentInfo, error) {
+	agents, err := g.client.ListAgents()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]AgentInfo, 0, len(agents))
+	for _, a := range agents {
+		info := AgentInfo{ID: a.ID, Name: a.Name, Description: a.Description}
+		for _, t := range a.Tools {
+			info.Tools = append(info.Tools, string(t))
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (g *gatewayBackend) UpdateGateway(port int, token string) {
+	g.client.UpdateSettings(port, token)
+}
+
+func (g *gatewayBackend) ResetSession(sessionKey string) error {
+	return g.client.ResetSession(sessionKey)
+}
+
+func (g *gatewayBackend) ForkSession(sessionKey, newKey string) error {
+	return g.client.ForkSession(sessionKey, newKey)
+}
+
+func (g *gatewayBackend) CompactSession(sessionKey string) (CompactStats, error) {
+	result, err := g.client.CompactSession(sessionKey)
+	if err != nil {
+		return CompactStats{}, err
+	}
+	return CompactStats{TokensBefore: result.TokensBefore, TokensAfter: result.TokensAfter}, nil
+}
@@ -0,0 +1,97 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// gatewayBreakerThreshold consecutive unreachable errors open the circuit
+	gatewayBreakerThreshold = 3
+	// gatewayBreakerCooldown is how long the circuit stays open before the
+	// gateway is pinged again
+	gatewayBreakerCooldown = 15 * time.Second
+)
+
+// ErrGatewayUnavailable is returned without contacting the gateway while
+// it's considered down
+var ErrGatewayUnavailable = errors.New("gateway unavailable (circuit open)")
+
+// Pinger is implemented by backends that can cheaply check they're reachable
+type Pinger interface {
+	Ping() error
+}
+
+// CircuitReporter is implemented by backends guarded by a breaker
+type CircuitReporter interface {
+	CircuitOpen() bool
+}
+
+// gatewayBreaker stops runs on a gateway backend after repeated connection
+// failures. Once the cooldown has passed the next run pings the gateway
+// first and goes ahead only if it answers.
+type gatewayBreaker struct {
+	mu       sync.Mutex
+	open     bool
+	since    time.Time
+	failures int
+	probing  bool
+}
+
+// allow returns nil if a run may go ahead, pinging the gateway when an
+// open circuit's cooldown has passed
+func (b *gatewayBreaker) allow(ping func() error) error {
+	b.mu.Lock()
+	if !b.open {
+		b.mu.Unlock()
+		return nil
+	}
+	if b.probing || time.Since(b.since) < gatewayBreakerCooldown {
+		b.mu.Unlock()
+		return ErrGatewayUnavailable
+	}
+	b.probing = true
+	b.mu.Unlock()
+
+	err := ping()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if err != nil {
+		b.since = time.Now()
+		return fmt.Errorf("%w: %w", ErrGatewayUnavailable, err)
+	}
+	b.open = false
+	b.failures = 0
+	b.since = time.Now()
+	log.Println("[Backend] Gateway reachable again, circuit closed")
+	return nil
+}
+
+// record updates the breaker with the outcome of an allowed run
+func (b *gatewayBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !errors.Is(err, ErrGatewayUnreachable) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if !b.open && b.failures >= gatewayBreakerThreshold {
+		b.open = true
+		b.since = time.Now()
+		log.Printf("[Backend] Gateway unreachable %d times in a row, circuit open: %v", b.failures, err)
+	}
+}
+
+// isOpen reports whether runs are currently being refused
+func (b *gatewayBreaker) isOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
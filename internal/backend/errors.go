@@ -0,0 +1,95 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/sse"
+)
+
+// ErrContentBlocked is returned when the model refuses to answer or its
+// answer is withheld by a content filter
+var ErrContentBlocked = errors.New("reply blocked by content filter")
+
+// ErrorKind classifies a failed run by what the user can do about it
+type ErrorKind string
+
+const (
+	ErrorUnreachable    ErrorKind = "unreachable"
+	ErrorAuth           ErrorKind = "auth"
+	ErrorTimeout        ErrorKind = "timeout"
+	ErrorRateLimited    ErrorKind = "rate_limited"
+	ErrorContentBlocked ErrorKind = "content_blocked"
+	ErrorInternal       ErrorKind = "internal"
+)
+
+// errorKeywords classify errors that carry nothing but a message, such as
+// the ones reported inside a stream or by the gateway protocol. They're
+// matched in order against the lowercased message.
+var errorKeywords = []struct {
+	kind  ErrorKind
+	words []string
+}{
+	{ErrorContentBlocked, []string{"content_filter", "content filter", "content policy", "content_policy", "safety", "sensitive", "refusal"}},
+	{ErrorRateLimited, []string{"rate_limit", "rate limit", "too many requests", "quota", "overloaded"}},
+	{ErrorAuth, []string{"authentication", "unauthorized", "invalid api key", "invalid x-api-key", "invalid_api_key", "permission_error", "forbidden", "auth failed"}},
+	{ErrorTimeout, []string{"timeout", "timed out", "deadline exceeded"}},
+	{ErrorUnreachable, []string{"connection refused", "no such host", "connection reset", "bad gateway", "service unavailable"}},
+}
+
+// Classify tells what kind of failure err is; errors it can't place are
+// ErrorInternal
+func Classify(err error) ErrorKind {
+	if err == nil {
+		return ""
+	}
+	switch {
+	case errors.Is(err, ErrContentBlocked):
+		return ErrorContentBlocked
+	case errors.Is(err, ErrGatewayUnreachable), errors.Is(err, ErrGatewayUnavailable):
+		return ErrorUnreachable
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorTimeout
+	}
+
+	var status *sse.StatusError
+	if errors.As(err, &status) {
+		if kind, ok := statusKinds[status.StatusCode]; ok {
+			return kind
+		}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorTimeout
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return ErrorUnreachable
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, k := range errorKeywords {
+		for _, w := range k.words {
+			if strings.Contains(msg, w) {
+				return k.kind
+			}
+		}
+	}
+	return ErrorInternal
+}
+
+// statusKinds classify HTTP statuses returned by model APIs and gateways
+var statusKinds = map[int]ErrorKind{
+	http.StatusUnauthorized:       ErrorAuth,
+	http.StatusForbidden:          ErrorAuth,
+	http.StatusRequestTimeout:     ErrorTimeout,
+	http.StatusTooManyRequests:    ErrorRateLimited,
+	http.StatusBadGateway:         ErrorUnreachable,
+	http.StatusServiceUnavailable: ErrorUnreachable,
+	http.StatusGatewayTimeout:     ErrorTimeout,
+	// Anthropic's "overloaded"
+	529: ErrorRateLimited,
+}
@@ -0,0 +1,86 @@
+package backend
+
+import "sync"
+
+// maxHistoryMessages bounds how many messages are kept per session
+const maxHistoryMessages = 40
+
+// chatMessage is a single turn in a conversation
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// history keeps per-session conversation turns in memory for backends
+// that talk to stateless HTTP APIs
+type history struct {
+	sessions map[string][]chatMessage
+	mu       sync.Mutex
+}
+
+func newHistory() *history {
+	return &history{sessions: make(map[string][]chatMessage)}
+}
+
+// with returns the session history followed by a new user message
+func (h *history) with(sessionKey, text string) []chatMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	msgs := make([]chatMessage, 0, len(h.sessions[sessionKey])+1)
+	msgs = append(msgs, h.sessions[sessionKey]...)
+	return append(msgs, chatMessage{Role: "user", Content: text})
+}
+
+// append records a completed user/assistant exchange
+func (h *history) append(sessionKey, user, assistant string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	msgs := append(h.sessions[sessionKey],
+		chatMessage{Role: "user", Content: user},
+		chatMessage{Role: "assistant", Content: assistant},
+	)
+	if len(msgs) > maxHistoryMessages {
+		msgs = msgs[len(msgs)-maxHistoryMessages:]
+	}
+	h.sessions[sessionKey] = msgs
+}
+
+func (h *history) reset(sessionKey string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.sessions, sessionKey)
+}
+
+// snapshot returns a copy of a session's history
+func (h *history) snapshot(sessionKey string) []HistoryMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var out []HistoryMessage
+	for _, m := range h.sessions[sessionKey] {
+		out = append(out, HistoryMessage{Role: m.Role, Content: m.Content})
+	}
+	return out
+}
+
+// restore replaces a session's history
+func (h *history) restore(sessionKey string, msgs []HistoryMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(msgs) == 0 {
+		delete(h.sessions, sessionKey)
+		return
+	}
+	if len(msgs) > maxHistoryMessages {
+		msgs = msgs[len(msgs)-maxHistoryMessages:]
+	}
+	restored := make([]chatMessage, len(msgs))
+	for i, m := range msgs {
+		restored[i] = chatMessage{Role: m.Role, Content: m.Content}
+	}
+	h.sessions[sessionKey] = restored
+}
@@ -0,0 +1,59 @@
+package backend
+
+import "sync"
+
+// ModelSwitcher is implemented by backends that can change the model per session
+type ModelSwitcher interface {
+	// Model returns the model used for a session
+	Model(sessionKey string) string
+	// SetModel selects the model for a session; empty restores the default
+	SetModel(sessionKey, model string)
+}
+
+// ModelLister is implemented by backends that can enumerate available models
+type ModelLister interface {
+	ListModels() ([]string, error)
+}
+
+// modelOverrides tracks per-session model selections on top of a default
+type modelOverrides struct {
+	defaultModel string
+	sessions     map[string]string
+	mu           sync.RWMutex
+}
+
+func newModelOverrides(defaultModel string) *modelOverrides {
+	return &modelOverrides{
+		defaultModel: defaultModel,
+		sessions:     make(map[string]string),
+	}
+}
+
+func (m *modelOverrides) get(sessionKey string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if model, ok := m.sessions[sessionKey]; ok {
+		return model
+	}
+	return m.defaultModel
+}
+
+func (m *modelOverrides) set(sessionKey, model string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if model == "" {
+		delete(m.sessions, sessionKey)
+		return
+	}
+	m.sessions[sessionKey] = model
+}
+
+// override returns a session's own model selection, empty if it uses the
+// default
+func (m *modelOverrides) override(sessionKey string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sessions[sessionKey]
+}
@@ -0,0 +1,202 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/sse"
+)
+
+// OllamaClient talks to a local Ollama server
+type OllamaClient struct {
+	baseURL      string
+	systemPrompt string
+	httpClient   *http.Client
+	history      *history
+	models       *modelOverrides
+}
+
+// NewOllamaClient creates a new Ollama client using model by default
+func NewOllamaClient(baseURL, model, systemPrompt string) *OllamaClient {
+	return &OllamaClient{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		systemPrompt: systemPrompt,
+		httpClient:   &http.Client{},
+		history:      newHistory(),
+		models:       newModelOverrides(model),
+	}
+}
+
+// ollamaChatRequest is the body of POST /api/chat
+type ollamaChatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+}
+
+// ollamaChatChunk is one NDJSON line of a streamed /api/chat response
+type ollamaChatChunk struct {
+	Message struct {
+		Content   string `json:"content"`
+		ToolCalls []struct {
+			Function struct {
+				Name string `json:"name"`
+			} `json:"function"`
+		} `json:"tool_calls"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+// Ask sends text to the selected model and streams the reply
+func (c *OllamaClient) Ask(ctx context.Context, text, sessionKey string, onProgress ProgressFunc) (string, error) {
+	var msgs []chatMessage
+	if c.systemPrompt != "" {
+		msgs = append(msgs, chatMessage{Role: "system", Content: c.systemPrompt})
+	}
+	msgs = append(msgs, c.history.with(sessionKey, text)...)
+
+	body, err := json.Marshal(ollamaChatRequest{
+		Model:    c.models.get(sessionKey),
+		Messages: msgs,
+		Stream:   true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call ollama: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("ollama error: %w", &sse.StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: strings.TrimSpace(string(msg))})
+	}
+
+	var reply strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var chunk ollamaChatChunk
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			logging.Printf(ctx, "[Ollama] Failed to parse chunk: %v", err)
+			continue
+		}
+		if chunk.Error != "" {
+			return "", fmt.Errorf("ollama error: %s", chunk.Error)
+		}
+
+		for _, call := range chunk.Message.ToolCalls {
+			if call.Function.Name != "" {
+				emit(onProgress, StreamToolCall, map[string]string{"name": call.Function.Name})
+			}
+		}
+		if chunk.Message.Content != "" {
+			reply.WriteString(chunk.Message.Content)
+			emit(onProgress, StreamAssistant, map[string]string{"delta": chunk.Message.Content})
+		}
+		if chunk.Done {
+			emit(onProgress, StreamUsage, Usage{
+				InputTokens:  chunk.PromptEvalCount,
+				OutputTokens: chunk.EvalCount,
+			})
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read ollama stream: %w", err)
+	}
+
+	c.history.append(sessionKey, text, reply.String())
+	return reply.String(), nil
+}
+
+// ResetSession clears the in-memory history of a session
+func (c *OllamaClient) ResetSession(sessionKey string) error {
+	c.history.reset(sessionKey)
+	return nil
+}
+
+// Model returns the model used for a session
+func (c *OllamaClient) Model(sessionKey string) string {
+	return c.models.get(sessionKey)
+}
+
+// SetModel selects the model for a session; empty restores the default
+func (c *OllamaClient) SetModel(sessionKey, model string) {
+	c.models.set(sessionKey, model)
+}
+
+// ListModels returns the models installed on the Ollama server
+func (c *OllamaClient) ListModels() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call ollama: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ollama returned %s", resp.Status)
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("failed to parse model list: %w", err)
+	}
+
+	names := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		names = append(names, m.Name)
+	}
+	return names, nil
+}
+
+// SessionState returns a session's model override and history
+func (c *OllamaClient) SessionState(sessionKey string) SessionState {
+	return SessionState{Model: c.models.override(sessionKey), History: c.history.snapshot(sessionKey)}
+}
+
+// RestoreSession replaces a session's model override and history
+func (c *OllamaClient) RestoreSession(sessionKey string, state SessionState) {
+	c.models.set(sessionKey, state.Model)
+	c.history.restore(sessionKey, state.History)
+}
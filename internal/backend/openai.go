@@ -0,0 +1,183 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/sse"
+)
+
+// OpenAIClient talks to an OpenAI-compatible Chat Completions API directly
+type OpenAIClient struct {
+	baseURL      string
+	apiKey       string
+	systemPrompt string
+	maxTokens    int
+	httpClient   *http.Client
+	history      *history
+	models       *modelOverrides
+}
+
+// NewOpenAIClient creates a new Chat Completions API client
+func NewOpenAIClient(baseURL, apiKey, model, systemPrompt string, maxTokens int) *OpenAIClient {
+	return &OpenAIClient{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		apiKey:       apiKey,
+		systemPrompt: systemPrompt,
+		maxTokens:    maxTokens,
+		httpClient:   &http.Client{},
+		history:      newHistory(),
+		models:       newModelOverrides(model),
+	}
+}
+
+// openaiRequest is the body of POST /chat/completions
+type openaiRequest struct {
+	Model         string        `json:"model"`
+	MaxTokens     int           `json:"max_tokens,omitempty"`
+	Messages      []chatMessage `json:"messages"`
+	Stream        bool          `json:"stream"`
+	StreamOptions struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+// openaiChunk covers the fields we use from streamed chunks
+type openaiChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Function struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Ask sends text to the Chat Completions API and streams the reply
+func (c *OpenAIClient) Ask(ctx context.Context, text, sessionKey string, onProgress ProgressFunc) (string, error) {
+	var msgs []chatMessage
+	if c.systemPrompt != "" {
+		msgs = append(msgs, chatMessage{Role: "system", Content: c.systemPrompt})
+	}
+	msgs = append(msgs, c.history.with(sessionKey, text)...)
+
+	reqBody := openaiRequest{
+		Model:     c.models.get(sessionKey),
+		MaxTokens: c.maxTokens,
+		Messages:  msgs,
+		Stream:    true,
+	}
+	reqBody.StreamOptions.IncludeUsage = true
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	stream, err := sse.Do(c.httpClient, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call openai api: %w", err)
+	}
+	defer stream.Close()
+
+	var reply strings.Builder
+	filtered := false
+	for {
+		ev, err := stream.Next()
+		if err == io.EOF || (err == nil && ev.Data == "[DONE]") {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read openai stream: %w", err)
+		}
+
+		var chunk openaiChunk
+		if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+			logging.Printf(ctx, "[OpenAI] Failed to parse chunk: %v", err)
+			continue
+		}
+		if chunk.Error != nil {
+			return "", fmt.Errorf("openai api error: %s", chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			emit(onProgress, StreamUsage, Usage{
+				InputTokens:  chunk.Usage.PromptTokens,
+				OutputTokens: chunk.Usage.CompletionTokens,
+			})
+		}
+
+		for _, choice := range chunk.Choices {
+			for _, call := range choice.Delta.ToolCalls {
+				if call.Function.Name != "" {
+					emit(onProgress, StreamToolCall, map[string]string{"name": call.Function.Name})
+				}
+			}
+			if choice.Delta.Content != "" {
+				reply.WriteString(choice.Delta.Content)
+				emit(onProgress, StreamAssistant, map[string]string{"delta": choice.Delta.Content})
+			}
+			if choice.FinishReason == "content_filter" {
+				filtered = true
+			}
+		}
+	}
+	if filtered && reply.Len() == 0 {
+		return "", ErrContentBlocked
+	}
+
+	c.history.append(sessionKey, text, reply.String())
+	return reply.String(), nil
+}
+
+// Model returns the model used for a session
+func (c *OpenAIClient) Model(sessionKey string) string {
+	return c.models.get(sessionKey)
+}
+
+// SetModel selects the model for a session; empty restores the default
+func (c *OpenAIClient) SetModel(sessionKey, model string) {
+	c.models.set(sessionKey, model)
+}
+
+// ResetSession clears the in-memory history of a session
+func (c *OpenAIClient) ResetSession(sessionKey string) error {
+	c.history.reset(sessionKey)
+	return nil
+}
+
+// SessionState returns a session's model override and history
+func (c *OpenAIClient) SessionState(sessionKey string) SessionState {
+	return SessionState{Model: c.models.override(sessionKey), History: c.history.snapshot(sessionKey)}
+}
+
+// RestoreSession replaces a session's model override and history
+func (c *OpenAIClient) RestoreSession(sessionKey string, state SessionState) {
+	c.models.set(sessionKey, state.Model)
+	c.history.restore(sessionKey, state.History)
+}
@@ -0,0 +1,295 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/internal/seal"
+)
+
+// Recording is one gateway run as captured by the recorder: the message,
+// every progress event with its offset from the start, and the outcome.
+// Recordings are stored one per JSON file, sealed when encryption is
+// configured.
+type Recording struct {
+	Text       string          `json:"text"`
+	SessionKey string          `json:"session_key"`
+	AgentID    string          `json:"agent_id,omitempty"`
+	Started    time.Time       `json:"started"`
+	Events     []RecordedEvent `json:"events"`
+	Reply      string          `json:"reply"`
+	Error      string          `json:"error,omitempty"`
+	DurationMs int64           `json:"duration_ms"`
+}
+
+// RecordedEvent is one progress update of a recorded run
+type RecordedEvent struct {
+	AtMs   int64  `json:"at_ms"`
+	Stream string `json:"stream"`
+	Data   string `json:"data"`
+}
+
+// recorder writes the runs of a gateway backend to dir, sealed with keys
+// when they're set
+type recorder struct {
+	dir  string
+	keys *seal.Keyring
+	seq  atomic.Int64
+}
+
+func newRecorder(dir string, keys *seal.Keyring) *recorder {
+	return &recorder{dir: dir, keys: keys}
+}
+
+// recordingRun collects one run; progress callbacks may be concurrent
+type recordingRun struct {
+	r   *recorder
+	mu  sync.Mutex
+	rec Recording
+}
+
+// start begins recording a run and returns the progress callback to pass
+// to the gateway, which records each event before forwarding it
+func (r *recorder) start(agentID, text, sessionKey string, onProgress ProgressFunc) (*recordingRun, ProgressFunc) {
+	run := &recordingRun{r: r, rec: Recording{
+		Text:       redact.String(text),
+		SessionKey: sessionKey,
+		AgentID:    agentID,
+		Started:    time.Now(),
+	}}
+	return run, func(stream, data string) {
+		run.mu.Lock()
+		run.rec.Events = append(run.rec.Events, RecordedEvent{
+			AtMs:   time.Since(run.rec.Started).Milliseconds(),
+			Stream: stream,
+			Data:   redact.String(data),
+		})
+		run.mu.Unlock()
+		if onProgress != nil {
+			onProgress(stream, data)
+		}
+	}
+}
+
+// finish writes the recording. Failures are logged; they never fail the run.
+func (run *recordingRun) finish(reply string, err error) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	run.rec.Reply = redact.String(reply)
+	if err != nil {
+		run.rec.Error = redact.String(err.Error())
+	}
+	run.rec.DurationMs = time.Since(run.rec.Started).Milliseconds()
+
+	if err := os.MkdirAll(run.r.dir, 0700); err != nil {
+		log.Printf("[Recorder] Failed to create %s: %v", run.r.dir, err)
+		return
+	}
+	data, err := json.MarshalIndent(run.rec, "", "  ")
+	if err != nil {
+		log.Printf("[Recorder] Failed to encode recording: %v", err)
+		return
+	}
+	data = run.r.keys.Seal(data)
+	name := fmt.Sprintf("%s-%03d.json", run.rec.Started.Format("20060102-150405.000"), run.r.seq.Add(1)%1000)
+	path := filepath.Join(run.r.dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		log.Printf("[Recorder] Failed to write %s: %v", path, err)
+	}
+}
+
+// ReplayClient answers messages by playing back recorded gateway runs, so
+// streaming and card behavior can be regression-tested against real
+// conversations without a gateway. A message is answered by the next
+// recording of the same text, in file name order, cycling when they run
+// out.
+type ReplayClient struct {
+	recordings []*Recording
+	speed      float64
+	mu         sync.Mutex
+	played     map[string]int
+}
+
+// RecordingFiles returns the recording files in dir in name order
+func RecordingFiles(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recordings: %w", err)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// ReadRecording reads one recording file, opening it with keys if it was
+// sealed
+func ReadRecording(path string, keys *seal.Keyring) (*Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	data, err = keys.Open(bytes.TrimSpace(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &rec, nil
+}
+
+// DeleteRecordings removes the recordings in dir that match and returns
+// how many it removed. Recordings keys can't open are left alone.
+func DeleteRecordings(dir string, keys *seal.Keyring, match func(*Recording) bool) (int, error) {
+	paths, err := RecordingFiles(dir)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, path := range paths {
+		rec, err := ReadRecording(path, keys)
+		if err != nil || !match(rec) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return n, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// RekeyRecordings reseals the recordings in dir with the active key, see
+// seal.Keyring.Reseal, and returns how many it rewrote
+func RekeyRecordings(dir string, keys *seal.Keyring) (int, error) {
+	paths, err := RecordingFiles(dir)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return n, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		data, changed := keys.Reseal(bytes.TrimSpace(data))
+		if !changed {
+			continue
+		}
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0600); err != nil {
+			return n, fmt.Errorf("failed to write %s: %w", tmp, err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			os.Remove(tmp)
+			return n, fmt.Errorf("failed to replace %s: %w", path, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// NewReplayClient loads the recordings in dir, opening sealed ones with
+// keys. Events are replayed at their recorded pace divided by speed;
+// speed <= 0 replays without waiting.
+func NewReplayClient(dir string, speed float64, keys *seal.Keyring) (*ReplayClient, error) {
+	paths, err := RecordingFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &ReplayClient{speed: speed, played: make(map[string]int)}
+	for _, path := range paths {
+		rec, err := ReadRecording(path, keys)
+		if err != nil {
+			return nil, err
+		}
+		c.recordings = append(c.recordings, rec)
+	}
+	if len(c.recordings) == 0 {
+		return nil, fmt.Errorf("no recordings found in %s", dir)
+	}
+	return c, nil
+}
+
+// next returns the recording to play for text
+func (c *ReplayClient) next(text string) (*Recording, error) {
+	// Recordings hold redacted text
+	text = redact.String(text)
+	var matches []*Recording
+	for _, rec := range c.recordings {
+		if rec.Text == text {
+			matches = append(matches, rec)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no recording matches message %q", text)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.played[text]
+	c.played[text] = n + 1
+	return matches[n%len(matches)], nil
+}
+
+// Ask plays back the recording for text
+func (c *ReplayClient) Ask(ctx context.Context, text, sessionKey string, onProgress ProgressFunc) (string, error) {
+	rec, err := c.next(text)
+	if err != nil {
+		return "", err
+	}
+
+	start := time.Now()
+	for _, ev := range rec.Events {
+		if err := c.waitUntil(ctx, start, ev.AtMs); err != nil {
+			return "", err
+		}
+		if onProgress != nil {
+			onProgress(ev.Stream, ev.Data)
+		}
+	}
+	if err := c.waitUntil(ctx, start, rec.DurationMs); err != nil {
+		return "", err
+	}
+	if rec.Error != "" {
+		return "", errors.New(rec.Error)
+	}
+	return rec.Reply, nil
+}
+
+// waitUntil sleeps until atMs after start, scaled by the replay speed
+func (c *ReplayClient) waitUntil(ctx context.Context, start time.Time, atMs int64) error {
+	if c.speed <= 0 {
+		return ctx.Err()
+	}
+	at := start.Add(time.Duration(float64(atMs)/c.speed) * time.Millisecond)
+	wait := time.Until(at)
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ResetSession does nothing; recordings carry no history to clear
+func (c *ReplayClient) ResetSession(sessionKey string) error {
+	return nil
+}
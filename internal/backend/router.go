@@ -0,0 +1,202 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/netdial"
+	"github.com/wy51ai/moltbotCNAPP/internal/seal"
+	"github.com/wy51ai/moltbotCNAPP/internal/store"
+)
+
+// routeBucket is the store bucket holding admin route overrides (chatID -> backend)
+const routeBucket = "routes"
+
+// Router picks the backend that answers a message based on bridge.json
+// routes and runtime overrides set by admins
+type Router struct {
+	backends    map[string]Backend
+	defaultName string
+	chats       map[string]string
+	commands    map[string]string
+	overrides   map[string]string
+	store       store.KV
+	mu          sync.RWMutex
+}
+
+// NewRouter creates every configured backend. Backends of type "embedded"
+// are taken from embedded by name. Overrides are read from st on every
+// lookup so changes made by other instances sharing it take effect; st
+// may be nil, in which case overrides only live in memory.
+func NewRouter(cfg *config.Config, st store.KV, embedded map[string]Backend) (*Router, error) {
+	r := &Router{
+		backends:    make(map[string]Backend),
+		defaultName: cfg.Routes.Default,
+		chats:       cfg.Routes.Chats,
+		commands:    cfg.Routes.Commands,
+		overrides:   make(map[string]string),
+		store:       st,
+	}
+
+	// Recordings hold whole conversations, so they're only written
+	// unencrypted when encryption isn't configured at all
+	gw := cfg.Clawdbot
+	keys, keysErr := seal.FromConfig(cfg.Encryption)
+	if keysErr != nil && gw.RecordDir != "" {
+		log.Printf("[Router] Not recording gateway runs, encryption keys unavailable: %v", keysErr)
+		gw.RecordDir = ""
+	}
+
+	for name, bc := range cfg.Backends {
+		if c, ok := embedded[name]; ok && bc.Type == "embedded" {
+			r.backends[name] = c
+			continue
+		}
+		b, err := New(bc, gw, netdial.New(cfg.Network), keys)
+		if err != nil {
+			return nil, fmt.Errorf("backend %s: %w", name, err)
+		}
+		r.backends[name] = b
+	}
+
+	return r, nil
+}
+
+// Route returns the backend for a message in chatID.
+// If text starts with a configured command prefix, the prefix is
+// stripped from the returned text.
+func (r *Router) Route(chatID, text string) (name string, b Backend, rest string) {
+	for prefix, target := range r.commands {
+		if text == prefix || strings.HasPrefix(text, prefix+" ") {
+			return target, r.backends[target], strings.TrimSpace(strings.TrimPrefix(text, prefix))
+		}
+	}
+
+	name = r.ChatBackend(chatID)
+	return name, r.backends[name], text
+}
+
+// ChatBackend returns the backend name currently serving chatID
+func (r *Router) ChatBackend(chatID string) string {
+	if name, ok := r.override(chatID); ok {
+		return name
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if name, ok := r.chats[chatID]; ok {
+		return name
+	}
+	return r.defaultName
+}
+
+// Override returns the backend chatID was switched to at runtime, or ""
+// when it follows the configured route
+func (r *Router) Override(chatID string) string {
+	name, _ := r.override(chatID)
+	return name
+}
+
+// override returns the runtime override for chatID, if any
+func (r *Router) override(chatID string) (string, bool) {
+	if r.store == nil {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		name, ok := r.overrides[chatID]
+		return name, ok
+	}
+
+	var name string
+	ok, err := r.store.Get(routeBucket, chatID, &name)
+	if err != nil {
+		log.Printf("[Router] Failed to read override for %s: %v", chatID, err)
+		return "", false
+	}
+	if !ok {
+		return "", false
+	}
+	if _, exists := r.backends[name]; !exists {
+		log.Printf("[Router] Ignoring override for %s: unknown backend %s", chatID, name)
+		return "", false
+	}
+	return name, true
+}
+
+// Backend returns a backend by name
+func (r *Router) Backend(name string) (Backend, bool) {
+	b, ok := r.backends[name]
+	return b, ok
+}
+
+// Names returns the sorted names of all backends
+func (r *Router) Names() []string {
+	names := make([]string, 0, len(r.backends))
+	for name := range r.backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetOverride routes chatID to the named backend at runtime.
+// An empty name removes the override and restores the configured route.
+func (r *Router) SetOverride(chatID, name string) error {
+	if name != "" {
+		if _, ok := r.backends[name]; !ok {
+			return fmt.Errorf("unknown backend: %s", name)
+		}
+	}
+
+	if r.store == nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if name == "" {
+			delete(r.overrides, chatID)
+		} else {
+			r.overrides[chatID] = name
+		}
+		return nil
+	}
+	if name == "" {
+		return r.store.Delete(routeBucket, chatID)
+	}
+	return r.store.Set(routeBucket, chatID, name)
+}
+
+// UpdateGateway applies new gateway settings to every gateway backend
+func (r *Router) UpdateGateway(port int, token string) {
+	for name, b := range r.backends {
+		if u, ok := b.(GatewayUpdater); ok {
+			u.UpdateGateway(port, token)
+			log.Printf("[Router] Backend %s now uses gateway port %d", name, port)
+		}
+	}
+}
+
+// PingGateways checks every gateway backend and returns the first failure
+func (r *Router) PingGateways() error {
+	for _, name := range r.Names() {
+		if p, ok := r.backends[name].(Pinger); ok {
+			if err := p.Ping(); err != nil {
+				return fmt.Errorf("backend %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// OpenCircuits lists the gateway backends currently refusing runs
+func (r *Router) OpenCircuits() []string {
+	var names []string
+	for _, name := range r.Names() {
+		if c, ok := r.backends[name].(CircuitReporter); ok && c.CircuitOpen() {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,42 @@
+package backend
+
+// HistoryMessage is one turn of a session's conversation history
+type HistoryMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// SessionState is what a backend keeps for a session inside the bridge
+type SessionState struct {
+	// Model is the session's model override; empty means the default
+	Model   string           `json:"model,omitempty"`
+	History []HistoryMessage `json:"history,omitempty"`
+}
+
+// SessionSnapshotter is implemented by backends that keep session state
+// in the bridge process, so it can be saved and restored elsewhere.
+// Gateways keep history themselves, under the session key.
+type SessionSnapshotter interface {
+	SessionState(sessionKey string) SessionState
+	// RestoreSession replaces the session's state with state
+	RestoreSession(sessionKey string, state SessionState)
+}
+
+// CompactStats is the size of a session's context before and after
+// compaction, in tokens; zero when unknown
+type CompactStats struct {
+	TokensBefore int
+	TokensAfter  int
+}
+
+// SessionCompactor is implemented by backends that can summarize a
+// session's history in place themselves
+type SessionCompactor interface {
+	CompactSession(sessionKey string) (CompactStats, error)
+}
+
+// SessionForker is implemented by backends that can copy a session's
+// history into a new session themselves
+type SessionForker interface {
+	ForkSession(sessionKey, newKey string) error
+}
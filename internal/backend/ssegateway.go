@@ -0,0 +1,182 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/sse"
+)
+
+// SSEGatewayClient talks to a custom HTTP gateway that streams the
+// bridge's own event names over Server-Sent Events:
+//
+//	POST {base_url}        {"message":"...","session_key":"...","model":"..."}
+//	event: assistant       data: {"delta":"..."} or {"text":"..."}
+//	event: thought         data: {"delta":"..."}
+//	event: tool_call       data: {"name":"..."}
+//	event: tool_result     data: {...}
+//	event: usage           data: {"input_tokens":1,"output_tokens":2}
+//	event: done            data: {"text":"final reply"}
+//	event: error           data: {"message":"..."}
+//
+// POST {base_url}/reset with {"session_key":"..."} clears a session.
+// The gateway keeps conversation history itself.
+type SSEGatewayClient struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	models     *modelOverrides
+}
+
+// NewSSEGatewayClient creates a client for a custom SSE gateway
+func NewSSEGatewayClient(baseURL, apiKey, model string) *SSEGatewayClient {
+	return &SSEGatewayClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{},
+		models:     newModelOverrides(model),
+	}
+}
+
+// sseGatewayRequest is the body of POST {base_url}
+type sseGatewayRequest struct {
+	Message    string `json:"message"`
+	SessionKey string `json:"session_key"`
+	Model      string `json:"model,omitempty"`
+}
+
+// Ask sends text to the gateway and forwards its event stream
+func (c *SSEGatewayClient) Ask(ctx context.Context, text, sessionKey string, onProgress ProgressFunc) (string, error) {
+	body, err := json.Marshal(sseGatewayRequest{
+		Message:    text,
+		SessionKey: sessionKey,
+		Model:      c.models.get(sessionKey),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, c.baseURL, body)
+	if err != nil {
+		return "", err
+	}
+
+	stream, err := sse.Do(c.httpClient, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call gateway: %w", err)
+	}
+	defer stream.Close()
+
+	var buffer string
+	for {
+		ev, err := stream.Next()
+		if err == io.EOF {
+			return "", fmt.Errorf("gateway closed the stream before done")
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read gateway stream: %w", err)
+		}
+
+		var data struct {
+			Text    string `json:"text,omitempty"`
+			Delta   string `json:"delta,omitempty"`
+			Message string `json:"message,omitempty"`
+		}
+		json.Unmarshal([]byte(ev.Data), &data)
+
+		switch ev.Event {
+		case StreamAssistant:
+			if data.Text != "" {
+				buffer = data.Text
+			} else {
+				buffer += data.Delta
+			}
+			if onProgress != nil {
+				onProgress(ev.Event, ev.Data)
+			}
+		case StreamThought, StreamToolCall, StreamToolResult, StreamUsage:
+			if onProgress != nil {
+				onProgress(ev.Event, ev.Data)
+			}
+		case "done":
+			if data.Text != "" {
+				return data.Text, nil
+			}
+			return buffer, nil
+		case "error":
+			if data.Message != "" {
+				return "", fmt.Errorf("gateway error: %s", data.Message)
+			}
+			return "", fmt.Errorf("gateway error")
+		}
+	}
+}
+
+// ResetSession asks the gateway to clear a session
+func (c *SSEGatewayClient) ResetSession(sessionKey string) error {
+	body, err := json.Marshal(map[string]string{"session_key": sessionKey})
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, c.baseURL+"/reset", body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call gateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("gateway returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Model returns the model requested for a session
+func (c *SSEGatewayClient) Model(sessionKey string) string {
+	return c.models.get(sessionKey)
+}
+
+// SetModel selects the model requested for a session; empty restores the default
+func (c *SSEGatewayClient) SetModel(sessionKey, model string) {
+	c.models.set(sessionKey, model)
+}
+
+func (c *SSEGatewayClient) newRequest(ctx context.Context, url string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return req, nil
+}
+
+// SessionState returns a session's model override; the gateway keeps the
+// history
+func (c *SSEGatewayClient) SessionState(sessionKey string) SessionState {
+	return SessionState{Model: c.models.override(sessionKey)}
+}
+
+// RestoreSession restores a session's model override
+func (c *SSEGatewayClient) RestoreSession(sessionKey string, state SessionState) {
+	c.models.set(sessionKey, state.Model)
+}
@@ -0,0 +1,184 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// maxActivityArgs caps the arguments shown for a tool call, in characters
+const maxActivityArgs = 80
+
+// maxActivitySteps caps the tool calls listed in one card
+const maxActivitySteps = 30
+
+// toolEvent covers the fields we use from tool_call and tool_result data.
+// Gateways name them differently, so several spellings are accepted.
+type toolEvent struct {
+	Name            string          `json:"name"`
+	ID              string          `json:"id"`
+	ToolCallID      string          `json:"toolCallId"`
+	ToolCallIDSnake string          `json:"tool_call_id"`
+	Args            json.RawMessage `json:"args"`
+	Arguments       json.RawMessage `json:"arguments"`
+	Input           json.RawMessage `json:"input"`
+	IsError         bool            `json:"isError"`
+	ErrorMessage    string          `json:"error"`
+}
+
+func (e toolEvent) id() string {
+	for _, id := range []string{e.ID, e.ToolCallID, e.ToolCallIDSnake} {
+		if id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+func (e toolEvent) args() json.RawMessage {
+	for _, a := range []json.RawMessage{e.Args, e.Arguments, e.Input} {
+		if len(a) > 0 && string(a) != "null" {
+			return a
+		}
+	}
+	return nil
+}
+
+// toolStep is one tool call made during a run
+type toolStep struct {
+	id, name, args    string
+	started, finished time.Time
+	failed, blocked   bool
+}
+
+// toolActivity records the tool calls of one run for the 执行过程 card.
+// Stream callbacks may run concurrently, so it locks.
+type toolActivity struct {
+	mu    sync.Mutex
+	steps []*toolStep
+}
+
+// call records a tool_call event
+func (a *toolActivity) call(data string, blocked bool) {
+	var ev toolEvent
+	if err := json.Unmarshal([]byte(data), &ev); err != nil || ev.Name == "" {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.steps = append(a.steps, &toolStep{
+		id:      ev.id(),
+		name:    ev.Name,
+		args:    shortArgs(ev.args()),
+		started: time.Now(),
+		blocked: blocked,
+	})
+}
+
+// result records a tool_result event against the call it answers: the
+// one with its id, else the oldest unfinished call of the same tool,
+// else the oldest unfinished call
+func (a *toolActivity) result(data string) {
+	var ev toolEvent
+	_ = json.Unmarshal([]byte(data), &ev)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var match *toolStep
+	for _, s := range a.steps {
+		if !s.finished.IsZero() {
+			continue
+		}
+		if id := ev.id(); id != "" && s.id == id {
+			match = s
+			break
+		}
+		if match == nil && (ev.Name == "" || s.name == ev.Name) {
+			match = s
+		}
+	}
+	if match == nil {
+		return
+	}
+	match.finished = time.Now()
+	match.failed = ev.IsError || ev.ErrorMessage != ""
+}
+
+// shortArgs renders tool arguments on one line, secrets masked, cut to
+// maxActivityArgs characters
+func shortArgs(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	text := string(raw)
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		text = s
+	}
+	text = redact.String(strings.Join(strings.Fields(text), " "))
+	if runes := []rune(text); len(runes) > maxActivityArgs {
+		text = string(runes[:maxActivityArgs]) + "…"
+	}
+	return text
+}
+
+// markdown lists the steps, or returns "" when no tools were called
+func (a *toolActivity) markdown() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.steps) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	for i, s := range a.steps {
+		if i == maxActivitySteps {
+			fmt.Fprintf(&sb, "……另有 %d 次调用\n", len(a.steps)-maxActivitySteps)
+			break
+		}
+		fmt.Fprintf(&sb, "%d. **%s**", i+1, s.name)
+		if s.args != "" {
+			// Backticks would end the code span early
+			fmt.Fprintf(&sb, " `%s`", strings.ReplaceAll(s.args, "`", "'"))
+		}
+		switch {
+		case s.blocked:
+			sb.WriteString(" — 已拦截")
+		case s.finished.IsZero():
+			sb.WriteString(" — 未完成")
+		default:
+			fmt.Fprintf(&sb, " — %.1fs", s.finished.Sub(s.started).Seconds())
+			if s.failed {
+				sb.WriteString("（出错）")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// sendToolActivity sends the collapsed 执行过程 card for a run that
+// called tools
+func (b *Bridge) sendToolActivity(ctx context.Context, chatID string, activity *toolActivity) {
+	text := activity.markdown()
+	if text == "" {
+		return
+	}
+	activity.mu.Lock()
+	calls := len(activity.steps)
+	activity.mu.Unlock()
+
+	card := feishu.NewCard("", "").
+		AddCollapsible(fmt.Sprintf("执行过程（%d 次工具调用）", calls), text, false)
+	if _, err := b.feishuClient.SendCard(chatID, card); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to send tool activity: %v", err)
+		b.observeDelivery(ctx, err)
+	}
+}
@@ -0,0 +1,178 @@
+package bridge
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// SimulateRequest is the body of POST /v1/simulate: a message to handle
+// as if it had arrived from Feishu
+type SimulateRequest struct {
+	ChatID string `json:"chat_id"`
+	// ChatType is "p2p" (default) or "group"
+	ChatType string `json:"chat_type,omitempty"`
+	SenderID string `json:"sender_id,omitempty"`
+	Text     string `json:"text"`
+	// Mention @-mentions the bot, which always triggers a reply in groups
+	Mention bool `json:"mention,omitempty"`
+}
+
+// SimulateResponse identifies the injected message in logs and events
+type SimulateResponse struct {
+	MessageID     string `json:"message_id"`
+	CorrelationID string `json:"correlation_id"`
+}
+
+// RestoreResponse reports where a snapshot was restored
+type RestoreResponse struct {
+	ChatID     string `json:"chat_id"`
+	SessionKey string `json:"session_key"`
+}
+
+// simulatedSender is the open_id simulated messages come from by default
+const simulatedSender = "ou_simulated"
+
+// AdminHandler serves the admin API used by the CLI. Every request must
+// carry the configured token.
+func (b *Bridge) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/simulate", b.handleSimulate)
+	mux.HandleFunc("/v1/sessions/snapshot", b.handleSnapshot)
+	mux.HandleFunc("/v1/sessions/restore", b.handleRestore)
+	mux.HandleFunc("/v1/health", b.handleHealth)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if b.cfg.Admin.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(b.cfg.Admin.Token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// handleSimulate feeds a fabricated message through the normal pipeline:
+// triggers, commands and streaming behave as for a real one, and replies
+// go to the chat
+func (b *Bridge) handleSimulate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req SimulateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	msg, err := req.message()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := logging.NewContext(context.Background())
+	logging.SetChatID(ctx, msg.ChatID)
+	logging.Printf(ctx, "[Bridge] Simulating %s message %s from %s", msg.ChatType, msg.MessageID, msg.SenderID)
+	b.audit.Record(ctx, audit.Event{Action: "admin.simulate", Actor: "admin_api", ChatID: msg.ChatID, Target: msg.MessageID})
+	if err := b.HandleMessage(ctx, msg); err != nil {
+		http.Error(w, fmt.Sprintf("failed to handle message: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SimulateResponse{MessageID: msg.MessageID, CorrelationID: logging.CorrelationID(ctx)})
+}
+
+// handleSnapshot returns the session state of ?chat_id= as a
+// SessionSnapshot
+func (b *Bridge) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := logging.NewContext(r.Context())
+	snap, err := b.Snapshot(ctx, r.URL.Query().Get("chat_id"), "admin_api")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snap)
+}
+
+// handleRestore applies the SessionSnapshot in the body to ?chat_id=, or
+// to the chat it was taken from
+func (b *Bridge) handleRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var snap SessionSnapshot
+	if err := json.NewDecoder(r.Body).Decode(&snap); err != nil {
+		http.Error(w, fmt.Sprintf("invalid snapshot: %v", err), http.StatusBadRequest)
+		return
+	}
+	chatID := r.URL.Query().Get("chat_id")
+	if chatID == "" {
+		chatID = snap.ChatID
+	}
+	ctx := logging.NewContext(r.Context())
+	logging.SetChatID(ctx, chatID)
+	sessionKey, err := b.Restore(ctx, &snap, chatID, "admin_api")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to restore: %v", err), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RestoreResponse{ChatID: chatID, SessionKey: sessionKey})
+}
+
+// handleHealth returns the current Status, uptime and reliability
+// counters included
+func (b *Bridge) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(b.Status(r.Context(), time.Now()))
+}
+
+// message builds the Feishu message req describes
+func (req SimulateRequest) message() (*feishu.Message, error) {
+	if req.ChatID == "" || strings.TrimSpace(req.Text) == "" {
+		return nil, fmt.Errorf("chat_id and text are required")
+	}
+	msg := &feishu.Message{
+		MessageID: "sim_" + uuid.NewString(),
+		ChatID:    req.ChatID,
+		ChatType:  req.ChatType,
+		SenderID:  req.SenderID,
+		Content:   req.Text,
+	}
+	switch msg.ChatType {
+	case "":
+		msg.ChatType = "p2p"
+	case "p2p", "group":
+	default:
+		return nil, fmt.Errorf("chat_type must be p2p or group")
+	}
+	if msg.SenderID == "" {
+		msg.SenderID = simulatedSender
+	}
+	if req.Mention {
+		msg.Content = "@_user_1 " + msg.Content
+		msg.Mentions = []feishu.Mention{{Key: "@_user_1", Name: "bot"}}
+	}
+	return msg, nil
+}
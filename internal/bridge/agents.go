@@ -0,0 +1,291 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/backend"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// chatAgentBucket stores each chat's selected default agent
+const chatAgentBucket = "chat_agent"
+
+// chatAgent is a chat's default agent chosen via /agents
+type chatAgent struct {
+	// Name is a configured agent name; empty for gateway agents
+	Name string `json:"name,omitempty"`
+	// AgentID is the gateway agent to address
+	AgentID string `json:"agent_id"`
+}
+
+// agentMention matches a leading "@名称" addressing a configured agent.
+// Real Feishu mentions arrive as @_user_N and are removed before this runs.
+var agentMention = regexp.MustCompile(`^[@＠](\S+)\s*`)
+
+// runRequest describes one message forwarded to a backend
+type runRequest struct {
+	// ctx carries the message's root trace span
+	ctx         context.Context
+	chatID      string
+	chatType    string
+	senderID    string
+	text        string
+	backendName string
+	agent       backend.Backend
+	sessionKey  string
+	// agentName is the addressed agent's display name, tagged on replies
+	agentName string
+	// received is when the message arrived, for end-to-end latency
+	received time.Time
+	// msg is the message that started the run, if any; the retry button
+	// submits it again
+	msg *feishu.Message
+	// priority lets the run go ahead of others waiting for a slot
+	priority bool
+	// queued is the run's place in the queue, nil when it took a slot
+	// right away
+	queued *queuedRun
+	// tag is the conversation's category, when classification is on
+	tag string
+	// tagRoutable lets the tag's route pick the agent, as neither the
+	// message nor the chat did
+	tagRoutable bool
+}
+
+// agentsFor returns the agent names chatID may address
+func (b *Bridge) agentsFor(chatID string) []string {
+	if names, ok := b.cfg.ChatAgents[chatID]; ok {
+		return names
+	}
+	names := make([]string, 0, len(b.cfg.Agents))
+	for name := range b.cfg.Agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// chatAgent returns the default agent selected for chatID, if any
+func (b *Bridge) chatAgent(ctx context.Context, chatID string) (chatAgent, bool) {
+	var sel chatAgent
+	ok, err := b.store.Get(chatAgentBucket, chatID, &sel)
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to read agent selection for %s: %v", chatID, err)
+	}
+	return sel, ok && err == nil
+}
+
+// resolveAgent checks whether text addresses one of the chat's agents.
+// It returns the agent name and the text without the mention.
+func (b *Bridge) resolveAgent(chatID, text string) (name, rest string, ok bool) {
+	m := agentMention.FindStringSubmatch(text)
+	if m == nil {
+		return "", text, false
+	}
+	for _, allowed := range b.agentsFor(chatID) {
+		if allowed == m[1] {
+			return allowed, text[len(m[0]):], true
+		}
+	}
+	return "", text, false
+}
+
+// bindAgent points req at the named agent, giving it a separate session
+// so several agents can work in one chat without sharing context
+func (b *Bridge) bindAgent(req *runRequest, name string) {
+	a, ok := b.cfg.Agents[name]
+	if !ok {
+		return
+	}
+	if a.Backend != "" {
+		if agent, ok := b.router.Backend(a.Backend); ok {
+			req.backendName = a.Backend
+			req.agent = agent
+		}
+	}
+	req.agentName = name
+
+	agentID := a.AgentID
+	if agentID == "" {
+		agentID = name
+	}
+	bindAgentID(req, agentID)
+}
+
+// bindAgentID addresses a gateway agent by ID in its own session
+func bindAgentID(req *runRequest, agentID string) {
+	req.agent = backend.WithAgent(req.agent, agentID)
+	req.sessionKey = req.sessionKey + ":agent:" + agentID
+}
+
+const agentsUsage = "/agents 列出可用的 Agent，并为当前会话选择默认 Agent"
+
+// cmdAgents lists configured and gateway-discovered agents as a selectable card
+func cmdAgents(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	current, hasCurrent := b.chatAgent(ctx, msg.ChatID)
+	card := feishu.NewCard("可用 Agent", "blue")
+
+	var buttons []feishu.CardButton
+	selectButton := func(label, name, agentID string) {
+		typ := "default"
+		if hasCurrent && current.AgentID == agentID && current.Name == name {
+			typ = "primary"
+		}
+		buttons = append(buttons, feishu.CardButton{
+			Text: label,
+			Type: typ,
+			Value: map[string]interface{}{
+				"action":   "select_agent",
+				"name":     name,
+				"agent_id": agentID,
+			},
+		})
+	}
+
+	// Agents configured in bridge.json, addressable with @名称
+	if names := b.agentsFor(msg.ChatID); len(names) > 0 {
+		var lines []string
+		for _, name := range names {
+			a := b.cfg.Agents[name]
+			line := fmt.Sprintf("**@%s**", name)
+			if a.Description != "" {
+				line += "：" + a.Description
+			}
+			lines = append(lines, line)
+			agentID := a.AgentID
+			if agentID == "" {
+				agentID = name
+			}
+			selectButton(name, name, agentID)
+		}
+		card.AddMarkdown(strings.Join(lines, "\n"))
+	}
+
+	// Agents discovered on the chat's backend; only offered when the chat
+	// isn't restricted to a configured set
+	_, routed, _ := b.router.Route(msg.ChatID, "")
+	if _, restricted := b.cfg.ChatAgents[msg.ChatID]; !restricted {
+		if lister, ok := routed.(backend.AgentLister); ok {
+			agents, err := lister.ListAgents()
+			if err != nil {
+				logging.Printf(ctx, "[Bridge] Failed to list gateway agents: %v", err)
+				card.AddNote("无法从 Gateway 获取 Agent 列表：" + err.Error())
+			} else if len(agents) > 0 {
+				card.AddDivider()
+				var lines []string
+				for _, a := range agents {
+					label := a.ID
+					if a.Name != "" {
+						label = a.Name + "（" + a.ID + "）"
+					}
+					line := "**" + label + "**"
+					if a.Description != "" {
+						line += "：" + a.Description
+					}
+					if len(a.Tools) > 0 {
+						line += "\n工具：" + summarizeTools(a.Tools, 8)
+					}
+					lines = append(lines, line)
+					selectButton(label, "", a.ID)
+				}
+				card.AddMarkdown(strings.Join(lines, "\n\n"))
+			}
+		}
+	}
+
+	if len(buttons) == 0 {
+		return "当前没有可用的 Agent"
+	}
+
+	buttons = append(buttons, feishu.CardButton{
+		Text:  "恢复默认",
+		Value: map[string]interface{}{"action": "select_agent"},
+	})
+	card.AddDivider()
+	for i := 0; i < len(buttons); i += 4 {
+		end := i + 4
+		if end > len(buttons) {
+			end = len(buttons)
+		}
+		card.AddButtons(buttons[i:end]...)
+	}
+	if hasCurrent {
+		label := current.Name
+		if label == "" {
+			label = current.AgentID
+		}
+		card.AddNote("当前默认 Agent：" + label)
+	}
+
+	if _, err := b.feishuClient.SendCard(msg.ChatID, card); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to send agents card: %v", err)
+		return "发送 Agent 列表失败"
+	}
+	return ""
+}
+
+// actionSelectAgent stores the chat's default agent picked on the /agents card
+func actionSelectAgent(ctx context.Context, b *Bridge, action *feishu.CardAction) (string, error) {
+	if action.ChatID == "" {
+		return "", fmt.Errorf("缺少会话信息")
+	}
+
+	sel := chatAgent{
+		Name:    actionString(action, "name"),
+		AgentID: actionString(action, "agent_id"),
+	}
+	if sel.Name != "" {
+		allowed := false
+		for _, name := range b.agentsFor(action.ChatID) {
+			allowed = allowed || name == sel.Name
+		}
+		if !allowed {
+			b.audit.Record(ctx, audit.Event{Action: "agent.select", Actor: action.OperatorID, ChatID: action.ChatID, Target: sel.Name, Outcome: audit.Denied, Detail: "agent not allowed in chat"})
+			return "", fmt.Errorf("该会话不能使用 %s", sel.Name)
+		}
+	}
+
+	if sel.AgentID == "" {
+		if err := b.store.Delete(chatAgentBucket, action.ChatID); err != nil {
+			return "", err
+		}
+		logging.Printf(ctx, "[Bridge] %s cleared default agent of %s", action.OperatorID, action.ChatID)
+		b.audit.Record(ctx, audit.Event{Action: "agent.select", Actor: action.OperatorID, ChatID: action.ChatID, Target: "default"})
+		return "已恢复默认 Agent", nil
+	}
+
+	if err := b.store.Set(chatAgentBucket, action.ChatID, sel); err != nil {
+		return "", err
+	}
+	logging.Printf(ctx, "[Bridge] %s set default agent of %s to %s", action.OperatorID, action.ChatID, sel.AgentID)
+	b.audit.Record(ctx, audit.Event{Action: "agent.select", Actor: action.OperatorID, ChatID: action.ChatID, Target: sel.AgentID})
+
+	label := sel.Name
+	if label == "" {
+		label = sel.AgentID
+	}
+	return "已切换到 " + label, nil
+}
+
+// summarizeTools joins up to max tool names
+func summarizeTools(tools []string, max int) string {
+	if len(tools) <= max {
+		return strings.Join(tools, ", ")
+	}
+	return fmt.Sprintf("%s 等 %d 个", strings.Join(tools[:max], ", "), len(tools))
+}
+
+// tagReply prefixes text with the responding agent's name
+func (req *runRequest) tagReply(text string) string {
+	if req.agentName == "" || text == "" {
+		return text
+	}
+	return "【" + req.agentName + "】" + text
+}
@@ -0,0 +1,177 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/backend"
+	"github.com/wy51ai/moltbotCNAPP/internal/errreport"
+	"github.com/wy51ai/moltbotCNAPP/internal/events"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/metrics"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// Alert keys; each is deduplicated separately
+const (
+	alertGatewayDown   = "gateway_unreachable"
+	alertFeishuAuth    = "feishu_auth"
+	alertQueue         = "queue_backlog"
+	alertFeishuCircuit = "feishu_circuit"
+	alertFeishuSilent  = "feishu_silent"
+	alertErrorRate     = "error_rate"
+)
+
+// connectionWatcher is implemented by Feishu clients that watch their
+// long connection for silence
+type connectionWatcher interface {
+	Connection() feishu.ConnectionStatus
+	OnConnectionChange(fn func(feishu.ConnectionStatus))
+}
+
+// gatewayUnavailableReply answers messages while the gateway circuit is open
+const gatewayUnavailableReply = "AI 服务暂不可用，请稍后再试"
+
+// Alert posts an operational alert to the configured alert chat/webhook.
+// It blocks while sending.
+func (b *Bridge) Alert(key, text string) {
+	b.alerts.Notify(key, text)
+}
+
+// sendAlert posts through the bridge's Feishu client once it is set
+func (b *Bridge) sendAlert(chatID, text string) (string, error) {
+	if b.feishuClient == nil {
+		return "", fmt.Errorf("feishu client not ready")
+	}
+	return b.feishuClient.SendMessage(chatID, text)
+}
+
+// observeRun feeds a finished run into the alert checks and counts
+// failed gateway runs
+func (b *Bridge) observeRun(ctx context.Context, backendName string, err error) {
+	if err != nil && b.cfg.Backends[backendName].Type == "clawdbot" {
+		b.gatewayErrors.Add(1)
+		metrics.Inc("gateway_errors", "backend", backendName)
+	}
+	if b.alerts == nil {
+		return
+	}
+	if errors.Is(err, backend.ErrGatewayUnreachable) {
+		go b.Alert(alertGatewayDown, fmt.Sprintf("后端 %s 无法连接 Gateway：%v", backendName, err))
+	}
+
+	failed, total := b.runErrors.record(err != nil, time.Now())
+	cfg := b.cfg.Alerts
+	if total >= cfg.MinSamples && float64(failed)*100 >= cfg.ErrorRatePercent*float64(total) {
+		logging.Printf(ctx, "[Bridge] Error rate %d/%d over the last %s", failed, total, cfg.ErrorWindow)
+		go b.Alert(alertErrorRate, fmt.Sprintf("最近 %s 内 %d/%d 次请求失败", cfg.ErrorWindow, failed, total))
+	}
+}
+
+// observeDelivery records a failed reply delivery and alerts when Feishu
+// rejects the app's credentials
+func (b *Bridge) observeDelivery(ctx context.Context, err error) {
+	metrics.Inc("delivery_errors")
+	// The breaker already reported the outage once
+	if errors.Is(err, feishu.ErrCircuitOpen) {
+		return
+	}
+	errreport.Capture(ctx, fmt.Errorf("failed to deliver reply: %w", err))
+	b.events.Emit(ctx, events.Event{Type: events.Error, Detail: "delivery: " + err.Error()})
+	if errors.Is(err, feishu.ErrAuth) {
+		go b.Alert(alertFeishuAuth, fmt.Sprintf("飞书接口鉴权失败，请检查 App ID/App Secret：%v", err))
+	}
+}
+
+// delivered records a completed reply delivery
+func (b *Bridge) delivered(ctx context.Context, how string) {
+	b.events.Emit(ctx, events.Event{Type: events.Delivery, Detail: how})
+}
+
+// deliverLater hands a final reply the Feishu API couldn't take to the
+// client's pending buffer, and reports whether it did
+func (b *Bridge) deliverLater(ctx context.Context, chatID, messageID, text string, post bool, err error) bool {
+	if !feishu.IsUnavailable(err) {
+		return false
+	}
+	b.feishuClient.DeferReply(chatID, messageID, text, post)
+	logging.Printf(ctx, "[Bridge] Feishu API unavailable, reply to %s held until it recovers: %v", chatID, err)
+	metrics.Inc("replies_deferred")
+	b.delivered(ctx, "deferred")
+	return true
+}
+
+// circuitChanged reports Feishu API breaker transitions
+func (b *Bridge) circuitChanged(st feishu.CircuitStatus) {
+	open := 0.0
+	if st.State != feishu.CircuitClosed {
+		open = 1
+	}
+	metrics.Set("feishu_circuit_open", open)
+
+	if st.State == feishu.CircuitOpen {
+		b.Alert(alertFeishuCircuit, fmt.Sprintf("飞书接口连续失败，已暂停调用，%d 条回复等待恢复后发送", st.Pending))
+	}
+}
+
+// observeQueue alerts when too many messages are being processed at once
+func (b *Bridge) observeQueue(inflight int) {
+	if t := b.cfg.Alerts.QueueThreshold; t > 0 && inflight >= t {
+		go b.Alert(alertQueue, fmt.Sprintf("当前有 %d 条消息正在处理，超过阈值 %d", inflight, t))
+	}
+}
+
+// errorWindow counts failed runs over a sliding time window
+type errorWindow struct {
+	window  time.Duration
+	mu      sync.Mutex
+	samples []errorSample
+}
+
+type errorSample struct {
+	at     time.Time
+	failed bool
+}
+
+func newErrorWindow(window time.Duration) *errorWindow {
+	return &errorWindow{window: window}
+}
+
+// record adds an outcome and returns the failures and total in the window
+func (w *errorWindow) record(failed bool, now time.Time) (failures, total int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	kept := w.samples[:0]
+	for _, s := range w.samples {
+		if now.Sub(s.at) < w.window {
+			kept = append(kept, s)
+		}
+	}
+	w.samples = append(kept, errorSample{at: now, failed: failed})
+
+	for _, s := range w.samples {
+		if s.failed {
+			failures++
+		}
+	}
+	return failures, len(w.samples)
+}
+
+// connectionChanged reports the Feishu long connection going silent,
+// which the client answers by reconnecting, and recovering
+func (b *Bridge) connectionChanged(st feishu.ConnectionStatus) {
+	silent := 0.0
+	if st.Silent {
+		silent = 1
+		metrics.Inc("feishu_reconnects")
+	}
+	metrics.Set("feishu_connection_silent", silent)
+
+	if st.Silent {
+		b.Alert(alertFeishuSilent, fmt.Sprintf("飞书长连接长时间没有收到任何消息（包括心跳），已新建长连接替换（第 %d 次）", st.Reconnects))
+	}
+}
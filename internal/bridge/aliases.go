@@ -0,0 +1,48 @@
+package bridge
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+)
+
+// SetAliases replaces the custom commands defined in bridge.json, e.g.
+// after the config is reloaded. Built-in commands keep their names.
+func (b *Bridge) SetAliases(aliases map[string]config.Alias) {
+	var names []string
+	for name := range aliases {
+		if _, builtin := commands[name]; builtin {
+			log.Printf("[Bridge] Alias /%s is ignored: it's a built-in command", name)
+			continue
+		}
+		names = append(names, "/"+name)
+	}
+	sort.Strings(names)
+	b.aliases.Store(&aliases)
+	if len(names) > 0 {
+		log.Printf("[Bridge] Command aliases: %s", strings.Join(names, ", "))
+	}
+}
+
+// expandAlias returns what text stands for when it invokes an alias
+func (b *Bridge) expandAlias(text string) (string, bool) {
+	if !strings.HasPrefix(text, "/") {
+		return "", false
+	}
+	name := commandName(text)
+	if _, builtin := commands[name]; builtin {
+		return "", false
+	}
+	aliases := b.aliases.Load()
+	if aliases == nil {
+		return "", false
+	}
+	alias, ok := (*aliases)[name]
+	if !ok {
+		return "", false
+	}
+	_, args := cutSpace(text)
+	return alias.Expand(args), true
+}
@@ -0,0 +1,139 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// announceTimeout bounds the agent rewriting an announcement
+const announceTimeout = 3 * time.Minute
+
+// announcer is implemented by Feishu clients that can edit group
+// announcements
+type announcer interface {
+	Announcement(chatID string) (string, error)
+	SetAnnouncement(chatID, text string) error
+}
+
+// announcePrompt asks the agent to fold a change into the announcement
+const announcePrompt = `请根据本群的对话和下面的变更说明，更新群公告。群公告用于记录“当前状态/值班信息”，应简洁、准确，去掉已过时的内容。
+只输出更新后的完整公告正文，不要任何解释。每行一项；“# ”开头为一级标题，“## ”开头为二级标题，“- ”开头为列表项。
+
+当前群公告：
+%s
+
+变更说明：
+%s`
+
+const announceUsage = "/announce 查看群公告；/announce set 全文 替换群公告；/announce update 变更说明 让 Agent 按说明更新群公告（修改需管理员）"
+
+// cmdAnnounce shows the group's announcement, replaces it, or has the
+// agent rewrite it
+func cmdAnnounce(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if msg.ChatType != "group" {
+		return "群公告只能在群聊中使用"
+	}
+	client, ok := b.feishuClient.(announcer)
+	if !ok {
+		return "当前飞书客户端不支持群公告"
+	}
+	sub, text := cutSpace(args)
+	switch strings.ToLower(sub) {
+	case "":
+		current, err := client.Announcement(msg.ChatID)
+		if err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to read announcement of %s: %v", msg.ChatID, err)
+			return fmt.Sprintf("读取群公告失败：%s", redact.Error(err))
+		}
+		if current == "" {
+			return "本群还没有群公告\n\n" + announceUsage
+		}
+		return "当前群公告：\n" + current
+	case "set", "update":
+	default:
+		return announceUsage
+	}
+	if text == "" {
+		return announceUsage
+	}
+
+	action := "announcement." + strings.ToLower(sub)
+	if !b.cfg.IsAdmin(msg.SenderID) {
+		logging.Printf(ctx, "[Bridge] Denied /announce %s from non-admin %s in %s", sub, msg.SenderID, msg.ChatID)
+		b.audit.Record(ctx, audit.Event{Action: action, Actor: msg.SenderID, ChatID: msg.ChatID, Outcome: audit.Denied, Detail: "not an admin"})
+		return "只有管理员可以修改群公告"
+	}
+
+	if action == "announcement.set" && b.cfg.TwoPerson.Requires(action) {
+		return b.requestConfirmation(ctx, pendingAction{
+			Action:      action,
+			ChatID:      msg.ChatID,
+			Target:      text,
+			RequestedBy: msg.SenderID,
+		})
+	}
+	if action == "announcement.update" {
+		rewritten, err := b.rewriteAnnouncement(ctx, client, msg.ChatID, text)
+		if err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to rewrite announcement of %s: %v", msg.ChatID, err)
+			return fmt.Sprintf("生成群公告失败：%s", redact.Error(err))
+		}
+		text = rewritten
+	}
+	reply, _ := b.setAnnouncement(ctx, action, msg.SenderID, msg.ChatID, text, args, "")
+	return reply
+}
+
+// setAnnouncement replaces the announcement of chatID with text for
+// actor and returns the text reporting it. detail goes to the audit log;
+// confirmedBy names the second admin, if any. The error is only set when
+// the announcement wasn't replaced.
+func (b *Bridge) setAnnouncement(ctx context.Context, action, actor, chatID, text, detail, confirmedBy string) (string, error) {
+	client, ok := b.feishuClient.(announcer)
+	if !ok {
+		return "当前飞书客户端不支持群公告", errors.New("the Feishu client can't edit announcements")
+	}
+	if err := client.SetAnnouncement(chatID, text); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to set announcement of %s: %v", chatID, err)
+		return fmt.Sprintf("更新群公告失败：%s（请确认机器人有编辑群公告的权限）", redact.Error(err)), err
+	}
+
+	logging.Printf(ctx, "[Bridge] %s ran %s in %s", actor, action, chatID)
+	if confirmedBy != "" {
+		detail += ", confirmed by " + confirmedBy
+	}
+	b.audit.Record(ctx, audit.Event{Action: action, Actor: actor, ChatID: chatID, Detail: detail})
+	return "已更新群公告：\n" + text, nil
+}
+
+// rewriteAnnouncement has the chat's agent fold change into the current
+// announcement, so it can draw on what the group discussed
+func (b *Bridge) rewriteAnnouncement(ctx context.Context, client announcer, chatID, change string) (string, error) {
+	current, err := client.Announcement(chatID)
+	if err != nil {
+		return "", err
+	}
+	if current == "" {
+		current = "（空）"
+	}
+	req := b.chatRun(ctx, chatID)
+	ctx, cancel := context.WithTimeout(ctx, announceTimeout)
+	defer cancel()
+	reply, err := req.agent.Ask(ctx, fmt.Sprintf(announcePrompt, current, change), req.sessionKey, nil)
+	if err != nil {
+		return "", err
+	}
+	reply = strings.TrimSpace(reply)
+	if reply == "" {
+		return "", fmt.Errorf("the agent returned an empty announcement")
+	}
+	return reply, nil
+}
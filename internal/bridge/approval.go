@@ -0,0 +1,264 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/metrics"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// chatApprovalBucket stores each group's approval state
+const chatApprovalBucket = "chat_approval"
+
+// maxHeldMessages caps the messages kept per group while it waits for
+// approval; older ones are dropped first
+const maxHeldMessages = 20
+
+// Approval states
+const (
+	approvalPending  = "pending"
+	approvalApproved = "approved"
+	approvalRejected = "rejected"
+)
+
+// chatApproval is a group's approval state
+type chatApproval struct {
+	Status string `json:"status"`
+	// Name is the group name, when the bot-added event provided it
+	Name string `json:"name,omitempty"`
+	// RequestedBy added the bot or sent the group's first message
+	RequestedBy string    `json:"requested_by,omitempty"`
+	Requested   time.Time `json:"requested"`
+	DecidedBy   string    `json:"decided_by,omitempty"`
+	Decided     time.Time `json:"decided"`
+	// Held are messages received while pending, answered on approval
+	Held []feishu.Message `json:"held,omitempty"`
+}
+
+// label names the group in cards and logs
+func (a chatApproval) label(chatID string) string {
+	if a.Name == "" {
+		return chatID
+	}
+	return fmt.Sprintf("%s（%s）", a.Name, chatID)
+}
+
+// needsApproval reports whether messages from chatID wait for approval
+func (b *Bridge) needsApproval(chatType, chatID string) bool {
+	return b.cfg.Approval.Enabled && chatType == "group" && !b.cfg.Approval.Preapproved(chatID)
+}
+
+// holdForApproval keeps msg back if its group isn't approved yet and
+// reports whether it did. The first held message asks the admins.
+func (b *Bridge) holdForApproval(ctx context.Context, msg *feishu.Message) bool {
+	if !b.needsApproval(msg.ChatType, msg.ChatID) {
+		return false
+	}
+
+	b.approvalMu.Lock()
+	var a chatApproval
+	found, err := b.store.Get(chatApprovalBucket, msg.ChatID, &a)
+	if err != nil {
+		b.approvalMu.Unlock()
+		// Unknown state; don't answer a group that may not be approved
+		logging.Printf(ctx, "[Bridge] Failed to read approval of %s, dropping message: %v", msg.ChatID, err)
+		metrics.Inc("messages_skipped", "reason", "approval_error")
+		return true
+	}
+	switch a.Status {
+	case approvalApproved:
+		b.approvalMu.Unlock()
+		return false
+	case approvalRejected:
+		b.approvalMu.Unlock()
+		logging.Printf(ctx, "[Bridge] Skipping message from rejected group %s", msg.ChatID)
+		metrics.Inc("messages_skipped", "reason", "chat_rejected")
+		return true
+	}
+
+	if !found {
+		a = chatApproval{Status: approvalPending, RequestedBy: msg.SenderID, Requested: time.Now()}
+	}
+	if !heldAlready(a.Held, msg.MessageID) {
+		a.Held = append(a.Held, *msg)
+		if len(a.Held) > maxHeldMessages {
+			a.Held = a.Held[len(a.Held)-maxHeldMessages:]
+		}
+	}
+	err = b.store.Set(chatApprovalBucket, msg.ChatID, a)
+	b.approvalMu.Unlock()
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to hold message from %s: %v", msg.ChatID, err)
+	}
+	logging.Printf(ctx, "[Bridge] Holding message from %s until the group is approved (%d held)", msg.ChatID, len(a.Held))
+	metrics.Inc("messages_skipped", "reason", "chat_pending")
+
+	if !found {
+		b.requestApproval(ctx, msg.ChatID, a)
+	}
+	return true
+}
+
+// heldAlready reports whether a redelivered message is already held
+func heldAlready(held []feishu.Message, messageID string) bool {
+	if messageID == "" {
+		return false
+	}
+	for _, m := range held {
+		if m.MessageID == messageID {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleBotAdded asks the admins to approve a group the bot was just
+// added to. A group rejected earlier is asked about again.
+func (b *Bridge) HandleBotAdded(ctx context.Context, added *feishu.BotAdded) {
+	if !b.needsApproval("group", added.ChatID) {
+		return
+	}
+
+	b.approvalMu.Lock()
+	var a chatApproval
+	found, err := b.store.Get(chatApprovalBucket, added.ChatID, &a)
+	if err != nil {
+		b.approvalMu.Unlock()
+		logging.Printf(ctx, "[Bridge] Failed to read approval of %s: %v", added.ChatID, err)
+		return
+	}
+	if found && a.Status != approvalRejected {
+		b.approvalMu.Unlock()
+		return
+	}
+	a = chatApproval{Status: approvalPending, Name: added.ChatName, RequestedBy: added.OperatorID, Requested: time.Now()}
+	err = b.store.Set(chatApprovalBucket, added.ChatID, a)
+	b.approvalMu.Unlock()
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to save approval request for %s: %v", added.ChatID, err)
+		return
+	}
+	b.requestApproval(ctx, added.ChatID, a)
+}
+
+// requestApproval sends the approval card to the admin chat and tells
+// the group it's waiting
+func (b *Bridge) requestApproval(ctx context.Context, chatID string, a chatApproval) {
+	logging.Printf(ctx, "[Bridge] Requesting approval for group %s", a.label(chatID))
+	if _, err := b.feishuClient.SendCard(b.cfg.Approval.AdminChat, approvalCard(chatID, a)); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to send approval card: %v", err)
+	}
+	if _, err := b.feishuClient.SendMessage(chatID, "本群尚未开通 AI 助手，已通知管理员审批。审批通过后会回复本群暂存的消息。"); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to tell %s it awaits approval: %v", chatID, err)
+	}
+}
+
+// approvalCard asks the admins to approve or reject a group
+func approvalCard(chatID string, a chatApproval) *feishu.Card {
+	card := feishu.NewCard("新群聊待审批", "orange").
+		AddMarkdown(fmt.Sprintf("**群聊**：%s", a.label(chatID)))
+	if a.RequestedBy != "" {
+		card.AddMarkdown(fmt.Sprintf("**发起人**：<at id=%s></at>", a.RequestedBy))
+	}
+	return card.
+		AddNote(fmt.Sprintf("审批前该群的消息会暂存（最多 %d 条），批准后依次回复；拒绝后不再回复该群。", maxHeldMessages)).
+		AddButtons(
+			feishu.CardButton{Text: "批准", Type: "primary", Value: map[string]interface{}{"action": "approve_chat", "chat_id": chatID}},
+			feishu.CardButton{Text: "拒绝", Type: "danger", Value: map[string]interface{}{"action": "reject_chat", "chat_id": chatID}},
+		)
+}
+
+// decidedCard replaces the approval card once an admin has decided
+func decidedCard(chatID string, a chatApproval) *feishu.Card {
+	title, template, verdict := "群聊已批准", "green", "批准"
+	if a.Status == approvalRejected {
+		title, template, verdict = "群聊已拒绝", "grey", "拒绝"
+	}
+	return feishu.NewCard(title, template).
+		AddMarkdown(fmt.Sprintf("**群聊**：%s", a.label(chatID))).
+		AddNote(fmt.Sprintf("<at id=%s></at> 于 %s %s", a.DecidedBy, a.Decided.Format("2006-01-02 15:04"), verdict))
+}
+
+// actionApproveChat handles the approve button of an approval card
+func actionApproveChat(ctx context.Context, b *Bridge, action *feishu.CardAction) (string, error) {
+	return b.decideChat(ctx, action, approvalApproved)
+}
+
+// actionRejectChat handles the reject button of an approval card
+func actionRejectChat(ctx context.Context, b *Bridge, action *feishu.CardAction) (string, error) {
+	return b.decideChat(ctx, action, approvalRejected)
+}
+
+// decideChat records an admin's decision on a group, answers the held
+// messages if it was approved, and updates the card
+func (b *Bridge) decideChat(ctx context.Context, action *feishu.CardAction, status string) (string, error) {
+	chatID := actionString(action, "chat_id")
+	auditAction := "chat.approve"
+	if status == approvalRejected {
+		auditAction = "chat.reject"
+	}
+	if !b.cfg.IsAdmin(action.OperatorID) {
+		b.audit.Record(ctx, audit.Event{Action: auditAction, Actor: action.OperatorID, Target: chatID, Outcome: audit.Denied, Detail: "not an admin"})
+		return "", fmt.Errorf("只有管理员可以审批群聊")
+	}
+
+	b.approvalMu.Lock()
+	var a chatApproval
+	found, err := b.store.Get(chatApprovalBucket, chatID, &a)
+	if err != nil {
+		b.approvalMu.Unlock()
+		return "", err
+	}
+	if !found {
+		b.approvalMu.Unlock()
+		return "", fmt.Errorf("未找到该群的审批请求")
+	}
+	if a.Status != approvalPending {
+		b.approvalMu.Unlock()
+		return "该群已审批过", nil
+	}
+	held := a.Held
+	a.Status, a.DecidedBy, a.Decided, a.Held = status, action.OperatorID, time.Now(), nil
+	err = b.store.Set(chatApprovalBucket, chatID, a)
+	b.approvalMu.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	logging.Printf(ctx, "[Bridge] %s %s group %s", action.OperatorID, status, a.label(chatID))
+	b.audit.Record(ctx, audit.Event{Action: auditAction, Actor: action.OperatorID, ChatID: chatID, Target: chatID,
+		Detail: fmt.Sprintf("%d held messages", len(held))})
+	if action.MessageID != "" {
+		if err := b.feishuClient.UpdateCard(action.MessageID, decidedCard(chatID, a)); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to update approval card: %v", err)
+		}
+	}
+
+	if status == approvalRejected {
+		if _, err := b.feishuClient.SendMessage(chatID, "管理员未批准在本群使用 AI 助手。"); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to tell %s it was rejected: %v", chatID, err)
+		}
+		return "已拒绝", nil
+	}
+
+	if _, err := b.feishuClient.SendMessage(chatID, "管理员已批准，本群已开通 AI 助手。"); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to tell %s it was approved: %v", chatID, err)
+	}
+	go b.replayHeld(chatID, held)
+	return fmt.Sprintf("已批准，正在处理 %d 条暂存消息", len(held)), nil
+}
+
+// replayHeld handles messages held while their group waited for approval
+func (b *Bridge) replayHeld(chatID string, held []feishu.Message) {
+	for i := range held {
+		ctx := logging.NewContext(context.Background())
+		logging.SetChatID(ctx, chatID)
+		if err := b.HandleMessage(ctx, &held[i]); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to handle held message %s: %v", held[i].MessageID, err)
+		}
+	}
+}
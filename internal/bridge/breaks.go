@@ -0,0 +1,73 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// breakMarker is what the agent writes between parts of a reply that
+// should go out as separate messages
+const breakMarker = "[[break]]"
+
+// messageBreak matches breakMarker with the blank space around it
+var messageBreak = regexp.MustCompile(`\s*` + regexp.QuoteMeta(breakMarker) + `\s*`)
+
+const messageBreakInstructions = "回答可以分成几条消息发送：先给出简短的结论，再在下一条消息中展开细节或附录时，在两部分之间单独一行写 " +
+	breakMarker + "。只在确实需要分开时使用，这个标记不会显示给用户。"
+
+// splitMessages splits text at message breaks into at most max parts,
+// the last keeping whatever is past the limit. Empty parts are dropped,
+// and a break text ends partway through while streaming is cut off.
+// There's always at least one part.
+func splitMessages(text string, max int) []string {
+	if i := strings.LastIndex(text, "[["); i >= 0 && !strings.Contains(text[i:], "]]") && strings.HasPrefix(breakMarker, text[i:]) {
+		text = text[:i]
+	}
+	var parts []string
+	for _, part := range messageBreak.Split(text, -1) {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return []string{""}
+	}
+	if max > 0 && len(parts) > max {
+		parts = append(parts[:max-1], strings.Join(parts[max-1:], "\n\n"))
+	}
+	return parts
+}
+
+// finishPart makes messageID, the message a part of the reply streamed
+// into, show the part in full, or sends the part anew when there's no
+// such message. It returns the ID of the message the part ended up in.
+func (b *Bridge) finishPart(ctx context.Context, chatID, messageID string, post bool, part string) string {
+	if messageID == "" {
+		msgID, _, err := b.sendReply(chatID, part)
+		if err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to send reply part: %v", err)
+			b.observeDelivery(ctx, err)
+		}
+		return msgID
+	}
+	if !post && feishu.NeedsPost(part) {
+		if err := b.feishuClient.DeleteMessage(messageID); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to delete plain streaming message: %v", err)
+		}
+		return b.finishPart(ctx, chatID, "", false, part)
+	}
+	err := b.editReply(ctx, messageID, post, part)
+	if errors.Is(err, feishu.ErrNotEditable) {
+		messageID, _, err = b.moveReply(ctx, chatID, messageID, part)
+	}
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to finish reply part: %v", err)
+		b.observeDelivery(ctx, err)
+	}
+	return messageID
+}
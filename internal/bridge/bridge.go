@@ -1,105 +1,287 @@
 package bridge
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/wy51ai/moltbotCNAPP/internal/clawdbot"
-	"github.com/wy51ai/moltbotCNAPP/internal/feishu"
+	"github.com/wy51ai/moltbotCNAPP/internal/alert"
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/backend"
+	"github.com/wy51ai/moltbotCNAPP/internal/cluster"
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/diagram"
+	"github.com/wy51ai/moltbotCNAPP/internal/errreport"
+	"github.com/wy51ai/moltbotCNAPP/internal/events"
+	"github.com/wy51ai/moltbotCNAPP/internal/linkpreview"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/media"
+	"github.com/wy51ai/moltbotCNAPP/internal/metrics"
+	"github.com/wy51ai/moltbotCNAPP/internal/retention"
+	"github.com/wy51ai/moltbotCNAPP/internal/seal"
+	"github.com/wy51ai/moltbotCNAPP/internal/store"
+	"github.com/wy51ai/moltbotCNAPP/internal/tracing"
+	"github.com/wy51ai/moltbotCNAPP/internal/transcript"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Bridge connects Feishu and ClawdBot
 type Bridge struct {
-	feishuClient   *feishu.Client
-	clawdbotClient *clawdbot.Client
-	thinkingMs     int
-	sessionKey     string
-	seenMessages   *messageCache
-}
+	feishuClient MessageSender
+	router       *backend.Router
+	store        store.KV
+	shared       store.Shared
+	instance     string
+	cfg          *config.Config
+	thinkingMs   int
+	sessionKey   string
+	shadow       *shadowRunner
+	audit        *audit.Log
+	alerts       *alert.Alerter
+	runErrors    *errorWindow
+	transcripts  *transcript.Store
+	latency      *latencyTracker
+	queue        *runQueue
+	pacer        *updatePacer
+	diagrams     *diagram.Renderer
+	charts       *diagram.Charts
+	images       *media.Finder
+	math         *diagram.Math
+	links        *linkpreview.Fetcher
+	events       *events.Log
+	cluster      *cluster.Node
+	inflight     atomic.Int32
+	// started is when the bridge was created; served and gatewayErrors
+	// count messages answered and failed gateway runs since
+	started       time.Time
+	served        atomic.Int64
+	gatewayErrors atomic.Int64
+	// version is the bridge build, shown in the startup card
+	version string
+	// aliases are the custom commands from bridge.json
+	aliases atomic.Pointer[map[string]config.Alias]
+	// wiki is the index of the configured wiki spaces, once built
+	wiki atomic.Pointer[wikiIndex]
+	// approvalMu serializes updates to group approval state
+	approvalMu sync.Mutex
+	// pollMu serializes updates to polls
+	pollMu sync.Mutex
+	// surveyMu serializes updates to satisfaction survey state
+	surveyMu sync.Mutex
+	// ticketMu guards runFailures, each user's consecutive failed
+	// requests per chat
+	ticketMu    sync.Mutex
+	runFailures map[string]int
+	// timezones caches the timezone in each user's Feishu profile, by
+	// open_id
+	timezones sync.Map
+	// keys seals conversation text kept in the state store and
+	// shadow records; keysErr is why it didn't load when configured
+	keys    *seal.Keyring
+	keysErr error
 
-// messageCache stores seen message IDs to prevent duplicate processing
-type messageCache struct {
-	cache map[string]time.Time
-	mu    sync.RWMutex
-	ttl   time.Duration
+	// Background loops started by Start and stopped by Close
+	stop  context.CancelFunc
+	loops sync.WaitGroup
 }
 
-func newMessageCache(ttl time.Duration) *messageCache {
-	mc := &messageCache{
-		cache: make(map[string]time.Time),
-		ttl:   ttl,
+// NewBridge creates a new bridge. st keeps per-chat settings and shared
+// holds dedupe, rate-limit and run state; point both at Redis when several
+// instances serve the same app.
+func NewBridge(feishuClient MessageSender, router *backend.Router, st store.KV, shared store.Shared, cfg *config.Config) *Bridge {
+	b := &Bridge{
+		feishuClient: feishuClient,
+		router:       router,
+		store:        st,
+		cfg:          cfg,
+		thinkingMs:   cfg.Feishu.ThinkingThresholdMs,
+		sessionKey:   cfg.Clawdbot.SessionKey,
+		shared:       shared,
+		instance:     instanceID(),
+		started:      time.Now(),
+		pacer:        newUpdatePacer(cfg.Streaming),
+		diagrams:     diagram.New(cfg.Mermaid),
+		charts:       diagram.NewCharts(cfg.Charts),
+		images:       media.New(cfg.Images),
+		math:         diagram.NewMath(cfg.Math),
+		links:        linkpreview.New(cfg.LinkPreview),
 	}
+	b.SetAliases(cfg.Aliases)
 
-	// Start cleanup goroutine
-	go mc.cleanup()
+	// Without its keys, recording would either fail or fall back to
+	// plaintext, so transcripts, shadow records, dead letters and retry
+	// prompts are off until the keyring loads
+	b.keys, b.keysErr = seal.FromConfig(cfg.Encryption)
 
-	return mc
-}
+	if name := cfg.Shadow.Backend; name != "" && cfg.Shadow.Percent > 0 && b.keysErr == nil {
+		if agent, ok := router.Backend(name); ok {
+			b.shadow = newShadowRunner(name, agent, cfg.Shadow.Percent, cfg.Shadow.Dir, b.keys)
+			log.Printf("[Bridge] Shadowing %.1f%% of messages to backend %s", cfg.Shadow.Percent, name)
+		}
+	}
 
-func (mc *messageCache) has(messageID string) bool {
-	mc.mu.RLock()
-	defer mc.mu.RUnlock()
+	auditLog, err := audit.Open(cfg.Audit.Dir)
+	if err != nil {
+		log.Printf("[Bridge] Audit log disabled: %v", err)
+	}
+	b.audit = auditLog
 
-	_, exists := mc.cache[messageID]
-	return exists
-}
+	err = b.keysErr
+	if err == nil {
+		b.transcripts, err = transcript.Open(cfg.Transcripts.Dir, cfg.Transcripts.StoreContent, b.keys)
+	}
+	if err != nil {
+		log.Printf("[Bridge] Transcripts disabled: %v", err)
+	}
+
+	eventLog, err := events.Open(cfg.Events.Dir)
+	if err != nil {
+		log.Printf("[Bridge] Event log disabled: %v", err)
+	}
+	b.events = eventLog
+
+	b.latency = newLatencyTracker(cfg.SLO.Window)
+	b.queue = newRunQueue(cfg.Queue)
 
-func (mc *messageCache) add(messageID string) {
-	mc.mu.Lock()
-	defer mc.mu.Unlock()
+	b.alerts = alert.New(cfg.Alerts, b.sendAlert, shared)
+	b.runErrors = newErrorWindow(cfg.Alerts.ErrorWindow)
 
-	mc.cache[messageID] = time.Now()
+	return b
 }
 
-func (mc *messageCache) cleanup() {
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
+// Start runs the bridge's background work (SLO tracking, the weekly
+// digest, usage reports, satisfaction surveys, the cluster inbox, approval outcomes, the wiki
+// index, data retention) until ctx ends or Close is called. Messages can be handled
+// without it, but nothing periodic happens.
+func (b *Bridge) Start(ctx context.Context) {
+	ctx, b.stop = context.WithCancel(ctx)
 
-	for range ticker.C {
-		mc.mu.Lock()
-		now := time.Now()
-		for id, timestamp := range mc.cache {
-			if now.Sub(timestamp) > mc.ttl {
-				delete(mc.cache, id)
-			}
-		}
-		mc.mu.Unlock()
+	b.spawn(func() { b.sloLoop(ctx) })
+	if b.cfg.Alerts.HeartbeatURL != "" {
+		b.spawn(func() { b.heartbeatLoop(ctx) })
+	}
+	if b.cfg.Alerts.StartupCard {
+		b.spawn(func() { b.startupCardLoop(ctx) })
+	}
+	if b.cfg.Analytics.DigestChat != "" && b.transcripts != nil {
+		b.spawn(func() { b.digestLoop(ctx) })
+	}
+	if len(b.cfg.Analytics.Reports) > 0 && b.transcripts != nil {
+		b.spawn(func() { b.reportLoop(ctx) })
+	}
+	if b.cfg.Survey.Enabled && b.transcripts != nil {
+		b.spawn(func() { b.surveyLoop(ctx) })
+	}
+	if b.cluster != nil {
+		b.spawn(func() { b.cluster.Run(ctx, b.receiveForwarded) })
+	}
+	if b.cfg.Approvals.Enabled {
+		b.spawn(func() { b.approvalLoop(ctx) })
+	}
+	if b.cfg.Wiki.Enabled {
+		b.spawn(func() { b.wikiLoop(ctx) })
+	}
+	b.spawn(func() { retention.Run(ctx, retention.Targets(b.cfg), time.Hour) })
+	if b.cfg.DeadLetters.RetentionDays > 0 {
+		b.spawn(func() { b.deadLetterRetentionLoop(ctx) })
 	}
 }
 
-// NewBridge creates a new bridge
-func NewBridge(feishuClient *feishu.Client, clawdbotClient *clawdbot.Client, thinkingMs int, sessionKey string) *Bridge {
-	return &Bridge{
-		feishuClient:   feishuClient,
-		clawdbotClient: clawdbotClient,
-		thinkingMs:     thinkingMs,
-		sessionKey:     sessionKey,
-		seenMessages:   newMessageCache(10 * time.Minute),
+// Close stops the background work started by Start and waits for it to
+// exit. It doesn't wait for in-flight messages; call Drain first for that.
+func (b *Bridge) Close() error {
+	if b.stop != nil {
+		b.stop()
 	}
+	b.loops.Wait()
+	return nil
+}
+
+// spawn runs fn as a background loop tracked by Close
+func (b *Bridge) spawn(fn func()) {
+	b.loops.Add(1)
+	go func() {
+		defer b.loops.Done()
+		fn()
+	}()
+}
+
+// SetVersion sets the bridge build version shown in the startup card
+func (b *Bridge) SetVersion(version string) {
+	b.version = version
 }
 
 // SetFeishuClient sets the Feishu client after construction
-func (b *Bridge) SetFeishuClient(client *feishu.Client) {
+func (b *Bridge) SetFeishuClient(client MessageSender) {
 	b.feishuClient = client
+	client.OnCircuitChange(b.circuitChanged)
+	if w, ok := client.(connectionWatcher); ok {
+		w.OnConnectionChange(b.connectionChanged)
+	}
+	if d, ok := client.(replyDropper); ok {
+		d.OnReplyDropped(b.replyDropped)
+	}
 }
 
-// HandleMessage processes a message from Feishu
-func (b *Bridge) HandleMessage(msg *feishu.Message) error {
-	// Check for duplicates
-	if msg.MessageID != "" && b.seenMessages.has(msg.MessageID) {
-		log.Printf("[Bridge] Skipping duplicate message: %s", msg.MessageID)
+// HandleMessage processes a message from Feishu, or forwards it to the
+// instance that owns its chat when chats are partitioned
+func (b *Bridge) HandleMessage(ctx context.Context, msg *feishu.Message) error {
+	if b.holdForApproval(ctx, msg) {
 		return nil
 	}
+	if b.forwardToOwner(ctx, msg) {
+		return nil
+	}
+	return b.handleMessage(ctx, msg)
+}
+
+// handleMessage processes a message on this instance
+func (b *Bridge) handleMessage(ctx context.Context, msg *feishu.Message) error {
+	// Processing outlives the Feishu event callback; keep only its values
+	ctx = context.WithoutCancel(ctx)
+
+	// Root span covering receipt through delivery; ended by processMessage
+	// unless the message is dropped or handled as a command here
+	ctx, span := tracing.Start(ctx, "feishu.message",
+		attribute.String("bridge.correlation_id", logging.CorrelationID(ctx)),
+		attribute.String("feishu.message_id", msg.MessageID),
+		attribute.String("feishu.chat_id", msg.ChatID),
+		attribute.String("feishu.chat_type", msg.ChatType),
+	)
+	metrics.Inc("messages_received", "chat_type", msg.ChatType)
+	skip := func(reason string) {
+		metrics.Inc("messages_skipped", "reason", reason)
+		span.SetAttributes(attribute.String("bridge.skipped", reason))
+		span.End()
+	}
 
-	// Mark as seen
+	// Check for duplicates; the claim is shared so only one instance
+	// handles a redelivered event
 	if msg.MessageID != "" {
-		b.seenMessages.add(msg.MessageID)
+		first, err := b.shared.Claim(ctx, "msg:"+msg.MessageID, dedupeTTL)
+		if err != nil {
+			// Prefer answering twice over not answering at all
+			logging.Printf(ctx, "[Bridge] Dedupe check failed, processing anyway: %v", err)
+		} else if !first {
+			logging.Printf(ctx, "[Bridge] Skipping duplicate message: %s", msg.MessageID)
+			skip("duplicate")
+			return nil
+		}
 	}
+	b.events.Emit(ctx, events.Event{
+		Type:   events.MessageIn,
+		Fields: map[string]interface{}{"message_id": msg.MessageID, "chat_type": msg.ChatType, "sender": msg.SenderID},
+	})
 
 	// Clean up message text
 	text := msg.Content
@@ -107,39 +289,195 @@ func (b *Bridge) HandleMessage(msg *feishu.Message) error {
 	text = strings.TrimSpace(text)
 
 	if text == "" {
+		skip("empty")
+		return nil
+	}
+
+	// Private chats may need an invite code before anything but /激活
+	if !isActivate(text) && b.awaitingInvite(ctx, msg) {
+		logging.Printf(ctx, "[Bridge] Skipping message from %s (not activated)", msg.SenderID)
+		if _, err := b.feishuClient.SendMessage(msg.ChatID, inviteRequiredReply); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to send invite prompt: %v", err)
+		}
+		skip("not_activated")
+		return nil
+	}
+
+	// Aliases from bridge.json stand for a prompt or a bridge command and,
+	// like commands, need no trigger in groups
+	aliased := false
+	if expanded, ok := b.expandAlias(text); ok {
+		logging.Printf(ctx, "[Bridge] Expanding alias %s", strings.Fields(text)[0])
+		metrics.Inc("aliases", "alias", commandName(text))
+		text, aliased = expanded, true
+	}
+
+	// Bridge commands are handled locally, even in groups without a trigger
+	if cmd, args, ok := parseCommand(text); ok {
+		logging.Printf(ctx, "[Bridge] Running command from %s: %s", msg.ChatID, text)
+		span.SetAttributes(attribute.String("bridge.command", strings.Fields(text)[0]))
+		metrics.Inc("commands", "command", commandName(text))
+		b.events.Emit(ctx, events.Event{Type: events.Command, Detail: commandName(text)})
+		b.transcripts.Append(transcript.Record{
+			Kind:          transcript.KindCommand,
+			ChatID:        msg.ChatID,
+			ChatType:      msg.ChatType,
+			UserID:        msg.SenderID,
+			Command:       commandName(text),
+			CorrelationID: logging.CorrelationID(ctx),
+		})
+		go func() {
+			defer span.End()
+			defer errreport.Recover(ctx, "command")
+			b.runCommand(ctx, msg, cmd, args)
+		}()
+		return nil
+	}
+
+	// Pick the backend; a routed command prefix counts as a trigger, as
+	// does a priority prefix
+	prompt, urgent := cutPriorityPrefix(b.cfg.Queue.PriorityPrefixes, text)
+	backendName, agent, routed := b.router.Route(msg.ChatID, prompt)
+	req := &runRequest{
+		ctx:         ctx,
+		received:    time.Now(),
+		chatID:      msg.ChatID,
+		chatType:    msg.ChatType,
+		senderID:    msg.SenderID,
+		text:        routed,
+		backendName: backendName,
+		agent:       agent,
+		sessionKey:  b.sessionKeyFor(msg.ChatID),
+		msg:         msg,
+		priority:    urgent || b.cfg.Queue.PriorityChat(msg.ChatID),
+	}
+	addressed := routed != text || aliased
+
+	// "@代码助手 ..." addresses a specific agent, which also counts as a trigger
+	if name, rest, ok := b.resolveAgent(msg.ChatID, req.text); ok {
+		b.bindAgent(req, name)
+		req.text = strings.TrimSpace(rest)
+		addressed = true
+	} else if sel, ok := b.chatAgent(ctx, msg.ChatID); ok && routed == text {
+		// Chat default agent chosen via /agents
+		if sel.Name != "" {
+			b.bindAgent(req, sel.Name)
+			req.agentName = ""
+		} else {
+			bindAgentID(req, sel.AgentID)
+		}
+	} else {
+		// Nothing picked the agent, so the conversation's tag may
+		req.tagRoutable = routed == prompt
+	}
+	if req.text == "" {
+		skip("empty")
 		return nil
 	}
 
 	// For group chats, check if we should respond
-	if msg.ChatType == "group" {
+	if msg.ChatType == "group" && !addressed {
 		if !shouldRespondInGroup(text, msg.Mentions) {
-			log.Printf("[Bridge] Skipping group message (no trigger): %s", text)
+			logging.Printf(ctx, "[Bridge] Skipping group message (no trigger): %s", text)
+			skip("no_trigger")
 			return nil
 		}
 	}
+	span.SetAttributes(
+		attribute.String("bridge.backend", req.backendName),
+		attribute.String("bridge.agent", req.agentName),
+	)
 
-	log.Printf("[Bridge] Processing message from %s: %s", msg.ChatID, text)
+	logging.Printf(ctx, "[Bridge] Processing message from %s via %s (agent=%s): %s", msg.ChatID, req.backendName, req.agentName, req.text)
+
+	// Take a slot or a place in the queue now, so the chat's overflow
+	// policy sees the messages in the order they arrived
+	w, admission := b.queue.enter(req)
+	switch admission {
+	case runRejected:
+		logging.Printf(ctx, "[Bridge] Rejecting message in %s, chat at its run limit", msg.ChatID)
+		if _, err := b.feishuClient.SendMessage(msg.ChatID, chatBusyReply); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to send busy notice: %v", err)
+		}
+		skip("chat_busy")
+		return nil
+	case runMerged:
+		logging.Printf(ctx, "[Bridge] Merged message into the waiting one in %s", msg.ChatID)
+		metrics.Inc("runs_merged", "backend", w.req.backendName)
+		skip("merged")
+		return nil
+	case runQueued:
+		req.queued = w
+	}
 
 	// Process asynchronously
-	go b.processMessage(msg.ChatID, text)
+	inflight := b.inflight.Add(1)
+	metrics.Set("inflight", float64(inflight))
+	b.observeQueue(int(inflight))
+	go b.processMessage(req)
 
 	return nil
 }
 
-func (b *Bridge) processMessage(chatID, text string) {
+func (b *Bridge) processMessage(req *runRequest) {
+	ctx := req.ctx
+	defer trace.SpanFromContext(ctx).End()
+	defer errreport.Recover(ctx, "processMessage")
+
+	defer b.trackRun(ctx, req)()
+
+	// Counted in handleMessage so Drain can't miss a run that hasn't started yet
+	defer func() {
+		metrics.Set("inflight", float64(b.inflight.Add(-1)))
+		b.recordResponse(req, time.Since(req.received))
+		b.served.Add(1)
+		metrics.Inc("messages_served")
+	}()
+
+	// The conversation's tag may pick the agent, so it comes first
+	if b.cfg.Classify.Enabled {
+		b.tagRun(ctx, req)
+	}
+
+	chatID := req.chatID
+	// Reasoning goes to the placeholder only in chats that asked for it
+	showThoughts := b.showThoughts(chatID)
+	// Chats in final-only mode see nothing until the answer is complete
+	finalOnly := b.finalOnly(chatID)
+	// With gateway delivery the gateway posts the reply itself, so the
+	// bridge only shows progress and reports failures
+	gatewayDelivers := b.cfg.GatewayDelivers(req.backendName)
 	var placeholderID string
 	var responseMessageID string
+	// responsePost is set when the response message is rich text
+	var responsePost bool
+	// finishedParts counts the parts of a reply split by message breaks
+	// already sent in full
+	var finishedParts int
 	var done bool
-	var thinkingDots int
+	// progress drives the thinking placeholder; guarded by mu
+	progress := newRunProgress(req.received)
 	var mu sync.Mutex
 
+	// Wait for a free slot when runs are limited; the placeholder shows
+	// the queue position meanwhile and the thinking state afterwards
+	placeholderID = b.waitForSlot(ctx, req)
+	defer b.queue.leave(chatID)
+	// Messages merged in while waiting are part of the text now
+	text := req.text
+	if placeholderID != "" {
+		if err := b.feishuClient.UpdateMessage(placeholderID, progress.placeholder(time.Now())); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to update queue placeholder: %v", err)
+		}
+	}
+
 	// Dynamic thinking animation ticker
 	var thinkingTicker *time.Ticker
 	var thinkingStop chan bool
 
 	// Show "thinking..." if response takes too long
 	var timer *time.Timer
-	if b.thinkingMs > 0 {
+	if b.thinkingMs > 0 && !finalOnly {
 		timer = time.AfterFunc(time.Duration(b.thinkingMs)*time.Millisecond, func() {
 			mu.Lock()
 			defer mu.Unlock()
@@ -148,14 +486,15 @@ func (b *Bridge) processMessage(chatID, text string) {
 				return
 			}
 
-			// Send initial thinking message
-			msgID, err := b.feishuClient.SendMessage(chatID, "正在思考.")
-			if err != nil {
-				log.Printf("[Bridge] Failed to send thinking message: %v", err)
-				return
+			// Send initial thinking message, unless the queue left one
+			if placeholderID == "" {
+				msgID, err := b.feishuClient.SendMessage(chatID, progress.placeholder(time.Now()))
+				if err != nil {
+					logging.Printf(ctx, "[Bridge] Failed to send thinking message: %v", err)
+					return
+				}
+				placeholderID = msgID
 			}
-			placeholderID = msgID
-			thinkingDots = 1
 
 			// Start thinking animation
 			thinkingStop = make(chan bool)
@@ -169,14 +508,9 @@ func (b *Bridge) processMessage(chatID, text string) {
 							mu.Unlock()
 							return
 						}
-						
-						// Cycle through 1, 2, 3 dots
-						thinkingDots = (thinkingDots % 3) + 1
-						dots := strings.Repeat(".", thinkingDots)
-						thinkingText := "正在思考" + dots
-						
-						if err := b.feishuClient.UpdateMessage(placeholderID, thinkingText); err != nil {
-							log.Printf("[Bridge] Failed to update thinking animation: %v", err)
+
+						if err := b.feishuClient.UpdateMessage(placeholderID, progress.placeholder(time.Now())); err != nil {
+							logging.Printf(ctx, "[Bridge] Failed to update thinking animation: %v", err)
 						}
 						mu.Unlock()
 					case <-thinkingStop:
@@ -190,11 +524,74 @@ func (b *Bridge) processMessage(chatID, text string) {
 	// Stream buffer for accumulating response
 	var streamBuffer strings.Builder
 	var lastUpdateTime time.Time
-	updateInterval := 300 * time.Millisecond // Update every 300ms
+	// endStreaming releases this reply's share of the edit budget
+	endStreaming := func() {}
+	defer func() {
+		mu.Lock()
+		defer mu.Unlock()
+		endStreaming()
+	}()
+
+	// The run is cancelled if the agent calls a tool the chat may not use
+	policyCtx := b.withToolPolicy(ctx, chatID)
+	runCtx, cancelRun := context.WithCancel(policyCtx)
+	defer cancelRun()
+	var blockedTool string
+	// toolImages are images named in tool results, sent after the reply
+	var toolImages []string
+	activity := &toolActivity{}
 
 	// Progress callback for streaming
 	onProgress := func(stream, data string) {
-		if stream != "assistant" {
+		if stream == backend.StreamToolCall {
+			// Surface tool use in the thinking placeholder
+			var toolData struct {
+				Name string `json:"name,omitempty"`
+			}
+			if err := json.Unmarshal([]byte(data), &toolData); err == nil && toolData.Name != "" {
+				permitted := b.toolPermitted(chatID, toolData.Name)
+				activity.call(data, !permitted)
+				if !permitted {
+					mu.Lock()
+					first := blockedTool == ""
+					if first {
+						blockedTool = toolData.Name
+					}
+					mu.Unlock()
+					if first {
+						b.recordToolBlocked(ctx, req, toolData.Name)
+						cancelRun()
+					}
+					return
+				}
+				b.events.Emit(ctx, events.Event{Type: events.ToolCall, Backend: req.backendName, Detail: toolData.Name})
+				mu.Lock()
+				progress.toolCall(toolData.Name)
+				mu.Unlock()
+			}
+			return
+		}
+		if stream == backend.StreamToolResult {
+			activity.result(data)
+			mu.Lock()
+			progress.toolResult()
+			mu.Unlock()
+			if refs := b.images.Find(jsonText(data)); len(refs) > 0 {
+				mu.Lock()
+				toolImages = append(toolImages, refs...)
+				mu.Unlock()
+			}
+			return
+		}
+		if stream == backend.StreamThought && showThoughts {
+			if thought, replace := thoughtText(data); thought != "" {
+				mu.Lock()
+				progress.think(thought, replace)
+				mu.Unlock()
+			}
+			return
+		}
+		if stream != backend.StreamAssistant {
 			return
 		}
 
@@ -211,7 +608,7 @@ func (b *Bridge) processMessage(chatID, text string) {
 			Delta string `json:"delta,omitempty"`
 		}
 		if err := json.Unmarshal([]byte(data), &streamData); err != nil {
-			log.Printf("[Bridge] Failed to parse stream data: %v", err)
+			logging.Printf(ctx, "[Bridge] Failed to parse stream data: %v", err)
 			return
 		}
 
@@ -226,65 +623,175 @@ func (b *Bridge) processMessage(chatID, text string) {
 		}
 
 		currentText := streamBuffer.String()
-		if currentText == "" {
+		if b.directivesEnabled() {
+			currentText = stripDirectives(currentText)
+		}
+		if currentText == "" || gatewayDelivers || finalOnly {
 			return
 		}
+		// Parts before a message break are complete; each is finished in
+		// its own message and the rest streams into a new one
+		if b.cfg.MessageBreaks.Enabled {
+			parts := splitMessages(currentText, b.cfg.MessageBreaks.MaxMessages)
+			for ; finishedParts < len(parts)-1; finishedParts++ {
+				if responseMessageID == "" && placeholderID != "" {
+					if err := b.feishuClient.DeleteMessage(placeholderID); err != nil {
+						logging.Printf(ctx, "[Bridge] Failed to delete thinking placeholder: %v", err)
+					}
+					placeholderID = ""
+				}
+				b.finishPart(ctx, chatID, responseMessageID, responsePost, req.tagReply(parts[finishedParts]))
+				responseMessageID, responsePost = "", false
+				endStreaming()
+				endStreaming = func() {}
+			}
+			if currentText = parts[len(parts)-1]; currentText == "" {
+				return
+			}
+		}
+		// A reply this long will be sent as a file; stop editing it here
+		if b.tooLong(currentText) {
+			if responseMessageID != "" {
+				return
+			}
+			currentText = summarize(currentText, b.cfg.LongReply.SummaryChars)
+		}
 
 		// First chunk - delete thinking message and create response message
 		if responseMessageID == "" {
+			progress.replying()
+
 			// Stop thinking animation
 			if thinkingTicker != nil {
 				thinkingTicker.Stop()
 				if thinkingStop != nil {
 					close(thinkingStop)
 				}
+				thinkingTicker, thinkingStop = nil, nil
 			}
 
 			// Delete thinking placeholder
 			if placeholderID != "" {
 				if err := b.feishuClient.DeleteMessage(placeholderID); err != nil {
-					log.Printf("[Bridge] Failed to delete thinking placeholder: %v", err)
+					logging.Printf(ctx, "[Bridge] Failed to delete thinking placeholder: %v", err)
 				}
 				placeholderID = ""
 			}
 
 			// Create new response message with first chunk
-			msgID, err := b.feishuClient.SendMessage(chatID, currentText)
+			msgID, post, err := b.sendReply(chatID, req.tagReply(withCursor(currentText)))
 			if err != nil {
-				log.Printf("[Bridge] Failed to create response message: %v", err)
+				logging.Printf(ctx, "[Bridge] Failed to create response message: %v", err)
+				b.observeDelivery(ctx, err)
 				return
 			}
-			responseMessageID = msgID
+			responseMessageID, responsePost = msgID, post
 			lastUpdateTime = time.Now()
+			endStreaming = b.pacer.begin()
 			return
 		}
 
-		// Throttle updates to avoid rate limiting
-		if time.Since(lastUpdateTime) < updateInterval {
+		// Throttle updates to avoid rate limiting; the pace slows as more
+		// replies stream at once or Feishu pushes back
+		if time.Since(lastUpdateTime) < b.pacer.interval() {
 			return
 		}
 
-		// Update existing message with accumulated content
-		if err := b.feishuClient.UpdateMessage(responseMessageID, currentText); err != nil {
-			log.Printf("[Bridge] Failed to update streaming message: %v", err)
-		} else {
+		// A text message can't turn into rich text, so once code shows up
+		// the reply moves to a post message
+		if !responsePost && feishu.NeedsPost(currentText) {
+			msgID, err := b.feishuClient.SendPost(chatID, req.tagReply(withCursor(currentText)))
+			b.pacer.observe(err)
 			lastUpdateTime = time.Now()
+			if err != nil {
+				logging.Printf(ctx, "[Bridge] Failed to switch streaming message to rich text: %v", err)
+				return
+			}
+			if err := b.feishuClient.DeleteMessage(responseMessageID); err != nil {
+				logging.Printf(ctx, "[Bridge] Failed to delete plain streaming message: %v", err)
+			}
+			responseMessageID, responsePost = msgID, true
+			return
 		}
-	}
 
-	// Ask ClawdBot with streaming
-	sessionKey := b.sessionKey
-	if sessionKey == "" {
-		sessionKey = fmt.Sprintf("feishu:%s", chatID)
+		// Update existing message with accumulated content
+		shown := req.tagReply(withCursor(currentText))
+		err := b.editReply(ctx, responseMessageID, responsePost, shown)
+		// Count failed edits too so a rejected update isn't retried on the very next chunk
+		lastUpdateTime = time.Now()
+		if errors.Is(err, feishu.ErrNotEditable) {
+			// Out of edits on this message; keep streaming into a new one
+			msgID, post, err := b.moveReply(ctx, chatID, responseMessageID, shown)
+			if err != nil {
+				logging.Printf(ctx, "[Bridge] Failed to move streaming message: %v", err)
+				return
+			}
+			responseMessageID, responsePost = msgID, post
+			return
+		}
+		if err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to update streaming message: %v", err)
+		}
 	}
-	log.Printf("[Bridge] sessionKey: %s", sessionKey)
+
+	// Ask the backend with streaming
+	sessionKey := req.sessionKey
+	logging.Printf(ctx, "[Bridge] sessionKey: %s", sessionKey)
+	// Pinned context, preferences and linked sheets go to the backend
+	// only; transcripts keep the message
+	prompt := b.promptFor(ctx, chatID, req.senderID, text)
 	
-	reply, err := b.clawdbotClient.AskClawdbot(text, sessionKey, onProgress)
-	log.Printf("[Bridge] reply: %s", reply)
+	// Mirror a sample of messages to the shadow backend
+	var shadowResult <-chan runResult
+	if b.shadow.sample() {
+		shadowResult = b.shadow.start(policyCtx, prompt, sessionKey)
+	}
+
+	_, runSpan := tracing.Start(ctx, "backend.run",
+		attribute.String("backend.name", req.backendName),
+		attribute.String("backend.session_key", sessionKey),
+	)
+	b.events.Emit(ctx, events.Event{Type: events.RunStart, Backend: req.backendName, Fields: map[string]interface{}{"agent": req.agentName, "session": sessionKey}})
+	primary, err := runAndMeasure(runCtx, req.backendName, req.agent, prompt, sessionKey, onProgress)
+	mu.Lock()
+	blocked := blockedTool
+	mu.Unlock()
+	if blocked != "" {
+		// Stopping a run on policy isn't a backend failure
+		err = nil
+		primary.Error = "blocked tool " + blocked
+	}
+	b.events.Emit(ctx, events.Event{
+		Type:    events.RunEnd,
+		Backend: req.backendName,
+		Detail:  primary.Error,
+		Fields: map[string]interface{}{
+			"latency_ms":    primary.LatencyMs,
+			"input_tokens":  primary.InputTokens,
+			"output_tokens": primary.OutputTokens,
+		},
+	})
+	runSpan.SetAttributes(
+		attribute.String("backend.run_id", primary.RunID),
+		attribute.Int("backend.input_tokens", primary.InputTokens),
+		attribute.Int("backend.output_tokens", primary.OutputTokens),
+	)
+	if err != nil {
+		runSpan.RecordError(err)
+		runSpan.SetStatus(codes.Error, err.Error())
+	}
+	runSpan.End()
+	reply := primary.Reply
+	logging.Printf(ctx, "[Bridge] reply: %s", reply)
+
+	if shadowResult != nil {
+		go b.shadow.record(ctx, chatID, text, primary, shadowResult)
+	}
 	
 	// Mark as done
 	mu.Lock()
 	done = true
+	progress.finish()
 	
 	// Stop thinking animation
 	if thinkingTicker != nil {
@@ -299,30 +806,78 @@ func (b *Bridge) processMessage(chatID, text string) {
 		timer.Stop()
 	}
 
-	if err != nil {
-		reply = fmt.Sprintf("（系统出错）%v", err)
-		log.Printf("[Bridge] Error from ClawdBot: %v", err)
+	if blocked != "" {
+		// The run was cancelled on purpose; whatever it streamed is replaced
+		reply = toolBlockedReply(blocked)
+	} else if errors.Is(err, backend.ErrGatewayUnavailable) {
+		// The breaker refused the run; this isn't worth a report per message
+		reply = gatewayUnavailableReply
+		logging.Printf(ctx, "[Bridge] Gateway unavailable, run skipped: %v", err)
+	} else if err != nil {
+		var kind backend.ErrorKind
+		kind, reply = runErrorReply(req.backendName, err)
+		logging.Printf(ctx, "[Bridge] Error from ClawdBot (%s): %v", kind, err)
+		errreport.Capture(ctx, fmt.Errorf("backend %s: %w", req.backendName, err))
+		b.events.Emit(ctx, events.Event{Type: events.Error, Backend: req.backendName, Detail: err.Error()})
+	}
+	b.observeRun(ctx, req.backendName, err)
+	b.escalateOnFailure(ctx, req, err != nil)
+	recordRunMetrics(primary)
+	b.transcripts.Append(transcript.Record{
+		Kind:          transcript.KindMessage,
+		ChatID:        chatID,
+		ChatType:      req.chatType,
+		UserID:        req.senderID,
+		Backend:       req.backendName,
+		Agent:         req.agentName,
+		LatencyMs:     primary.LatencyMs,
+		InputTokens:   primary.InputTokens,
+		OutputTokens:  primary.OutputTokens,
+		Error:         primary.Error,
+		RunID:         primary.RunID,
+		Tag:           req.tag,
+		CorrelationID: logging.CorrelationID(ctx),
+		Prompt:        text,
+		Reply:         primary.Reply,
+	})
+	if blocked == "" {
+		b.sampleForQA(ctx, req, text, primary)
 	}
 
 	// Clean up reply
 	reply = strings.TrimSpace(reply)
-	log.Printf("[Bridge] ClawdBot raw reply: %q", reply)
+	logging.Printf(ctx, "[Bridge] ClawdBot raw reply: %q", reply)
+	// Directives are for the bridge, not the chat; tasks created from
+	// them are noted under the reply
+	if err == nil && blocked == "" && b.directivesEnabled() {
+		b.applyMemory(ctx, req, reply)
+		notes := joinNotes(b.applyTasks(ctx, req, reply), b.applySheets(ctx, req, reply), b.applyApprovals(ctx, req, reply), b.applyGroups(ctx, req, reply), b.applyHelpdesk(ctx, req, reply))
+		reply = stripDirectives(reply)
+		if notes != "" {
+			reply = strings.TrimSpace(reply + "\n\n" + notes)
+		}
+	}
+
+	_, deliverSpan := tracing.Start(ctx, "feishu.deliver")
+	defer deliverSpan.End()
 
 	// Check for NO_REPLY
 	if reply == "" || reply == "NO_REPLY" {
-		log.Printf("[Bridge] Received NO_REPLY, not sending message")
+		deliverSpan.SetAttributes(attribute.Bool("bridge.no_reply", true))
+		logging.Printf(ctx, "[Bridge] Received NO_REPLY, not sending message")
+		b.delivered(ctx, "no reply")
 
 		mu.Lock()
 		// Delete thinking placeholder if it exists
 		if placeholderID != "" {
 			if err := b.feishuClient.DeleteMessage(placeholderID); err != nil {
-				log.Printf("[Bridge] Failed to delete placeholder: %v", err)
+				logging.Printf(ctx, "[Bridge] Failed to delete placeholder: %v", err)
 			}
 		}
 		// Delete response message if it exists
 		if responseMessageID != "" {
 			if err := b.feishuClient.DeleteMessage(responseMessageID); err != nil {
-				log.Printf("[Bridge] Failed to delete response message: %v", err)
+				logging.Printf(ctx, "[Bridge] Failed to delete response message: %v", err)
 			}
 		}
 		mu.Unlock()
@@ -332,83 +887,272 @@ func (b *Bridge) processMessage(chatID, text string) {
 	mu.Lock()
 	currentPlaceholder := placeholderID
 	currentResponse := responseMessageID
+	currentPost := responsePost
 	mu.Unlock()
 
+	// A quiet spell after an answer brings a satisfaction card
+	if err == nil && blocked == "" && b.cfg.Survey.Enabled && b.transcripts != nil {
+		b.scheduleSurvey(ctx, req)
+	}
+
+	// The gateway already posted the reply; sending it too would
+	// duplicate it
+	if err == nil && blocked == "" && gatewayDelivers {
+		if currentPlaceholder != "" {
+			if err := b.feishuClient.DeleteMessage(currentPlaceholder); err != nil {
+				logging.Printf(ctx, "[Bridge] Failed to delete placeholder: %v", err)
+			}
+		}
+		logging.Printf(ctx, "[Bridge] Reply in %s left to gateway delivery", chatID)
+		b.delivered(ctx, "gateway")
+		return
+	}
+
+	// A failed run is reported on a card whose button runs it again
+	if err != nil && blocked == "" && b.sendRetryCard(ctx, req, reply, currentPlaceholder, currentResponse) {
+		logging.Printf(ctx, "[Bridge] Sent retry card to %s", chatID)
+		b.delivered(ctx, "retry card")
+		return
+	}
+
+	// Link previews, diagrams and charts follow the text, however the
+	// text is delivered (deferred calls run last first)
+	if urls := b.links.URLs(reply); len(urls) > 0 {
+		defer b.sendLinkPreviews(ctx, chatID, urls)
+	}
+	if specs := b.charts.Find(text, reply); len(specs) > 0 {
+		defer b.sendCharts(ctx, chatID, specs)
+	}
+	if blocks := b.diagrams.Blocks(reply); len(blocks) > 0 {
+		defer b.sendDiagrams(ctx, chatID, blocks)
+	}
+	// Images generated by the run replace the paths naming them
+	if err == nil && blocked == "" {
+		mu.Lock()
+		refs := append(toolImages, b.images.Find(reply)...)
+		mu.Unlock()
+		if images := b.loadImages(ctx, b.images.Limit(refs)); len(images) > 0 {
+			reply = replaceImagePaths(reply, images)
+			defer b.sendGeneratedImages(ctx, chatID, images)
+		}
+	}
+	if b.cfg.Streaming.ToolActivity {
+		defer b.sendToolActivity(ctx, chatID, activity)
+	}
+
+	// Parts before a message break go out as messages of their own, after
+	// those finished while streaming; the last part is delivered below
+	if b.cfg.MessageBreaks.Enabled && finalOnly {
+		// Final-only chats get one message; the parts become paragraphs
+		reply = strings.Join(splitMessages(reply, 0), "\n\n")
+	} else if b.cfg.MessageBreaks.Enabled {
+		mu.Lock()
+		finished := finishedParts
+		mu.Unlock()
+		parts := splitMessages(reply, b.cfg.MessageBreaks.MaxMessages)
+		if finished >= len(parts) {
+			finished = len(parts) - 1
+		}
+		for _, part := range parts[finished : len(parts)-1] {
+			if currentResponse == "" && currentPlaceholder != "" {
+				if err := b.feishuClient.DeleteMessage(currentPlaceholder); err != nil {
+					logging.Printf(ctx, "[Bridge] Failed to delete placeholder: %v", err)
+				}
+				currentPlaceholder = ""
+			}
+			b.finishPart(ctx, chatID, currentResponse, currentPost, b.renderMath(ctx, req.tagReply(part)))
+			currentResponse, currentPost = "", false
+		}
+		reply = parts[len(parts)-1]
+	}
+
+	reply = req.tagReply(reply)
+
+	// Replies too long to read in chat go out as a file with a summary
+	if b.tooLong(reply) {
+		reply = b.sendAsFile(ctx, chatID, reply)
+	}
+
+	// Formulas show as images inside a rich text reply
+	reply = b.renderMath(ctx, reply)
+
+	// Code or formulas that arrived after the last streamed update need rich text,
+	// which the plain streaming message can't become; send it anew
+	if currentResponse != "" && !currentPost && feishu.NeedsPost(reply) {
+		if err := b.feishuClient.DeleteMessage(currentResponse); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to delete plain streaming message: %v", err)
+		}
+		currentResponse = ""
+	}
+
 	// If we have a response message (from streaming), do final update
 	if currentResponse != "" {
-		if err := b.feishuClient.UpdateMessage(currentResponse, reply); err != nil {
-			log.Printf("[Bridge] Failed to final update message: %v", err)
+		err := b.editReply(ctx, currentResponse, currentPost, reply)
+		if errors.Is(err, feishu.ErrNotEditable) {
+			// The final text must land; send it anew
+			currentResponse, currentPost, err = b.moveReply(ctx, chatID, currentResponse, reply)
+		}
+		if b.deliverLater(ctx, chatID, currentResponse, reply, currentPost, err) {
+			return
+		}
+		if err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to final update message: %v", err)
+			b.observeDelivery(ctx, err)
+			b.deadLetter(ctx, chatID, req.senderID, reply, currentPost, err)
 		} else {
-			log.Printf("[Bridge] Final updated message in %s", chatID)
+			logging.Printf(ctx, "[Bridge] Final updated message in %s", chatID)
+			b.delivered(ctx, "final update")
 		}
 	} else if currentPlaceholder != "" {
 		// No streaming happened, delete placeholder and send new message
 		if err := b.feishuClient.DeleteMessage(currentPlaceholder); err != nil {
-			log.Printf("[Bridge] Failed to delete placeholder: %v", err)
+			logging.Printf(ctx, "[Bridge] Failed to delete placeholder: %v", err)
 		}
 		
-		if _, err := b.feishuClient.SendMessage(chatID, reply); err != nil {
-			log.Printf("[Bridge] Failed to send message: %v", err)
+		_, post, err := b.sendReply(chatID, reply)
+		if b.deliverLater(ctx, chatID, "", reply, post, err) {
+			return
+		}
+		if err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to send message: %v", err)
+			b.observeDelivery(ctx, err)
+			b.deadLetter(ctx, chatID, req.senderID, reply, post, err)
 		} else {
-			log.Printf("[Bridge] Sent new message to %s", chatID)
+			logging.Printf(ctx, "[Bridge] Sent new message to %s", chatID)
+			b.delivered(ctx, "replaced placeholder")
 		}
 	} else {
 		// No placeholder, send new message
-		if _, err := b.feishuClient.SendMessage(chatID, reply); err != nil {
-			log.Printf("[Bridge] Failed to send message: %v", err)
+		_, post, err := b.sendReply(chatID, reply)
+		if b.deliverLater(ctx, chatID, "", reply, post, err) {
+			return
+		}
+		if err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to send message: %v", err)
+			b.observeDelivery(ctx, err)
+			b.deadLetter(ctx, chatID, req.senderID, reply, post, err)
 		} else {
-			log.Printf("[Bridge] Sent message to %s", chatID)
+			logging.Printf(ctx, "[Bridge] Sent message to %s", chatID)
+			b.delivered(ctx, "new message")
 		}
 	}
 }
 
-// shouldRespondInGroup determines if the bot should respond in a group chat
-func shouldRespondInGroup(text string, mentions []feishu.Mention) bool {
-	// Always respond if mentioned
-	if len(mentions) > 0 {
-		return true
+// sendReply sends an agent reply, as rich text when it has code blocks
+// or images so they render properly. It reports whether the message is rich text.
+func (b *Bridge) sendReply(chatID, text string) (string, bool, error) {
+	if feishu.NeedsPost(text) {
+		messageID, err := b.feishuClient.SendPost(chatID, text)
+		return messageID, true, err
 	}
+	messageID, err := b.feishuClient.SendMessage(chatID, text)
+	return messageID, false, err
+}
 
-	lowerText := strings.ToLower(text)
+// updateReply replaces the text of a reply sent with sendReply
+func (b *Bridge) updateReply(messageID string, post bool, text string) error {
+	if post {
+		return b.feishuClient.UpdatePost(messageID, text)
+	}
+	return b.feishuClient.UpdateMessage(messageID, text)
+}
 
-	// Question marks
-	if strings.HasSuffix(text, "?") || strings.HasSuffix(text, "？") {
-		return true
+// sessionKeyFor returns the gateway session key used for a chat: the
+// one restored from a snapshot, if any, or the configured scheme's
+func (b *Bridge) sessionKeyFor(chatID string) string {
+	var key string
+	if ok, err := b.store.Get(chatSessionBucket, chatID, &key); err != nil {
+		log.Printf("[Bridge] Failed to read session key for %s: %v", chatID, err)
+	} else if ok && key != "" {
+		return key
 	}
+	return b.defaultSessionKey(chatID)
+}
 
-	// English question words
-	questionWords := []string{"why", "how", "what", "when", "where", "who", "help"}
-	for _, word := range questionWords {
-		if regexp.MustCompile(`\b` + word + `\b`).MatchString(lowerText) {
-			return true
-		}
+// defaultSessionKey returns the session key the configuration gives chatID
+func (b *Bridge) defaultSessionKey(chatID string) string {
+	if b.sessionKey != "" {
+		return b.sessionKey
 	}
+	return fmt.Sprintf("feishu:%s", chatID)
+}
 
-	// Chinese action verbs
-	actionVerbs := []string{
+// Group-chat triggers. English words match whole words; action verbs match
+// anywhere; bot names must open the message.
+var (
+	questionWords = []string{"why", "how", "what", "when", "where", "who", "help"}
+	actionVerbs   = []string{
 		"帮", "麻烦", "请", "能否", "可以", "解释", "看看",
 		"排查", "分析", "总结", "写", "改", "修", "查", "对比", "翻译",
 	}
-	for _, verb := range actionVerbs {
-		if strings.Contains(text, verb) {
-			return true
+	botTriggers = []string{"alen", "clawdbot", "bot", "助手", "智能体"}
+)
+
+// groupTrigger is compiled once at startup rather than per keyword per message
+var groupTrigger = newTriggerMatcher(questionWords, actionVerbs, botTriggers)
+
+// mentionPattern matches the @_user_N placeholders Feishu puts in text
+var mentionPattern = regexp.MustCompile(`@_user_\d+\s*`)
+
+// triggerMatcher tests lowercase text against every group trigger. Each
+// kind uses its cheapest check: one anchored pattern for bot names, plain
+// substring search for verbs, and one whole-word pattern for English words.
+// (A single alternation of all of them defeats RE2's fast paths because
+// of \b and runs several times slower.)
+type triggerMatcher struct {
+	names *regexp.Regexp
+	verbs []string
+	words *regexp.Regexp
+}
+
+func newTriggerMatcher(words, verbs, names []string) *triggerMatcher {
+	alternation := func(list []string) string {
+		quoted := make([]string, len(list))
+		for i, s := range list {
+			quoted[i] = regexp.QuoteMeta(strings.ToLower(s))
 		}
+		return strings.Join(quoted, "|")
+	}
+	lowerVerbs := make([]string, len(verbs))
+	for i, v := range verbs {
+		lowerVerbs[i] = strings.ToLower(v)
+	}
+	return &triggerMatcher{
+		names: regexp.MustCompile(`^(?:` + alternation(names) + `)[\s,:，：]`),
+		verbs: lowerVerbs,
+		words: regexp.MustCompile(`\b(?:` + alternation(words) + `)\b`),
 	}
+}
 
-	// Bot names/triggers
-	botTriggers := []string{"alen", "clawdbot", "bot", "助手", "智能体"}
-	for _, trigger := range botTriggers {
-		pattern := fmt.Sprintf(`^%s[\s,:，：]`, trigger)
-		if matched, _ := regexp.MatchString(pattern, lowerText); matched {
+// Match reports whether lowercase text contains any trigger
+func (m *triggerMatcher) Match(text string) bool {
+	if m.names.MatchString(text) {
+		return true
+	}
+	for _, v := range m.verbs {
+		if strings.Contains(text, v) {
 			return true
 		}
 	}
+	return m.words.MatchString(text)
+}
+
+// shouldRespondInGroup determines if the bot should respond in a group chat
+func shouldRespondInGroup(text string, mentions []feishu.Mention) bool {
+	// Always respond if mentioned
+	if len(mentions) > 0 {
+		return true
+	}
+
+	// Question marks
+	if strings.HasSuffix(text, "?") || strings.HasSuffix(text, "？") {
+		return true
+	}
 
-	return false
+	return groupTrigger.Match(strings.ToLower(text))
 }
 
 // removeMentions removes @mention patterns from text
 func removeMentions(text string) string {
-	re := regexp.MustCompile(`@_user_\d+\s*`)
-	return re.ReplaceAllString(text, "")
+	return mentionPattern.ReplaceAllString(text, "")
 }
@@ -0,0 +1,48 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// cardActionHandler handles a card button click and returns toast text
+type cardActionHandler func(ctx context.Context, b *Bridge, action *feishu.CardAction) (string, error)
+
+// cardActions maps the "action" field of button values to handlers
+var cardActions = map[string]cardActionHandler{
+	"select_agent":   actionSelectAgent,
+	"approve_chat":   actionApproveChat,
+	"reject_chat":    actionRejectChat,
+	"confirm_action": actionConfirm,
+	"cancel_action":  actionCancel,
+	"poll_vote":      actionPollVote,
+	"poll_close":     actionPollClose,
+	"retry_run":      actionRetry,
+	"survey_answer":  actionSurveyAnswer,
+}
+
+// HandleCardAction dispatches a card button click from Feishu
+func (b *Bridge) HandleCardAction(ctx context.Context, action *feishu.CardAction) (string, error) {
+	name := actionString(action, "action")
+	handler, ok := cardActions[name]
+	if !ok {
+		logging.Printf(ctx, "[Bridge] Ignoring unknown card action %q from %s", name, action.OperatorID)
+		return "", nil
+	}
+	logging.Printf(ctx, "[Bridge] Card action %s from %s in %s", name, action.OperatorID, action.ChatID)
+	return handler(ctx, b, action)
+}
+
+// actionString reads a string field from a button value
+func actionString(action *feishu.CardAction, key string) string {
+	if v, ok := action.Value[key]; ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+		return fmt.Sprint(v)
+	}
+	return ""
+}
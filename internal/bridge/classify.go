@@ -0,0 +1,111 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/metrics"
+)
+
+// classifyBucket stores each chat's current conversation tag by chat ID
+const classifyBucket = "conversation_tag"
+
+// maxClassifyText caps the message text sent for classification
+const maxClassifyText = 2000
+
+// conversationTag is the tag of a chat's current conversation
+type conversationTag struct {
+	Tag string `json:"tag"`
+	// Last is when the conversation last had a message
+	Last time.Time `json:"last"`
+}
+
+// tagRun tags req with its conversation's category, classifying the
+// first message of a conversation, and binds the agent routed for the
+// tag when nothing else chose one
+func (b *Bridge) tagRun(ctx context.Context, req *runRequest) {
+	now := time.Now()
+	var c conversationTag
+	if _, err := b.store.Get(classifyBucket, req.chatID, &c); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to read conversation tag of %s: %v", req.chatID, err)
+	}
+	if c.Last.IsZero() || now.Sub(c.Last) >= b.cfg.Classify.Idle {
+		c.Tag = b.classify(ctx, req)
+	}
+	c.Last = now
+	if err := b.store.Set(classifyBucket, req.chatID, c); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to save conversation tag of %s: %v", req.chatID, err)
+	}
+	req.tag = c.Tag
+
+	if name, ok := b.cfg.Classify.Routes[c.Tag]; ok && req.tagRoutable {
+		b.bindAgent(req, name)
+		logging.Printf(ctx, "[Bridge] Routed %s conversation in %s to agent %s", c.Tag, req.chatID, name)
+	}
+}
+
+// classify asks the classifier backend which tag fits req's message. It
+// returns "" when the classification fails or names no known tag.
+func (b *Bridge) classify(ctx context.Context, req *runRequest) string {
+	cfg := b.cfg.Classify
+	agent := req.agent
+	if cfg.Backend != "" {
+		if a, ok := b.router.Backend(cfg.Backend); ok {
+			agent = a
+		}
+	}
+
+	text := req.text
+	if r := []rune(text); len(r) > maxClassifyText {
+		text = string(r[:maxClassifyText])
+	}
+	prompt := fmt.Sprintf("请判断下面这条消息开启的对话属于哪一类，只回答类别名称，不要解释。可选类别：%s\n\n消息：\n%s",
+		strings.Join(cfg.Tags, "、"), text)
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	// A session of its own keeps the chat's history out, and is reset so
+	// classifications don't pile up in it
+	sessionKey := "classify:" + req.chatID
+	defer func() {
+		if err := agent.ResetSession(sessionKey); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to reset classification session: %v", err)
+		}
+	}()
+
+	start := time.Now()
+	reply, err := agent.Ask(ctx, prompt, sessionKey, nil)
+	metrics.Timing("classify", time.Since(start))
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to classify conversation in %s: %v", req.chatID, err)
+		return ""
+	}
+	tag := matchTag(reply, cfg.Tags)
+	if tag == "" {
+		logging.Printf(ctx, "[Bridge] Classifier named no known tag: %q", reply)
+		return ""
+	}
+	metrics.Inc("conversations_tagged", "tag", tag)
+	logging.Printf(ctx, "[Bridge] Tagged conversation in %s as %s", req.chatID, tag)
+	return tag
+}
+
+// matchTag returns the tag reply names, preferring an exact answer and
+// then the longest tag it mentions
+func matchTag(reply string, tags []string) string {
+	reply = strings.ToLower(strings.Trim(strings.TrimSpace(reply), "`\"'。.*"))
+	var best string
+	for _, tag := range tags {
+		t := strings.ToLower(tag)
+		if reply == t {
+			return tag
+		}
+		if strings.Contains(reply, t) && len(tag) > len(best) {
+			best = tag
+		}
+	}
+	return best
+}
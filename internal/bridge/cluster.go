@@ -0,0 +1,77 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/cluster"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/metrics"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// forwardedMessage is a Feishu message handed to the instance owning its chat
+type forwardedMessage struct {
+	CorrelationID string          `json:"cid"`
+	From          string          `json:"from"`
+	Message       *feishu.Message `json:"message"`
+}
+
+// JoinCluster partitions chats with the other members of node. Start
+// begins handling messages forwarded to this instance; call it first.
+func (b *Bridge) JoinCluster(node *cluster.Node) {
+	b.cluster = node
+	b.instance = node.ID()
+	log.Printf("[Bridge] Joined cluster as %s", node.ID())
+}
+
+// forwardToOwner queues msg for the instance that owns its chat and
+// reports whether it did. Messages stay local when this instance owns the
+// chat or forwarding fails.
+func (b *Bridge) forwardToOwner(ctx context.Context, msg *feishu.Message) bool {
+	if b.cluster == nil {
+		return false
+	}
+	owner := b.cluster.Owner(msg.ChatID)
+	if owner == "" || owner == b.cluster.ID() {
+		return false
+	}
+
+	payload, err := json.Marshal(forwardedMessage{
+		CorrelationID: logging.CorrelationID(ctx),
+		From:          b.cluster.ID(),
+		Message:       msg,
+	})
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to encode message for %s: %v", owner, err)
+		return false
+	}
+	if err := b.cluster.Forward(ctx, owner, payload); err != nil {
+		logging.Printf(ctx, "[Bridge] %v; handling locally", err)
+		return false
+	}
+
+	metrics.Inc("messages_forwarded", "to", owner)
+	logging.Printf(ctx, "[Bridge] Forwarded message %s to owner %s", msg.MessageID, owner)
+	return true
+}
+
+// receiveForwarded handles a message another instance forwarded here.
+// It is never forwarded again, so members with briefly different views
+// of the ring can't bounce a message between them.
+func (b *Bridge) receiveForwarded(payload []byte) {
+	var fwd forwardedMessage
+	if err := json.Unmarshal(payload, &fwd); err != nil || fwd.Message == nil {
+		log.Printf("[Bridge] Dropping malformed forwarded message: %v", err)
+		return
+	}
+
+	ctx := logging.WithCorrelationID(context.Background(), fwd.CorrelationID)
+	logging.SetChatID(ctx, fwd.Message.ChatID)
+	logging.Printf(ctx, "[Bridge] Received message %s forwarded by %s", fwd.Message.MessageID, fwd.From)
+
+	if err := b.handleMessage(ctx, fwd.Message); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to handle forwarded message: %v", err)
+	}
+}
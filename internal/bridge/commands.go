@@ -0,0 +1,264 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/backend"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// commandHandler runs a bridge command and returns the reply text
+type commandHandler func(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string
+
+// command is a slash command handled by the bridge itself
+// instead of being forwarded to the agent
+type command struct {
+	usage   string
+	handler commandHandler
+}
+
+// commands maps command names (without the leading slash) to handlers
+var commands = map[string]command{
+	"model": {
+		usage:   modelUsage,
+		handler: cmdModel,
+	},
+	"backend": {
+		usage:   backendUsage,
+		handler: cmdBackend,
+	},
+	"agents": {
+		usage:   agentsUsage,
+		handler: cmdAgents,
+	},
+	"usage": {
+		usage:   usageUsage,
+		handler: cmdUsage,
+	},
+	"fork": {
+		usage:   forkUsage,
+		handler: cmdFork,
+	},
+	"context": {
+		usage:   contextUsage,
+		handler: cmdContext,
+	},
+	"pref": {
+		usage:   prefUsage,
+		handler: cmdPref,
+	},
+	"memory": {
+		usage:   memoryUsage,
+		handler: cmdMemory,
+	},
+	"task": {
+		usage:   taskUsage,
+		handler: cmdTask,
+	},
+	"sheet": {
+		usage:   sheetUsage,
+		handler: cmdSheet,
+	},
+	"apply": {
+		usage:   applyUsage,
+		handler: cmdApply,
+	},
+	"wiki": {
+		usage:   wikiUsage,
+		handler: cmdWiki,
+	},
+	"poll": {
+		usage:   pollUsage,
+		handler: cmdPoll,
+	},
+	"group": {
+		usage:   groupUsage,
+		handler: cmdGroup,
+	},
+	"announce": {
+		usage:   announceUsage,
+		handler: cmdAnnounce,
+	},
+	"final": {
+		usage:   finalOnlyUsage,
+		handler: cmdFinalOnly,
+	},
+	"thoughts": {
+		usage:   thoughtsUsage,
+		handler: cmdThoughts,
+	},
+	"compact": {
+		usage:   compactUsage,
+		handler: cmdCompact,
+	},
+	"link": {
+		usage:   linkUsage,
+		handler: cmdLink,
+	},
+	"unlink": {
+		usage:   unlinkUsage,
+		handler: cmdUnlink,
+	},
+	"sh": {
+		usage:   shellUsage,
+		handler: cmdShell,
+	},
+	"工单": {
+		usage:   ticketUsage,
+		handler: cmdTicket,
+	},
+	"忘记我": {
+		usage:   forgetUsage,
+		handler: cmdForget,
+	},
+	activateCommand: {
+		usage:   activateUsage,
+		handler: cmdActivate,
+	},
+}
+
+// parseCommand splits "/name args" into its parts.
+// ok is false when text is not a known bridge command.
+func parseCommand(text string) (cmd command, args string, ok bool) {
+	if !strings.HasPrefix(text, "/") {
+		return command{}, "", false
+	}
+	_, args, _ = strings.Cut(text[1:], " ")
+	cmd, ok = commands[commandName(text)]
+	return cmd, strings.TrimSpace(args), ok
+}
+
+// cutSpace splits s at its first whitespace, so arguments after a
+// subcommand may span lines
+func cutSpace(s string) (head, rest string) {
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+// commandName returns the lowercased name of a "/name args" command
+func commandName(text string) string {
+	name, _, _ := strings.Cut(strings.TrimPrefix(text, "/"), " ")
+	return strings.ToLower(name)
+}
+
+// runCommand executes a bridge command and sends its reply
+func (b *Bridge) runCommand(ctx context.Context, msg *feishu.Message, cmd command, args string) {
+	reply := cmd.handler(ctx, b, msg, args)
+	if reply == "" {
+		return
+	}
+	if _, err := b.feishuClient.SendMessage(msg.ChatID, reply); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to send command reply: %v", err)
+	}
+}
+
+const modelUsage = "/model [模型名|default] 查看或切换当前会话使用的模型"
+
+// cmdModel shows or switches the model of the chat's session
+func cmdModel(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	_, agent, _ := b.router.Route(msg.ChatID, "")
+	switcher, ok := agent.(backend.ModelSwitcher)
+	if !ok {
+		return "当前后端不支持切换模型"
+	}
+	sessionKey := b.sessionKeyFor(msg.ChatID)
+
+	if args == "" {
+		reply := fmt.Sprintf("当前模型：%s", switcher.Model(sessionKey))
+		if lister, ok := agent.(backend.ModelLister); ok {
+			models, err := lister.ListModels()
+			if err != nil {
+				logging.Printf(ctx, "[Bridge] Failed to list models: %v", err)
+			} else if len(models) > 0 {
+				sort.Strings(models)
+				reply += "\n可用模型：\n- " + strings.Join(models, "\n- ")
+			}
+		}
+		return reply + "\n\n" + modelUsage
+	}
+
+	if args == "default" {
+		switcher.SetModel(sessionKey, "")
+		b.audit.Record(ctx, audit.Event{Action: "model.switch", Actor: msg.SenderID, ChatID: msg.ChatID, Target: "default"})
+		return fmt.Sprintf("已恢复默认模型：%s", switcher.Model(sessionKey))
+	}
+
+	if lister, ok := agent.(backend.ModelLister); ok {
+		if models, err := lister.ListModels(); err == nil && !containsModel(models, args) {
+			return fmt.Sprintf("未找到模型 %s，发送 /model 查看可用模型", args)
+		}
+	}
+	switcher.SetModel(sessionKey, args)
+	logging.Printf(ctx, "[Bridge] Switched model for %s to %s", sessionKey, args)
+	b.audit.Record(ctx, audit.Event{Action: "model.switch", Actor: msg.SenderID, ChatID: msg.ChatID, Target: args})
+	return fmt.Sprintf("已切换模型：%s", args)
+}
+
+// containsModel reports whether name is in models, ignoring an implicit ":latest" tag
+func containsModel(models []string, name string) bool {
+	for _, m := range models {
+		if m == name || strings.TrimSuffix(m, ":latest") == name {
+			return true
+		}
+	}
+	return false
+}
+
+const backendUsage = "/backend [名称|default] 查看或切换当前会话使用的后端（切换需管理员权限）"
+
+// cmdBackend shows the chat's backend or, for admins, overrides it
+func cmdBackend(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if args == "" {
+		return fmt.Sprintf("当前后端：%s\n可用后端：%s\n\n%s",
+			b.router.ChatBackend(msg.ChatID), strings.Join(b.router.Names(), ", "), backendUsage)
+	}
+
+	if !b.cfg.IsAdmin(msg.SenderID) {
+		logging.Printf(ctx, "[Bridge] Denied /backend from non-admin %s in %s", msg.SenderID, msg.ChatID)
+		b.audit.Record(ctx, audit.Event{Action: "backend.switch", Actor: msg.SenderID, ChatID: msg.ChatID, Target: args, Outcome: audit.Denied, Detail: "not an admin"})
+		return "只有管理员可以切换后端"
+	}
+
+	if b.cfg.TwoPerson.Requires("backend.switch") {
+		if _, ok := b.router.Backend(args); !ok && args != "default" {
+			return fmt.Sprintf("未找到后端 %s", args)
+		}
+		return b.requestConfirmation(ctx, pendingAction{
+			Action:      "backend.switch",
+			ChatID:      msg.ChatID,
+			Target:      args,
+			RequestedBy: msg.SenderID,
+		})
+	}
+	reply, _ := switchBackend(ctx, b, msg.SenderID, msg.ChatID, args, "")
+	return reply
+}
+
+// switchBackend routes chatID to the named backend, or back to its
+// default for "default". confirmedBy names the second admin, if any.
+func switchBackend(ctx context.Context, b *Bridge, actor, chatID, args, confirmedBy string) (string, error) {
+	name := args
+	if name == "default" {
+		name = ""
+	}
+	if err := b.router.SetOverride(chatID, name); err != nil {
+		return fmt.Sprintf("切换失败：%s", redact.Error(err)), err
+	}
+	logging.Printf(ctx, "[Bridge] %s routed %s to backend %s", actor, chatID, b.router.ChatBackend(chatID))
+	ev := audit.Event{Action: "backend.switch", Actor: actor, ChatID: chatID, Target: args}
+	if confirmedBy != "" {
+		ev.Detail = "confirmed by " + confirmedBy
+	}
+	b.audit.Record(ctx, ev)
+	return fmt.Sprintf("已切换后端：%s", b.router.ChatBackend(chatID)), nil
+}
@@ -0,0 +1,113 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/backend"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// compactTimeout bounds summarizing a session
+const compactTimeout = 3 * time.Minute
+
+// compactPrompt asks the model for the summary that replaces a session's
+// history
+const compactPrompt = "请把以上对话压缩成一份简洁的摘要，保留关键事实、结论、决定、待办事项和尚未解决的问题，供后续对话继续使用。只输出摘要本身。"
+
+const compactUsage = "/compact 总结并压缩当前会话的上下文，避免长期使用的会话超出上下文长度"
+
+// cmdCompact summarizes the chat's session in place and reports how much
+// smaller its context got
+func cmdCompact(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if args != "" {
+		return compactUsage
+	}
+	req := b.chatRun(ctx, msg.ChatID)
+	ctx, cancel := context.WithTimeout(ctx, compactTimeout)
+	defer cancel()
+
+	reply, err := b.compactSession(ctx, req)
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to compact %s: %v", req.sessionKey, err)
+		return fmt.Sprintf("压缩失败：%s", redact.Error(err))
+	}
+	logging.Printf(ctx, "[Bridge] Compacted %s on %s: %s", req.sessionKey, req.backendName, reply)
+	b.audit.Record(ctx, audit.Event{Action: "session.compact", Actor: msg.SenderID, ChatID: msg.ChatID, Target: req.sessionKey})
+	return reply
+}
+
+// compactSession compacts req's session and describes the result: the
+// gateway does it itself when it can, or is asked to through its own
+// /compact command; sessions kept in the bridge are summarized by their
+// model and replaced with the summary
+func (b *Bridge) compactSession(ctx context.Context, req *runRequest) (string, error) {
+	raw, _ := b.router.Backend(req.backendName)
+	if c, ok := raw.(backend.SessionCompactor); ok {
+		stats, err := c.CompactSession(req.sessionKey)
+		if err == nil {
+			if stats.TokensBefore == 0 {
+				return "已压缩上下文", nil
+			}
+			return fmt.Sprintf("已压缩上下文：%d → %d tokens", stats.TokensBefore, stats.TokensAfter), nil
+		}
+		logging.Printf(ctx, "[Bridge] Backend %s couldn't compact %s, asking the agent: %v", req.backendName, req.sessionKey, err)
+		reply, err := req.agent.Ask(ctx, "/compact", req.sessionKey, nil)
+		if err != nil {
+			return "", err
+		}
+		reply = strings.TrimSpace(reply)
+		if reply == "" {
+			return "已请求 Agent 压缩上下文", nil
+		}
+		return "已请求 Agent 压缩上下文：" + reply, nil
+	}
+
+	s, ok := raw.(backend.SessionSnapshotter)
+	if !ok {
+		return "", fmt.Errorf("backend %s can't compact sessions", req.backendName)
+	}
+	state := s.SessionState(req.sessionKey)
+	if len(state.History) == 0 {
+		return "当前会话没有可压缩的上下文", nil
+	}
+	if len(state.History) <= 2 {
+		return "上下文很短，无需压缩", nil
+	}
+
+	// Summarize in a scratch session so a failure leaves the history as it was
+	scratch := req.sessionKey + ":compact"
+	s.RestoreSession(scratch, state)
+	defer s.RestoreSession(scratch, backend.SessionState{})
+	summary, err := req.agent.Ask(ctx, compactPrompt, scratch, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize: %w", err)
+	}
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", fmt.Errorf("the model returned an empty summary")
+	}
+
+	compacted := backend.SessionState{Model: state.Model, History: []backend.HistoryMessage{
+		{Role: "user", Content: "以下是此前对话的摘要，请在此基础上继续：\n" + summary},
+		{Role: "assistant", Content: "好的，我会在此基础上继续。"},
+	}}
+	s.RestoreSession(req.sessionKey, compacted)
+	return fmt.Sprintf("已压缩上下文：%d 条消息、%d 字 → %d 条消息、%d 字",
+		len(state.History), historySize(state.History), len(compacted.History), historySize(compacted.History)), nil
+}
+
+// historySize counts the characters in msgs
+func historySize(msgs []backend.HistoryMessage) int {
+	var n int
+	for _, m := range msgs {
+		n += utf8.RuneCountInString(m.Content)
+	}
+	return n
+}
@@ -0,0 +1,225 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// pendingActionBucket stores admin actions waiting for a second admin.
+// Kept in the shared store so any instance can take the confirmation.
+const pendingActionBucket = "pending_action"
+
+// pendingAction is an admin action waiting for confirmation
+type pendingAction struct {
+	ID string `json:"id"`
+	// Action is the audit action name, e.g. "backend.switch"
+	Action      string    `json:"action"`
+	ChatID      string    `json:"chat_id"`
+	Target      string    `json:"target"`
+	RequestedBy string    `json:"requested_by"`
+	Expires     time.Time `json:"expires"`
+}
+
+// confirmedAction carries out a confirmed action and returns the text
+// sent back to the chat it was requested in
+type confirmedAction func(ctx context.Context, b *Bridge, p pendingAction, confirmedBy string) (string, error)
+
+// confirmedActions maps the actions that can require two admins to
+// what runs once they're confirmed
+var confirmedActions = map[string]confirmedAction{
+	"backend.switch": func(ctx context.Context, b *Bridge, p pendingAction, confirmedBy string) (string, error) {
+		return switchBackend(ctx, b, p.RequestedBy, p.ChatID, p.Target, confirmedBy)
+	},
+	"session.link": func(ctx context.Context, b *Bridge, p pendingAction, confirmedBy string) (string, error) {
+		return linkChats(ctx, b, p.RequestedBy, p.ChatID, p.Target, confirmedBy)
+	},
+	"shell.exec": func(ctx context.Context, b *Bridge, p pendingAction, confirmedBy string) (string, error) {
+		return runShell(ctx, b, p.RequestedBy, p.ChatID, p.Target, confirmedBy)
+	},
+	"group.remove": func(ctx context.Context, b *Bridge, p pendingAction, confirmedBy string) (string, error) {
+		return b.changeMembers(ctx, p.ChatID, p.RequestedBy, "remove", strings.Split(p.Target, ","), confirmedBy)
+	},
+	"announcement.set": func(ctx context.Context, b *Bridge, p pendingAction, confirmedBy string) (string, error) {
+		return b.setAnnouncement(ctx, "announcement.set", p.RequestedBy, p.ChatID, p.Target, "set "+p.Target, confirmedBy)
+	},
+}
+
+// actionLabels describe actions on confirmation cards
+var actionLabels = map[string]string{
+	"backend.switch":   "切换后端",
+	"session.link":     "共享会话上下文",
+	"shell.exec":       "执行命令",
+	"group.remove":     "移出群成员",
+	"announcement.set": "替换群公告",
+}
+
+// describe renders the action for cards and replies
+func (p pendingAction) describe() string {
+	label := actionLabels[p.Action]
+	if label == "" {
+		label = p.Action
+	}
+	return fmt.Sprintf("%s：%s（会话 %s）", label, p.Target, p.ChatID)
+}
+
+// requestConfirmation stores p and posts a card asking another admin to
+// confirm it within the configured window. It returns the reply for the
+// requesting admin.
+func (b *Bridge) requestConfirmation(ctx context.Context, p pendingAction) string {
+	b.sweepPendingActions()
+
+	p.ID = uuid.NewString()
+	p.Expires = time.Now().Add(b.cfg.TwoPerson.Window)
+	if err := b.store.Set(pendingActionBucket, p.ID, p); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to save pending %s: %v", p.Action, err)
+		return "操作未能提交确认，请稍后再试"
+	}
+	logging.Printf(ctx, "[Bridge] %s requested %s on %s, awaiting a second admin", p.RequestedBy, p.Action, p.Target)
+	b.audit.Record(ctx, audit.Event{Action: p.Action, Actor: p.RequestedBy, ChatID: p.ChatID, Target: p.Target,
+		Outcome: audit.Pending, Detail: "awaiting second admin, request " + p.ID})
+
+	chatID := b.cfg.TwoPerson.ChatID
+	if chatID == "" {
+		chatID = p.ChatID
+	}
+	if _, err := b.feishuClient.SendCard(chatID, confirmCard(p)); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to send confirmation card: %v", err)
+		b.store.Delete(pendingActionBucket, p.ID)
+		return "确认卡片发送失败，操作未执行"
+	}
+	return fmt.Sprintf("该操作需要另一位管理员在 %d 分钟内确认，已发送确认卡片", int(b.cfg.TwoPerson.Window.Minutes()))
+}
+
+// sweepPendingActions drops requests nobody confirmed in time
+func (b *Bridge) sweepPendingActions() {
+	now := time.Now()
+	for _, id := range b.store.Keys(pendingActionBucket) {
+		var p pendingAction
+		if ok, err := b.store.Get(pendingActionBucket, id, &p); err == nil && ok && now.After(p.Expires) {
+			b.store.Delete(pendingActionBucket, id)
+		}
+	}
+}
+
+// confirmCard asks a second admin to confirm or cancel p
+func confirmCard(p pendingAction) *feishu.Card {
+	return feishu.NewCard("管理操作待确认", "orange").
+		AddMarkdown(fmt.Sprintf("**操作**：%s\n**发起人**：<at id=%s></at>", p.describe(), p.RequestedBy)).
+		AddNote(fmt.Sprintf("需由另一位管理员在 %s 前确认，过期自动作废", p.Expires.Format("15:04"))).
+		AddButtons(
+			feishu.CardButton{Text: "确认执行", Type: "danger", Value: map[string]interface{}{"action": "confirm_action", "id": p.ID}},
+			feishu.CardButton{Text: "取消", Value: map[string]interface{}{"action": "cancel_action", "id": p.ID}},
+		)
+}
+
+// resolvedCard replaces the confirmation card once it's been handled
+func resolvedCard(p pendingAction, title, template, note string) *feishu.Card {
+	return feishu.NewCard(title, template).
+		AddMarkdown(fmt.Sprintf("**操作**：%s\n**发起人**：<at id=%s></at>", p.describe(), p.RequestedBy)).
+		AddNote(note)
+}
+
+// takePendingAction loads and removes the request a card button refers
+// to. The shared claim makes sure only one click acts on it.
+func (b *Bridge) takePendingAction(ctx context.Context, action *feishu.CardAction) (pendingAction, error) {
+	id := actionString(action, "id")
+	var p pendingAction
+	ok, err := b.store.Get(pendingActionBucket, id, &p)
+	if err != nil {
+		return p, err
+	}
+	if !ok {
+		return p, fmt.Errorf("该操作不存在或已处理")
+	}
+	first, err := b.shared.Claim(ctx, "confirm:"+id, b.cfg.TwoPerson.Window)
+	if err != nil {
+		return p, err
+	}
+	if !first {
+		return p, fmt.Errorf("该操作已被处理")
+	}
+	if err := b.store.Delete(pendingActionBucket, id); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to delete pending action %s: %v", id, err)
+	}
+	return p, nil
+}
+
+// actionConfirm runs a pending action once a second admin confirms it
+func actionConfirm(ctx context.Context, b *Bridge, action *feishu.CardAction) (string, error) {
+	if !b.cfg.IsAdmin(action.OperatorID) {
+		b.audit.Record(ctx, audit.Event{Action: "action.confirm", Actor: action.OperatorID, Target: actionString(action, "id"), Outcome: audit.Denied, Detail: "not an admin"})
+		return "", fmt.Errorf("只有管理员可以确认")
+	}
+
+	// Check the confirmer before taking the request, so the requester
+	// clicking doesn't use it up
+	id := actionString(action, "id")
+	var p pendingAction
+	if ok, err := b.store.Get(pendingActionBucket, id, &p); err == nil && ok && p.RequestedBy == action.OperatorID {
+		b.audit.Record(ctx, audit.Event{Action: p.Action, Actor: action.OperatorID, ChatID: p.ChatID, Target: p.Target, Outcome: audit.Denied, Detail: "requester cannot confirm own request " + id})
+		return "", fmt.Errorf("需要另一位管理员确认")
+	}
+
+	p, err := b.takePendingAction(ctx, action)
+	if err != nil {
+		return "", err
+	}
+	if time.Now().After(p.Expires) {
+		b.audit.Record(ctx, audit.Event{Action: p.Action, Actor: p.RequestedBy, ChatID: p.ChatID, Target: p.Target, Outcome: audit.Denied, Detail: "confirmation window expired, request " + p.ID})
+		b.updateConfirmCard(ctx, action, resolvedCard(p, "管理操作已过期", "grey", "未在有效期内确认，操作未执行"))
+		return "", fmt.Errorf("该操作已过期")
+	}
+	run, ok := confirmedActions[p.Action]
+	if !ok {
+		return "", fmt.Errorf("不支持的操作 %s", p.Action)
+	}
+
+	logging.Printf(ctx, "[Bridge] %s confirmed %s requested by %s", action.OperatorID, p.Action, p.RequestedBy)
+	reply, err := run(ctx, b, p, action.OperatorID)
+	note := fmt.Sprintf("<at id=%s></at> 已确认：%s", action.OperatorID, reply)
+	if err != nil {
+		b.updateConfirmCard(ctx, action, resolvedCard(p, "管理操作执行失败", "red", note))
+		return "", fmt.Errorf("%s", reply)
+	}
+	b.updateConfirmCard(ctx, action, resolvedCard(p, "管理操作已执行", "green", note))
+	if p.ChatID != action.ChatID {
+		if _, err := b.feishuClient.SendMessage(p.ChatID, reply); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to report confirmed action: %v", err)
+		}
+	}
+	return "已确认并执行", nil
+}
+
+// actionCancel drops a pending action; any admin, including the
+// requester, may cancel
+func actionCancel(ctx context.Context, b *Bridge, action *feishu.CardAction) (string, error) {
+	if !b.cfg.IsAdmin(action.OperatorID) {
+		return "", fmt.Errorf("只有管理员可以取消")
+	}
+	p, err := b.takePendingAction(ctx, action)
+	if err != nil {
+		return "", err
+	}
+	logging.Printf(ctx, "[Bridge] %s cancelled %s requested by %s", action.OperatorID, p.Action, p.RequestedBy)
+	b.audit.Record(ctx, audit.Event{Action: p.Action, Actor: p.RequestedBy, ChatID: p.ChatID, Target: p.Target, Outcome: audit.Denied, Detail: "cancelled by " + action.OperatorID})
+	b.updateConfirmCard(ctx, action, resolvedCard(p, "管理操作已取消", "grey", fmt.Sprintf("<at id=%s></at> 已取消", action.OperatorID)))
+	return "已取消", nil
+}
+
+// updateConfirmCard replaces the clicked confirmation card
+func (b *Bridge) updateConfirmCard(ctx context.Context, action *feishu.CardAction, card *feishu.Card) {
+	if action.MessageID == "" {
+		return
+	}
+	if err := b.feishuClient.UpdateCard(action.MessageID, card); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to update confirmation card: %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// chatContextBucket stores the context pinned to each chat
+const chatContextBucket = "chat_context"
+
+// maxPinnedContext bounds pinned context, in characters, since it goes
+// with every request
+const maxPinnedContext = 2000
+
+// pinnedContext is background an admin pinned to a chat
+type pinnedContext struct {
+	Text  string    `json:"text"`
+	SetBy string    `json:"set_by"`
+	SetAt time.Time `json:"set_at"`
+}
+
+const contextUsage = "/context [show|set 内容|clear] 查看、设置或清除当前会话的固定背景信息，它会附在每次请求之前（设置和清除需管理员权限）"
+
+// cmdContext shows the chat's pinned context or, for admins, sets or
+// clears it
+func cmdContext(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	// Pinned context often spans lines
+	sub, text := cutSpace(args)
+	switch strings.ToLower(sub) {
+	case "", "show":
+		pinned, ok := b.pinnedContext(msg.ChatID)
+		if !ok {
+			return "当前会话没有固定背景信息\n\n" + contextUsage
+		}
+		return fmt.Sprintf("当前会话的固定背景信息（%s 由 %s 设置）：\n%s",
+			pinned.SetAt.Local().Format("2006-01-02 15:04"), pinned.SetBy, pinned.Text)
+	case "set", "clear":
+	default:
+		return contextUsage
+	}
+
+	action := "context." + strings.ToLower(sub)
+	if !b.cfg.IsAdmin(msg.SenderID) {
+		logging.Printf(ctx, "[Bridge] Denied /context %s from non-admin %s in %s", sub, msg.SenderID, msg.ChatID)
+		b.audit.Record(ctx, audit.Event{Action: action, Actor: msg.SenderID, ChatID: msg.ChatID, Outcome: audit.Denied, Detail: "not an admin"})
+		return "只有管理员可以修改固定背景信息"
+	}
+
+	var err error
+	if action == "context.clear" {
+		err = b.store.Delete(chatContextBucket, msg.ChatID)
+	} else {
+		if text == "" {
+			return contextUsage
+		}
+		if n := utf8.RuneCountInString(text); n > maxPinnedContext {
+			return fmt.Sprintf("背景信息过长（%d 字），最多 %d 字", n, maxPinnedContext)
+		}
+		err = b.store.Set(chatContextBucket, msg.ChatID, pinnedContext{Text: text, SetBy: msg.SenderID, SetAt: time.Now().UTC()})
+	}
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to update pinned context of %s: %v", msg.ChatID, err)
+		return fmt.Sprintf("保存失败：%s", redact.Error(err))
+	}
+
+	logging.Printf(ctx, "[Bridge] %s ran /context %s in %s", msg.SenderID, sub, msg.ChatID)
+	b.audit.Record(ctx, audit.Event{Action: action, Actor: msg.SenderID, ChatID: msg.ChatID})
+	if action == "context.clear" {
+		return "已清除固定背景信息"
+	}
+	return "已设置固定背景信息，之后的每次请求都会附带"
+}
+
+// pinnedContext returns the context pinned to chatID, if any
+func (b *Bridge) pinnedContext(chatID string) (pinnedContext, bool) {
+	var pinned pinnedContext
+	ok, err := b.store.Get(chatContextBucket, chatID, &pinned)
+	if err != nil {
+		log.Printf("[Bridge] Failed to read pinned context of %s: %v", chatID, err)
+	}
+	return pinned, ok && err == nil && pinned.Text != ""
+}
+
+// promptFor prepends chatID's pinned context and memory, how to create
+// tasks, submit approvals, escalate to the helpdesk and, for admins,
+// manage groups, the spreadsheets and Minutes linked in text, the wiki
+// passages relevant to it and the preferences of userID, who sent text,
+// to text
+func (b *Bridge) promptFor(ctx context.Context, chatID, userID, text string) string {
+	var sections []string
+	if pinned, ok := b.pinnedContext(chatID); ok {
+		sections = append(sections, "[背景信息]\n"+pinned.Text)
+	}
+	if b.cfg.Memory.Enabled {
+		sections = append(sections, "[记忆]\n"+b.memoryPrompt(chatID))
+	}
+	if b.cfg.Tasks.Enabled {
+		sections = append(sections, "[任务]\n"+taskInstructions)
+	}
+	if b.cfg.Approvals.Enabled {
+		sections = append(sections, "[审批]\n"+b.approvalsPrompt(chatID))
+	}
+	if groups := b.groupsPrompt(userID); groups != "" {
+		sections = append(sections, "[群管理]\n"+groups)
+	}
+	if b.cfg.Helpdesk.Enabled {
+		sections = append(sections, "[人工客服]\n"+helpdeskInstructions)
+	}
+	if b.cfg.MessageBreaks.Enabled {
+		sections = append(sections, "[分条回复]\n"+messageBreakInstructions)
+	}
+	if sheets := b.sheetsPrompt(ctx, chatID, userID, text); sheets != "" {
+		sections = append(sections, "[表格]\n"+sheets)
+	}
+	if minutes := b.minutesPrompt(ctx, chatID, userID, text); minutes != "" {
+		sections = append(sections, "[妙记]\n"+minutes)
+	}
+	if wiki := b.wikiPrompt(text); wiki != "" {
+		sections = append(sections, "[参考资料]\n"+wiki)
+	}
+	if prefs, ok := b.userPrefs(userID); ok {
+		if text := prefsPrompt(prefs); text != "" {
+			sections = append(sections, "[用户偏好]\n"+text)
+		}
+	}
+	if now := b.timePrompt(ctx, userID); now != "" {
+		sections = append(sections, "[时间]\n"+now)
+	}
+	if len(sections) == 0 {
+		return text
+	}
+	return strings.Join(sections, "\n\n") + "\n\n[消息]\n" + text
+}
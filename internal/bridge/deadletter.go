@@ -0,0 +1,83 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/deadletter"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/metrics"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// alertDeadLetter is the alert key for undeliverable replies
+const alertDeadLetter = "dead_letter"
+
+// replyDropper is implemented by Feishu clients that report held replies
+// they gave up on
+type replyDropper interface {
+	OnReplyDropped(fn func(feishu.DroppedReply))
+}
+
+// deadLetter keeps a final reply that couldn't be delivered, for
+// "clawdbot-bridge deadletter" to retry or drop
+func (b *Bridge) deadLetter(ctx context.Context, chatID, userID, text string, post bool, err error) {
+	if b.keysErr != nil {
+		// Kept unsealed it would leak the reply
+		logging.Printf(ctx, "[Bridge] Not keeping undeliverable reply to %s, encryption keys unavailable: %v", chatID, b.keysErr)
+		if b.alerts != nil {
+			go b.Alert(alertDeadLetter, fmt.Sprintf("发往 %s 的回复无法送达，且加密密钥环无法加载，回复未保存：%v", chatID, err))
+		}
+		return
+	}
+	l, serr := deadletter.Add(b.store, b.keys, deadletter.Letter{
+		ChatID:        chatID,
+		UserID:        userID,
+		Text:          text,
+		Post:          post,
+		Error:         err.Error(),
+		CorrelationID: logging.CorrelationID(ctx),
+	})
+	if serr != nil {
+		logging.Printf(ctx, "[Bridge] Failed to keep undeliverable reply to %s: %v", chatID, serr)
+		return
+	}
+	logging.Printf(ctx, "[Bridge] Kept undeliverable reply to %s as dead letter %s", chatID, l.ID)
+	metrics.Inc("dead_letters")
+	if b.alerts != nil {
+		go b.Alert(alertDeadLetter, fmt.Sprintf("发往 %s 的回复无法送达，已保存为 %s，可用 clawdbot-bridge deadletter retry 重发：%v", chatID, l.ID, err))
+	}
+}
+
+// deadLetterRetentionLoop deletes dead letters older than the configured
+// retention once an hour
+func (b *Bridge) deadLetterRetentionLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		cutoff := time.Now().AddDate(0, 0, -b.cfg.DeadLetters.RetentionDays)
+		n, err := deadletter.Expire(b.store, cutoff, false)
+		if n > 0 {
+			log.Printf("[Bridge] Removed %d expired dead letters", n)
+		}
+		if err != nil {
+			log.Printf("[Bridge] Failed to expire dead letters: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// replyDropped keeps a reply the Feishu client held during an outage and
+// then gave up on
+func (b *Bridge) replyDropped(r feishu.DroppedReply) {
+	log.Printf("[Bridge] Feishu client dropped held reply to %s: %v", r.ChatID, r.Err)
+	b.deadLetter(context.Background(), r.ChatID, "", r.Text, r.Post, r.Err)
+}
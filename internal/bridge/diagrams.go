@@ -0,0 +1,64 @@
+package bridge
+
+import (
+	"context"
+	"strings"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// sendDiagrams renders each Mermaid block and sends it as an image. A
+// diagram that fails to render is skipped; its source is still in the
+// text reply.
+func (b *Bridge) sendDiagrams(ctx context.Context, chatID string, blocks []string) {
+	b.sendImages(ctx, chatID, "diagram", len(blocks), func(i int) ([]byte, error) {
+		return b.diagrams.Render(ctx, blocks[i])
+	})
+}
+
+// sendCharts renders each chart spec and sends it as an image
+func (b *Bridge) sendCharts(ctx context.Context, chatID string, specs [][]byte) {
+	b.sendImages(ctx, chatID, "chart", len(specs), func(i int) ([]byte, error) {
+		return b.charts.Render(ctx, specs[i])
+	})
+}
+
+// sendImages sends the n images render produces, skipping any that fail
+func (b *Bridge) sendImages(ctx context.Context, chatID, kind string, n int, render func(i int) ([]byte, error)) {
+	for i := 0; i < n; i++ {
+		image, err := render(i)
+		if err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to render %s %d/%d: %v", kind, i+1, n, err)
+			continue
+		}
+		if _, err := b.feishuClient.SendImage(chatID, image); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to send %s %d/%d: %v", kind, i+1, n, err)
+			b.observeDelivery(ctx, err)
+			continue
+		}
+		logging.Printf(ctx, "[Bridge] Sent %s %d/%d to %s", kind, i+1, n, chatID)
+	}
+}
+
+// renderMath renders the formulas in reply and swaps each for its image,
+// so the reply goes out as rich text. Formulas that fail to render stay
+// TeX.
+func (b *Bridge) renderMath(ctx context.Context, reply string) string {
+	formulas := b.math.Formulas(reply)
+	for i, f := range formulas {
+		image, err := b.math.Render(ctx, f.TeX)
+		if err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to render formula %d/%d: %v", i+1, len(formulas), err)
+			continue
+		}
+		imageKey, err := b.feishuClient.UploadImage(image)
+		if err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to upload formula %d/%d: %v", i+1, len(formulas), err)
+			b.observeDelivery(ctx, err)
+			continue
+		}
+		reply = strings.ReplaceAll(reply, f.Source, feishu.ImageRef("公式", imageKey))
+	}
+	return reply
+}
@@ -0,0 +1,78 @@
+package bridge
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/analytics"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// digestBucket remembers when the last weekly digest and scheduled
+// reports were posted
+const digestBucket = "analytics"
+
+// digestLoop posts the weekly usage digest at the configured weekday and hour
+func (b *Bridge) digestLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+
+		cfg := b.cfg.Analytics
+		if now.Weekday() != cfg.DigestWeekday || now.Hour() != cfg.DigestHour {
+			continue
+		}
+
+		var last time.Time
+		if _, err := b.store.Get(digestBucket, "last_digest", &last); err != nil {
+			log.Printf("[Bridge] Failed to read last digest time: %v", err)
+			continue
+		}
+		// Already posted this week, possibly before a restart
+		if now.Sub(last) < 24*time.Hour {
+			continue
+		}
+
+		// Another instance sharing state may be posting the same digest
+		first, err := b.shared.Claim(ctx, "digest:"+now.Format("2006-01-02"), 24*time.Hour)
+		if err != nil {
+			log.Printf("[Bridge] Failed to claim weekly digest: %v", err)
+			continue
+		}
+		if !first {
+			continue
+		}
+
+		if err := b.postDigest(now); err != nil {
+			log.Printf("[Bridge] Failed to post weekly digest: %v", err)
+			continue
+		}
+		if err := b.store.Set(digestBucket, "last_digest", now); err != nil {
+			log.Printf("[Bridge] Failed to save last digest time: %v", err)
+		}
+	}
+}
+
+// postDigest sends the usage report of the week before now to the digest chat
+func (b *Bridge) postDigest(now time.Time) error {
+	report, err := analytics.Build(b.transcripts, now.AddDate(0, 0, -7), now)
+	if err != nil {
+		return err
+	}
+
+	card := feishu.NewCard("每周使用报告", "blue")
+	card.AddMarkdown(report.Markdown())
+	if _, err := b.feishuClient.SendCard(b.cfg.Analytics.DigestChat, card); err != nil {
+		return err
+	}
+	log.Printf("[Bridge] Posted weekly digest to %s (%d messages)", b.cfg.Analytics.DigestChat, report.Messages)
+	return nil
+}
@@ -0,0 +1,52 @@
+package bridge
+
+import (
+	"regexp"
+	"strings"
+)
+
+// directive matches any marker the agent writes for the bridge to carry
+// out, such as [[memory:set 名称=内容]] or [[task:create 标题]]; sheet
+// markers span lines
+var directive = regexp.MustCompile(`\[\[(?:memory|task|approval|group|helpdesk):[^\]\n]*\]\]|\[\[sheet:[^\]]*\]\]`)
+
+// directivePrefixes start the markers directive matches
+var directivePrefixes = []string{"[[memory:", "[[task:", "[[sheet:", "[[approval:", "[[group:", "[[helpdesk:"}
+
+// directivesEnabled reports whether the agent is told about any markers
+func (b *Bridge) directivesEnabled() bool {
+	return b.cfg.Memory.Enabled || b.cfg.Tasks.Enabled || b.cfg.Sheets.Enabled || b.cfg.Approvals.Enabled || b.cfg.Groups.Enabled || b.cfg.Helpdesk.Enabled
+}
+
+// joinNotes joins the non-empty notes reporting carried out markers
+func joinNotes(notes ...string) string {
+	var lines []string
+	for _, note := range notes {
+		if note != "" {
+			lines = append(lines, note)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// stripDirectives removes the bridge's markers from text, including one it
+// ends partway through while streaming
+func stripDirectives(text string) string {
+	if !strings.Contains(text, "[[") {
+		return text
+	}
+	text = directive.ReplaceAllString(text, "")
+	if i := strings.LastIndex(text, "[["); i >= 0 && !strings.Contains(text[i:], "]]") {
+		tail := text[i:]
+		for _, prefix := range directivePrefixes {
+			if strings.HasPrefix(tail, prefix) || strings.HasPrefix(prefix, tail) {
+				text = text[:i]
+				break
+			}
+		}
+	}
+	for strings.Contains(text, "\n\n\n") {
+		text = strings.ReplaceAll(text, "\n\n\n", "\n\n")
+	}
+	return strings.TrimSpace(text)
+}
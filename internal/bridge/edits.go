@@ -0,0 +1,51 @@
+package bridge
+
+import (
+	"context"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/metrics"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+const (
+	// editAttempts bounds the tries of one reply edit
+	editAttempts = 3
+	// editRetryDelay is the pause before the first retry; it doubles
+	// after each one
+	editRetryDelay = 300 * time.Millisecond
+)
+
+// editReply replaces the text of a reply sent with sendReply, retrying
+// with backoff when Feishu rate-limits the edit or fails on its side
+func (b *Bridge) editReply(ctx context.Context, messageID string, post bool, text string) error {
+	delay := editRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := b.updateReply(messageID, post, text)
+		b.pacer.observe(err)
+		if err == nil || attempt == editAttempts || !feishu.IsRetryable(err) {
+			return err
+		}
+		logging.Printf(ctx, "[Bridge] Retrying edit of %s in %s: %v", messageID, delay, err)
+		metrics.Inc("edit_retries")
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// moveReply sends text as a new message in place of messageID, which
+// Feishu won't let the bot edit any more, so later edits go to the new
+// one. The old message is deleted when Feishu allows it.
+func (b *Bridge) moveReply(ctx context.Context, chatID, messageID, text string) (string, bool, error) {
+	newID, post, err := b.sendReply(chatID, text)
+	if err != nil {
+		return "", post, err
+	}
+	logging.Printf(ctx, "[Bridge] Message %s can't be edited any more, continuing in %s", messageID, newID)
+	metrics.Inc("replies_moved")
+	if err := b.feishuClient.DeleteMessage(messageID); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to delete uneditable message %s: %v", messageID, err)
+	}
+	return newID, post, nil
+}
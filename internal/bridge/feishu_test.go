@@ -0,0 +1,63 @@
+package bridge_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/backend"
+	"github.com/wy51ai/moltbotCNAPP/internal/bridge"
+	"github.com/wy51ai/moltbotCNAPP/internal/store"
+	"github.com/wy51ai/moltbotCNAPP/internal/testutil"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// TestFeishuRoundTrip sends a message over the fake Feishu server's long
+// connection and checks the reply the bridge posts through its API
+func TestFeishuRoundTrip(t *testing.T) {
+	server := testutil.NewFeishuServer()
+	defer server.Close()
+
+	cfg := testutil.Config(t, "")
+	agent := &testutil.FakeBackend{Reply: "收到，正在处理"}
+	router := testutil.Router(t, cfg, map[string]backend.Backend{"default": agent})
+	st, err := store.Open(filepath.Join(t.TempDir(), "bridge-state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared := store.NewLocal()
+	defer shared.Close()
+
+	b := bridge.NewBridge(nil, router, st, shared, cfg)
+	client := feishu.NewClient(cfg.Feishu.AppID, cfg.Feishu.AppSecret, b.HandleMessage, feishu.WithDomain(server.URL))
+	b.SetFeishuClient(client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.Start(ctx)
+	select {
+	case <-server.Connected():
+	case <-time.After(10 * time.Second):
+		t.Fatal("client didn't open the long connection")
+	}
+
+	if err := server.PushText("oc_p2p", "p2p", "ou_alice", "你好"); err != nil {
+		t.Fatal(err)
+	}
+	replied := server.WaitFor(10*time.Second, func(msgs []testutil.Message) bool {
+		for _, m := range msgs {
+			if m.ChatID == "oc_p2p" && !m.Deleted && strings.Contains(m.Text, "收到，正在处理") {
+				return true
+			}
+		}
+		return false
+	})
+	if !replied {
+		t.Fatalf("no reply in oc_p2p; messages: %+v", server.Messages())
+	}
+	if asks := agent.Asks(); len(asks) != 1 || asks[0].Text != "你好" {
+		t.Errorf("backend asks = %+v, want one for 你好", asks)
+	}
+}
@@ -0,0 +1,50 @@
+package bridge
+
+import (
+	"context"
+	"strings"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// chatFinalOnlyBucket marks the chats that get only the finished answer,
+// without a placeholder or streamed text
+const chatFinalOnlyBucket = "chat_final_only"
+
+const finalOnlyUsage = "/final [on|off] 查看或设置当前会话是否只发送最终回答（开启后不显示\"思考中\"提示和逐字输出，回答完成后一次性发送）"
+
+// cmdFinalOnly shows or sets whether the chat gets only final answers
+func cmdFinalOnly(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	var err error
+	switch strings.ToLower(args) {
+	case "":
+		if b.finalOnly(msg.ChatID) {
+			return "当前会话只发送最终回答\n\n" + finalOnlyUsage
+		}
+		return "当前会话流式显示回答\n\n" + finalOnlyUsage
+	case "on", "开":
+		err = b.store.Set(chatFinalOnlyBucket, msg.ChatID, true)
+	case "off", "关":
+		err = b.store.Delete(chatFinalOnlyBucket, msg.ChatID)
+	default:
+		return finalOnlyUsage
+	}
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to save final-only setting for %s: %v", msg.ChatID, err)
+		return "设置失败，请稍后重试"
+	}
+	logging.Printf(ctx, "[Bridge] %s turned final-only %s in %s", msg.SenderID, strings.ToLower(args), msg.ChatID)
+	if b.finalOnly(msg.ChatID) {
+		return "已开启：之后的回答完成后一次性发送，不再显示\"思考中\"提示和逐字输出"
+	}
+	return "已关闭：恢复流式显示回答"
+}
+
+// finalOnly reports whether chatID gets only the finished answer, in a
+// single message
+func (b *Bridge) finalOnly(chatID string) bool {
+	var on bool
+	ok, err := b.store.Get(chatFinalOnlyBucket, chatID, &on)
+	return err == nil && ok && on
+}
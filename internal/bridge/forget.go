@@ -0,0 +1,311 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/backend"
+	"github.com/wy51ai/moltbotCNAPP/internal/deadletter"
+	"github.com/wy51ai/moltbotCNAPP/internal/jsonl"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/transcript"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+const forgetUsage = "/忘记我 删除你的使用记录、私聊设置，并清空私聊会话上下文"
+
+// ForgetReport lists what ForgetUser removed
+type ForgetReport struct {
+	UserID string
+	// Transcripts counts deleted usage records
+	Transcripts int
+	// Shadow counts deleted shadow comparison records
+	Shadow int
+	// Recordings counts deleted gateway run recordings
+	Recordings int
+	// Preferences is set when the user's /pref preferences were deleted
+	Preferences bool
+	// Chats are the user's private chats whose settings were cleared
+	Chats []string
+	// Sessions counts private chats whose sessions were reset on every backend
+	Sessions int
+	// SharedSession is set when all chats share one configured session,
+	// which is left alone
+	SharedSession bool
+	// Failures describes steps that didn't complete
+	Failures []string
+}
+
+// Text renders the report as a confirmation for the user
+func (r ForgetReport) Text() string {
+	var sb strings.Builder
+	if len(r.Failures) == 0 {
+		sb.WriteString("已删除你的数据：\n")
+	} else {
+		sb.WriteString("部分数据未能删除：\n")
+	}
+	fmt.Fprintf(&sb, "- 使用记录：%d 条\n", r.Transcripts)
+	if r.Shadow > 0 {
+		fmt.Fprintf(&sb, "- 模型对比记录：%d 条\n", r.Shadow)
+	}
+	if r.Recordings > 0 {
+		fmt.Fprintf(&sb, "- 网关运行录制：%d 条\n", r.Recordings)
+	}
+	if r.Preferences {
+		sb.WriteString("- 个人偏好：已删除\n")
+	}
+	fmt.Fprintf(&sb, "- 私聊设置（后端、Agent、模型）：%d 个会话\n", len(r.Chats))
+	if r.SharedSession {
+		sb.WriteString("- 会话上下文：所有会话共用同一个会话，未清空\n")
+	} else {
+		fmt.Fprintf(&sb, "- 会话上下文：已重置 %d 个\n", r.Sessions)
+	}
+	for _, f := range r.Failures {
+		fmt.Fprintf(&sb, "- 失败：%s\n", f)
+	}
+	sb.WriteString("\n群聊中的共享上下文无法按人删除；事件日志和审计日志按保留策略到期删除。")
+	return sb.String()
+}
+
+// ForgetUser deletes userID's transcripts and, for each of the user's
+// private chats, the chat's settings and conversation sessions. chats
+// adds private chats known to the caller, e.g. the one the request came
+// from; the rest are found in the transcripts before they're deleted.
+// actor is recorded in the audit log as who asked for the deletion.
+func (b *Bridge) ForgetUser(ctx context.Context, actor, userID string, chats ...string) ForgetReport {
+	report := ForgetReport{UserID: userID}
+	fail := func(step string, err error) {
+		logging.Printf(ctx, "[Bridge] Forget %s: %s: %v", userID, step, err)
+		report.Failures = append(report.Failures, step)
+	}
+
+	private := make(map[string]bool)
+	for _, c := range chats {
+		private[c] = true
+	}
+	if b.transcripts != nil {
+		err := b.transcripts.Query(time.Time{}, time.Now().Add(time.Minute), func(r transcript.Record) bool {
+			if r.UserID == userID && r.ChatType == "p2p" {
+				private[r.ChatID] = true
+			}
+			return true
+		})
+		if err != nil {
+			fail("查找私聊会话", err)
+		}
+	}
+	for c := range private {
+		report.Chats = append(report.Chats, c)
+	}
+	sort.Strings(report.Chats)
+
+	n, err := b.transcripts.DeleteUser(userID)
+	report.Transcripts = n
+	if err != nil {
+		fail("删除使用记录", err)
+	}
+
+	// The shadow log has no user IDs, only the chats prompts came from
+	report.Shadow, err = b.forgetShadow(private)
+	if err != nil {
+		fail("删除模型对比记录", err)
+	}
+
+	if _, ok := b.userPrefs(userID); ok {
+		if err := b.store.Delete(userPrefBucket, userID); err != nil {
+			fail("删除个人偏好", err)
+		} else {
+			report.Preferences = true
+		}
+	}
+
+	if _, err := b.forgetVotes(userID); err != nil {
+		fail("删除投票记录", err)
+	}
+	if err := b.store.Delete(ticketBucket, userID); err != nil {
+		fail("删除工单记录", err)
+	}
+	if err := b.forgetRetryPrompts(userID); err != nil {
+		fail("删除待重试消息", err)
+	}
+	if err := b.forgetSurveys(userID); err != nil {
+		fail("删除满意度调查", err)
+	}
+	if err := b.forgetQASamples(userID); err != nil {
+		fail("删除 QA 抽样", err)
+	}
+	if _, err := deadletter.DeleteUser(b.store, userID); err != nil {
+		fail("删除未送达回复", err)
+	}
+
+	report.SharedSession = b.sessionKey != ""
+	// Recordings have no user IDs either, only session keys, which
+	// identify the user's chats unless all chats share one
+	if !report.SharedSession {
+		report.Recordings, err = b.forgetRecordings(report.Chats)
+		if err != nil {
+			fail("删除网关运行录制", err)
+		}
+	}
+	for _, chatID := range report.Chats {
+		b.forgetChat(chatID, &report, fail)
+	}
+
+	logging.Printf(ctx, "[Bridge] Forgot user %s: %d records, %d chats, %d sessions, %d failures",
+		userID, report.Transcripts, len(report.Chats), report.Sessions, len(report.Failures))
+	b.audit.Record(ctx, audit.Event{Action: "user.forget", Actor: actor, Target: userID,
+		Detail: fmt.Sprintf("%d records, %d chats, %d failures", report.Transcripts, len(report.Chats), len(report.Failures))})
+	return report
+}
+
+// forgetShadow removes shadow comparison records of chats
+func (b *Bridge) forgetShadow(chats map[string]bool) (int, error) {
+	if b.keysErr != nil {
+		return 0, b.keysErr
+	}
+	if b.shadow != nil {
+		b.shadow.mu.Lock()
+		defer b.shadow.mu.Unlock()
+	}
+	files, err := ShadowFiles(b.cfg)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, path := range files {
+		n, err := jsonl.Filter(path, func(line []byte) bool {
+			plain, err := b.keys.Open(line)
+			if err != nil {
+				return false
+			}
+			var r shadowRecord
+			return json.Unmarshal(plain, &r) == nil && chats[r.ChatID]
+		})
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// forgetRecordings removes the gateway run recordings of chats' sessions
+func (b *Bridge) forgetRecordings(chats []string) (int, error) {
+	dir := b.cfg.Clawdbot.RecordDir
+	if dir == "" {
+		return 0, nil
+	}
+	if b.keysErr != nil {
+		return 0, b.keysErr
+	}
+	sessions := make(map[string]bool)
+	for _, chatID := range chats {
+		for _, key := range b.chatSessionKeys(chatID) {
+			// Shadow runs use their own session beside the chat's
+			sessions[key] = true
+			sessions["shadow:"+key] = true
+		}
+	}
+	return backend.DeleteRecordings(dir, b.keys, func(rec *backend.Recording) bool {
+		return sessions[rec.SessionKey]
+	})
+}
+
+// chatSessionKeys returns the session keys chatID has used: its current
+// one and those of its branches
+func (b *Bridge) chatSessionKeys(chatID string) []string {
+	sessionKeys := []string{b.sessionKeyFor(chatID)}
+	var forks chatForks
+	if ok, _ := b.store.Get(chatForkBucket, chatID, &forks); ok {
+		for _, name := range append([]string{mainBranch}, forks.Branches...) {
+			if key := forks.branchKey(name); key != sessionKeys[0] {
+				sessionKeys = append(sessionKeys, key)
+			}
+		}
+	}
+	return sessionKeys
+}
+
+// forgetChat clears one private chat's settings and sessions
+func (b *Bridge) forgetChat(chatID string, report *ForgetReport, fail func(string, error)) {
+	if err := b.router.SetOverride(chatID, ""); err != nil {
+		fail("清除后端设置 "+chatID, err)
+	}
+	if err := b.store.Delete(chatAgentBucket, chatID); err != nil {
+		fail("清除 Agent 设置 "+chatID, err)
+	}
+	if err := b.store.Delete(chatContextBucket, chatID); err != nil {
+		fail("清除背景信息 "+chatID, err)
+	}
+	if err := b.store.Delete(savedPromptBucket, chatID); err != nil {
+		fail("清除提示词模板 "+chatID, err)
+	}
+	if err := b.store.Delete(chatMemoryBucket, chatID); err != nil {
+		fail("清除记忆 "+chatID, err)
+	}
+	if err := b.store.Delete(chatThoughtsBucket, chatID); err != nil {
+		fail("清除思考过程设置 "+chatID, err)
+	}
+	if err := b.store.Delete(chatFinalOnlyBucket, chatID); err != nil {
+		fail("清除最终回答设置 "+chatID, err)
+	}
+	if err := b.untrackChatTasks(chatID); err != nil {
+		fail("清除任务记录 "+chatID, err)
+	}
+	if err := b.untrackChatApprovals(chatID); err != nil {
+		fail("清除审批记录 "+chatID, err)
+	}
+	sessionKeys := b.chatSessionKeys(chatID)
+	if err := b.store.Delete(chatSessionBucket, chatID); err != nil {
+		fail("清除会话映射 "+chatID, err)
+	}
+	// Chats linked to this one would otherwise keep receiving its context
+	if err := b.store.Delete(chatLinkBucket, chatID); err != nil {
+		fail("清除会话共享 "+chatID, err)
+	}
+	for _, linked := range b.linkedChats(chatID) {
+		link, _ := b.chatLink(linked)
+		if err := b.unlinkChat(linked, link); err != nil {
+			fail("取消会话共享 "+linked, err)
+		}
+	}
+	if err := b.store.Delete(chatForkBucket, chatID); err != nil {
+		fail("清除会话分支 "+chatID, err)
+	}
+	if report.SharedSession {
+		return
+	}
+	reset := true
+	for _, name := range b.router.Names() {
+		agent, _ := b.router.Backend(name)
+		for _, sessionKey := range sessionKeys {
+			if switcher, ok := agent.(backend.ModelSwitcher); ok {
+				switcher.SetModel(sessionKey, "")
+			}
+			if err := agent.ResetSession(sessionKey); err != nil {
+				fail(fmt.Sprintf("重置后端 %s 的会话 %s", name, sessionKey), err)
+				reset = false
+			}
+		}
+	}
+	if reset {
+		report.Sessions++
+	}
+}
+
+// cmdForget deletes the sender's own data
+func cmdForget(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if msg.SenderID == "" {
+		return "无法识别你的身份，未删除任何数据"
+	}
+	var chats []string
+	if msg.ChatType == "p2p" {
+		chats = append(chats, msg.ChatID)
+	}
+	return b.ForgetUser(ctx, msg.SenderID, msg.SenderID, chats...).Text()
+}
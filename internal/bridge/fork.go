@@ -0,0 +1,214 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/backend"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/internal/transcript"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// chatForkBucket stores each chat's session branches
+const chatForkBucket = "chat_fork"
+
+// mainBranch names the session a chat had before forking
+const mainBranch = "main"
+
+// forkContextTurns is how many recent exchanges seed a branch copied
+// from the transcript
+const forkContextTurns = 10
+
+// forkSeedTimeout bounds the run that hands a branch its context
+const forkSeedTimeout = 2 * time.Minute
+
+// branchName is what /fork accepts as a branch name
+var branchName = regexp.MustCompile(`^[\p{L}\p{N}_-]{1,32}$`)
+
+// errNothingToCopy means neither the backend nor the transcript could
+// supply the conversation to fork
+var errNothingToCopy = errors.New("no conversation to copy")
+
+// chatForks are a chat's branches
+type chatForks struct {
+	// Base is the session key the chat had before its first fork
+	Base     string   `json:"base"`
+	Branches []string `json:"branches"`
+	// Current is the branch in use; empty for main
+	Current string `json:"current,omitempty"`
+}
+
+// branchKey returns the session key of a branch
+func (f chatForks) branchKey(name string) string {
+	if name == mainBranch || name == "" {
+		return f.Base
+	}
+	return f.Base + ":fork:" + name
+}
+
+func (f chatForks) has(name string) bool {
+	for _, b := range f.Branches {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+const forkUsage = "/fork [分支名|main] 把当前会话复制为新分支并切换过去，或切换到已有分支；main 为原会话"
+
+// cmdFork lists, creates and switches the chat's session branches
+func cmdFork(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if link, ok := b.chatLink(msg.ChatID); ok {
+		return fmt.Sprintf("当前会话共用 %s 的上下文，不能创建分支；请先发送 /unlink", link.From)
+	}
+	var forks chatForks
+	ok, err := b.store.Get(chatForkBucket, msg.ChatID, &forks)
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to read branches of %s: %v", msg.ChatID, err)
+		return "读取分支失败，请稍后再试"
+	}
+	if !ok {
+		forks.Base = b.sessionKeyFor(msg.ChatID)
+	}
+
+	if args == "" {
+		current := forks.Current
+		if current == "" {
+			current = mainBranch
+		}
+		branches := append([]string{mainBranch}, forks.Branches...)
+		return fmt.Sprintf("当前分支：%s\n全部分支：%s\n\n%s", current, strings.Join(branches, ", "), forkUsage)
+	}
+	if args != mainBranch && !branchName.MatchString(args) {
+		return "分支名只能包含文字、数字、下划线和连字符，最长 32 个字符"
+	}
+	if args == mainBranch || forks.has(args) {
+		if err := b.switchBranch(msg.ChatID, &forks, args); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to switch %s to branch %s: %v", msg.ChatID, args, err)
+			return fmt.Sprintf("切换失败：%s", redact.Error(err))
+		}
+		b.audit.Record(ctx, audit.Event{Action: "session.switch", Actor: msg.SenderID, ChatID: msg.ChatID, Target: args})
+		return fmt.Sprintf("已切换到分支：%s", args)
+	}
+
+	req := b.chatRun(ctx, msg.ChatID)
+	// Agent sessions hang off the chat's key, so the branch keeps the suffix
+	suffix := strings.TrimPrefix(req.sessionKey, b.sessionKeyFor(msg.ChatID))
+	to := forks.branchKey(args) + suffix
+	how, err := b.cloneSession(ctx, req, to)
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to fork %s into %s: %v", req.sessionKey, to, err)
+		if errors.Is(err, errNothingToCopy) {
+			return "无法复制当前会话：后端不支持会话分支，且没有可用的对话记录（需开启 transcripts.store_content）"
+		}
+		return fmt.Sprintf("创建分支失败：%s", redact.Error(err))
+	}
+	forks.Branches = append(forks.Branches, args)
+	if err := b.switchBranch(msg.ChatID, &forks, args); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to switch %s to branch %s: %v", msg.ChatID, args, err)
+		return fmt.Sprintf("分支已创建，但切换失败：%s", redact.Error(err))
+	}
+	logging.Printf(ctx, "[Bridge] Forked %s into %s (%s)", req.sessionKey, to, how)
+	b.audit.Record(ctx, audit.Event{Action: "session.fork", Actor: msg.SenderID, ChatID: msg.ChatID, Target: args, Detail: how})
+	return fmt.Sprintf("已创建分支 %s 并切换过去，之后的对话不影响原会话。发送 /fork main 回到原会话。", args)
+}
+
+// switchBranch points chatID's session at a branch and saves forks
+func (b *Bridge) switchBranch(chatID string, forks *chatForks, name string) error {
+	key := forks.branchKey(name)
+	if key == b.defaultSessionKey(chatID) {
+		if err := b.store.Delete(chatSessionBucket, chatID); err != nil {
+			return err
+		}
+	} else if err := b.store.Set(chatSessionBucket, chatID, key); err != nil {
+		return err
+	}
+	forks.Current = name
+	if name == mainBranch {
+		forks.Current = ""
+	}
+	return b.store.Set(chatForkBucket, chatID, forks)
+}
+
+// chatRun returns the backend, agent and session a plain message in
+// chatID would go to
+func (b *Bridge) chatRun(ctx context.Context, chatID string) *runRequest {
+	req := &runRequest{chatID: chatID, sessionKey: b.sessionKeyFor(chatID)}
+	req.backendName, req.agent, _ = b.router.Route(chatID, "")
+	if sel, ok := b.chatAgent(ctx, chatID); ok {
+		if sel.Name != "" {
+			b.bindAgent(req, sel.Name)
+			req.agentName = ""
+		} else {
+			bindAgentID(req, sel.AgentID)
+		}
+	}
+	return req
+}
+
+// cloneSession copies req's session into the new session to and says
+// how: through the backend if it can fork sessions, from the state it
+// keeps in the bridge, or by replaying recent transcript exchanges
+func (b *Bridge) cloneSession(ctx context.Context, req *runRequest, to string) (string, error) {
+	raw, _ := b.router.Backend(req.backendName)
+	if f, ok := raw.(backend.SessionForker); ok {
+		err := f.ForkSession(req.sessionKey, to)
+		if err == nil {
+			return "backend", nil
+		}
+		logging.Printf(ctx, "[Bridge] Backend %s couldn't fork %s, trying the transcript: %v", req.backendName, req.sessionKey, err)
+	}
+	if s, ok := raw.(backend.SessionSnapshotter); ok {
+		state := s.SessionState(req.sessionKey)
+		s.RestoreSession(to, state)
+		if len(state.History) > 0 {
+			return "history", nil
+		}
+	}
+	return "transcript", b.seedFromTranscript(ctx, req, to)
+}
+
+// seedFromTranscript hands the session to the chat's last exchanges on
+// req's backend, as stored in the transcript
+func (b *Bridge) seedFromTranscript(ctx context.Context, req *runRequest, to string) error {
+	if b.transcripts == nil || !b.cfg.Transcripts.StoreContent {
+		return errNothingToCopy
+	}
+	var recent []transcript.Record
+	err := b.transcripts.Query(time.Time{}, time.Now().Add(time.Minute), func(r transcript.Record) bool {
+		if r.Kind == transcript.KindMessage && r.ChatID == req.chatID && r.Backend == req.backendName &&
+			r.Agent == req.agentName && r.Prompt != "" && r.Reply != "" {
+			recent = append(recent, r)
+			if len(recent) > forkContextTurns {
+				recent = recent[1:]
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read transcript: %w", err)
+	}
+	if len(recent) == 0 {
+		return errNothingToCopy
+	}
+
+	var seed strings.Builder
+	seed.WriteString("以下是此前对话的记录，请把它作为上下文，之后的问题在此基础上继续。本条无需回复。\n")
+	for _, r := range recent {
+		fmt.Fprintf(&seed, "\n用户：%s\n助手：%s\n", r.Prompt, r.Reply)
+	}
+	seedCtx, cancel := context.WithTimeout(ctx, forkSeedTimeout)
+	defer cancel()
+	if _, err := req.agent.Ask(seedCtx, seed.String(), to, nil); err != nil {
+		return fmt.Errorf("failed to hand the branch its context: %w", err)
+	}
+	return nil
+}
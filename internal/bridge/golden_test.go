@@ -0,0 +1,27 @@
+package bridge_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/testutil/golden"
+)
+
+// TestGolden plays each scripted conversation in testdata through the
+// bridge against its recorded gateway runs and compares the Feishu
+// operations with the .golden file. Run with -update to rewrite them.
+func TestGolden(t *testing.T) {
+	scripts, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) == 0 {
+		t.Fatal("no scripts in testdata")
+	}
+	for _, path := range scripts {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".json"), func(t *testing.T) {
+			golden.Run(t, path)
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// maxGroupName bounds a group name, in characters
+const maxGroupName = 60
+
+// groupDirective matches [[group:create 群名|成员,成员]],
+// [[group:add 成员,成员]], [[group:remove 成员,成员]] and
+// [[group:rename 群名]]
+var groupDirective = regexp.MustCompile(`\[\[group:(create|add|remove|rename)\s+([^\]\n]*?)\s*\]\]`)
+
+// groupManager is implemented by Feishu clients that can create groups
+// and manage their members
+type groupManager interface {
+	CreateChat(input feishu.ChatInput) (*feishu.Chat, error)
+	AddChatMembers(chatID string, ids []string) ([]string, error)
+	RemoveChatMembers(chatID string, ids []string) ([]string, error)
+	SetChatName(chatID, name string) error
+	ChatLink(chatID string) (string, error)
+}
+
+const groupUsage = "/group create 群名 [@成员] [团队] 创建群聊并拉入自己和成员；/group add @成员|团队 把成员加入本群；/group remove @成员|团队 把成员移出本群；/group rename 群名 修改本群名称；/group teams 列出团队（需管理员）"
+
+// cmdGroup creates a group or manages the members and name of this one
+func cmdGroup(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if !b.cfg.Groups.Enabled {
+		return "群管理功能未启用"
+	}
+	sub, rest := cutSpace(args)
+	sub = strings.ToLower(sub)
+	switch sub {
+	case "", "teams":
+		return describeTeams(b.cfg.Groups.Teams) + "\n\n" + groupUsage
+	case "create", "add", "remove", "rename":
+	default:
+		return groupUsage
+	}
+
+	action := "group." + sub
+	if !b.cfg.IsAdmin(msg.SenderID) {
+		logging.Printf(ctx, "[Bridge] Denied /group %s from non-admin %s in %s", sub, msg.SenderID, msg.ChatID)
+		b.audit.Record(ctx, audit.Event{Action: action, Actor: msg.SenderID, ChatID: msg.ChatID, Outcome: audit.Denied, Detail: "not an admin"})
+		return "只有管理员可以管理群聊"
+	}
+	if sub != "create" && msg.ChatType != "group" {
+		return "请在要管理的群聊中使用"
+	}
+
+	switch sub {
+	case "create":
+		name, words := cutSpace(rest)
+		members, err := b.groupMembers(strings.Fields(words), msg.SenderID)
+		if err != nil {
+			return err.Error()
+		}
+		if name == "" {
+			return groupUsage
+		}
+		members = append(members, taskAssignees(msg)...)
+		return b.createGroup(ctx, msg.ChatID, msg.SenderID, name, members, approvalUUID(msg.MessageID))
+	case "rename":
+		if rest == "" {
+			return groupUsage
+		}
+		return b.renameGroup(ctx, msg.ChatID, msg.SenderID, rest)
+	}
+	members, err := b.groupMembers(strings.Fields(rest), msg.SenderID)
+	if err != nil {
+		return err.Error()
+	}
+	members = append(members, taskAssignees(msg)...)
+	if len(members) == 0 {
+		return groupUsage
+	}
+	return b.requestMemberChange(ctx, msg.ChatID, msg.SenderID, sub, members)
+}
+
+// groupMembers resolves team names, "我" (actor) and open_ids to open_ids
+func (b *Bridge) groupMembers(words []string, actor string) ([]string, error) {
+	var ids []string
+	for _, word := range words {
+		switch {
+		case word == "我":
+			ids = append(ids, actor)
+		case strings.HasPrefix(word, "ou_"):
+			ids = append(ids, word)
+		default:
+			team, ok := b.cfg.Groups.Teams[word]
+			if !ok {
+				return nil, fmt.Errorf("没有名为 %s 的团队\n\n%s", word, describeTeams(b.cfg.Groups.Teams))
+			}
+			ids = append(ids, team...)
+		}
+	}
+	return ids, nil
+}
+
+// createGroup creates a group named name with actor and members in it,
+// approves it for the bot and returns the text reporting it
+func (b *Bridge) createGroup(ctx context.Context, chatID, actor, name string, members []string, uuid string) string {
+	manager, ok := b.feishuClient.(groupManager)
+	if !ok {
+		return "当前连接不支持管理群聊"
+	}
+	if n := utf8.RuneCountInString(name); n > maxGroupName {
+		return fmt.Sprintf("群名过长（%d 字），最多 %d 字", n, maxGroupName)
+	}
+	members = uniqueIDs(append([]string{actor}, members...))
+	chat, err := manager.CreateChat(feishu.ChatInput{
+		Name:        name,
+		Description: "由飞书机器人创建",
+		Members:     members,
+		UUID:        uuid,
+	})
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to create group %q for %s: %v", name, actor, err)
+		return fmt.Sprintf("创建群聊失败：%s", redact.Error(err))
+	}
+
+	// An admin asked for the group, so it needs no separate approval
+	if b.cfg.Approval.Enabled {
+		now := time.Now()
+		b.approvalMu.Lock()
+		err := b.store.Set(chatApprovalBucket, chat.ChatID, chatApproval{
+			Status: approvalApproved, Name: name, RequestedBy: actor, Requested: now, DecidedBy: actor, Decided: now,
+		})
+		b.approvalMu.Unlock()
+		if err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to approve created group %s: %v", chat.ChatID, err)
+		}
+	}
+	welcome := fmt.Sprintf(`本群由 <at user_id="%s"></at> 通过机器人创建，@我 即可提问`, actor)
+	if _, err := b.feishuClient.SendMessage(chat.ChatID, welcome); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to welcome created group %s: %v", chat.ChatID, err)
+	}
+
+	logging.Printf(ctx, "[Bridge] %s created group %s (%s) with %d members from %s", actor, chat.ChatID, name, len(members), chatID)
+	b.audit.Record(ctx, audit.Event{Action: "group.create", Actor: actor, ChatID: chatID, Target: chat.ChatID, Detail: name})
+	text := fmt.Sprintf("已创建群聊「%s」，拉入 %d 人", name, len(members))
+	if link, err := manager.ChatLink(chat.ChatID); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to get link of group %s: %v", chat.ChatID, err)
+	} else if link != "" {
+		text += "\n" + link
+	}
+	return text
+}
+
+// requestMemberChange changes the members of chatID like changeMembers,
+// or asks a second admin to confirm a removal first when two-person
+// confirmation covers it, and returns the text reporting it
+func (b *Bridge) requestMemberChange(ctx context.Context, chatID, actor, sub string, members []string) string {
+	if sub == "remove" && b.cfg.TwoPerson.Requires("group.remove") {
+		return b.requestConfirmation(ctx, pendingAction{
+			Action:      "group.remove",
+			ChatID:      chatID,
+			Target:      strings.Join(uniqueIDs(members), ","),
+			RequestedBy: actor,
+		})
+	}
+	text, _ := b.changeMembers(ctx, chatID, actor, sub, members, "")
+	return text
+}
+
+// changeMembers adds ("add") or removes ("remove") members to or from
+// chatID and returns the text reporting it. confirmedBy names the second
+// admin, if any. The error is only set when the change failed.
+func (b *Bridge) changeMembers(ctx context.Context, chatID, actor, sub string, members []string, confirmedBy string) (string, error) {
+	manager, ok := b.feishuClient.(groupManager)
+	if !ok {
+		return "当前连接不支持管理群聊", errors.New("the Feishu client can't manage groups")
+	}
+	members = uniqueIDs(members)
+	change, verb := manager.AddChatMembers, "加入"
+	if sub == "remove" {
+		change, verb = manager.RemoveChatMembers, "移出"
+	}
+	skipped, err := change(chatID, members)
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to %s members of %s for %s: %v", sub, chatID, actor, err)
+		return fmt.Sprintf("%s成员失败：%s", verb, redact.Error(err)), err
+	}
+	logging.Printf(ctx, "[Bridge] %s ran group %s for %d members in %s", actor, sub, len(members), chatID)
+	detail := strings.Join(members, ",")
+	if confirmedBy != "" {
+		detail += ", confirmed by " + confirmedBy
+	}
+	b.audit.Record(ctx, audit.Event{Action: "group." + sub, Actor: actor, ChatID: chatID, Target: chatID, Detail: detail})
+	text := fmt.Sprintf("已将 %d 人%s本群", len(members)-len(skipped), verb)
+	if len(skipped) > 0 {
+		text += fmt.Sprintf("，%d 人未能%s（已离职、不在本群或等待群主审批）", len(skipped), verb)
+	}
+	return text, nil
+}
+
+// renameGroup renames chatID and returns the text reporting it
+func (b *Bridge) renameGroup(ctx context.Context, chatID, actor, name string) string {
+	manager, ok := b.feishuClient.(groupManager)
+	if !ok {
+		return "当前连接不支持管理群聊"
+	}
+	if n := utf8.RuneCountInString(name); n > maxGroupName {
+		return fmt.Sprintf("群名过长（%d 字），最多 %d 字", n, maxGroupName)
+	}
+	if err := manager.SetChatName(chatID, name); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to rename %s for %s: %v", chatID, actor, err)
+		return fmt.Sprintf("修改群名失败：%s", redact.Error(err))
+	}
+	logging.Printf(ctx, "[Bridge] %s renamed %s to %q", actor, chatID, name)
+	b.audit.Record(ctx, audit.Event{Action: "group.rename", Actor: actor, ChatID: chatID, Target: chatID, Detail: name})
+	return "已将群名改为「" + name + "」"
+}
+
+// applyGroups carries out the group markers in the agent's reply to req,
+// for admins only, and returns lines reporting them
+func (b *Bridge) applyGroups(ctx context.Context, req *runRequest, reply string) string {
+	if !b.cfg.Groups.Enabled || req.senderID == "" {
+		return ""
+	}
+	matches := groupDirective.FindAllStringSubmatch(reply, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	if !b.cfg.IsAdmin(req.senderID) {
+		logging.Printf(ctx, "[Bridge] Ignoring group directives for non-admin %s in %s", req.senderID, req.chatID)
+		b.audit.Record(ctx, audit.Event{Action: "group." + matches[0][1], Actor: req.senderID, ChatID: req.chatID, Outcome: audit.Denied, Detail: "not an admin"})
+		return "只有管理员可以让我管理群聊"
+	}
+
+	var notes []string
+	for i, m := range matches {
+		sub, args := m[1], strings.TrimSpace(m[2])
+		if sub == "create" {
+			name, list, _ := strings.Cut(args, "|")
+			members, err := b.groupMembers(splitMembers(list), req.senderID)
+			if err != nil {
+				notes = append(notes, err.Error())
+				continue
+			}
+			uuid := approvalUUID(fmt.Sprintf("%s:%s:%d", req.chatID, req.received.Format(time.RFC3339Nano), i))
+			notes = append(notes, b.createGroup(ctx, req.chatID, req.senderID, strings.TrimSpace(name), members, uuid))
+			continue
+		}
+		if req.chatType != "group" {
+			continue
+		}
+		if sub == "rename" {
+			notes = append(notes, b.renameGroup(ctx, req.chatID, req.senderID, args))
+			continue
+		}
+		members, err := b.groupMembers(splitMembers(args), req.senderID)
+		if err != nil {
+			notes = append(notes, err.Error())
+			continue
+		}
+		if len(members) > 0 {
+			notes = append(notes, b.requestMemberChange(ctx, req.chatID, req.senderID, sub, members))
+		}
+	}
+	return strings.Join(notes, "\n")
+}
+
+// groupsPrompt tells an admin's agent how to manage groups and which
+// teams it may add
+func (b *Bridge) groupsPrompt(userID string) string {
+	if !b.cfg.Groups.Enabled || !b.cfg.IsAdmin(userID) {
+		return ""
+	}
+	text := "如果用户要求建群或管理群成员，可在回答末尾单独一行写：[[group:create 群名|成员,成员]] 创建群聊（用户本人会自动加入），" +
+		"[[group:add 成员,成员]] 或 [[group:remove 成员,成员]] 把成员加入或移出当前群，[[group:rename 群名]] 修改当前群名称。" +
+		"成员可写“我”或下列团队名称。这些标记不会显示给用户。"
+	if len(b.cfg.Groups.Teams) == 0 {
+		return text
+	}
+	return text + "\n" + describeTeams(b.cfg.Groups.Teams)
+}
+
+// describeTeams lists the configured teams and their sizes
+func describeTeams(teams map[string][]string) string {
+	if len(teams) == 0 {
+		return "没有配置团队"
+	}
+	names := make([]string, 0, len(teams))
+	for name := range teams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := []string{"团队："}
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("- %s（%d 人）", name, len(teams[name])))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splitMembers splits a comma-separated member list
+func splitMembers(list string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == '，' || r == ' ' }) {
+		words = append(words, strings.TrimSpace(word))
+	}
+	return words
+}
+
+// uniqueIDs drops repeated and empty ids, keeping the first of each
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, id := range ids {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
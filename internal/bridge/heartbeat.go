@@ -0,0 +1,79 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/metrics"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// heartbeatLoop pings the heartbeat URL every interval while the bridge
+// is healthy. A degraded bridge just stops pinging, so the monitor on the
+// other end alerts even when the bridge can't.
+func (b *Bridge) heartbeatLoop(ctx context.Context) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	ticker := time.NewTicker(b.cfg.Alerts.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		if err := b.healthy(); err != nil {
+			log.Printf("[Bridge] Skipping heartbeat: %v", err)
+			metrics.Inc("heartbeats_skipped")
+		} else if err := ping(ctx, client, b.cfg.Alerts.HeartbeatURL); err != nil {
+			log.Printf("[Bridge] Failed to send heartbeat: %v", err)
+		} else {
+			metrics.Inc("heartbeats")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// healthy returns why the bridge can't serve messages right now, or nil:
+// the Feishu API and long connection must be up and every gateway reachable
+func (b *Bridge) healthy() error {
+	if b.feishuClient == nil {
+		return fmt.Errorf("feishu client not ready")
+	}
+	if c := b.feishuClient.Circuit(); c.State != feishu.CircuitClosed {
+		return fmt.Errorf("feishu API circuit %s", c.State)
+	}
+	if w, ok := b.feishuClient.(connectionWatcher); ok {
+		conn := w.Connection()
+		if conn.ConnectedAt.IsZero() {
+			return fmt.Errorf("feishu long connection not up yet")
+		}
+		if conn.Silent {
+			return fmt.Errorf("feishu long connection silent since %s", conn.LastActivity.Format(time.RFC3339))
+		}
+	}
+	if down := b.router.OpenCircuits(); len(down) > 0 {
+		return fmt.Errorf("gateway circuit open for %s", strings.Join(down, ", "))
+	}
+	return b.router.PingGateways()
+}
+
+// ping sends a GET to url and checks the answer
+func ping(ctx context.Context, client *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("heartbeat URL returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// ticketBucket stores each user's latest Helpdesk ticket, by open_id
+const ticketBucket = "helpdesk_ticket"
+
+// ticketCooldown is how long after a ticket a user isn't escalated
+// automatically again
+const ticketCooldown = 24 * time.Hour
+
+// ticketSummaryTimeout bounds the agent summarizing a conversation for a
+// ticket
+const ticketSummaryTimeout = time.Minute
+
+// helpdeskDirective matches [[helpdesk:escalate 问题摘要]]; the summary
+// is optional
+var helpdeskDirective = regexp.MustCompile(`\[\[helpdesk:escalate\s*([^\]\n]*?)\s*\]\]`)
+
+// ticketStarter is implemented by Feishu clients that can open Helpdesk
+// tickets
+type ticketStarter interface {
+	StartTicket(input feishu.TicketInput) (*feishu.Ticket, error)
+}
+
+// openedTicket is a ticket opened for a user
+type openedTicket struct {
+	ID       string    `json:"id"`
+	ChatID   string    `json:"chat_id"`
+	URL      string    `json:"url"`
+	Summary  string    `json:"summary"`
+	OpenedAt time.Time `json:"opened_at"`
+}
+
+// helpdeskInstructions tell the agent how to hand a user over to the
+// helpdesk
+const helpdeskInstructions = "如果你无法解决用户的问题，或用户要求转人工，可在回答末尾单独一行写 [[helpdesk:escalate 问题摘要]]，" +
+	"为用户创建服务台工单，由人工客服跟进；摘要写清问题、已尝试的方法和现状。这些标记不会显示给用户。"
+
+// ticketSummaryPrompt asks the agent to summarize the conversation for
+// the helpdesk agents
+const ticketSummaryPrompt = "用户要求转人工客服。请用几句话总结用户在本次对话中遇到的问题、已经尝试过的方法和目前的状态，供客服接手。只输出摘要本身。"
+
+const ticketUsage = "/工单 [问题描述] 转人工客服，在服务台创建工单；不写描述时由 Agent 总结当前对话"
+
+// cmdTicket opens a Helpdesk ticket for the sender
+func cmdTicket(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if !b.cfg.Helpdesk.Enabled {
+		return "服务台工单未启用"
+	}
+	summary := args
+	if summary == "" {
+		summary = b.summarizeForTicket(ctx, msg.ChatID)
+	}
+	return b.openTicket(ctx, msg.ChatID, msg.SenderID, summary, "request")
+}
+
+// summarizeForTicket has the chat's agent summarize the conversation,
+// falling back to a generic note
+func (b *Bridge) summarizeForTicket(ctx context.Context, chatID string) string {
+	req := b.chatRun(ctx, chatID)
+	ctx, cancel := context.WithTimeout(ctx, ticketSummaryTimeout)
+	defer cancel()
+	summary, err := req.agent.Ask(ctx, ticketSummaryPrompt, req.sessionKey, nil)
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to summarize %s for a ticket: %v", chatID, err)
+	}
+	if summary = strings.TrimSpace(summary); summary == "" {
+		return "用户在机器人会话中请求人工支持"
+	}
+	return summary
+}
+
+// openTicket opens a ticket for userID from chatID, reason being why, and
+// returns the text reporting it
+func (b *Bridge) openTicket(ctx context.Context, chatID, userID, summary, reason string) string {
+	starter, ok := b.feishuClient.(ticketStarter)
+	if !ok {
+		return "当前连接不支持创建服务台工单"
+	}
+	ticket, err := starter.StartTicket(feishu.TicketInput{User: userID, Agents: b.cfg.Helpdesk.Agents, Summary: summary})
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to open a ticket for %s in %s: %v", userID, chatID, err)
+		return fmt.Sprintf("创建服务台工单失败：%s", redact.Error(err))
+	}
+	opened := openedTicket{ID: ticket.ID, ChatID: ticket.ChatID, URL: ticket.URL, Summary: summary, OpenedAt: time.Now().UTC()}
+	if err := b.store.Set(ticketBucket, userID, opened); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to record ticket %s: %v", ticket.ID, err)
+	}
+	logging.Printf(ctx, "[Bridge] Opened ticket %s for %s from %s (%s)", ticket.ID, userID, chatID, reason)
+	b.audit.Record(ctx, audit.Event{Action: "helpdesk.ticket", Actor: userID, ChatID: chatID, Target: ticket.ID, Detail: reason})
+	return "已为你创建服务台工单，人工客服会在服务台会话中跟进：\n" + ticket.URL
+}
+
+// applyHelpdesk opens the ticket the agent's reply to req asks for and
+// returns the line reporting it
+func (b *Bridge) applyHelpdesk(ctx context.Context, req *runRequest, reply string) string {
+	if !b.cfg.Helpdesk.Enabled || req.senderID == "" {
+		return ""
+	}
+	m := helpdeskDirective.FindStringSubmatch(reply)
+	if m == nil {
+		return ""
+	}
+	summary := m[1]
+	if summary == "" {
+		summary = "用户的问题：" + req.text
+	}
+	return b.openTicket(ctx, req.chatID, req.senderID, summary, "agent")
+}
+
+// escalateOnFailure counts the sender's consecutive failed requests in
+// req's chat and opens a ticket once they reach the configured number,
+// unless the sender got one recently
+func (b *Bridge) escalateOnFailure(ctx context.Context, req *runRequest, failed bool) {
+	limit := b.cfg.Helpdesk.EscalateAfter
+	if !b.cfg.Helpdesk.Enabled || limit == 0 || req.senderID == "" {
+		return
+	}
+	key := req.chatID + "/" + req.senderID
+	b.ticketMu.Lock()
+	if b.runFailures == nil {
+		b.runFailures = make(map[string]int)
+	}
+	count := 0
+	if failed {
+		b.runFailures[key]++
+		count = b.runFailures[key]
+	}
+	if count == 0 || count >= limit {
+		delete(b.runFailures, key)
+	}
+	b.ticketMu.Unlock()
+	if count < limit {
+		return
+	}
+
+	var last openedTicket
+	if ok, _ := b.store.Get(ticketBucket, req.senderID, &last); ok && time.Since(last.OpenedAt) < ticketCooldown {
+		logging.Printf(ctx, "[Bridge] %s failed %d times in %s but already has ticket %s", req.senderID, count, req.chatID, last.ID)
+		return
+	}
+	summary := fmt.Sprintf("用户在机器人会话中的请求连续 %d 次处理失败，最近一条消息：%s", count, req.text)
+	go func() {
+		text := b.openTicket(context.WithoutCancel(ctx), req.chatID, req.senderID, summary, "failures")
+		text = fmt.Sprintf("你的请求已连续 %d 次处理失败。", count) + text
+		if _, err := b.feishuClient.SendMessage(req.chatID, text); err != nil {
+			log.Printf("[Bridge] Failed to post ticket to %s: %v", req.chatID, err)
+		}
+	}()
+}
@@ -0,0 +1,80 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/media"
+)
+
+// generatedImageNote stands in for the local path of an image sent below
+// the reply
+const generatedImageNote = "（图片见下方）"
+
+// loadImages loads the generated images refs name, skipping any that
+// can't be read or downloaded
+func (b *Bridge) loadImages(ctx context.Context, refs []string) []media.Image {
+	var images []media.Image
+	for _, ref := range refs {
+		img, err := b.images.Load(ctx, ref)
+		if err != nil {
+			logging.Printf(ctx, "[Bridge] Skipping generated image: %v", err)
+			continue
+		}
+		images = append(images, img)
+	}
+	return images
+}
+
+// sendGeneratedImages sends loaded images as image messages
+func (b *Bridge) sendGeneratedImages(ctx context.Context, chatID string, images []media.Image) {
+	b.sendImages(ctx, chatID, "image", len(images), func(i int) ([]byte, error) {
+		return images[i].Data, nil
+	})
+}
+
+// replaceImagePaths swaps the local paths of images about to be sent for
+// a note pointing at them. Paths mean nothing to chat members; URLs are
+// left as links.
+func replaceImagePaths(reply string, images []media.Image) string {
+	for _, img := range images {
+		if !img.Local {
+			continue
+		}
+		markdown := regexp.MustCompile(`!?\[[^\]]*\]\(` + regexp.QuoteMeta(img.Ref) + `\)`)
+		reply = markdown.ReplaceAllString(reply, generatedImageNote)
+		reply = strings.ReplaceAll(reply, img.Ref, generatedImageNote)
+	}
+	return reply
+}
+
+// jsonText returns the string values in a tool result, so paths are
+// found with their JSON escaping undone. Results that aren't JSON are
+// returned as they are.
+func jsonText(data string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		return data
+	}
+	var parts []string
+	var walk func(interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case string:
+			parts = append(parts, v)
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(v)
+	return strings.Join(parts, "\n")
+}
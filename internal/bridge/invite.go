@@ -0,0 +1,72 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/invite"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+const activateUsage = "/激活 邀请码 使用邀请码开通私聊"
+
+// activateCommand is the command name users may run before activating
+const activateCommand = "激活"
+
+// inviteRequiredReply tells a user who hasn't redeemed a code how to
+const inviteRequiredReply = "你还没有开通 AI 助手，请发送「/激活 邀请码」开通。邀请码请向管理员获取。"
+
+// isActivate reports whether text is the /激活 command
+func isActivate(text string) bool {
+	return strings.HasPrefix(text, "/") && commandName(text) == activateCommand
+}
+
+// awaitingInvite reports whether msg comes from a user who must redeem an
+// invite code before being answered in a private chat
+func (b *Bridge) awaitingInvite(ctx context.Context, msg *feishu.Message) bool {
+	if !b.cfg.Invite.Required || msg.ChatType != "p2p" || b.cfg.IsAdmin(msg.SenderID) {
+		return false
+	}
+	active, err := invite.Activated(b.store, msg.SenderID)
+	if err != nil {
+		// Unknown state; don't answer a user who may not be invited
+		logging.Printf(ctx, "[Bridge] Failed to check activation of %s: %v", msg.SenderID, err)
+		return true
+	}
+	return !active
+}
+
+// cmdActivate redeems an invite code for the sender
+func cmdActivate(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if !b.cfg.Invite.Required {
+		return "无需邀请码，可以直接使用"
+	}
+	if msg.SenderID == "" {
+		return "无法识别你的身份，请稍后再试"
+	}
+	if args == "" {
+		return activateUsage
+	}
+
+	err := invite.Redeem(b.store, args, msg.SenderID, time.Now())
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] %s failed to redeem an invite code: %v", msg.SenderID, err)
+		b.audit.Record(ctx, audit.Event{Action: "invite.redeem", Actor: msg.SenderID, ChatID: msg.ChatID, Outcome: audit.Denied, Detail: err.Error()})
+		switch {
+		case errors.Is(err, invite.ErrUnknownCode):
+			return "邀请码无效，请检查后重试"
+		case errors.Is(err, invite.ErrExpired):
+			return "邀请码已过期，请向管理员重新获取"
+		case errors.Is(err, invite.ErrUsedUp):
+			return "邀请码已达使用次数上限，请向管理员重新获取"
+		}
+		return "激活失败，请稍后再试"
+	}
+	logging.Printf(ctx, "[Bridge] %s activated with an invite code", msg.SenderID)
+	b.audit.Record(ctx, audit.Event{Action: "invite.redeem", Actor: msg.SenderID, ChatID: msg.ChatID})
+	return "已开通，现在可以直接向我提问了"
+}
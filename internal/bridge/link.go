@@ -0,0 +1,180 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// chatLinkBucket stores, per linked chat, the chat whose session it shares
+const chatLinkBucket = "chat_link"
+
+// sessionLink makes a chat continue another chat's session
+type sessionLink struct {
+	// From is the chat whose session is shared
+	From       string `json:"from"`
+	SessionKey string `json:"session_key"`
+	// Previous is the chat's own session mapping before the link, if any
+	Previous string    `json:"previous,omitempty"`
+	LinkedBy string    `json:"linked_by"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+const linkUsage = "/link [会话ID] 让另一个会话（如团队群）共用当前会话的上下文（需管理员权限）"
+
+const unlinkUsage = "/unlink [会话ID] 取消当前会话或指定会话的共享上下文，恢复其原来的会话（需管理员权限）"
+
+// cmdLink lists the chats sharing this chat's session or, for admins,
+// links another chat to it
+func cmdLink(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if args == "" {
+		return b.describeLinks(msg.ChatID) + "\n\n" + linkUsage
+	}
+	if !b.cfg.IsAdmin(msg.SenderID) {
+		logging.Printf(ctx, "[Bridge] Denied /link from non-admin %s in %s", msg.SenderID, msg.ChatID)
+		b.audit.Record(ctx, audit.Event{Action: "session.link", Actor: msg.SenderID, ChatID: msg.ChatID, Target: args, Outcome: audit.Denied, Detail: "not an admin"})
+		return "只有管理员可以共享会话"
+	}
+	if args == msg.ChatID {
+		return "不能把会话链接到自己"
+	}
+	if _, linked := b.chatLink(args); linked {
+		return fmt.Sprintf("会话 %s 已在共用其他会话的上下文，请先在该会话发送 /unlink", args)
+	}
+	if chats := b.linkedChats(args); len(chats) > 0 {
+		return fmt.Sprintf("会话 %s 的上下文已共享给 %s，请先取消这些共享", args, strings.Join(chats, ", "))
+	}
+
+	if b.cfg.TwoPerson.Requires("session.link") {
+		return b.requestConfirmation(ctx, pendingAction{
+			Action:      "session.link",
+			ChatID:      msg.ChatID,
+			Target:      args,
+			RequestedBy: msg.SenderID,
+		})
+	}
+	reply, _ := linkChats(ctx, b, msg.SenderID, msg.ChatID, args, "")
+	return reply
+}
+
+// linkChats makes chat to continue the session of chat from, or of the
+// chat from itself follows. confirmedBy names the second admin, if any.
+func linkChats(ctx context.Context, b *Bridge, actor, from, to, confirmedBy string) (string, error) {
+	if parent, ok := b.chatLink(from); ok {
+		from = parent.From
+	}
+	link := sessionLink{
+		From:       from,
+		SessionKey: b.sessionKeyFor(from),
+		LinkedBy:   actor,
+		LinkedAt:   time.Now().UTC(),
+	}
+	if _, err := b.store.Get(chatSessionBucket, to, &link.Previous); err != nil {
+		return fmt.Sprintf("共享失败：%s", redact.Error(err)), err
+	}
+	if err := b.store.Set(chatLinkBucket, to, link); err != nil {
+		return fmt.Sprintf("共享失败：%s", redact.Error(err)), err
+	}
+	if err := b.store.Set(chatSessionBucket, to, link.SessionKey); err != nil {
+		b.store.Delete(chatLinkBucket, to)
+		return fmt.Sprintf("共享失败：%s", redact.Error(err)), err
+	}
+
+	logging.Printf(ctx, "[Bridge] %s linked %s to the session of %s (%s)", actor, to, from, link.SessionKey)
+	ev := audit.Event{Action: "session.link", Actor: actor, ChatID: from, Target: to}
+	if confirmedBy != "" {
+		ev.Detail = "confirmed by " + confirmedBy
+	}
+	b.audit.Record(ctx, ev)
+
+	reply := fmt.Sprintf("会话 %s 已共用当前会话的上下文，发送 /unlink %s 取消", to, to)
+	// History lives with the backend, so a chat routed elsewhere starts empty
+	if src, dst := b.router.ChatBackend(from), b.router.ChatBackend(to); src != dst {
+		reply += fmt.Sprintf("\n注意：两个会话使用不同的后端（%s / %s），上下文只在同一后端内共享", src, dst)
+	}
+	return reply, nil
+}
+
+// cmdUnlink gives a linked chat its own session back
+func cmdUnlink(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if !b.cfg.IsAdmin(msg.SenderID) {
+		logging.Printf(ctx, "[Bridge] Denied /unlink from non-admin %s in %s", msg.SenderID, msg.ChatID)
+		b.audit.Record(ctx, audit.Event{Action: "session.unlink", Actor: msg.SenderID, ChatID: msg.ChatID, Target: args, Outcome: audit.Denied, Detail: "not an admin"})
+		return "只有管理员可以取消共享会话"
+	}
+	chatID := args
+	if chatID == "" {
+		chatID = msg.ChatID
+	}
+	link, ok := b.chatLink(chatID)
+	if !ok {
+		return fmt.Sprintf("会话 %s 没有共用其他会话的上下文\n\n%s", chatID, unlinkUsage)
+	}
+	if err := b.unlinkChat(chatID, link); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to unlink %s: %v", chatID, err)
+		return fmt.Sprintf("取消失败：%s", redact.Error(err))
+	}
+	logging.Printf(ctx, "[Bridge] %s unlinked %s from the session of %s", msg.SenderID, chatID, link.From)
+	b.audit.Record(ctx, audit.Event{Action: "session.unlink", Actor: msg.SenderID, ChatID: link.From, Target: chatID})
+	return fmt.Sprintf("会话 %s 已恢复使用自己的上下文", chatID)
+}
+
+// chatLink returns the link chatID follows, if any
+func (b *Bridge) chatLink(chatID string) (sessionLink, bool) {
+	var link sessionLink
+	ok, err := b.store.Get(chatLinkBucket, chatID, &link)
+	if err != nil {
+		log.Printf("[Bridge] Failed to read session link of %s: %v", chatID, err)
+	}
+	return link, ok && err == nil
+}
+
+// unlinkChat restores chatID's session mapping from before link
+func (b *Bridge) unlinkChat(chatID string, link sessionLink) error {
+	var err error
+	if link.Previous != "" {
+		err = b.store.Set(chatSessionBucket, chatID, link.Previous)
+	} else {
+		err = b.store.Delete(chatSessionBucket, chatID)
+	}
+	if err != nil {
+		return err
+	}
+	return b.store.Delete(chatLinkBucket, chatID)
+}
+
+// linkedChats returns the chats following chatID's session, sorted
+func (b *Bridge) linkedChats(chatID string) []string {
+	var chats []string
+	for _, c := range b.store.Keys(chatLinkBucket) {
+		if link, ok := b.chatLink(c); ok && link.From == chatID {
+			chats = append(chats, c)
+		}
+	}
+	sort.Strings(chats)
+	return chats
+}
+
+// describeLinks says which chats chatID shares a session with
+func (b *Bridge) describeLinks(chatID string) string {
+	var lines []string
+	if link, ok := b.chatLink(chatID); ok {
+		lines = append(lines, fmt.Sprintf("当前会话共用 %s 的上下文（%s 由 %s 设置）",
+			link.From, link.LinkedAt.Local().Format("2006-01-02 15:04"), link.LinkedBy))
+	}
+	if chats := b.linkedChats(chatID); len(chats) > 0 {
+		lines = append(lines, "共用当前会话上下文的会话："+strings.Join(chats, ", "))
+	}
+	if len(lines) == 0 {
+		return "当前会话没有与其他会话共享上下文"
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,63 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/linkpreview"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// sendLinkPreviews fetches the linked pages and sends a card previewing
+// the ones that answered. Links that fail are left out of the card.
+func (b *Bridge) sendLinkPreviews(ctx context.Context, chatID string, urls []string) {
+	previews := make([]linkpreview.Preview, len(urls))
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			p, err := b.links.Fetch(ctx, u)
+			if err != nil {
+				logging.Printf(ctx, "[Bridge] No preview for link: %v", err)
+				return
+			}
+			previews[i] = p
+		}(i, u)
+	}
+	wg.Wait()
+
+	card := feishu.NewCard("链接预览", "blue")
+	shown := 0
+	for _, p := range previews {
+		if p.URL == "" {
+			continue
+		}
+		if shown > 0 {
+			card.AddDivider()
+		}
+		card.AddMarkdown(previewMarkdown(p))
+		shown++
+	}
+	if shown == 0 {
+		return
+	}
+	if _, err := b.feishuClient.SendCard(chatID, card); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to send link previews: %v", err)
+		b.observeDelivery(ctx, err)
+	}
+}
+
+// previewMarkdown renders one link's preview for a card
+func previewMarkdown(p linkpreview.Preview) string {
+	// Brackets in the title would end the link text early
+	title := strings.NewReplacer("[", "【", "]", "】").Replace(p.Title)
+	text := fmt.Sprintf("**[%s](%s)**", title, p.URL)
+	if p.Description != "" {
+		text += "\n" + p.Description
+	}
+	return text + "\n<font color='grey'>" + p.Site + "</font>"
+}
@@ -0,0 +1,56 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+)
+
+// tooLong reports whether a reply should go out as a file
+func (b *Bridge) tooLong(text string) bool {
+	return utf8.RuneCountInString(text) > b.cfg.LongReply.MaxChars
+}
+
+// sendAsFile uploads a long reply to the chat as a Markdown file and
+// returns the summary to send in its place. If the upload fails the
+// reply is returned unchanged, so it's still delivered in full.
+func (b *Bridge) sendAsFile(ctx context.Context, chatID, reply string) string {
+	name := "reply-" + time.Now().Format("20060102-150405") + ".md"
+	if _, err := b.feishuClient.SendFile(chatID, name, []byte(reply)); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to send long reply as file, sending it inline: %v", err)
+		return reply
+	}
+	logging.Printf(ctx, "[Bridge] Sent %d-character reply to %s as %s", utf8.RuneCountInString(reply), chatID, name)
+	return fmt.Sprintf("%s\n\n……（完整回复共 %d 字，见文件 %s）",
+		summarize(reply, b.cfg.LongReply.SummaryChars), utf8.RuneCountInString(reply), name)
+}
+
+// summarize returns about the first n characters of text, cut at a line
+// break where there's one in the second half, with any code block left
+// open closed again
+func summarize(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	head := string(runes[:n])
+	if i := strings.LastIndex(head, "\n"); i > len(head)/2 {
+		head = head[:i]
+	}
+	head = strings.TrimRight(head, " \n")
+
+	fences := 0
+	for _, line := range strings.Split(head, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fences++
+		}
+	}
+	if fences%2 == 1 {
+		head += "\n```"
+	}
+	return head
+}
@@ -0,0 +1,206 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// chatMemoryBucket stores the facts remembered for each chat, by chat_id
+const chatMemoryBucket = "chat_memory"
+
+// Bounds on a remembered fact, in characters
+const (
+	maxMemoryKey   = 32
+	maxMemoryValue = 500
+)
+
+// memoryDirective matches the markers the agent writes to change memory:
+// [[memory:set 名称=内容]] and [[memory:delete 名称]]
+var memoryDirective = regexp.MustCompile(`\[\[memory:(set|delete)\s+([^\]\n]*?)\s*\]\]`)
+
+// memoryEntry is one remembered fact
+type memoryEntry struct {
+	Value string    `json:"value"`
+	SetBy string    `json:"set_by"`
+	SetAt time.Time `json:"set_at"`
+}
+
+// memoryInstructions tell the agent how to change memory
+const memoryInstructions = "需要长期记住的事实（不随会话重置丢失）可在回答末尾单独一行写 [[memory:set 名称=内容]]，" +
+	"不再需要时写 [[memory:delete 名称]]。这些标记不会显示给用户，只在用户要求或事实确实需要长期保留时使用。"
+
+const memoryUsage = "/memory [set 名称 内容|delete 名称|clear] 查看或修改 AI 为当前会话记住的事实，会话重置后仍然保留（群聊中修改需管理员权限）"
+
+// cmdMemory lists the chat's memory or changes it by hand
+func cmdMemory(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if !b.cfg.Memory.Enabled {
+		return "记忆功能未启用"
+	}
+	sub, rest := cutSpace(args)
+	sub = strings.ToLower(sub)
+	switch sub {
+	case "", "list":
+		return describeMemory(b.chatMemory(msg.ChatID)) + "\n\n" + memoryUsage
+	case "set", "delete", "clear":
+	default:
+		return memoryUsage
+	}
+
+	action := "memory." + sub
+	if msg.ChatType == "group" && !b.cfg.IsAdmin(msg.SenderID) {
+		logging.Printf(ctx, "[Bridge] Denied /memory %s from non-admin %s in %s", sub, msg.SenderID, msg.ChatID)
+		b.audit.Record(ctx, audit.Event{Action: action, Actor: msg.SenderID, ChatID: msg.ChatID, Outcome: audit.Denied, Detail: "not an admin"})
+		return "群聊中只有管理员可以修改记忆"
+	}
+
+	memory := b.chatMemory(msg.ChatID)
+	var key, reply string
+	switch sub {
+	case "set":
+		var value string
+		key, value = cutSpace(rest)
+		if key == "" || value == "" {
+			return memoryUsage
+		}
+		if err := b.remember(memory, key, value, msg.SenderID); err != nil {
+			return err.Error()
+		}
+		reply = fmt.Sprintf("已记住 %s：%s", key, value)
+	case "delete":
+		key = rest
+		if _, ok := memory[key]; !ok {
+			return fmt.Sprintf("没有名为 %s 的记忆", key)
+		}
+		delete(memory, key)
+		reply = fmt.Sprintf("已删除记忆 %s", key)
+	case "clear":
+		memory = nil
+		reply = "已清除当前会话的全部记忆"
+	}
+	if err := b.saveMemory(msg.ChatID, memory); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to save memory of %s: %v", msg.ChatID, err)
+		return fmt.Sprintf("保存失败：%s", redact.Error(err))
+	}
+	logging.Printf(ctx, "[Bridge] %s ran /memory %s in %s", msg.SenderID, sub, msg.ChatID)
+	b.audit.Record(ctx, audit.Event{Action: action, Actor: msg.SenderID, ChatID: msg.ChatID, Target: key})
+	return reply
+}
+
+// applyMemory carries out the memory directives in the agent's reply to
+// req
+func (b *Bridge) applyMemory(ctx context.Context, req *runRequest, reply string) {
+	if !b.cfg.Memory.Enabled {
+		return
+	}
+	memory := b.chatMemory(req.chatID)
+	changed := false
+	for _, m := range memoryDirective.FindAllStringSubmatch(reply, -1) {
+		op, body := m[1], m[2]
+		if op == "delete" {
+			if _, ok := memory[body]; ok {
+				delete(memory, body)
+				changed = true
+				b.audit.Record(ctx, audit.Event{Action: "memory.delete", Actor: req.senderID, ChatID: req.chatID, Target: body, Detail: "by agent"})
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(body, "=")
+		if !ok {
+			logging.Printf(ctx, "[Bridge] Ignoring malformed memory directive in %s: %q", req.chatID, m[0])
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if err := b.remember(memory, key, value, req.senderID); err != nil {
+			logging.Printf(ctx, "[Bridge] Ignoring memory %q in %s: %v", key, req.chatID, err)
+			continue
+		}
+		changed = true
+		b.audit.Record(ctx, audit.Event{Action: "memory.set", Actor: req.senderID, ChatID: req.chatID, Target: key, Detail: "by agent"})
+	}
+	if changed {
+		if err := b.saveMemory(req.chatID, memory); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to save memory of %s: %v", req.chatID, err)
+		} else {
+			logging.Printf(ctx, "[Bridge] Agent updated memory of %s, %d facts", req.chatID, len(memory))
+		}
+	}
+}
+
+// remember validates a fact and stores it in memory, within the
+// configured number of facts
+func (b *Bridge) remember(memory map[string]memoryEntry, key, value, setBy string) error {
+	if key == "" || value == "" {
+		return fmt.Errorf("名称和内容都不能为空")
+	}
+	if n := utf8.RuneCountInString(key); n > maxMemoryKey {
+		return fmt.Errorf("名称过长（%d 字），最多 %d 字", n, maxMemoryKey)
+	}
+	if n := utf8.RuneCountInString(value); n > maxMemoryValue {
+		return fmt.Errorf("内容过长（%d 字），最多 %d 字", n, maxMemoryValue)
+	}
+	if _, exists := memory[key]; !exists && len(memory) >= b.cfg.Memory.MaxEntries {
+		return fmt.Errorf("最多记住 %d 条事实，请先删除不用的记忆", b.cfg.Memory.MaxEntries)
+	}
+	memory[key] = memoryEntry{Value: value, SetBy: setBy, SetAt: time.Now().UTC()}
+	return nil
+}
+
+// chatMemory returns the facts remembered for chatID
+func (b *Bridge) chatMemory(chatID string) map[string]memoryEntry {
+	memory := make(map[string]memoryEntry)
+	if _, err := b.store.Get(chatMemoryBucket, chatID, &memory); err != nil {
+		log.Printf("[Bridge] Failed to read memory of %s: %v", chatID, err)
+	}
+	return memory
+}
+
+// saveMemory stores chatID's memory, deleting it once empty
+func (b *Bridge) saveMemory(chatID string, memory map[string]memoryEntry) error {
+	if len(memory) == 0 {
+		return b.store.Delete(chatMemoryBucket, chatID)
+	}
+	return b.store.Set(chatMemoryBucket, chatID, memory)
+}
+
+// memoryPrompt lists chatID's memory for the agent and tells it how to
+// change it
+func (b *Bridge) memoryPrompt(chatID string) string {
+	memory := b.chatMemory(chatID)
+	if len(memory) == 0 {
+		return "当前会话还没有记住的事实。\n" + memoryInstructions
+	}
+	return "当前会话记住的事实：\n" + memoryLines(memory) + "\n" + memoryInstructions
+}
+
+// describeMemory lists memory for /memory
+func describeMemory(memory map[string]memoryEntry) string {
+	if len(memory) == 0 {
+		return "当前会话还没有记住的事实"
+	}
+	return fmt.Sprintf("当前会话记住的事实（%d 条）：\n%s", len(memory), memoryLines(memory))
+}
+
+// memoryLines renders memory as "- 名称：内容" lines, sorted by name
+func memoryLines(memory map[string]memoryEntry) string {
+	keys := make([]string, 0, len(memory))
+	for key := range memory {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = fmt.Sprintf("- %s：%s", key, memory[key].Value)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,19 @@
+package bridge
+
+import (
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/metrics"
+)
+
+// recordRunMetrics counts a finished backend run and its token usage
+func recordRunMetrics(res runResult) {
+	outcome := "ok"
+	if res.Error != "" {
+		outcome = "error"
+	}
+	metrics.Inc("runs", "backend", res.Backend, "outcome", outcome)
+	metrics.Timing("run_latency", time.Duration(res.LatencyMs)*time.Millisecond, "backend", res.Backend)
+	metrics.Add("tokens", float64(res.InputTokens), "backend", res.Backend, "direction", "input")
+	metrics.Add("tokens", float64(res.OutputTokens), "backend", res.Backend, "direction", "output")
+}
@@ -0,0 +1,83 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// maxMinutesPerMessage bounds the Minutes read for one message
+const maxMinutesPerMessage = 2
+
+// minutesReader is implemented by Feishu clients that can read Minutes
+type minutesReader interface {
+	GetMinutes(token string) (*feishu.Minutes, error)
+}
+
+// minutesInstructions tell the agent what to do with a transcript
+const minutesInstructions = "用户分享了会议妙记。除非用户另有要求，请根据文字记录总结会议要点、结论，并列出待办事项（事项、负责人、截止日期，记录中没有的写“未明确”）。"
+
+// minutesTaskInstructions ask the agent to create the action items as
+// tasks
+const minutesTaskInstructions = "对每个明确的待办事项，在回答末尾按[任务]中的方式各创建一个任务。"
+
+// minutesPrompt gives the agent the transcripts of the Minutes linked in
+// text, fetched for userID
+func (b *Bridge) minutesPrompt(ctx context.Context, chatID, userID, text string) string {
+	if !b.cfg.Minutes.Enabled {
+		return ""
+	}
+	tokens := feishu.FindMinutes(text)
+	if len(tokens) == 0 {
+		return ""
+	}
+	reader, ok := b.feishuClient.(minutesReader)
+	if !ok {
+		return ""
+	}
+	if len(tokens) > maxMinutesPerMessage {
+		tokens = tokens[:maxMinutesPerMessage]
+	}
+
+	sections := []string{minutesInstructions}
+	if b.cfg.Minutes.CreateTasks && b.cfg.Tasks.Enabled {
+		sections[0] += minutesTaskInstructions
+	}
+	budget := b.cfg.Minutes.MaxChars / len(tokens)
+	for _, token := range tokens {
+		minutes, err := reader.GetMinutes(token)
+		if err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to read minutes %s for %s: %v", token, userID, err)
+			sections = append(sections, fmt.Sprintf("妙记 %s 读取失败：%s（请提醒用户确认应用有该妙记的访问权限）", token, redact.Error(err)))
+			continue
+		}
+		transcript, cut := truncateRunes(strings.TrimSpace(minutes.Transcript), budget)
+		header := fmt.Sprintf("妙记《%s》", minutes.Title)
+		if minutes.Duration > 0 {
+			header += fmt.Sprintf("，时长 %d 分钟", int(minutes.Duration.Round(time.Minute).Minutes()))
+		}
+		if cut {
+			header += "，文字记录过长，只保留了开头部分"
+		}
+		sections = append(sections, header+"：\n"+transcript)
+		logging.Printf(ctx, "[Bridge] %s shared minutes %s in %s (%d chars)", userID, token, chatID, len([]rune(minutes.Transcript)))
+		b.audit.Record(ctx, audit.Event{Action: "minutes.read", Actor: userID, ChatID: chatID, Target: token, Detail: minutes.Title})
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// truncateRunes cuts s to at most n characters and reports whether it
+// did
+func truncateRunes(s string, n int) (string, bool) {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s, false
+	}
+	return string(runes[:n]), true
+}
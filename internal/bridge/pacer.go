@@ -0,0 +1,98 @@
+package bridge
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/metrics"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// backoffDecay is how long updates must succeed before a rate-limit
+// slowdown is halved
+const backoffDecay = 10 * time.Second
+
+// updatePacer sets how often a streaming reply is edited. All streaming
+// replies share one edit budget, so each one slows down as more stream at
+// once, and Feishu rate-limit responses add a backoff that decays once
+// updates succeed again.
+type updatePacer struct {
+	cfg config.StreamingConfig
+
+	mu        sync.Mutex
+	streaming int
+	backoff   time.Duration
+	limitedAt time.Time
+}
+
+func newUpdatePacer(cfg config.StreamingConfig) *updatePacer {
+	return &updatePacer{cfg: cfg}
+}
+
+// begin counts a reply as streaming until the returned function is called
+func (p *updatePacer) begin() func() {
+	p.mu.Lock()
+	p.streaming++
+	metrics.Set("streaming_replies", float64(p.streaming))
+	p.mu.Unlock()
+
+	return func() {
+		p.mu.Lock()
+		p.streaming--
+		metrics.Set("streaming_replies", float64(p.streaming))
+		p.mu.Unlock()
+	}
+}
+
+// interval returns the current minimum gap between edits of one reply
+func (p *updatePacer) interval() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.decay(time.Now())
+
+	d := p.cfg.MinInterval
+	if share := time.Duration(float64(p.streaming) / p.cfg.UpdatesPerSecond * float64(time.Second)); share > d {
+		d = share
+	}
+	d += p.backoff
+	if d > p.cfg.MaxInterval {
+		d = p.cfg.MaxInterval
+	}
+	return d
+}
+
+// observe adjusts the backoff after an edit
+func (p *updatePacer) observe(err error) {
+	if !errors.Is(err, feishu.ErrRateLimited) {
+		return
+	}
+	metrics.Inc("stream_rate_limited")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.backoff == 0 {
+		p.backoff = p.cfg.MinInterval
+	} else {
+		p.backoff *= 2
+	}
+	if p.backoff > p.cfg.MaxInterval {
+		p.backoff = p.cfg.MaxInterval
+	}
+	p.limitedAt = time.Now()
+}
+
+// decay halves the backoff for every quiet period since the last rate
+// limit; callers must hold mu
+func (p *updatePacer) decay(now time.Time) {
+	for p.backoff > 0 && now.Sub(p.limitedAt) >= backoffDecay {
+		p.backoff /= 2
+		if p.backoff < p.cfg.MinInterval/4 {
+			p.backoff = 0
+		}
+		p.limitedAt = p.limitedAt.Add(backoffDecay)
+	}
+}
@@ -0,0 +1,315 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// pollBucket stores open polls by ID
+const pollBucket = "poll"
+
+// Bounds on a poll
+const (
+	minPollOptions = 2
+	maxPollOptions = 10
+	maxPollText    = 100
+)
+
+// pollSummaryTimeout bounds asking the agent to summarize a closed poll
+const pollSummaryTimeout = 2 * time.Minute
+
+// poll is an open poll and its votes
+type poll struct {
+	ID       string   `json:"id"`
+	ChatID   string   `json:"chat_id"`
+	Question string   `json:"question"`
+	Options  []string `json:"options"`
+	// Votes maps each voter's open_id to the index of their option
+	Votes     map[string]int `json:"votes"`
+	CreatedBy string         `json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+	MessageID string         `json:"message_id,omitempty"`
+}
+
+const pollUsage = "/poll 问题 选项1 选项2 ... 发起投票（也可第一行写问题、之后每行一个选项，最多 10 个选项）；/poll close 结束本会话最近的投票；/poll 列出本会话进行中的投票"
+
+// cmdPoll starts a poll, closes one or lists the chat's open polls
+func cmdPoll(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if !b.cfg.Polls.Enabled {
+		return "投票功能未启用"
+	}
+	switch args {
+	case "", "list":
+		return b.describePolls(msg.ChatID) + "\n\n" + pollUsage
+	case "close":
+		polls := b.chatPolls(msg.ChatID)
+		if len(polls) == 0 {
+			return "本会话没有进行中的投票"
+		}
+		p := polls[len(polls)-1]
+		if err := b.closePoll(ctx, p.ID, msg.SenderID); err != nil {
+			return err.Error()
+		}
+		return ""
+	}
+
+	question, options := parsePoll(args)
+	if question == "" || len(options) < minPollOptions {
+		return pollUsage
+	}
+	if len(options) > maxPollOptions {
+		return fmt.Sprintf("最多 %d 个选项，当前 %d 个", maxPollOptions, len(options))
+	}
+	for _, s := range append([]string{question}, options...) {
+		if n := utf8.RuneCountInString(s); n > maxPollText {
+			return fmt.Sprintf("问题和选项最多 %d 字，「%s」有 %d 字", maxPollText, s, n)
+		}
+	}
+
+	p := poll{
+		ID:        uuid.NewString(),
+		ChatID:    msg.ChatID,
+		Question:  question,
+		Options:   options,
+		Votes:     make(map[string]int),
+		CreatedBy: msg.SenderID,
+		CreatedAt: time.Now().UTC(),
+	}
+	messageID, err := b.feishuClient.SendCard(msg.ChatID, pollCard(p))
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to send poll card: %v", err)
+		return fmt.Sprintf("发送投票卡片失败：%s", redact.Error(err))
+	}
+	p.MessageID = messageID
+	if err := b.store.Set(pollBucket, p.ID, p); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to save poll %s: %v", p.ID, err)
+		return "投票保存失败，投票将无法计票"
+	}
+	logging.Printf(ctx, "[Bridge] %s started poll %s in %s with %d options", msg.SenderID, p.ID, msg.ChatID, len(options))
+	b.audit.Record(ctx, audit.Event{Action: "poll.create", Actor: msg.SenderID, ChatID: msg.ChatID, Target: p.ID, Detail: question})
+	return ""
+}
+
+// parsePoll splits a question from its options: by line when args spans
+// several, otherwise by whitespace
+func parsePoll(args string) (string, []string) {
+	var parts []string
+	if strings.Contains(args, "\n") {
+		for _, line := range strings.Split(args, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				parts = append(parts, line)
+			}
+		}
+	} else {
+		parts = strings.Fields(args)
+	}
+	if len(parts) == 0 {
+		return "", nil
+	}
+	return parts[0], parts[1:]
+}
+
+// actionPollVote records a vote from the poll card; voting again changes
+// the vote
+func actionPollVote(ctx context.Context, b *Bridge, action *feishu.CardAction) (string, error) {
+	var option int
+	if _, err := fmt.Sscan(actionString(action, "option"), &option); err != nil {
+		return "", fmt.Errorf("无效的选项")
+	}
+
+	b.pollMu.Lock()
+	defer b.pollMu.Unlock()
+	id := actionString(action, "id")
+	var p poll
+	ok, err := b.store.Get(pollBucket, id, &p)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("该投票不存在或已结束")
+	}
+	if option < 0 || option >= len(p.Options) {
+		return "", fmt.Errorf("无效的选项")
+	}
+	if prev, voted := p.Votes[action.OperatorID]; voted && prev == option {
+		return "你已投给「" + p.Options[option] + "」", nil
+	}
+	p.Votes[action.OperatorID] = option
+	if err := b.store.Set(pollBucket, id, p); err != nil {
+		return "", err
+	}
+	if err := b.feishuClient.UpdateCard(action.MessageID, pollCard(p)); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to update poll card %s: %v", id, err)
+	}
+	return "已投给「" + p.Options[option] + "」", nil
+}
+
+// actionPollClose closes a poll from its card
+func actionPollClose(ctx context.Context, b *Bridge, action *feishu.CardAction) (string, error) {
+	if err := b.closePoll(ctx, actionString(action, "id"), action.OperatorID); err != nil {
+		return "", err
+	}
+	return "投票已结束", nil
+}
+
+// closePoll closes poll id for actor, who must have started it or be an
+// admin, shows the results on its card and posts them, summarized by the
+// agent when configured
+func (b *Bridge) closePoll(ctx context.Context, id, actor string) error {
+	b.pollMu.Lock()
+	var p poll
+	ok, err := b.store.Get(pollBucket, id, &p)
+	if err == nil && ok && p.CreatedBy != actor && !b.cfg.IsAdmin(actor) {
+		b.pollMu.Unlock()
+		b.audit.Record(ctx, audit.Event{Action: "poll.close", Actor: actor, ChatID: p.ChatID, Target: id, Outcome: audit.Denied, Detail: "not the creator or an admin"})
+		return fmt.Errorf("只有发起人或管理员可以结束投票")
+	}
+	if err == nil && ok {
+		err = b.store.Delete(pollBucket, id)
+	}
+	b.pollMu.Unlock()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("该投票不存在或已结束")
+	}
+
+	logging.Printf(ctx, "[Bridge] %s closed poll %s in %s with %d votes", actor, id, p.ChatID, len(p.Votes))
+	b.audit.Record(ctx, audit.Event{Action: "poll.close", Actor: actor, ChatID: p.ChatID, Target: id, Detail: fmt.Sprintf("%d votes", len(p.Votes))})
+	if p.MessageID != "" {
+		if err := b.feishuClient.UpdateCard(p.MessageID, closedPollCard(p, actor)); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to update poll card %s: %v", id, err)
+		}
+	}
+	// Card callbacks must answer quickly; the agent may take a while
+	go b.postPollResults(ctx, p)
+	return nil
+}
+
+// postPollResults posts a closed poll's results to its chat, summarized
+// by the chat's agent when configured
+func (b *Bridge) postPollResults(ctx context.Context, p poll) {
+	results := fmt.Sprintf("投票「%s」已结束，共 %d 人投票：\n%s", p.Question, len(p.Votes), pollTally(p, false))
+	text := results
+	if b.cfg.Polls.Summarize && len(p.Votes) > 0 {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), pollSummaryTimeout)
+		defer cancel()
+		req := b.chatRun(ctx, p.ChatID)
+		summary, err := req.agent.Ask(ctx, results+"\n\n请结合会话上下文，用几句话总结投票结果和可以得出的结论。", req.sessionKey, nil)
+		if err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to summarize poll %s: %v", p.ID, err)
+		} else if summary = strings.TrimSpace(summary); summary != "" {
+			text = results + "\n\n" + summary
+		}
+	}
+	if _, err := b.feishuClient.SendMessage(p.ChatID, text); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to post results of poll %s: %v", p.ID, err)
+	}
+}
+
+// pollCard shows an open poll with a button per option
+func pollCard(p poll) *feishu.Card {
+	card := feishu.NewCard("投票："+p.Question, "blue").
+		AddMarkdown(pollTally(p, true)).
+		AddNote(fmt.Sprintf("共 %d 人投票 · 发起人 <at id=%s></at> · 可重新点击改票", len(p.Votes), p.CreatedBy))
+	buttons := make([]feishu.CardButton, len(p.Options))
+	for i, option := range p.Options {
+		buttons[i] = feishu.CardButton{Text: option, Value: map[string]interface{}{"action": "poll_vote", "id": p.ID, "option": fmt.Sprint(i)}}
+	}
+	for i := 0; i < len(buttons); i += 3 {
+		card.AddButtons(buttons[i:min(i+3, len(buttons))]...)
+	}
+	card.AddDivider()
+	card.AddButtons(feishu.CardButton{Text: "结束投票", Type: "danger", Value: map[string]interface{}{"action": "poll_close", "id": p.ID}})
+	return card
+}
+
+// closedPollCard replaces a poll's card once it's closed
+func closedPollCard(p poll, closedBy string) *feishu.Card {
+	return feishu.NewCard("投票已结束："+p.Question, "grey").
+		AddMarkdown(pollTally(p, true)).
+		AddNote(fmt.Sprintf("共 %d 人投票 · <at id=%s></at> 结束了投票", len(p.Votes), closedBy))
+}
+
+// pollTally renders each option's votes, as bars for cards
+func pollTally(p poll, bars bool) string {
+	counts := make([]int, len(p.Options))
+	for _, option := range p.Votes {
+		if option >= 0 && option < len(counts) {
+			counts[option]++
+		}
+	}
+	lines := make([]string, len(p.Options))
+	for i, option := range p.Options {
+		percent := 0
+		if len(p.Votes) > 0 {
+			percent = counts[i] * 100 / len(p.Votes)
+		}
+		if bars {
+			lines[i] = fmt.Sprintf("**%s**\n%s %d 票（%d%%）", option, strings.Repeat("▇", percent/10)+"▏", counts[i], percent)
+		} else {
+			lines[i] = fmt.Sprintf("- %s：%d 票（%d%%）", option, counts[i], percent)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// chatPolls returns chatID's open polls, oldest first
+func (b *Bridge) chatPolls(chatID string) []poll {
+	var polls []poll
+	for _, id := range b.store.Keys(pollBucket) {
+		var p poll
+		if ok, err := b.store.Get(pollBucket, id, &p); err == nil && ok && p.ChatID == chatID {
+			polls = append(polls, p)
+		}
+	}
+	sort.Slice(polls, func(i, j int) bool { return polls[i].CreatedAt.Before(polls[j].CreatedAt) })
+	return polls
+}
+
+// describePolls lists chatID's open polls for /poll
+func (b *Bridge) describePolls(chatID string) string {
+	polls := b.chatPolls(chatID)
+	if len(polls) == 0 {
+		return "本会话没有进行中的投票"
+	}
+	lines := []string{fmt.Sprintf("本会话进行中的投票（%d 个）：", len(polls))}
+	for _, p := range polls {
+		lines = append(lines, fmt.Sprintf("- %s（%d 人已投票）", p.Question, len(p.Votes)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// forgetVotes removes userID's votes from open polls and returns how many
+func (b *Bridge) forgetVotes(userID string) (int, error) {
+	b.pollMu.Lock()
+	defer b.pollMu.Unlock()
+	n := 0
+	for _, id := range b.store.Keys(pollBucket) {
+		var p poll
+		if ok, err := b.store.Get(pollBucket, id, &p); err != nil || !ok {
+			continue
+		}
+		if _, voted := p.Votes[userID]; !voted {
+			continue
+		}
+		delete(p.Votes, userID)
+		if err := b.store.Set(pollBucket, id, p); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,212 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// userPrefBucket stores each user's preferences, by open_id
+const userPrefBucket = "user_pref"
+
+// maxPrefLength bounds free-text preferences, in characters
+const maxPrefLength = 32
+
+// userPrefs personalize the agent's answers to one user in every chat
+type userPrefs struct {
+	Nickname string `json:"nickname,omitempty"`
+	Language string `json:"language,omitempty"`
+	// Verbosity is "concise" or "detailed"; empty leaves it to the agent
+	Verbosity string    `json:"verbosity,omitempty"`
+	Timezone  string    `json:"timezone,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (p userPrefs) empty() bool {
+	return p.Nickname == "" && p.Language == "" && p.Verbosity == "" && p.Timezone == ""
+}
+
+// prefField is one preference /pref can set
+type prefField struct {
+	label string
+	// set validates value and stores it in p; empty clears it
+	set func(p *userPrefs, value string) error
+	get func(p userPrefs) string
+}
+
+var prefFields = map[string]prefField{
+	"name": {
+		label: "称呼",
+		set: func(p *userPrefs, v string) error {
+			p.Nickname = v
+			return checkPrefLength(v)
+		},
+		get: func(p userPrefs) string { return p.Nickname },
+	},
+	"lang": {
+		label: "语言",
+		set: func(p *userPrefs, v string) error {
+			p.Language = v
+			return checkPrefLength(v)
+		},
+		get: func(p userPrefs) string { return p.Language },
+	},
+	"verbosity": {
+		label: "详略",
+		set: func(p *userPrefs, v string) error {
+			switch strings.ToLower(v) {
+			case "":
+				p.Verbosity = ""
+			case "concise", "brief", "简洁", "简短":
+				p.Verbosity = "concise"
+			case "detailed", "详细":
+				p.Verbosity = "detailed"
+			default:
+				return fmt.Errorf("详略只能是 简洁 或 详细")
+			}
+			return nil
+		},
+		get: func(p userPrefs) string { return verbosityLabels[p.Verbosity] },
+	},
+	"tz": {
+		label: "时区",
+		set: func(p *userPrefs, v string) error {
+			if _, err := time.LoadLocation(v); err != nil || v == "Local" {
+				return fmt.Errorf("未知时区 %s，请使用 IANA 名称，如 Asia/Shanghai", v)
+			}
+			p.Timezone = v
+			return nil
+		},
+		get: func(p userPrefs) string { return p.Timezone },
+	},
+}
+
+// prefOrder lists preferences in the order they're shown
+var prefOrder = []string{"name", "lang", "verbosity", "tz"}
+
+// prefAliases map the other accepted names to prefFields keys
+var prefAliases = map[string]string{
+	"nickname": "name", "称呼": "name", "昵称": "name",
+	"language": "lang", "语言": "lang",
+	"详略":       "verbosity",
+	"timezone": "tz", "时区": "tz",
+}
+
+var verbosityLabels = map[string]string{"concise": "简洁", "detailed": "详细"}
+
+func checkPrefLength(v string) error {
+	if n := utf8.RuneCountInString(v); n > maxPrefLength {
+		return fmt.Errorf("内容过长（%d 字），最多 %d 字", n, maxPrefLength)
+	}
+	return nil
+}
+
+const prefUsage = "/pref [name|lang|verbosity|tz 值] 查看或设置你的个人偏好（称呼、回答语言、详略：简洁/详细、时区），在所有会话中生效；/pref clear [项] 清除"
+
+// cmdPref shows or changes the sender's preferences
+func cmdPref(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if msg.SenderID == "" {
+		return "无法识别你的身份"
+	}
+	prefs, _ := b.userPrefs(msg.SenderID)
+	if args == "" {
+		return describePrefs(prefs) + "\n\n" + prefUsage
+	}
+
+	key, value, _ := strings.Cut(args, " ")
+	key, value = strings.ToLower(key), strings.TrimSpace(value)
+	if key == "clear" {
+		if value == "" {
+			prefs = userPrefs{}
+		} else if field, ok := lookupPref(value); ok {
+			field.set(&prefs, "")
+		} else {
+			return prefUsage
+		}
+	} else {
+		field, ok := lookupPref(key)
+		if !ok || value == "" {
+			return prefUsage
+		}
+		if err := field.set(&prefs, value); err != nil {
+			return err.Error()
+		}
+	}
+
+	var err error
+	if prefs.empty() {
+		err = b.store.Delete(userPrefBucket, msg.SenderID)
+	} else {
+		prefs.UpdatedAt = time.Now().UTC()
+		err = b.store.Set(userPrefBucket, msg.SenderID, prefs)
+	}
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to save preferences of %s: %v", msg.SenderID, err)
+		return fmt.Sprintf("保存失败：%s", redact.Error(err))
+	}
+	logging.Printf(ctx, "[Bridge] %s updated preferences: %s", msg.SenderID, args)
+	return "已更新\n" + describePrefs(prefs)
+}
+
+// lookupPref finds a preference by name or alias
+func lookupPref(name string) (prefField, bool) {
+	if alias, ok := prefAliases[name]; ok {
+		name = alias
+	}
+	field, ok := prefFields[name]
+	return field, ok
+}
+
+// describePrefs lists the preferences that are set
+func describePrefs(p userPrefs) string {
+	if p.empty() {
+		return "你还没有设置个人偏好"
+	}
+	lines := []string{"你的个人偏好："}
+	for _, key := range prefOrder {
+		field := prefFields[key]
+		if v := field.get(p); v != "" {
+			lines = append(lines, fmt.Sprintf("- %s：%s", field.label, v))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// userPrefs returns userID's preferences
+func (b *Bridge) userPrefs(userID string) (userPrefs, bool) {
+	var prefs userPrefs
+	if userID == "" {
+		return prefs, false
+	}
+	ok, err := b.store.Get(userPrefBucket, userID, &prefs)
+	if err != nil {
+		log.Printf("[Bridge] Failed to read preferences of %s: %v", userID, err)
+	}
+	return prefs, ok && err == nil && !prefs.empty()
+}
+
+// prefsPrompt renders p as instructions for the agent; the timezone is
+// left to timePrompt
+func prefsPrompt(p userPrefs) string {
+	var lines []string
+	if p.Nickname != "" {
+		lines = append(lines, fmt.Sprintf("称呼用户为「%s」", p.Nickname))
+	}
+	if p.Language != "" {
+		lines = append(lines, fmt.Sprintf("使用%s回答", p.Language))
+	}
+	switch p.Verbosity {
+	case "concise":
+		lines = append(lines, "回答尽量简洁，只给出要点")
+	case "detailed":
+		lines = append(lines, "回答尽量详细，说明原因和步骤")
+	}
+	return strings.Join(lines, "\n")
+}
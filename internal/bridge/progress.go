@@ -0,0 +1,135 @@
+package bridge
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// runPhase is where a run is, as shown in the thinking placeholder
+type runPhase int
+
+const (
+	phaseThinking runPhase = iota
+	phaseTool
+	phaseReplying
+	phaseDone
+)
+
+// phaseLabels name the phases in the indicator; done isn't shown
+var phaseLabels = []string{"思考", "工具", "回复"}
+
+// phaseMoves lists the transitions a run may make. The agent may go back
+// to thinking after a tool returns, but once the answer streams the
+// placeholder is gone and later tool calls don't change the phase.
+var phaseMoves = map[runPhase][]runPhase{
+	phaseThinking: {phaseTool, phaseReplying, phaseDone},
+	phaseTool:     {phaseThinking, phaseTool, phaseReplying, phaseDone},
+	phaseReplying: {phaseDone},
+}
+
+// streamCursor is appended to a partial answer while it streams
+const streamCursor = "▍"
+
+// runProgress is the state behind the thinking placeholder: the phase,
+// the tool being called and how long the user has waited. It isn't
+// locked itself; callers hold the run's mutex.
+type runProgress struct {
+	started time.Time
+	phase   runPhase
+	tool    string
+	// calls counts tool calls, so the indicator shows tools were used
+	// after the agent goes back to thinking
+	calls int
+	frame int
+	// thought is the latest reasoning, shown when the chat asked for it
+	thought string
+	// thoughtCut is set once earlier reasoning was dropped from thought
+	thoughtCut bool
+}
+
+func newRunProgress(started time.Time) *runProgress {
+	return &runProgress{started: started}
+}
+
+// move changes phase if the transition is allowed and reports whether it was
+func (p *runProgress) move(to runPhase) bool {
+	for _, next := range phaseMoves[p.phase] {
+		if next == to {
+			p.phase = to
+			return true
+		}
+	}
+	return false
+}
+
+// toolCall records that the agent called a tool
+func (p *runProgress) toolCall(name string) {
+	if p.move(phaseTool) {
+		p.tool = name
+		p.calls++
+	}
+}
+
+// toolResult records that a tool returned; the agent thinks again
+func (p *runProgress) toolResult() {
+	if p.phase == phaseTool && p.move(phaseThinking) {
+		p.tool = ""
+	}
+}
+
+// think records reasoning from the agent, replacing what came before
+// when replace is set
+func (p *runProgress) think(text string, replace bool) {
+	if !replace {
+		text = p.thought + text
+	}
+	trimmed := trimThought(text)
+	p.thoughtCut = (p.thoughtCut && !replace) || trimmed != text
+	p.thought = trimmed
+}
+
+// replying records that the answer started streaming
+func (p *runProgress) replying() { p.move(phaseReplying) }
+
+// finish records that the run ended
+func (p *runProgress) finish() { p.move(phaseDone) }
+
+// placeholder renders the thinking message for now: the status with
+// animated dots, then the phase indicator and elapsed time. Each call
+// advances the animation.
+func (p *runProgress) placeholder(now time.Time) string {
+	p.frame = p.frame%3 + 1
+	status := "正在思考"
+	if p.phase == phaseTool && p.tool != "" {
+		status = "正在调用工具 " + p.tool
+	}
+
+	steps := make([]string, len(phaseLabels))
+	for i, label := range phaseLabels {
+		switch {
+		case runPhase(i) == p.phase:
+			steps[i] = "【" + label + "】"
+		case runPhase(i) == phaseTool && p.calls == 0:
+			steps[i] = label
+		case runPhase(i) < p.phase || runPhase(i) == phaseTool:
+			steps[i] = label + " ✓"
+		default:
+			steps[i] = label
+		}
+	}
+	elapsed := int(now.Sub(p.started).Seconds())
+	text := fmt.Sprintf("%s%s\n%s · 已用 %d 秒", status, strings.Repeat(".", p.frame), strings.Join(steps, " → "), elapsed)
+	if thought := strings.TrimSpace(p.thought); thought != "" {
+		if p.thoughtCut {
+			thought = "…" + thought
+		}
+		text += "\n\n" + thought
+	}
+	return text
+}
+
+// withCursor marks a partial answer as still being written
+func withCursor(text string) string {
+	return text + streamCursor
+}
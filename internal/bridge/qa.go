@@ -0,0 +1,173 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/errreport"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// qaBucket stores each chat's last QA card by chat ID, so the feedback
+// from a satisfaction survey can be added to it
+const qaBucket = "qa_sample"
+
+// Bounds on the texts shown on a QA card
+const (
+	maxQAPrompt = 1000
+	maxQAReply  = 3000
+)
+
+// qaSample is a QA card awaiting feedback
+type qaSample struct {
+	MessageID string    `json:"message_id"`
+	UserID    string    `json:"user_id"`
+	Body      string    `json:"body"`
+	Failed    bool      `json:"failed,omitempty"`
+	Sent      time.Time `json:"sent"`
+}
+
+// sampleForQA forwards a share of answered messages, with their prompt,
+// reply and latency, to the QA group
+func (b *Bridge) sampleForQA(ctx context.Context, req *runRequest, prompt string, res runResult) {
+	cfg := b.cfg.QA
+	if cfg.ChatID == "" || req.chatID == cfg.ChatID || rand.Float64()*100 >= cfg.Percent {
+		return
+	}
+	if req.chatType == "p2p" && !cfg.IncludePrivate {
+		return
+	}
+
+	s := qaSample{UserID: req.senderID, Body: qaBody(req, prompt, res), Failed: res.Error != "", Sent: time.Now()}
+	ctx = logging.Fork(ctx)
+	go func() {
+		defer errreport.Recover(ctx, "qa")
+		messageID, err := b.feishuClient.SendCard(cfg.ChatID, qaCard(s, b.qaFeedbackText("")))
+		if err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to send QA sample: %v", err)
+			return
+		}
+		logging.Printf(ctx, "[Bridge] Sent QA sample of %s to %s", req.chatID, cfg.ChatID)
+		if !b.cfg.Survey.Enabled {
+			return
+		}
+		b.sweepQASamples()
+		s.MessageID = messageID
+		if err := b.store.Set(qaBucket, req.chatID, s); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to save QA sample of %s: %v", req.chatID, err)
+		}
+	}()
+}
+
+// qaBody describes a sampled run; secrets are masked and long texts cut
+func qaBody(req *runRequest, prompt string, res runResult) string {
+	chatType := "群聊"
+	if req.chatType == "p2p" {
+		chatType = "私聊"
+	}
+	via := res.Backend
+	if req.agentName != "" {
+		via += " / " + req.agentName
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**会话**：%s（%s），用户 %s\n", req.chatID, chatType, req.senderID)
+	fmt.Fprintf(&sb, "**后端**：%s", via)
+	if req.tag != "" {
+		fmt.Fprintf(&sb, "，分类 %s", req.tag)
+	}
+	fmt.Fprintf(&sb, "\n**耗时**：%.1fs，Token %d/%d\n", float64(res.LatencyMs)/1000, res.InputTokens, res.OutputTokens)
+
+	text, cut := truncateRunes(redact.String(prompt), maxQAPrompt)
+	if cut {
+		text += "…"
+	}
+	fmt.Fprintf(&sb, "\n**提问**\n%s\n", text)
+	if res.Error != "" {
+		fmt.Fprintf(&sb, "\n**错误**\n%s", redact.String(res.Error))
+		return sb.String()
+	}
+	text, cut = truncateRunes(redact.String(res.Reply), maxQAReply)
+	if cut {
+		text += "…"
+	}
+	fmt.Fprintf(&sb, "\n**回答**\n%s", text)
+	return sb.String()
+}
+
+// qaCard shows a sampled run with its feedback note
+func qaCard(s qaSample, feedback string) *feishu.Card {
+	template := "blue"
+	if s.Failed {
+		template = "red"
+	}
+	card := feishu.NewCard("QA 抽样", template).AddMarkdown(s.Body)
+	if feedback != "" {
+		card.AddNote(feedback)
+	}
+	return card
+}
+
+// qaFeedbackText describes the survey answer for a QA card: "" when
+// surveys are off, pending while answer is "", else the answer
+func (b *Bridge) qaFeedbackText(answer string) string {
+	if !b.cfg.Survey.Enabled {
+		return ""
+	}
+	if answer == "" {
+		return "用户反馈：等待满意度调查"
+	}
+	return "用户反馈：" + answer
+}
+
+// qaFeedback adds a survey answer from chatID to the chat's last QA card,
+// if it was sampled in the conversation the survey asked about
+func (b *Bridge) qaFeedback(ctx context.Context, chatID string, surveySent time.Time, helpful bool) {
+	var s qaSample
+	ok, err := b.store.Get(qaBucket, chatID, &s)
+	if err != nil || !ok || s.Sent.After(surveySent) || surveySent.Sub(s.Sent) > b.cfg.Survey.Interval {
+		return
+	}
+	if err := b.store.Delete(qaBucket, chatID); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to delete QA sample of %s: %v", chatID, err)
+	}
+
+	answer := "👎 没帮助"
+	if helpful {
+		answer = "👍 有帮助"
+	}
+	if err := b.feishuClient.UpdateCard(s.MessageID, qaCard(s, b.qaFeedbackText(answer))); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to add feedback to QA card: %v", err)
+	}
+}
+
+// sweepQASamples drops QA cards too old for a survey to still be about
+// their conversation
+func (b *Bridge) sweepQASamples() {
+	now := time.Now()
+	for _, chatID := range b.store.Keys(qaBucket) {
+		var s qaSample
+		if ok, err := b.store.Get(qaBucket, chatID, &s); err == nil && ok && now.Sub(s.Sent) > b.cfg.Survey.Interval {
+			b.store.Delete(qaBucket, chatID)
+		}
+	}
+}
+
+// forgetQASamples drops the kept QA cards of userID's messages
+func (b *Bridge) forgetQASamples(userID string) error {
+	for _, chatID := range b.store.Keys(qaBucket) {
+		var s qaSample
+		if ok, err := b.store.Get(qaBucket, chatID, &s); err != nil || !ok || s.UserID != userID {
+			continue
+		}
+		if err := b.store.Delete(qaBucket, chatID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/metrics"
+)
+
+// queueUpdateInterval is how often a waiting run's placeholder is
+// refreshed with its position
+const queueUpdateInterval = 2 * time.Second
+
+// chatBusyReply turns a message away while its chat is at its limit
+const chatBusyReply = "上一条消息还在处理中，请等回答完成后再发送"
+
+// admission is what runQueue.enter made of a new run
+type admission int
+
+const (
+	// runStarted took a slot right away
+	runStarted admission = iota
+	// runQueued waits for a slot
+	runQueued
+	// runMerged was added to the prompt of a run waiting in its chat
+	runMerged
+	// runRejected was turned away because its chat is at its limit
+	runRejected
+)
+
+// runQueue holds runs back while the bridge or their chat is running as
+// many as configured, and lets them go in arrival order, high-priority
+// runs first
+type runQueue struct {
+	maxRuns int
+	perChat int
+	// overflow is the policy for runs of a chat at perChat
+	overflow string
+	// maxSkips bounds the high-priority runs started in a row while
+	// others wait
+	maxSkips int
+
+	mu      sync.Mutex
+	running int
+	chats   map[string]int
+	waiting []*queuedRun
+	// skips counts the high-priority runs started in a row while others
+	// waited
+	skips int
+}
+
+// queuedRun is a run waiting for a slot; ready is closed once it has one
+type queuedRun struct {
+	chatID string
+	high   bool
+	// req is the run's request, whose text later messages of the chat
+	// may be merged into until ready is closed
+	req   *runRequest
+	ready chan struct{}
+}
+
+func newRunQueue(cfg config.QueueConfig) *runQueue {
+	return &runQueue{
+		maxRuns:  cfg.MaxRuns,
+		perChat:  cfg.PerChat,
+		overflow: cfg.Overflow,
+		maxSkips: cfg.MaxSkips,
+		chats:    make(map[string]int),
+	}
+}
+
+// fits reports whether a run in chatID may start now; callers hold mu
+func (q *runQueue) fits(chatID string) bool {
+	return (q.maxRuns == 0 || q.running < q.maxRuns) && (q.perChat == 0 || q.chats[chatID] < q.perChat)
+}
+
+// start takes a slot; callers hold mu
+func (q *runQueue) start(chatID string, high bool) {
+	q.running++
+	q.chats[chatID]++
+	if !high {
+		q.skips = 0
+		return
+	}
+	for _, w := range q.waiting {
+		if !w.high {
+			q.skips++
+			return
+		}
+	}
+}
+
+// enter takes a slot for req's run, or queues it when there's none free
+// or others are already waiting for one. When the chat already has
+// perChat runs going or waiting, the overflow policy may merge the run
+// into the chat's last waiting one or reject it instead. The queued or
+// merged-into run is returned for runQueued and runMerged.
+func (q *runQueue) enter(req *runRequest) (*queuedRun, admission) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	chatID, high := req.chatID, req.priority
+
+	if q.perChat > 0 && q.chats[chatID]+q.waitingIn(chatID) >= q.perChat {
+		switch q.overflow {
+		case "reject":
+			return nil, runRejected
+		case "merge":
+			for i := len(q.waiting) - 1; i >= 0; i-- {
+				if w := q.waiting[i]; w.chatID == chatID {
+					w.req.text += "\n\n" + req.text
+					w.high = w.high || high
+					return w, runMerged
+				}
+			}
+		}
+	}
+
+	if q.fits(chatID) && q.position(chatID, high, len(q.waiting)) == 1 {
+		q.start(chatID, high)
+		return nil, runStarted
+	}
+	w := &queuedRun{chatID: chatID, high: high, req: req, ready: make(chan struct{})}
+	q.waiting = append(q.waiting, w)
+	metrics.Set("queued", float64(len(q.waiting)))
+	return w, runQueued
+}
+
+// waitingIn counts the runs of chatID waiting for a slot; callers hold mu
+func (q *runQueue) waitingIn(chatID string) int {
+	n := 0
+	for _, w := range q.waiting {
+		if w.chatID == chatID {
+			n++
+		}
+	}
+	return n
+}
+
+// leave frees the slot of a finished run in chatID and starts the
+// waiting runs that now fit
+func (q *runQueue) leave(chatID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.running--
+	if q.chats[chatID]--; q.chats[chatID] <= 0 {
+		delete(q.chats, chatID)
+	}
+
+	for i := q.next(); i >= 0; i = q.next() {
+		w := q.waiting[i]
+		q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+		q.start(w.chatID, w.high)
+		close(w.ready)
+	}
+	metrics.Set("queued", float64(len(q.waiting)))
+}
+
+// next returns the index of the waiting run to start next, or -1 when
+// none fits: the oldest high-priority run that fits, unless maxSkips of
+// them went ahead of the others in a row, then the oldest other run that
+// fits; callers hold mu
+func (q *runQueue) next() int {
+	high, normal := -1, -1
+	for i, w := range q.waiting {
+		if !q.fits(w.chatID) {
+			continue
+		}
+		if w.high && high < 0 {
+			high = i
+		} else if !w.high && normal < 0 {
+			normal = i
+		}
+	}
+	if high >= 0 && (normal < 0 || q.skips < q.maxSkips) {
+		return high
+	}
+	return normal
+}
+
+// place returns w's position in the queue, 1 being next, and how many
+// runs at once serve the queue it's waiting in
+func (q *runQueue) place(w *queuedRun) (position, slots int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, other := range q.waiting {
+		if other == w {
+			return q.position(w.chatID, w.high, i), q.slots()
+		}
+	}
+	return 0, q.slots()
+}
+
+// position counts the waiting runs that a run in chatID, at index n or
+// past the end for a new one, waits behind, plus one: the earlier runs,
+// bar the earlier normal ones for a high-priority run, and the later
+// high-priority runs for a normal one. Without a bridge-wide limit only
+// runs in the same chat count. Callers hold mu.
+func (q *runQueue) position(chatID string, high bool, n int) int {
+	ahead := 0
+	for i, w := range q.waiting {
+		if q.maxRuns == 0 && w.chatID != chatID {
+			continue
+		}
+		if (i < n && (w.high || !high)) || (i > n && w.high && !high) {
+			ahead++
+		}
+	}
+	return ahead + 1
+}
+
+// slots is how many runs at once the binding limit allows
+func (q *runQueue) slots() int {
+	if q.maxRuns > 0 {
+		return q.maxRuns
+	}
+	return q.perChat
+}
+
+// cutPriorityPrefix strips a priority prefix from text and reports
+// whether there was one
+func cutPriorityPrefix(prefixes []string, text string) (string, bool) {
+	for _, prefix := range prefixes {
+		if text == prefix || strings.HasPrefix(text, prefix+" ") {
+			return strings.TrimSpace(strings.TrimPrefix(text, prefix)), true
+		}
+	}
+	return text, false
+}
+
+// waitForSlot blocks until req, queued by runQueue.enter, may run. While
+// it waits, a placeholder shows its position and the expected wait; the
+// placeholder's ID is returned for the run to keep using, or "" when it
+// didn't wait.
+func (b *Bridge) waitForSlot(ctx context.Context, req *runRequest) string {
+	w := req.queued
+	if w == nil {
+		return ""
+	}
+	metrics.Inc("runs_queued", "backend", req.backendName)
+	queued := time.Now()
+
+	position, slots := b.queue.place(w)
+	logging.Printf(ctx, "[Bridge] Queued run in %s at position %d (priority=%t)", req.chatID, position, req.priority)
+	var placeholderID string
+	if !b.finalOnly(req.chatID) {
+		var err error
+		if placeholderID, err = b.feishuClient.SendMessage(req.chatID, b.queueStatus(req, position, slots)); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to send queue placeholder: %v", err)
+		}
+	}
+
+	ticker := time.NewTicker(queueUpdateInterval)
+	defer ticker.Stop()
+	shown := position
+	for {
+		select {
+		case <-w.ready:
+			logging.Printf(ctx, "[Bridge] Run in %s left the queue after %s", req.chatID, time.Since(queued).Round(time.Millisecond))
+			metrics.Timing("queue_wait", time.Since(queued), "backend", req.backendName)
+			return placeholderID
+		case <-ticker.C:
+		}
+		if placeholderID == "" {
+			continue
+		}
+		if position, slots = b.queue.place(w); position == 0 || position == shown {
+			continue
+		}
+		shown = position
+		if err := b.feishuClient.UpdateMessage(placeholderID, b.queueStatus(req, position, slots)); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to update queue placeholder: %v", err)
+		}
+	}
+}
+
+// queueStatus renders the placeholder of a waiting run. The wait is
+// estimated from the median response time of its backend, taken once per
+// round of runs ahead of it.
+func (b *Bridge) queueStatus(req *runRequest, position, slots int) string {
+	text := fmt.Sprintf("排队中（第 %d 位）", position)
+	stats := b.latency.stats(time.Now())
+	median, ok := stats[latencyBackendPrefix+req.backendName]
+	if !ok {
+		median, ok = stats[latencyOverall]
+	}
+	if !ok || median.P50Ms == 0 || slots == 0 {
+		return text
+	}
+	rounds := (position + slots - 1) / slots
+	seconds := (int64(rounds)*median.P50Ms + 999) / 1000
+	return fmt.Sprintf("%s/预计 %d 秒", text, seconds)
+}
@@ -0,0 +1,217 @@
+package bridge
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+)
+
+// queueRun is a run entered into a runQueue by a test
+type queueRun struct {
+	name   string
+	chatID string
+	high   bool
+}
+
+func enterRun(q *runQueue, r queueRun) (*queuedRun, admission) {
+	return q.enter(&runRequest{chatID: r.chatID, text: r.name, priority: r.high})
+}
+
+func TestRunQueueStartOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxSkips int
+		runs     []queueRun
+		want     []string
+	}{
+		{
+			name:     "arrival order",
+			maxSkips: 3,
+			runs:     []queueRun{{"n1", "oc_a", false}, {"n2", "oc_b", false}, {"n3", "oc_a", false}},
+			want:     []string{"n1", "n2", "n3"},
+		},
+		{
+			name:     "high priority first",
+			maxSkips: 3,
+			runs:     []queueRun{{"n1", "oc_a", false}, {"n2", "oc_b", false}, {"h1", "oc_c", true}, {"h2", "oc_d", true}},
+			want:     []string{"h1", "h2", "n1", "n2"},
+		},
+		{
+			name:     "one skip in a row",
+			maxSkips: 1,
+			runs:     []queueRun{{"n1", "oc_a", false}, {"n2", "oc_b", false}, {"h1", "oc_c", true}, {"h2", "oc_d", true}},
+			want:     []string{"h1", "n1", "h2", "n2"},
+		},
+		{
+			name:     "two skips in a row",
+			maxSkips: 2,
+			runs:     []queueRun{{"n1", "oc_a", false}, {"h1", "oc_b", true}, {"h2", "oc_c", true}, {"h3", "oc_d", true}, {"n2", "oc_e", false}},
+			want:     []string{"h1", "h2", "n1", "h3", "n2"},
+		},
+		{
+			name:     "no skips",
+			maxSkips: 0,
+			runs:     []queueRun{{"n1", "oc_a", false}, {"h1", "oc_b", true}, {"n2", "oc_c", false}},
+			want:     []string{"n1", "n2", "h1"},
+		},
+		{
+			name:     "high priority without others waiting",
+			maxSkips: 0,
+			runs:     []queueRun{{"h1", "oc_a", true}, {"h2", "oc_b", true}},
+			want:     []string{"h1", "h2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newRunQueue(config.QueueConfig{MaxRuns: 1, MaxSkips: tt.maxSkips})
+			if _, got := enterRun(q, queueRun{"first", "oc_first", false}); got != runStarted {
+				t.Fatalf("first run: admission %d, want runStarted", got)
+			}
+			queued := make(map[string]*queuedRun)
+			for _, r := range tt.runs {
+				w, got := enterRun(q, r)
+				if got != runQueued {
+					t.Fatalf("%s: admission %d, want runQueued", r.name, got)
+				}
+				queued[r.name] = w
+			}
+
+			var started []string
+			chatID := "oc_first"
+			for range tt.runs {
+				q.leave(chatID)
+				for name, w := range queued {
+					select {
+					case <-w.ready:
+						started = append(started, name)
+						chatID = w.chatID
+						delete(queued, name)
+					default:
+					}
+				}
+			}
+			if !reflect.DeepEqual(started, tt.want) {
+				t.Errorf("started %v, want %v", started, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunQueueMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		merged   queueRun
+		wantText []string
+		wantHigh []bool
+	}{
+		{
+			name:     "into the chat's last waiting run",
+			merged:   queueRun{"a4", "oc_a", false},
+			wantText: []string{"a2", "b1", "a3\n\na4"},
+			wantHigh: []bool{false, false, false},
+		},
+		{
+			name:     "high priority raises the run",
+			merged:   queueRun{"a4", "oc_a", true},
+			wantText: []string{"a2", "b1", "a3\n\na4"},
+			wantHigh: []bool{false, false, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newRunQueue(config.QueueConfig{MaxRuns: 1, PerChat: 3, Overflow: "merge"})
+			enterRun(q, queueRun{"a1", "oc_a", false})
+			var waiting []*queuedRun
+			for _, r := range []queueRun{{"a2", "oc_a", false}, {"b1", "oc_b", false}, {"a3", "oc_a", false}} {
+				w, got := enterRun(q, r)
+				if got != runQueued {
+					t.Fatalf("%s: admission %d, want runQueued", r.name, got)
+				}
+				waiting = append(waiting, w)
+			}
+
+			w, got := enterRun(q, tt.merged)
+			if got != runMerged || w != waiting[2] {
+				t.Fatalf("admission %d into %v, want runMerged into a3", got, w)
+			}
+			if len(q.waiting) != 3 {
+				t.Errorf("%d runs waiting after the merge, want 3", len(q.waiting))
+			}
+			for i, w := range waiting {
+				if w.req.text != tt.wantText[i] || w.high != tt.wantHigh[i] {
+					t.Errorf("waiting run %d: text %q, high %t, want %q, %t", i, w.req.text, w.high, tt.wantText[i], tt.wantHigh[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRunQueuePlace(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       config.QueueConfig
+		started   []queueRun
+		waiting   []queueRun
+		want      map[string]int
+		wantSlots int
+	}{
+		{
+			name:      "normal runs in arrival order",
+			cfg:       config.QueueConfig{MaxRuns: 2},
+			started:   []queueRun{{"s1", "oc_a", false}, {"s2", "oc_b", false}},
+			waiting:   []queueRun{{"n1", "oc_a", false}, {"n2", "oc_b", false}, {"n3", "oc_c", false}},
+			want:      map[string]int{"n1": 1, "n2": 2, "n3": 3},
+			wantSlots: 2,
+		},
+		{
+			name:      "high priority ahead of earlier normal runs",
+			cfg:       config.QueueConfig{MaxRuns: 1, MaxSkips: 3},
+			started:   []queueRun{{"s1", "oc_a", false}},
+			waiting:   []queueRun{{"n1", "oc_a", false}, {"h1", "oc_b", true}, {"n2", "oc_c", false}, {"h2", "oc_d", true}},
+			want:      map[string]int{"h1": 1, "h2": 2, "n1": 3, "n2": 4},
+			wantSlots: 1,
+		},
+		{
+			name:      "only the chat's runs without a bridge-wide limit",
+			cfg:       config.QueueConfig{PerChat: 1, MaxSkips: 3},
+			started:   []queueRun{{"a0", "oc_a", false}, {"b0", "oc_b", false}},
+			waiting:   []queueRun{{"a1", "oc_a", false}, {"b1", "oc_b", false}, {"a2", "oc_a", true}, {"b2", "oc_b", false}},
+			want:      map[string]int{"a2": 1, "a1": 2, "b1": 1, "b2": 2},
+			wantSlots: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newRunQueue(tt.cfg)
+			for _, r := range tt.started {
+				if _, got := enterRun(q, r); got != runStarted {
+					t.Fatalf("%s: admission %d, want runStarted", r.name, got)
+				}
+			}
+			got := make(map[string]int)
+			queued := make(map[string]*queuedRun)
+			for _, r := range tt.waiting {
+				w, adm := enterRun(q, r)
+				if adm != runQueued {
+					t.Fatalf("%s: admission %d, want runQueued", r.name, adm)
+				}
+				queued[r.name] = w
+			}
+			for name, w := range queued {
+				position, slots := q.place(w)
+				if slots != tt.wantSlots {
+					t.Errorf("%s: %d slots, want %d", name, slots, tt.wantSlots)
+				}
+				got[name] = position
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("positions %v, want %v", got, tt.want)
+			}
+
+			if position, _ := q.place(&queuedRun{chatID: "oc_a"}); position != 0 {
+				t.Errorf("position of a run not in the queue = %d, want 0", position)
+			}
+		})
+	}
+}
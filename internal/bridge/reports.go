@@ -0,0 +1,107 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/analytics"
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// reportLoop posts the scheduled usage reports when they fall due
+func (b *Bridge) reportLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+
+		for _, r := range b.cfg.Analytics.Reports {
+			if now.Hour() != r.Hour || (r.Period == "monthly" && now.Day() != 1) {
+				continue
+			}
+			b.postReportOnce(ctx, r, now)
+		}
+	}
+}
+
+// postReportOnce posts r for the period ending before now, unless this or
+// another instance already did
+func (b *Bridge) postReportOnce(ctx context.Context, r config.ReportConfig, now time.Time) {
+	_, since, _ := reportPeriod(r.Period, now)
+	key := "last_report:" + r.Period + ":" + r.Chat
+
+	var last time.Time
+	if _, err := b.store.Get(digestBucket, key, &last); err != nil {
+		log.Printf("[Bridge] Failed to read last %s report time: %v", r.Period, err)
+		return
+	}
+	if last.Equal(since) {
+		return
+	}
+
+	first, err := b.shared.Claim(ctx, "report:"+r.Period+":"+r.Chat+":"+since.Format("2006-01-02"), 24*time.Hour)
+	if err != nil {
+		log.Printf("[Bridge] Failed to claim %s report: %v", r.Period, err)
+		return
+	}
+	if !first {
+		return
+	}
+
+	if err := b.postReport(r, now); err != nil {
+		log.Printf("[Bridge] Failed to post %s report: %v", r.Period, err)
+		return
+	}
+	if err := b.store.Set(digestBucket, key, since); err != nil {
+		log.Printf("[Bridge] Failed to save last %s report time: %v", r.Period, err)
+	}
+}
+
+// reportPeriod returns the start of the period before the last one, and
+// the last full day or month before now
+func reportPeriod(period string, now time.Time) (prev, since, until time.Time) {
+	if period == "monthly" {
+		until = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return until.AddDate(0, -2, 0), until.AddDate(0, -1, 0), until
+	}
+	until = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return until.AddDate(0, 0, -2), until.AddDate(0, 0, -1), until
+}
+
+// postReport sends r's usage report for the period ending before now,
+// with the CSV attachment if configured
+func (b *Bridge) postReport(r config.ReportConfig, now time.Time) error {
+	prev, since, until := reportPeriod(r.Period, now)
+	title, name := "每日使用报告", since.Format("2006-01-02")
+	if r.Period == "monthly" {
+		title, name = "每月使用报告", since.Format("2006-01")
+	}
+
+	report, err := analytics.Period(b.transcripts, prev, since, until, b.cfg.Pricing)
+	if err != nil {
+		return err
+	}
+
+	card := feishu.NewCard(title, "blue")
+	card.AddMarkdown(report.Markdown())
+	if _, err := b.feishuClient.SendCard(r.Chat, card); err != nil {
+		return err
+	}
+	if r.CSV {
+		file := fmt.Sprintf("usage-%s.csv", name)
+		if _, err := b.feishuClient.SendFile(r.Chat, file, report.CSV()); err != nil {
+			return fmt.Errorf("failed to send %s: %w", file, err)
+		}
+	}
+	log.Printf("[Bridge] Posted %s report to %s (%d messages)", r.Period, r.Chat, report.Current.Messages)
+	return nil
+}
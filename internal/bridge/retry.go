@@ -0,0 +1,140 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// retryBucket stores the messages of failed runs, by message ID, so the
+// retry button on the error card can submit them again. Kept in the
+// shared store so any instance can take the click.
+const retryBucket = "retry_prompt"
+
+// retryTTL is how long the retry button on an error card works
+const retryTTL = 24 * time.Hour
+
+// retryPrompt is a message whose run failed. Its content is sealed when
+// encryption is configured.
+type retryPrompt struct {
+	Message feishu.Message `json:"message"`
+	Expires time.Time      `json:"expires"`
+}
+
+// sendRetryCard reports a failed run with a card whose button submits
+// req's message again, replacing the placeholder and whatever was
+// streamed. It returns false when no card was sent, so the caller falls
+// back to a plain reply.
+func (b *Bridge) sendRetryCard(ctx context.Context, req *runRequest, reply string, stale ...string) bool {
+	// Kept unsealed the message would leak
+	if req.msg == nil || req.msg.MessageID == "" || b.keysErr != nil {
+		return false
+	}
+	b.sweepRetryPrompts()
+
+	id := req.msg.MessageID
+	msg := *req.msg
+	msg.Content = string(b.keys.Seal([]byte(msg.Content)))
+	if err := b.store.Set(retryBucket, id, retryPrompt{Message: msg, Expires: time.Now().Add(retryTTL)}); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to save message %s for retry: %v", id, err)
+		return false
+	}
+	if _, err := b.feishuClient.SendCard(req.chatID, retryCard(id, reply)); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to send retry card: %v", err)
+		b.store.Delete(retryBucket, id)
+		return false
+	}
+	for _, msgID := range stale {
+		if msgID == "" {
+			continue
+		}
+		if err := b.feishuClient.DeleteMessage(msgID); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to delete message %s: %v", msgID, err)
+		}
+	}
+	return true
+}
+
+// sweepRetryPrompts drops messages whose retry button has expired
+func (b *Bridge) sweepRetryPrompts() {
+	now := time.Now()
+	for _, id := range b.store.Keys(retryBucket) {
+		var p retryPrompt
+		if ok, err := b.store.Get(retryBucket, id, &p); err == nil && ok && now.After(p.Expires) {
+			b.store.Delete(retryBucket, id)
+		}
+	}
+}
+
+// retryCard tells the chat a run failed and offers to run it again
+func retryCard(id, reply string) *feishu.Card {
+	return feishu.NewCard("处理失败", "red").
+		AddMarkdown(reply).
+		AddButtons(feishu.CardButton{Text: "重试", Type: "primary", Value: map[string]interface{}{"action": "retry_run", "id": id}}).
+		AddNote(fmt.Sprintf("重试会重新提交原消息，无需再次输入；%d 小时内有效", int(retryTTL.Hours())))
+}
+
+// actionRetry submits a failed run's message again; only its sender may
+func actionRetry(ctx context.Context, b *Bridge, action *feishu.CardAction) (string, error) {
+	id := actionString(action, "id")
+	var p retryPrompt
+	ok, err := b.store.Get(retryBucket, id, &p)
+	if err != nil {
+		return "", err
+	}
+	if !ok || time.Now().After(p.Expires) {
+		return "", fmt.Errorf("该消息已无法重试，请重新发送")
+	}
+	if p.Message.SenderID != action.OperatorID {
+		return "", fmt.Errorf("只有提问者可以重试")
+	}
+	content, err := b.keys.Open([]byte(p.Message.Content))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt message %s: %w", id, err)
+	}
+	p.Message.Content = string(content)
+	first, err := b.shared.Claim(ctx, "retry:"+id, retryTTL)
+	if err != nil {
+		return "", err
+	}
+	if !first {
+		return "", fmt.Errorf("已重新提交")
+	}
+	if err := b.store.Delete(retryBucket, id); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to delete retry prompt %s: %v", id, err)
+	}
+
+	if action.MessageID != "" {
+		card := feishu.NewCard("处理失败", "grey").AddNote(fmt.Sprintf("<at id=%s></at> 已重新提交", action.OperatorID))
+		if err := b.feishuClient.UpdateCard(action.MessageID, card); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to update retry card: %v", err)
+		}
+	}
+
+	run := p.Message
+	// A new ID gets past deduplication, and a card for the retry if it
+	// fails too
+	run.MessageID = id + ":retry"
+	logging.Printf(ctx, "[Bridge] %s retried message %s in %s", action.OperatorID, id, run.ChatID)
+	if err := b.handleMessage(ctx, &run); err != nil {
+		return "", err
+	}
+	return "已重新提交", nil
+}
+
+// forgetRetryPrompts deletes userID's messages kept for retry
+func (b *Bridge) forgetRetryPrompts(userID string) error {
+	for _, id := range b.store.Keys(retryBucket) {
+		var p retryPrompt
+		if ok, err := b.store.Get(retryBucket, id, &p); err != nil || !ok || p.Message.SenderID != userID {
+			continue
+		}
+		if err := b.store.Delete(retryBucket, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package bridge
+
+import (
+	"github.com/wy51ai/moltbotCNAPP/internal/backend"
+	"github.com/wy51ai/moltbotCNAPP/internal/metrics"
+)
+
+// runErrorReplies tell the chat why a run failed without exposing the
+// error itself, which only goes to the logs
+var runErrorReplies = map[backend.ErrorKind]string{
+	backend.ErrorUnreachable:    "暂时无法连接 AI 服务，请稍后再试",
+	backend.ErrorAuth:           "AI 服务鉴权失败，请联系管理员检查配置",
+	backend.ErrorTimeout:        "AI 服务响应超时，请稍后重试，或把问题拆小一些",
+	backend.ErrorRateLimited:    "AI 服务当前请求过多，请稍等片刻再试",
+	backend.ErrorContentBlocked: "这个问题的回答被内容安全策略拦截了，请换个问法试试",
+	backend.ErrorInternal:       "处理消息时出错了，请稍后再试；如果一直出现，请联系管理员",
+}
+
+// runErrorReply classifies a failed run on backendName, counts it and
+// returns the reply for the chat
+func runErrorReply(backendName string, err error) (backend.ErrorKind, string) {
+	kind := backend.Classify(err)
+	metrics.Inc("run_errors", "backend", backendName, "kind", string(kind))
+	return kind, runErrorReplies[kind]
+}
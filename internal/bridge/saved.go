@@ -0,0 +1,285 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// savedPromptBucket stores prompt templates, keyed by chat_id or
+// globalPrompts
+const savedPromptBucket = "saved_prompt"
+
+// globalPrompts is the savedPromptBucket key of templates every chat sees
+const globalPrompts = "global"
+
+// maxSavedPrompt bounds a template, in characters
+const maxSavedPrompt = 4000
+
+// maxSavedPrompts bounds how many templates one chat, or the global
+// library, keeps
+const maxSavedPrompts = 50
+
+// promptName is what /saved accepts as a template name
+var promptName = regexp.MustCompile(`^[\p{L}\p{N}_-]{1,32}$`)
+
+// placeholder matches {名称} in a template
+var placeholder = regexp.MustCompile(`\{([^{}\s]{1,32})\}`)
+
+// savedPrompt is a named prompt template
+type savedPrompt struct {
+	Text      string    `json:"text"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// placeholders returns the template's distinct placeholders in order of
+// first use
+func (p savedPrompt) placeholders() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range placeholder.FindAllStringSubmatch(p.Text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// fill substitutes args for the placeholders in order; the last one
+// takes the rest of args. Without placeholders, args are appended.
+func (p savedPrompt) fill(args string) (string, error) {
+	names := p.placeholders()
+	if len(names) == 0 {
+		if args == "" {
+			return p.Text, nil
+		}
+		return p.Text + "\n" + args, nil
+	}
+	values := make(map[string]string, len(names))
+	rest := args
+	for i, name := range names {
+		if rest == "" {
+			return "", fmt.Errorf("缺少参数：{%s}", strings.Join(names[i:], "} {"))
+		}
+		if i == len(names)-1 {
+			values[name] = rest
+			break
+		}
+		values[name], rest = cutSpace(rest)
+	}
+	return placeholder.ReplaceAllStringFunc(p.Text, func(m string) string {
+		return values[m[1:len(m)-1]]
+	}), nil
+}
+
+const savedUsage = `/saved 管理常用提示词模板，模板中的 {名称} 为占位符：
+/saved 列出模板
+/saved add [-g] 名称 内容 保存模板（-g 为所有会话可用的全局模板，需管理员权限）
+/saved show 名称 查看模板
+/saved delete [-g] 名称 删除模板
+/saved run 名称 [参数...] 按顺序填入占位符后发送`
+
+// Running a template goes back through handleMessage, which looks
+// commands up, so /saved can't be listed in the commands map itself
+func init() {
+	commands["saved"] = command{usage: savedUsage, handler: cmdSaved}
+}
+
+// cmdSaved stores, lists and runs prompt templates
+func cmdSaved(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	sub, rest := cutSpace(args)
+	switch strings.ToLower(sub) {
+	case "", "list":
+		return b.listSavedPrompts(msg.ChatID)
+	case "add":
+		return b.addSavedPrompt(ctx, msg, rest)
+	case "show":
+		prompt, scope, ok := b.savedPrompt(msg.ChatID, rest)
+		if !ok {
+			return fmt.Sprintf("未找到模板 %s", rest)
+		}
+		return fmt.Sprintf("模板 %s（%s，%s 由 %s 保存）：\n%s", rest, scopeLabel(scope),
+			prompt.CreatedAt.Local().Format("2006-01-02 15:04"), prompt.CreatedBy, prompt.Text)
+	case "delete":
+		return b.deleteSavedPrompt(ctx, msg, rest)
+	case "run":
+		return b.runSavedPrompt(ctx, msg, rest)
+	}
+	return savedUsage
+}
+
+// addSavedPrompt handles "/saved add [-g] name text"
+func (b *Bridge) addSavedPrompt(ctx context.Context, msg *feishu.Message, args string) string {
+	scope, args, denied := b.promptScope(ctx, msg, "saved.add", args)
+	if denied != "" {
+		return denied
+	}
+	name, text := cutSpace(args)
+	if !promptName.MatchString(name) || text == "" {
+		return "模板名只能包含文字、数字、下划线和连字符，最长 32 个字符，且需要内容\n\n" + savedUsage
+	}
+	if n := utf8.RuneCountInString(text); n > maxSavedPrompt {
+		return fmt.Sprintf("模板过长（%d 字），最多 %d 字", n, maxSavedPrompt)
+	}
+	// A template that runs /saved would run itself
+	if strings.HasPrefix(text, "/saved") {
+		return "模板不能以 /saved 开头"
+	}
+
+	prompts := b.savedPrompts(scope)
+	if _, exists := prompts[name]; !exists && len(prompts) >= maxSavedPrompts {
+		return fmt.Sprintf("最多保存 %d 个模板，请先删除不用的模板", maxSavedPrompts)
+	}
+	prompts[name] = savedPrompt{Text: text, CreatedBy: msg.SenderID, CreatedAt: time.Now().UTC()}
+	if err := b.store.Set(savedPromptBucket, scope, prompts); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to save prompt %s: %v", name, err)
+		return fmt.Sprintf("保存失败：%s", redact.Error(err))
+	}
+	logging.Printf(ctx, "[Bridge] %s saved prompt %s (%s)", msg.SenderID, name, scope)
+	b.audit.Record(ctx, audit.Event{Action: "saved.add", Actor: msg.SenderID, ChatID: msg.ChatID, Target: scope + "/" + name})
+	reply := fmt.Sprintf("已保存%s模板 %s", scopeLabel(scope), name)
+	if names := prompts[name].placeholders(); len(names) > 0 {
+		reply += fmt.Sprintf("，占位符：{%s}", strings.Join(names, "} {"))
+	}
+	return reply + fmt.Sprintf("\n使用：/saved run %s", name)
+}
+
+// deleteSavedPrompt handles "/saved delete [-g] name"
+func (b *Bridge) deleteSavedPrompt(ctx context.Context, msg *feishu.Message, args string) string {
+	scope, name, denied := b.promptScope(ctx, msg, "saved.delete", args)
+	if denied != "" {
+		return denied
+	}
+	prompts := b.savedPrompts(scope)
+	if _, ok := prompts[name]; !ok {
+		return fmt.Sprintf("未找到%s模板 %s", scopeLabel(scope), name)
+	}
+	delete(prompts, name)
+	var err error
+	if len(prompts) == 0 {
+		err = b.store.Delete(savedPromptBucket, scope)
+	} else {
+		err = b.store.Set(savedPromptBucket, scope, prompts)
+	}
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to delete prompt %s: %v", name, err)
+		return fmt.Sprintf("删除失败：%s", redact.Error(err))
+	}
+	logging.Printf(ctx, "[Bridge] %s deleted prompt %s (%s)", msg.SenderID, name, scope)
+	b.audit.Record(ctx, audit.Event{Action: "saved.delete", Actor: msg.SenderID, ChatID: msg.ChatID, Target: scope + "/" + name})
+	return fmt.Sprintf("已删除%s模板 %s", scopeLabel(scope), name)
+}
+
+// promptScope strips a leading -g from args and returns the scope it
+// selects. Only admins may change global templates; denied is the reply
+// for anyone else.
+func (b *Bridge) promptScope(ctx context.Context, msg *feishu.Message, action, args string) (scope, rest, denied string) {
+	flag, rest := cutSpace(args)
+	if flag != "-g" {
+		return msg.ChatID, args, ""
+	}
+	if !b.cfg.IsAdmin(msg.SenderID) {
+		logging.Printf(ctx, "[Bridge] Denied global /saved from non-admin %s in %s", msg.SenderID, msg.ChatID)
+		b.audit.Record(ctx, audit.Event{Action: action, Actor: msg.SenderID, ChatID: msg.ChatID, Target: globalPrompts, Outcome: audit.Denied, Detail: "not an admin"})
+		return "", "", "只有管理员可以修改全局模板"
+	}
+	return globalPrompts, rest, ""
+}
+
+// runSavedPrompt fills a template and handles it as if the sender had
+// typed it
+func (b *Bridge) runSavedPrompt(ctx context.Context, msg *feishu.Message, args string) string {
+	name, rest := cutSpace(args)
+	prompt, _, ok := b.savedPrompt(msg.ChatID, name)
+	if !ok {
+		return fmt.Sprintf("未找到模板 %s，发送 /saved 查看可用模板", name)
+	}
+	text, err := prompt.fill(rest)
+	if err != nil {
+		return fmt.Sprintf("%s\n用法：/saved run %s %s", err.Error(), name, placeholderHint(prompt))
+	}
+
+	run := *msg
+	run.MessageID = msg.MessageID + ":saved"
+	run.Content = text
+	// The command addressed the bot, so its prompt does too in groups
+	run.Mentions = append(append([]feishu.Mention(nil), msg.Mentions...), feishu.Mention{Name: "saved:" + name})
+	logging.Printf(ctx, "[Bridge] Running saved prompt %s for %s", name, msg.SenderID)
+	if err := b.handleMessage(ctx, &run); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to run saved prompt %s: %v", name, err)
+		return fmt.Sprintf("运行失败：%s", redact.Error(err))
+	}
+	return ""
+}
+
+// savedPrompts returns the templates of scope
+func (b *Bridge) savedPrompts(scope string) map[string]savedPrompt {
+	prompts := make(map[string]savedPrompt)
+	if _, err := b.store.Get(savedPromptBucket, scope, &prompts); err != nil {
+		log.Printf("[Bridge] Failed to read saved prompts of %s: %v", scope, err)
+	}
+	return prompts
+}
+
+// savedPrompt looks name up in chatID's templates, then the global ones
+func (b *Bridge) savedPrompt(chatID, name string) (savedPrompt, string, bool) {
+	for _, scope := range []string{chatID, globalPrompts} {
+		if prompt, ok := b.savedPrompts(scope)[name]; ok {
+			return prompt, scope, true
+		}
+	}
+	return savedPrompt{}, "", false
+}
+
+// listSavedPrompts lists the templates chatID can run
+func (b *Bridge) listSavedPrompts(chatID string) string {
+	var sb strings.Builder
+	for _, scope := range []string{chatID, globalPrompts} {
+		prompts := b.savedPrompts(scope)
+		if len(prompts) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(prompts))
+		for name := range prompts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(&sb, "%s模板：\n", scopeLabel(scope))
+		for _, name := range names {
+			fmt.Fprintf(&sb, "- %s\n", strings.TrimSpace(name+" "+placeholderHint(prompts[name])))
+		}
+		sb.WriteString("\n")
+	}
+	if sb.Len() == 0 {
+		return "还没有保存的模板\n\n" + savedUsage
+	}
+	return sb.String() + savedUsage
+}
+
+// placeholderHint shows a template's placeholders as arguments
+func placeholderHint(p savedPrompt) string {
+	names := p.placeholders()
+	if len(names) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(names, "} {") + "}"
+}
+
+func scopeLabel(scope string) string {
+	if scope == globalPrompts {
+		return "全局"
+	}
+	return "本会话"
+}
@@ -0,0 +1,24 @@
+package bridge
+
+import "github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+
+// MessageSender is the part of the Feishu client the bridge uses.
+// *feishu.Client implements it; tests substitute testutil.FakeSender.
+type MessageSender interface {
+	SendMessage(chatID, text string) (string, error)
+	UpdateMessage(messageID, text string) error
+	DeleteMessage(messageID string) error
+	SendPost(chatID, text string) (string, error)
+	UpdatePost(messageID, text string) error
+	SendCard(chatID string, card *feishu.Card) (string, error)
+	UpdateCard(messageID string, card *feishu.Card) error
+	SendFile(chatID, name string, data []byte) (string, error)
+	SendImage(chatID string, data []byte) (string, error)
+	UploadImage(data []byte) (string, error)
+	// DeferReply queues a reply to send once the Feishu API recovers
+	DeferReply(chatID, messageID, text string, post bool)
+	Circuit() feishu.CircuitStatus
+	OnCircuitChange(fn func(feishu.CircuitStatus))
+}
+
+var _ MessageSender = (*feishu.Client)(nil)
@@ -0,0 +1,186 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/backend"
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/errreport"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/seal"
+)
+
+// runResult captures the outcome of one backend run for comparison
+type runResult struct {
+	Backend      string `json:"backend"`
+	RunID        string `json:"run_id,omitempty"`
+	Reply        string `json:"reply"`
+	Error        string `json:"error,omitempty"`
+	LatencyMs    int64  `json:"latency_ms"`
+	InputTokens  int    `json:"input_tokens"`
+	OutputTokens int    `json:"output_tokens"`
+}
+
+// shadowRecord is one line of a daily shadow file, sealed when
+// encryption is configured
+type shadowRecord struct {
+	Time    time.Time `json:"time"`
+	ChatID  string    `json:"chat_id"`
+	Prompt  string    `json:"prompt"`
+	Primary runResult `json:"primary"`
+	Shadow  runResult `json:"shadow"`
+}
+
+// shadowRunner sends a sample of messages to a candidate backend and
+// records both replies side by side without delivering the shadow reply
+type shadowRunner struct {
+	name    string
+	backend backend.Backend
+	percent float64
+	dir     string
+	keys    *seal.Keyring
+	mu      sync.Mutex
+}
+
+func newShadowRunner(name string, b backend.Backend, percent float64, dir string, keys *seal.Keyring) *shadowRunner {
+	return &shadowRunner{
+		name:    name,
+		backend: b,
+		percent: percent,
+		dir:     dir,
+		keys:    keys,
+	}
+}
+
+// sample reports whether this message should be shadowed
+func (s *shadowRunner) sample() bool {
+	return s != nil && rand.Float64()*100 < s.percent
+}
+
+// start runs the shadow backend in the background.
+// The returned channel yields its result once finished.
+func (s *shadowRunner) start(ctx context.Context, text, sessionKey string) <-chan runResult {
+	ch := make(chan runResult, 1)
+	ctx = logging.Fork(ctx)
+	go func() {
+		defer errreport.Recover(ctx, "shadow")
+		// Separate session so the candidate keeps its own history
+		res, _ := runAndMeasure(ctx, s.name, s.backend, text, "shadow:"+sessionKey, nil)
+		ch <- res
+	}()
+	return ch
+}
+
+// record waits for the shadow run and appends both results to the log
+func (s *shadowRunner) record(ctx context.Context, chatID, prompt string, primary runResult, shadow <-chan runResult) {
+	rec := shadowRecord{
+		Time:    time.Now(),
+		ChatID:  chatID,
+		Prompt:  prompt,
+		Primary: primary,
+		Shadow:  <-shadow,
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		logging.Printf(ctx, "[Shadow] Failed to encode record: %v", err)
+		return
+	}
+	line = s.keys.Seal(line)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		logging.Printf(ctx, "[Shadow] Failed to create %s: %v", s.dir, err)
+		return
+	}
+	path := filepath.Join(s.dir, rec.Time.Format("2006-01-02")+".jsonl")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		logging.Printf(ctx, "[Shadow] Failed to open %s: %v", path, err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		logging.Printf(ctx, "[Shadow] Failed to write record: %v", err)
+	}
+	logging.Printf(ctx, "[Shadow] Recorded %s (%dms) vs %s (%dms) for %s",
+		rec.Primary.Backend, rec.Primary.LatencyMs, rec.Shadow.Backend, rec.Shadow.LatencyMs, chatID)
+}
+
+// ShadowFiles returns the files shadow records are kept in: the daily
+// files under cfg.Shadow.Dir, and shadow.jsonl if an older version left
+// one behind
+func ShadowFiles(cfg *config.Config) ([]string, error) {
+	var files []string
+	if _, err := os.Stat(filepath.Join(cfg.Dir, "shadow.jsonl")); err == nil {
+		files = append(files, filepath.Join(cfg.Dir, "shadow.jsonl"))
+	}
+	entries, err := os.ReadDir(cfg.Shadow.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return files, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", cfg.Shadow.Dir, err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".jsonl") {
+			files = append(files, filepath.Join(cfg.Shadow.Dir, e.Name()))
+		}
+	}
+	return files, nil
+}
+
+// runAndMeasure asks a backend and captures latency, run ID and token usage.
+// onProgress, if set, still receives every stream event.
+func runAndMeasure(ctx context.Context, name string, agent backend.Backend, text, sessionKey string, onProgress backend.ProgressFunc) (runResult, error) {
+	var usage backend.Usage
+	var run struct {
+		RunID string `json:"runId"`
+	}
+	var mu sync.Mutex
+
+	start := time.Now()
+	reply, err := agent.Ask(ctx, text, sessionKey, func(stream, data string) {
+		switch stream {
+		case backend.StreamUsage:
+			mu.Lock()
+			json.Unmarshal([]byte(data), &usage)
+			mu.Unlock()
+		case backend.StreamRun:
+			mu.Lock()
+			json.Unmarshal([]byte(data), &run)
+			mu.Unlock()
+		}
+		if onProgress != nil {
+			onProgress(stream, data)
+		}
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	res := runResult{
+		Backend:      name,
+		RunID:        run.RunID,
+		Reply:        reply,
+		LatencyMs:    time.Since(start).Milliseconds(),
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	return res, err
+}
@@ -0,0 +1,259 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// maxSheetsPerMessage bounds the spreadsheets read for one message
+const maxSheetsPerMessage = 3
+
+// sheetColumns is how many columns are read from a sheet, A to Z
+const sheetColumns = 26
+
+// sheetDirective matches the block the agent writes to append rows:
+// [[sheet:append 表格链接 followed by one row per line, cells separated
+// by |, and closed by ]]
+var sheetDirective = regexp.MustCompile(`\[\[sheet:append\s+(\S+)\s*\n([^\]]*)\]\]`)
+
+// sheetClient is implemented by Feishu clients that can use spreadsheets
+type sheetClient interface {
+	FirstSheet(token string) (string, error)
+	ReadRange(token, rng string) ([][]string, error)
+	AppendRows(token, rng string, rows [][]string) (string, error)
+}
+
+const sheetUsage = "/sheet 表格链接 [范围] 读取飞书表格（范围如 A1:D20，默认前若干行）；/sheet append 表格链接 后换行，每行一条记录、单元格用 | 分隔，追加到表格末尾"
+
+// cmdSheet reads a linked spreadsheet or appends rows to it
+func cmdSheet(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if !b.cfg.Sheets.Enabled {
+		return "表格功能未启用"
+	}
+	sub, rest := cutSpace(args)
+	if strings.ToLower(sub) == "append" {
+		link, body, _ := strings.Cut(rest, "\n")
+		ref, ok := sheetRef(strings.TrimSpace(link))
+		if !ok {
+			return sheetUsage
+		}
+		return b.appendRows(ctx, msg.ChatID, msg.SenderID, ref, parseRows(body))
+	}
+
+	ref, ok := sheetRef(sub)
+	if !ok {
+		return sheetUsage
+	}
+	rows, rng, err := b.readSheet(ctx, msg.ChatID, msg.SenderID, ref, rest)
+	if err != nil {
+		return err.Error()
+	}
+	if len(rows) == 0 {
+		return fmt.Sprintf("%s 没有数据", rng)
+	}
+	return fmt.Sprintf("%s（%d 行）：\n%s", rng, len(rows), formatRows(rows))
+}
+
+// sheetRef finds the spreadsheet a link, or a bare token, names
+func sheetRef(s string) (feishu.SheetRef, bool) {
+	if refs := feishu.FindSheets(s); len(refs) > 0 {
+		return refs[0], true
+	}
+	if s != "" && !strings.ContainsAny(s, "/:?! \n") {
+		return feishu.SheetRef{Token: s, URL: s}, true
+	}
+	return feishu.SheetRef{}, false
+}
+
+// readSheet reads rng, or the first rows, of ref's sheet for actor
+func (b *Bridge) readSheet(ctx context.Context, chatID, actor string, ref feishu.SheetRef, rng string) ([][]string, string, error) {
+	client, ok := b.feishuClient.(sheetClient)
+	if !ok {
+		return nil, "", fmt.Errorf("当前连接不支持读取表格")
+	}
+	if !b.cfg.Sheets.CanRead(ref.Token) {
+		b.audit.Record(ctx, audit.Event{Action: "sheet.read", Actor: actor, ChatID: chatID, Target: ref.Token, Outcome: audit.Denied, Detail: "not in sheets.read"})
+		return nil, "", fmt.Errorf("未授权读取表格 %s", ref.Token)
+	}
+	sheetID, err := b.sheetID(client, ref)
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to find a sheet in %s: %v", ref.Token, err)
+		return nil, "", fmt.Errorf("读取表格失败：%s", redact.Error(err))
+	}
+	if rng == "" {
+		rng = fmt.Sprintf("A1:%s%d", columnName(sheetColumns), b.cfg.Sheets.MaxRows)
+	}
+	if !strings.Contains(rng, "!") {
+		rng = sheetID + "!" + rng
+	}
+	rows, err := client.ReadRange(ref.Token, rng)
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to read %s of %s: %v", rng, ref.Token, err)
+		return nil, "", fmt.Errorf("读取表格失败：%s", redact.Error(err))
+	}
+	rows = trimRows(rows)
+	if len(rows) > b.cfg.Sheets.MaxRows {
+		rows = rows[:b.cfg.Sheets.MaxRows]
+	}
+	logging.Printf(ctx, "[Bridge] %s read %d rows of %s %s", actor, len(rows), ref.Token, rng)
+	b.audit.Record(ctx, audit.Event{Action: "sheet.read", Actor: actor, ChatID: chatID, Target: ref.Token, Detail: rng})
+	return rows, rng, nil
+}
+
+// appendRows appends rows to ref's sheet for actor and returns the text
+// reporting it
+func (b *Bridge) appendRows(ctx context.Context, chatID, actor string, ref feishu.SheetRef, rows [][]string) string {
+	client, ok := b.feishuClient.(sheetClient)
+	if !ok {
+		return "当前连接不支持写入表格"
+	}
+	if !b.cfg.Sheets.CanWrite(ref.Token) {
+		b.audit.Record(ctx, audit.Event{Action: "sheet.append", Actor: actor, ChatID: chatID, Target: ref.Token, Outcome: audit.Denied, Detail: "not in sheets.write"})
+		return fmt.Sprintf("未授权写入表格 %s", ref.Token)
+	}
+	if len(rows) == 0 {
+		return "没有要写入的数据\n\n" + sheetUsage
+	}
+	if len(rows) > b.cfg.Sheets.MaxRows {
+		return fmt.Sprintf("一次最多写入 %d 行，当前 %d 行", b.cfg.Sheets.MaxRows, len(rows))
+	}
+	sheetID, err := b.sheetID(client, ref)
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to find a sheet in %s: %v", ref.Token, err)
+		return fmt.Sprintf("写入表格失败：%s", redact.Error(err))
+	}
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	written, err := client.AppendRows(ref.Token, fmt.Sprintf("%s!A1:%s1", sheetID, columnName(width)), rows)
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to append %d rows to %s: %v", len(rows), ref.Token, err)
+		return fmt.Sprintf("写入表格失败：%s", redact.Error(err))
+	}
+	logging.Printf(ctx, "[Bridge] %s appended %d rows to %s at %s", actor, len(rows), ref.Token, written)
+	b.audit.Record(ctx, audit.Event{Action: "sheet.append", Actor: actor, ChatID: chatID, Target: ref.Token, Detail: fmt.Sprintf("%d rows at %s", len(rows), written)})
+	return fmt.Sprintf("已写入 %d 行到表格 %s（%s）", len(rows), ref.URL, written)
+}
+
+// sheetID returns the sheet ref opened to, or the spreadsheet's first
+func (b *Bridge) sheetID(client sheetClient, ref feishu.SheetRef) (string, error) {
+	if ref.SheetID != "" {
+		return ref.SheetID, nil
+	}
+	return client.FirstSheet(ref.Token)
+}
+
+// sheetsPrompt gives the agent the contents of the spreadsheets linked
+// in text and, for writable ones, how to append to them
+func (b *Bridge) sheetsPrompt(ctx context.Context, chatID, userID, text string) string {
+	if !b.cfg.Sheets.Enabled {
+		return ""
+	}
+	refs := feishu.FindSheets(text)
+	if len(refs) > maxSheetsPerMessage {
+		refs = refs[:maxSheetsPerMessage]
+	}
+	var sections []string
+	writable := false
+	for _, ref := range refs {
+		rows, rng, err := b.readSheet(ctx, chatID, userID, ref, "")
+		if err != nil {
+			sections = append(sections, fmt.Sprintf("表格 %s：%s", ref.URL, err.Error()))
+			continue
+		}
+		section := fmt.Sprintf("表格 %s（%s，%d 行，单元格以 | 分隔）：\n%s", ref.URL, rng, len(rows), formatRows(rows))
+		if b.cfg.Sheets.CanWrite(ref.Token) {
+			section += "\n（可写入）"
+			writable = true
+		}
+		sections = append(sections, section)
+	}
+	if writable {
+		sections = append(sections, "如需把数据追加到可写入的表格，在回答末尾写：\n[[sheet:append 表格链接\n单元格 | 单元格 | 单元格\n]]\n"+
+			"每行一条记录，列的顺序与表头一致；桥接服务会写入表格并把结果告诉用户，这段标记不会显示给用户。")
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// applySheets appends the rows the agent's reply to req asks for and
+// returns lines reporting them
+func (b *Bridge) applySheets(ctx context.Context, req *runRequest, reply string) string {
+	if !b.cfg.Sheets.Enabled {
+		return ""
+	}
+	var notes []string
+	for _, m := range sheetDirective.FindAllStringSubmatch(reply, -1) {
+		ref, ok := sheetRef(m[1])
+		if !ok {
+			continue
+		}
+		notes = append(notes, b.appendRows(ctx, req.chatID, req.senderID, ref, parseRows(m[2])))
+	}
+	return strings.Join(notes, "\n")
+}
+
+// parseRows reads one row per line with cells separated by |, or by tabs
+// as pasted from a spreadsheet; a Markdown table's outer pipes and
+// separator line are ignored
+func parseRows(text string) [][]string {
+	var rows [][]string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.Trim(line, "|-: ") == "" {
+			continue
+		}
+		sep := "|"
+		if !strings.Contains(line, "|") {
+			sep = "\t"
+		}
+		line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+		var row []string
+		for _, cell := range strings.Split(line, sep) {
+			row = append(row, strings.TrimSpace(cell))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// formatRows renders rows one per line, cells separated by |
+func formatRows(rows [][]string) string {
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		lines[i] = strings.Join(row, " | ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// trimRows drops trailing empty cells and rows
+func trimRows(rows [][]string) [][]string {
+	for i, row := range rows {
+		n := len(row)
+		for n > 0 && row[n-1] == "" {
+			n--
+		}
+		rows[i] = row[:n]
+	}
+	n := len(rows)
+	for n > 0 && len(rows[n-1]) == 0 {
+		n--
+	}
+	return rows[:n]
+}
+
+// columnName returns the letters of the n-th column, counting from 1
+func columnName(n int) string {
+	var name string
+	for ; n > 0; n = (n - 1) / 26 {
+		name = string(rune('A'+(n-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,176 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// shellMeta are characters a shell would interpret. Commands run without
+// one, so they'd be passed on literally; rejecting them keeps admins from
+// thinking a pipe or redirect did what it looks like.
+const shellMeta = "|&;<>()$`\\\"'*?[]{}~\n\r"
+
+// shellEnv are the environment variables commands inherit; the rest,
+// which may include the bridge's own credentials, are withheld
+var shellEnv = []string{"PATH", "HOME", "USER", "LANG", "LC_ALL", "TZ"}
+
+const shellUsage = "/sh 命令 在 bridge 所在主机上执行白名单内的命令并返回输出（需管理员权限，且需在配置中启用）"
+
+// cmdShell runs an allowlisted command on the bridge host for an admin
+func cmdShell(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	deny := func(reply, detail string) string {
+		logging.Printf(ctx, "[Bridge] Denied /sh from %s in %s: %s", msg.SenderID, msg.ChatID, detail)
+		b.audit.Record(ctx, audit.Event{Action: "shell.exec", Actor: msg.SenderID, ChatID: msg.ChatID, Target: redact.String(args), Outcome: audit.Denied, Detail: detail})
+		return reply
+	}
+
+	if !b.cfg.Shell.Enabled {
+		return deny("/sh 未启用", "shell disabled")
+	}
+	if !b.cfg.IsAdmin(msg.SenderID) {
+		return deny("只有管理员可以执行命令", "not an admin")
+	}
+	if !b.cfg.Shell.MayRun(msg.SenderID) {
+		return deny("你不在允许执行命令的管理员名单中", "not in shell.users")
+	}
+	if msg.ChatType == "group" && !b.cfg.Shell.Groups {
+		return deny("/sh 只能在私聊中使用", "group chat")
+	}
+	if args == "" {
+		return shellUsage
+	}
+	if _, reason := b.checkShell(args); reason != "" {
+		return deny(reason, "not allowed")
+	}
+
+	if b.cfg.TwoPerson.Requires("shell.exec") {
+		return b.requestConfirmation(ctx, pendingAction{
+			Action:      "shell.exec",
+			ChatID:      msg.ChatID,
+			Target:      args,
+			RequestedBy: msg.SenderID,
+		})
+	}
+	reply, _ := runShell(ctx, b, msg.SenderID, msg.ChatID, args, "")
+	return reply
+}
+
+// checkShell splits line into arguments and checks them against the
+// allowlist; reason explains a refusal
+func (b *Bridge) checkShell(line string) (args []string, reason string) {
+	if i := strings.IndexAny(line, shellMeta); i >= 0 {
+		return nil, fmt.Sprintf("命令不能包含 %q：不经过 shell 执行，不支持管道、重定向、变量、通配符和引号", line[i])
+	}
+	args = strings.Fields(line)
+	if !b.cfg.Shell.Permits(args) {
+		allowed := make([]string, len(b.cfg.Shell.Allow))
+		for i, prefix := range b.cfg.Shell.Allow {
+			allowed[i] = strings.Join(prefix, " ")
+		}
+		return nil, fmt.Sprintf("命令不在白名单中，允许的命令：%s", strings.Join(allowed, ", "))
+	}
+	return args, ""
+}
+
+// runShell runs line for actor and replies with its trimmed output.
+// confirmedBy names the second admin, if any. The error is only set when
+// the command couldn't run; a non-zero exit is reported in the reply.
+func runShell(ctx context.Context, b *Bridge, actor, chatID, line, confirmedBy string) (string, error) {
+	// The allowlist may have changed while a confirmation was pending
+	args, reason := b.checkShell(line)
+	if reason != "" {
+		b.audit.Record(ctx, audit.Event{Action: "shell.exec", Actor: actor, ChatID: chatID, Target: redact.String(line), Outcome: audit.Denied, Detail: "not allowed"})
+		return reason, errors.New(reason)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.cfg.Shell.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = b.cfg.Shell.Dir
+	cmd.Env = shellEnviron()
+	// Don't wait on children that outlive the command and hold its output
+	cmd.WaitDelay = time.Second
+	out := &cappedBuffer{max: b.cfg.Shell.MaxOutput * 4}
+	cmd.Stdout, cmd.Stderr = out, out
+
+	start := time.Now()
+	err := cmd.Run()
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	var status string
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		status = fmt.Sprintf("timed out after %s", b.cfg.Shell.Timeout)
+	case errors.As(err, &exitErr):
+		status = fmt.Sprintf("exit %d", exitErr.ExitCode())
+	case err != nil:
+		logging.Printf(ctx, "[Bridge] Failed to run /sh %s for %s: %v", args[0], actor, err)
+		b.audit.Record(ctx, audit.Event{Action: "shell.exec", Actor: actor, ChatID: chatID, Target: redact.String(line), Detail: "failed to start: " + redact.Error(err)})
+		reply := fmt.Sprintf("执行失败：%s", redact.Error(err))
+		return reply, err
+	default:
+		status = "exit 0"
+	}
+
+	logging.Printf(ctx, "[Bridge] %s ran /sh %s in %s: %s, %s", actor, redact.String(line), chatID, status, elapsed)
+	detail := fmt.Sprintf("%s, %s, %d bytes of output", status, elapsed, out.total)
+	if confirmedBy != "" {
+		detail += ", confirmed by " + confirmedBy
+	}
+	b.audit.Record(ctx, audit.Event{Action: "shell.exec", Actor: actor, ChatID: chatID, Target: redact.String(line), Detail: detail})
+
+	output := strings.TrimSpace(redact.String(out.buf.String()))
+	if r := []rune(output); len(r) > b.cfg.Shell.MaxOutput || out.total > out.buf.Len() {
+		if len(r) > b.cfg.Shell.MaxOutput {
+			output = string(r[:b.cfg.Shell.MaxOutput])
+		}
+		output += fmt.Sprintf("\n…（输出共 %d 字节，已截断）", out.total)
+	}
+	if output == "" {
+		output = "（无输出）"
+	}
+	return fmt.Sprintf("$ %s\n%s\n\n[%s, %s]", redact.String(line), output, status, elapsed), nil
+}
+
+// shellEnviron returns the bridge's values of shellEnv
+func shellEnviron() []string {
+	var env []string
+	for _, name := range shellEnv {
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	return env
+}
+
+// cappedBuffer keeps the first max bytes written to it and counts the
+// rest. It doesn't embed bytes.Buffer, whose ReadFrom would bypass Write.
+type cappedBuffer struct {
+	buf   bytes.Buffer
+	max   int
+	total int
+}
+
+func (c *cappedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	c.total += n
+	if room := c.max - c.buf.Len(); room > 0 {
+		if n > room {
+			p = p[:room]
+		}
+		c.buf.Write(p)
+	}
+	return n, nil
+}
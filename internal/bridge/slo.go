@@ -0,0 +1,267 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/metrics"
+	"github.com/wy51ai/moltbotCNAPP/internal/store"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// statusFile is written periodically for `clawdbot-bridge status`
+const statusFile = "bridge-status.json"
+
+// LatencyStats are percentiles of end-to-end response latency
+type LatencyStats struct {
+	Samples int   `json:"samples"`
+	P50Ms   int64 `json:"p50_ms"`
+	P95Ms   int64 `json:"p95_ms"`
+	P99Ms   int64 `json:"p99_ms"`
+}
+
+// Status is the runtime snapshot the bridge writes to bridge-status.json
+type Status struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	// Window is the span latency percentiles cover
+	Window   string                  `json:"window"`
+	Overall  LatencyStats            `json:"overall"`
+	Backends map[string]LatencyStats `json:"backends"`
+	Chats    map[string]LatencyStats `json:"chats"`
+	// ActiveRuns lists requests in flight on every instance sharing state
+	ActiveRuns []store.Run `json:"active_runs,omitempty"`
+	// Feishu is the state of the Feishu API circuit breaker
+	Feishu *feishu.CircuitStatus `json:"feishu,omitempty"`
+	// Connection is the state of the Feishu long connection
+	Connection *feishu.ConnectionStatus `json:"connection,omitempty"`
+	// GatewayDown lists backends whose gateway circuit is open
+	GatewayDown []string `json:"gateway_down,omitempty"`
+	Uptime      Uptime   `json:"uptime"`
+}
+
+// Uptime reports how long this instance has been up and what it has done
+// since it started
+type Uptime struct {
+	StartedAt time.Time `json:"started_at"`
+	Seconds   int64     `json:"seconds"`
+	// MessagesServed counts the messages answered by the agent
+	MessagesServed int64 `json:"messages_served"`
+	// GatewayErrors counts failed runs on gateway backends
+	GatewayErrors int64 `json:"gateway_errors"`
+}
+
+// ReadStatus loads the last snapshot written by a running bridge
+func ReadStatus(dir string) (*Status, error) {
+	data, err := os.ReadFile(filepath.Join(dir, statusFile))
+	if err != nil {
+		return nil, err
+	}
+	var st Status
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", statusFile, err)
+	}
+	return &st, nil
+}
+
+// latencyTracker keeps response latencies over a sliding window,
+// grouped by key (overall, per backend, per chat)
+type latencyTracker struct {
+	window  time.Duration
+	mu      sync.Mutex
+	samples map[string][]latencySample
+}
+
+type latencySample struct {
+	at time.Time
+	ms float64
+}
+
+func newLatencyTracker(window time.Duration) *latencyTracker {
+	return &latencyTracker{window: window, samples: make(map[string][]latencySample)}
+}
+
+// record adds one response latency under each key
+func (t *latencyTracker) record(d time.Duration, now time.Time, keys ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, k := range keys {
+		t.samples[k] = append(t.samples[k], latencySample{at: now, ms: float64(d.Milliseconds())})
+	}
+}
+
+// stats drops expired samples and returns percentiles for every key
+func (t *latencyTracker) stats(now time.Time) map[string]LatencyStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make(map[string]LatencyStats, len(t.samples))
+	for k, samples := range t.samples {
+		kept := samples[:0]
+		for _, s := range samples {
+			if now.Sub(s.at) < t.window {
+				kept = append(kept, s)
+			}
+		}
+		if len(kept) == 0 {
+			delete(t.samples, k)
+			continue
+		}
+		t.samples[k] = kept
+
+		values := make([]float64, len(kept))
+		for i, s := range kept {
+			values[i] = s.ms
+		}
+		sort.Float64s(values)
+		out[k] = LatencyStats{
+			Samples: len(values),
+			P50Ms:   int64(metrics.Percentile(values, 50)),
+			P95Ms:   int64(metrics.Percentile(values, 95)),
+			P99Ms:   int64(metrics.Percentile(values, 99)),
+		}
+	}
+	return out
+}
+
+// Latency keys
+const (
+	latencyOverall       = "overall"
+	latencyBackendPrefix = "backend:"
+	latencyChatPrefix    = "chat:"
+)
+
+// recordResponse tracks the end-to-end latency of one answered message
+func (b *Bridge) recordResponse(req *runRequest, d time.Duration) {
+	metrics.Timing("response_latency", d, "backend", req.backendName)
+	b.latency.record(d, time.Now(), latencyOverall, latencyBackendPrefix+req.backendName, latencyChatPrefix+req.chatID)
+}
+
+// sloLoop refreshes latency gauges and the status file every minute and
+// alerts when a backend misses the latency SLO for the sustain period
+func (b *Bridge) sloLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	breachSince := make(map[string]time.Time)
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+
+		status := b.Status(ctx, now)
+		for name, s := range status.Backends {
+			metrics.Set("response_latency_p50", float64(s.P50Ms), "backend", name)
+			metrics.Set("response_latency_p95", float64(s.P95Ms), "backend", name)
+			metrics.Set("response_latency_p99", float64(s.P99Ms), "backend", name)
+			b.checkSLO(name, s, now, breachSince)
+		}
+		metrics.Set("gateway_circuit_open", float64(len(status.GatewayDown)))
+		metrics.Set("uptime_seconds", float64(status.Uptime.Seconds))
+		if c := status.Connection; c != nil && !c.ConnectedAt.IsZero() {
+			metrics.Set("feishu_connection_uptime_seconds", now.Sub(c.ConnectedAt).Seconds())
+		}
+		b.writeStatus(status)
+	}
+}
+
+// Status returns the runtime snapshot as of now
+func (b *Bridge) Status(ctx context.Context, now time.Time) Status {
+	stats := b.latency.stats(now)
+	status := Status{
+		UpdatedAt: now,
+		Window:    b.cfg.SLO.Window.String(),
+		Overall:   stats[latencyOverall],
+		Backends:  make(map[string]LatencyStats),
+		Chats:     make(map[string]LatencyStats),
+		Uptime: Uptime{
+			StartedAt:      b.started,
+			Seconds:        int64(now.Sub(b.started).Seconds()),
+			MessagesServed: b.served.Load(),
+			GatewayErrors:  b.gatewayErrors.Load(),
+		},
+	}
+	for key, s := range stats {
+		if name, ok := strings.CutPrefix(key, latencyBackendPrefix); ok {
+			status.Backends[name] = s
+		} else if chatID, ok := strings.CutPrefix(key, latencyChatPrefix); ok {
+			status.Chats[chatID] = s
+		}
+	}
+	runs, err := b.shared.Runs(ctx)
+	if err != nil {
+		log.Printf("[Bridge] Failed to list active runs: %v", err)
+	}
+	status.ActiveRuns = runs
+	if b.feishuClient != nil {
+		circuit := b.feishuClient.Circuit()
+		status.Feishu = &circuit
+	}
+	if w, ok := b.feishuClient.(connectionWatcher); ok {
+		conn := w.Connection()
+		status.Connection = &conn
+	}
+	status.GatewayDown = b.router.OpenCircuits()
+	return status
+}
+
+// checkSLO alerts once a backend's latency percentile has exceeded the
+// objective continuously for the sustain period
+func (b *Bridge) checkSLO(backendName string, s LatencyStats, now time.Time, breachSince map[string]time.Time) {
+	slo := b.cfg.SLO
+	if slo.LatencyMs <= 0 {
+		return
+	}
+
+	var observed int64
+	switch {
+	case slo.Percentile >= 99:
+		observed = s.P99Ms
+	case slo.Percentile >= 95:
+		observed = s.P95Ms
+	default:
+		observed = s.P50Ms
+	}
+	if s.Samples < slo.MinSamples || observed <= int64(slo.LatencyMs) {
+		delete(breachSince, backendName)
+		return
+	}
+
+	since, ok := breachSince[backendName]
+	if !ok {
+		breachSince[backendName] = now
+		return
+	}
+	if now.Sub(since) >= slo.Sustain {
+		go b.Alert("slo_"+backendName, fmt.Sprintf("后端 %s 响应耗时 P%.0f 为 %.1fs，已连续 %s 超过目标 %.1fs",
+			backendName, slo.Percentile, float64(observed)/1000, now.Sub(since).Round(time.Minute), float64(slo.LatencyMs)/1000))
+	}
+}
+
+// writeStatus atomically replaces the status file
+func (b *Bridge) writeStatus(st Status) {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		log.Printf("[Bridge] Failed to encode status: %v", err)
+		return
+	}
+	path := filepath.Join(b.cfg.Dir, statusFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		log.Printf("[Bridge] Failed to write status: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		log.Printf("[Bridge] Failed to write status: %v", err)
+	}
+}
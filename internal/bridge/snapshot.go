@@ -0,0 +1,133 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/backend"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+)
+
+// chatSessionBucket stores session keys restored onto chats whose
+// configured scheme would give them a different one
+const chatSessionBucket = "chat_session"
+
+// snapshotVersion is the SessionSnapshot format written by Snapshot
+const snapshotVersion = 1
+
+// SessionSnapshot is a chat's session state: the session key its history
+// lives under, its backend and agent selection, its pinned context, and
+// what the serving backend keeps in the bridge for it (model override,
+// history)
+type SessionSnapshot struct {
+	Version    int       `json:"version"`
+	ChatID     string    `json:"chat_id"`
+	TakenAt    time.Time `json:"taken_at"`
+	SessionKey string    `json:"session_key"`
+	// Backend is the chat's runtime backend override; empty follows the
+	// configured route
+	Backend string `json:"backend,omitempty"`
+	// ServedBy is the backend that was answering the chat, whose state
+	// Session holds
+	ServedBy string `json:"served_by"`
+	// AgentName and AgentID are the default agent chosen with /agents
+	AgentName string `json:"agent_name,omitempty"`
+	AgentID   string `json:"agent_id,omitempty"`
+	// PinnedContext is the context set with /context
+	PinnedContext string               `json:"pinned_context,omitempty"`
+	Session       backend.SessionState `json:"session"`
+}
+
+// Snapshot captures chatID's session state
+func (b *Bridge) Snapshot(ctx context.Context, chatID, actor string) (*SessionSnapshot, error) {
+	if chatID == "" {
+		return nil, fmt.Errorf("chat_id is required")
+	}
+	snap := &SessionSnapshot{
+		Version:    snapshotVersion,
+		ChatID:     chatID,
+		TakenAt:    time.Now().UTC(),
+		SessionKey: b.sessionKeyFor(chatID),
+		Backend:    b.router.Override(chatID),
+		ServedBy:   b.router.ChatBackend(chatID),
+	}
+	if sel, ok := b.chatAgent(ctx, chatID); ok {
+		snap.AgentName, snap.AgentID = sel.Name, sel.AgentID
+	}
+	if pinned, ok := b.pinnedContext(chatID); ok {
+		snap.PinnedContext = pinned.Text
+	}
+	if agent, ok := b.router.Backend(snap.ServedBy); ok {
+		if s, ok := agent.(backend.SessionSnapshotter); ok {
+			snap.Session = s.SessionState(snap.SessionKey)
+		}
+	}
+
+	logging.Printf(ctx, "[Bridge] %s took a snapshot of %s (session %s)", actor, chatID, snap.SessionKey)
+	b.audit.Record(ctx, audit.Event{Action: "session.snapshot", Actor: actor, ChatID: chatID, Target: snap.SessionKey})
+	return snap, nil
+}
+
+// Restore applies snap to chatID, or to the chat it was taken from when
+// chatID is empty, and returns the chat's session key. Nothing changes if
+// the snapshot names a backend or agent this bridge doesn't have.
+func (b *Bridge) Restore(ctx context.Context, snap *SessionSnapshot, chatID, actor string) (string, error) {
+	if snap.Version != snapshotVersion {
+		return "", fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	if chatID == "" {
+		chatID = snap.ChatID
+	}
+	if chatID == "" || snap.SessionKey == "" {
+		return "", fmt.Errorf("the snapshot has no chat_id or session_key")
+	}
+	if snap.Backend != "" {
+		if _, ok := b.router.Backend(snap.Backend); !ok {
+			return "", fmt.Errorf("unknown backend: %s", snap.Backend)
+		}
+	}
+	if snap.AgentName != "" {
+		if _, ok := b.cfg.Agents[snap.AgentName]; !ok {
+			return "", fmt.Errorf("unknown agent: %s", snap.AgentName)
+		}
+	}
+
+	if snap.SessionKey == b.defaultSessionKey(chatID) {
+		if err := b.store.Delete(chatSessionBucket, chatID); err != nil {
+			return "", fmt.Errorf("failed to clear session key: %w", err)
+		}
+	} else if err := b.store.Set(chatSessionBucket, chatID, snap.SessionKey); err != nil {
+		return "", fmt.Errorf("failed to save session key: %w", err)
+	}
+	if err := b.router.SetOverride(chatID, snap.Backend); err != nil {
+		return "", fmt.Errorf("failed to restore backend: %w", err)
+	}
+	if snap.AgentName != "" || snap.AgentID != "" {
+		if err := b.store.Set(chatAgentBucket, chatID, chatAgent{Name: snap.AgentName, AgentID: snap.AgentID}); err != nil {
+			return "", fmt.Errorf("failed to restore agent: %w", err)
+		}
+	} else if err := b.store.Delete(chatAgentBucket, chatID); err != nil {
+		return "", fmt.Errorf("failed to clear agent: %w", err)
+	}
+	if snap.PinnedContext != "" {
+		pinned := pinnedContext{Text: snap.PinnedContext, SetBy: actor, SetAt: time.Now().UTC()}
+		if err := b.store.Set(chatContextBucket, chatID, pinned); err != nil {
+			return "", fmt.Errorf("failed to restore pinned context: %w", err)
+		}
+	} else if err := b.store.Delete(chatContextBucket, chatID); err != nil {
+		return "", fmt.Errorf("failed to clear pinned context: %w", err)
+	}
+	// The state belongs to the backend it was taken from
+	if agent, ok := b.router.Backend(snap.ServedBy); ok {
+		if s, ok := agent.(backend.SessionSnapshotter); ok {
+			s.RestoreSession(snap.SessionKey, snap.Session)
+		}
+	}
+
+	logging.Printf(ctx, "[Bridge] %s restored %s onto %s (session %s)", actor, snap.ChatID, chatID, snap.SessionKey)
+	b.audit.Record(ctx, audit.Event{Action: "session.restore", Actor: actor, ChatID: chatID, Target: snap.SessionKey,
+		Detail: fmt.Sprintf("snapshot of %s taken %s", snap.ChatID, snap.TakenAt.Format(time.RFC3339))})
+	return snap.SessionKey, nil
+}
@@ -0,0 +1,117 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/pkg/clawdbot"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+const (
+	// startupCheckInterval is how often startupCardLoop checks health
+	startupCheckInterval = 15 * time.Second
+	// minOutage is how long the bridge must have been unhealthy before
+	// its recovery is announced, so a single failed check stays quiet
+	minOutage = time.Minute
+)
+
+// startupCardLoop posts a status card to the alert chat once the bridge
+// is first healthy, confirming a deploy, and again each time it recovers
+// from an outage
+func (b *Bridge) startupCardLoop(ctx context.Context) {
+	ticker := time.NewTicker(startupCheckInterval)
+	defer ticker.Stop()
+
+	announced := false
+	var failingSince time.Time
+	var failure error
+	for {
+		now := time.Now()
+		if err := b.healthy(); err != nil {
+			if failingSince.IsZero() {
+				failingSince, failure = now, err
+			}
+		} else if !announced {
+			if b.postStatusCard(b.startupCard(now)) {
+				announced = true
+				failingSince = time.Time{}
+			}
+		} else if !failingSince.IsZero() {
+			if now.Sub(failingSince) < minOutage || b.postStatusCard(b.recoveryCard(now, failingSince, failure)) {
+				failingSince = time.Time{}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// postStatusCard sends card to the alert chat and reports whether it went out
+func (b *Bridge) postStatusCard(card *feishu.Card) bool {
+	if _, err := b.feishuClient.SendCard(b.cfg.Alerts.ChatID, card); err != nil {
+		log.Printf("[Bridge] Failed to post status card: %v", err)
+		return false
+	}
+	log.Printf("[Bridge] Posted status card to %s", b.cfg.Alerts.ChatID)
+	return true
+}
+
+// startupCard confirms the bridge is up and what it is connected to
+func (b *Bridge) startupCard(now time.Time) *feishu.Card {
+	return b.statusCard("桥接服务已启动").
+		AddNote(fmt.Sprintf("%s 就绪，启动耗时 %s", now.Format("2006-01-02 15:04:05"), now.Sub(b.started).Round(time.Second)))
+}
+
+// recoveryCard reports the bridge healthy again after an outage that
+// began at since with failure
+func (b *Bridge) recoveryCard(now, since time.Time, failure error) *feishu.Card {
+	return b.statusCard("桥接服务已恢复").
+		AddNote(fmt.Sprintf("%s 起异常，%s 恢复（约 %s）：%v", since.Format("2006-01-02 15:04:05"), now.Format("15:04:05"), now.Sub(since).Round(time.Second), failure))
+}
+
+// statusCard lists the build, Feishu app and backends of the bridge
+func (b *Bridge) statusCard(title string) *feishu.Card {
+	version := b.version
+	if version == "" {
+		version = "dev"
+	}
+	lines := []string{
+		fmt.Sprintf("**版本**：%s", version),
+		fmt.Sprintf("**实例**：%s", b.instance),
+		fmt.Sprintf("**飞书应用**：%s", b.cfg.Feishu.AppID),
+	}
+	for _, name := range b.router.Names() {
+		lines = append(lines, fmt.Sprintf("**后端 %s**：%s", name, b.backendSummary(name)))
+	}
+	return feishu.NewCard(title, "green").
+		AddMarkdown(strings.Join(lines, "\n"))
+}
+
+// backendSummary describes what backend name talks to: the gateway
+// protocol, transport and agent for gateway backends, else type and model
+func (b *Bridge) backendSummary(name string) string {
+	bc := b.cfg.Backends[name]
+	if bc.Type != "clawdbot" {
+		if bc.Model == "" {
+			return bc.Type
+		}
+		return fmt.Sprintf("%s（%s）", bc.Type, bc.Model)
+	}
+	agentID := bc.AgentID
+	if agentID == "" {
+		agentID = b.cfg.Clawdbot.AgentID
+	}
+	transport := b.cfg.Clawdbot.Transport
+	if transport == "" {
+		transport = "websocket"
+	}
+	return fmt.Sprintf("Gateway 协议 v%d（%s），Agent %s", clawdbot.ProtocolVersion, transport, agentID)
+}
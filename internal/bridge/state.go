@@ -0,0 +1,69 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/store"
+)
+
+const (
+	// dedupeTTL is how long a message ID stays claimed; Feishu redelivers
+	// unacknowledged events within a few minutes
+	dedupeTTL = 10 * time.Minute
+	// runTTL drops runs left behind by an instance that died mid-request
+	runTTL = 15 * time.Minute
+)
+
+// instanceID names this process in shared run tracking
+func instanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+// trackRun registers req in the shared run list and returns the function
+// that removes it again
+func (b *Bridge) trackRun(ctx context.Context, req *runRequest) func() {
+	id := logging.CorrelationID(ctx)
+	run := store.Run{
+		ID:       id,
+		Instance: b.instance,
+		ChatID:   req.chatID,
+		Backend:  req.backendName,
+		Started:  req.received,
+	}
+	if err := b.shared.StartRun(ctx, run, runTTL); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to track run: %v", err)
+		return func() {}
+	}
+	return func() {
+		if err := b.shared.EndRun(ctx, id); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to untrack run: %v", err)
+		}
+	}
+}
+
+// Drain waits until every message accepted so far has been answered, or
+// ctx ends. Messages arriving meanwhile are still handled and waited for.
+func (b *Bridge) Drain(ctx context.Context) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		n := b.inflight.Load()
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d runs still in flight: %w", n, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
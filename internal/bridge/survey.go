@@ -0,0 +1,182 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/transcript"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// surveyBucket stores each chat's satisfaction survey state by chat ID
+const surveyBucket = "survey"
+
+// chatSurvey is a chat's pending and last sent satisfaction card
+type chatSurvey struct {
+	// UserID asked the last question; only they may answer in groups
+	UserID   string `json:"user_id"`
+	ChatType string `json:"chat_type"`
+	// Due is when the card is sent, zero once it has been
+	Due time.Time `json:"due,omitempty"`
+	// SentID identifies the card awaiting an answer, empty once answered
+	SentID string    `json:"sent_id,omitempty"`
+	Sent   time.Time `json:"sent,omitempty"`
+}
+
+// scheduleSurvey (re)starts the idle wait before req's chat gets a
+// survey card, unless the chat had one within the configured interval
+func (b *Bridge) scheduleSurvey(ctx context.Context, req *runRequest) {
+	b.surveyMu.Lock()
+	defer b.surveyMu.Unlock()
+
+	var s chatSurvey
+	if _, err := b.store.Get(surveyBucket, req.chatID, &s); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to read survey state of %s: %v", req.chatID, err)
+		return
+	}
+	now := time.Now()
+	if !s.Sent.IsZero() && now.Sub(s.Sent) < b.cfg.Survey.Interval {
+		return
+	}
+	s.UserID, s.ChatType, s.Due = req.senderID, req.chatType, now.Add(b.cfg.Survey.Idle)
+	if err := b.store.Set(surveyBucket, req.chatID, s); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to schedule survey in %s: %v", req.chatID, err)
+	}
+}
+
+// surveyLoop sends the survey cards that fall due
+func (b *Bridge) surveyLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		b.sendDueSurveys(ctx)
+	}
+}
+
+// sendDueSurveys sends a card to each chat whose conversation has been
+// idle long enough. Chats with a run in flight wait for its reply, which
+// restarts the wait.
+func (b *Bridge) sendDueSurveys(ctx context.Context) {
+	busy := make(map[string]bool)
+	runs, err := b.shared.Runs(ctx)
+	if err != nil {
+		log.Printf("[Bridge] Failed to list runs for surveys: %v", err)
+		return
+	}
+	for _, r := range runs {
+		busy[r.ChatID] = true
+	}
+
+	now := time.Now()
+	for _, chatID := range b.store.Keys(surveyBucket) {
+		b.surveyMu.Lock()
+		var s chatSurvey
+		ok, err := b.store.Get(surveyBucket, chatID, &s)
+		if err != nil || !ok || s.Due.IsZero() || now.Before(s.Due) || busy[chatID] {
+			b.surveyMu.Unlock()
+			continue
+		}
+		// Another instance sharing state may be sending the same card
+		first, err := b.shared.Claim(ctx, fmt.Sprintf("survey:%s:%d", chatID, s.Due.Unix()), 24*time.Hour)
+		if err != nil || !first {
+			b.surveyMu.Unlock()
+			continue
+		}
+
+		s.Due, s.SentID, s.Sent = time.Time{}, uuid.NewString(), now
+		if _, err := b.feishuClient.SendCard(chatID, surveyCard(chatID, s)); err != nil {
+			log.Printf("[Bridge] Failed to send survey card to %s: %v", chatID, err)
+			s.SentID, s.Sent = "", time.Time{}
+		}
+		if err := b.store.Set(surveyBucket, chatID, s); err != nil {
+			log.Printf("[Bridge] Failed to save survey state of %s: %v", chatID, err)
+		}
+		b.surveyMu.Unlock()
+	}
+}
+
+// surveyCard asks whether the chat's last answers helped
+func surveyCard(chatID string, s chatSurvey) *feishu.Card {
+	question := "这次回答有帮助吗？"
+	if s.ChatType == "group" {
+		question = fmt.Sprintf("<at id=%s></at> %s", s.UserID, question)
+	}
+	value := func(helpful string) map[string]interface{} {
+		return map[string]interface{}{"action": "survey_answer", "chat": chatID, "id": s.SentID, "helpful": helpful}
+	}
+	return feishu.NewCard("满意度调查", "blue").
+		AddMarkdown(question).
+		AddButtons(
+			feishu.CardButton{Text: "👍 有帮助", Type: "primary", Value: value("yes")},
+			feishu.CardButton{Text: "👎 没帮助", Type: "default", Value: value("no")},
+		)
+}
+
+// actionSurveyAnswer records the answer to a survey card in the transcripts
+func actionSurveyAnswer(ctx context.Context, b *Bridge, action *feishu.CardAction) (string, error) {
+	chatID, id := actionString(action, "chat"), actionString(action, "id")
+	helpful := actionString(action, "helpful") == "yes"
+
+	b.surveyMu.Lock()
+	defer b.surveyMu.Unlock()
+	var s chatSurvey
+	ok, err := b.store.Get(surveyBucket, chatID, &s)
+	if err != nil {
+		return "", err
+	}
+	if !ok || s.SentID != id {
+		return "", fmt.Errorf("该调查已结束")
+	}
+	if s.ChatType == "group" && action.OperatorID != s.UserID {
+		return "", fmt.Errorf("只有提问者可以评价")
+	}
+	s.SentID = ""
+	if err := b.store.Set(surveyBucket, chatID, s); err != nil {
+		return "", err
+	}
+
+	b.transcripts.Append(transcript.Record{
+		Kind:     transcript.KindSurvey,
+		ChatID:   chatID,
+		ChatType: s.ChatType,
+		UserID:   action.OperatorID,
+		Helpful:  helpful,
+	})
+	logging.Printf(ctx, "[Bridge] Survey answer from %s in %s: helpful=%t", action.OperatorID, chatID, helpful)
+	b.qaFeedback(ctx, chatID, s.Sent, helpful)
+
+	if action.MessageID != "" {
+		card := feishu.NewCard("满意度调查", "grey").AddNote("感谢你的反馈")
+		if err := b.feishuClient.UpdateCard(action.MessageID, card); err != nil {
+			logging.Printf(ctx, "[Bridge] Failed to update survey card: %v", err)
+		}
+	}
+	return "感谢反馈", nil
+}
+
+// forgetSurveys drops the survey state of chats where userID asked last
+func (b *Bridge) forgetSurveys(userID string) error {
+	b.surveyMu.Lock()
+	defer b.surveyMu.Unlock()
+	for _, chatID := range b.store.Keys(surveyBucket) {
+		var s chatSurvey
+		if ok, err := b.store.Get(surveyBucket, chatID, &s); err != nil || !ok || s.UserID != userID {
+			continue
+		}
+		if err := b.store.Delete(surveyBucket, chatID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
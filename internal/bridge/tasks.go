@@ -0,0 +1,322 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// chatTaskBucket stores the open tasks created from chats, by task GUID,
+// so their completion can be posted back
+const chatTaskBucket = "chat_task"
+
+// maxTaskSummary bounds a task title, in characters
+const maxTaskSummary = 200
+
+// taskDirective matches [[task:create 标题|截止日期|说明]]; the due date
+// and description are optional
+var taskDirective = regexp.MustCompile(`\[\[task:create\s+([^\]\n]*?)\s*\]\]`)
+
+// taskCreator is implemented by Feishu clients that can create tasks
+type taskCreator interface {
+	CreateTask(input feishu.TaskInput) (*feishu.Task, error)
+}
+
+// trackedTask is a task created from a chat
+type trackedTask struct {
+	ChatID    string    `json:"chat_id"`
+	Summary   string    `json:"summary"`
+	URL       string    `json:"url,omitempty"`
+	Assignees []string  `json:"assignees"`
+	Due       time.Time `json:"due,omitempty"`
+	// Timed is set when Due is a time of day rather than a date
+	Timed     bool      `json:"timed,omitempty"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// taskInstructions tell the agent how to create tasks
+const taskInstructions = "如果用户要求创建待办、任务或提醒，可在回答末尾单独一行写 [[task:create 标题|截止日期|说明]]，" +
+	"截止日期可写 2006-01-02、明天、周五 等；要在某个时刻提醒时写上时间，如 明天 09:00（按用户所在时区），到时会提醒用户。" +
+	"截止日期和说明可省略；任务会分配给提问的用户。这些标记不会显示给用户。"
+
+const taskUsage = "/task [标题 @负责人 截止:日期] 创建飞书任务（不 @ 时分配给自己，日期可写 2006-01-02、10-20、今天、明天、后天、周五，可带时间如 明天09:00、明早9点，到时提醒），完成后会在本会话通知；/task 列出本会话未完成的任务"
+
+// cmdTask creates a Feishu task, or lists the chat's open ones
+func cmdTask(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if !b.cfg.Tasks.Enabled {
+		return "任务功能未启用"
+	}
+	if args == "" || args == "list" {
+		return b.describeTasks(msg.ChatID) + "\n\n" + taskUsage
+	}
+
+	var words []string
+	input := feishu.TaskInput{Assignees: taskAssignees(msg)}
+	for _, word := range strings.Fields(args) {
+		if rest, ok := cutDuePrefix(word); ok {
+			due, allDay, ok := parseWhen(rest, b.userNow(ctx, msg.SenderID))
+			if !ok {
+				return fmt.Sprintf("无法识别截止日期 %s\n\n%s", rest, taskUsage)
+			}
+			input.Due, input.AllDay, input.Remind = due, allDay, !allDay
+			continue
+		}
+		words = append(words, word)
+	}
+	input.Summary = strings.Join(words, " ")
+	if input.Summary == "" {
+		return taskUsage
+	}
+	if len(input.Assignees) == 0 {
+		input.Assignees = []string{msg.SenderID}
+	}
+	input.Description = "由飞书机器人在会话中创建"
+	input.ClientToken = msg.MessageID
+	return b.createTask(ctx, msg.ChatID, msg.SenderID, input)
+}
+
+// taskAssignees returns the users @mentioned after the command in msg;
+// a mention before it addresses the bot
+func taskAssignees(msg *feishu.Message) []string {
+	start := strings.Index(msg.Content, "/")
+	var ids []string
+	for _, m := range msg.Mentions {
+		if m.ID == "" || m.Key == "" {
+			continue
+		}
+		if i := strings.Index(msg.Content, m.Key); i > start && start >= 0 {
+			ids = append(ids, m.ID)
+		}
+	}
+	return ids
+}
+
+// createTask creates input for actor in chatID, tracks it for its
+// completion update and returns the text reporting it
+func (b *Bridge) createTask(ctx context.Context, chatID, actor string, input feishu.TaskInput) string {
+	creator, ok := b.feishuClient.(taskCreator)
+	if !ok {
+		return "当前连接不支持创建任务"
+	}
+	if n := utf8.RuneCountInString(input.Summary); n > maxTaskSummary {
+		return fmt.Sprintf("任务标题过长（%d 字），最多 %d 字", n, maxTaskSummary)
+	}
+	task, err := creator.CreateTask(input)
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to create task for %s in %s: %v", actor, chatID, err)
+		return fmt.Sprintf("创建任务失败：%s", redact.Error(err))
+	}
+	tracked := trackedTask{
+		ChatID:    chatID,
+		Summary:   input.Summary,
+		URL:       task.URL,
+		Assignees: input.Assignees,
+		Due:       input.Due,
+		Timed:     !input.Due.IsZero() && !input.AllDay,
+		CreatedBy: actor,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := b.store.Set(chatTaskBucket, task.GUID, tracked); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to track task %s: %v", task.GUID, err)
+	}
+	logging.Printf(ctx, "[Bridge] %s created task %s in %s", actor, task.GUID, chatID)
+	b.audit.Record(ctx, audit.Event{Action: "task.create", Actor: actor, ChatID: chatID, Target: task.GUID, Detail: input.Summary})
+	return "已创建任务：" + describeTask(tracked)
+}
+
+// applyTasks creates the tasks the agent's reply to req asks for and
+// returns lines reporting them
+func (b *Bridge) applyTasks(ctx context.Context, req *runRequest, reply string) string {
+	if !b.cfg.Tasks.Enabled {
+		return ""
+	}
+	var notes []string
+	for i, m := range taskDirective.FindAllStringSubmatch(reply, -1) {
+		parts := strings.Split(m[1], "|")
+		input := feishu.TaskInput{
+			Summary:     strings.TrimSpace(parts[0]),
+			Assignees:   []string{req.senderID},
+			Description: "由 AI 根据会话内容创建",
+			ClientToken: fmt.Sprintf("%s:%s:%d", req.chatID, req.received.Format(time.RFC3339Nano), i),
+		}
+		if input.Summary == "" || req.senderID == "" {
+			continue
+		}
+		if len(parts) > 1 {
+			if due, allDay, ok := parseWhen(parts[1], b.userNow(ctx, req.senderID)); ok {
+				input.Due, input.AllDay, input.Remind = due, allDay, !allDay
+			}
+		}
+		if len(parts) > 2 {
+			input.Description = strings.TrimSpace(strings.Join(parts[2:], "|"))
+		}
+		notes = append(notes, b.createTask(ctx, req.chatID, req.senderID, input))
+	}
+	return strings.Join(notes, "\n")
+}
+
+// HandleTaskUpdate posts the completion of a task created from a chat
+// back into that chat
+func (b *Bridge) HandleTaskUpdate(ctx context.Context, update *feishu.TaskUpdate) {
+	if update.Kind != feishu.TaskCompleted && update.Kind != feishu.TaskDeleted {
+		return
+	}
+	var task trackedTask
+	ok, err := b.store.Get(chatTaskBucket, update.GUID, &task)
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to read task %s: %v", update.GUID, err)
+		return
+	}
+	if !ok {
+		return
+	}
+	// Only one instance posts the update
+	if first, err := b.shared.Claim(ctx, fmt.Sprintf("task:%s:%d", update.GUID, update.Kind), time.Hour); err != nil || !first {
+		return
+	}
+	if err := b.store.Delete(chatTaskBucket, update.GUID); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to untrack task %s: %v", update.GUID, err)
+	}
+	if update.Kind == feishu.TaskDeleted {
+		logging.Printf(ctx, "[Bridge] Task %s from %s was deleted", update.GUID, task.ChatID)
+		return
+	}
+
+	logging.Printf(ctx, "[Bridge] Task %s from %s completed", update.GUID, task.ChatID)
+	b.audit.Record(ctx, audit.Event{Action: "task.complete", ChatID: task.ChatID, Target: update.GUID, Detail: task.Summary})
+	text := "✅ 任务已完成：" + task.Summary
+	for _, id := range task.Assignees {
+		text += fmt.Sprintf(` <at user_id="%s"></at>`, id)
+	}
+	if _, err := b.feishuClient.SendMessage(task.ChatID, text); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to post completion of task %s: %v", update.GUID, err)
+	}
+}
+
+// chatTasks returns the open tasks created from chatID, oldest first
+func (b *Bridge) chatTasks(chatID string) []trackedTask {
+	var tasks []trackedTask
+	for _, guid := range b.store.Keys(chatTaskBucket) {
+		var task trackedTask
+		ok, err := b.store.Get(chatTaskBucket, guid, &task)
+		if err != nil {
+			log.Printf("[Bridge] Failed to read task %s: %v", guid, err)
+			continue
+		}
+		if ok && task.ChatID == chatID {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].CreatedAt.Before(tasks[j].CreatedAt) })
+	return tasks
+}
+
+// untrackChatTasks stops tracking the tasks created from chatID; the
+// tasks themselves stay in Feishu
+func (b *Bridge) untrackChatTasks(chatID string) error {
+	for _, guid := range b.store.Keys(chatTaskBucket) {
+		var task trackedTask
+		if ok, err := b.store.Get(chatTaskBucket, guid, &task); err != nil || !ok || task.ChatID != chatID {
+			continue
+		}
+		if err := b.store.Delete(chatTaskBucket, guid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describeTasks lists chatID's open tasks for /task
+func (b *Bridge) describeTasks(chatID string) string {
+	tasks := b.chatTasks(chatID)
+	if len(tasks) == 0 {
+		return "本会话没有未完成的任务"
+	}
+	lines := []string{fmt.Sprintf("本会话未完成的任务（%d 个）：", len(tasks))}
+	for _, task := range tasks {
+		lines = append(lines, "- "+describeTask(task))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// describeTask renders a task's title, due date and link
+func describeTask(t trackedTask) string {
+	text := t.Summary
+	// Due keeps the offset of the timezone it was given in
+	switch {
+	case t.Timed:
+		text += "，截止 " + t.Due.Format("2006-01-02 15:04")
+	case !t.Due.IsZero():
+		text += "，截止 " + t.Due.Format("2006-01-02")
+	}
+	if t.URL != "" {
+		text += "\n" + t.URL
+	}
+	return text
+}
+
+// cutDuePrefix reports whether word gives a due date, as 截止:日期,
+// 截止日期 or due:日期, and returns the date part
+func cutDuePrefix(word string) (string, bool) {
+	for _, prefix := range []string{"截止:", "截止：", "截止", "due:"} {
+		if rest, ok := strings.CutPrefix(word, prefix); ok && rest != "" {
+			return rest, true
+		}
+	}
+	return "", false
+}
+
+// weekdays maps the Chinese weekday names to time.Weekday
+var weekdays = map[string]time.Weekday{
+	"一": time.Monday, "二": time.Tuesday, "三": time.Wednesday, "四": time.Thursday,
+	"五": time.Friday, "六": time.Saturday, "日": time.Sunday, "天": time.Sunday,
+}
+
+// parseDue parses a due date relative to now: 2006-01-02, 01-02 (the next
+// one), 今天, 明天, 后天, or 周五 / 星期五 (the next one, today excluded)
+func parseDue(s string, now time.Time) (time.Time, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch s {
+	case "今天":
+		return today, true
+	case "明天":
+		return today.AddDate(0, 0, 1), true
+	case "后天":
+		return today.AddDate(0, 0, 2), true
+	}
+	for _, prefix := range []string{"周", "星期", "礼拜"} {
+		if name, ok := strings.CutPrefix(s, prefix); ok {
+			day, ok := weekdays[name]
+			if !ok {
+				return time.Time{}, false
+			}
+			ahead := (int(day) - int(today.Weekday()) + 7) % 7
+			if ahead == 0 {
+				ahead = 7
+			}
+			return today.AddDate(0, 0, ahead), true
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("1-2", s, now.Location()); err == nil {
+		due := time.Date(now.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location())
+		if due.Before(today) {
+			due = due.AddDate(1, 0, 0)
+		}
+		return due, true
+	}
+	return time.Time{}, false
+}
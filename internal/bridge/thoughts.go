@@ -0,0 +1,80 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// chatThoughtsBucket marks the chats that show the agent's reasoning in
+// the thinking placeholder
+const chatThoughtsBucket = "chat_thoughts"
+
+// maxThoughtChars bounds the reasoning shown in the placeholder; the
+// latest is kept
+const maxThoughtChars = 300
+
+const thoughtsUsage = "/thoughts [on|off] 查看或设置当前会话是否在\"思考中\"提示里显示 Agent 的思考过程（需后端输出思考过程）"
+
+// cmdThoughts shows or sets whether the chat sees the agent's reasoning
+func cmdThoughts(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	var err error
+	switch strings.ToLower(args) {
+	case "":
+		if b.showThoughts(msg.ChatID) {
+			return "当前会话显示思考过程\n\n" + thoughtsUsage
+		}
+		return "当前会话不显示思考过程\n\n" + thoughtsUsage
+	case "on", "开":
+		err = b.store.Set(chatThoughtsBucket, msg.ChatID, true)
+	case "off", "关":
+		err = b.store.Delete(chatThoughtsBucket, msg.ChatID)
+	default:
+		return thoughtsUsage
+	}
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to save thoughts setting for %s: %v", msg.ChatID, err)
+		return "设置失败，请稍后重试"
+	}
+	logging.Printf(ctx, "[Bridge] %s turned thoughts %s in %s", msg.SenderID, strings.ToLower(args), msg.ChatID)
+	if b.showThoughts(msg.ChatID) {
+		return "已开启：回复前的\"思考中\"提示会显示 Agent 的思考过程"
+	}
+	return "已关闭：不再显示思考过程"
+}
+
+// showThoughts reports whether chatID shows the agent's reasoning
+func (b *Bridge) showThoughts(chatID string) bool {
+	var show bool
+	ok, err := b.store.Get(chatThoughtsBucket, chatID, &show)
+	return err == nil && ok && show
+}
+
+// thoughtText returns the reasoning carried by a thought event, and
+// whether it replaces what came before instead of adding to it
+func thoughtText(data string) (text string, replace bool) {
+	var thought struct {
+		Text  string `json:"text,omitempty"`
+		Delta string `json:"delta,omitempty"`
+	}
+	if err := json.Unmarshal([]byte(data), &thought); err != nil {
+		return "", false
+	}
+	if thought.Text != "" {
+		return thought.Text, true
+	}
+	return thought.Delta, false
+}
+
+// trimThought keeps the last maxThoughtChars characters of text
+func trimThought(text string) string {
+	if utf8.RuneCountInString(text) <= maxThoughtChars {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[len(runes)-maxThoughtChars:])
+}
@@ -0,0 +1,166 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+)
+
+// profileTimezoneTTL is how long a timezone read from a Feishu profile is
+// reused before it's read again
+const profileTimezoneTTL = 24 * time.Hour
+
+// profileReader is implemented by Feishu clients that can read the
+// timezone in a user's profile
+type profileReader interface {
+	UserTimezone(openID string) (string, error)
+}
+
+// profileTimezone is a timezone read from a profile; name is empty when
+// the profile has none or couldn't be read
+type profileTimezone struct {
+	name    string
+	fetched time.Time
+}
+
+// userLocation returns userID's timezone and its name: the one set with
+// /pref tz, else the one in their Feishu profile. It's the server's, with
+// an empty name, when neither is known.
+func (b *Bridge) userLocation(ctx context.Context, userID string) (*time.Location, string) {
+	if userID == "" {
+		return time.Local, ""
+	}
+	name := ""
+	if prefs, ok := b.userPrefs(userID); ok {
+		name = prefs.Timezone
+	}
+	if name == "" {
+		name = b.profileTimezone(ctx, userID)
+	}
+	if name == "" || name == "Local" {
+		return time.Local, ""
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local, ""
+	}
+	return loc, name
+}
+
+// userNow returns the current time in userID's timezone
+func (b *Bridge) userNow(ctx context.Context, userID string) time.Time {
+	loc, _ := b.userLocation(ctx, userID)
+	return time.Now().In(loc)
+}
+
+// profileTimezone returns the timezone in userID's Feishu profile, read at
+// most once a day
+func (b *Bridge) profileTimezone(ctx context.Context, userID string) string {
+	reader, ok := b.feishuClient.(profileReader)
+	if !ok {
+		return ""
+	}
+	if v, ok := b.timezones.Load(userID); ok {
+		if cached := v.(profileTimezone); time.Since(cached.fetched) < profileTimezoneTTL {
+			return cached.name
+		}
+	}
+	name, err := reader.UserTimezone(userID)
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to read the timezone of %s: %v", userID, err)
+	}
+	b.timezones.Store(userID, profileTimezone{name: name, fetched: time.Now()})
+	return name
+}
+
+// timePrompt tells the agent userID's timezone and the time there, when
+// the timezone is known
+func (b *Bridge) timePrompt(ctx context.Context, userID string) string {
+	loc, name := b.userLocation(ctx, userID)
+	if name == "" {
+		return ""
+	}
+	now := time.Now().In(loc)
+	return fmt.Sprintf("用户所在时区为 %s，当前时间 %s 星期%s", name, now.Format("2006-01-02 15:04"), weekdayNames[now.Weekday()])
+}
+
+// weekdayNames are the Chinese names of the days of the week
+var weekdayNames = [...]string{"日", "一", "二", "三", "四", "五", "六"}
+
+// dueTime matches a due date ending in a time of day: 09:00, 9点, 9点半,
+// 9点15分, 下午3点, 九点; the date before it is optional
+var dueTime = regexp.MustCompile(`^(.*?)\s*(早上|上午|中午|下午|晚上)?\s*([0-9]{1,2}|[一二两三四五六七八九十]{1,3})(?::([0-9]{2})|点(半|[0-9]{1,2}分?)?)$`)
+
+// dueShorthands expand the shorthands for a day and part of it
+var dueShorthands = map[string]string{
+	"今早": "今天上午", "今晚": "今天晚上", "明早": "明天上午", "明晚": "明天晚上",
+}
+
+// chineseHours are the hours written in Chinese numerals
+var chineseHours = map[string]int{
+	"一": 1, "二": 2, "两": 2, "三": 3, "四": 4, "五": 5, "六": 6, "七": 7, "八": 8, "九": 9,
+	"十": 10, "十一": 11, "十二": 12,
+}
+
+// parseWhen parses a due date as parseDue does, optionally followed by a
+// time of day, e.g. 明天 09:00 or 明早九点; a time without a date is the
+// next one. allDay is false when a time was given.
+func parseWhen(s string, now time.Time) (due time.Time, allDay, ok bool) {
+	s = strings.TrimSpace(s)
+	for short, long := range dueShorthands {
+		if rest, found := strings.CutPrefix(s, short); found {
+			s = long + rest
+		}
+	}
+	m := dueTime.FindStringSubmatch(s)
+	if m == nil {
+		due, ok = parseDue(s, now)
+		return due, true, ok
+	}
+
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if m[1] != "" {
+		if day, ok = parseDue(m[1], now); !ok {
+			return time.Time{}, false, false
+		}
+	}
+	hour, err := strconv.Atoi(m[3])
+	if err != nil {
+		if hour, ok = chineseHours[m[3]]; !ok {
+			return time.Time{}, false, false
+		}
+	}
+	minute := 0
+	switch {
+	case m[4] != "":
+		minute, _ = strconv.Atoi(m[4])
+	case m[5] == "半":
+		minute = 30
+	case m[5] != "":
+		minute, _ = strconv.Atoi(strings.TrimSuffix(m[5], "分"))
+	}
+	switch m[2] {
+	case "下午", "晚上":
+		if hour < 12 {
+			hour += 12
+		}
+	case "中午":
+		if hour < 6 {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return time.Time{}, false, false
+	}
+
+	due = time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, now.Location())
+	if m[1] == "" && due.Before(now) {
+		due = due.AddDate(0, 0, 1)
+	}
+	return due, false, true
+}
@@ -0,0 +1,41 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/events"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/pkg/clawdbot"
+)
+
+// toolBlockedReply tells the chat a run was stopped by its tool policy
+func toolBlockedReply(tool string) string {
+	return fmt.Sprintf("根据本群的使用策略，AI 不能使用工具 %s，本次回答已中止。如需使用请联系管理员。", tool)
+}
+
+// withToolPolicy asks gateway backends to restrict the run to the chat's
+// tool policy. Other backends only see the tool calls, which the bridge
+// blocks as they stream in.
+func (b *Bridge) withToolPolicy(ctx context.Context, chatID string) context.Context {
+	policy, ok := b.cfg.ToolPolicyFor(chatID)
+	if !ok {
+		return ctx
+	}
+	return clawdbot.WithToolPolicy(ctx, clawdbot.ToolPolicy{Allow: policy.Allow, Deny: policy.Deny})
+}
+
+// toolPermitted reports whether chatID's tool policy lets the agent use tool
+func (b *Bridge) toolPermitted(chatID, tool string) bool {
+	policy, ok := b.cfg.ToolPolicyFor(chatID)
+	return !ok || policy.Permits(tool)
+}
+
+// recordToolBlocked logs, audits and emits a tool call stopped by policy
+func (b *Bridge) recordToolBlocked(ctx context.Context, req *runRequest, tool string) {
+	logging.Printf(ctx, "[Bridge] Blocked tool %s in %s by chat tool policy", tool, req.chatID)
+	b.audit.Record(ctx, audit.Event{Action: "tool.call", Actor: req.senderID, ChatID: req.chatID, Target: tool,
+		Outcome: audit.Denied, Detail: "not allowed by chat_tools"})
+	b.events.Emit(ctx, events.Event{Type: events.ToolBlocked, ChatID: req.chatID, Backend: req.backendName, Detail: tool})
+}
@@ -0,0 +1,141 @@
+package bridge
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// legacyShouldRespondInGroup is the per-keyword check triggerMatcher
+// replaced, kept to show both give the same answers
+func legacyShouldRespondInGroup(text string, mentions []feishu.Mention, words, verbs, names []string) bool {
+	if len(mentions) > 0 {
+		return true
+	}
+	lowerText := strings.ToLower(text)
+	if strings.HasSuffix(text, "?") || strings.HasSuffix(text, "？") {
+		return true
+	}
+	for _, word := range words {
+		if regexp.MustCompile(`\b` + word + `\b`).MatchString(lowerText) {
+			return true
+		}
+	}
+	for _, verb := range verbs {
+		if strings.Contains(text, verb) {
+			return true
+		}
+	}
+	for _, trigger := range names {
+		pattern := fmt.Sprintf(`^%s[\s,:，：]`, trigger)
+		if matched, _ := regexp.MatchString(pattern, lowerText); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func TestShouldRespondInGroup(t *testing.T) {
+	mention := []feishu.Mention{{Key: "@_user_1", ID: "ou_bot", Name: "bot"}}
+	tests := []struct {
+		name     string
+		text     string
+		mentions []feishu.Mention
+		want     bool
+	}{
+		{"mention", "随便聊聊", mention, true},
+		{"mention without text", "", mention, true},
+		{"question mark", "今天发版吗?", nil, true},
+		{"full-width question mark", "今天发版吗？", nil, true},
+		{"question mark inside", "a?b 好的", nil, false},
+		{"question word", "how does it work", nil, true},
+		{"question word any case", "HOW does it work", nil, true},
+		{"question word mid sentence", "not sure what happened", nil, true},
+		{"question word inside a word", "somehow it works", nil, false},
+		{"question word prefix", "whyever not", nil, false},
+		{"help", "need help.", nil, true},
+		{"action verb", "帮我看下日志", nil, true},
+		{"action verb mid sentence", "这个问题麻烦跟进一下", nil, true},
+		{"verb inside other text", "今天写完了", nil, true},
+		{"bot name with comma", "bot, 在吗", nil, true},
+		{"bot name any case", "ClawdBot: status", nil, true},
+		{"bot name full-width colon", "助手：今天天气", nil, true},
+		{"bot name not first", "the bot, again", nil, false},
+		{"bot name without separator", "bots are fun", nil, false},
+		{"bot name alone", "alen", nil, false},
+		{"chatter", "哈哈哈 好的 收到", nil, false},
+		{"english chatter", "lgtm, merged", nil, false},
+		{"empty", "", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := shouldRespondInGroup(tt.text, tt.mentions)
+			if got != tt.want {
+				t.Errorf("shouldRespondInGroup(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+			legacy := legacyShouldRespondInGroup(tt.text, tt.mentions, questionWords, actionVerbs, botTriggers)
+			if got != legacy {
+				t.Errorf("shouldRespondInGroup(%q) = %v, per-keyword check gives %v", tt.text, got, legacy)
+			}
+		})
+	}
+}
+
+// manyTriggers returns n keywords of each kind besides the built-in ones
+func manyTriggers(n int) (words, verbs, names []string) {
+	words = append([]string(nil), questionWords...)
+	verbs = append([]string(nil), actionVerbs...)
+	names = append([]string(nil), botTriggers...)
+	for i := 0; i < n; i++ {
+		words = append(words, fmt.Sprintf("keyword%d", i))
+		verbs = append(verbs, fmt.Sprintf("动作%d", i))
+		names = append(names, fmt.Sprintf("agent%d", i))
+	}
+	return words, verbs, names
+}
+
+// chattyInput is group chatter that matches no trigger, the common case
+var chattyInput = []string{
+	"哈哈哈 好的 收到，晚点再对一下",
+	"lgtm, merged into main and deployed to staging already",
+	"今天中午吃什么 楼下那家面馆还开着吗 要不点外卖",
+	"ok 👍 somehow the build went green after the rerun",
+	"周五下午的会议挪到三点了，记得更新日历",
+}
+
+func TestTriggerMatcherManyKeywords(t *testing.T) {
+	words, verbs, names := manyTriggers(50)
+	m := newTriggerMatcher(words, verbs, names)
+	texts := append([]string{"keyword7 is broken", "agent42, 在吗", "请 动作13", "akeyword7b"}, chattyInput...)
+	for _, text := range texts {
+		got := m.Match(strings.ToLower(text))
+		legacy := legacyShouldRespondInGroup(text, nil, words, verbs, names)
+		if got != legacy {
+			t.Errorf("Match(%q) = %v, per-keyword check gives %v", text, got, legacy)
+		}
+	}
+}
+
+func BenchmarkShouldRespondInGroup(b *testing.B) {
+	words, verbs, names := manyTriggers(50)
+	m := newTriggerMatcher(words, verbs, names)
+
+	b.Run("builtin", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			shouldRespondInGroup(chattyInput[i%len(chattyInput)], nil)
+		}
+	})
+	b.Run("many keywords", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			m.Match(strings.ToLower(chattyInput[i%len(chattyInput)]))
+		}
+	})
+	b.Run("many keywords per-keyword", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			legacyShouldRespondInGroup(chattyInput[i%len(chattyInput)], nil, words, verbs, names)
+		}
+	})
+}
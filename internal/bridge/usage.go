@@ -0,0 +1,68 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/analytics"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+const usageUsage = "/usage [today|week|month] [all] 查看当前会话的消息数、Token 和费用（all 为全局统计，需管理员权限）"
+
+// usagePeriods maps /usage periods to their label and start time
+var usagePeriods = map[string]struct {
+	label string
+	start func(now time.Time) time.Time
+}{
+	"today": {"今天", func(now time.Time) time.Time {
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	}},
+	"week": {"本周", func(now time.Time) time.Time {
+		offset := (int(now.Weekday()) + 6) % 7 // days since Monday
+		return time.Date(now.Year(), now.Month(), now.Day()-offset, 0, 0, 0, 0, now.Location())
+	}},
+	"month": {"本月", func(now time.Time) time.Time {
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}},
+}
+
+// cmdUsage reports message, token and cost totals for the chat or, for
+// admins, across all chats
+func cmdUsage(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if b.transcripts == nil {
+		return "未启用使用记录"
+	}
+
+	period, global := "today", false
+	for _, arg := range strings.Fields(args) {
+		switch {
+		case arg == "all":
+			global = true
+		case usagePeriods[arg].start != nil:
+			period = arg
+		default:
+			return usageUsage
+		}
+	}
+	if global && !b.cfg.IsAdmin(msg.SenderID) {
+		return "只有管理员可以查看全局用量"
+	}
+
+	p := usagePeriods[period]
+	now := time.Now()
+	chatID, scope := msg.ChatID, "当前会话"
+	if global {
+		chatID, scope = "", "全部会话"
+	}
+
+	summary, err := analytics.Usage(b.transcripts, p.start(now), now, chatID, b.cfg.Pricing)
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to compute usage: %v", err)
+		return "查询用量失败"
+	}
+	return fmt.Sprintf("%s%s用量：\n%s", scope, p.label, summary.Text())
+}
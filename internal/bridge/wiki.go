@@ -0,0 +1,171 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/knowledge"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// wikiReader is implemented by Feishu clients that can read wiki spaces
+type wikiReader interface {
+	WikiPages(spaceID string) ([]feishu.WikiPage, error)
+	DocumentText(token string) (string, error)
+}
+
+// wikiIndex is the current index of the configured wiki spaces
+type wikiIndex struct {
+	*knowledge.Index
+	pages int
+	built time.Time
+}
+
+// cachedPage is a page's text as of its last edit, kept between refreshes
+// so unchanged pages aren't read again
+type cachedPage struct {
+	edited time.Time
+	text   string
+}
+
+const wikiUsage = "/wiki 查看知识库索引状态；/wiki 关键词 搜索知识库中相关的段落"
+
+// cmdWiki reports the wiki index or searches it
+func cmdWiki(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if !b.cfg.Wiki.Enabled {
+		return "知识库检索未启用"
+	}
+	ix := b.wiki.Load()
+	if ix == nil {
+		return "知识库索引尚未建立，请稍后再试"
+	}
+	if args == "" {
+		return fmt.Sprintf("知识库：%d 个空间，%d 篇文档，%d 个段落，索引于 %s\n\n%s",
+			len(b.cfg.Wiki.Spaces), ix.pages, ix.Len(), ix.built.Local().Format("2006-01-02 15:04"), wikiUsage)
+	}
+	passages := ix.Search(args, b.cfg.Wiki.MaxPassages)
+	if len(passages) == 0 {
+		return "知识库中没有找到相关内容"
+	}
+	return formatPassages(passages)
+}
+
+// wikiLoop indexes the configured wiki spaces now and then at the
+// configured interval
+func (b *Bridge) wikiLoop(ctx context.Context) {
+	ticker := time.NewTicker(b.cfg.Wiki.Refresh)
+	defer ticker.Stop()
+
+	cache := make(map[string]cachedPage)
+	for {
+		b.indexWiki(cache)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// indexWiki reads the configured spaces' documents, reusing cache for
+// unchanged ones, and replaces the index. A space that fails to list
+// keeps its documents from cache.
+func (b *Bridge) indexWiki(cache map[string]cachedPage) {
+	reader, ok := b.feishuClient.(wikiReader)
+	if !ok {
+		return
+	}
+	start := time.Now()
+	var docs []knowledge.Document
+	used := make(map[string]bool)
+	failed := false
+	for _, space := range b.cfg.Wiki.Spaces {
+		pages, err := reader.WikiPages(space)
+		if err != nil {
+			log.Printf("[Bridge] Failed to list wiki space %s: %v", space, err)
+			failed = true
+			continue
+		}
+		for _, page := range pages {
+			if page.ObjType != "docx" || used[page.ObjToken] {
+				continue
+			}
+			used[page.ObjToken] = true
+			cached, ok := cache[page.ObjToken]
+			if !ok || !cached.edited.Equal(page.Edited) {
+				text, err := reader.DocumentText(page.ObjToken)
+				if err != nil {
+					log.Printf("[Bridge] Failed to read wiki page %s: %v", page.Title, err)
+					if !ok {
+						continue
+					}
+				} else {
+					cached = cachedPage{edited: page.Edited, text: text}
+					cache[page.ObjToken] = cached
+				}
+			}
+			docs = append(docs, knowledge.Document{Title: page.Title, URL: b.wikiURL(page), Text: cached.text})
+		}
+	}
+	// Forget pages that are gone, unless a space couldn't be listed
+	if !failed {
+		for token := range cache {
+			if !used[token] {
+				delete(cache, token)
+			}
+		}
+	}
+	if failed && len(docs) == 0 && b.wiki.Load() != nil {
+		return
+	}
+
+	ix := knowledge.Build(docs, b.cfg.Wiki.PassageChars)
+	b.wiki.Store(&wikiIndex{Index: ix, pages: len(docs), built: time.Now()})
+	log.Printf("[Bridge] Indexed %d wiki pages into %d passages in %s", len(docs), ix.Len(), time.Since(start).Round(time.Millisecond))
+}
+
+// wikiURL links to page, when the tenant's address is configured
+func (b *Bridge) wikiURL(page feishu.WikiPage) string {
+	if b.cfg.Wiki.BaseURL == "" {
+		return ""
+	}
+	return b.cfg.Wiki.BaseURL + "/wiki/" + page.NodeToken
+}
+
+// wikiPrompt gives the agent the wiki passages relevant to text and asks
+// it to cite them
+func (b *Bridge) wikiPrompt(text string) string {
+	if !b.cfg.Wiki.Enabled {
+		return ""
+	}
+	passages := b.wiki.Load().search(text, b.cfg.Wiki.MaxPassages)
+	if len(passages) == 0 {
+		return ""
+	}
+	return "以下是从公司知识库检索到的可能相关的内容。回答时优先依据这些资料，并注明引用的文档标题和链接；与问题无关的资料请忽略，不要编造资料中没有的内容。\n\n" +
+		formatPassages(passages)
+}
+
+// search is Search on a possibly not yet built index
+func (ix *wikiIndex) search(query string, k int) []knowledge.Passage {
+	if ix == nil {
+		return nil
+	}
+	return ix.Search(query, k)
+}
+
+// formatPassages renders passages with their title and link
+func formatPassages(passages []knowledge.Passage) string {
+	parts := make([]string, len(passages))
+	for i, p := range passages {
+		source := "《" + p.Title + "》"
+		if p.URL != "" {
+			source += " " + p.URL
+		}
+		parts[i] = fmt.Sprintf("[%d] %s\n%s", i+1, source, p.Text)
+	}
+	return strings.Join(parts, "\n\n")
+}
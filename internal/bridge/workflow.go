@@ -0,0 +1,409 @@
+package bridge
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/audit"
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// approvalInstanceBucket stores the pending approval instances submitted
+// from chats, by instance code, so their outcome can be posted back
+const approvalInstanceBucket = "approval_instance"
+
+// maxApprovalSummary bounds the summary of an instance's form, in
+// characters
+const maxApprovalSummary = 100
+
+// approvalDirective matches [[approval:submit 流程|字段=值|字段=值]]
+var approvalDirective = regexp.MustCompile(`\[\[approval:submit\s+([^\]\n]*?)\s*\]\]`)
+
+// approvalClient is implemented by Feishu clients that can submit and
+// query approval instances
+type approvalClient interface {
+	CreateApproval(input feishu.ApprovalInput) (*feishu.ApprovalInstance, error)
+	GetApproval(code string) (*feishu.ApprovalInstance, error)
+}
+
+// trackedApproval is an approval instance submitted from a chat
+type trackedApproval struct {
+	ChatID      string    `json:"chat_id"`
+	Flow        string    `json:"flow"`
+	Summary     string    `json:"summary"`
+	URL         string    `json:"url,omitempty"`
+	Applicant   string    `json:"applicant"`
+	Status      string    `json:"status"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// approvalStatusNames are the Chinese names of instance statuses
+var approvalStatusNames = map[string]string{
+	feishu.ApprovalPending:  "审批中",
+	feishu.ApprovalApproved: "已通过",
+	feishu.ApprovalRejected: "已拒绝",
+	feishu.ApprovalCanceled: "已撤回",
+	feishu.ApprovalDeleted:  "已删除",
+}
+
+const applyUsage = "/apply 列出可发起的审批流程；/apply 流程 字段=值|字段=值 以自己的名义发起审批（也可每行一个字段），结果会在本会话通知；/apply status 查看本会话发起的审批进度"
+
+// cmdApply submits an approval instance, or lists flows and the chat's
+// pending instances
+func cmdApply(ctx context.Context, b *Bridge, msg *feishu.Message, args string) string {
+	if !b.cfg.Approvals.Enabled {
+		return "审批功能未启用"
+	}
+	name, rest := cutSpace(args)
+	switch name {
+	case "", "list":
+		return describeFlows(b.cfg.Approvals) + "\n\n" + applyUsage
+	case "status":
+		b.checkApprovals(ctx)
+		return b.describeApprovals(msg.ChatID)
+	}
+	flow, ok := b.cfg.Approvals.Flow(name)
+	if !ok {
+		return fmt.Sprintf("没有名为 %s 的审批流程\n\n%s", name, describeFlows(b.cfg.Approvals))
+	}
+	fields, err := approvalFields(name, flow, rest)
+	if err != nil {
+		return err.Error()
+	}
+	input := feishu.ApprovalInput{Code: flow.Code, Applicant: msg.SenderID, Form: fields, UUID: approvalUUID(msg.MessageID)}
+	return b.submitApproval(ctx, msg.ChatID, name, flow, input)
+}
+
+// submitApproval submits input for chatID, tracks it for its outcome and
+// returns the text reporting it
+func (b *Bridge) submitApproval(ctx context.Context, chatID, name string, flow config.ApprovalFlow, input feishu.ApprovalInput) string {
+	client, ok := b.feishuClient.(approvalClient)
+	if !ok {
+		return "当前连接不支持发起审批"
+	}
+	instance, err := client.CreateApproval(input)
+	if err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to submit %s for %s in %s: %v", name, input.Applicant, chatID, err)
+		return fmt.Sprintf("发起审批失败：%s", redact.Error(err))
+	}
+	tracked := trackedApproval{
+		ChatID:      chatID,
+		Flow:        name,
+		Summary:     approvalSummary(flow, input.Form),
+		URL:         instance.URL,
+		Applicant:   input.Applicant,
+		Status:      instance.Status,
+		SubmittedAt: time.Now().UTC(),
+	}
+	if err := b.store.Set(approvalInstanceBucket, instance.Code, tracked); err != nil {
+		logging.Printf(ctx, "[Bridge] Failed to track approval %s: %v", instance.Code, err)
+	}
+	logging.Printf(ctx, "[Bridge] %s submitted approval %s (%s) in %s", input.Applicant, instance.Code, name, chatID)
+	b.audit.Record(ctx, audit.Event{Action: "approval.submit", Actor: input.Applicant, ChatID: chatID, Target: instance.Code, Detail: name})
+	return "已发起审批：" + describeApproval(tracked)
+}
+
+// applyApprovals submits the approval instances the agent's reply to req
+// asks for and returns lines reporting them
+func (b *Bridge) applyApprovals(ctx context.Context, req *runRequest, reply string) string {
+	if !b.cfg.Approvals.Enabled || req.senderID == "" {
+		return ""
+	}
+	var notes []string
+	for i, m := range approvalDirective.FindAllStringSubmatch(reply, -1) {
+		name, rest, _ := strings.Cut(m[1], "|")
+		name = strings.TrimSpace(name)
+		flow, ok := b.cfg.Approvals.Flow(name)
+		if !ok {
+			logging.Printf(ctx, "[Bridge] Ignoring approval directive for unknown flow %q in %s", name, req.chatID)
+			continue
+		}
+		fields, err := approvalFields(name, flow, rest)
+		if err != nil {
+			notes = append(notes, err.Error())
+			continue
+		}
+		input := feishu.ApprovalInput{
+			Code:      flow.Code,
+			Applicant: req.senderID,
+			Form:      fields,
+			UUID:      approvalUUID(fmt.Sprintf("%s:%s:%d", req.chatID, req.received.Format(time.RFC3339Nano), i)),
+		}
+		notes = append(notes, b.submitApproval(ctx, req.chatID, name, flow, input))
+	}
+	return strings.Join(notes, "\n")
+}
+
+// approvalLoop checks pending approval instances at the configured
+// interval and posts their outcome
+func (b *Bridge) approvalLoop(ctx context.Context) {
+	ticker := time.NewTicker(b.cfg.Approvals.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		b.checkApprovals(ctx)
+	}
+}
+
+// checkApprovals refreshes the tracked instances and posts the outcome of
+// those no longer pending back into their chat
+func (b *Bridge) checkApprovals(ctx context.Context) {
+	client, ok := b.feishuClient.(approvalClient)
+	if !ok {
+		return
+	}
+	for _, code := range b.store.Keys(approvalInstanceBucket) {
+		var tracked trackedApproval
+		if ok, err := b.store.Get(approvalInstanceBucket, code, &tracked); err != nil || !ok {
+			continue
+		}
+		instance, err := client.GetApproval(code)
+		if err != nil {
+			log.Printf("[Bridge] Failed to check approval %s: %v", code, err)
+			continue
+		}
+		if instance.Status == tracked.Status {
+			continue
+		}
+		if instance.Status == feishu.ApprovalPending {
+			tracked.Status = instance.Status
+			if err := b.store.Set(approvalInstanceBucket, code, tracked); err != nil {
+				log.Printf("[Bridge] Failed to update approval %s: %v", code, err)
+			}
+			continue
+		}
+
+		// Only one instance posts the outcome
+		if first, err := b.shared.Claim(ctx, fmt.Sprintf("approval:%s:%s", code, instance.Status), 24*time.Hour); err != nil || !first {
+			continue
+		}
+		if err := b.store.Delete(approvalInstanceBucket, code); err != nil {
+			log.Printf("[Bridge] Failed to untrack approval %s: %v", code, err)
+		}
+		tracked.Status = instance.Status
+		log.Printf("[Bridge] Approval %s from %s is %s", code, tracked.ChatID, instance.Status)
+		b.audit.Record(ctx, audit.Event{Action: "approval.decide", ChatID: tracked.ChatID, Target: code, Detail: instance.Status})
+		text := fmt.Sprintf("审批「%s」%s：%s", tracked.Flow, statusName(instance.Status), tracked.Summary)
+		if tracked.Applicant != "" {
+			text += fmt.Sprintf(` <at user_id="%s"></at>`, tracked.Applicant)
+		}
+		if _, err := b.feishuClient.SendMessage(tracked.ChatID, text); err != nil {
+			log.Printf("[Bridge] Failed to post outcome of approval %s: %v", code, err)
+		}
+	}
+}
+
+// chatApprovals returns the pending instances submitted from chatID,
+// oldest first
+func (b *Bridge) chatApprovals(chatID string) []trackedApproval {
+	var approvals []trackedApproval
+	for _, code := range b.store.Keys(approvalInstanceBucket) {
+		var tracked trackedApproval
+		ok, err := b.store.Get(approvalInstanceBucket, code, &tracked)
+		if err != nil {
+			log.Printf("[Bridge] Failed to read approval %s: %v", code, err)
+			continue
+		}
+		if ok && tracked.ChatID == chatID {
+			approvals = append(approvals, tracked)
+		}
+	}
+	sort.Slice(approvals, func(i, j int) bool { return approvals[i].SubmittedAt.Before(approvals[j].SubmittedAt) })
+	return approvals
+}
+
+// untrackChatApprovals stops tracking the instances submitted from
+// chatID; the instances themselves stay in Feishu
+func (b *Bridge) untrackChatApprovals(chatID string) error {
+	for _, code := range b.store.Keys(approvalInstanceBucket) {
+		var tracked trackedApproval
+		if ok, err := b.store.Get(approvalInstanceBucket, code, &tracked); err != nil || !ok || tracked.ChatID != chatID {
+			continue
+		}
+		if err := b.store.Delete(approvalInstanceBucket, code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// approvalsPrompt tells the agent which flows it may submit and how, and
+// what the chat has pending
+func (b *Bridge) approvalsPrompt(chatID string) string {
+	lines := []string{describeFlows(b.cfg.Approvals)}
+	if pending := b.chatApprovals(chatID); len(pending) > 0 {
+		lines = append(lines, "本会话审批中的申请：")
+		for _, a := range pending {
+			lines = append(lines, "- "+describeApproval(a))
+		}
+	}
+	lines = append(lines, "如果用户明确要求发起上述审批，可在回答末尾单独一行写 [[approval:submit 流程|字段=值|字段=值]]，"+
+		"审批以提问用户的名义发起，结果会通知到本会话；信息不全时先向用户确认，不要编造字段。这些标记不会显示给用户。")
+	return strings.Join(lines, "\n")
+}
+
+// describeApprovals lists chatID's pending instances for /apply status
+func (b *Bridge) describeApprovals(chatID string) string {
+	approvals := b.chatApprovals(chatID)
+	if len(approvals) == 0 {
+		return "本会话没有审批中的申请"
+	}
+	lines := []string{fmt.Sprintf("本会话审批中的申请（%d 个）：", len(approvals))}
+	for _, a := range approvals {
+		lines = append(lines, "- "+describeApproval(a))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// describeApproval renders an instance's flow, form summary, status and
+// link
+func describeApproval(a trackedApproval) string {
+	text := fmt.Sprintf("%s（%s）：%s", a.Flow, statusName(a.Status), a.Summary)
+	if a.URL != "" {
+		text += "\n" + a.URL
+	}
+	return text
+}
+
+// describeFlows lists the flows that may be submitted and their fields
+func describeFlows(cfg config.ApprovalsConfig) string {
+	names := make([]string, 0, len(cfg.Flows))
+	for name := range cfg.Flows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := []string{"可发起的审批流程："}
+	for _, name := range names {
+		flow := cfg.Flows[name]
+		line := "- " + name
+		if flow.Description != "" {
+			line += "：" + flow.Description
+		}
+		var fields []string
+		for _, f := range flow.Fields {
+			field := f.Name
+			if f.Required {
+				field += "（必填）"
+			}
+			fields = append(fields, field)
+		}
+		if len(fields) > 0 {
+			line += "；字段：" + strings.Join(fields, "、")
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// approvalFields parses "字段=值" pairs, separated by | or new lines,
+// into flow's form
+func approvalFields(name string, flow config.ApprovalFlow, text string) ([]feishu.FormField, error) {
+	values := make(map[string]string)
+	for _, part := range strings.FieldsFunc(text, func(r rune) bool { return r == '|' || r == '\n' }) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := cutField(part)
+		if !ok {
+			return nil, fmt.Errorf("无法识别 %q，请写成 字段=值\n\n%s", part, applyUsage)
+		}
+		field, ok := flowField(flow, key)
+		if !ok {
+			return nil, fmt.Errorf("审批流程 %s 没有字段 %s\n\n%s", name, key, describeFlows(config.ApprovalsConfig{Flows: map[string]config.ApprovalFlow{name: flow}}))
+		}
+		values[field.ID] = value
+	}
+
+	var form []feishu.FormField
+	for _, f := range flow.Fields {
+		value, ok := values[f.ID]
+		if !ok || value == "" {
+			if f.Required {
+				return nil, fmt.Errorf("缺少必填字段 %s", f.Name)
+			}
+			continue
+		}
+		if f.Type == "date" {
+			due, ok := parseDue(value, time.Now())
+			if !ok {
+				return nil, fmt.Errorf("无法识别字段 %s 的日期 %s", f.Name, value)
+			}
+			value = due.Format(time.RFC3339)
+		}
+		form = append(form, feishu.FormField{ID: f.ID, Type: f.Type, Value: value})
+	}
+	return form, nil
+}
+
+// cutField splits "字段=值" or "字段：值"
+func cutField(s string) (string, string, bool) {
+	for _, sep := range []string{"=", "：", ":"} {
+		if key, value, ok := strings.Cut(s, sep); ok {
+			return strings.TrimSpace(key), strings.TrimSpace(value), strings.TrimSpace(key) != ""
+		}
+	}
+	return "", "", false
+}
+
+// flowField finds flow's field by name or widget ID
+func flowField(flow config.ApprovalFlow, key string) (config.ApprovalField, bool) {
+	for _, f := range flow.Fields {
+		if f.Name == key || f.ID == key {
+			return f, true
+		}
+	}
+	return config.ApprovalField{}, false
+}
+
+// approvalSummary renders form as "字段：值" pairs, shortened
+func approvalSummary(flow config.ApprovalFlow, form []feishu.FormField) string {
+	var parts []string
+	for _, value := range form {
+		for _, f := range flow.Fields {
+			if f.ID != value.ID {
+				continue
+			}
+			text := value.Value
+			if t, err := time.Parse(time.RFC3339, text); f.Type == "date" && err == nil {
+				text = t.Format("2006-01-02")
+			}
+			parts = append(parts, f.Name+"："+text)
+		}
+	}
+	summary := strings.Join(parts, "，")
+	if utf8.RuneCountInString(summary) > maxApprovalSummary {
+		summary = string([]rune(summary)[:maxApprovalSummary]) + "…"
+	}
+	return summary
+}
+
+// statusName returns an instance status in Chinese
+func statusName(status string) string {
+	if name, ok := approvalStatusNames[status]; ok {
+		return name
+	}
+	return status
+}
+
+// approvalUUID derives the idempotency UUID Feishu expects from seed
+func approvalUUID(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	h := fmt.Sprintf("%x", sum[:16])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
@@ -0,0 +1,143 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transport is the shared-state plumbing partitioning needs
+type Transport interface {
+	// Heartbeat keeps instance in the member list for ttl
+	Heartbeat(ctx context.Context, instance string, ttl time.Duration) error
+	// Leave removes instance from the member list
+	Leave(ctx context.Context, instance string) error
+	// Members lists the live instances
+	Members(ctx context.Context) ([]string, error)
+	// Push appends payload to instance's inbox
+	Push(ctx context.Context, instance string, payload []byte) error
+	// Pop waits up to timeout for the next payload in instance's inbox;
+	// it returns nil without error when none arrived
+	Pop(ctx context.Context, instance string, timeout time.Duration) ([]byte, error)
+}
+
+// Node is this instance's membership in a partitioned deployment. Each
+// chat ID is owned by exactly one live member; messages for chats owned
+// elsewhere are forwarded to the owner's inbox, which it drains in order.
+type Node struct {
+	id        string
+	transport Transport
+	heartbeat time.Duration
+
+	mu      sync.RWMutex
+	ring    *Ring
+	members []string
+}
+
+// New returns a Node named id. It owns every chat until Run has loaded
+// the member list.
+func New(id string, t Transport, heartbeat time.Duration) *Node {
+	return &Node{
+		id:        id,
+		transport: t,
+		heartbeat: heartbeat,
+		ring:      NewRing([]string{id}),
+		members:   []string{id},
+	}
+}
+
+// ID returns this instance's member name
+func (n *Node) ID() string {
+	return n.id
+}
+
+// Owner returns the member that handles chatID
+func (n *Node) Owner(chatID string) string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.ring.Owner(chatID)
+}
+
+// Members returns the member list the ring was last built from
+func (n *Node) Members() []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return append([]string(nil), n.members...)
+}
+
+// Forward queues payload for owner
+func (n *Node) Forward(ctx context.Context, owner string, payload []byte) error {
+	if err := n.transport.Push(ctx, owner, payload); err != nil {
+		return fmt.Errorf("failed to forward to %s: %w", owner, err)
+	}
+	return nil
+}
+
+// Run heartbeats, refreshes the ring and passes forwarded payloads to
+// deliver, one at a time, until ctx is cancelled
+func (n *Node) Run(ctx context.Context, deliver func([]byte)) {
+	n.refresh(ctx)
+	go n.membershipLoop(ctx)
+
+	for ctx.Err() == nil {
+		payload, err := n.transport.Pop(ctx, n.id, n.heartbeat)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("[Cluster] Failed to read inbox: %v", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+		if payload != nil {
+			deliver(payload)
+		}
+	}
+
+	leaveCtx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := n.transport.Leave(leaveCtx, n.id); err != nil {
+		log.Printf("[Cluster] Failed to leave: %v", err)
+	}
+}
+
+func (n *Node) membershipLoop(ctx context.Context) {
+	ticker := time.NewTicker(n.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.refresh(ctx)
+		}
+	}
+}
+
+// refresh renews this member's heartbeat and rebuilds the ring if the
+// member list changed. Members expire after three missed heartbeats.
+func (n *Node) refresh(ctx context.Context) {
+	if err := n.transport.Heartbeat(ctx, n.id, 3*n.heartbeat); err != nil {
+		log.Printf("[Cluster] Heartbeat failed: %v", err)
+		return
+	}
+	members, err := n.transport.Members(ctx)
+	if err != nil {
+		log.Printf("[Cluster] Failed to list members: %v", err)
+		return
+	}
+	sort.Strings(members)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if strings.Join(members, ",") == strings.Join(n.members, ",") {
+		return
+	}
+	log.Printf("[Cluster] Members changed: %v -> %v", n.members, members)
+	n.members = members
+	n.ring = NewRing(members)
+}
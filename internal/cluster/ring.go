@@ -0,0 +1,53 @@
+package cluster
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// replicas is the number of virtual points each member gets on the ring;
+// more points spread chats more evenly across few members
+const replicas = 128
+
+// Ring assigns keys to members by consistent hashing, so adding or
+// removing one member only moves the keys that member owned
+type Ring struct {
+	points []uint32
+	owners map[uint32]string
+}
+
+// NewRing builds a ring over members
+func NewRing(members []string) *Ring {
+	r := &Ring{owners: make(map[uint32]string, len(members)*replicas)}
+	for _, m := range members {
+		for i := 0; i < replicas; i++ {
+			h := hash(m + "#" + strconv.Itoa(i))
+			r.points = append(r.points, h)
+			r.owners[h] = m
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+// Owner returns the member responsible for key, or "" for an empty ring
+func (r *Ring) Owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := hash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+// hash places a string on the ring. MD5 spreads similar names (bridge-1,
+// bridge-2) far better than CRC32; it isn't used for security.
+func hash(s string) uint32 {
+	sum := md5.Sum([]byte(s))
+	return binary.BigEndian.Uint32(sum[:4])
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Alias is a chat command defined in bridge.json
+type Alias struct {
+	// Text is sent in place of the alias: a prompt, or a bridge command
+	// when it starts with "/". {args} stands for whatever follows the
+	// alias; without it, that is appended.
+	Text        string
+	Description string
+}
+
+// Expand returns what "/alias args" stands for
+func (a Alias) Expand(args string) string {
+	if strings.Contains(a.Text, "{args}") {
+		return strings.TrimSpace(strings.ReplaceAll(a.Text, "{args}", args))
+	}
+	if args == "" {
+		return a.Text
+	}
+	return a.Text + " " + args
+}
+
+// aliasJSON is an entry of the "aliases" section: either the text alone
+// or {"text": ..., "description": ...}
+type aliasJSON struct {
+	Text        string `json:"text"`
+	Description string `json:"description,omitempty"`
+}
+
+// UnmarshalJSON accepts "text" or {"text":"...","description":"..."}
+func (a *aliasJSON) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		a.Text = text
+		return nil
+	}
+	type plain aliasJSON
+	return json.Unmarshal(data, (*plain)(a))
+}
+
+// buildAliases normalizes alias names to lowercase without the slash and
+// rejects ones that can't be typed as a command
+func buildAliases(in map[string]aliasJSON) (map[string]Alias, error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]Alias, len(in))
+	for name, a := range in {
+		key := strings.ToLower(strings.TrimPrefix(name, "/"))
+		if key == "" || strings.ContainsAny(key, " \t\n/") {
+			return nil, fmt.Errorf("aliases.%s: name must be a single word", name)
+		}
+		if strings.TrimSpace(a.Text) == "" {
+			return nil, fmt.Errorf("aliases.%s: text is required", name)
+		}
+		if _, dup := out[key]; dup {
+			return nil, fmt.Errorf("aliases.%s: defined more than once", name)
+		}
+		out[key] = Alias{Text: strings.TrimSpace(a.Text), Description: a.Description}
+	}
+	return out, nil
+}
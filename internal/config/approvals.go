@@ -0,0 +1,114 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// ApprovalsConfig lets /apply and the agent submit Feishu approval
+// instances and reports their outcome in the chat they came from
+type ApprovalsConfig struct {
+	Enabled bool
+	// Flows are the approval definitions that may be submitted, by the
+	// name used in chats
+	Flows map[string]ApprovalFlow
+	// PollInterval is how often pending instances are checked
+	PollInterval time.Duration
+}
+
+// ApprovalFlow is an approval definition that may be submitted
+type ApprovalFlow struct {
+	// Code is the definition's approval_code
+	Code string
+	// Description tells users and the agent what the flow is for
+	Description string
+	Fields      []ApprovalField
+}
+
+// ApprovalField is a widget of an approval form
+type ApprovalField struct {
+	// ID is the widget ID in the approval definition
+	ID string
+	// Name is what users and the agent call the field
+	Name string
+	// Type is input, textarea, number or date
+	Type     string
+	Required bool
+}
+
+// Flow returns the flow called name
+func (a ApprovalsConfig) Flow(name string) (ApprovalFlow, bool) {
+	flow, ok := a.Flows[name]
+	return flow, ok
+}
+
+// approvalFieldTypes are the widget types forms may use
+var approvalFieldTypes = map[string]bool{"input": true, "textarea": true, "number": true, "date": true}
+
+// approvalsJSON matches the "approvals" section of bridge.json
+type approvalsJSON struct {
+	Enabled             bool                        `json:"enabled"`
+	Flows               map[string]approvalFlowJSON `json:"flows,omitempty"`
+	PollIntervalSeconds int                         `json:"poll_interval_seconds,omitempty"`
+}
+
+type approvalFlowJSON struct {
+	Code        string `json:"code"`
+	Description string `json:"description,omitempty"`
+	Fields      []struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		Type     string `json:"type,omitempty"`
+		Required bool   `json:"required,omitempty"`
+	} `json:"fields"`
+}
+
+func (a approvalsJSON) toConfig() ApprovalsConfig {
+	cfg := ApprovalsConfig{
+		Enabled:      a.Enabled,
+		Flows:        make(map[string]ApprovalFlow, len(a.Flows)),
+		PollInterval: time.Duration(orDefault(a.PollIntervalSeconds, 60)) * time.Second,
+	}
+	for name, f := range a.Flows {
+		flow := ApprovalFlow{Code: f.Code, Description: f.Description}
+		for _, field := range f.Fields {
+			typ := field.Type
+			if typ == "" {
+				typ = "input"
+			}
+			flow.Fields = append(flow.Fields, ApprovalField{ID: field.ID, Name: field.Name, Type: typ, Required: field.Required})
+		}
+		cfg.Flows[name] = flow
+	}
+	return cfg
+}
+
+// validateApprovals checks that enabled approvals have usable flows
+func validateApprovals(cfg *Config) error {
+	a := cfg.Approvals
+	if !a.Enabled {
+		return nil
+	}
+	if len(a.Flows) == 0 {
+		return fmt.Errorf("approvals.flows must not be empty when approvals are enabled")
+	}
+	for name, flow := range a.Flows {
+		if flow.Code == "" {
+			return fmt.Errorf("approvals.flows.%s: code is required", name)
+		}
+		seen := make(map[string]bool)
+		for _, field := range flow.Fields {
+			if field.ID == "" || field.Name == "" {
+				return fmt.Errorf("approvals.flows.%s: every field needs an id and a name", name)
+			}
+			if seen[field.Name] {
+				return fmt.Errorf("approvals.flows.%s: duplicate field name %q", name, field.Name)
+			}
+			seen[field.Name] = true
+			if !approvalFieldTypes[field.Type] {
+				return fmt.Errorf("approvals.flows.%s: field %s has unsupported type %q (use input, textarea, number or date)", name, field.Name, field.Type)
+			}
+		}
+	}
+	return nil
+}
@@ -1,23 +1,757 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds all configuration for the bridge
 type Config struct {
 	Feishu   FeishuConfig
 	Clawdbot ClawdbotConfig
+	// Network tunes the connections to Feishu and the gateway
+	Network NetworkConfig
+	Backend BackendConfig
+	// Backends holds all named backends; the "backend" section is
+	// registered here as "default"
+	Backends map[string]BackendConfig
+	Routes   RoutesConfig
+	// Admins lists Feishu open_ids allowed to run admin commands
+	Admins []string
+	Shadow ShadowConfig
+	// Agents maps display names (used as "@名称" in chats) to agents
+	Agents map[string]AgentConfig
+	// ChatAgents limits which agents each chat may address;
+	// chats not listed can address every agent
+	ChatAgents map[string][]string
+	// ChatTools restricts which agent tools each chat may use;
+	// chats not listed may use every tool
+	ChatTools map[string]ToolPolicy
+	// Aliases maps custom command names, lowercase and without the slash,
+	// to what they expand to
+	Aliases       map[string]Alias
+	Observability ObservabilityConfig
+	Audit         AuditConfig
+	Alerts        AlertConfig
+	Transcripts   TranscriptConfig
+	Encryption    EncryptionConfig
+	Events        EventsConfig
+	DeadLetters   DeadLetterConfig
+	Redis         RedisConfig
+	Cluster       ClusterConfig
+	Failover      FailoverConfig
+	Streaming     StreamingConfig
+	LongReply     LongReplyConfig
+	MessageBreaks MessageBreaksConfig
+	Mermaid       MermaidConfig
+	Charts        ChartConfig
+	Math          MathConfig
+	LinkPreview   LinkPreviewConfig
+	Images        GeneratedImagesConfig
+	Admin         AdminConfig
+	// DrainTimeout bounds how long shutdown waits for in-flight runs
+	DrainTimeout time.Duration
+	Analytics    AnalyticsConfig
+	SLO          SLOConfig
+	Approval     ApprovalConfig
+	Invite       InviteConfig
+	TwoPerson    TwoPersonConfig
+	Shell        ShellConfig
+	Memory       MemoryConfig
+	Tasks        TasksConfig
+	Sheets       SheetsConfig
+	Approvals    ApprovalsConfig
+	Wiki         WikiConfig
+	Minutes      MinutesConfig
+	Polls        PollsConfig
+	Survey       SurveyConfig
+	Classify     ClassifyConfig
+	QA           QAConfig
+	Groups       GroupsConfig
+	Helpdesk     HelpdeskConfig
+	Queue        QueueConfig
+	// Pricing maps backend names to token prices for /usage cost estimates
+	Pricing map[string]Price
+	// Dir is the directory the config was loaded from
+	Dir string
+	// BridgePath is the bridge.json the config was loaded from
+	BridgePath string
+}
+
+// AlertConfig names where operational alerts go and when they fire
+type AlertConfig struct {
+	// ChatID is a Feishu chat the bot posts alerts to
+	ChatID string
+	// Webhook is a Feishu custom-bot webhook URL; it still works when the
+	// app itself can't authenticate
+	Webhook string
+	// Cooldown suppresses repeats of the same alert
+	Cooldown time.Duration
+	// MaxPerHour caps alerts across all kinds
+	MaxPerHour int
+	// ErrorRatePercent alerts when that share of runs in ErrorWindow fail,
+	// once at least MinSamples runs were seen
+	ErrorRatePercent float64
+	ErrorWindow      time.Duration
+	MinSamples       int
+	// QueueThreshold alerts when this many messages are in flight
+	QueueThreshold int
+	// HeartbeatURL is pinged every HeartbeatInterval while the bridge is
+	// healthy, so an external monitor (healthchecks.io and the like)
+	// alerts when the pings stop
+	HeartbeatURL      string
+	HeartbeatInterval time.Duration
+	// StartupCard posts a status card to ChatID once the bridge is up,
+	// and again when it recovers from an outage
+	StartupCard bool
+}
+
+// Enabled reports whether any alert destination is configured
+func (a AlertConfig) Enabled() bool {
+	return a.ChatID != "" || a.Webhook != ""
+}
+
+// SLOConfig defines the end-to-end response latency objective
+type SLOConfig struct {
+	// LatencyMs is the objective for the chosen percentile; 0 disables alerting
+	LatencyMs  int
+	Percentile float64
+	// Window is the span percentiles are computed over
+	Window time.Duration
+	// Sustain is how long the objective must be missed before alerting
+	Sustain time.Duration
+	// MinSamples avoids alerting on a handful of slow messages
+	MinSamples int
+}
+
+// Price is the cost of a backend's tokens, per million tokens
+type Price struct {
+	InputPerMTok  float64 `json:"input_per_mtok"`
+	OutputPerMTok float64 `json:"output_per_mtok"`
+}
+
+// TranscriptConfig controls the per-message transcript store
+type TranscriptConfig struct {
+	// Dir holds one YYYY-MM-DD.jsonl file per day
+	Dir string
+	// StoreContent keeps prompt and reply text; otherwise only metadata
+	StoreContent bool
+	// RetentionDays is how long daily files are kept; 0 keeps them forever
+	RetentionDays int
+}
+
+// EncryptionConfig controls encryption of stored conversations
+type EncryptionConfig struct {
+	// KeyringFile holds the AES keys; empty stores records unencrypted.
+	// Keep it outside the config directory.
+	KeyringFile string
+}
+
+// EventsConfig controls the structured event log
+type EventsConfig struct {
+	// Dir holds one YYYY-MM-DD.jsonl file per day
+	Dir string
+	// RetentionDays is how long daily files are kept; 0 keeps them forever
+	RetentionDays int
+}
+
+// AdminConfig enables the local admin API the CLI uses to reach a
+// running bridge, e.g. for simulate
+type AdminConfig struct {
+	// Listen is the host:port to serve on; empty disables the API
+	Listen string
+	// Token must be sent as "Authorization: Bearer <token>"
+	Token string
+}
+
+// RedisConfig points multiple bridge instances at shared state
+type RedisConfig struct {
+	// Addr is host:port of the Redis server; empty keeps state local
+	Addr     string
+	Password string
+	DB       int
+	// Prefix namespaces every key the bridge writes
+	Prefix string
+}
+
+// ClusterConfig partitions chats across bridge instances sharing Redis
+type ClusterConfig struct {
+	Enabled bool
+	// Instance names this member; keep it stable across restarts so the
+	// instance picks up messages queued for it while it was down
+	Instance  string
+	Heartbeat time.Duration
+}
+
+// FailoverConfig runs the bridge as one half of a primary/standby pair
+type FailoverConfig struct {
+	Enabled bool
+	// LockFile selects a file lock on shared storage; empty uses a Redis lease
+	LockFile string
+	// Lease is how long a dead primary keeps the lease before the standby
+	// takes over
+	Lease time.Duration
+}
+
+// StreamingConfig paces edits of a reply while it streams in
+type StreamingConfig struct {
+	// MinInterval is the fastest a single reply is edited
+	MinInterval time.Duration
+	// MaxInterval caps the slowdown under load or rate limiting
+	MaxInterval time.Duration
+	// UpdatesPerSecond is the edit budget shared by all streaming replies
+	UpdatesPerSecond float64
+	// ToolActivity follows each reply that used tools with a collapsed
+	// card listing the tool calls
+	ToolActivity bool
+}
+
+// LongReplyConfig sends replies too long to read in chat as a file
+type LongReplyConfig struct {
+	// MaxChars is the longest reply, in characters, sent as a message
+	MaxChars int
+	// SummaryChars is how much of a longer reply is shown in the chat
+	// next to the file
+	SummaryChars int
+}
+
+// MessageBreaksConfig lets the agent split a reply into several messages,
+// such as a short answer followed by the details
+type MessageBreaksConfig struct {
+	Enabled bool
+	// MaxMessages bounds the messages one reply becomes; parts past it
+	// stay in the last message
+	MaxMessages int
+}
+
+// MermaidConfig renders ```mermaid blocks in replies to images. Set
+// either Command or URL; with neither, diagrams stay text.
+type MermaidConfig struct {
+	// Command is a mermaid-cli compatible renderer, e.g. "mmdc", run
+	// with -i <input.mmd> -o <output.png> appended
+	Command string
+	// URL receives the diagram source in a POST body and returns the PNG,
+	// e.g. a Kroki server's /mermaid/png endpoint
+	URL string
+	// Timeout bounds rendering one diagram
+	Timeout time.Duration
+	// MaxDiagrams caps the images sent for one reply
+	MaxDiagrams int
+}
+
+// Enabled reports whether a renderer is configured
+func (m MermaidConfig) Enabled() bool {
+	return m.Command != "" || m.URL != ""
+}
+
+// ChartConfig renders simple chart specs and CSV data in messages to
+// images via Vega-Lite. Set either Command or URL; with neither, charts
+// aren't drawn.
+type ChartConfig struct {
+	// Command reads a Vega-Lite spec on stdin and writes the PNG to
+	// stdout, e.g. "vl2png" from vega-lite's CLI
+	Command string
+	// URL receives the spec in a POST body and returns the PNG, e.g. a
+	// Kroki server's /vegalite/png endpoint
+	URL string
+	// Timeout bounds rendering one chart
+	Timeout time.Duration
+	// MaxCharts caps the images sent for one reply
+	MaxCharts int
+}
+
+// Enabled reports whether a renderer is configured
+func (c ChartConfig) Enabled() bool {
+	return c.Command != "" || c.URL != ""
+}
+
+// MathConfig renders TeX formulas in replies to images. Set either
+// Command or URL; with neither, formulas stay TeX.
+type MathConfig struct {
+	// Command reads a formula's TeX on stdin and writes a PNG to stdout
+	Command string
+	// URL renders a formula to PNG. With a {tex} placeholder it's fetched
+	// with the URL-escaped TeX in its place; otherwise the TeX is POSTed.
+	URL string
+	// Timeout bounds rendering one formula
+	Timeout time.Duration
+	// MaxFormulas caps the formulas rendered for one reply
+	MaxFormulas int
+}
+
+// Enabled reports whether a renderer is configured
+func (m MathConfig) Enabled() bool {
+	return m.Command != "" || m.URL != ""
+}
+
+// LinkPreviewConfig adds a card previewing the pages linked in replies
+type LinkPreviewConfig struct {
+	Enabled bool
+	// Hosts limits previews to these domains and their subdomains, which
+	// may be on private networks. When empty, any public host is
+	// previewed and private addresses are refused.
+	Hosts []string
+	// MaxLinks caps the links previewed for one reply
+	MaxLinks int
+	// Timeout bounds fetching one page
+	Timeout time.Duration
+}
+
+// GeneratedImagesConfig sends images that agent tools produce, named by
+// URL or local path in tool results or the reply, as image messages
+type GeneratedImagesConfig struct {
+	Enabled bool
+	// Dirs are the directories local images may be read from, e.g. an
+	// image generator's output directory; other paths are ignored
+	Dirs []string
+	// Hosts limits image URLs to these domains and their subdomains,
+	// which may be on private networks. When empty, any public host is
+	// fetched and private addresses are refused.
+	Hosts []string
+	// MaxImages caps the images sent for one reply
+	MaxImages int
+	// Timeout bounds downloading one image
+	Timeout time.Duration
+}
+
+// AnalyticsConfig controls the weekly usage digest and the scheduled
+// usage reports
+type AnalyticsConfig struct {
+	// DigestChat receives the digest card; defaults to the alert chat.
+	// Empty disables the digest.
+	DigestChat    string
+	DigestWeekday time.Weekday
+	DigestHour    int
+	Reports       []ReportConfig
+}
+
+// ReportConfig is a scheduled usage report job
+type ReportConfig struct {
+	// Period is "daily", covering the day before, or "monthly", covering
+	// the month before and posted on the 1st
+	Period string
+	// Hour is the local hour the report is posted at
+	Hour int
+	// Chat receives the report card; defaults to the alert chat
+	Chat string
+	// CSV attaches the per-chat statistics as a CSV file
+	CSV bool
+}
+
+// ApprovalConfig makes new groups wait for an admin's approval before
+// the bot answers in them
+type ApprovalConfig struct {
+	// Enabled holds messages from unapproved groups
+	Enabled bool
+	// AdminChat receives the approval cards; defaults to the alert chat
+	AdminChat string
+	// Chats are groups approved up front, e.g. ones the bot served
+	// before approval was enabled
+	Chats []string
+}
+
+// Preapproved reports whether chatID is listed in Chats
+func (a ApprovalConfig) Preapproved(chatID string) bool {
+	for _, id := range a.Chats {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+// InviteConfig makes users redeem an invitation code before the bot
+// answers them in private chats
+type InviteConfig struct {
+	// Required turns the check on; admins are always exempt
+	Required bool
+}
+
+// MemoryConfig lets the agent keep facts per chat in the bridge, where
+// they survive session resets
+type MemoryConfig struct {
+	Enabled bool
+	// MaxEntries bounds the facts kept per chat
+	MaxEntries int
+}
+
+// TasksConfig lets /task and the agent create Feishu tasks
+type TasksConfig struct {
+	Enabled bool
+}
+
+// PollsConfig enables /poll voting cards
+type PollsConfig struct {
+	Enabled bool
+	// Summarize asks the chat's agent to summarize a poll's results when
+	// it closes
+	Summarize bool
+}
+
+// SurveyConfig sends a one-tap satisfaction card once a conversation
+// has gone quiet; answers are kept in the transcripts
+type SurveyConfig struct {
+	Enabled bool
+	// Idle is how long after the last reply the card is sent
+	Idle time.Duration
+	// Interval is the least time between two cards in one chat
+	Interval time.Duration
+}
+
+// ClassifyConfig tags each conversation with a category, classified from
+// its first message. Tags are kept in the transcripts for analytics and
+// can route conversations to agents.
+type ClassifyConfig struct {
+	Enabled bool
+	// Backend runs the classification; empty uses the chat's routed backend
+	Backend string
+	// Tags are the categories to choose from
+	Tags []string
+	// Routes maps tags to the agent (see Agents) that answers
+	// conversations with that tag, unless the message addresses an agent,
+	// the chat has chosen one or a command prefix routed it
+	Routes map[string]string
+	// Idle is the quiet time after which the next message starts a new
+	// conversation and is classified again
+	Idle time.Duration
+	// Timeout bounds one classification; the conversation goes untagged
+	// when it runs out
+	Timeout time.Duration
+}
+
+// QAConfig forwards a sample of answered messages to a review group, so
+// the bot's owners can follow its quality without reading every chat
+type QAConfig struct {
+	// ChatID receives the sampled cards; empty disables sampling
+	ChatID string
+	// Percent is the share of answers (0-100) forwarded
+	Percent float64
+	// IncludePrivate samples private chats too; off by default, as users
+	// there don't expect anyone else to read along
+	IncludePrivate bool
+}
+
+// GroupsConfig enables /group and the agent's markers for creating groups
+// and managing their members
+type GroupsConfig struct {
+	Enabled bool
+	// Teams maps names, such as "值班", to their members' open_ids so a
+	// whole team can be added at once
+	Teams map[string][]string
+}
+
+// HelpdeskConfig escalates conversations to Feishu Helpdesk (服务台)
+// tickets
+type HelpdeskConfig struct {
+	Enabled bool
+	// ID and Token authenticate as the helpdesk, as shown in its settings
+	ID    string
+	Token string
+	// Agents are the open_ids of the helpdesk agents tickets are
+	// assigned to; empty lets the helpdesk assign them
+	Agents []string
+	// EscalateAfter opens a ticket once a user's requests fail this many
+	// times in a row; 0 only escalates on request
+	EscalateAfter int
+}
+
+// QueueConfig limits how many runs go at once; messages past the limits
+// wait their turn
+type QueueConfig struct {
+	// MaxRuns bounds the runs across all chats; 0 is unlimited
+	MaxRuns int
+	// PerChat bounds the runs in one chat; 1 (default) answers a chat's
+	// messages one at a time, in order. 0 is unlimited.
+	PerChat int
+	// Overflow is what becomes of a message in a chat already at PerChat:
+	// "queue" (default) waits its turn, "reject" is turned away with a
+	// notice and "merge" joins the chat's waiting message, if any
+	Overflow string
+	// PriorityChats' messages go ahead of other waiting runs; the alert
+	// and approval admin chats are always included
+	PriorityChats []string
+	// PriorityPrefixes mark a message as high priority, e.g. "/urgent";
+	// the prefix is stripped before the message is forwarded
+	PriorityPrefixes []string
+	// MaxSkips bounds how many high-priority runs in a row may start
+	// while other runs wait, so those still make progress
+	MaxSkips int
+}
+
+// PriorityChat reports whether chatID's messages are high priority
+func (q QueueConfig) PriorityChat(chatID string) bool {
+	for _, id := range q.PriorityChats {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+// MinutesConfig passes the transcript of Feishu Minutes linked in a
+// message to the agent to summarize
+type MinutesConfig struct {
+	Enabled bool
+	// MaxChars bounds the transcript passed on, in characters
+	MaxChars int
+	// CreateTasks asks the agent to create Feishu tasks for the action
+	// items it finds; needs tasks enabled
+	CreateTasks bool
+}
+
+// WikiConfig attaches passages from Feishu wiki spaces that match a
+// message to its prompt
+type WikiConfig struct {
+	Enabled bool
+	// Spaces are the IDs of the wiki spaces to index
+	Spaces []string
+	// BaseURL is the tenant's Feishu address, e.g.
+	// https://example.feishu.cn, used to link cited pages
+	BaseURL string
+	// Refresh is how often the spaces are indexed again
+	Refresh time.Duration
+	// MaxPassages bounds the passages attached to one message
+	MaxPassages int
+	// PassageChars is the size passages are split to, in characters
+	PassageChars int
+}
+
+// TwoPersonActions are the admin actions that can require a second
+// admin's confirmation, named as in the audit log
+var TwoPersonActions = []string{"backend.switch", "session.link", "shell.exec", "group.remove", "announcement.set"}
+
+// TwoPersonConfig makes listed admin actions wait for a second admin to
+// confirm them
+type TwoPersonConfig struct {
+	// Actions lists the audit action names that need confirmation
+	Actions []string
+	// Window is how long a request waits for confirmation
+	Window time.Duration
+	// ChatID receives the confirmation cards; defaults to the alert
+	// chat, or the chat the action was requested in
+	ChatID string
+}
+
+// Requires reports whether action needs a second admin
+func (t TwoPersonConfig) Requires(action string) bool {
+	for _, a := range t.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditConfig controls the security audit log
+type AuditConfig struct {
+	// Dir holds one audit-YYYY-MM-DD.jsonl file per day
+	Dir string
+	// RetentionDays is how long daily files are kept; 0 keeps them forever
+	RetentionDays int
+}
+
+// ObservabilityConfig groups tracing and metrics export settings
+type ObservabilityConfig struct {
+	Tracing  TracingConfig
+	Sentry   SentryConfig
+	StatsD   StatsDConfig
+	InfluxDB InfluxDBConfig
+	// MetricPrefix is prepended to pushed metric names
+	MetricPrefix string
+	// PushInterval is how often metrics are pushed
+	PushInterval time.Duration
+}
+
+// StatsDConfig pushes metrics to a StatsD agent over UDP
+type StatsDConfig struct {
+	// Addr is host:port; empty disables StatsD
+	Addr string
+	// DogStatsD sends tags in the DogStatsD "|#k:v" extension
+	DogStatsD bool
+}
+
+// InfluxDBConfig pushes metrics to an InfluxDB write endpoint
+type InfluxDBConfig struct {
+	// URL is the full write URL; empty disables InfluxDB
+	URL   string
+	Token string
+}
+
+// SentryConfig controls error reporting to a Sentry-compatible service
+type SentryConfig struct {
+	// DSN is the project DSN; empty disables reporting
+	DSN         string
+	Environment string
+	// SampleRate is the fraction of error events sent (0-1)
+	SampleRate float64
+}
+
+// TracingConfig controls OpenTelemetry span export
+type TracingConfig struct {
+	// Endpoint is the OTLP/HTTP collector URL, e.g. http://127.0.0.1:4318;
+	// empty disables tracing
+	Endpoint    string
+	Headers     map[string]string
+	ServiceName string
+	// SampleRatio is the fraction of messages traced (0-1)
+	SampleRatio float64
+}
+
+// AgentConfig binds a display name to an agent on a named backend
+type AgentConfig struct {
+	// Backend is the backend name; empty uses the chat's routed backend
+	Backend string
+	// AgentID is the gateway agent to address
+	AgentID string
+	// Description is shown in agent listings
+	Description string
+}
+
+// ShadowConfig mirrors a sample of messages to a second backend for comparison.
+// Shadow replies are never delivered to users; results go to Dir.
+type ShadowConfig struct {
+	// Backend is the name of the candidate backend; empty disables shadowing
+	Backend string
+	// Percent is the share of messages (0-100) also sent to Backend
+	Percent float64
+	// Dir holds one YYYY-MM-DD.jsonl file of comparisons per day
+	Dir string
+	// RetentionDays is how long daily files are kept; 0 keeps them forever
+	RetentionDays int
+}
+
+// DeadLetterConfig controls how long undeliverable replies are kept
+type DeadLetterConfig struct {
+	// RetentionDays is how long letters are kept after their last
+	// failure; 0 keeps them until retried or dropped
+	RetentionDays int
 }
 
 // FeishuConfig contains Feishu-specific configuration
 type FeishuConfig struct {
-	AppID               string
-	AppSecret           string
+	AppID     string
+	AppSecret string
+	// AppSecretFile holds the secret instead of bridge.json, e.g. as
+	// written by a secret manager agent
+	AppSecretFile       string
 	ThinkingThresholdMs int
+	// SilenceTimeout reconnects the long connection when nothing, not
+	// even a heartbeat, arrives on it for this long; 0 disables it
+	SilenceTimeout time.Duration
+	// Proxy carries Feishu API calls: an http, https or socks5 URL,
+	// "direct" for none, or empty to follow HTTPS_PROXY and NO_PROXY
+	Proxy string
+	// ConnectionProxy overrides Proxy for the long connection
+	ConnectionProxy string
+}
+
+// Proxies returns the proxies for Feishu API calls and the long
+// connection, as for http.Transport.Proxy
+func (f FeishuConfig) Proxies() (api, conn func(*http.Request) (*url.URL, error)) {
+	api = proxyFunc(f.Proxy)
+	conn = api
+	if f.ConnectionProxy != "" {
+		conn = proxyFunc(f.ConnectionProxy)
+	}
+	return api, conn
+}
+
+// proxyFunc turns a proxy setting checked by validateProxy into a proxy
+// function
+func proxyFunc(setting string) func(*http.Request) (*url.URL, error) {
+	switch setting {
+	case "":
+		return http.ProxyFromEnvironment
+	case "direct":
+		return nil
+	}
+	u, _ := url.Parse(setting)
+	return http.ProxyURL(u)
+}
+
+// validateGatewayTLS checks the gateway's TLS files, so mistakes show at
+// startup rather than on the first run
+func validateGatewayTLS(g ClawdbotConfig) error {
+	if (g.TLSCertFile == "") != (g.TLSKeyFile == "") {
+		return fmt.Errorf("gateway.tls_cert_file and gateway.tls_key_file must be set together")
+	}
+	if g.TLSCertFile == "" && g.TLSCAFile == "" && g.TLSServerName == "" {
+		return nil
+	}
+	if !g.TLS && !g.GRPCTLS {
+		return fmt.Errorf("gateway.tls_* settings need gateway.tls, or gateway.grpc_tls with the grpc transport")
+	}
+	if g.TLSCertFile != "" {
+		if _, err := tls.LoadX509KeyPair(g.TLSCertFile, g.TLSKeyFile); err != nil {
+			return fmt.Errorf("failed to load gateway client certificate: %w", err)
+		}
+	}
+	_, err := g.TLSConfig()
+	return err
+}
+
+// loadNetwork checks the "network" section and fills in n's defaults
+func loadNetwork(n *NetworkConfig, raw networkJSON) error {
+	if secs := raw.KeepAliveSeconds; secs != nil {
+		if *secs < 0 {
+			return fmt.Errorf("network.keepalive_seconds must not be negative")
+		}
+		n.KeepAlive = -1
+		if *secs > 0 {
+			n.KeepAlive = time.Duration(*secs) * time.Second
+		}
+	}
+	switch n.IPVersion {
+	case "", "ipv4", "ipv6", "ipv4_only", "ipv6_only":
+	default:
+		return fmt.Errorf("network.ip_version must be ipv4, ipv6, ipv4_only or ipv6_only")
+	}
+	if n.SourceAddr != "" {
+		ip := net.ParseIP(n.SourceAddr)
+		if ip == nil {
+			return fmt.Errorf("network.source_addr must be an IP address")
+		}
+		if n.Interface != "" {
+			return fmt.Errorf("network.source_addr and network.interface can't be used together")
+		}
+		if v4 := ip.To4() != nil; (v4 && n.IPVersion == "ipv6_only") || (!v4 && n.IPVersion == "ipv4_only") {
+			return fmt.Errorf("network.source_addr doesn't match network.ip_version")
+		}
+	}
+	if n.DNS != "" {
+		if net.ParseIP(n.DNS) != nil {
+			n.DNS = net.JoinHostPort(n.DNS, "53")
+		} else if _, _, err := net.SplitHostPort(n.DNS); err != nil {
+			return fmt.Errorf("network.dns must be an IP address or host:port")
+		}
+	}
+	return nil
+}
+
+// validateProxy checks a proxy setting
+func validateProxy(key, setting string) error {
+	if setting == "" || setting == "direct" {
+		return nil
+	}
+	u, err := url.Parse(setting)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%s must be a proxy URL or \"direct\"", key)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return nil
+	}
+	return fmt.Errorf("%s: unsupported proxy scheme %q (expected http, https or socks5)", key, u.Scheme)
 }
 
 // ClawdbotConfig contains Clawdbot Gateway configuration
@@ -26,6 +760,551 @@ type ClawdbotConfig struct {
 	GatewayToken string
 	AgentID      string
 	SessionKey   string
+	// Transport is "websocket" (default) or "grpc"
+	Transport string
+	// GRPCAddr is the host:port of the gRPC gateway front
+	GRPCAddr string
+	// GRPCTLS enables TLS for the gRPC connection
+	GRPCTLS bool
+	// ConfigPath is the clawdbot.json/openclaw.json the settings came from
+	ConfigPath string
+	// TokenFromBridge is set when bridge.json overrides the gateway token
+	TokenFromBridge bool
+	// TokenFile holds the overriding token instead of bridge.json
+	TokenFile string
+	// RecordDir, when set, receives a recording of every gateway run for
+	// the "replay" backend
+	RecordDir string
+	// RecordRetentionDays is how long recordings are kept; 0 keeps them
+	// forever
+	RecordRetentionDays int
+	// Deliver asks the gateway to send replies through its own Feishu
+	// channel; the bridge then leaves successful replies to it
+	Deliver bool
+	// Host is where the WebSocket gateway listens, as seen from the
+	// proxy or SSH server when one is used; defaults to 127.0.0.1
+	Host string
+	// Proxy carries the WebSocket connection: an http, https or socks5
+	// URL, or empty to connect directly
+	Proxy string
+	// SSH tunnels the WebSocket connection through an SSH server
+	SSH SSHTunnelConfig
+	// TLS connects to the WebSocket gateway with wss
+	TLS bool
+	// TLSCertFile and TLSKeyFile hold the client certificate presented to
+	// the gateway over wss or gRPC with TLS; both are read on every
+	// handshake, so renewed certificates apply to the next connection
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSCAFile holds the CAs trusted for the gateway's certificate
+	// instead of the system's
+	TLSCAFile string
+	// TLSServerName overrides the name the gateway's certificate is
+	// checked against, e.g. when tunneling to 127.0.0.1
+	TLSServerName string
+}
+
+// SSHTunnelConfig describes the SSH server the gateway connection is
+// tunneled through; Addr is empty when no tunnel is used
+type SSHTunnelConfig struct {
+	// Addr is the host:port of the SSH server
+	Addr string
+	User string
+	// KeyFile is the unencrypted private key to log in with
+	KeyFile string
+	// KnownHosts lists the accepted host keys; defaults to
+	// ~/.ssh/known_hosts
+	KnownHosts string
+}
+
+// NetworkConfig holds low-level dial settings for constrained networks;
+// the zero value leaves everything to the system
+type NetworkConfig struct {
+	// IPVersion is "ipv4" or "ipv6" to try that version's addresses
+	// first, or "ipv4_only" or "ipv6_only" to use no others
+	IPVersion string
+	// SourceAddr is the local IP connections are made from
+	SourceAddr string
+	// Interface is the network interface whose address connections are
+	// made from, looked up at dial time
+	Interface string
+	// DNS is the host:port of the DNS server names are resolved with
+	DNS string
+	// KeepAlive is the TCP keepalive interval; 0 keeps Go's default and
+	// a negative value turns keepalives off
+	KeepAlive time.Duration
+}
+
+// WebSocketAddr returns the gateway's WebSocket host:port
+func (c ClawdbotConfig) WebSocketAddr() string {
+	host := c.Host
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(c.GatewayPort))
+}
+
+// TLSConfig returns the TLS settings for the gateway connection, or nil
+// when the defaults apply
+func (c ClawdbotConfig) TLSConfig() (*tls.Config, error) {
+	if c.TLSCertFile == "" && c.TLSCAFile == "" && c.TLSServerName == "" {
+		return nil, nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: c.TLSServerName}
+	if c.TLSCAFile != "" {
+		pem, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gateway CA file: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.TLSCAFile)
+		}
+	}
+	if c.TLSCertFile != "" {
+		certFile, keyFile := c.TLSCertFile, c.TLSKeyFile
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load gateway client certificate: %w", err)
+			}
+			return &cert, nil
+		}
+	}
+	return cfg, nil
+}
+
+// ProxyFunc returns the proxy for the gateway connection as for
+// http.Transport.Proxy, or nil to connect directly
+func (c ClawdbotConfig) ProxyFunc() func(*http.Request) (*url.URL, error) {
+	if c.Proxy == "" {
+		return nil
+	}
+	return proxyFunc(c.Proxy)
+}
+
+// BackendConfig selects which AI backend answers messages.
+// Type "clawdbot" (default) uses the local gateway; "anthropic" and
+// "openai" talk to the HTTP APIs directly without a gateway; "ollama"
+// uses a local Ollama server for offline deployments; "sse" talks to a
+// custom HTTP gateway streaming bridge events over Server-Sent Events;
+// "replay" plays back recorded gateway runs; "embedded" is supplied in
+// code by a program embedding the bridge.
+type BackendConfig struct {
+	Type         string
+	BaseURL      string
+	APIKey       string
+	Model        string
+	SystemPrompt string
+	MaxTokens    int
+	// AgentID overrides the gateway agent for "clawdbot" backends
+	AgentID string
+	// Recordings is the directory of recorded runs for "replay" backends
+	Recordings string
+}
+
+// RoutesConfig decides which named backend answers a message
+type RoutesConfig struct {
+	// Default is the backend used when no other rule matches
+	Default string
+	// Chats maps chat IDs to backend names
+	Chats map[string]string
+	// Commands maps message prefixes (e.g. "/ops") to backend names;
+	// the prefix is stripped before the message is forwarded
+	Commands map[string]string
+}
+
+// IsAdmin reports whether openID is listed in Admins
+func (c *Config) IsAdmin(openID string) bool {
+	if openID == "" {
+		return false
+	}
+	for _, id := range c.Admins {
+		if id == openID {
+			return true
+		}
+	}
+	return false
+}
+
+// GatewayDelivers reports whether replies of the named backend reach the
+// chat through the gateway's own delivery instead of the bridge
+func (c *Config) GatewayDelivers(backendName string) bool {
+	return c.Clawdbot.Deliver && c.Backends[backendName].Type == "clawdbot"
+}
+
+// backendJSON matches the "backend" section of bridge.json
+type backendJSON struct {
+	Type         string `json:"type"`
+	BaseURL      string `json:"base_url,omitempty"`
+	APIKey       string `json:"api_key,omitempty"`
+	Model        string `json:"model,omitempty"`
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	MaxTokens    int    `json:"max_tokens,omitempty"`
+	AgentID      string `json:"agent_id,omitempty"`
+	Recordings   string `json:"recordings,omitempty"`
+}
+
+// gatewayJSON matches the "gateway" section of bridge.json
+type gatewayJSON struct {
+	Transport string `json:"transport,omitempty"`
+	GRPCAddr  string `json:"grpc_addr,omitempty"`
+	GRPCTLS   bool   `json:"grpc_tls,omitempty"`
+	Token     string `json:"token,omitempty"`
+	TokenFile string `json:"token_file,omitempty"`
+	RecordDir string `json:"record_dir,omitempty"`
+	// RecordRetentionDays defaults to 30
+	RecordRetentionDays *int   `json:"record_retention_days,omitempty"`
+	Deliver             bool   `json:"deliver,omitempty"`
+	Host                string `json:"host,omitempty"`
+	Proxy               string `json:"proxy,omitempty"`
+	TLS                 bool   `json:"tls,omitempty"`
+	// TLSCertFile, TLSKeyFile and TLSCAFile are PEM files
+	TLSCertFile   string `json:"tls_cert_file,omitempty"`
+	TLSKeyFile    string `json:"tls_key_file,omitempty"`
+	TLSCAFile     string `json:"tls_ca_file,omitempty"`
+	TLSServerName string `json:"tls_server_name,omitempty"`
+	SSH           struct {
+		Addr       string `json:"addr"`
+		User       string `json:"user"`
+		KeyFile    string `json:"key_file"`
+		KnownHosts string `json:"known_hosts,omitempty"`
+	} `json:"ssh"`
+}
+
+// remote reports whether the gateway is reached on another host, so no
+// local clawdbot.json is needed
+func (g gatewayJSON) remote() bool {
+	return g.Transport == "grpc" || g.Host != "" || g.Proxy != "" || g.SSH.Addr != ""
+}
+
+// agentJSON matches an entry of the "agents" section of bridge.json
+type agentJSON struct {
+	Backend     string `json:"backend,omitempty"`
+	AgentID     string `json:"agent_id,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// toolsJSON matches an entry of the "chat_tools" section of bridge.json
+type toolsJSON struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// observabilityJSON matches the "observability" section of bridge.json
+type observabilityJSON struct {
+	Tracing struct {
+		Endpoint    string            `json:"endpoint,omitempty"`
+		Headers     map[string]string `json:"headers,omitempty"`
+		ServiceName string            `json:"service_name,omitempty"`
+		SampleRatio *float64          `json:"sample_ratio,omitempty"`
+	} `json:"tracing"`
+	Sentry struct {
+		DSN         string   `json:"dsn,omitempty"`
+		Environment string   `json:"environment,omitempty"`
+		SampleRate  *float64 `json:"sample_rate,omitempty"`
+	} `json:"sentry"`
+	StatsD struct {
+		Addr      string `json:"addr,omitempty"`
+		DogStatsD bool   `json:"dogstatsd,omitempty"`
+	} `json:"statsd"`
+	InfluxDB struct {
+		URL   string `json:"url,omitempty"`
+		Token string `json:"token,omitempty"`
+	} `json:"influxdb"`
+	MetricPrefix        *string `json:"metric_prefix,omitempty"`
+	PushIntervalSeconds int     `json:"push_interval_seconds,omitempty"`
+}
+
+// alertsJSON matches the "alerts" section of bridge.json
+type alertsJSON struct {
+	ChatID             string  `json:"chat_id,omitempty"`
+	Webhook            string  `json:"webhook,omitempty"`
+	CooldownMinutes    int     `json:"cooldown_minutes,omitempty"`
+	MaxPerHour         int     `json:"max_per_hour,omitempty"`
+	ErrorRatePercent   float64 `json:"error_rate_percent,omitempty"`
+	ErrorWindowMinutes int     `json:"error_window_minutes,omitempty"`
+	MinSamples         int     `json:"min_samples,omitempty"`
+	QueueThreshold     int     `json:"queue_threshold,omitempty"`
+	HeartbeatURL       string  `json:"heartbeat_url,omitempty"`
+	HeartbeatSeconds   int     `json:"heartbeat_interval_seconds,omitempty"`
+	StartupCard        bool    `json:"startup_card,omitempty"`
+}
+
+// sloJSON matches the "slo" section of bridge.json
+type sloJSON struct {
+	LatencyMs      int     `json:"latency_ms,omitempty"`
+	Percentile     float64 `json:"percentile,omitempty"`
+	WindowMinutes  int     `json:"window_minutes,omitempty"`
+	SustainMinutes int     `json:"sustain_minutes,omitempty"`
+	MinSamples     int     `json:"min_samples,omitempty"`
+}
+
+// transcriptsJSON matches the "transcripts" section of bridge.json
+type transcriptsJSON struct {
+	Dir           string `json:"dir,omitempty"`
+	StoreContent  bool   `json:"store_content,omitempty"`
+	RetentionDays *int   `json:"retention_days,omitempty"`
+}
+
+// encryptionJSON matches the "encryption" section of bridge.json
+type encryptionJSON struct {
+	KeyringFile string `json:"keyring_file,omitempty"`
+}
+
+// eventsJSON matches the "events" section of bridge.json
+type eventsJSON struct {
+	Dir           string `json:"dir,omitempty"`
+	RetentionDays *int   `json:"retention_days,omitempty"`
+}
+
+// adminJSON matches the "admin" section of bridge.json
+type adminJSON struct {
+	Listen    string `json:"listen,omitempty"`
+	Token     string `json:"token,omitempty"`
+	TokenFile string `json:"token_file,omitempty"`
+}
+
+// redisJSON matches the "redis" section of bridge.json
+type redisJSON struct {
+	Addr     string `json:"addr,omitempty"`
+	Password string `json:"password,omitempty"`
+	DB       int    `json:"db,omitempty"`
+	Prefix   string `json:"prefix,omitempty"`
+}
+
+// clusterJSON matches the "cluster" section of bridge.json
+type clusterJSON struct {
+	Enabled          bool   `json:"enabled,omitempty"`
+	Instance         string `json:"instance,omitempty"`
+	HeartbeatSeconds int    `json:"heartbeat_seconds,omitempty"`
+}
+
+// failoverJSON matches the "failover" section of bridge.json
+type failoverJSON struct {
+	Enabled      bool   `json:"enabled,omitempty"`
+	LockFile     string `json:"lock_file,omitempty"`
+	LeaseSeconds int    `json:"lease_seconds,omitempty"`
+}
+
+// shutdownJSON matches the "shutdown" section of bridge.json
+type shutdownJSON struct {
+	DrainSeconds int `json:"drain_seconds,omitempty"`
+}
+
+// streamingJSON matches the "streaming" section of bridge.json
+type streamingJSON struct {
+	MinIntervalMs    int     `json:"min_interval_ms,omitempty"`
+	MaxIntervalMs    int     `json:"max_interval_ms,omitempty"`
+	UpdatesPerSecond float64 `json:"updates_per_second,omitempty"`
+	ToolActivity     bool    `json:"tool_activity,omitempty"`
+}
+
+// longReplyJSON matches the "long_reply" section of bridge.json
+type longReplyJSON struct {
+	MaxChars     int `json:"max_chars,omitempty"`
+	SummaryChars int `json:"summary_chars,omitempty"`
+}
+
+// messageBreaksJSON matches the "message_breaks" section of bridge.json
+type messageBreaksJSON struct {
+	Enabled     bool `json:"enabled"`
+	MaxMessages int  `json:"max_messages,omitempty"`
+}
+
+// mermaidJSON matches the "mermaid" section of bridge.json
+type mermaidJSON struct {
+	Command        string `json:"command,omitempty"`
+	URL            string `json:"url,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+	MaxDiagrams    int    `json:"max_diagrams,omitempty"`
+}
+
+// chartsJSON matches the "charts" section of bridge.json
+type chartsJSON struct {
+	Command        string `json:"command,omitempty"`
+	URL            string `json:"url,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+	MaxCharts      int    `json:"max_charts,omitempty"`
+}
+
+// mathJSON matches the "math" section of bridge.json
+type mathJSON struct {
+	Command        string `json:"command,omitempty"`
+	URL            string `json:"url,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+	MaxFormulas    int    `json:"max_formulas,omitempty"`
+}
+
+// linkPreviewJSON matches the "link_preview" section of bridge.json
+type linkPreviewJSON struct {
+	Enabled        bool     `json:"enabled"`
+	Hosts          []string `json:"hosts,omitempty"`
+	MaxLinks       int      `json:"max_links,omitempty"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"`
+}
+
+// generatedImagesJSON matches the "generated_images" section of bridge.json
+type generatedImagesJSON struct {
+	Enabled        bool     `json:"enabled"`
+	Dirs           []string `json:"dirs,omitempty"`
+	Hosts          []string `json:"hosts,omitempty"`
+	MaxImages      int      `json:"max_images,omitempty"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"`
+}
+
+// analyticsJSON matches the "analytics" section of bridge.json
+type analyticsJSON struct {
+	DigestChat    string       `json:"digest_chat,omitempty"`
+	DigestWeekday string       `json:"digest_weekday,omitempty"`
+	DigestHour    *int         `json:"digest_hour,omitempty"`
+	Reports       []reportJSON `json:"reports,omitempty"`
+}
+
+// reportJSON matches an entry of "analytics.reports" in bridge.json
+type reportJSON struct {
+	Period string `json:"period"`
+	Hour   *int   `json:"hour,omitempty"`
+	Chat   string `json:"chat,omitempty"`
+	CSV    bool   `json:"csv,omitempty"`
+}
+
+// approvalJSON matches the "approval" section of bridge.json
+type approvalJSON struct {
+	Enabled   bool     `json:"enabled"`
+	AdminChat string   `json:"admin_chat,omitempty"`
+	Chats     []string `json:"chats,omitempty"`
+}
+
+// memoryJSON matches the "memory" section of bridge.json
+type memoryJSON struct {
+	Enabled    bool `json:"enabled"`
+	MaxEntries int  `json:"max_entries,omitempty"`
+}
+
+// tasksJSON matches the "tasks" section of bridge.json
+type tasksJSON struct {
+	Enabled bool `json:"enabled"`
+}
+
+// pollsJSON matches the "polls" section of bridge.json
+type pollsJSON struct {
+	Enabled   bool `json:"enabled"`
+	Summarize bool `json:"summarize,omitempty"`
+}
+
+// surveyJSON matches the "survey" section of bridge.json
+type surveyJSON struct {
+	Enabled       bool `json:"enabled"`
+	IdleMinutes   int  `json:"idle_minutes,omitempty"`
+	IntervalHours int  `json:"interval_hours,omitempty"`
+}
+
+// classifyJSON matches the "classify" section of bridge.json
+type classifyJSON struct {
+	Enabled        bool              `json:"enabled"`
+	Backend        string            `json:"backend,omitempty"`
+	Tags           []string          `json:"tags,omitempty"`
+	Routes         map[string]string `json:"routes,omitempty"`
+	IdleMinutes    int               `json:"idle_minutes,omitempty"`
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty"`
+}
+
+// qaJSON matches the "qa" section of bridge.json
+type qaJSON struct {
+	ChatID         string  `json:"chat_id,omitempty"`
+	Percent        float64 `json:"percent,omitempty"`
+	IncludePrivate bool    `json:"include_private,omitempty"`
+}
+
+// groupsJSON matches the "groups" section of bridge.json
+type groupsJSON struct {
+	Enabled bool                `json:"enabled"`
+	Teams   map[string][]string `json:"teams,omitempty"`
+}
+
+// helpdeskJSON matches the "helpdesk" section of bridge.json
+type helpdeskJSON struct {
+	Enabled       bool     `json:"enabled"`
+	ID            string   `json:"id"`
+	Token         string   `json:"token"`
+	Agents        []string `json:"agents,omitempty"`
+	EscalateAfter int      `json:"escalate_after,omitempty"`
+}
+
+// queueJSON matches the "queue" section of bridge.json
+type queueJSON struct {
+	MaxRuns          int      `json:"max_runs,omitempty"`
+	PerChat          *int     `json:"per_chat,omitempty"`
+	Overflow         string   `json:"overflow,omitempty"`
+	PriorityChats    []string `json:"priority_chats,omitempty"`
+	PriorityPrefixes []string `json:"priority_prefixes,omitempty"`
+	MaxSkips         int      `json:"max_skips,omitempty"`
+}
+
+// networkJSON matches the "network" section of bridge.json
+type networkJSON struct {
+	IPVersion  string `json:"ip_version,omitempty"`
+	SourceAddr string `json:"source_addr,omitempty"`
+	Interface  string `json:"interface,omitempty"`
+	DNS        string `json:"dns,omitempty"`
+	// KeepAliveSeconds defaults to Go's 15; 0 turns keepalives off
+	KeepAliveSeconds *int `json:"keepalive_seconds,omitempty"`
+}
+
+// minutesJSON matches the "minutes" section of bridge.json
+type minutesJSON struct {
+	Enabled     bool `json:"enabled"`
+	MaxChars    int  `json:"max_chars,omitempty"`
+	CreateTasks bool `json:"create_tasks,omitempty"`
+}
+
+// wikiJSON matches the "wiki" section of bridge.json
+type wikiJSON struct {
+	Enabled        bool     `json:"enabled"`
+	Spaces         []string `json:"spaces,omitempty"`
+	BaseURL        string   `json:"base_url,omitempty"`
+	RefreshMinutes int      `json:"refresh_minutes,omitempty"`
+	MaxPassages    int      `json:"max_passages,omitempty"`
+	PassageChars   int      `json:"passage_chars,omitempty"`
+}
+
+// inviteJSON matches the "invite" section of bridge.json
+type inviteJSON struct {
+	Required bool `json:"required"`
+}
+
+// twoPersonJSON matches the "two_person" section of bridge.json
+type twoPersonJSON struct {
+	Actions       []string `json:"actions,omitempty"`
+	WindowMinutes int      `json:"window_minutes,omitempty"`
+	ChatID        string   `json:"chat_id,omitempty"`
+}
+
+// auditJSON matches the "audit" section of bridge.json
+type auditJSON struct {
+	Dir           string `json:"dir,omitempty"`
+	RetentionDays *int   `json:"retention_days,omitempty"`
+}
+
+// shadowJSON matches the "shadow" section of bridge.json
+type shadowJSON struct {
+	Backend       string  `json:"backend,omitempty"`
+	Percent       float64 `json:"percent,omitempty"`
+	Dir           string  `json:"dir,omitempty"`
+	RetentionDays *int    `json:"retention_days,omitempty"`
+}
+
+// deadLettersJSON matches the "dead_letters" section of bridge.json
+type deadLettersJSON struct {
+	RetentionDays *int `json:"retention_days,omitempty"`
+}
+
+// routesJSON matches the "routes" section of bridge.json
+type routesJSON struct {
+	Default  string            `json:"default,omitempty"`
+	Chats    map[string]string `json:"chats,omitempty"`
+	Commands map[string]string `json:"commands,omitempty"`
 }
 
 // clawdbotJSON matches ~/.clawdbot/clawdbot.json (managed by ClawdBot)
@@ -41,16 +1320,73 @@ type clawdbotJSON struct {
 // bridgeJSON matches ~/.clawdbot/bridge.json
 type bridgeJSON struct {
 	Feishu struct {
-		AppID     string `json:"app_id"`
-		AppSecret string `json:"app_secret"`
+		AppID         string `json:"app_id"`
+		AppSecret     string `json:"app_secret"`
+		AppSecretFile string `json:"app_secret_file,omitempty"`
+		// SilenceTimeoutSeconds defaults to 300; 0 disables the watchdog
+		SilenceTimeoutSeconds *int   `json:"silence_timeout_seconds,omitempty"`
+		Proxy                 string `json:"proxy,omitempty"`
+		ConnectionProxy       string `json:"connection_proxy,omitempty"`
 	} `json:"feishu"`
-	ThinkingThresholdMs *int   `json:"thinking_threshold_ms,omitempty"`
-	AgentID             string `json:"agent_id"`
-	SessionKey          string `json:"session_key"`
+	ThinkingThresholdMs *int                   `json:"thinking_threshold_ms,omitempty"`
+	AgentID             string                 `json:"agent_id"`
+	SessionKey          string                 `json:"session_key"`
+	Backend             backendJSON            `json:"backend"`
+	Backends            map[string]backendJSON `json:"backends,omitempty"`
+	Routes              routesJSON             `json:"routes"`
+	Gateway             gatewayJSON            `json:"gateway"`
+	Network             networkJSON            `json:"network"`
+	Shadow              shadowJSON             `json:"shadow"`
+	Agents              map[string]agentJSON   `json:"agents,omitempty"`
+	ChatAgents          map[string][]string    `json:"chat_agents,omitempty"`
+	ChatTools           map[string]toolsJSON   `json:"chat_tools,omitempty"`
+	Aliases             map[string]aliasJSON   `json:"aliases,omitempty"`
+	Observability       observabilityJSON      `json:"observability"`
+	Audit               auditJSON              `json:"audit"`
+	Alerts              alertsJSON             `json:"alerts"`
+	Transcripts         transcriptsJSON        `json:"transcripts"`
+	Encryption          encryptionJSON         `json:"encryption"`
+	Events              eventsJSON             `json:"events"`
+	DeadLetters         deadLettersJSON        `json:"dead_letters"`
+	Redis               redisJSON              `json:"redis"`
+	Admin               adminJSON              `json:"admin"`
+	Cluster             clusterJSON            `json:"cluster"`
+	Failover            failoverJSON           `json:"failover"`
+	Shutdown            shutdownJSON           `json:"shutdown"`
+	Streaming           streamingJSON          `json:"streaming"`
+	LongReply           longReplyJSON          `json:"long_reply"`
+	MessageBreaks       messageBreaksJSON      `json:"message_breaks"`
+	Mermaid             mermaidJSON            `json:"mermaid"`
+	Charts              chartsJSON             `json:"charts"`
+	Math                mathJSON               `json:"math"`
+	LinkPreview         linkPreviewJSON        `json:"link_preview"`
+	Images              generatedImagesJSON    `json:"generated_images"`
+	Analytics           analyticsJSON          `json:"analytics"`
+	Approval            approvalJSON           `json:"approval"`
+	Invite              inviteJSON             `json:"invite"`
+	TwoPerson           twoPersonJSON          `json:"two_person"`
+	Shell               shellJSON              `json:"shell"`
+	Memory              memoryJSON             `json:"memory"`
+	Tasks               tasksJSON              `json:"tasks"`
+	Sheets              sheetsJSON             `json:"sheets"`
+	Approvals           approvalsJSON          `json:"approvals"`
+	Wiki                wikiJSON               `json:"wiki"`
+	Minutes             minutesJSON            `json:"minutes"`
+	Polls               pollsJSON              `json:"polls"`
+	Survey              surveyJSON             `json:"survey"`
+	Classify            classifyJSON           `json:"classify"`
+	QA                  qaJSON                 `json:"qa"`
+	Groups              groupsJSON             `json:"groups"`
+	Helpdesk            helpdeskJSON           `json:"helpdesk"`
+	Queue               queueJSON              `json:"queue"`
+	Pricing             map[string]Price       `json:"pricing,omitempty"`
+	SLO                 sloJSON                `json:"slo"`
+	Admins              []string               `json:"admins,omitempty"`
 }
 
 // Dir returns the config directory path
-// Tries ~/.clawdbot first, falls back to ~/.openclaw
+// Prefers the one holding bridge.json, then tries ~/.clawdbot first,
+// falls back to ~/.openclaw
 func Dir() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -63,6 +1399,15 @@ func Dir() (string, error) {
 		filepath.Join(home, ".openclaw"),
 	}
 
+	// The one holding bridge.json wins, so once migrate has moved the
+	// bridge's files out of .clawdbot the gateway's files left there
+	// don't keep it chosen
+	for _, dir := range candidates {
+		if _, err := os.Stat(filepath.Join(dir, "bridge.json")); err == nil {
+			return dir, nil
+		}
+	}
+
 	// Return first existing directory, or default to .clawdbot
 	for _, dir := range candidates {
 		if info, err := os.Stat(dir); err == nil && info.IsDir() {
@@ -96,20 +1441,11 @@ func Load() (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	return LoadDir(dir)
+}
 
-	// Find gateway config file: clawdbot.json or openclaw.json
-	gwPath, err := findConfigFile(dir, "clawdbot.json", "openclaw.json")
-	if err != nil {
-		return nil, fmt.Errorf("failed to find gateway config (clawdbot.json or openclaw.json) in %s: %w", dir, err)
-	}
-	gwData, err := os.ReadFile(gwPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read %s: %w", gwPath, err)
-	}
-	var gwCfg clawdbotJSON
-	if err := json.Unmarshal(gwData, &gwCfg); err != nil {
-		return nil, fmt.Errorf("failed to parse %s: %w", gwPath, err)
-	}
+// LoadDir reads configuration from the config files in dir
+func LoadDir(dir string) (*Config, error) {
 
 	// Find bridge config file: bridge.json
 	brPath, err := findConfigFile(dir, "bridge.json")
@@ -126,10 +1462,27 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to parse %s: %w", brPath, err)
 	}
 
+	// Find gateway config file: clawdbot.json or openclaw.json
+	// Only required when the gateway backend is in use
+	var gwCfg clawdbotJSON
+	gwPath, err := findConfigFile(dir, "clawdbot.json", "openclaw.json")
+	if err == nil {
+		if gwCfg, err = readGatewayFile(gwPath); err != nil {
+			return nil, err
+		}
+	} else if brCfg.usesGateway() && !brCfg.Gateway.remote() {
+		return nil, fmt.Errorf("failed to find gateway config (clawdbot.json or openclaw.json) in %s: %w", dir, err)
+	}
+
 	// Validate required fields
 	if brCfg.Feishu.AppID == "" {
 		return nil, fmt.Errorf("feishu.app_id is required in ~/.clawdbot/bridge.json")
 	}
+	if path := brCfg.Feishu.AppSecretFile; path != "" {
+		if brCfg.Feishu.AppSecret, err = readSecretFile(path); err != nil {
+			return nil, err
+		}
+	}
 	if brCfg.Feishu.AppSecret == "" {
 		return nil, fmt.Errorf("feishu.app_secret is required in ~/.clawdbot/bridge.json")
 	}
@@ -139,16 +1492,405 @@ func Load() (*Config, error) {
 		Feishu: FeishuConfig{
 			AppID:               brCfg.Feishu.AppID,
 			AppSecret:           brCfg.Feishu.AppSecret,
+			AppSecretFile:       brCfg.Feishu.AppSecretFile,
 			ThinkingThresholdMs: 0,
+			SilenceTimeout:      5 * time.Minute,
+			Proxy:               brCfg.Feishu.Proxy,
+			ConnectionProxy:     brCfg.Feishu.ConnectionProxy,
 		},
 		Clawdbot: ClawdbotConfig{
-			GatewayPort:  gwCfg.Gateway.Port,
-			GatewayToken: gwCfg.Gateway.Auth.Token,
-			AgentID:      "main",
-			SessionKey:   "",
+			GatewayPort:         gwCfg.Gateway.Port,
+			GatewayToken:        gwCfg.Gateway.Auth.Token,
+			AgentID:             "main",
+			SessionKey:          "",
+			Transport:           "websocket",
+			GRPCAddr:            brCfg.Gateway.GRPCAddr,
+			GRPCTLS:             brCfg.Gateway.GRPCTLS,
+			RecordDir:           brCfg.Gateway.RecordDir,
+			RecordRetentionDays: 30,
+			Deliver:             brCfg.Gateway.Deliver,
+			ConfigPath:          gwPath,
+			Host:                brCfg.Gateway.Host,
+			Proxy:               brCfg.Gateway.Proxy,
+			TLS:                 brCfg.Gateway.TLS,
+			TLSCertFile:         brCfg.Gateway.TLSCertFile,
+			TLSKeyFile:          brCfg.Gateway.TLSKeyFile,
+			TLSCAFile:           brCfg.Gateway.TLSCAFile,
+			TLSServerName:       brCfg.Gateway.TLSServerName,
+			SSH: SSHTunnelConfig{
+				Addr:       brCfg.Gateway.SSH.Addr,
+				User:       brCfg.Gateway.SSH.User,
+				KeyFile:    brCfg.Gateway.SSH.KeyFile,
+				KnownHosts: brCfg.Gateway.SSH.KnownHosts,
+			},
+		},
+		Network: NetworkConfig{
+			IPVersion:  brCfg.Network.IPVersion,
+			SourceAddr: brCfg.Network.SourceAddr,
+			Interface:  brCfg.Network.Interface,
+			DNS:        brCfg.Network.DNS,
+		},
+		Backend:  brCfg.Backend.toConfig(),
+		Backends: make(map[string]BackendConfig),
+		Routes: RoutesConfig{
+			Default:  brCfg.Routes.Default,
+			Chats:    brCfg.Routes.Chats,
+			Commands: brCfg.Routes.Commands,
 		},
+		Admins: brCfg.Admins,
+		Shadow: ShadowConfig{
+			Backend:       brCfg.Shadow.Backend,
+			Percent:       brCfg.Shadow.Percent,
+			Dir:           brCfg.Shadow.Dir,
+			RetentionDays: 30,
+		},
+		Agents:     make(map[string]AgentConfig),
+		ChatAgents: brCfg.ChatAgents,
+		Observability: ObservabilityConfig{
+			Tracing: TracingConfig{
+				Endpoint:    brCfg.Observability.Tracing.Endpoint,
+				Headers:     brCfg.Observability.Tracing.Headers,
+				ServiceName: brCfg.Observability.Tracing.ServiceName,
+				SampleRatio: 1,
+			},
+			Sentry: SentryConfig{
+				DSN:         brCfg.Observability.Sentry.DSN,
+				Environment: brCfg.Observability.Sentry.Environment,
+				SampleRate:  1,
+			},
+			StatsD: StatsDConfig{
+				Addr:      brCfg.Observability.StatsD.Addr,
+				DogStatsD: brCfg.Observability.StatsD.DogStatsD,
+			},
+			InfluxDB: InfluxDBConfig{
+				URL:   brCfg.Observability.InfluxDB.URL,
+				Token: brCfg.Observability.InfluxDB.Token,
+			},
+			MetricPrefix: "clawdbot_bridge.",
+			PushInterval: time.Duration(orDefault(brCfg.Observability.PushIntervalSeconds, 10)) * time.Second,
+		},
+		Audit: AuditConfig{
+			Dir:           brCfg.Audit.Dir,
+			RetentionDays: 90,
+		},
+		Alerts: AlertConfig{
+			ChatID:            brCfg.Alerts.ChatID,
+			Webhook:           brCfg.Alerts.Webhook,
+			Cooldown:          time.Duration(orDefault(brCfg.Alerts.CooldownMinutes, 10)) * time.Minute,
+			MaxPerHour:        orDefault(brCfg.Alerts.MaxPerHour, 20),
+			ErrorRatePercent:  brCfg.Alerts.ErrorRatePercent,
+			ErrorWindow:       time.Duration(orDefault(brCfg.Alerts.ErrorWindowMinutes, 5)) * time.Minute,
+			MinSamples:        orDefault(brCfg.Alerts.MinSamples, 5),
+			QueueThreshold:    brCfg.Alerts.QueueThreshold,
+			HeartbeatURL:      brCfg.Alerts.HeartbeatURL,
+			HeartbeatInterval: time.Duration(orDefault(brCfg.Alerts.HeartbeatSeconds, 60)) * time.Second,
+			StartupCard:       brCfg.Alerts.StartupCard,
+		},
+		Transcripts: TranscriptConfig{
+			Dir:          brCfg.Transcripts.Dir,
+			StoreContent: brCfg.Transcripts.StoreContent,
+		},
+		Encryption: EncryptionConfig{KeyringFile: brCfg.Encryption.KeyringFile},
+		Events: EventsConfig{
+			Dir:           brCfg.Events.Dir,
+			RetentionDays: 30,
+		},
+		DeadLetters: DeadLetterConfig{RetentionDays: 30},
+		Redis: RedisConfig{
+			Addr:     brCfg.Redis.Addr,
+			Password: brCfg.Redis.Password,
+			DB:       brCfg.Redis.DB,
+			Prefix:   brCfg.Redis.Prefix,
+		},
+		Cluster: ClusterConfig{
+			Enabled:   brCfg.Cluster.Enabled,
+			Instance:  brCfg.Cluster.Instance,
+			Heartbeat: time.Duration(orDefault(brCfg.Cluster.HeartbeatSeconds, 5)) * time.Second,
+		},
+		Failover: FailoverConfig{
+			Enabled:  brCfg.Failover.Enabled,
+			LockFile: brCfg.Failover.LockFile,
+			Lease:    time.Duration(orDefault(brCfg.Failover.LeaseSeconds, 10)) * time.Second,
+		},
+		Streaming: StreamingConfig{
+			MinInterval:      time.Duration(orDefault(brCfg.Streaming.MinIntervalMs, 300)) * time.Millisecond,
+			MaxInterval:      time.Duration(orDefault(brCfg.Streaming.MaxIntervalMs, 5000)) * time.Millisecond,
+			UpdatesPerSecond: brCfg.Streaming.UpdatesPerSecond,
+			ToolActivity:     brCfg.Streaming.ToolActivity,
+		},
+		LongReply: LongReplyConfig{
+			MaxChars:     orDefault(brCfg.LongReply.MaxChars, 8000),
+			SummaryChars: orDefault(brCfg.LongReply.SummaryChars, 500),
+		},
+		MessageBreaks: MessageBreaksConfig{
+			Enabled:     brCfg.MessageBreaks.Enabled,
+			MaxMessages: orDefault(brCfg.MessageBreaks.MaxMessages, 5),
+		},
+		Mermaid: MermaidConfig{
+			Command:     brCfg.Mermaid.Command,
+			URL:         brCfg.Mermaid.URL,
+			Timeout:     time.Duration(orDefault(brCfg.Mermaid.TimeoutSeconds, 30)) * time.Second,
+			MaxDiagrams: orDefault(brCfg.Mermaid.MaxDiagrams, 5),
+		},
+		Charts: ChartConfig{
+			Command:   brCfg.Charts.Command,
+			URL:       brCfg.Charts.URL,
+			Timeout:   time.Duration(orDefault(brCfg.Charts.TimeoutSeconds, 30)) * time.Second,
+			MaxCharts: orDefault(brCfg.Charts.MaxCharts, 3),
+		},
+		Math: MathConfig{
+			Command:     brCfg.Math.Command,
+			URL:         brCfg.Math.URL,
+			Timeout:     time.Duration(orDefault(brCfg.Math.TimeoutSeconds, 10)) * time.Second,
+			MaxFormulas: orDefault(brCfg.Math.MaxFormulas, 20),
+		},
+		LinkPreview: LinkPreviewConfig{
+			Enabled:  brCfg.LinkPreview.Enabled,
+			Hosts:    brCfg.LinkPreview.Hosts,
+			MaxLinks: orDefault(brCfg.LinkPreview.MaxLinks, 5),
+			Timeout:  time.Duration(orDefault(brCfg.LinkPreview.TimeoutSeconds, 5)) * time.Second,
+		},
+		Images: GeneratedImagesConfig{
+			Enabled:   brCfg.Images.Enabled,
+			Dirs:      brCfg.Images.Dirs,
+			Hosts:     brCfg.Images.Hosts,
+			MaxImages: orDefault(brCfg.Images.MaxImages, 4),
+			Timeout:   time.Duration(orDefault(brCfg.Images.TimeoutSeconds, 15)) * time.Second,
+		},
+		DrainTimeout: time.Duration(orDefault(brCfg.Shutdown.DrainSeconds, 120)) * time.Second,
+		Analytics: AnalyticsConfig{
+			DigestChat:    brCfg.Analytics.DigestChat,
+			DigestWeekday: time.Monday,
+			DigestHour:    9,
+		},
+		Approval: ApprovalConfig{
+			Enabled:   brCfg.Approval.Enabled,
+			AdminChat: brCfg.Approval.AdminChat,
+			Chats:     brCfg.Approval.Chats,
+		},
+		Invite: InviteConfig{Required: brCfg.Invite.Required},
+		Shell:  brCfg.Shell.toConfig(),
+		Memory: MemoryConfig{
+			Enabled:    brCfg.Memory.Enabled,
+			MaxEntries: orDefault(brCfg.Memory.MaxEntries, 50),
+		},
+		Tasks:     TasksConfig{Enabled: brCfg.Tasks.Enabled},
+		Sheets:    brCfg.Sheets.toConfig(),
+		Approvals: brCfg.Approvals.toConfig(),
+		Polls:     PollsConfig{Enabled: brCfg.Polls.Enabled, Summarize: brCfg.Polls.Summarize},
+		Classify: ClassifyConfig{
+			Enabled: brCfg.Classify.Enabled,
+			Backend: brCfg.Classify.Backend,
+			Tags:    brCfg.Classify.Tags,
+			Routes:  brCfg.Classify.Routes,
+			Idle:    time.Duration(orDefault(brCfg.Classify.IdleMinutes, 30)) * time.Minute,
+			Timeout: time.Duration(orDefault(brCfg.Classify.TimeoutSeconds, 10)) * time.Second,
+		},
+		QA: QAConfig{
+			ChatID:         brCfg.QA.ChatID,
+			Percent:        brCfg.QA.Percent,
+			IncludePrivate: brCfg.QA.IncludePrivate,
+		},
+		Survey: SurveyConfig{
+			Enabled:  brCfg.Survey.Enabled,
+			Idle:     time.Duration(orDefault(brCfg.Survey.IdleMinutes, 10)) * time.Minute,
+			Interval: time.Duration(orDefault(brCfg.Survey.IntervalHours, 24)) * time.Hour,
+		},
+		Groups: GroupsConfig{Enabled: brCfg.Groups.Enabled, Teams: brCfg.Groups.Teams},
+		Helpdesk: HelpdeskConfig{
+			Enabled:       brCfg.Helpdesk.Enabled,
+			ID:            brCfg.Helpdesk.ID,
+			Token:         brCfg.Helpdesk.Token,
+			Agents:        brCfg.Helpdesk.Agents,
+			EscalateAfter: brCfg.Helpdesk.EscalateAfter,
+		},
+		Queue: QueueConfig{
+			MaxRuns:          brCfg.Queue.MaxRuns,
+			PerChat:          1,
+			Overflow:         brCfg.Queue.Overflow,
+			PriorityChats:    brCfg.Queue.PriorityChats,
+			PriorityPrefixes: brCfg.Queue.PriorityPrefixes,
+			MaxSkips:         orDefault(brCfg.Queue.MaxSkips, 3),
+		},
+		Minutes: MinutesConfig{
+			Enabled:     brCfg.Minutes.Enabled,
+			MaxChars:    orDefault(brCfg.Minutes.MaxChars, 30000),
+			CreateTasks: brCfg.Minutes.CreateTasks,
+		},
+		Wiki: WikiConfig{
+			Enabled:      brCfg.Wiki.Enabled,
+			Spaces:       brCfg.Wiki.Spaces,
+			BaseURL:      strings.TrimSuffix(brCfg.Wiki.BaseURL, "/"),
+			Refresh:      time.Duration(orDefault(brCfg.Wiki.RefreshMinutes, 60)) * time.Minute,
+			MaxPassages:  orDefault(brCfg.Wiki.MaxPassages, 3),
+			PassageChars: orDefault(brCfg.Wiki.PassageChars, 500),
+		},
+		TwoPerson: TwoPersonConfig{
+			Actions: brCfg.TwoPerson.Actions,
+			Window:  time.Duration(orDefault(brCfg.TwoPerson.WindowMinutes, 10)) * time.Minute,
+			ChatID:  brCfg.TwoPerson.ChatID,
+		},
+		SLO: SLOConfig{
+			LatencyMs:  brCfg.SLO.LatencyMs,
+			Percentile: brCfg.SLO.Percentile,
+			Window:     time.Duration(orDefault(brCfg.SLO.WindowMinutes, 15)) * time.Minute,
+			Sustain:    time.Duration(orDefault(brCfg.SLO.SustainMinutes, 5)) * time.Minute,
+			MinSamples: orDefault(brCfg.SLO.MinSamples, 5),
+		},
+		Pricing:    brCfg.Pricing,
+		Dir:        dir,
+		BridgePath: brPath,
+	}
+	if cfg.SLO.Percentile <= 0 || cfg.SLO.Percentile > 100 {
+		cfg.SLO.Percentile = 95
+	}
+	if cfg.Redis.Prefix == "" {
+		cfg.Redis.Prefix = "clawdbot:"
+	}
+	if path := brCfg.Admin.TokenFile; path != "" {
+		if brCfg.Admin.Token, err = readSecretFile(path); err != nil {
+			return nil, err
+		}
+	}
+	cfg.Admin = AdminConfig{Listen: brCfg.Admin.Listen, Token: brCfg.Admin.Token}
+	if cfg.Admin.Listen != "" && cfg.Admin.Token == "" {
+		return nil, fmt.Errorf("admin.token or admin.token_file is required when admin.listen is set")
+	}
+	if cfg.Cluster.Enabled && cfg.Redis.Addr == "" {
+		return nil, fmt.Errorf("cluster mode requires redis.addr")
+	}
+	if cfg.Cluster.Enabled && cfg.Cluster.Instance == "" {
+		cfg.Cluster.Instance, _ = os.Hostname()
+	}
+	if cfg.Failover.Enabled && cfg.Failover.LockFile == "" && cfg.Redis.Addr == "" {
+		return nil, fmt.Errorf("failover requires failover.lock_file or redis.addr")
+	}
+	if cfg.Streaming.UpdatesPerSecond <= 0 {
+		cfg.Streaming.UpdatesPerSecond = 20
+	}
+	if cfg.Streaming.MaxInterval < cfg.Streaming.MinInterval {
+		cfg.Streaming.MaxInterval = cfg.Streaming.MinInterval
+	}
+	if cfg.LongReply.SummaryChars >= cfg.LongReply.MaxChars {
+		return nil, fmt.Errorf("long_reply.summary_chars must be less than long_reply.max_chars")
+	}
+	if cfg.MessageBreaks.MaxMessages < 2 {
+		return nil, fmt.Errorf("message_breaks.max_messages must be at least 2")
+	}
+	if cfg.Mermaid.Command != "" && cfg.Mermaid.URL != "" {
+		return nil, fmt.Errorf("mermaid: set either command or url, not both")
+	}
+	if cfg.Charts.Command != "" && cfg.Charts.URL != "" {
+		return nil, fmt.Errorf("charts: set either command or url, not both")
+	}
+	if cfg.Math.Command != "" && cfg.Math.URL != "" {
+		return nil, fmt.Errorf("math: set either command or url, not both")
+	}
+	if cfg.Events.Dir == "" {
+		cfg.Events.Dir = filepath.Join(dir, "events")
+	}
+	if cfg.Transcripts.Dir == "" {
+		cfg.Transcripts.Dir = filepath.Join(dir, "transcripts")
+	}
+	if cfg.Shadow.Dir == "" {
+		cfg.Shadow.Dir = filepath.Join(dir, "shadow")
+	}
+	if cfg.Analytics.DigestChat == "" {
+		cfg.Analytics.DigestChat = cfg.Alerts.ChatID
+	}
+	if cfg.Approval.AdminChat == "" {
+		cfg.Approval.AdminChat = cfg.Alerts.ChatID
+	}
+	if cfg.TwoPerson.ChatID == "" {
+		cfg.TwoPerson.ChatID = cfg.Alerts.ChatID
+	}
+	for _, chatID := range []string{cfg.Alerts.ChatID, cfg.Approval.AdminChat} {
+		if chatID != "" && !cfg.Queue.PriorityChat(chatID) {
+			cfg.Queue.PriorityChats = append(cfg.Queue.PriorityChats, chatID)
+		}
+	}
+	if err := validateTwoPerson(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Approval.Enabled && cfg.Approval.AdminChat == "" {
+		return nil, fmt.Errorf("approval.admin_chat is required when approval is enabled (or set alerts.chat_id)")
+	}
+	if name := brCfg.Analytics.DigestWeekday; name != "" {
+		day, err := parseWeekday(name)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Analytics.DigestWeekday = day
+	}
+	if h := brCfg.Analytics.DigestHour; h != nil {
+		if *h < 0 || *h > 23 {
+			return nil, fmt.Errorf("analytics.digest_hour must be 0-23, got %d", *h)
+		}
+		cfg.Analytics.DigestHour = *h
+	}
+	for i, r := range brCfg.Analytics.Reports {
+		report, err := loadReport(r, cfg.Alerts.ChatID)
+		if err != nil {
+			return nil, fmt.Errorf("analytics.reports[%d]: %w", i, err)
+		}
+		cfg.Analytics.Reports = append(cfg.Analytics.Reports, report)
+	}
+	if cfg.Alerts.ErrorRatePercent == 0 {
+		cfg.Alerts.ErrorRatePercent = 50
+	}
+	if cfg.Audit.Dir == "" {
+		cfg.Audit.Dir = filepath.Join(dir, "audit")
+	}
+	if d := brCfg.Audit.RetentionDays; d != nil {
+		cfg.Audit.RetentionDays = *d
+	}
+	if d := brCfg.Transcripts.RetentionDays; d != nil {
+		cfg.Transcripts.RetentionDays = *d
+	}
+	if d := brCfg.Events.RetentionDays; d != nil {
+		cfg.Events.RetentionDays = *d
+	}
+	if d := brCfg.Shadow.RetentionDays; d != nil {
+		cfg.Shadow.RetentionDays = *d
+	}
+	if d := brCfg.DeadLetters.RetentionDays; d != nil {
+		cfg.DeadLetters.RetentionDays = *d
+	}
+	if d := brCfg.Gateway.RecordRetentionDays; d != nil {
+		cfg.Clawdbot.RecordRetentionDays = *d
+	}
+	if r := brCfg.Observability.Tracing.SampleRatio; r != nil {
+		cfg.Observability.Tracing.SampleRatio = *r
+	}
+	if p := brCfg.Observability.MetricPrefix; p != nil {
+		cfg.Observability.MetricPrefix = *p
+	}
+	if r := brCfg.Observability.Sentry.SampleRate; r != nil {
+		cfg.Observability.Sentry.SampleRate = *r
+	}
+	if cfg.Observability.Tracing.ServiceName == "" {
+		cfg.Observability.Tracing.ServiceName = "clawdbot-bridge"
+	}
+	for name, a := range brCfg.Agents {
+		cfg.Agents[name] = AgentConfig{
+			Backend:     a.Backend,
+			AgentID:     a.AgentID,
+			Description: a.Description,
+		}
+	}
+	if len(brCfg.ChatTools) > 0 {
+		cfg.ChatTools = make(map[string]ToolPolicy, len(brCfg.ChatTools))
+		for chatID, t := range brCfg.ChatTools {
+			cfg.ChatTools[chatID] = ToolPolicy{Allow: t.Allow, Deny: t.Deny}
+		}
+	}
+	if cfg.Aliases, err = buildAliases(brCfg.Aliases); err != nil {
+		return nil, err
 	}
 
+	if brCfg.Feishu.SilenceTimeoutSeconds != nil {
+		cfg.Feishu.SilenceTimeout = time.Duration(*brCfg.Feishu.SilenceTimeoutSeconds) * time.Second
+	}
 	if brCfg.ThinkingThresholdMs != nil {
 		cfg.Feishu.ThinkingThresholdMs = *brCfg.ThinkingThresholdMs
 	}
@@ -161,6 +1903,401 @@ func Load() (*Config, error) {
 	if cfg.Clawdbot.GatewayPort == 0 {
 		cfg.Clawdbot.GatewayPort = 18789
 	}
+	if path := brCfg.Gateway.TokenFile; path != "" {
+		if brCfg.Gateway.Token, err = readSecretFile(path); err != nil {
+			return nil, err
+		}
+		cfg.Clawdbot.TokenFile = path
+	}
+	if brCfg.Gateway.Token != "" {
+		cfg.Clawdbot.GatewayToken = brCfg.Gateway.Token
+		cfg.Clawdbot.TokenFromBridge = true
+	}
+	switch brCfg.Gateway.Transport {
+	case "", "websocket":
+	case "grpc":
+		if cfg.Clawdbot.GRPCAddr == "" {
+			return nil, fmt.Errorf("gateway.grpc_addr is required in bridge.json when gateway.transport is grpc")
+		}
+		if g := cfg.Clawdbot; g.Host != "" || g.Proxy != "" || g.SSH.Addr != "" || g.TLS {
+			return nil, fmt.Errorf("gateway.host, gateway.proxy, gateway.ssh and gateway.tls only apply to the websocket transport")
+		}
+		cfg.Clawdbot.Transport = "grpc"
+	default:
+		return nil, fmt.Errorf("unknown gateway.transport %q in bridge.json (expected websocket or grpc)", brCfg.Gateway.Transport)
+	}
+	if cfg.Backend.Type == "" {
+		cfg.Backend.Type = "clawdbot"
+	}
+	if err := validateBackend(&cfg.Backend); err != nil {
+		return nil, err
+	}
+
+	// Named backends; the plain "backend" section becomes "default"
+	for name, b := range brCfg.Backends {
+		bc := b.toConfig()
+		if err := validateBackend(&bc); err != nil {
+			return nil, fmt.Errorf("backends.%s: %w", name, err)
+		}
+		cfg.Backends[name] = bc
+	}
+	if _, ok := cfg.Backends["default"]; !ok && (len(brCfg.Backends) == 0 || brCfg.Backend.Type != "") {
+		cfg.Backends["default"] = cfg.Backend
+	}
+	if err := validateRoutes(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateAgents(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateClassify(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateChatTools(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateShell(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateSheets(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateApprovals(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Minutes.CreateTasks && !cfg.Tasks.Enabled {
+		return nil, fmt.Errorf("minutes.create_tasks needs tasks.enabled")
+	}
+	for name, members := range cfg.Groups.Teams {
+		if name == "" || strings.ContainsAny(name, " \t\n,，|") {
+			return nil, fmt.Errorf("groups.teams: name %q must not be empty or contain spaces, commas or |", name)
+		}
+		if len(members) == 0 {
+			return nil, fmt.Errorf("groups.teams.%s has no members", name)
+		}
+	}
+	if cfg.Helpdesk.Enabled && (cfg.Helpdesk.ID == "" || cfg.Helpdesk.Token == "") {
+		return nil, fmt.Errorf("helpdesk.id and helpdesk.token are required when helpdesk is enabled")
+	}
+	if cfg.Helpdesk.EscalateAfter < 0 {
+		return nil, fmt.Errorf("helpdesk.escalate_after must not be negative")
+	}
+	if err := validateProxy("feishu.proxy", cfg.Feishu.Proxy); err != nil {
+		return nil, err
+	}
+	if err := validateProxy("feishu.connection_proxy", cfg.Feishu.ConnectionProxy); err != nil {
+		return nil, err
+	}
+	if err := validateProxy("gateway.proxy", cfg.Clawdbot.Proxy); err != nil {
+		return nil, err
+	}
+	if err := validateGatewayTLS(cfg.Clawdbot); err != nil {
+		return nil, err
+	}
+	if err := loadNetwork(&cfg.Network, brCfg.Network); err != nil {
+		return nil, err
+	}
+	if t := &cfg.Clawdbot.SSH; t.Addr != "" {
+		if cfg.Clawdbot.Proxy != "" {
+			return nil, fmt.Errorf("gateway.proxy and gateway.ssh can't be used together")
+		}
+		if _, _, err := net.SplitHostPort(t.Addr); err != nil {
+			return nil, fmt.Errorf("gateway.ssh.addr must be host:port: %w", err)
+		}
+		if t.User == "" || t.KeyFile == "" {
+			return nil, fmt.Errorf("gateway.ssh.user and gateway.ssh.key_file are required when gateway.ssh.addr is set")
+		}
+		if t.KnownHosts == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get home directory: %w", err)
+			}
+			t.KnownHosts = filepath.Join(home, ".ssh", "known_hosts")
+		}
+	}
+	if t := cfg.Feishu.SilenceTimeout; t != 0 && t < 3*time.Minute {
+		// Feishu answers the SDK's pings every 2 minutes
+		return nil, fmt.Errorf("feishu.silence_timeout_seconds must be 0 or at least 180")
+	}
+	if u := cfg.Alerts.HeartbeatURL; u != "" && !strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "http://") {
+		return nil, fmt.Errorf("alerts.heartbeat_url must be an http(s) URL")
+	}
+	if cfg.Alerts.HeartbeatInterval < 10*time.Second {
+		return nil, fmt.Errorf("alerts.heartbeat_interval_seconds must be at least 10")
+	}
+	if cfg.Alerts.StartupCard && cfg.Alerts.ChatID == "" {
+		return nil, fmt.Errorf("alerts.startup_card needs alerts.chat_id")
+	}
+	if n := brCfg.Queue.PerChat; n != nil {
+		cfg.Queue.PerChat = *n
+	}
+	if cfg.Queue.MaxRuns < 0 || cfg.Queue.PerChat < 0 {
+		return nil, fmt.Errorf("queue.max_runs and queue.per_chat must not be negative")
+	}
+	switch cfg.Queue.Overflow {
+	case "":
+		cfg.Queue.Overflow = "queue"
+	case "queue":
+	case "reject", "merge":
+		if cfg.Queue.PerChat == 0 {
+			return nil, fmt.Errorf("queue.overflow %q needs a queue.per_chat limit", cfg.Queue.Overflow)
+		}
+	default:
+		return nil, fmt.Errorf("unknown queue.overflow %q in bridge.json (expected queue, reject or merge)", cfg.Queue.Overflow)
+	}
+	for _, prefix := range cfg.Queue.PriorityPrefixes {
+		if prefix == "" || strings.ContainsAny(prefix, " \t\n") {
+			return nil, fmt.Errorf("queue.priority_prefixes must be single words, got %q", prefix)
+		}
+	}
+	if cfg.Wiki.Enabled && len(cfg.Wiki.Spaces) == 0 {
+		return nil, fmt.Errorf("wiki.spaces must list wiki space IDs when wiki is enabled")
+	}
+	if cfg.Shadow.Backend != "" {
+		if _, ok := cfg.Backends[cfg.Shadow.Backend]; !ok {
+			return nil, fmt.Errorf("shadow.backend refers to unknown backend %q", cfg.Shadow.Backend)
+		}
+		if cfg.Shadow.Percent < 0 || cfg.Shadow.Percent > 100 {
+			return nil, fmt.Errorf("shadow.percent must be between 0 and 100")
+		}
+	}
+	if cfg.QA.ChatID != "" {
+		if cfg.QA.Percent == 0 {
+			cfg.QA.Percent = 5
+		}
+		if cfg.QA.Percent < 0 || cfg.QA.Percent > 100 {
+			return nil, fmt.Errorf("qa.percent must be between 0 and 100")
+		}
+	}
 
 	return cfg, nil
 }
+
+// readGatewayFile parses clawdbot.json/openclaw.json
+func readGatewayFile(path string) (clawdbotJSON, error) {
+	var gwCfg clawdbotJSON
+	gwData, err := os.ReadFile(path)
+	if err != nil {
+		return gwCfg, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(gwData, &gwCfg); err != nil {
+		return gwCfg, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return gwCfg, nil
+}
+
+func (b backendJSON) toConfig() BackendConfig {
+	return BackendConfig{
+		Type:         b.Type,
+		BaseURL:      b.BaseURL,
+		APIKey:       b.APIKey,
+		Model:        b.Model,
+		SystemPrompt: b.SystemPrompt,
+		MaxTokens:    b.MaxTokens,
+		AgentID:      b.AgentID,
+		Recordings:   b.Recordings,
+	}
+}
+
+// usesGateway reports whether any configured backend needs the ClawdBot gateway
+func (b *bridgeJSON) usesGateway() bool {
+	if len(b.Backends) == 0 || b.Backend.Type != "" {
+		if b.Backend.Type == "" || b.Backend.Type == "clawdbot" {
+			return true
+		}
+	}
+	for _, nb := range b.Backends {
+		if nb.Type == "" || nb.Type == "clawdbot" {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAgents checks agent bindings refer to configured backends and agents
+func validateAgents(cfg *Config) error {
+	for name, a := range cfg.Agents {
+		if strings.ContainsAny(name, " \t@") {
+			return fmt.Errorf("agents.%s: name must not contain spaces or @", name)
+		}
+		if a.Backend != "" {
+			if _, ok := cfg.Backends[a.Backend]; !ok {
+				return fmt.Errorf("agents.%s refers to unknown backend %q", name, a.Backend)
+			}
+		}
+	}
+	for chatID, names := range cfg.ChatAgents {
+		for _, name := range names {
+			if _, ok := cfg.Agents[name]; !ok {
+				return fmt.Errorf("chat_agents.%s refers to unknown agent %q", chatID, name)
+			}
+		}
+	}
+	return nil
+}
+
+// defaultTags are the conversation categories used when classify.tags
+// is unset
+var defaultTags = []string{"howto", "incident", "code-review", "smalltalk"}
+
+// validateClassify fills in the default tags and checks the classifier
+// backend and tag routes exist
+func validateClassify(cfg *Config) error {
+	c := &cfg.Classify
+	if len(c.Tags) == 0 {
+		c.Tags = defaultTags
+	}
+	if c.Backend != "" {
+		if _, ok := cfg.Backends[c.Backend]; !ok {
+			return fmt.Errorf("classify.backend refers to unknown backend %q", c.Backend)
+		}
+	}
+	known := make(map[string]bool)
+	for _, tag := range c.Tags {
+		known[tag] = true
+	}
+	for tag, agent := range c.Routes {
+		if !known[tag] {
+			return fmt.Errorf("classify.routes.%s: unknown tag (have %s)", tag, strings.Join(c.Tags, ", "))
+		}
+		if _, ok := cfg.Agents[agent]; !ok {
+			return fmt.Errorf("classify.routes.%s refers to unknown agent %q", tag, agent)
+		}
+	}
+	return nil
+}
+
+// validateTwoPerson checks two-person actions are known and can be confirmed
+func validateTwoPerson(cfg *Config) error {
+	for _, action := range cfg.TwoPerson.Actions {
+		known := false
+		for _, a := range TwoPersonActions {
+			known = known || a == action
+		}
+		if !known {
+			return fmt.Errorf("two_person.actions: unknown action %q (supported: %s)", action, strings.Join(TwoPersonActions, ", "))
+		}
+	}
+	if len(cfg.TwoPerson.Actions) > 0 && len(cfg.Admins) < 2 {
+		return fmt.Errorf("two_person needs at least two admins")
+	}
+	return nil
+}
+
+// validateRoutes checks that every route points at a configured backend
+func validateRoutes(cfg *Config) error {
+	if cfg.Routes.Default == "" {
+		if _, ok := cfg.Backends["default"]; ok {
+			cfg.Routes.Default = "default"
+		} else if len(cfg.Backends) == 1 {
+			for name := range cfg.Backends {
+				cfg.Routes.Default = name
+			}
+		} else {
+			return fmt.Errorf("routes.default is required in bridge.json when multiple backends are configured")
+		}
+	}
+	if _, ok := cfg.Backends[cfg.Routes.Default]; !ok {
+		return fmt.Errorf("routes.default refers to unknown backend %q", cfg.Routes.Default)
+	}
+	for chatID, name := range cfg.Routes.Chats {
+		if _, ok := cfg.Backends[name]; !ok {
+			return fmt.Errorf("routes.chats.%s refers to unknown backend %q", chatID, name)
+		}
+	}
+	for prefix, name := range cfg.Routes.Commands {
+		if _, ok := cfg.Backends[name]; !ok {
+			return fmt.Errorf("routes.commands.%s refers to unknown backend %q", prefix, name)
+		}
+	}
+	return nil
+}
+
+// validateBackend checks a backend section and fills in defaults
+func validateBackend(b *BackendConfig) error {
+	switch b.Type {
+	case "":
+		b.Type = "clawdbot"
+	case "clawdbot":
+	case "anthropic":
+		if b.BaseURL == "" {
+			b.BaseURL = "https://api.anthropic.com"
+		}
+		if b.Model == "" {
+			b.Model = "claude-sonnet-4-5"
+		}
+	case "openai":
+		if b.BaseURL == "" {
+			b.BaseURL = "https://api.openai.com/v1"
+		}
+		if b.Model == "" {
+			b.Model = "gpt-4o-mini"
+		}
+	case "ollama":
+		if b.BaseURL == "" {
+			b.BaseURL = "http://127.0.0.1:11434"
+		}
+		if b.Model == "" {
+			b.Model = "qwen2.5"
+		}
+	case "sse":
+		if b.BaseURL == "" {
+			return fmt.Errorf("backend.base_url is required in bridge.json for backend type \"sse\"")
+		}
+	case "replay":
+		if b.Recordings == "" {
+			return fmt.Errorf("backend.recordings is required in bridge.json for backend type \"replay\"")
+		}
+	case "embedded":
+		// Supplied in code by a program embedding the bridge
+	default:
+		return fmt.Errorf("unknown backend.type %q in bridge.json (expected clawdbot, anthropic, openai, ollama, sse, replay or embedded)", b.Type)
+	}
+	if (b.Type == "anthropic" || b.Type == "openai") && b.APIKey == "" {
+		return fmt.Errorf("backend.api_key is required in bridge.json for backend type %q", b.Type)
+	}
+	if b.MaxTokens == 0 {
+		b.MaxTokens = 4096
+	}
+	return nil
+}
+
+// orDefault returns v, or def when v is unset
+func orDefault(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+// parseWeekday parses an English weekday name such as "monday"
+func parseWeekday(name string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), name) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday: %s", name)
+}
+
+// loadReport converts a report job, posting to alertChat unless it names
+// a chat
+func loadReport(r reportJSON, alertChat string) (ReportConfig, error) {
+	report := ReportConfig{Period: r.Period, Hour: 9, Chat: r.Chat, CSV: r.CSV}
+	if r.Period != "daily" && r.Period != "monthly" {
+		return report, fmt.Errorf("period must be daily or monthly, got %q", r.Period)
+	}
+	if r.Hour != nil {
+		if *r.Hour < 0 || *r.Hour > 23 {
+			return report, fmt.Errorf("hour must be 0-23, got %d", *r.Hour)
+		}
+		report.Hour = *r.Hour
+	}
+	if report.Chat == "" {
+		report.Chat = alertChat
+	}
+	if report.Chat == "" {
+		return report, fmt.Errorf("chat is required (or set alerts.chat_id)")
+	}
+	return report, nil
+}
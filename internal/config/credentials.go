@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Credentials are the secrets the bridge can rotate while running
+type Credentials struct {
+	AppSecret string
+	// GatewayToken is only set when bridge.json overrides the gateway token
+	GatewayToken string
+}
+
+// Credentials returns the secrets this config was loaded with
+func (c *Config) Credentials() Credentials {
+	creds := Credentials{AppSecret: c.Feishu.AppSecret}
+	if c.Clawdbot.TokenFromBridge {
+		creds.GatewayToken = c.Clawdbot.GatewayToken
+	}
+	return creds
+}
+
+// CredentialFiles lists the files credentials are read from, so they can
+// be watched for rotation
+func (c *Config) CredentialFiles() []string {
+	var paths []string
+	for _, p := range []string{c.BridgePath, c.Feishu.AppSecretFile, c.Clawdbot.TokenFile} {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// readSecretFile reads a secret written to its own file, ignoring
+// surrounding whitespace. The error never includes the file's content.
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return secret, nil
+}
+
+// Secrets lists every credential value in the config, so it can be masked
+// in logs and error messages
+func (c *Config) Secrets() []string {
+	secrets := []string{
+		c.Feishu.AppSecret,
+		c.Clawdbot.GatewayToken,
+		c.Redis.Password,
+		c.Observability.InfluxDB.Token,
+		c.Observability.Sentry.DSN,
+		c.Alerts.Webhook,
+		c.Alerts.HeartbeatURL,
+		c.Admin.Token,
+		c.Helpdesk.Token,
+	}
+	for _, b := range c.Backends {
+		secrets = append(secrets, b.APIKey)
+	}
+	for _, p := range []string{c.Feishu.Proxy, c.Feishu.ConnectionProxy, c.Clawdbot.Proxy} {
+		if u, err := url.Parse(p); err == nil && u.User != nil {
+			password, _ := u.User.Password()
+			secrets = append(secrets, password)
+		}
+	}
+	for _, v := range c.Observability.Tracing.Headers {
+		secrets = append(secrets, v)
+	}
+	return secrets
+}
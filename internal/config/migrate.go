@@ -0,0 +1,377 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// gatewayFiles are the gateway's own config files, which tell which
+// directory the gateway uses
+var gatewayFiles = []string{"clawdbot.json", "openclaw.json"}
+
+// bridgeFiles are the entries of a config directory the bridge owns.
+// Only these are migrated; everything else belongs to the gateway or
+// the user and stays where it is.
+var bridgeFiles = []string{
+	"bridge.json", "bridge-state.json", "bridge-state.json.lock", "bridge-status.json", "bridge.log",
+	"shadow.jsonl", "transcripts", "events", "audit", "shadow",
+}
+
+// Migration brings the config directories to the current layout: one
+// directory, next to the gateway's config, holding everything the bridge
+// keeps. Without it both directories may hold a bridge.json and Dir picks
+// ~/.clawdbot, so after the gateway moves to ~/.openclaw the bridge would
+// keep reading stale settings from the old directory.
+type Migration struct {
+	// Dir is the config directory to keep
+	Dir string
+	// From is the other directory, whose bridge files are moved out, or
+	// empty when only one exists
+	From string
+	// Reason says why Dir was kept over From
+	Reason string
+	// Moves lists the bridge files of From copied into Dir
+	Moves []string
+	// Conflicts lists the bridge files of From that Dir already has; the
+	// old copies are only kept in the backup
+	Conflicts []string
+	// Skipped lists the other entries of From, such as the gateway's
+	// config and state, which stay where they are
+	Skipped []string
+	// Rewrites lists bridge.json values pointing into From
+	Rewrites []FieldChange
+	// Unknown lists bridge.json keys the bridge doesn't read, e.g. typos
+	// or settings since removed; they're left as they are
+	Unknown []string
+}
+
+// FieldChange is a bridge.json value changed by a migration
+type FieldChange struct {
+	Key      string
+	Old, New string
+}
+
+// Empty reports whether the migration changes nothing
+func (m *Migration) Empty() bool {
+	return len(m.Moves) == 0 && len(m.Conflicts) == 0 && len(m.Rewrites) == 0
+}
+
+// PlanMigration inspects the config directories under home and returns
+// what Apply would change
+func PlanMigration(home string) (*Migration, error) {
+	clawdbot := filepath.Join(home, ".clawdbot")
+	openclaw := filepath.Join(home, ".openclaw")
+
+	m := &Migration{}
+	switch cb, oc := isDir(clawdbot), isDir(openclaw); {
+	case cb && oc:
+		m.Dir, m.From, m.Reason = pickConfigDir(clawdbot, openclaw)
+	case oc:
+		m.Dir = openclaw
+	default:
+		m.Dir = clawdbot
+	}
+
+	if m.From != "" {
+		entries, err := os.ReadDir(m.From)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", m.From, err)
+		}
+		for _, e := range entries {
+			name := e.Name()
+			switch {
+			case !isBridgeFile(name):
+				m.Skipped = append(m.Skipped, name)
+			case exists(filepath.Join(m.Dir, name)):
+				m.Conflicts = append(m.Conflicts, name)
+			default:
+				m.Moves = append(m.Moves, name)
+			}
+		}
+	}
+
+	bridgePath := filepath.Join(m.Dir, "bridge.json")
+	if !exists(bridgePath) && m.From != "" {
+		bridgePath = filepath.Join(m.From, "bridge.json")
+	}
+	data, err := os.ReadFile(bridgePath)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", bridgePath, err)
+	}
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", bridgePath, err)
+	}
+	unknownKeys("", raw, reflect.TypeOf(bridgeJSON{}), &m.Unknown)
+	if m.From != "" {
+		rewritePaths("", raw, m.From, m.Dir, &m.Rewrites)
+	}
+	return m, nil
+}
+
+// pickConfigDir decides which of two existing config directories to keep:
+// the one with the gateway's config, or the newer one if both have it
+func pickConfigDir(clawdbot, openclaw string) (dir, from, reason string) {
+	cbTime, cbOK := gatewayFileTime(clawdbot)
+	ocTime, ocOK := gatewayFileTime(openclaw)
+	switch {
+	case ocOK && !cbOK:
+		return openclaw, clawdbot, "only " + openclaw + " has the gateway's config"
+	case cbOK && !ocOK:
+		return clawdbot, openclaw, "only " + clawdbot + " has the gateway's config"
+	case ocOK && ocTime.After(cbTime):
+		return openclaw, clawdbot, "the gateway's config in " + openclaw + " is newer"
+	case ocOK:
+		return clawdbot, openclaw, "the gateway's config in " + clawdbot + " is newer"
+	default:
+		return clawdbot, openclaw, "neither has the gateway's config and " + clawdbot + " is read first"
+	}
+}
+
+// gatewayFileTime returns when dir's gateway config was last changed
+func gatewayFileTime(dir string) (time.Time, bool) {
+	var latest time.Time
+	found := false
+	for _, name := range gatewayFiles {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			found = true
+			if info.ModTime().After(latest) {
+				latest = info.ModTime()
+			}
+		}
+	}
+	return latest, found
+}
+
+func isBridgeFile(name string) bool {
+	for _, f := range bridgeFiles {
+		if name == f {
+			return true
+		}
+	}
+	return false
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// unknownKeys appends the keys of v, decoded from JSON, that t has no
+// field for
+func unknownKeys(prefix string, v interface{}, t reflect.Type, out *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := jsonFields(t)
+			for _, key := range sortedKeys(val) {
+				ft, ok := fields[key]
+				if !ok {
+					*out = append(*out, joinKey(prefix, key))
+					continue
+				}
+				unknownKeys(joinKey(prefix, key), val[key], ft, out)
+			}
+		case reflect.Map:
+			for _, key := range sortedKeys(val) {
+				unknownKeys(joinKey(prefix, key), val[key], t.Elem(), out)
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice {
+			for i, item := range val {
+				unknownKeys(fmt.Sprintf("%s[%d]", prefix, i), item, t.Elem(), out)
+			}
+		}
+	}
+}
+
+// jsonFields maps the JSON keys of struct t to their field types
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// rewritePaths records the string values of v that are from or a path
+// inside it, and changes them to point into to
+func rewritePaths(prefix string, v interface{}, from, to string, out *[]FieldChange) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(val) {
+			if s, ok := val[key].(string); ok {
+				if moved, ok := movePath(s, from, to); ok {
+					val[key] = moved
+					*out = append(*out, FieldChange{Key: joinKey(prefix, key), Old: s, New: moved})
+				}
+				continue
+			}
+			rewritePaths(joinKey(prefix, key), val[key], from, to, out)
+		}
+	case []interface{}:
+		for i, item := range val {
+			key := fmt.Sprintf("%s[%d]", prefix, i)
+			if s, ok := item.(string); ok {
+				if moved, ok := movePath(s, from, to); ok {
+					val[i] = moved
+					*out = append(*out, FieldChange{Key: key, Old: s, New: moved})
+				}
+				continue
+			}
+			rewritePaths(key, item, from, to, out)
+		}
+	}
+}
+
+// movePath returns path relocated from one directory to another, if it
+// is one of the bridge files in from or inside one
+func movePath(path, from, to string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, from+string(filepath.Separator))
+	if !ok {
+		return "", false
+	}
+	first, _, _ := strings.Cut(rest, string(filepath.Separator))
+	if !isBridgeFile(first) {
+		return "", false
+	}
+	return filepath.Join(to, rest), true
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Apply carries out the migration. The bridge files of From are moved
+// into a backup directory next to it once copied, leaving From with the
+// gateway's files and anything else, and bridge.json is backed up before
+// it is rewritten; the backup paths are returned.
+func (m *Migration) Apply(now time.Time) ([]string, error) {
+	suffix := ".bak-" + now.Format("20060102-150405")
+	var backups []string
+
+	if retired := append(append([]string(nil), m.Moves...), m.Conflicts...); len(retired) > 0 {
+		if err := os.MkdirAll(m.Dir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", m.Dir, err)
+		}
+		for _, name := range m.Moves {
+			if err := copyTree(filepath.Join(m.From, name), filepath.Join(m.Dir, name)); err != nil {
+				return nil, fmt.Errorf("failed to copy %s: %w", name, err)
+			}
+		}
+		backup := m.From + suffix
+		if err := os.MkdirAll(backup, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", backup, err)
+		}
+		backups = append(backups, backup)
+		for _, name := range retired {
+			if err := os.Rename(filepath.Join(m.From, name), filepath.Join(backup, name)); err != nil {
+				return backups, fmt.Errorf("failed to move %s aside: %w", name, err)
+			}
+		}
+	}
+
+	if len(m.Rewrites) > 0 {
+		path := filepath.Join(m.Dir, "bridge.json")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return backups, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if err := os.WriteFile(path+suffix, data, 0600); err != nil {
+			return backups, fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+		backups = append(backups, path+suffix)
+
+		var raw interface{}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return backups, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		rewritePaths("", raw, m.From, m.Dir, new([]FieldChange))
+		out, err := json.MarshalIndent(raw, "", "  ")
+		if err != nil {
+			return backups, err
+		}
+		if err := os.WriteFile(path, append(out, '\n'), 0600); err != nil {
+			return backups, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return backups, nil
+}
+
+// copyTree copies the file, symlink or directory at src to dst, keeping
+// permissions
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case !d.Type().IsRegular():
+			// Sockets and the like mean nothing once the bridge is stopped
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+}
@@ -0,0 +1,61 @@
+package config
+
+import "fmt"
+
+// SheetsConfig lets the bridge read and append to Feishu spreadsheets
+// linked in chats
+type SheetsConfig struct {
+	Enabled bool
+	// Read and Write list the spreadsheet tokens that may be read or
+	// appended to; "*" stands for any. Writable spreadsheets are also
+	// readable.
+	Read  []string
+	Write []string
+	// MaxRows bounds the rows read from a sheet or appended at once
+	MaxRows int
+}
+
+// CanRead reports whether the spreadsheet token may be read
+func (s SheetsConfig) CanRead(token string) bool {
+	return s.Enabled && (listsToken(s.Read, token) || listsToken(s.Write, token))
+}
+
+// CanWrite reports whether rows may be appended to the spreadsheet token
+func (s SheetsConfig) CanWrite(token string) bool {
+	return s.Enabled && listsToken(s.Write, token)
+}
+
+func listsToken(list []string, token string) bool {
+	for _, t := range list {
+		if t == "*" || t == token {
+			return true
+		}
+	}
+	return false
+}
+
+// sheetsJSON matches the "sheets" section of bridge.json
+type sheetsJSON struct {
+	Enabled bool     `json:"enabled"`
+	Read    []string `json:"read,omitempty"`
+	Write   []string `json:"write,omitempty"`
+	MaxRows int      `json:"max_rows,omitempty"`
+}
+
+func (s sheetsJSON) toConfig() SheetsConfig {
+	return SheetsConfig{
+		Enabled: s.Enabled,
+		Read:    s.Read,
+		Write:   s.Write,
+		MaxRows: orDefault(s.MaxRows, 100),
+	}
+}
+
+// validateSheets requires at least one spreadsheet scope when sheets are
+// enabled
+func validateSheets(cfg *Config) error {
+	if cfg.Sheets.Enabled && len(cfg.Sheets.Read) == 0 && len(cfg.Sheets.Write) == 0 {
+		return fmt.Errorf("sheets.read or sheets.write must list spreadsheet tokens (or \"*\") when sheets are enabled")
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ShellConfig enables /sh, which runs allowlisted commands on the bridge
+// host for admins
+type ShellConfig struct {
+	Enabled bool
+	// Allow lists the permitted command prefixes, compared word by word:
+	// "kubectl get" permits "kubectl get pods" but not "kubectl delete"
+	Allow [][]string
+	// Users, if set, limits /sh to these admins
+	Users []string
+	// Groups lets /sh run in group chats, where every member sees the output
+	Groups bool
+	// Dir is the working directory; empty means the bridge's own
+	Dir     string
+	Timeout time.Duration
+	// MaxOutput bounds the output sent back, in characters
+	MaxOutput int
+}
+
+// Permits reports whether the command args starts with an allowed prefix
+func (s ShellConfig) Permits(args []string) bool {
+	for _, prefix := range s.Allow {
+		if len(args) < len(prefix) {
+			continue
+		}
+		match := true
+		for i, word := range prefix {
+			if args[i] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// MayRun reports whether admin openID may use /sh
+func (s ShellConfig) MayRun(openID string) bool {
+	if len(s.Users) == 0 {
+		return true
+	}
+	for _, id := range s.Users {
+		if id == openID {
+			return true
+		}
+	}
+	return false
+}
+
+// shellJSON matches the "shell" section of bridge.json
+type shellJSON struct {
+	Enabled        bool     `json:"enabled"`
+	Allow          []string `json:"allow,omitempty"`
+	Users          []string `json:"users,omitempty"`
+	Groups         bool     `json:"groups"`
+	Dir            string   `json:"dir,omitempty"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"`
+	MaxOutput      int      `json:"max_output,omitempty"`
+}
+
+func (s shellJSON) toConfig() ShellConfig {
+	cfg := ShellConfig{
+		Enabled:   s.Enabled,
+		Users:     s.Users,
+		Groups:    s.Groups,
+		Dir:       s.Dir,
+		Timeout:   time.Duration(orDefault(s.TimeoutSeconds, 10)) * time.Second,
+		MaxOutput: orDefault(s.MaxOutput, 3000),
+	}
+	for _, prefix := range s.Allow {
+		cfg.Allow = append(cfg.Allow, strings.Fields(prefix))
+	}
+	return cfg
+}
+
+// validateShell requires an allowlist when /sh is enabled and that its
+// users are admins
+func validateShell(cfg *Config) error {
+	s := cfg.Shell
+	if !s.Enabled {
+		return nil
+	}
+	if len(s.Allow) == 0 {
+		return fmt.Errorf("shell.allow must list at least one command prefix when shell is enabled")
+	}
+	for _, prefix := range s.Allow {
+		if len(prefix) == 0 {
+			return fmt.Errorf("shell.allow: command prefixes must not be empty")
+		}
+	}
+	if len(cfg.Admins) == 0 {
+		return fmt.Errorf("shell is enabled but no admins are configured")
+	}
+	for _, id := range s.Users {
+		if !cfg.IsAdmin(id) {
+			return fmt.Errorf("shell.users: %s is not listed in admins", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ToolPolicy restricts which agent tools a chat may use
+type ToolPolicy struct {
+	// Allow, if set, lists the only tools the chat may use
+	Allow []string
+	// Deny lists tools the chat may never use; it wins over Allow
+	Deny []string
+}
+
+// Permits reports whether the policy lets the agent use tool.
+// Names are compared case-insensitively.
+func (p ToolPolicy) Permits(tool string) bool {
+	for _, name := range p.Deny {
+		if strings.EqualFold(name, tool) {
+			return false
+		}
+	}
+	if len(p.Allow) == 0 {
+		return true
+	}
+	for _, name := range p.Allow {
+		if strings.EqualFold(name, tool) {
+			return true
+		}
+	}
+	return false
+}
+
+// ToolPolicyFor returns the tool policy of chatID, and false when the
+// chat may use every tool
+func (c *Config) ToolPolicyFor(chatID string) (ToolPolicy, bool) {
+	p, ok := c.ChatTools[chatID]
+	return p, ok
+}
+
+// validateChatTools rejects empty tool names and policies that restrict nothing
+func validateChatTools(cfg *Config) error {
+	for chatID, p := range cfg.ChatTools {
+		if len(p.Allow) == 0 && len(p.Deny) == 0 {
+			return fmt.Errorf("chat_tools.%s: set allow or deny", chatID)
+		}
+		for _, name := range append(append([]string(nil), p.Allow...), p.Deny...) {
+			if strings.TrimSpace(name) == "" {
+				return fmt.Errorf("chat_tools.%s: tool names must not be empty", chatID)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+)
+
+// GatewaySettings are the connection settings read from clawdbot.json/openclaw.json
+type GatewaySettings struct {
+	Port  int
+	Token string
+}
+
+// WatchGateway polls the gateway config file and calls onChange with the
+// new settings whenever the port or token changes (e.g. after a gateway
+// upgrade rotates them). It returns when ctx is cancelled.
+func WatchGateway(ctx context.Context, path string, interval time.Duration, current GatewaySettings, onChange func(GatewaySettings)) {
+	var lastMod time.Time
+	var lastSize int64
+	if info, err := os.Stat(path); err == nil {
+		lastMod, lastSize = info.ModTime(), info.Size()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			continue // file may be mid-rewrite
+		}
+		if info.ModTime().Equal(lastMod) && info.Size() == lastSize {
+			continue
+		}
+		lastMod, lastSize = info.ModTime(), info.Size()
+
+		gwCfg, err := readGatewayFile(path)
+		if err != nil {
+			log.Printf("[Config] Ignoring unreadable gateway config: %v", err)
+			continue
+		}
+
+		next := GatewaySettings{Port: gwCfg.Gateway.Port, Token: gwCfg.Gateway.Auth.Token}
+		if next.Port == 0 {
+			next.Port = 18789
+		}
+		if next == current {
+			continue
+		}
+
+		log.Printf("[Config] Gateway config changed in %s (port %d -> %d, token rotated: %v)",
+			path, current.Port, next.Port, next.Token != current.Token)
+		current = next
+		onChange(next)
+	}
+}
+
+// WatchFiles polls paths and calls onChange once per poll in which any of
+// them was modified. It returns when ctx is cancelled.
+func WatchFiles(ctx context.Context, paths []string, interval time.Duration, onChange func()) {
+	type stamp struct {
+		mod  time.Time
+		size int64
+	}
+	last := make(map[string]stamp, len(paths))
+	for _, p := range paths {
+		if info, err := os.Stat(p); err == nil {
+			last[p] = stamp{info.ModTime(), info.Size()}
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed := false
+		for _, p := range paths {
+			info, err := os.Stat(p)
+			if err != nil {
+				continue // file may be mid-rewrite
+			}
+			if st := (stamp{info.ModTime(), info.Size()}); st != last[p] {
+				last[p] = st
+				changed = true
+			}
+		}
+		if changed {
+			onChange()
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Package deadletter keeps final replies the bridge couldn't deliver, so
+// they can be retried or dropped by hand instead of being lost. Letters
+// live in the bridge's state store, so every instance sharing it sees
+// them. Their text is sealed with the keyring when encryption is
+// configured.
+package deadletter
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/seal"
+	"github.com/wy51ai/moltbotCNAPP/internal/store"
+)
+
+// bucket is the store bucket letters are kept in, by ID
+const bucket = "dead_letter"
+
+// MaxLetters bounds the stored letters; the oldest go first
+const MaxLetters = 500
+
+// ErrNotFound is returned for an unknown letter ID
+var ErrNotFound = errors.New("dead letter not found")
+
+// Letter is a reply that couldn't be delivered
+type Letter struct {
+	ID     string `json:"id"`
+	ChatID string `json:"chat_id"`
+	// UserID is who the reply answered, if known
+	UserID string `json:"user_id,omitempty"`
+	// Text is sealed in the store when encryption is configured
+	Text string `json:"text"`
+	// Post is set for replies sent as rich text
+	Post bool `json:"post,omitempty"`
+	// Error is the last delivery failure
+	Error  string    `json:"error"`
+	Failed time.Time `json:"failed"`
+	// Attempts counts manual retries that failed too
+	Attempts      int    `json:"attempts,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// Add stores l under a new ID, its text sealed with keys, and returns it
+// as given
+func Add(st store.KV, keys *seal.Keyring, l Letter) (Letter, error) {
+	id, err := newID()
+	if err != nil {
+		return Letter{}, err
+	}
+	l.ID = id
+	if l.Failed.IsZero() {
+		l.Failed = time.Now()
+	}
+
+	letters, err := stored(st)
+	if err != nil {
+		return Letter{}, err
+	}
+	for len(letters) >= MaxLetters {
+		log.Printf("[DeadLetter] Store full, dropping %s for %s", letters[0].ID, letters[0].ChatID)
+		if err := st.Delete(bucket, letters[0].ID); err != nil {
+			return Letter{}, fmt.Errorf("failed to drop oldest dead letter: %w", err)
+		}
+		letters = letters[1:]
+	}
+
+	if err := save(st, keys, l); err != nil {
+		return Letter{}, err
+	}
+	return l, nil
+}
+
+// save stores l with its text sealed
+func save(st store.KV, keys *seal.Keyring, l Letter) error {
+	l.Text = string(keys.Seal([]byte(l.Text)))
+	if err := st.Set(bucket, l.ID, l); err != nil {
+		return fmt.Errorf("failed to save dead letter: %w", err)
+	}
+	return nil
+}
+
+// open decrypts the text of a stored letter. Letters kept before
+// encryption was enabled are returned as they are.
+func open(keys *seal.Keyring, l Letter) (Letter, error) {
+	text, err := keys.Open([]byte(l.Text))
+	if err != nil {
+		return Letter{}, fmt.Errorf("failed to decrypt dead letter %s: %w", l.ID, err)
+	}
+	l.Text = string(text)
+	return l, nil
+}
+
+// newID returns a random letter ID
+func newID() (string, error) {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate dead letter ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// List returns all stored letters, oldest first, with their text
+// decrypted with keys
+func List(st store.KV, keys *seal.Keyring) ([]Letter, error) {
+	letters, err := stored(st)
+	if err != nil {
+		return nil, err
+	}
+	for i, l := range letters {
+		if letters[i], err = open(keys, l); err != nil {
+			return nil, err
+		}
+	}
+	return letters, nil
+}
+
+// stored returns all letters as stored, oldest first
+func stored(st store.KV) ([]Letter, error) {
+	var letters []Letter
+	for _, key := range st.Keys(bucket) {
+		var l Letter
+		ok, err := st.Get(bucket, key, &l)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			letters = append(letters, l)
+		}
+	}
+	sort.Slice(letters, func(i, j int) bool { return letters[i].Failed.Before(letters[j].Failed) })
+	return letters, nil
+}
+
+// Get returns the letter with id, its text decrypted with keys
+func Get(st store.KV, keys *seal.Keyring, id string) (Letter, error) {
+	var l Letter
+	ok, err := st.Get(bucket, id, &l)
+	if err != nil {
+		return Letter{}, err
+	}
+	if !ok {
+		return Letter{}, ErrNotFound
+	}
+	return open(keys, l)
+}
+
+// Drop deletes the letter with id
+func Drop(st store.KV, id string) error {
+	var l Letter
+	ok, err := st.Get(bucket, id, &l)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	if err := st.Delete(bucket, id); err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	return nil
+}
+
+// RetryFailed records that delivering l failed again with err
+func RetryFailed(st store.KV, keys *seal.Keyring, l Letter, err error, now time.Time) error {
+	l.Attempts++
+	l.Error = err.Error()
+	l.Failed = now
+	return save(st, keys, l)
+}
+
+// Rekey re-saves every letter whose text isn't sealed with the active
+// key, and returns how many were rewritten
+func Rekey(st store.KV, keys *seal.Keyring) (int, error) {
+	letters, err := stored(st)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, l := range letters {
+		if id, ok := seal.KeyID([]byte(l.Text)); ok && id == keys.Active() {
+			continue
+		}
+		if l, err = open(keys, l); err != nil {
+			return n, err
+		}
+		if err := save(st, keys, l); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Expire deletes the letters that last failed before cutoff and returns
+// how many there were. With dryRun nothing is deleted, only counted.
+func Expire(st store.KV, cutoff time.Time, dryRun bool) (int, error) {
+	letters, err := stored(st)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, l := range letters {
+		if !l.Failed.Before(cutoff) {
+			break
+		}
+		if !dryRun {
+			if err := st.Delete(bucket, l.ID); err != nil {
+				return n, fmt.Errorf("failed to delete dead letter: %w", err)
+			}
+		}
+		n++
+	}
+	return n, nil
+}
+
+// DeleteUser deletes the letters answering userID and returns how many
+// were deleted
+func DeleteUser(st store.KV, userID string) (int, error) {
+	letters, err := stored(st)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, l := range letters {
+		if l.UserID != userID {
+			continue
+		}
+		if err := st.Delete(bucket, l.ID); err != nil {
+			return n, fmt.Errorf("failed to delete dead letter: %w", err)
+		}
+		n++
+	}
+	return n, nil
+}
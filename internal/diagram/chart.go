@@ -0,0 +1,318 @@
+package diagram
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+)
+
+// vegaLiteSchema is stamped on the specs built here
+const vegaLiteSchema = "https://vega.github.io/schema/vega-lite/v5.json"
+
+// chartKeywords in a user's message ask for their data to be drawn
+var chartKeywords = []string{"图表", "画图", "画个图", "画一下", "作图", "可视化", "折线图", "柱状图", "趋势图",
+	"chart", "plot", "graph", "visualize", "visualise"}
+
+// Chart is the simple spec an agent can write in a ```chart block:
+//
+//	{"type": "line", "title": "QPS", "x": ["10:00", "10:05"],
+//	 "series": [{"name": "api", "values": [120, 135]}]}
+//
+// A ```chart block holding a full Vega-Lite spec (one with "mark") is
+// rendered as it is.
+type Chart struct {
+	// Type is the mark: bar (default), line, area or point
+	Type   string        `json:"type,omitempty"`
+	Title  string        `json:"title,omitempty"`
+	XLabel string        `json:"x_label,omitempty"`
+	YLabel string        `json:"y_label,omitempty"`
+	X      []interface{} `json:"x"`
+	Series []ChartSeries `json:"series"`
+}
+
+// ChartSeries is one named line or set of bars
+type ChartSeries struct {
+	Name   string    `json:"name,omitempty"`
+	Values []float64 `json:"values"`
+}
+
+// Charts turns chart specs and CSV data into PNG images
+type Charts struct {
+	cfg        config.ChartConfig
+	httpClient *http.Client
+}
+
+// NewCharts returns a Charts, or nil when no renderer is configured. A
+// nil Charts finds no charts.
+func NewCharts(cfg config.ChartConfig) *Charts {
+	if !cfg.Enabled() {
+		return nil
+	}
+	return &Charts{cfg: cfg, httpClient: &http.Client{}}
+}
+
+// Find returns Vega-Lite specs for the ```chart blocks in reply and, when
+// prompt asks for a chart, for the CSV data in reply or prompt; up to the
+// configured limit. Blocks that don't parse are skipped.
+func (c *Charts) Find(prompt, reply string) [][]byte {
+	if c == nil {
+		return nil
+	}
+	var specs [][]byte
+	for _, block := range fencedBlocks(reply, "chart") {
+		if spec, err := chartSpec(block); err == nil {
+			specs = append(specs, spec)
+		}
+	}
+	if len(specs) == 0 && wantsChart(prompt) {
+		data := fencedBlocks(reply, "csv")
+		if len(data) == 0 {
+			data = fencedBlocks(prompt, "csv")
+		}
+		if len(data) == 0 {
+			if run := csvRun(prompt); run != "" {
+				data = []string{run}
+			}
+		}
+		for _, block := range data {
+			if spec, err := csvSpec(block); err == nil {
+				specs = append(specs, spec)
+			}
+		}
+	}
+	if len(specs) > c.cfg.MaxCharts {
+		specs = specs[:c.cfg.MaxCharts]
+	}
+	return specs
+}
+
+// Render returns a Vega-Lite spec rendered as a PNG
+func (c *Charts) Render(ctx context.Context, spec []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	if c.cfg.URL != "" {
+		return renderHTTP(ctx, c.httpClient, c.cfg.URL, "application/json", string(spec))
+	}
+
+	args := strings.Fields(c.cfg.Command)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(spec)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to run %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("%s wrote no image", args[0])
+	}
+	if stdout.Len() > maxImageBytes {
+		return nil, fmt.Errorf("rendered chart is %d bytes, over the %d byte limit", stdout.Len(), maxImageBytes)
+	}
+	return stdout.Bytes(), nil
+}
+
+// wantsChart reports whether a user's message asks for a chart
+func wantsChart(prompt string) bool {
+	lower := strings.ToLower(prompt)
+	for _, kw := range chartKeywords {
+		if strings.Contains(lower, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// chartSpec parses a ```chart block into a Vega-Lite spec
+func chartSpec(block string) ([]byte, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(block), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse chart: %w", err)
+	}
+	if _, ok := raw["mark"]; ok {
+		return []byte(block), nil
+	}
+
+	var ch Chart
+	if err := json.Unmarshal([]byte(block), &ch); err != nil {
+		return nil, fmt.Errorf("failed to parse chart: %w", err)
+	}
+	return ch.vegaLite()
+}
+
+// vegaLite builds the Vega-Lite spec for ch
+func (ch Chart) vegaLite() ([]byte, error) {
+	if len(ch.X) == 0 || len(ch.Series) == 0 {
+		return nil, fmt.Errorf("chart needs x values and at least one series")
+	}
+	mark := ch.Type
+	switch mark {
+	case "":
+		mark = "bar"
+	case "bar", "line", "area", "point":
+	default:
+		return nil, fmt.Errorf("unsupported chart type %q", ch.Type)
+	}
+
+	var values []map[string]interface{}
+	for i, s := range ch.Series {
+		name := s.Name
+		if name == "" {
+			name = fmt.Sprintf("series %d", i+1)
+		}
+		for j, v := range s.Values {
+			if j >= len(ch.X) {
+				break
+			}
+			values = append(values, map[string]interface{}{"x": fmt.Sprint(ch.X[j]), "series": name, "value": v})
+		}
+	}
+
+	encoding := map[string]interface{}{
+		// Keep the x values in the order given rather than sorted
+		"x": map[string]interface{}{"field": "x", "type": "ordinal", "sort": nil, "title": titleOrNil(ch.XLabel)},
+		"y": map[string]interface{}{"field": "value", "type": "quantitative", "title": titleOrNil(ch.YLabel)},
+	}
+	if len(ch.Series) > 1 {
+		encoding["color"] = map[string]interface{}{"field": "series", "type": "nominal", "title": nil}
+		if mark == "bar" {
+			encoding["xOffset"] = map[string]interface{}{"field": "series"}
+		}
+	}
+	markDef := map[string]interface{}{"type": mark}
+	if mark == "line" {
+		markDef["point"] = true
+	}
+
+	spec := map[string]interface{}{
+		"$schema":    vegaLiteSchema,
+		"width":      600,
+		"height":     300,
+		"background": "white",
+		"data":       map[string]interface{}{"values": values},
+		"mark":       markDef,
+		"encoding":   encoding,
+	}
+	if ch.Title != "" {
+		spec["title"] = ch.Title
+	}
+	return json.Marshal(spec)
+}
+
+// titleOrNil leaves an axis untitled rather than titled with the field name
+func titleOrNil(title string) interface{} {
+	if title == "" {
+		return nil
+	}
+	return title
+}
+
+// csvSpec charts CSV data with a header row: the first column labels the
+// x axis and each numeric column becomes a series
+func csvSpec(data string) ([]byte, error) {
+	r := csv.NewReader(strings.NewReader(data))
+	r.Comma = ','
+	if !strings.Contains(data, ",") && strings.Contains(data, "\t") {
+		r.Comma = '\t'
+	}
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse csv: %w", err)
+	}
+	if len(rows) < 3 || len(rows[0]) < 2 {
+		return nil, fmt.Errorf("csv needs a header, two rows and two columns")
+	}
+
+	header, body := rows[0], rows[1:]
+	ch := Chart{Type: "bar", XLabel: strings.TrimSpace(header[0])}
+	for _, row := range body {
+		ch.X = append(ch.X, strings.TrimSpace(row[0]))
+	}
+	for col := 1; col < len(header); col++ {
+		values := make([]float64, 0, len(body))
+		for _, row := range body {
+			v, ok := parseNumber(row[col])
+			if !ok {
+				break
+			}
+			values = append(values, v)
+		}
+		// Columns with text in them aren't data to draw
+		if len(values) == len(body) {
+			ch.Series = append(ch.Series, ChartSeries{Name: strings.TrimSpace(header[col]), Values: values})
+		}
+	}
+	if len(ch.Series) == 0 {
+		return nil, fmt.Errorf("csv has no numeric columns")
+	}
+	if len(body) > 12 || looksLikeTime(ch.XLabel) {
+		ch.Type = "line"
+	}
+	if len(ch.Series) == 1 {
+		ch.YLabel = ch.Series[0].Name
+	}
+	return ch.vegaLite()
+}
+
+// parseNumber reads a CSV cell as a number, allowing thousands
+// separators and a trailing percent sign
+func parseNumber(cell string) (float64, bool) {
+	cell = strings.TrimSuffix(strings.ReplaceAll(strings.TrimSpace(cell), ",", ""), "%")
+	v, err := strconv.ParseFloat(cell, 64)
+	return v, err == nil
+}
+
+// looksLikeTime reports whether a column header names a time axis, which
+// reads better as a line
+func looksLikeTime(header string) bool {
+	lower := strings.ToLower(header)
+	for _, kw := range []string{"时间", "日期", "月", "周", "年", "time", "date", "day", "week", "month", "year", "hour"} {
+		if strings.Contains(lower, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// csvRun finds CSV or tab-separated data pasted into a message without a
+// fence: the longest run of at least three lines with the same number of
+// fields
+func csvRun(text string) string {
+	var best, current []string
+	fields := 0
+	flush := func() {
+		if len(current) > len(best) {
+			best = current
+		}
+		current, fields = nil, 0
+	}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		sep := ","
+		if !strings.Contains(line, ",") {
+			sep = "\t"
+		}
+		n := strings.Count(line, sep) + 1
+		if n < 2 {
+			flush()
+			continue
+		}
+		if len(current) > 0 && n != fields {
+			flush()
+		}
+		current, fields = append(current, line), n
+	}
+	flush()
+	if len(best) < 3 {
+		return ""
+	}
+	return strings.Join(best, "\n")
+}
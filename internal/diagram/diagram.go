@@ -0,0 +1,155 @@
+// Package diagram renders Mermaid diagrams and simple charts found in
+// chat messages to PNG images, with a local binary or an HTTP rendering
+// service.
+package diagram
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+)
+
+// maxImageBytes caps a rendered image; Feishu rejects images over 10 MB
+const maxImageBytes = 10 << 20
+
+// Renderer turns Mermaid source into PNG images
+type Renderer struct {
+	cfg        config.MermaidConfig
+	httpClient *http.Client
+}
+
+// New returns a Renderer, or nil when no renderer is configured. A nil
+// Renderer finds no diagrams.
+func New(cfg config.MermaidConfig) *Renderer {
+	if !cfg.Enabled() {
+		return nil
+	}
+	return &Renderer{cfg: cfg, httpClient: &http.Client{}}
+}
+
+// Blocks returns the source of each ```mermaid block in text, up to the
+// configured limit
+func (r *Renderer) Blocks(text string) []string {
+	if r == nil {
+		return nil
+	}
+	blocks := fencedBlocks(text, "mermaid")
+	if len(blocks) > r.cfg.MaxDiagrams {
+		blocks = blocks[:r.cfg.MaxDiagrams]
+	}
+	return blocks
+}
+
+// fencedBlocks returns the trimmed content of each fenced code block in
+// text labelled lang. An unclosed block is left out; it's likely cut off.
+func fencedBlocks(text, lang string) []string {
+	var blocks []string
+	var current []string
+	inFence, matches := false, false
+	for _, line := range strings.Split(text, "\n") {
+		fence := strings.TrimSpace(line)
+		if !strings.HasPrefix(fence, "```") {
+			if inFence && matches {
+				current = append(current, line)
+			}
+			continue
+		}
+		if !inFence {
+			label := strings.Fields(strings.TrimPrefix(fence, "```"))
+			inFence, matches = true, len(label) > 0 && strings.EqualFold(label[0], lang)
+			current = nil
+			continue
+		}
+		if matches {
+			if source := strings.TrimSpace(strings.Join(current, "\n")); source != "" {
+				blocks = append(blocks, source)
+			}
+		}
+		inFence, matches = false, false
+	}
+	return blocks
+}
+
+// Render returns source rendered as a PNG
+func (r *Renderer) Render(ctx context.Context, source string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+	if r.cfg.URL != "" {
+		return renderHTTP(ctx, r.httpClient, r.cfg.URL, "text/plain", source)
+	}
+	return r.renderCommand(ctx, source)
+}
+
+// renderCommand runs the configured mermaid-cli compatible binary
+func (r *Renderer) renderCommand(ctx context.Context, source string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "mermaid-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	in, out := filepath.Join(dir, "diagram.mmd"), filepath.Join(dir, "diagram.png")
+	if err := os.WriteFile(in, []byte(source), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write diagram: %w", err)
+	}
+
+	// The command may carry its own flags, e.g. "mmdc -b white"
+	args := strings.Fields(r.cfg.Command)
+	args = append(args, "-i", in, "-o", out)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to run %s: %w: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+
+	image, err := os.ReadFile(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rendered diagram: %w", err)
+	}
+	if len(image) > maxImageBytes {
+		return nil, fmt.Errorf("rendered diagram is %d bytes, over the %d byte limit", len(image), maxImageBytes)
+	}
+	return image, nil
+}
+
+// renderHTTP posts source to a rendering service that replies with a PNG
+func renderHTTP(ctx context.Context, client *http.Client, url, contentType, source string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(source))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	return fetchImage(client, req)
+}
+
+// fetchImage sends req and returns the PNG the service replies with
+func fetchImage(client *http.Client, req *http.Request) ([]byte, error) {
+	req.Header.Set("Accept", "image/png")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call renderer: %w", err)
+	}
+	defer resp.Body.Close()
+
+	image, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rendered diagram: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body := string(image)
+		if len(body) > 200 {
+			body = body[:200]
+		}
+		return nil, fmt.Errorf("renderer returned %s: %s", resp.Status, strings.TrimSpace(body))
+	}
+	if len(image) > maxImageBytes {
+		return nil, fmt.Errorf("rendered diagram is over the %d byte limit", maxImageBytes)
+	}
+	return image, nil
+}
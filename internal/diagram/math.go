@@ -0,0 +1,119 @@
+package diagram
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+)
+
+// formulaPattern matches $$...$$ and \[...\] display math and \(...\)
+// inline math. Single dollars are left alone; they're too often prices.
+var formulaPattern = regexp.MustCompile(`(?s)\$\$(.+?)\$\$|\\\[(.+?)\\\]|\\\((.+?)\\\)`)
+
+// Formula is a TeX formula found in a reply
+type Formula struct {
+	// Source is the formula as written, delimiters included
+	Source string
+	TeX    string
+}
+
+// Math turns TeX formulas into PNG images
+type Math struct {
+	cfg        config.MathConfig
+	httpClient *http.Client
+}
+
+// NewMath returns a Math, or nil when no renderer is configured. A nil
+// Math finds no formulas.
+func NewMath(cfg config.MathConfig) *Math {
+	if !cfg.Enabled() {
+		return nil
+	}
+	return &Math{cfg: cfg, httpClient: &http.Client{}}
+}
+
+// Formulas returns the distinct formulas in text outside code blocks, up
+// to the configured limit
+func (m *Math) Formulas(text string) []Formula {
+	if m == nil {
+		return nil
+	}
+	var formulas []Formula
+	seen := make(map[string]bool)
+	for _, prose := range outsideCode(text) {
+		for _, match := range formulaPattern.FindAllStringSubmatch(prose, -1) {
+			tex := strings.TrimSpace(match[1] + match[2] + match[3])
+			if tex == "" || seen[match[0]] {
+				continue
+			}
+			seen[match[0]] = true
+			formulas = append(formulas, Formula{Source: match[0], TeX: tex})
+			if len(formulas) == m.cfg.MaxFormulas {
+				return formulas
+			}
+		}
+	}
+	return formulas
+}
+
+// outsideCode returns the stretches of text between fenced code blocks
+func outsideCode(text string) []string {
+	var parts, current []string
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if !inFence {
+				parts = append(parts, strings.Join(current, "\n"))
+				current = nil
+			}
+			inFence = !inFence
+			continue
+		}
+		if !inFence {
+			current = append(current, line)
+		}
+	}
+	return append(parts, strings.Join(current, "\n"))
+}
+
+// Render returns tex rendered as a PNG
+func (m *Math) Render(ctx context.Context, tex string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+
+	if m.cfg.URL != "" {
+		if !strings.Contains(m.cfg.URL, "{tex}") {
+			return renderHTTP(ctx, m.httpClient, m.cfg.URL, "text/plain", tex)
+		}
+		// Escape "+" too, which query strings would read as a space
+		escaped := strings.ReplaceAll(url.QueryEscape(tex), "+", "%20")
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.ReplaceAll(m.cfg.URL, "{tex}", escaped), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		return fetchImage(m.httpClient, req)
+	}
+
+	args := strings.Fields(m.cfg.Command)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(tex)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to run %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("%s wrote no image", args[0])
+	}
+	if stdout.Len() > maxImageBytes {
+		return nil, fmt.Errorf("rendered formula is %d bytes, over the %d byte limit", stdout.Len(), maxImageBytes)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,91 @@
+package errreport
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+)
+
+// Setup initializes reporting to a Sentry-compatible DSN. Without a DSN
+// Capture and Recover only log locally. The returned function flushes
+// pending events and should run before exit.
+func Setup(cfg config.SentryConfig, version string) (func(), error) {
+	if cfg.DSN == "" {
+		return func() {}, nil
+	}
+
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:              cfg.DSN,
+		Environment:      cfg.Environment,
+		Release:          "clawdbot-bridge@" + version,
+		SampleRate:       cfg.SampleRate,
+		AttachStacktrace: true,
+		BeforeSend:       redactEvent,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to init sentry: %w", err)
+	}
+
+	log.Printf("[ErrReport] Reporting errors to Sentry (environment=%s)", cfg.Environment)
+	return func() { sentry.Flush(5 * time.Second) }, nil
+}
+
+// hubFor returns a hub whose scope is tagged with the correlation fields of ctx
+func hubFor(ctx context.Context) *sentry.Hub {
+	hub := sentry.CurrentHub().Clone()
+	fields := logging.FieldsOf(ctx)
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		if fields.CorrelationID != "" {
+			scope.SetTag("cid", fields.CorrelationID)
+		}
+		if fields.ChatID != "" {
+			scope.SetTag("chat_id", fields.ChatID)
+		}
+		if fields.RunID != "" {
+			scope.SetTag("run_id", fields.RunID)
+		}
+	})
+	return hub
+}
+
+// Capture reports an error-level event for err, tagged with the chat,
+// run and correlation IDs of ctx
+func Capture(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	hubFor(ctx).CaptureException(err)
+}
+
+// Recover reports and swallows a panic so one bad message can't take the
+// bridge down. Call it deferred at the top of message-handling goroutines:
+//
+//	defer errreport.Recover(ctx, "processMessage")
+func Recover(ctx context.Context, where string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	logging.Printf(ctx, "[ErrReport] Recovered panic in %s: %v\n%s", where, r, debug.Stack())
+
+	hub := hubFor(ctx)
+	hub.RecoverWithContext(ctx, r)
+	hub.Flush(2 * time.Second)
+}
+
+// redactEvent masks credentials in an event's messages before it's sent
+func redactEvent(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+	event.Message = redact.String(event.Message)
+	for i := range event.Exception {
+		event.Exception[i].Value = redact.String(event.Exception[i].Value)
+	}
+	return event
+}
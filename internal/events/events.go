@@ -0,0 +1,213 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+)
+
+// Event types
+const (
+	MessageIn = "message_in"
+	Command   = "command"
+	RunStart  = "run_start"
+	RunEnd    = "run_end"
+	ToolCall  = "tool_call"
+	Delivery  = "delivery"
+	Error     = "error"
+
+	// ToolBlocked is a tool call stopped by the chat's tool policy
+	ToolBlocked = "tool_blocked"
+)
+
+// Event is one line of the events log
+type Event struct {
+	Time          time.Time `json:"time"`
+	Type          string    `json:"type"`
+	ChatID        string    `json:"chat_id,omitempty"`
+	CorrelationID string    `json:"cid,omitempty"`
+	RunID         string    `json:"run_id,omitempty"`
+	Backend       string    `json:"backend,omitempty"`
+	// Detail is a short human-readable description
+	Detail string `json:"detail,omitempty"`
+	// Fields carries type-specific values such as latency or tool name
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Log appends events to one JSONL file per day
+type Log struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// Open prepares dir for event files
+func Open(dir string) (*Log, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create events dir: %w", err)
+	}
+	return &Log{dir: dir}, nil
+}
+
+// Emit records ev, filling in the time and the chat, run and correlation
+// IDs carried by ctx when ev doesn't set them
+func (l *Log) Emit(ctx context.Context, ev Event) {
+	if l == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	ev.Detail = redact.String(ev.Detail)
+	fields := logging.FieldsOf(ctx)
+	if ev.CorrelationID == "" {
+		ev.CorrelationID = fields.CorrelationID
+	}
+	if ev.ChatID == "" {
+		ev.ChatID = fields.ChatID
+	}
+	if ev.RunID == "" {
+		ev.RunID = fields.RunID
+	}
+
+	line, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("[Events] Failed to encode event: %v", err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	path := filepath.Join(l.dir, ev.Time.Format("2006-01-02")+".jsonl")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Printf("[Events] Failed to open %s: %v", path, err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Printf("[Events] Failed to write event: %v", err)
+	}
+}
+
+// Filter selects events in a query; zero fields match everything
+type Filter struct {
+	Since         time.Time
+	ChatID        string
+	CorrelationID string
+	// Types, when set, limits results to these event types
+	Types []string
+}
+
+func (f Filter) match(ev Event) bool {
+	if ev.Time.Before(f.Since) {
+		return false
+	}
+	if f.ChatID != "" && ev.ChatID != f.ChatID {
+		return false
+	}
+	if f.CorrelationID != "" && ev.CorrelationID != f.CorrelationID {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if ev.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Query calls fn for each event in dir matching f, oldest first
+func Query(dir string, f Filter, fn func(Event)) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to list events: %w", err)
+	}
+
+	var names []string
+	sinceDay := f.Since.Format("2006-01-02")
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasSuffix(name, ".jsonl") && strings.TrimSuffix(name, ".jsonl") >= sinceDay {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := queryFile(filepath.Join(dir, name), f, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func queryFile(path string, f Filter, fn func(Event)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var ev Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		if f.match(ev) {
+			fn(ev)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
+
+// Format renders ev as a single log-style line
+func (ev Event) Format() string {
+	var sb strings.Builder
+	sb.WriteString(ev.Time.Format("2006-01-02 15:04:05.000"))
+	sb.WriteString(" " + ev.Type)
+	if ev.ChatID != "" {
+		sb.WriteString(" chat=" + ev.ChatID)
+	}
+	if ev.CorrelationID != "" {
+		sb.WriteString(" cid=" + ev.CorrelationID)
+	}
+	if ev.RunID != "" {
+		sb.WriteString(" run=" + ev.RunID)
+	}
+	if ev.Backend != "" {
+		sb.WriteString(" backend=" + ev.Backend)
+	}
+	keys := make([]string, 0, len(ev.Fields))
+	for k := range ev.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&sb, " %s=%v", k, ev.Fields[k])
+	}
+	if ev.Detail != "" {
+		sb.WriteString(": " + ev.Detail)
+	}
+	return sb.String()
+}
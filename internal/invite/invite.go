@@ -0,0 +1,184 @@
+// Package invite manages invitation codes users redeem before the bot
+// answers them in private chats. Codes and activations live in the
+// bridge's state store, so every instance sharing it sees them.
+package invite
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/store"
+)
+
+// Store buckets
+const (
+	codeBucket = "invite_code"
+	userBucket = "invite_user"
+)
+
+// alphabet leaves out characters easily mistaken for each other (0/O, 1/I/L)
+const alphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// codeLength is the number of characters in a generated code
+const codeLength = 8
+
+// Errors returned by Redeem
+var (
+	ErrUnknownCode = errors.New("invite code not found")
+	ErrExpired     = errors.New("invite code expired")
+	ErrUsedUp      = errors.New("invite code has no uses left")
+)
+
+// redeemMu serializes redemptions on this instance so a code's use
+// count isn't raced; instances sharing Redis may still overshoot by one
+var redeemMu sync.Mutex
+
+// Code is an invitation code and how much of it is left
+type Code struct {
+	Code string `json:"code"`
+	// MaxUses is how many users may redeem the code; 0 is unlimited
+	MaxUses int `json:"max_uses"`
+	Uses    int `json:"uses"`
+	// Expires is when the code stops working; zero never expires
+	Expires time.Time `json:"expires"`
+	Created time.Time `json:"created"`
+	Note    string    `json:"note,omitempty"`
+}
+
+// Activation records which code let a user in
+type Activation struct {
+	Code string    `json:"code"`
+	Time time.Time `json:"time"`
+}
+
+// Create generates and stores a new code
+func Create(st store.KV, maxUses int, ttl time.Duration, note string) (Code, error) {
+	code, err := generate()
+	if err != nil {
+		return Code{}, err
+	}
+	c := Code{Code: code, MaxUses: maxUses, Created: time.Now(), Note: note}
+	if ttl > 0 {
+		c.Expires = c.Created.Add(ttl)
+	}
+	if err := st.Set(codeBucket, c.Code, c); err != nil {
+		return Code{}, fmt.Errorf("failed to save invite code: %w", err)
+	}
+	return c, nil
+}
+
+// generate returns a random code
+func generate() (string, error) {
+	var sb strings.Builder
+	max := big.NewInt(int64(len(alphabet)))
+	for i := 0; i < codeLength; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate invite code: %w", err)
+		}
+		sb.WriteByte(alphabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// List returns all stored codes, oldest first
+func List(st store.KV) ([]Code, error) {
+	var codes []Code
+	for _, key := range st.Keys(codeBucket) {
+		var c Code
+		ok, err := st.Get(codeBucket, key, &c)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			codes = append(codes, c)
+		}
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Created.Before(codes[j].Created) })
+	return codes, nil
+}
+
+// Revoke deletes a code and deactivates the users who redeemed it,
+// returning how many were deactivated
+func Revoke(st store.KV, code string) (int, error) {
+	code = normalize(code)
+	var c Code
+	ok, err := st.Get(codeBucket, code, &c)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrUnknownCode
+	}
+	if err := st.Delete(codeBucket, code); err != nil {
+		return 0, fmt.Errorf("failed to delete invite code: %w", err)
+	}
+
+	users := 0
+	for _, userID := range st.Keys(userBucket) {
+		var a Activation
+		if ok, err := st.Get(userBucket, userID, &a); err != nil || !ok || a.Code != code {
+			continue
+		}
+		if err := st.Delete(userBucket, userID); err != nil {
+			return users, fmt.Errorf("failed to deactivate %s: %w", userID, err)
+		}
+		users++
+	}
+	return users, nil
+}
+
+// Redeem activates userID with code. Redeeming again as an active user
+// doesn't use the code up further.
+func Redeem(st store.KV, code, userID string, now time.Time) error {
+	code = normalize(code)
+
+	redeemMu.Lock()
+	defer redeemMu.Unlock()
+
+	var c Code
+	ok, err := st.Get(codeBucket, code, &c)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUnknownCode
+	}
+	if active, err := Activated(st, userID); err != nil {
+		return err
+	} else if active {
+		return nil
+	}
+	if !c.Expires.IsZero() && now.After(c.Expires) {
+		return ErrExpired
+	}
+	if c.MaxUses > 0 && c.Uses >= c.MaxUses {
+		return ErrUsedUp
+	}
+
+	c.Uses++
+	if err := st.Set(codeBucket, code, c); err != nil {
+		return fmt.Errorf("failed to save invite code: %w", err)
+	}
+	if err := st.Set(userBucket, userID, Activation{Code: code, Time: now}); err != nil {
+		return fmt.Errorf("failed to activate user: %w", err)
+	}
+	return nil
+}
+
+// Activated reports whether userID has redeemed a code
+func Activated(st store.KV, userID string) (bool, error) {
+	var a Activation
+	return st.Get(userBucket, userID, &a)
+}
+
+// normalize makes codes case-insensitive and tolerant of stray spaces
+func normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,85 @@
+// Package jsonl edits append-only JSON-lines files in place
+package jsonl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Filter rewrites path without the lines drop returns true for and
+// reports how many were removed. The file is replaced atomically and left
+// untouched when nothing matches. A missing file removes nothing.
+// Callers must stop other writers to path while it runs.
+func Filter(path string, drop func(line []byte) bool) (int, error) {
+	return Rewrite(path, func(line []byte) ([]byte, bool) {
+		if drop(line) {
+			return nil, true
+		}
+		return line, false
+	})
+}
+
+// Rewrite replaces each line of path with what edit returns, dropping it
+// when edit returns nil, and reports how many lines edit changed. As with
+// Filter the file is replaced atomically, and only if something changed.
+func Rewrite(path string, edit func(line []byte) (out []byte, changed bool)) (int, error) {
+	in, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	out, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	changed := 0
+	w := bufio.NewWriter(out)
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line, ok := edit(scanner.Bytes())
+		if ok {
+			changed++
+		}
+		if line == nil {
+			continue
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+
+	if err := w.Flush(); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", out.Name(), err)
+	}
+	if err := out.Chmod(info.Mode().Perm()); err != nil {
+		return 0, fmt.Errorf("failed to set permissions on %s: %w", out.Name(), err)
+	}
+	if err := out.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", out.Name(), err)
+	}
+	in.Close()
+	if err := os.Rename(out.Name(), path); err != nil {
+		return 0, fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return changed, nil
+}
@@ -0,0 +1,220 @@
+// Package knowledge splits documents into passages and finds those
+// relevant to a question, so answers can be grounded in company docs.
+//
+// Search is keyword based (BM25 over words and, for Chinese, character
+// bigrams); there is no embedding model to call.
+package knowledge
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// stopTerms are question words too common to say what a passage is about
+var stopTerms = map[string]bool{
+	"什么": true, "怎么": true, "如何": true, "为什": true, "么办": true, "怎样": true,
+	"一个": true, "我们": true, "你们": true, "他们": true, "这个": true, "那个": true,
+	"可以": true, "需要": true, "是不": true, "不是": true, "有没": true, "没有": true,
+	"the": true, "and": true, "for": true, "how": true, "what": true, "is": true, "to": true,
+}
+
+// Document is a text to index
+type Document struct {
+	Title string
+	URL   string
+	Text  string
+}
+
+// Passage is part of a document
+type Passage struct {
+	Title string
+	URL   string
+	Text  string
+}
+
+type passage struct {
+	Passage
+	terms  map[string]int
+	length int
+}
+
+// Index holds the passages of a set of documents. It is immutable once
+// built and safe for concurrent use.
+type Index struct {
+	passages []passage
+	// df counts the passages each term appears in
+	df        map[string]int
+	avgLength float64
+}
+
+// Build splits docs into passages of about size characters and indexes
+// them
+func Build(docs []Document, size int) *Index {
+	ix := &Index{df: make(map[string]int)}
+	total := 0
+	for _, doc := range docs {
+		for _, text := range Split(doc.Text, size) {
+			p := passage{Passage: Passage{Title: doc.Title, URL: doc.URL, Text: text}, terms: make(map[string]int)}
+			// The title is part of every passage's terms, so a passage
+			// deep in a runbook still matches the runbook's name
+			for _, term := range Terms(doc.Title + "\n" + text) {
+				p.terms[term]++
+				p.length++
+			}
+			for term := range p.terms {
+				ix.df[term]++
+			}
+			total += p.length
+			ix.passages = append(ix.passages, p)
+		}
+	}
+	if len(ix.passages) > 0 {
+		ix.avgLength = float64(total) / float64(len(ix.passages))
+	}
+	return ix
+}
+
+// Len returns the number of passages
+func (ix *Index) Len() int {
+	return len(ix.passages)
+}
+
+// Search returns up to k passages relevant to query, best first. A
+// passage must share at least two distinct terms with a query that has
+// two or more.
+func (ix *Index) Search(query string, k int) []Passage {
+	if ix == nil || len(ix.passages) == 0 || k <= 0 {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var terms []string
+	for _, term := range Terms(query) {
+		if !seen[term] && ix.df[term] > 0 {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	minMatched := min(2, len(terms))
+	if minMatched == 0 {
+		return nil
+	}
+
+	type scored struct {
+		i     int
+		score float64
+	}
+	var hits []scored
+	n := float64(len(ix.passages))
+	for i, p := range ix.passages {
+		score, matched := 0.0, 0
+		for _, term := range terms {
+			tf := float64(p.terms[term])
+			if tf == 0 {
+				continue
+			}
+			matched++
+			df := float64(ix.df[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(p.length)/ix.avgLength))
+		}
+		if matched >= minMatched {
+			hits = append(hits, scored{i, score})
+		}
+	}
+	sort.SliceStable(hits, func(a, b int) bool { return hits[a].score > hits[b].score })
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	results := make([]Passage, len(hits))
+	for j, h := range hits {
+		results[j] = ix.passages[h.i].Passage
+	}
+	return results
+}
+
+// Terms returns the search terms of text: lowercased words of letters and
+// digits and, for runs of Chinese characters, their bigrams (or the
+// character alone), without stop terms
+func Terms(text string) []string {
+	var terms []string
+	var word []rune
+	var han []rune
+	flushWord := func() {
+		if len(word) > 1 {
+			if w := string(word); !stopTerms[w] {
+				terms = append(terms, w)
+			}
+		}
+		word = word[:0]
+	}
+	flushHan := func() {
+		if len(han) == 1 {
+			terms = append(terms, string(han))
+		}
+		for i := 0; i+1 < len(han); i++ {
+			if bigram := string(han[i : i+2]); !stopTerms[bigram] {
+				terms = append(terms, bigram)
+			}
+		}
+		han = han[:0]
+	}
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+	return terms
+}
+
+// Split cuts text into passages of about size characters, keeping
+// paragraphs together where they fit
+func Split(text string, size int) []string {
+	var passages []string
+	var current strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			passages = append(passages, s)
+		}
+		current.Reset()
+	}
+	for _, para := range strings.Split(text, "\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		if current.Len() > 0 && utf8.RuneCountInString(current.String())+utf8.RuneCountInString(para) > size {
+			flush()
+		}
+		for utf8.RuneCountInString(para) > size {
+			runes := []rune(para)
+			current.WriteString(string(runes[:size]))
+			flush()
+			para = string(runes[size:])
+		}
+		if current.Len() > 0 {
+			current.WriteByte('\n')
+		}
+		current.WriteString(para)
+	}
+	flush()
+	return passages
+}
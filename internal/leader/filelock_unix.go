@@ -0,0 +1,74 @@
+//go:build !windows
+
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FileLease holds an exclusive flock on a file. The kernel drops the lock
+// when the holding process dies, so the standby can take over on its next
+// attempt. Both instances must see the same file, e.g. on shared storage
+// that supports locking.
+type FileLease struct {
+	path string
+	mu   sync.Mutex
+	f    *os.File
+}
+
+// NewFileLease returns a lease backed by the lock file at path
+func NewFileLease(path string) (*FileLease, error) {
+	return &FileLease{path: path}, nil
+}
+
+// Acquire implements Lease; ttl is unused since the lock lives as long
+// as the process
+func (l *FileLease) Acquire(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f != nil {
+		return true, nil
+	}
+
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return false, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to lock %s: %w", l.path, err)
+	}
+
+	// Record the holder for operators inspecting the file
+	f.Truncate(0)
+	f.WriteAt([]byte(holder+"\n"), 0)
+	l.f = f
+	return true, nil
+}
+
+// Release implements Lease
+func (l *FileLease) Release(ctx context.Context, holder string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return nil
+	}
+	err := syscall.Flock(int(l.f.Fd()), syscall.LOCK_UN)
+	l.f.Close()
+	l.f = nil
+	if err != nil {
+		return fmt.Errorf("failed to unlock %s: %w", l.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package leader
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// FileLease is not available on Windows; use a Redis lease instead
+type FileLease struct{}
+
+// NewFileLease always fails on Windows
+func NewFileLease(path string) (*FileLease, error) {
+	return nil, errors.New("file lock failover is not supported on Windows; configure redis instead")
+}
+
+// Acquire implements Lease
+func (l *FileLease) Acquire(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	return false, errors.New("file lock failover is not supported on Windows")
+}
+
+// Release implements Lease
+func (l *FileLease) Release(ctx context.Context, holder string) error {
+	return nil
+}
@@ -0,0 +1,114 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Lease is a lock at most one holder has at a time
+type Lease interface {
+	// Acquire takes the lease for holder, or extends it if holder already
+	// has it, and reports whether holder now holds it
+	Acquire(ctx context.Context, holder string, ttl time.Duration) (bool, error)
+	// Release gives the lease up if holder holds it
+	Release(ctx context.Context, holder string) error
+}
+
+// Elector runs one side of a primary/standby pair: the instance holding
+// the lease is primary, the other waits to take over
+type Elector struct {
+	lease  Lease
+	holder string
+	ttl    time.Duration
+}
+
+// New returns an Elector for holder. A primary that dies stops renewing,
+// so the standby takes over at most ttl later.
+func New(lease Lease, holder string, ttl time.Duration) *Elector {
+	return &Elector{lease: lease, holder: holder, ttl: ttl}
+}
+
+// Acquire blocks until this instance holds the lease or ctx ends
+func (e *Elector) Acquire(ctx context.Context) error {
+	ticker := time.NewTicker(e.retryInterval())
+	defer ticker.Stop()
+
+	waiting := false
+	for {
+		ok, err := e.lease.Acquire(ctx, e.holder, e.ttl)
+		switch {
+		case err != nil:
+			log.Printf("[Leader] Failed to acquire lease: %v", err)
+		case ok:
+			log.Printf("[Leader] %s is now primary", e.holder)
+			return nil
+		case !waiting:
+			log.Printf("[Leader] %s is standby, waiting for the primary to go away", e.holder)
+			waiting = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for lease: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Keep renews the lease until ctx ends. The returned channel is closed if
+// the lease is lost, either taken by another holder or not renewed in time;
+// the caller must then stop acting as primary.
+func (e *Elector) Keep(ctx context.Context) <-chan struct{} {
+	lost := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(e.retryInterval())
+		defer ticker.Stop()
+
+		renewed := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			ok, err := e.lease.Acquire(ctx, e.holder, e.ttl)
+			switch {
+			case err != nil:
+				log.Printf("[Leader] Failed to renew lease: %v", err)
+				if time.Since(renewed) < e.ttl {
+					continue
+				}
+				log.Printf("[Leader] Lease expired without renewal")
+			case ok:
+				renewed = time.Now()
+				continue
+			default:
+				log.Printf("[Leader] Lease taken by another instance")
+			}
+			close(lost)
+			return
+		}
+	}()
+	return lost
+}
+
+// Release gives the lease up so the standby can take over immediately
+func (e *Elector) Release() {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := e.lease.Release(ctx, e.holder); err != nil {
+		log.Printf("[Leader] Failed to release lease: %v", err)
+	}
+}
+
+// retryInterval renews well within the TTL so one slow call doesn't
+// cost the lease
+func (e *Elector) retryInterval() time.Duration {
+	if d := e.ttl / 3; d > 0 {
+		return d
+	}
+	return time.Second
+}
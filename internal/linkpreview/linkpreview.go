@@ -0,0 +1,197 @@
+// Package linkpreview fetches the title and description of web pages
+// linked in agent replies, so they can be shown as a preview card.
+package linkpreview
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/safehttp"
+)
+
+// maxPageBytes caps how much of a page is read looking for its metadata
+const maxPageBytes = 512 << 10
+
+// maxDescription caps a preview's description, in characters
+const maxDescription = 120
+
+// urlPattern matches http(s) links, stopping at whitespace, brackets,
+// quotes and full-width punctuation
+var urlPattern = regexp.MustCompile(`https?://[^\s<>()\[\]"'` + "`" + `，。；：！？、）】》]+`)
+
+// Preview is what a page says about itself
+type Preview struct {
+	URL         string
+	Title       string
+	Description string
+	// Site is the page's og:site_name, or its host
+	Site string
+}
+
+// Fetcher finds links in replies and fetches their previews
+type Fetcher struct {
+	cfg        config.LinkPreviewConfig
+	httpClient *http.Client
+}
+
+// New returns a Fetcher, or nil when previews are disabled. A nil Fetcher
+// finds no links.
+func New(cfg config.LinkPreviewConfig) *Fetcher {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Fetcher{cfg: cfg, httpClient: safehttp.NewClient(cfg.Timeout, cfg.Hosts)}
+}
+
+// URLs returns the distinct links in text outside code blocks, up to the
+// configured limit
+func (f *Fetcher) URLs(text string) []string {
+	if f == nil {
+		return nil
+	}
+	var urls []string
+	seen := make(map[string]bool)
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		for _, u := range urlPattern.FindAllString(line, -1) {
+			u = strings.TrimRight(u, ".,;:!?*_~")
+			parsed, err := url.Parse(u)
+			if err != nil || parsed.Host == "" || seen[u] || safehttp.CheckURL(parsed, f.cfg.Hosts) != nil {
+				continue
+			}
+			seen[u] = true
+			urls = append(urls, u)
+			if len(urls) == f.cfg.MaxLinks {
+				return urls
+			}
+		}
+	}
+	return urls
+}
+
+// Fetch returns the preview for a link
+func (f *Fetcher) Fetch(ctx context.Context, link string) (Preview, error) {
+	ctx, cancel := context.WithTimeout(ctx, f.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return Preview{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "clawdbot-bridge link preview")
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return Preview{}, fmt.Errorf("failed to fetch %s: %w", link, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Preview{}, fmt.Errorf("fetch %s returned %s", link, resp.Status)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.Contains(contentType, "html") {
+		return Preview{}, fmt.Errorf("%s is not a web page (%s)", link, contentType)
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxPageBytes), contentType)
+	if err != nil {
+		return Preview{}, fmt.Errorf("failed to decode %s: %w", link, err)
+	}
+	p := parse(body)
+	p.URL = link
+	if p.Title == "" {
+		return Preview{}, fmt.Errorf("%s has no title", link)
+	}
+	if p.Site == "" {
+		p.Site = resp.Request.URL.Hostname()
+	}
+	return p, nil
+}
+
+// parse reads the title, description and site name from a page's head
+func parse(r io.Reader) Preview {
+	var p Preview
+	var title, ogTitle, description, ogDescription string
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return finish(p, title, ogTitle, description, ogDescription)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch tok.Data {
+			case "title":
+				if title == "" && z.Next() == html.TextToken {
+					title = string(z.Text())
+				}
+			case "meta":
+				key, content := "", ""
+				for _, a := range tok.Attr {
+					switch a.Key {
+					case "name", "property":
+						key = strings.ToLower(a.Val)
+					case "content":
+						content = a.Val
+					}
+				}
+				switch key {
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					ogDescription = content
+				case "description":
+					description = content
+				case "og:site_name":
+					p.Site = strings.TrimSpace(content)
+				}
+			case "body":
+				// Everything we want is in the head
+				return finish(p, title, ogTitle, description, ogDescription)
+			}
+		}
+	}
+}
+
+// finish prefers Open Graph metadata over the plain title and description
+func finish(p Preview, title, ogTitle, description, ogDescription string) Preview {
+	p.Title = clean(firstNonEmpty(ogTitle, title), 0)
+	p.Description = clean(firstNonEmpty(ogDescription, description), maxDescription)
+	return p
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// clean collapses whitespace and, with max > 0, shortens s to max
+// characters
+func clean(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); max > 0 && len(runes) > max {
+		s = string(runes[:max]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// fields identify one conversation turn in interleaved logs
+type fields struct {
+	mu            sync.Mutex
+	correlationID string
+	chatID        string
+	runID         string
+}
+
+type ctxKey struct{}
+
+// NewContext returns ctx tagged with a fresh correlation ID
+func NewContext(ctx context.Context) context.Context {
+	return WithCorrelationID(ctx, strings.ReplaceAll(uuid.NewString(), "-", "")[:12])
+}
+
+// WithCorrelationID returns ctx tagged with an existing correlation ID,
+// e.g. one carried over from another bridge instance
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, &fields{correlationID: id})
+}
+
+// Fork returns ctx with a copy of its fields, so run IDs recorded on the
+// result (e.g. by a shadow run) don't leak back into the parent's logs
+func Fork(ctx context.Context) context.Context {
+	f := fromContext(ctx)
+	if f == nil {
+		return NewContext(ctx)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return context.WithValue(ctx, ctxKey{}, &fields{
+		correlationID: f.correlationID,
+		chatID:        f.chatID,
+	})
+}
+
+func fromContext(ctx context.Context) *fields {
+	if ctx == nil {
+		return nil
+	}
+	f, _ := ctx.Value(ctxKey{}).(*fields)
+	return f
+}
+
+// CorrelationID returns the correlation ID carried by ctx, if any
+func CorrelationID(ctx context.Context) string {
+	f := fromContext(ctx)
+	if f == nil {
+		return ""
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.correlationID
+}
+
+// Fields is a snapshot of the correlation fields carried by a context
+type Fields struct {
+	CorrelationID string
+	ChatID        string
+	RunID         string
+}
+
+// FieldsOf returns the correlation fields of ctx; zero if it has none
+func FieldsOf(ctx context.Context) Fields {
+	f := fromContext(ctx)
+	if f == nil {
+		return Fields{}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return Fields{CorrelationID: f.correlationID, ChatID: f.chatID, RunID: f.runID}
+}
+
+// SetChatID records the Feishu chat the turn belongs to
+func SetChatID(ctx context.Context, chatID string) {
+	if f := fromContext(ctx); f != nil {
+		f.mu.Lock()
+		f.chatID = chatID
+		f.mu.Unlock()
+	}
+}
+
+// SetRunID records the backend run serving the turn
+func SetRunID(ctx context.Context, runID string) {
+	if f := fromContext(ctx); f != nil {
+		f.mu.Lock()
+		f.runID = runID
+		f.mu.Unlock()
+	}
+}
+
+// suffix renders the fields as " cid=... chat=... run=..."
+func suffix(ctx context.Context) string {
+	f := fromContext(ctx)
+	if f == nil {
+		return ""
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var sb strings.Builder
+	sb.WriteString(" cid=" + f.correlationID)
+	if f.chatID != "" {
+		sb.WriteString(" chat=" + f.chatID)
+	}
+	if f.runID != "" {
+		sb.WriteString(" run=" + f.runID)
+	}
+	return sb.String()
+}
+
+// Printf logs like log.Printf and appends the correlation fields of ctx,
+// so one conversation can be grepped out by its cid
+func Printf(ctx context.Context, format string, args ...interface{}) {
+	log.Output(2, fmt.Sprintf(format, args...)+suffix(ctx))
+}
@@ -0,0 +1,182 @@
+// Package media finds the images agent tools produce, named by URL or
+// local file path, and loads them so they can be sent as image messages.
+package media
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/safehttp"
+)
+
+// maxImageBytes caps an image; Feishu rejects images over 10 MB
+const maxImageBytes = 10 << 20
+
+// urlPattern matches http(s) links to image files, query string included
+var urlPattern = regexp.MustCompile(`(?i)https?://[^\s"'<>()\[\]\\]+?\.(?:png|jpe?g|gif|webp)(?:\?[^\s"'<>()\[\]\\]*)?`)
+
+// anyURLPattern matches any link, image or not
+var anyURLPattern = regexp.MustCompile(`[A-Za-z][A-Za-z0-9+.-]*://\S+`)
+
+// pathPattern matches absolute paths to image files, as they appear in
+// text, JSON strings or Markdown links
+var pathPattern = regexp.MustCompile(`(?i)(?:^|[\s"'(（:：=\[])(/[^\s"'<>()\[\]\\]+?\.(?:png|jpe?g|gif|webp))\b`)
+
+// Image is an image loaded for sending
+type Image struct {
+	// Ref is the URL or path the image was found as
+	Ref string
+	// Local is set for images read from disk
+	Local bool
+	Data  []byte
+}
+
+// Finder finds and loads generated images
+type Finder struct {
+	cfg        config.GeneratedImagesConfig
+	httpClient *http.Client
+}
+
+// New returns a Finder, or nil when the feature is disabled. A nil
+// Finder finds no images.
+func New(cfg config.GeneratedImagesConfig) *Finder {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Finder{cfg: cfg, httpClient: safehttp.NewClient(cfg.Timeout, cfg.Hosts)}
+}
+
+// Find returns the image URLs and paths in text that may be loaded:
+// URLs the configured hosts allow and paths inside the configured
+// directories
+func (f *Finder) Find(text string) []string {
+	if f == nil {
+		return nil
+	}
+	var refs []string
+	for _, u := range urlPattern.FindAllString(text, -1) {
+		if parsed, err := url.Parse(u); err == nil && safehttp.CheckURL(parsed, f.cfg.Hosts) == nil {
+			refs = append(refs, u)
+		}
+	}
+	// Blank out links so the paths inside them aren't read as local paths
+	for _, m := range pathPattern.FindAllStringSubmatch(anyURLPattern.ReplaceAllString(text, " "), -1) {
+		if _, ok := f.allowedPath(m[1]); ok {
+			refs = append(refs, m[1])
+		}
+	}
+	return refs
+}
+
+// Limit drops repeated refs and caps them to the configured number
+func (f *Finder) Limit(refs []string) []string {
+	if f == nil {
+		return nil
+	}
+	var out []string
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		out = append(out, ref)
+		if len(out) == f.cfg.MaxImages {
+			break
+		}
+	}
+	return out
+}
+
+// allowedPath resolves path, following symlinks, and reports whether it
+// lies inside one of the configured directories
+func (f *Finder) allowedPath(path string) (string, bool) {
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(path))
+	if err != nil {
+		return "", false
+	}
+	for _, dir := range f.cfg.Dirs {
+		root, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(root, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, true
+		}
+	}
+	return "", false
+}
+
+// Load reads or downloads the image ref names
+func (f *Finder) Load(ctx context.Context, ref string) (Image, error) {
+	img := Image{Ref: ref}
+	var err error
+	if strings.HasPrefix(ref, "/") {
+		img.Local = true
+		img.Data, err = f.readFile(ref)
+	} else {
+		img.Data, err = f.download(ctx, ref)
+	}
+	if err != nil {
+		return img, err
+	}
+	if contentType := http.DetectContentType(img.Data); !strings.HasPrefix(contentType, "image/") {
+		return img, fmt.Errorf("%s is not an image (%s)", ref, contentType)
+	}
+	return img, nil
+}
+
+// readFile reads a local image inside the configured directories
+func (f *Finder) readFile(path string) ([]byte, error) {
+	resolved, ok := f.allowedPath(path)
+	if !ok {
+		return nil, fmt.Errorf("%s is outside generated_images.dirs", path)
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat image: %w", err)
+	}
+	if info.Size() > maxImageBytes {
+		return nil, fmt.Errorf("%s is %d bytes, over the %d byte limit", path, info.Size(), maxImageBytes)
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	return data, nil
+}
+
+// download fetches an image URL
+func (f *Finder) download(ctx context.Context, link string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, f.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", link, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s returned %s", link, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", link, err)
+	}
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("%s is over the %d byte limit", link, maxImageBytes)
+	}
+	return data, nil
+}
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of series
+const (
+	kindCounter = "counter"
+	kindGauge   = "gauge"
+	kindTiming  = "timing"
+)
+
+// series is one metric name plus its tag set
+type series struct {
+	name string
+	kind string
+	tags []string // sorted "key=value"
+	// counter total or gauge value
+	value float64
+	// counter value at the last flush, for delta-based sinks
+	flushed float64
+	// timing samples in milliseconds since the last flush
+	samples []float64
+}
+
+// Registry holds metric series in memory until a Pusher flushes them
+type Registry struct {
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{series: make(map[string]*series)}
+}
+
+// defaultRegistry is used by the package-level helpers
+var defaultRegistry = NewRegistry()
+
+// Default returns the process-wide registry
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Inc adds one to a counter. tags are key/value pairs.
+func Inc(name string, tags ...string) { defaultRegistry.Add(name, 1, tags...) }
+
+// Add adds delta to a counter
+func Add(name string, delta float64, tags ...string) { defaultRegistry.Add(name, delta, tags...) }
+
+// Set sets a gauge
+func Set(name string, value float64, tags ...string) { defaultRegistry.Set(name, value, tags...) }
+
+// Timing records a duration sample
+func Timing(name string, d time.Duration, tags ...string) { defaultRegistry.Timing(name, d, tags...) }
+
+// get returns the series for name/tags, creating it with kind
+func (r *Registry) get(name, kind string, tags []string) *series {
+	pairs := make([]string, 0, len(tags)/2)
+	for i := 0; i+1 < len(tags); i += 2 {
+		pairs = append(pairs, tags[i]+"="+tags[i+1])
+	}
+	sort.Strings(pairs)
+	key := name + "|" + strings.Join(pairs, ",")
+
+	s, ok := r.series[key]
+	if !ok {
+		s = &series{name: name, kind: kind, tags: pairs}
+		r.series[key] = s
+	}
+	return s
+}
+
+// Add adds delta to a counter
+func (r *Registry) Add(name string, delta float64, tags ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.get(name, kindCounter, tags).value += delta
+}
+
+// Set sets a gauge
+func (r *Registry) Set(name string, value float64, tags ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.get(name, kindGauge, tags).value = value
+}
+
+// Timing records a duration sample
+func (r *Registry) Timing(name string, d time.Duration, tags ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.get(name, kindTiming, tags)
+	s.samples = append(s.samples, float64(d.Microseconds())/1000)
+}
+
+// Point is one series as handed to a sink at flush time
+type Point struct {
+	Name string
+	Kind string
+	// Tags are sorted "key=value" pairs
+	Tags []string
+	// Value is the counter total or gauge value
+	Value float64
+	// Delta is the counter increase since the previous flush
+	Delta float64
+	// Samples are timing samples (ms) since the previous flush
+	Samples []float64
+}
+
+// snapshot returns all series and resets per-flush state
+func (r *Registry) snapshot() []Point {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	points := make([]Point, 0, len(r.series))
+	for _, s := range r.series {
+		p := Point{Name: s.name, Kind: s.kind, Tags: s.tags, Value: s.value}
+		switch s.kind {
+		case kindCounter:
+			p.Delta = s.value - s.flushed
+			s.flushed = s.value
+		case kindTiming:
+			if len(s.samples) == 0 {
+				continue
+			}
+			p.Samples = s.samples
+			s.samples = nil
+		}
+		points = append(points, p)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Name < points[j].Name })
+	return points
+}
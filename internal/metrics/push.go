@@ -0,0 +1,237 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sink receives flushed metrics
+type Sink interface {
+	Name() string
+	Write(points []Point) error
+}
+
+// Push flushes r to every sink each interval until ctx is done
+func Push(ctx context.Context, r *Registry, interval time.Duration, sinks ...Sink) {
+	if len(sinks) == 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			points := r.snapshot()
+			for _, sink := range sinks {
+				if err := sink.Write(points); err != nil {
+					log.Printf("[Metrics] Failed to push to %s: %v", sink.Name(), err)
+				}
+			}
+		}
+	}
+}
+
+// maxPacket keeps StatsD datagrams under a typical MTU
+const maxPacket = 1432
+
+// StatsD pushes metrics over UDP in StatsD format
+type StatsD struct {
+	addr      string
+	prefix    string
+	dogStatsD bool
+}
+
+// NewStatsD creates a StatsD sink. With dogStatsD, tags are sent as
+// "|#key:value"; plain StatsD drops them.
+func NewStatsD(addr, prefix string, dogStatsD bool) *StatsD {
+	return &StatsD{addr: addr, prefix: prefix, dogStatsD: dogStatsD}
+}
+
+func (s *StatsD) Name() string { return "statsd " + s.addr }
+
+func (s *StatsD) Write(points []Point) error {
+	conn, err := net.Dial("udp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to dial statsd: %w", err)
+	}
+	defer conn.Close()
+
+	var packet bytes.Buffer
+	send := func(line string) error {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxPacket {
+			if _, err := conn.Write(packet.Bytes()); err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+		return nil
+	}
+
+	for _, p := range points {
+		name := s.prefix + p.Name
+		tags := s.tags(p.Tags)
+		var err error
+		switch p.Kind {
+		case kindCounter:
+			if p.Delta != 0 {
+				err = send(name + ":" + formatFloat(p.Delta) + "|c" + tags)
+			}
+		case kindGauge:
+			err = send(name + ":" + formatFloat(p.Value) + "|g" + tags)
+		case kindTiming:
+			for _, v := range p.Samples {
+				if err = send(name + ":" + formatFloat(v) + "|ms" + tags); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write statsd packet: %w", err)
+		}
+	}
+	if packet.Len() > 0 {
+		if _, err := conn.Write(packet.Bytes()); err != nil {
+			return fmt.Errorf("failed to write statsd packet: %w", err)
+		}
+	}
+	return nil
+}
+
+// tags renders DogStatsD tags, or nothing for plain StatsD
+func (s *StatsD) tags(pairs []string) string {
+	if !s.dogStatsD || len(pairs) == 0 {
+		return ""
+	}
+	out := make([]string, len(pairs))
+	for i, p := range pairs {
+		out[i] = strings.Replace(p, "=", ":", 1)
+	}
+	return "|#" + strings.Join(out, ",")
+}
+
+// InfluxDB pushes metrics to an InfluxDB write endpoint in line protocol
+type InfluxDB struct {
+	url        string
+	token      string
+	prefix     string
+	httpClient *http.Client
+}
+
+// NewInfluxDB creates an InfluxDB sink. url is the full write URL, e.g.
+// http://127.0.0.1:8086/api/v2/write?org=ops&bucket=bridge
+func NewInfluxDB(url, token, prefix string) *InfluxDB {
+	return &InfluxDB{
+		url:        url,
+		token:      token,
+		prefix:     prefix,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (i *InfluxDB) Name() string { return "influxdb" }
+
+func (i *InfluxDB) Write(points []Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+	now := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	var body bytes.Buffer
+	for _, p := range points {
+		body.WriteString(escapeInflux(i.prefix+p.Name, false))
+		for _, t := range p.Tags {
+			k, v, _ := strings.Cut(t, "=")
+			body.WriteString("," + escapeInflux(k, true) + "=" + escapeInflux(v, true))
+		}
+		body.WriteByte(' ')
+		switch p.Kind {
+		case kindTiming:
+			body.WriteString(timingFields(p.Samples))
+		default:
+			body.WriteString("value=" + formatFloat(p.Value))
+		}
+		body.WriteString(" " + now + "\n")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, i.url, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if i.token != "" {
+		req.Header.Set("Authorization", "Token "+i.token)
+	}
+
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call influxdb: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("influxdb returned %s", resp.Status)
+	}
+	return nil
+}
+
+// timingFields summarizes timing samples as line protocol fields
+func timingFields(samples []float64) string {
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	return fmt.Sprintf("count=%di,mean=%s,p50=%s,p95=%s,p99=%s,max=%s",
+		len(sorted),
+		formatFloat(sum/float64(len(sorted))),
+		formatFloat(Percentile(sorted, 50)),
+		formatFloat(Percentile(sorted, 95)),
+		formatFloat(Percentile(sorted, 99)),
+		formatFloat(sorted[len(sorted)-1]),
+	)
+}
+
+// Percentile returns the p-th percentile of sorted values (nearest rank)
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p/100*float64(len(sorted))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// escapeInflux escapes a measurement name or tag key/value
+func escapeInflux(s string, tag bool) string {
+	r := strings.NewReplacer(",", `\,`, " ", `\ `)
+	if tag {
+		r = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+	}
+	return r.Replace(s)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,158 @@
+// Package netdial opens the bridge's TCP connections to Feishu and the
+// gateway as set in the "network" section of bridge.json: which IP
+// version to use, the local address to bind, the DNS server and the TCP
+// keepalive interval.
+package netdial
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+)
+
+// dialTimeout bounds each connection attempt, as in http.DefaultTransport
+const dialTimeout = 30 * time.Second
+
+// errNoLocalAddr is returned when the interface has no address of the
+// remote IP's version
+var errNoLocalAddr = errors.New("no local address")
+
+// DialFunc opens a connection like net.Dialer.DialContext
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dialer dials as set in a NetworkConfig
+type dialer struct {
+	cfg      config.NetworkConfig
+	resolver *net.Resolver
+}
+
+// New returns the dial function for cfg, or nil when cfg sets nothing, so
+// callers keep their defaults
+func New(cfg config.NetworkConfig) DialFunc {
+	if cfg == (config.NetworkConfig{}) {
+		return nil
+	}
+	d := &dialer{cfg: cfg, resolver: net.DefaultResolver}
+	if cfg.DNS != "" {
+		d.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var nd net.Dialer
+				return nd.DialContext(ctx, network, cfg.DNS)
+			},
+		}
+	}
+	return d.DialContext
+}
+
+// DialContext resolves addr and tries its IPs in the configured order
+// until one connects
+func (d *dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := d.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, ip := range ips {
+		nd := net.Dialer{Timeout: dialTimeout, KeepAlive: d.cfg.KeepAlive}
+		local, err := d.localIP(ip)
+		if errors.Is(err, errNoLocalAddr) {
+			errs = append(errs, err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if local != nil {
+			nd.LocalAddr = &net.TCPAddr{IP: local}
+		}
+		conn, err := nd.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no usable address for %s", host)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// resolve returns host's IPs of the allowed versions, preferred version
+// first
+func (d *dialer) resolve(ctx context.Context, host string) ([]net.IP, error) {
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := d.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+
+	want4 := strings.HasPrefix(d.cfg.IPVersion, "ipv4")
+	want6 := strings.HasPrefix(d.cfg.IPVersion, "ipv6")
+	only := strings.HasSuffix(d.cfg.IPVersion, "_only")
+	if src := net.ParseIP(d.cfg.SourceAddr); src != nil {
+		// Only addresses of the source address' version can be reached
+		want4, want6, only = src.To4() != nil, src.To4() == nil, true
+	}
+
+	kept := ips[:0]
+	for _, ip := range ips {
+		if !only || (ip.To4() != nil) == want4 {
+			kept = append(kept, ip)
+		}
+	}
+	if want4 || want6 {
+		sort.SliceStable(kept, func(i, j int) bool {
+			return (kept[i].To4() != nil) == want4 && (kept[j].To4() != nil) != want4
+		})
+	}
+	return kept, nil
+}
+
+// localIP returns the address to dial ip from, or nil for any
+func (d *dialer) localIP(ip net.IP) (net.IP, error) {
+	if d.cfg.SourceAddr != "" {
+		return net.ParseIP(d.cfg.SourceAddr), nil
+	}
+	if d.cfg.Interface == "" {
+		return nil, nil
+	}
+
+	ifi, err := net.InterfaceByName(d.cfg.Interface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find network interface: %w", err)
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses of %s: %w", d.cfg.Interface, err)
+	}
+	for _, a := range addrs {
+		n, ok := a.(*net.IPNet)
+		if !ok || (n.IP.To4() != nil) != (ip.To4() != nil) || n.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		return n.IP, nil
+	}
+	return nil, fmt.Errorf("network interface %s: %w for %s", d.cfg.Interface, errNoLocalAddr, ip)
+}
@@ -0,0 +1,105 @@
+// Package redact masks credentials in text before it's logged or shown
+// to users
+package redact
+
+import (
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces every redacted value
+const Mask = "[REDACTED]"
+
+// minSecretLen keeps short values (e.g. a test token "x") from masking
+// unrelated text
+const minSecretLen = 6
+
+var (
+	mu      sync.RWMutex
+	secrets []string
+	// replacer is rebuilt from secrets on every Register
+	replacer *strings.Replacer
+)
+
+// patterns catch credentials that were never registered, e.g. a token in
+// a gateway frame. The first group is kept, the rest is masked.
+var patterns = []*regexp.Regexp{
+	// Authorization: Bearer xxx, "authorization":"Token xxx"
+	regexp.MustCompile(`(?i)(\bauthorization"?\s*[:=]\s*"?(?:bearer |token |basic )?)[^\s",}]+`),
+	// "app_secret":"xxx", token=xxx, "apiKey": "xxx"
+	regexp.MustCompile(`(?i)(\b(?:app_?secret|secret|token|api_?key|password)"?\s*[:=]\s*"?)[^\s",}&]+`),
+}
+
+// Register adds known secret values, such as configured credentials, to
+// be masked wherever they appear. Empty and very short values are ignored.
+func Register(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, v := range values {
+		if len(v) < minSecretLen || contains(secrets, v) {
+			continue
+		}
+		secrets = append(secrets, v)
+	}
+	// Longest first so a secret containing another is masked whole
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+
+	pairs := make([]string, 0, 2*len(secrets))
+	for _, s := range secrets {
+		pairs = append(pairs, s, Mask)
+	}
+	replacer = strings.NewReplacer(pairs...)
+}
+
+// String returns s with registered secrets and credential patterns masked
+func String(s string) string {
+	mu.RLock()
+	r := replacer
+	mu.RUnlock()
+
+	if r != nil {
+		s = r.Replace(s)
+	}
+	for _, p := range patterns {
+		s = p.ReplaceAllString(s, "${1}"+Mask)
+	}
+	return s
+}
+
+// Error returns err's message with secrets masked, or "" for nil
+func Error(err error) string {
+	if err == nil {
+		return ""
+	}
+	return String(err.Error())
+}
+
+// Writer masks everything written through it before passing it on.
+// It expects whole lines per Write, as the log package does.
+func Writer(w io.Writer) io.Writer {
+	return writer{w}
+}
+
+type writer struct {
+	w io.Writer
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+// Package retention deletes daily data files once they fall outside the
+// configured retention window
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+)
+
+// Target is a directory of dated files. By default they are daily files
+// named <Prefix>YYYY-MM-DD.jsonl.
+type Target struct {
+	Name   string
+	Dir    string
+	Prefix string
+	// Layout, when set, is the time layout of the date that starts each
+	// name after Prefix; the rest of the name is ignored up to Suffix
+	Layout string
+	// Suffix defaults to ".jsonl"
+	Suffix string
+	// Days is how long files are kept; 0 keeps them forever
+	Days int
+}
+
+// Removed describes a file deleted (or, on a dry run, due) by Purge
+type Removed struct {
+	Target string
+	Path   string
+	Size   int64
+}
+
+// Targets returns the data stores covered by cfg's retention settings
+func Targets(cfg *config.Config) []Target {
+	return []Target{
+		{Name: "transcripts", Dir: cfg.Transcripts.Dir, Days: cfg.Transcripts.RetentionDays},
+		{Name: "events", Dir: cfg.Events.Dir, Days: cfg.Events.RetentionDays},
+		{Name: "shadow", Dir: cfg.Shadow.Dir, Days: cfg.Shadow.RetentionDays},
+		{Name: "audit", Dir: cfg.Audit.Dir, Prefix: "audit-", Days: cfg.Audit.RetentionDays},
+		// Gateway run recordings are named <YYYYMMDD-HHMMSS.mmm>-<seq>.json
+		{Name: "recordings", Dir: cfg.Clawdbot.RecordDir, Layout: "20060102", Suffix: ".json", Days: cfg.Clawdbot.RecordRetentionDays},
+	}
+}
+
+// Purge deletes the files of each target dated before its retention
+// window as of now. With dryRun nothing is deleted, only reported.
+// Targets without retention, or whose directory doesn't exist yet, are
+// skipped.
+func Purge(targets []Target, now time.Time, dryRun bool) ([]Removed, error) {
+	var removed []Removed
+	var errs []error
+	for _, t := range targets {
+		if t.Days <= 0 || t.Dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(t.Dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list %s: %w", t.Dir, err))
+			continue
+		}
+
+		suffix := t.Suffix
+		if suffix == "" {
+			suffix = ".jsonl"
+		}
+		cutoff := now.AddDate(0, 0, -t.Days)
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() || !strings.HasPrefix(name, t.Prefix) || !strings.HasSuffix(name, suffix) {
+				continue
+			}
+			date, layout := strings.TrimSuffix(strings.TrimPrefix(name, t.Prefix), suffix), "2006-01-02"
+			if t.Layout != "" {
+				layout = t.Layout
+				date = date[:min(len(date), len(layout))]
+			}
+			day, err := time.ParseInLocation(layout, date, now.Location())
+			if err != nil || !day.Before(cutoff) {
+				continue
+			}
+
+			r := Removed{Target: t.Name, Path: filepath.Join(t.Dir, name)}
+			if info, err := e.Info(); err == nil {
+				r.Size = info.Size()
+			}
+			if !dryRun {
+				if err := os.Remove(r.Path); err != nil {
+					errs = append(errs, fmt.Errorf("failed to remove %s: %w", r.Path, err))
+					continue
+				}
+			}
+			removed = append(removed, r)
+		}
+	}
+	return removed, errors.Join(errs...)
+}
+
+// Run purges once right away and then every interval until ctx is
+// cancelled
+func Run(ctx context.Context, targets []Target, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := Purge(targets, time.Now(), false)
+		for _, r := range removed {
+			log.Printf("[Retention] Removed expired %s file %s", r.Target, r.Path)
+		}
+		if err != nil {
+			log.Printf("[Retention] Purge incomplete: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
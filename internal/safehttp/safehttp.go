@@ -0,0 +1,81 @@
+// Package safehttp builds HTTP clients for fetching URLs that come from
+// agent output, which must not be able to reach the bridge's own network.
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned for connections to loopback, private and
+// link-local addresses
+var ErrPrivateAddress = errors.New("refusing to fetch a private address")
+
+// maxRedirects caps the redirects followed for one request
+const maxRedirects = 5
+
+// NewClient returns a client for http(s) URLs. With hosts, only those
+// domains and their subdomains may be fetched, wherever they resolve;
+// without, any host may be, but not on a private address.
+func NewClient(timeout time.Duration, hosts []string) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if len(hosts) == 0 {
+		dialer.Control = checkAddress
+	}
+	return &http.Client{
+		Timeout: timeout,
+		// No proxy: fetching through one would skip the address check
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			return CheckURL(req.URL, hosts)
+		},
+	}
+}
+
+// CheckURL rejects URLs a client from NewClient with the same hosts
+// would refuse before connecting
+func CheckURL(u *url.URL, hosts []string) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if len(hosts) == 0 || HostAllowed(u.Hostname(), hosts) {
+		return nil
+	}
+	return fmt.Errorf("host %s is not allowlisted", u.Hostname())
+}
+
+// HostAllowed reports whether host or a parent domain is in hosts
+func HostAllowed(host string, hosts []string) bool {
+	host = strings.ToLower(host)
+	for _, h := range hosts {
+		h = strings.ToLower(h)
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAddress stops connections to addresses on private networks. It
+// runs after DNS resolution, so a public name can't point inside either.
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return ErrPrivateAddress
+	}
+	return nil
+}
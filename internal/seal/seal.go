@@ -0,0 +1,175 @@
+// Package seal encrypts stored records with AES-GCM under a keyring of
+// named keys, so keys can be rotated without losing older records
+package seal
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+)
+
+// prefix marks a sealed line: enc1:<key id>:<base64 nonce+ciphertext>
+const prefix = "enc1:"
+
+// ErrNoKey is returned when a sealed line names a key the keyring lacks
+var ErrNoKey = errors.New("encryption key not in keyring")
+
+// Keyring holds the keys records may be sealed with. New records are
+// sealed with the active key; the others only decrypt older records.
+type Keyring struct {
+	active string
+	aeads  map[string]cipher.AEAD
+}
+
+// keyringJSON is the keyring file format. Keys are base64-encoded
+// 32-byte AES-256 keys.
+type keyringJSON struct {
+	Active string            `json:"active"`
+	Keys   map[string]string `json:"keys"`
+}
+
+// LoadKeyring reads a keyring file. The error never includes key material.
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+	var kj keyringJSON
+	if err := json.Unmarshal(data, &kj); err != nil {
+		return nil, fmt.Errorf("failed to parse keyring %s: invalid JSON", path)
+	}
+
+	keys := make(map[string][]byte, len(kj.Keys))
+	for id, encoded := range kj.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("keyring %s: key %q is not valid base64", path, id)
+		}
+		keys[id] = key
+	}
+	return NewKeyring(kj.Active, keys)
+}
+
+// FromConfig loads the configured keyring, or returns nil when
+// encryption is off
+func FromConfig(cfg config.EncryptionConfig) (*Keyring, error) {
+	if cfg.KeyringFile == "" {
+		return nil, nil
+	}
+	return LoadKeyring(cfg.KeyringFile)
+}
+
+// NewKeyring builds a keyring from raw 32-byte keys
+func NewKeyring(active string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("active key %q not in keyring", active)
+	}
+	k := &Keyring{active: active, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes, got %d", id, len(key))
+		}
+		if bytes.ContainsRune([]byte(id), ':') {
+			return nil, fmt.Errorf("key id %q must not contain ':'", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		k.aeads[id] = aead
+	}
+	return k, nil
+}
+
+// Active returns the ID of the key new records are sealed with
+func (k *Keyring) Active() string {
+	return k.active
+}
+
+// Seal encrypts a record with the active key. The key ID is bound as
+// additional data so a line can't be passed off under another key. A nil
+// keyring returns plain unchanged.
+func (k *Keyring) Seal(plain []byte) []byte {
+	if k == nil {
+		return plain
+	}
+	aead := k.aeads[k.active]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(fmt.Sprintf("seal: failed to read random nonce: %v", err))
+	}
+	sealed := aead.Seal(nonce, nonce, plain, []byte(k.active))
+	return []byte(prefix + k.active + ":" + base64.StdEncoding.EncodeToString(sealed))
+}
+
+// Open decrypts a line written by Seal. Lines that were never sealed, e.g.
+// written before encryption was enabled, are returned unchanged.
+func (k *Keyring) Open(line []byte) ([]byte, error) {
+	id, ok := KeyID(line)
+	if !ok {
+		return line, nil
+	}
+	if k == nil {
+		return nil, fmt.Errorf("%w: record sealed with %q but encryption is not configured", ErrNoKey, id)
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNoKey, id)
+	}
+
+	encoded := line[len(prefix)+len(id)+1:]
+	sealed := make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
+	n, err := base64.StdEncoding.Decode(sealed, encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode sealed record: %w", err)
+	}
+	sealed = sealed[:n]
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("sealed record is truncated")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt record with key %q: %w", id, err)
+	}
+	return plain, nil
+}
+
+// Reseal re-encrypts line with the active key unless it already is; a
+// line never sealed is sealed now. It reports whether line changed, and
+// leaves lines no key in the keyring can decrypt as they are.
+func (k *Keyring) Reseal(line []byte) ([]byte, bool) {
+	if id, ok := KeyID(line); ok && id == k.active {
+		return line, false
+	}
+	plain, err := k.Open(line)
+	if err != nil {
+		return line, false
+	}
+	return k.Seal(plain), true
+}
+
+// KeyID returns the ID of the key line was sealed with, and false for a
+// line that isn't sealed
+func KeyID(line []byte) (string, bool) {
+	rest, ok := bytes.CutPrefix(line, []byte(prefix))
+	if !ok {
+		return "", false
+	}
+	id, _, ok := bytes.Cut(rest, []byte(":"))
+	if !ok {
+		return "", false
+	}
+	return string(id), true
+}
@@ -0,0 +1,151 @@
+package seal_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/seal"
+)
+
+// key returns a 32-byte key filled with b
+func key(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func keyring(t *testing.T, active string, keys map[string][]byte) *seal.Keyring {
+	t.Helper()
+	k, err := seal.NewKeyring(active, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestRoundTrip(t *testing.T) {
+	k := keyring(t, "2026-10", map[string][]byte{"2026-10": key(1)})
+	plain := []byte(`{"text":"你好"}`)
+
+	sealed := k.Seal(plain)
+	if id, ok := seal.KeyID(sealed); !ok || id != "2026-10" {
+		t.Fatalf("KeyID(%q) = %q, %v, want 2026-10", sealed, id, ok)
+	}
+	if bytes.Contains(sealed, plain) {
+		t.Fatalf("sealed line %q contains the plaintext", sealed)
+	}
+	got, err := k.Open(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Errorf("Open = %q, want %q", got, plain)
+	}
+	if again := k.Seal(plain); bytes.Equal(again, sealed) {
+		t.Error("sealing twice gave the same line; nonces must differ")
+	}
+}
+
+func TestOpenRetiredKey(t *testing.T) {
+	old := keyring(t, "old", map[string][]byte{"old": key(1)})
+	sealed := old.Seal([]byte("旧记录"))
+
+	rotated := keyring(t, "new", map[string][]byte{"old": key(1), "new": key(2)})
+	got, err := rotated.Open(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "旧记录" {
+		t.Errorf("Open = %q, want 旧记录", got)
+	}
+
+	dropped := keyring(t, "new", map[string][]byte{"new": key(2)})
+	if _, err := dropped.Open(sealed); !errors.Is(err, seal.ErrNoKey) {
+		t.Errorf("Open without the retired key = %v, want ErrNoKey", err)
+	}
+}
+
+// TestTamperedKeyID relabels a line with another key ID holding the same
+// key material; only the key ID bound as additional data can catch it
+func TestTamperedKeyID(t *testing.T) {
+	k := keyring(t, "a", map[string][]byte{"a": key(1), "b": key(1)})
+	sealed := k.Seal([]byte("secret"))
+
+	tampered := bytes.Replace(sealed, []byte("enc1:a:"), []byte("enc1:b:"), 1)
+	if _, err := k.Open(tampered); err == nil {
+		t.Error("Open accepted a line relabeled with another key ID")
+	}
+}
+
+func TestReseal(t *testing.T) {
+	old := keyring(t, "old", map[string][]byte{"old": key(1)})
+	other := keyring(t, "other", map[string][]byte{"other": key(3)})
+	k := keyring(t, "new", map[string][]byte{"old": key(1), "new": key(2)})
+
+	current := k.Seal([]byte("current"))
+	retired := old.Seal([]byte("retired"))
+	unknown := other.Seal([]byte("unknown"))
+	plain := []byte(`{"text":"plain"}`)
+
+	tests := []struct {
+		name        string
+		line        []byte
+		wantChanged bool
+		want        string
+	}{
+		{"active key", current, false, "current"},
+		{"retired key", retired, true, "retired"},
+		{"plaintext", plain, true, `{"text":"plain"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := k.Reseal(tt.line)
+			if changed != tt.wantChanged {
+				t.Fatalf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if !changed && !bytes.Equal(got, tt.line) {
+				t.Errorf("unchanged line was rewritten: %q", got)
+			}
+			if id, _ := seal.KeyID(got); id != "new" {
+				t.Errorf("resealed with %q, want new", id)
+			}
+			opened, err := k.Open(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(opened) != tt.want {
+				t.Errorf("Open = %q, want %q", opened, tt.want)
+			}
+		})
+	}
+
+	t.Run("undecryptable", func(t *testing.T) {
+		got, changed := k.Reseal(unknown)
+		if changed || !bytes.Equal(got, unknown) {
+			t.Errorf("Reseal = %q, %v, want the line left as it is", got, changed)
+		}
+	})
+}
+
+func TestPlaintextPassthrough(t *testing.T) {
+	plain := []byte(`{"text":"启用加密前的记录"}`)
+
+	k := keyring(t, "a", map[string][]byte{"a": key(1)})
+	got, err := k.Open(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Errorf("Open = %q, want the line unchanged", got)
+	}
+
+	var none *seal.Keyring
+	if got := none.Seal(plain); !bytes.Equal(got, plain) {
+		t.Errorf("nil keyring Seal = %q, want the line unchanged", got)
+	}
+	if got, err := none.Open(plain); err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("nil keyring Open = %q, %v, want the line unchanged", got, err)
+	}
+	if _, err := none.Open(k.Seal(plain)); !errors.Is(err, seal.ErrNoKey) {
+		t.Errorf("nil keyring Open of a sealed line = %v, want ErrNoKey", err)
+	}
+}
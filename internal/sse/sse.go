@@ -0,0 +1,130 @@
+package sse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Event is a single server-sent event
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	Retry int
+}
+
+// Reader parses a text/event-stream body
+type Reader struct {
+	scanner *bufio.Scanner
+}
+
+// NewReader creates a Reader over r
+func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &Reader{scanner: scanner}
+}
+
+// Next returns the next event, or io.EOF when the stream ends
+func (r *Reader) Next() (*Event, error) {
+	var ev Event
+	var data strings.Builder
+	hasData := false
+
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+
+		// Blank line dispatches the event
+		if line == "" {
+			if !hasData {
+				ev = Event{}
+				continue
+			}
+			ev.Data = data.String()
+			return &ev, nil
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue // comment / keepalive
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.Event = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			ev.ID = value
+		case "retry":
+			if n, err := strconv.Atoi(value); err == nil {
+				ev.Retry = n
+			}
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Stream ended without a trailing blank line
+	if hasData {
+		ev.Data = data.String()
+		return &ev, nil
+	}
+	return nil, io.EOF
+}
+
+// StatusError is returned when the server doesn't answer 200 OK
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("server returned %s: %s", e.Status, e.Body)
+}
+
+// Stream is an open event stream
+type Stream struct {
+	*Reader
+	body io.ReadCloser
+}
+
+// Close releases the underlying response body
+func (s *Stream) Close() error {
+	return s.body.Close()
+}
+
+// Do sends req with event-stream headers and returns the opened stream.
+// Non-200 responses are returned as *StatusError.
+func Do(client *http.Client, req *http.Request) (*Stream, error) {
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       strings.TrimSpace(string(msg)),
+		}
+	}
+
+	return &Stream{Reader: NewReader(resp.Body), body: resp.Body}, nil
+}
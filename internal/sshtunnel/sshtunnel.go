@@ -0,0 +1,162 @@
+// Package sshtunnel opens TCP connections through an SSH server, like
+// ssh -L, so the bridge can reach a gateway that only listens on its own
+// host. One SSH connection carries all of them and is reopened when it
+// drops.
+package sshtunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// dialTimeout bounds connecting and logging in to the SSH server
+const dialTimeout = 10 * time.Second
+
+// Tunnel dials through an SSH server
+type Tunnel struct {
+	addr   string
+	config *ssh.ClientConfig
+	dial   func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// New prepares a tunnel through the SSH server at addr, logging in as user
+// with the private key in keyFile. The server's key must be listed in
+// knownHostsFile. The SSH server is dialed with dial, or directly if nil,
+// on the first connection.
+func New(addr, user, keyFile, knownHostsFile string, dial func(ctx context.Context, network, addr string) (net.Conn, error)) (*Tunnel, error) {
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH key %s: %w", keyFile, err)
+	}
+	hostKeys, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known hosts: %w", err)
+	}
+
+	if dial == nil {
+		d := &net.Dialer{Timeout: dialTimeout}
+		dial = d.DialContext
+	}
+	return &Tunnel{
+		addr: addr,
+		dial: dial,
+		config: &ssh.ClientConfig{
+			User:            user,
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+			HostKeyCallback: hostKeys,
+			Timeout:         dialTimeout,
+		},
+	}, nil
+}
+
+// DialContext connects to addr as seen from the SSH server
+func (t *Tunnel) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := t.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := client.DialContext(ctx, network, addr)
+	if err != nil {
+		var refused *ssh.OpenChannelError
+		if !errors.As(err, &refused) && ctx.Err() == nil {
+			// The SSH connection itself failed; open a new one next time
+			client.Close()
+		}
+		return nil, fmt.Errorf("failed to dial %s through SSH: %w", addr, err)
+	}
+	return &deadlineConn{Conn: conn}, nil
+}
+
+// connect returns the SSH connection, opening it if needed
+func (t *Tunnel) connect(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client != nil {
+		return t.client, nil
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	conn, err := t.dial(dialCtx, "tcp", t.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SSH server: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+	c, chans, reqs, err := ssh.NewClientConn(conn, t.addr, t.config)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to log in to SSH server: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	client := ssh.NewClient(c, chans, reqs)
+	t.client = client
+	log.Printf("[SSH] Connected to %s as %s", t.addr, t.config.User)
+
+	go func() {
+		err := client.Wait()
+		t.mu.Lock()
+		if t.client == client {
+			t.client = nil
+		}
+		t.mu.Unlock()
+		log.Printf("[SSH] Connection to %s closed: %v", t.addr, err)
+	}()
+	return client, nil
+}
+
+// deadlineConn adds deadlines to an SSH channel, which has none, by
+// closing it once a deadline passes
+type deadlineConn struct {
+	net.Conn
+	mu                    sync.Mutex
+	readTimer, writeTimer *time.Timer
+}
+
+func (c *deadlineConn) SetDeadline(t time.Time) error {
+	c.SetReadDeadline(t)
+	return c.SetWriteDeadline(t)
+}
+
+func (c *deadlineConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readTimer = c.closeAt(c.readTimer, t)
+	return nil
+}
+
+func (c *deadlineConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeTimer = c.closeAt(c.writeTimer, t)
+	return nil
+}
+
+// closeAt replaces timer with one closing the connection at t, or none
+// for the zero time
+func (c *deadlineConn) closeAt(timer *time.Timer, t time.Time) *time.Timer {
+	if timer != nil {
+		timer.Stop()
+	}
+	if t.IsZero() {
+		return nil
+	}
+	return time.AfterFunc(time.Until(t), func() { c.Conn.Close() })
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package store
+
+// lockFile is a no-op on Windows, which has no flock; changes still
+// reload the file first, which narrows but doesn't close the window for
+// two processes overwriting each other
+func lockFile(path string) (func(), error) {
+	return func() {}, nil
+}
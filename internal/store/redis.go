@@ -0,0 +1,328 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+)
+
+// redisTimeout bounds each call so a slow Redis can't stall message handling
+const redisTimeout = 3 * time.Second
+
+// incrScript increments a counter and sets its expiry on creation only,
+// giving fixed windows without relying on EXPIRE NX (Redis 7+)
+var incrScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// Redis keeps bridge state in Redis so several instances can share it.
+// It implements both KV (one hash per bucket) and Shared.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// OpenRedis connects to the configured server and verifies it responds
+func OpenRedis(cfg config.RedisConfig) (*Redis, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis %s: %w", cfg.Addr, err)
+	}
+	return &Redis{client: client, prefix: cfg.Prefix}, nil
+}
+
+// Close releases the connection pool
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
+
+func (r *Redis) bucketKey(bucket string) string {
+	return r.prefix + "kv:" + bucket
+}
+
+// Get implements KV
+func (r *Redis) Get(bucket, key string, v interface{}) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	raw, err := r.client.HGet(ctx, r.bucketKey(bucket), key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s/%s: %w", bucket, key, err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("failed to decode %s/%s: %w", bucket, key, err)
+	}
+	return true, nil
+}
+
+// Set implements KV
+func (r *Redis) Set(bucket, key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s/%s: %w", bucket, key, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := r.client.HSet(ctx, r.bucketKey(bucket), key, raw).Err(); err != nil {
+		return fmt.Errorf("failed to write %s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// Delete implements KV
+func (r *Redis) Delete(bucket, key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := r.client.HDel(ctx, r.bucketKey(bucket), key).Err(); err != nil {
+		return fmt.Errorf("failed to delete %s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// Keys implements KV. Errors are logged and yield no keys.
+func (r *Redis) Keys(bucket string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	keys, err := r.client.HKeys(ctx, r.bucketKey(bucket)).Result()
+	if err != nil {
+		log.Printf("[Store] Failed to list %s: %v", bucket, err)
+		return nil
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Claim implements Shared
+func (r *Redis) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	ok, err := r.client.SetNX(ctx, r.prefix+key, 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim %s: %w", key, err)
+	}
+	return ok, nil
+}
+
+// Incr implements Shared
+func (r *Redis) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	n, err := incrScript.Run(ctx, r.client, []string{r.prefix + key}, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// StartRun implements Shared
+func (r *Redis) StartRun(ctx context.Context, run Run, ttl time.Duration) error {
+	raw, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to encode run: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	if err := r.client.Set(ctx, r.prefix+runKey(run.ID), raw, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to record run %s: %w", run.ID, err)
+	}
+	return nil
+}
+
+// EndRun implements Shared
+func (r *Redis) EndRun(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	if err := r.client.Del(ctx, r.prefix+runKey(id)).Err(); err != nil {
+		return fmt.Errorf("failed to end run %s: %w", id, err)
+	}
+	return nil
+}
+
+// Runs implements Shared
+func (r *Redis) Runs(ctx context.Context) ([]Run, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	var keys []string
+	iter := r.client.Scan(ctx, 0, r.prefix+runKey("*"), 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runs: %w", err)
+	}
+
+	var runs []Run
+	for _, v := range values {
+		// Runs that ended between SCAN and MGET come back nil
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var run Run
+		if err := json.Unmarshal([]byte(s), &run); err == nil {
+			runs = append(runs, run)
+		}
+	}
+	sortRuns(runs)
+	return runs, nil
+}
+
+// membersKey is a sorted set of instance names scored by heartbeat expiry
+func (r *Redis) membersKey() string {
+	return r.prefix + "members"
+}
+
+// Heartbeat keeps instance in the member list for ttl
+func (r *Redis) Heartbeat(ctx context.Context, instance string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	expires := float64(time.Now().Add(ttl).UnixMilli())
+	if err := r.client.ZAdd(ctx, r.membersKey(), redis.Z{Score: expires, Member: instance}).Err(); err != nil {
+		return fmt.Errorf("failed to heartbeat: %w", err)
+	}
+	return nil
+}
+
+// Leave removes instance from the member list
+func (r *Redis) Leave(ctx context.Context, instance string) error {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	if err := r.client.ZRem(ctx, r.membersKey(), instance).Err(); err != nil {
+		return fmt.Errorf("failed to leave: %w", err)
+	}
+	return nil
+}
+
+// Members lists instances whose heartbeat hasn't expired
+func (r *Redis) Members(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	if err := r.client.ZRemRangeByScore(ctx, r.membersKey(), "-inf", "("+now).Err(); err != nil {
+		return nil, fmt.Errorf("failed to expire members: %w", err)
+	}
+	members, err := r.client.ZRange(ctx, r.membersKey(), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+	return members, nil
+}
+
+// Push appends payload to instance's inbox
+func (r *Redis) Push(ctx context.Context, instance string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	if err := r.client.RPush(ctx, r.prefix+"inbox:"+instance, payload).Err(); err != nil {
+		return fmt.Errorf("failed to push to %s: %w", instance, err)
+	}
+	return nil
+}
+
+// Pop waits up to timeout for the next payload in instance's inbox
+func (r *Redis) Pop(ctx context.Context, instance string, timeout time.Duration) ([]byte, error) {
+	res, err := r.client.BLPop(ctx, timeout, r.prefix+"inbox:"+instance).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to pop inbox: %w", err)
+	}
+	// res is [key, value]
+	return []byte(res[1]), nil
+}
+
+// acquireScript takes a lease that is free or already held by ARGV[1]
+var acquireScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[1])
+if v == false then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+if v == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// releaseScript deletes a lease only if ARGV[1] still holds it
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Lease returns the named lease, usable with the leader package
+func (r *Redis) Lease(name string) *RedisLease {
+	return &RedisLease{r: r, key: r.prefix + "lease:" + name}
+}
+
+// RedisLease is a lease that expires unless its holder renews it
+type RedisLease struct {
+	r   *Redis
+	key string
+}
+
+// Acquire takes the lease or extends it if holder already has it
+func (l *RedisLease) Acquire(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	n, err := acquireScript.Run(ctx, l.r.client, []string{l.key}, holder, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+	return n == 1, nil
+}
+
+// Release gives the lease up if holder holds it
+func (l *RedisLease) Release(ctx context.Context, holder string) error {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	if err := releaseScript.Run(ctx, l.r.client, []string{l.key}, holder).Err(); err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return nil
+}
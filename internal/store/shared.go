@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Run describes a request a bridge instance is currently working on
+type Run struct {
+	// ID is the correlation ID of the message being answered
+	ID       string    `json:"id"`
+	Instance string    `json:"instance"`
+	ChatID   string    `json:"chat_id"`
+	Backend  string    `json:"backend"`
+	Started  time.Time `json:"started"`
+}
+
+// Shared holds short-lived state that bridge instances must agree on:
+// message dedupe, rate-limit counters and in-flight runs
+type Shared interface {
+	// Claim records key for ttl and reports whether it wasn't already held
+	Claim(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Incr increments the counter at key, which expires ttl after creation
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// StartRun registers a run; it is dropped after ttl if never ended
+	StartRun(ctx context.Context, run Run, ttl time.Duration) error
+	EndRun(ctx context.Context, id string) error
+	// Runs lists the runs in flight across all instances, oldest first
+	Runs(ctx context.Context) ([]Run, error)
+}
+
+type localEntry struct {
+	count   int64
+	run     *Run
+	expires time.Time
+}
+
+// Local is the in-process Shared implementation used for a single instance
+type Local struct {
+	mu      sync.Mutex
+	entries map[string]*localEntry
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewLocal returns an empty Local and starts its expiry sweeper;
+// Close stops it
+func NewLocal() *Local {
+	l := &Local{
+		entries: make(map[string]*localEntry),
+		done:    make(chan struct{}),
+	}
+	go l.cleanup()
+	return l
+}
+
+// Close stops the expiry sweeper
+func (l *Local) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+// get returns the live entry at key; callers must hold mu
+func (l *Local) get(key string, now time.Time) *localEntry {
+	e, ok := l.entries[key]
+	if !ok || now.After(e.expires) {
+		return nil
+	}
+	return e
+}
+
+// Claim implements Shared
+func (l *Local) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.get(key, now) != nil {
+		return false, nil
+	}
+	l.entries[key] = &localEntry{expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Incr implements Shared
+func (l *Local) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	e := l.get(key, now)
+	if e == nil {
+		e = &localEntry{expires: now.Add(ttl)}
+		l.entries[key] = e
+	}
+	e.count++
+	return e.count, nil
+}
+
+// StartRun implements Shared
+func (l *Local) StartRun(ctx context.Context, run Run, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries[runKey(run.ID)] = &localEntry{run: &run, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// EndRun implements Shared
+func (l *Local) EndRun(ctx context.Context, id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, runKey(id))
+	return nil
+}
+
+// Runs implements Shared
+func (l *Local) Runs(ctx context.Context) ([]Run, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	var runs []Run
+	for key := range l.entries {
+		if e := l.get(key, now); e != nil && e.run != nil {
+			runs = append(runs, *e.run)
+		}
+	}
+	sortRuns(runs)
+	return runs, nil
+}
+
+func (l *Local) cleanup() {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		var now time.Time
+		select {
+		case <-l.done:
+			return
+		case now = <-ticker.C:
+		}
+
+		l.mu.Lock()
+		for key, e := range l.entries {
+			if now.After(e.expires) {
+				delete(l.entries, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+func runKey(id string) string {
+	return "run:" + id
+}
+
+func sortRuns(runs []Run) {
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Started.Before(runs[j].Started) })
+}
@@ -0,0 +1,184 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// KV is the bucketed key/value interface used for persisted bridge
+// settings. Store keeps it in a local file; Redis shares it between
+// bridge instances.
+type KV interface {
+	Get(bucket, key string, v interface{}) (bool, error)
+	Set(bucket, key string, v interface{}) error
+	Delete(bucket, key string) error
+	Keys(bucket string) []string
+}
+
+// Store is a small JSON-file backed key/value store for bridge state
+// such as per-chat settings. Values are grouped into named buckets and
+// the file is rewritten atomically on every change.
+//
+// Two processes may have the file open at once, e.g. the old and new
+// bridge during a restart handoff. Each change is made under a lock on
+// path+".lock" after rereading the file if the other process replaced
+// it, so neither overwrites the other's changes, and reads pick up the
+// other's changes too.
+type Store struct {
+	path string
+	data map[string]map[string]json.RawMessage
+	// sum is the hash of the file content data was last read from or
+	// written as. File metadata can't tell: a replacement may reuse the
+	// inode, size and, at the file system's timestamp granularity, the
+	// modification time of the file it replaced.
+	sum [sha256.Size]byte
+	mu  sync.Mutex
+}
+
+// Open loads the store at path, creating an empty one if it doesn't exist
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path: path,
+		data: make(map[string]map[string]json.RawMessage),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load rereads the file and decodes it again if its content changed
+// since it was last read or written; callers must hold mu
+func (s *Store) load() error {
+	raw, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", s.path, err)
+	}
+	sum := sha256.Sum256(raw)
+	if sum == s.sum {
+		return nil
+	}
+
+	data := make(map[string]map[string]json.RawMessage)
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	s.data = data
+	s.sum = sum
+	return nil
+}
+
+// change applies fn to the data under the file lock, after picking up
+// changes from other processes, and persists the result if fn reports a
+// change
+func (s *Store) change(fn func() bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	if !fn() {
+		return nil
+	}
+	return s.save()
+}
+
+// Get decodes the value stored under bucket/key into v.
+// It returns false if the key doesn't exist.
+func (s *Store) Get(bucket, key string, v interface{}) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return false, err
+	}
+	raw, ok := s.data[bucket][key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("failed to decode %s/%s: %w", bucket, key, err)
+	}
+	return true, nil
+}
+
+// Set stores v under bucket/key and persists the store
+func (s *Store) Set(bucket, key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s/%s: %w", bucket, key, err)
+	}
+
+	return s.change(func() bool {
+		if s.data[bucket] == nil {
+			s.data[bucket] = make(map[string]json.RawMessage)
+		}
+		s.data[bucket][key] = raw
+		return true
+	})
+}
+
+// Delete removes bucket/key and persists the store
+func (s *Store) Delete(bucket, key string) error {
+	return s.change(func() bool {
+		if _, ok := s.data[bucket][key]; !ok {
+			return false
+		}
+		delete(s.data[bucket], key)
+		if len(s.data[bucket]) == 0 {
+			delete(s.data, bucket)
+		}
+		return true
+	})
+}
+
+// Keys returns the sorted keys of a bucket
+func (s *Store) Keys(bucket string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		log.Printf("[Store] Using cached state: %v", err)
+	}
+
+	keys := make([]string, 0, len(s.data[bucket]))
+	for k := range s.data[bucket] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// save writes the store to disk; callers must hold mu and the file lock
+func (s *Store) save() error {
+	raw, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode store: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", filepath.Base(s.path), err)
+	}
+	s.sum = sha256.Sum256(raw)
+	return nil
+}
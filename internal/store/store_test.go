@@ -0,0 +1,55 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// TestInterleavedStores has two Stores on one file, as the old and new
+// bridge during a restart handoff, take turns setting values of the same
+// size in quick succession, so the file's size and, at coarse timestamp
+// granularity, its modification time stay the same; every value must
+// survive
+func TestInterleavedStores(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bridge-state.json")
+	a, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const rounds = 20
+	for i := 0; i < rounds; i++ {
+		if err := a.Set("chats", fmt.Sprintf("a%02d", i), "x"); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Set("chats", fmt.Sprintf("b%02d", i), "x"); err != nil {
+			t.Fatal(err)
+		}
+		// Overwriting keeps the size, leaving only the content to differ
+		if err := a.Set("chats", "last", "a"); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Set("chats", "last", "b"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, s := range map[string]*Store{"a": a, "b": b, "reopened": reopened} {
+		if keys := s.Keys("chats"); len(keys) != 2*rounds+1 {
+			t.Errorf("%s has %d keys, want %d: %v", name, len(keys), 2*rounds+1, keys)
+		}
+		var last string
+		if ok, err := s.Get("chats", "last", &last); !ok || err != nil || last != "b" {
+			t.Errorf("%s: last = %q, %v, %v, want b", name, last, ok, err)
+		}
+	}
+}
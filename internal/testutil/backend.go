@@ -0,0 +1,111 @@
+package testutil
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/backend"
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+)
+
+// Ask is one call recorded by FakeBackend
+type Ask struct {
+	AgentID    string
+	Text       string
+	SessionKey string
+}
+
+// FakeBackend answers every message with Reply, streamed to the bridge
+// as a single assistant delta. Set Respond to answer per message instead.
+// It also implements backend.AgentAsker, so agent bindings can be tested.
+type FakeBackend struct {
+	Reply string
+	// Respond, when set, replaces Reply. It may report progress, e.g.
+	// tool calls, before returning.
+	Respond func(ctx context.Context, text string, onProgress backend.ProgressFunc) (string, error)
+
+	mu     sync.Mutex
+	asks   []Ask
+	resets []string
+}
+
+func (f *FakeBackend) Ask(ctx context.Context, text, sessionKey string, onProgress backend.ProgressFunc) (string, error) {
+	return f.AskAgent(ctx, "", text, sessionKey, onProgress)
+}
+
+func (f *FakeBackend) AskAgent(ctx context.Context, agentID, text, sessionKey string, onProgress backend.ProgressFunc) (string, error) {
+	f.mu.Lock()
+	f.asks = append(f.asks, Ask{AgentID: agentID, Text: text, SessionKey: sessionKey})
+	f.mu.Unlock()
+
+	if onProgress == nil {
+		onProgress = func(stream, data string) {}
+	}
+	if f.Respond != nil {
+		return f.Respond(ctx, text, onProgress)
+	}
+	delta, _ := json.Marshal(map[string]string{"delta": f.Reply})
+	onProgress(backend.StreamAssistant, string(delta))
+	return f.Reply, nil
+}
+
+func (f *FakeBackend) ResetSession(sessionKey string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.resets = append(f.resets, sessionKey)
+	return nil
+}
+
+// Asks returns the calls made so far
+func (f *FakeBackend) Asks() []Ask {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Ask(nil), f.asks...)
+}
+
+// Resets returns the sessions reset so far
+func (f *FakeBackend) Resets() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.resets...)
+}
+
+// minimalConfig has the settings every bridge.json needs and routes to
+// an embedded backend
+const minimalConfig = `{
+  "feishu": {"app_id": "cli_test", "app_secret": "test"},
+  "backend": {"type": "embedded"}
+}`
+
+// Config loads bridgeJSON from a temporary config directory, so the
+// result has the same defaults as a real one. An empty bridgeJSON gives
+// a minimal config whose default backend is embedded.
+func Config(tb testing.TB, bridgeJSON string) *config.Config {
+	tb.Helper()
+	if bridgeJSON == "" {
+		bridgeJSON = minimalConfig
+	}
+	dir := tb.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bridge.json"), []byte(bridgeJSON), 0600); err != nil {
+		tb.Fatalf("failed to write bridge.json: %v", err)
+	}
+	cfg, err := config.LoadDir(dir)
+	if err != nil {
+		tb.Fatalf("failed to load config: %v", err)
+	}
+	return cfg
+}
+
+// Router routes cfg's embedded backends to the given fakes, by name
+func Router(tb testing.TB, cfg *config.Config, backends map[string]backend.Backend) *backend.Router {
+	tb.Helper()
+	r, err := backend.NewRouter(cfg, nil, backends)
+	if err != nil {
+		tb.Fatalf("failed to create router: %v", err)
+	}
+	return r
+}
@@ -0,0 +1,523 @@
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	larkws "github.com/larksuite/oapi-sdk-go/v3/ws"
+)
+
+// pushTimeout bounds how long Push waits for the client to acknowledge an
+// event
+const pushTimeout = 10 * time.Second
+
+// FeishuServer is a fake Feishu open platform for integration tests. It
+// serves the token, message, image and file APIs feishu.Client calls and
+// its long connection, over which tests push events. Point a client at
+// it with feishu.WithDomain(s.URL).
+type FeishuServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	messages  []*Message
+	byID      map[string]*Message
+	uploads   map[string]upload
+	nextID    int
+	nextEvent int
+	conn      *websocket.Conn
+	// secrets are the app secrets long connection handshakes used
+	secrets   []string
+	writeMu   sync.Mutex
+	acks      map[string]chan int
+	connected chan struct{}
+	latency   time.Duration
+	onChange  func(Message)
+	// updateLimit, when set, is how many edits per second succeed before
+	// the rest are rejected as rate limited
+	updateLimit int
+	window      time.Time
+	windowEdits int
+	rateLimited int
+}
+
+// upload is an image or file uploaded before it's sent
+type upload struct {
+	name string
+	data []byte
+}
+
+// NewFeishuServer starts a fake Feishu server; Close stops it
+func NewFeishuServer() *FeishuServer {
+	s := &FeishuServer{
+		byID:      make(map[string]*Message),
+		uploads:   make(map[string]upload),
+		acks:      make(map[string]chan int),
+		connected: make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/open-apis/auth/v3/tenant_access_token/internal", s.handleToken)
+	mux.HandleFunc("/open-apis/auth/v3/app_access_token/internal", s.handleToken)
+	mux.HandleFunc("/open-apis/im/v1/messages", s.handleCreate)
+	mux.HandleFunc("/open-apis/im/v1/messages/", s.handleMessage)
+	mux.HandleFunc("/open-apis/im/v1/images", s.handleImage)
+	mux.HandleFunc("/open-apis/im/v1/files", s.handleFile)
+	mux.HandleFunc(larkws.GenEndpointUri, s.handleEndpoint)
+	mux.HandleFunc("/ws", s.handleWS)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Connected is closed once a client opens the long connection
+func (s *FeishuServer) Connected() <-chan struct{} {
+	return s.connected
+}
+
+// SetLatency delays every message API call by d, like a remote Feishu
+func (s *FeishuServer) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// OnChange registers fn to be called with a copy of each message sent or
+// updated, as it's stored. It replaces any earlier callback.
+func (s *FeishuServer) OnChange(fn func(Message)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChange = fn
+}
+
+// SetUpdateLimit makes edits beyond perSecond in any second fail with
+// Feishu's message frequency limit code; 0 removes the limit
+func (s *FeishuServer) SetUpdateLimit(perSecond int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.updateLimit = perSecond
+}
+
+// RateLimited returns how many edits were rejected by the update limit
+func (s *FeishuServer) RateLimited() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rateLimited
+}
+
+// allowEdit counts an edit against the update limit and reports whether
+// it may proceed; callers hold mu
+func (s *FeishuServer) allowEdit() bool {
+	if s.updateLimit <= 0 {
+		return true
+	}
+	if now := time.Now(); now.Sub(s.window) >= time.Second {
+		s.window, s.windowEdits = now, 0
+	}
+	if s.windowEdits >= s.updateLimit {
+		s.rateLimited++
+		return false
+	}
+	s.windowEdits++
+	return true
+}
+
+// delay waits out the configured latency
+func (s *FeishuServer) delay() {
+	s.mu.Lock()
+	d := s.latency
+	s.mu.Unlock()
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// changed reports m to the OnChange callback; callers hold mu and call
+// the returned function once they release it
+func (s *FeishuServer) changed(m *Message) func() {
+	fn, snapshot := s.onChange, *m
+	return func() {
+		if fn != nil {
+			fn(snapshot)
+		}
+	}
+}
+
+// Messages returns copies of the messages clients sent, in order,
+// including deleted ones. Text holds the text of text messages, the file
+// name of files, the image key of images and the content JSON otherwise;
+// Data holds uploaded bytes.
+func (s *FeishuServer) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Message, len(s.messages))
+	for i, m := range s.messages {
+		out[i] = *m
+	}
+	return out
+}
+
+// Visible returns the messages in chatID that weren't deleted
+func (s *FeishuServer) Visible(chatID string) []Message {
+	var out []Message
+	for _, m := range s.Messages() {
+		if m.ChatID == chatID && !m.Deleted {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// WaitFor polls until cond holds for the messages sent so far or timeout
+// passes, and reports whether it held
+func (s *FeishuServer) WaitFor(timeout time.Duration, cond func([]Message) bool) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if cond(s.Messages()) {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// reply writes a Feishu API response
+func reply(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"code": 0, "msg": "success", "data": data})
+}
+
+// fail writes a Feishu API error
+func fail(w http.ResponseWriter, status, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"code": code, "msg": msg})
+}
+
+func (s *FeishuServer) handleToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":                0,
+		"msg":                 "ok",
+		"tenant_access_token": "t-fake",
+		"app_access_token":    "a-fake",
+		"expire":              7200,
+	})
+}
+
+// messageBody is the body of create and update message requests
+type messageBody struct {
+	ReceiveID string `json:"receive_id"`
+	MsgType   string `json:"msg_type"`
+	Content   string `json:"content"`
+}
+
+func (s *FeishuServer) handleCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		fail(w, http.StatusMethodNotAllowed, 1, "method not allowed")
+		return
+	}
+	var body messageBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		fail(w, http.StatusBadRequest, 99992402, "invalid body")
+		return
+	}
+	s.delay()
+
+	s.mu.Lock()
+	s.nextID++
+	m := &Message{ID: fmt.Sprintf("om_sent_%d", s.nextID), ChatID: body.ReceiveID}
+	s.setContent(m, body.MsgType, body.Content)
+	s.messages = append(s.messages, m)
+	s.byID[m.ID] = m
+	notify := s.changed(m)
+	s.mu.Unlock()
+	notify()
+	reply(w, map[string]interface{}{"message_id": m.ID, "chat_id": m.ChatID, "msg_type": body.MsgType})
+}
+
+// handleMessage serves update (PUT), card update (PATCH) and delete
+func (s *FeishuServer) handleMessage(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/open-apis/im/v1/messages/")
+	var body messageBody
+	if r.Method != http.MethodDelete {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			fail(w, http.StatusBadRequest, 99992402, "invalid body")
+			return
+		}
+	}
+	s.delay()
+
+	s.mu.Lock()
+	m, ok := s.byID[id]
+	if !ok || m.Deleted {
+		s.mu.Unlock()
+		fail(w, http.StatusBadRequest, 230011, "message not found")
+		return
+	}
+	if (r.Method == http.MethodPut || r.Method == http.MethodPatch) && !s.allowEdit() {
+		s.mu.Unlock()
+		fail(w, http.StatusBadRequest, 230020, "message operation frequency limit")
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		s.setContent(m, body.MsgType, body.Content)
+		m.Updates++
+	case http.MethodPatch:
+		s.setContent(m, "interactive", body.Content)
+		m.Updates++
+	case http.MethodDelete:
+		m.Deleted = true
+	default:
+		s.mu.Unlock()
+		fail(w, http.StatusMethodNotAllowed, 1, "method not allowed")
+		return
+	}
+	notify := s.changed(m)
+	s.mu.Unlock()
+	notify()
+	reply(w, map[string]interface{}{})
+}
+
+// setContent records a message's type and content; callers hold mu
+func (s *FeishuServer) setContent(m *Message, msgType, content string) {
+	var c struct {
+		Text     string `json:"text"`
+		ImageKey string `json:"image_key"`
+		FileKey  string `json:"file_key"`
+	}
+	_ = json.Unmarshal([]byte(content), &c)
+
+	m.Text, m.Data = content, nil
+	switch msgType {
+	case "text":
+		m.Kind, m.Text = KindText, c.Text
+	case "post":
+		m.Kind = KindPost
+	case "interactive":
+		m.Kind = KindCard
+	case "image":
+		m.Kind, m.Text, m.Data = KindImage, c.ImageKey, s.uploads[c.ImageKey].data
+	case "file":
+		m.Kind, m.Text, m.Data = KindFile, s.uploads[c.FileKey].name, s.uploads[c.FileKey].data
+	default:
+		m.Kind = msgType
+	}
+}
+
+func (s *FeishuServer) handleImage(w http.ResponseWriter, r *http.Request) {
+	key, ok := s.receiveUpload(w, r, "image", "img_fake")
+	if ok {
+		reply(w, map[string]interface{}{"image_key": key})
+	}
+}
+
+func (s *FeishuServer) handleFile(w http.ResponseWriter, r *http.Request) {
+	key, ok := s.receiveUpload(w, r, "file", "file_fake")
+	if ok {
+		reply(w, map[string]interface{}{"file_key": key})
+	}
+}
+
+// receiveUpload keeps the multipart field of an upload request under a
+// new key
+func (s *FeishuServer) receiveUpload(w http.ResponseWriter, r *http.Request, field, prefix string) (string, bool) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		fail(w, http.StatusBadRequest, 99992402, "invalid multipart body")
+		return "", false
+	}
+	f, _, err := r.FormFile(field)
+	if err != nil {
+		fail(w, http.StatusBadRequest, 99992402, "missing "+field)
+		return "", false
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		fail(w, http.StatusBadRequest, 99992402, "failed to read "+field)
+		return "", false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := fmt.Sprintf("%s_%d", prefix, len(s.uploads)+1)
+	s.uploads[key] = upload{name: r.FormValue("file_name"), data: data}
+	return key, true
+}
+
+// HandshakeSecrets returns the app secrets the client opened long
+// connections with, in order
+func (s *FeishuServer) HandshakeSecrets() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.secrets...)
+}
+
+// handleEndpoint hands out the long connection URL
+func (s *FeishuServer) handleEndpoint(w http.ResponseWriter, r *http.Request) {
+	var creds struct {
+		AppSecret string
+	}
+	json.NewDecoder(r.Body).Decode(&creds)
+	s.mu.Lock()
+	s.secrets = append(s.secrets, creds.AppSecret)
+	s.mu.Unlock()
+
+	wsURL := "ws" + strings.TrimPrefix(s.URL, "http") + "/ws?device_id=fake&service_id=1"
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(larkws.EndpointResp{
+		Code: larkws.OK,
+		Data: &larkws.Endpoint{
+			Url: wsURL,
+			ClientConfig: &larkws.ClientConfig{
+				ReconnectCount:    0,
+				ReconnectInterval: 1,
+				PingInterval:      120,
+			},
+		},
+	})
+}
+
+func (s *FeishuServer) handleWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	first := s.conn == nil
+	s.conn = conn
+	s.mu.Unlock()
+	if first {
+		close(s.connected)
+	}
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var frame larkws.Frame
+		if err := frame.Unmarshal(data); err != nil {
+			continue
+		}
+		headers := larkws.Headers(frame.Headers)
+		switch larkws.FrameType(frame.Method) {
+		case larkws.FrameTypeControl:
+			if headers.GetString(larkws.HeaderType) == string(larkws.MessageTypePing) {
+				pong := larkws.Frame{Method: int32(larkws.FrameTypeControl), Service: frame.Service}
+				pong.Headers = []larkws.Header{{Key: larkws.HeaderType, Value: string(larkws.MessageTypePong)}}
+				s.write(conn, &pong)
+			}
+		case larkws.FrameTypeData:
+			var resp larkws.Response
+			_ = json.Unmarshal(frame.Payload, &resp)
+			s.mu.Lock()
+			ack := s.acks[headers.GetString(larkws.HeaderMessageID)]
+			s.mu.Unlock()
+			if ack != nil {
+				ack <- resp.StatusCode
+			}
+		}
+	}
+}
+
+// write sends a frame on the long connection
+func (s *FeishuServer) write(conn *websocket.Conn, frame *larkws.Frame) error {
+	data, err := frame.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to encode frame: %w", err)
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+// Push delivers an event of eventType to the connected client and waits
+// for it to be handled. event is the event body, e.g. the sender and
+// message of im.message.receive_v1.
+func (s *FeishuServer) Push(eventType string, event interface{}) error {
+	s.mu.Lock()
+	conn := s.conn
+	s.nextEvent++
+	id := fmt.Sprintf("ev_fake_%d", s.nextEvent)
+	ack := make(chan int, 1)
+	s.acks[id] = ack
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.acks, id)
+		s.mu.Unlock()
+	}()
+	if conn == nil {
+		return fmt.Errorf("no client is connected")
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"schema": "2.0",
+		"header": map[string]interface{}{
+			"event_id":    id,
+			"event_type":  eventType,
+			"create_time": strconv.FormatInt(time.Now().UnixMilli(), 10),
+			"token":       "",
+			"app_id":      "cli_test",
+			"tenant_key":  "fake",
+		},
+		"event": event,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	frame := larkws.Frame{Method: int32(larkws.FrameTypeData), Service: 1, Payload: payload}
+	frame.Headers = []larkws.Header{
+		{Key: larkws.HeaderType, Value: string(larkws.MessageTypeEvent)},
+		{Key: larkws.HeaderMessageID, Value: id},
+		{Key: larkws.HeaderTraceID, Value: id},
+		{Key: larkws.HeaderSum, Value: "1"},
+		{Key: larkws.HeaderSeq, Value: "0"},
+	}
+	if err := s.write(conn, &frame); err != nil {
+		return fmt.Errorf("failed to push event: %w", err)
+	}
+
+	select {
+	case code := <-ack:
+		if code != http.StatusOK {
+			return fmt.Errorf("client failed to handle %s: status %d", eventType, code)
+		}
+		return nil
+	case <-time.After(pushTimeout):
+		return fmt.Errorf("client didn't acknowledge %s", eventType)
+	}
+}
+
+// PushText delivers a text message from senderID in chatID, as
+// im.message.receive_v1. chatType is "p2p" or "group".
+func (s *FeishuServer) PushText(chatID, chatType, senderID, text string) error {
+	content, _ := json.Marshal(map[string]string{"text": text})
+	s.mu.Lock()
+	s.nextID++
+	id := fmt.Sprintf("om_recv_%d", s.nextID)
+	s.mu.Unlock()
+	return s.Push("im.message.receive_v1", map[string]interface{}{
+		"sender": map[string]interface{}{
+			"sender_id":   map[string]string{"open_id": senderID},
+			"sender_type": "user",
+		},
+		"message": map[string]interface{}{
+			"message_id":   id,
+			"chat_id":      chatID,
+			"chat_type":    chatType,
+			"message_type": "text",
+			"content":      string(content),
+			"create_time":  strconv.FormatInt(time.Now().UnixMilli(), 10),
+		},
+	})
+}
@@ -0,0 +1,213 @@
+// Package golden runs scripted conversations through the bridge and
+// compares the Feishu operations they cause with golden files. It's kept
+// apart from testutil so the bridge's own tests can use the fakes.
+package golden
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/backend"
+	"github.com/wy51ai/moltbotCNAPP/internal/bridge"
+	"github.com/wy51ai/moltbotCNAPP/internal/store"
+	"github.com/wy51ai/moltbotCNAPP/internal/testutil"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// UpdateEnv names the environment variable that makes Run write
+// the transcripts it produces instead of comparing them
+const UpdateEnv = "UPDATE_GOLDEN"
+
+// update does the same as UpdateEnv, as in go test ./internal/bridge -update
+var update = flag.Bool("update", false, "write golden files instead of comparing with them")
+
+// settleTime is how long the Feishu operations must stay unchanged
+// after a message's runs finish before the next message is sent, so
+// commands and follow-up cards land in the transcript in order
+const settleTime = 200 * time.Millisecond
+
+// messageTimeout bounds how long one scripted message may take
+const messageTimeout = 30 * time.Second
+
+// Script is a scripted conversation: the bridge.json to run with,
+// the gateway recordings its embedded backends replay, and the messages
+// that arrive, one after the other
+type Script struct {
+	// Config is bridge.json; empty means a minimal config whose default
+	// backend is embedded
+	Config json.RawMessage `json:"config,omitempty"`
+	// Recordings is a directory of recorded gateway runs, relative to
+	// the script. Every embedded backend replays them without waiting.
+	Recordings string    `json:"recordings,omitempty"`
+	Messages   []Message `json:"messages"`
+}
+
+// Message is one inbound Feishu message of a script
+type Message struct {
+	ChatID string `json:"chat_id"`
+	// ChatType is "p2p" (default) or "group"
+	ChatType string `json:"chat_type,omitempty"`
+	SenderID string `json:"sender_id,omitempty"`
+	Text     string `json:"text"`
+	// Mention @-mentions the bot
+	Mention bool `json:"mention,omitempty"`
+}
+
+// Run plays the script at path (a JSON Script) through a Bridge
+// and compares every Feishu operation it makes, in order, with the file
+// of the same name ending in .golden. With -update, or UPDATE_GOLDEN=1 in
+// the environment, it writes that file instead.
+func Run(t *testing.T, path string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read script: %v", err)
+	}
+	var script Script
+	if err := json.Unmarshal(data, &script); err != nil {
+		t.Fatalf("failed to parse %s: %v", path, err)
+	}
+
+	got := Play(t, filepath.Dir(path), &script)
+	goldenPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".golden"
+	if *update || os.Getenv(UpdateEnv) != "" {
+		if err := os.WriteFile(goldenPath, []byte(got), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", goldenPath, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("failed to read %s (run with -update to create it): %v", goldenPath, err)
+	}
+	if got != string(want) {
+		t.Errorf("Feishu operations differ from %s (run with -update to accept):\n%s", goldenPath, lineDiff(string(want), got))
+	}
+}
+
+// Play plays script through a Bridge and returns its transcript:
+// each inbound message as a "> " line followed by the operations it
+// caused. dir resolves the script's recordings.
+func Play(t *testing.T, dir string, script *Script) string {
+	t.Helper()
+	cfg := testutil.Config(t, string(script.Config))
+
+	var agent backend.Backend = &testutil.FakeBackend{Respond: func(ctx context.Context, text string, onProgress backend.ProgressFunc) (string, error) {
+		return "", errors.New("the script has no recordings")
+	}}
+	if script.Recordings != "" {
+		replay, err := backend.NewReplayClient(filepath.Join(dir, script.Recordings), 0, nil)
+		if err != nil {
+			t.Fatalf("failed to load recordings: %v", err)
+		}
+		agent = replay
+	}
+	embedded := make(map[string]backend.Backend)
+	for name, bc := range cfg.Backends {
+		if bc.Type == "embedded" {
+			embedded[name] = agent
+		}
+	}
+	router := testutil.Router(t, cfg, embedded)
+
+	st, err := store.Open(filepath.Join(t.TempDir(), "bridge-state.json"))
+	if err != nil {
+		t.Fatalf("failed to open state store: %v", err)
+	}
+	shared := store.NewLocal()
+	t.Cleanup(func() { shared.Close() })
+
+	sender := testutil.NewFakeSender()
+	b := bridge.NewBridge(sender, router, st, shared, cfg)
+	b.SetFeishuClient(sender)
+
+	var out strings.Builder
+	seen := 0
+	for i, m := range script.Messages {
+		msg := m.message(i)
+		fmt.Fprintf(&out, "> %s %s %s: %q\n", msg.ChatType, msg.ChatID, msg.SenderID, m.Text)
+		if err := b.HandleMessage(context.Background(), msg); err != nil {
+			t.Fatalf("failed to handle message %d: %v", i+1, err)
+		}
+		settle(t, b, sender, i)
+
+		ops := sender.Ops()
+		for _, op := range ops[seen:] {
+			out.WriteString(op.String() + "\n")
+		}
+		seen = len(ops)
+	}
+	return out.String()
+}
+
+// settle waits for the runs of message i to finish and the operations
+// they cause to stop
+func settle(t *testing.T, b *bridge.Bridge, sender *testutil.FakeSender, i int) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), messageTimeout)
+	defer cancel()
+	if err := b.Drain(ctx); err != nil {
+		t.Fatalf("message %d didn't finish: %v", i+1, err)
+	}
+	n := len(sender.Ops())
+	for {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("message %d kept sending to Feishu", i+1)
+		case <-time.After(settleTime):
+		}
+		if now := len(sender.Ops()); now != n {
+			n = now
+			continue
+		}
+		return
+	}
+}
+
+// message builds the i-th message of a script as Feishu would deliver it
+func (m Message) message(i int) *feishu.Message {
+	msg := &feishu.Message{
+		MessageID: fmt.Sprintf("om_script_%d", i+1),
+		ChatID:    m.ChatID,
+		ChatType:  m.ChatType,
+		SenderID:  m.SenderID,
+		Content:   m.Text,
+	}
+	if msg.ChatType == "" {
+		msg.ChatType = "p2p"
+	}
+	if msg.SenderID == "" {
+		msg.SenderID = "ou_script"
+	}
+	if m.Mention {
+		msg.Content = "@_user_1 " + msg.Content
+		msg.Mentions = []feishu.Mention{{Key: "@_user_1", Name: "bot"}}
+	}
+	return msg
+}
+
+// lineDiff lists the lines of want and got from the first that differs
+func lineDiff(want, got string) string {
+	wl, gl := strings.Split(want, "\n"), strings.Split(got, "\n")
+	i := 0
+	for i < len(wl) && i < len(gl) && wl[i] == gl[i] {
+		i++
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "first difference at line %d\n", i+1)
+	for _, l := range wl[i:] {
+		b.WriteString("- " + l + "\n")
+	}
+	for _, l := range gl[i:] {
+		b.WriteString("+ " + l + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,309 @@
+// Package testutil provides fakes of the Feishu client and agent backends
+// so the bridge can be exercised without real services.
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// Message kinds recorded by FakeSender
+const (
+	KindText  = "text"
+	KindPost  = "post"
+	KindCard  = "card"
+	KindFile  = "file"
+	KindImage = "image"
+)
+
+// Message is a message as last sent or updated through FakeSender
+type Message struct {
+	ID     string
+	ChatID string
+	Kind   string
+	// Text is the text or post content, the card JSON, or the file name
+	Text    string
+	Data    []byte
+	Updates int
+	Deleted bool
+	// Deferred is set for replies queued with DeferReply
+	Deferred bool
+}
+
+// Op is one call made on FakeSender, in the order the calls were made
+type Op struct {
+	// Action is send, update, delete, upload or defer
+	Action    string
+	MessageID string
+	ChatID    string
+	Kind      string
+	Text      string
+	Size      int
+	// Err is set when the call failed
+	Err string
+}
+
+// String renders op as one line of a golden transcript
+func (op Op) String() string {
+	var b strings.Builder
+	b.WriteString(op.Action)
+	if op.Kind != "" {
+		b.WriteString(" " + op.Kind)
+	}
+	for _, field := range []string{op.MessageID, op.ChatID} {
+		if field != "" {
+			b.WriteString(" " + field)
+		}
+	}
+	if op.Text != "" {
+		b.WriteString(" " + strconv.Quote(op.Text))
+	}
+	if op.Size > 0 {
+		fmt.Fprintf(&b, " (%d bytes)", op.Size)
+	}
+	if op.Err != "" {
+		b.WriteString(" error: " + op.Err)
+	}
+	return b.String()
+}
+
+// FakeSender records what the bridge sends instead of calling Feishu.
+// It's safe for concurrent use.
+type FakeSender struct {
+	mu       sync.Mutex
+	ops      []Op
+	messages []*Message
+	byID     map[string]*Message
+	images   map[string][]byte
+	nextID   int
+	err      error
+	circuit  feishu.CircuitStatus
+	onChange func(feishu.CircuitStatus)
+}
+
+// NewFakeSender returns a FakeSender with a closed circuit
+func NewFakeSender() *FakeSender {
+	return &FakeSender{
+		byID:    make(map[string]*Message),
+		images:  make(map[string][]byte),
+		circuit: feishu.CircuitStatus{State: feishu.CircuitClosed, Since: time.Now()},
+	}
+}
+
+// FailWith makes every later call return err; nil makes them succeed again
+func (f *FakeSender) FailWith(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+// SetCircuit reports status from Circuit and passes it to the
+// OnCircuitChange callback
+func (f *FakeSender) SetCircuit(status feishu.CircuitStatus) {
+	f.mu.Lock()
+	f.circuit = status
+	fn := f.onChange
+	f.mu.Unlock()
+	if fn != nil {
+		fn(status)
+	}
+}
+
+// Messages returns copies of the messages sent so far, in order,
+// including deleted ones
+func (f *FakeSender) Messages() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]Message, len(f.messages))
+	for i, m := range f.messages {
+		out[i] = *m
+	}
+	return out
+}
+
+// Ops returns the calls made so far, in order
+func (f *FakeSender) Ops() []Op {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Op(nil), f.ops...)
+}
+
+// record appends op, with err if the call failed; callers hold mu
+func (f *FakeSender) record(op Op, err error) {
+	if err != nil {
+		op.Err = err.Error()
+	}
+	f.ops = append(f.ops, op)
+}
+
+// Visible returns the messages in chatID that weren't deleted
+func (f *FakeSender) Visible(chatID string) []Message {
+	var out []Message
+	for _, m := range f.Messages() {
+		if m.ChatID == chatID && !m.Deleted {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// send records a new message and returns its ID
+func (f *FakeSender) send(chatID, kind, text string, data []byte) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	op := Op{Action: "send", ChatID: chatID, Kind: kind, Text: text, Size: len(data)}
+	if f.err != nil {
+		f.record(op, f.err)
+		return "", f.err
+	}
+	f.nextID++
+	m := &Message{ID: fmt.Sprintf("om_fake_%d", f.nextID), ChatID: chatID, Kind: kind, Text: text, Data: data}
+	f.messages = append(f.messages, m)
+	f.byID[m.ID] = m
+	op.MessageID = m.ID
+	f.record(op, nil)
+	return m.ID, nil
+}
+
+// update replaces a message's content
+func (f *FakeSender) update(messageID, kind, text string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	op := Op{Action: "update", MessageID: messageID, Kind: kind, Text: text}
+	if f.err != nil {
+		f.record(op, f.err)
+		return f.err
+	}
+	m, ok := f.byID[messageID]
+	if !ok || m.Deleted {
+		err := fmt.Errorf("message %s not found", messageID)
+		f.record(op, err)
+		return err
+	}
+	m.Kind, m.Text = kind, text
+	m.Updates++
+	f.record(op, nil)
+	return nil
+}
+
+func (f *FakeSender) SendMessage(chatID, text string) (string, error) {
+	return f.send(chatID, KindText, text, nil)
+}
+
+func (f *FakeSender) UpdateMessage(messageID, text string) error {
+	return f.update(messageID, KindText, text)
+}
+
+func (f *FakeSender) DeleteMessage(messageID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	op := Op{Action: "delete", MessageID: messageID}
+	if f.err != nil {
+		f.record(op, f.err)
+		return f.err
+	}
+	m, ok := f.byID[messageID]
+	if !ok || m.Deleted {
+		err := fmt.Errorf("message %s not found", messageID)
+		f.record(op, err)
+		return err
+	}
+	m.Deleted = true
+	f.record(op, nil)
+	return nil
+}
+
+func (f *FakeSender) SendPost(chatID, text string) (string, error) {
+	return f.send(chatID, KindPost, text, nil)
+}
+
+func (f *FakeSender) UpdatePost(messageID, text string) error {
+	return f.update(messageID, KindPost, text)
+}
+
+func (f *FakeSender) SendCard(chatID string, card *feishu.Card) (string, error) {
+	content, err := json.Marshal(card)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode card: %w", err)
+	}
+	return f.send(chatID, KindCard, string(content), nil)
+}
+
+func (f *FakeSender) UpdateCard(messageID string, card *feishu.Card) error {
+	content, err := json.Marshal(card)
+	if err != nil {
+		return fmt.Errorf("failed to encode card: %w", err)
+	}
+	return f.update(messageID, KindCard, string(content))
+}
+
+func (f *FakeSender) SendFile(chatID, name string, data []byte) (string, error) {
+	return f.send(chatID, KindFile, name, data)
+}
+
+func (f *FakeSender) SendImage(chatID string, data []byte) (string, error) {
+	return f.send(chatID, KindImage, "", data)
+}
+
+// UploadImage keeps the image; Image returns it by key
+func (f *FakeSender) UploadImage(data []byte) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	op := Op{Action: "upload", Kind: KindImage, Size: len(data)}
+	if f.err != nil {
+		f.record(op, f.err)
+		return "", f.err
+	}
+	key := fmt.Sprintf("img_fake_%d", len(f.images)+1)
+	f.images[key] = data
+	op.MessageID = key
+	f.record(op, nil)
+	return key, nil
+}
+
+// Image returns an image uploaded with UploadImage
+func (f *FakeSender) Image(key string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.images[key]
+	return data, ok
+}
+
+// DeferReply applies the reply right away, marked Deferred
+func (f *FakeSender) DeferReply(chatID, messageID, text string, post bool) {
+	kind := KindText
+	if post {
+		kind = KindPost
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if m, ok := f.byID[messageID]; ok && !m.Deleted {
+		m.Kind, m.Text, m.Deferred = kind, text, true
+		m.Updates++
+		f.record(Op{Action: "defer", MessageID: messageID, Kind: kind, Text: text}, nil)
+		return
+	}
+	f.nextID++
+	m := &Message{ID: fmt.Sprintf("om_fake_%d", f.nextID), ChatID: chatID, Kind: kind, Text: text, Deferred: true}
+	f.messages = append(f.messages, m)
+	f.byID[m.ID] = m
+	f.record(Op{Action: "defer", MessageID: m.ID, ChatID: chatID, Kind: kind, Text: text}, nil)
+}
+
+func (f *FakeSender) Circuit() feishu.CircuitStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.circuit
+}
+
+func (f *FakeSender) OnCircuitChange(fn func(feishu.CircuitStatus)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onChange = fn
+}
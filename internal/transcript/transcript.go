@@ -0,0 +1,255 @@
+package transcript
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/jsonl"
+	"github.com/wy51ai/moltbotCNAPP/internal/seal"
+)
+
+// Record kinds
+const (
+	KindMessage = "message"
+	KindCommand = "command"
+	KindSurvey  = "survey"
+)
+
+// Record is one line of a daily transcript file
+type Record struct {
+	Time     time.Time `json:"time"`
+	Kind     string    `json:"kind"`
+	ChatID   string    `json:"chat_id"`
+	ChatType string    `json:"chat_type,omitempty"`
+	UserID   string    `json:"user_id,omitempty"`
+	Backend  string    `json:"backend,omitempty"`
+	Agent    string    `json:"agent,omitempty"`
+	// Command is the bridge command name for KindCommand records
+	Command      string `json:"command,omitempty"`
+	LatencyMs    int64  `json:"latency_ms,omitempty"`
+	InputTokens  int    `json:"input_tokens,omitempty"`
+	OutputTokens int    `json:"output_tokens,omitempty"`
+	Error        string `json:"error,omitempty"`
+	RunID        string `json:"run_id,omitempty"`
+	// Tag is the conversation's category, when classification is on
+	Tag string `json:"tag,omitempty"`
+	// Helpful is the answer of a KindSurvey record
+	Helpful bool `json:"helpful,omitempty"`
+	// CorrelationID links the record to the debug log (cid=...)
+	CorrelationID string `json:"cid,omitempty"`
+	// Prompt and Reply are only kept when content storage is enabled
+	Prompt string `json:"prompt,omitempty"`
+	Reply  string `json:"reply,omitempty"`
+}
+
+// Store appends records to one JSONL file per day
+type Store struct {
+	dir          string
+	storeContent bool
+	// keys seals each line when encryption is configured
+	keys *seal.Keyring
+	mu   sync.Mutex
+}
+
+// Open prepares dir for transcript files. Without storeContent only
+// metadata (counts, latency, tokens) is kept, never message text. With
+// keys every record is encrypted; unencrypted records written earlier
+// stay readable.
+func Open(dir string, storeContent bool, keys *seal.Keyring) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create transcript dir: %w", err)
+	}
+	return &Store{dir: dir, storeContent: storeContent, keys: keys}, nil
+}
+
+// dayFile returns the file holding records of day
+func (s *Store) dayFile(day time.Time) string {
+	return filepath.Join(s.dir, day.Format("2006-01-02")+".jsonl")
+}
+
+// Append writes r; failures are logged so recording never blocks a reply
+func (s *Store) Append(r Record) {
+	if s == nil {
+		return
+	}
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	if !s.storeContent {
+		r.Prompt, r.Reply = "", ""
+	}
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		log.Printf("[Transcript] Failed to encode record: %v", err)
+		return
+	}
+	line = s.keys.Seal(line)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.dayFile(r.Time)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Printf("[Transcript] Failed to open %s: %v", path, err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Printf("[Transcript] Failed to write record: %v", err)
+	}
+}
+
+// days returns the dates of all transcript files, oldest first
+func (s *Store) days() ([]time.Time, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transcripts: %w", err)
+	}
+	var days []time.Time
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		day, err := time.ParseInLocation("2006-01-02", strings.TrimSuffix(name, ".jsonl"), time.Local)
+		if err != nil {
+			continue
+		}
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days, nil
+}
+
+// Query calls fn for every record in [since, until) in time order.
+// Returning false from fn stops the scan; fn must not call Append.
+func (s *Store) Query(since, until time.Time, fn func(Record) bool) error {
+	days, err := s.days()
+	if err != nil {
+		return err
+	}
+
+	for _, day := range days {
+		if !day.AddDate(0, 0, 1).After(since) || !day.Before(until) {
+			continue
+		}
+		more, err := s.scanFile(s.dayFile(day), since, until, fn)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+	return nil
+}
+
+// scanFile feeds the records of one file in [since, until) to fn
+func (s *Store) scanFile(path string, since, until time.Time, fn func(Record) bool) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	unreadable := 0
+	defer func() {
+		if unreadable > 0 {
+			log.Printf("[Transcript] Skipped %d records in %s that couldn't be decrypted", unreadable, path)
+		}
+	}()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line, err := s.keys.Open(scanner.Bytes())
+		if err != nil {
+			unreadable++
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(line, &r); err != nil {
+			continue
+		}
+		if r.Time.Before(since) || !r.Time.Before(until) {
+			continue
+		}
+		if !fn(r) {
+			return false, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// DeleteUser removes every record of userID and returns how many were
+// removed
+func (s *Store) DeleteUser(userID string) (int, error) {
+	if s == nil || userID == "" {
+		return 0, nil
+	}
+	days, err := s.days()
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := 0
+	for _, day := range days {
+		n, err := jsonl.Filter(s.dayFile(day), func(line []byte) bool {
+			plain, err := s.keys.Open(line)
+			var r Record
+			return err == nil && json.Unmarshal(plain, &r) == nil && r.UserID == userID
+		})
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Rekey re-encrypts every record not sealed with the active key,
+// including records written before encryption was enabled, and returns
+// how many were rewritten. Afterwards older keys can be dropped from the
+// keyring. Records no key in the keyring can decrypt are left as they are.
+func (s *Store) Rekey() (int, error) {
+	if s.keys == nil {
+		return 0, fmt.Errorf("encryption is not configured")
+	}
+	days, err := s.days()
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := 0
+	for _, day := range days {
+		n, err := jsonl.Rewrite(s.dayFile(day), s.keys.Reseal)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,178 @@
+// Package bridge runs the Feishu bridge inside another program. It reads
+// bridge.json like the clawdbot-bridge command and answers messages with
+// the configured backends, plus any connectors the program supplies:
+//
+//	b, err := bridge.New(bridge.Options{
+//		Connectors: map[string]connector.Connector{"default": myAgent},
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer b.Close()
+//	log.Fatal(b.Run(ctx))
+//
+// with "backend": {"type": "embedded"} in bridge.json. Primary/standby
+// failover and credential rotation are left to the command.
+//
+// Exported names follow semantic versioning: they only change
+// incompatibly with a new major version of the module.
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/backend"
+	engine "github.com/wy51ai/moltbotCNAPP/internal/bridge"
+	"github.com/wy51ai/moltbotCNAPP/internal/cluster"
+	"github.com/wy51ai/moltbotCNAPP/internal/config"
+	"github.com/wy51ai/moltbotCNAPP/internal/netdial"
+	"github.com/wy51ai/moltbotCNAPP/internal/redact"
+	"github.com/wy51ai/moltbotCNAPP/internal/store"
+	"github.com/wy51ai/moltbotCNAPP/pkg/connector"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// Options configures an embedded bridge
+type Options struct {
+	// ConfigDir holds bridge.json, and clawdbot.json when a gateway
+	// backend is configured. Empty means ~/.clawdbot or ~/.openclaw.
+	ConfigDir string
+	// Connectors answer for the backends of type "embedded" in
+	// bridge.json, by backend name
+	Connectors map[string]connector.Connector
+	// FeishuOptions are passed to feishu.NewClient, e.g. WithDomain for
+	// Lark or a test server
+	FeishuOptions []feishu.Option
+}
+
+// Bridge is a configured bridge, ready to Run
+type Bridge struct {
+	cfg     *config.Config
+	engine  *engine.Bridge
+	feishu  *feishu.Client
+	closers []func() error
+}
+
+// New loads the configuration and sets up the bridge. It doesn't connect
+// to Feishu until Run.
+func New(opts Options) (*Bridge, error) {
+	dir := opts.ConfigDir
+	if dir == "" {
+		var err error
+		if dir, err = config.Dir(); err != nil {
+			return nil, err
+		}
+	}
+	cfg, err := config.LoadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.Failover.Enabled {
+		return nil, fmt.Errorf("failover is only supported by the clawdbot-bridge command")
+	}
+	for name := range opts.Connectors {
+		if bc, ok := cfg.Backends[name]; !ok || bc.Type != "embedded" {
+			return nil, fmt.Errorf("connector %s has no backend of type \"embedded\" in bridge.json", name)
+		}
+	}
+	redact.Register(cfg.Secrets()...)
+
+	b := &Bridge{cfg: cfg}
+	var st store.KV
+	var shared store.Shared
+	var rs *store.Redis
+	if cfg.Redis.Addr != "" {
+		if rs, err = store.OpenRedis(cfg.Redis); err != nil {
+			return nil, fmt.Errorf("failed to open shared state: %w", err)
+		}
+		b.closers = append(b.closers, rs.Close)
+		st, shared = rs, rs
+	} else {
+		fileStore, err := store.Open(filepath.Join(dir, "bridge-state.json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open state store: %w", err)
+		}
+		local := store.NewLocal()
+		b.closers = append(b.closers, local.Close)
+		st, shared = fileStore, local
+	}
+
+	embedded := make(map[string]backend.Backend, len(opts.Connectors))
+	for name, c := range opts.Connectors {
+		embedded[name] = c
+	}
+	router, err := backend.NewRouter(cfg, st, embedded)
+	if err != nil {
+		b.Close()
+		return nil, fmt.Errorf("failed to create backends: %w", err)
+	}
+
+	b.engine = engine.NewBridge(nil, router, st, shared, cfg)
+	var feishuOpts []feishu.Option
+	if cfg.Feishu.Proxy != "" || cfg.Feishu.ConnectionProxy != "" {
+		feishuOpts = append(feishuOpts, feishu.WithProxy(cfg.Feishu.Proxies()))
+	}
+	if dial := netdial.New(cfg.Network); dial != nil {
+		feishuOpts = append(feishuOpts, feishu.WithDialer(dial))
+	}
+	feishuOpts = append(feishuOpts, opts.FeishuOptions...)
+	b.feishu = feishu.NewClient(cfg.Feishu.AppID, cfg.Feishu.AppSecret, b.engine.HandleMessage, feishuOpts...)
+	b.feishu.SetCardActionHandler(b.engine.HandleCardAction)
+	b.feishu.SetBotAddedHandler(b.engine.HandleBotAdded)
+	b.feishu.SetTaskUpdateHandler(b.engine.HandleTaskUpdate)
+	b.engine.SetFeishuClient(b.feishu)
+	if cfg.Cluster.Enabled {
+		b.engine.JoinCluster(cluster.New(cfg.Cluster.Instance, rs, cfg.Cluster.Heartbeat))
+	}
+	return b, nil
+}
+
+// Feishu returns the bridge's Feishu client, e.g. to send messages of
+// your own
+func (b *Bridge) Feishu() *feishu.Client {
+	return b.feishu
+}
+
+// Run connects to Feishu and handles messages until ctx ends, then waits
+// up to drain_timeout for in-flight runs. It returns nil when ctx ends
+// and the connection error if the connection fails.
+func (b *Bridge) Run(ctx context.Context) error {
+	b.engine.Start(ctx)
+	defer b.engine.Close()
+
+	errChan := make(chan error, 1)
+	go func() {
+		if err := b.feishu.Start(ctx); err != nil {
+			errChan <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		drainCtx, cancel := context.WithTimeout(context.Background(), b.cfg.DrainTimeout)
+		defer cancel()
+		if err := b.engine.Drain(drainCtx); err != nil {
+			log.Printf("[Bridge] Stopping with %v", err)
+		}
+		return nil
+	case err := <-errChan:
+		b.engine.Alert("feishu_connection", fmt.Sprintf("飞书长连接异常退出：%v", err))
+		return fmt.Errorf("feishu connection failed: %w", err)
+	}
+}
+
+// Close releases the state store. The Feishu connection can't be closed
+// in place and stays up until the process exits.
+func (b *Bridge) Close() error {
+	var first error
+	for _, c := range b.closers {
+		if err := c(); err != nil && first == nil {
+			first = err
+		}
+	}
+	b.closers = nil
+	return first
+}
@@ -0,0 +1,605 @@
+package clawdbot
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+)
+
+// ProtocolVersion is the gateway protocol version the client speaks
+const ProtocolVersion = 3
+
+// ErrUnreachable is wrapped by errors returned when the gateway can't be dialed
+var ErrUnreachable = errors.New("failed to connect to gateway")
+
+// Client is a ClawdBot Gateway client. It speaks the gateway protocol
+// over WebSocket by default, or over gRPC when configured with WithGRPC.
+type Client struct {
+	port    int
+	token   string
+	agentID string
+	// deliver asks the gateway to deliver replies on its own channels
+	deliver bool
+	// host and ws are used by the WebSocket transport
+	host string
+	ws   *websocket.Dialer
+	// tls, when set, secures the connection for either transport
+	tls *tls.Config
+	// netDial opens network connections for either transport when set
+	netDial func(ctx context.Context, network, addr string) (net.Conn, error)
+	// dial opens a connection per request, so requests run concurrently
+	dial dialer
+	// settingsMu guards port and token, which may rotate at runtime
+	settingsMu sync.RWMutex
+}
+
+// NewClient creates a new ClawdBot Gateway client
+func NewClient(port int, token, agentID string, opts ...Option) *Client {
+	c := &Client{
+		port:    port,
+		token:   token,
+		agentID: agentID,
+		deliver: true,
+		host:    "127.0.0.1",
+		ws:      newWebSocketDialer(),
+	}
+	c.dial = c.dialWebSocket
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithDeliver sets whether agent runs ask the gateway to deliver the reply
+// through its own channels as well (the default), or only to return it
+func WithDeliver(deliver bool) Option {
+	return func(c *Client) {
+		c.deliver = deliver
+	}
+}
+
+// UpdateSettings switches to a new gateway port and token.
+// Requests already in flight finish on their existing connection;
+// the next request connects with the new settings.
+func (c *Client) UpdateSettings(port int, token string) {
+	c.settingsMu.Lock()
+	defer c.settingsMu.Unlock()
+
+	c.port = port
+	c.token = token
+}
+
+func (c *Client) currentPort() int {
+	c.settingsMu.RLock()
+	defer c.settingsMu.RUnlock()
+
+	return c.port
+}
+
+func (c *Client) currentToken() string {
+	c.settingsMu.RLock()
+	defer c.settingsMu.RUnlock()
+
+	return c.token
+}
+
+// Request represents a request to the gateway
+type Request struct {
+	Type   string      `json:"type"`
+	ID     string      `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+}
+
+// Response represents a response from the gateway
+type Response struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	OK      bool            `json:"ok,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Error   *ErrorInfo      `json:"error,omitempty"`
+	Event   string          `json:"event,omitempty"`
+}
+
+// ErrorInfo contains error details
+type ErrorInfo struct {
+	Message string `json:"message"`
+}
+
+// ConnectParams contains connection parameters
+type ConnectParams struct {
+	MinProtocol int        `json:"minProtocol"`
+	MaxProtocol int        `json:"maxProtocol"`
+	Client      ClientInfo `json:"client"`
+	Role        string     `json:"role"`
+	Scopes      []string   `json:"scopes"`
+	Auth        AuthInfo   `json:"auth"`
+	Locale      string     `json:"locale"`
+	UserAgent   string     `json:"userAgent"`
+}
+
+// ClientInfo contains client information
+type ClientInfo struct {
+	ID       string `json:"id"`
+	Version  string `json:"version"`
+	Platform string `json:"platform"`
+	Mode     string `json:"mode"`
+}
+
+// AuthInfo contains authentication information
+type AuthInfo struct {
+	Token string `json:"token"`
+}
+
+// AgentParams contains agent request parameters
+type AgentParams struct {
+	Message        string `json:"message"`
+	AgentID        string `json:"agentId"`
+	SessionKey     string `json:"sessionKey"`
+	Deliver        bool   `json:"deliver"`
+	IdempotencyKey string `json:"idempotencyKey"`
+	// Tools restricts the tools the agent may use in this run
+	Tools *ToolPolicy `json:"tools,omitempty"`
+}
+
+// ToolPolicy lists the tools an agent run may or may not use
+type ToolPolicy struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+type toolPolicyKey struct{}
+
+// WithToolPolicy returns a context whose agent requests ask the gateway
+// to restrict tools to policy
+func WithToolPolicy(ctx context.Context, policy ToolPolicy) context.Context {
+	return context.WithValue(ctx, toolPolicyKey{}, &policy)
+}
+
+// toolPolicy returns the policy set by WithToolPolicy, or nil
+func toolPolicy(ctx context.Context) *ToolPolicy {
+	p, _ := ctx.Value(toolPolicyKey{}).(*ToolPolicy)
+	return p
+}
+
+// maxEarlyEvents bounds the events held back while a run's ID is unknown
+const maxEarlyEvents = 1000
+
+// AgentPayload contains the agent response payload
+type AgentPayload struct {
+	RunID string `json:"runId,omitempty"`
+}
+
+// EventPayload contains event data
+type EventPayload struct {
+	RunID  string          `json:"runId,omitempty"`
+	Stream string          `json:"stream,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// StreamData contains stream data
+type StreamData struct {
+	Text    string          `json:"text,omitempty"`
+	Delta   string          `json:"delta,omitempty"`
+	Phase   string          `json:"phase,omitempty"`
+	Message string          `json:"message,omitempty"`
+	Usage   json.RawMessage `json:"usage,omitempty"`
+}
+
+// AskClawdbot sends a message to ClawdBot and returns the response.
+// Cancelling ctx abandons the wait for the reply.
+func (c *Client) AskClawdbot(ctx context.Context, text, sessionKey string, onProgress func(stream, data string)) (string, error) {
+	return c.AskAgent(ctx, c.agentID, text, sessionKey, onProgress)
+}
+
+// AskAgent is like AskClawdbot but addresses a specific agent
+func (c *Client) AskAgent(ctx context.Context, agentID, text, sessionKey string, onProgress func(stream, data string)) (string, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnreachable, err)
+	}
+	defer conn.Close()
+
+	var runID string
+	var buffer string
+	responseChan := make(chan string, 1)
+	errorChan := make(chan error, 1)
+	// confirmed is set once the agent response names the run; early holds
+	// the events received before that
+	var confirmed bool
+	var early []EventPayload
+
+	// handleEvent processes an event of this run and reports whether the
+	// run is over
+	handleEvent := func(eventPayload EventPayload) bool {
+		switch eventPayload.Stream {
+		case "assistant":
+			if onProgress != nil {
+				// Non-blocking call
+				go onProgress("assistant", string(eventPayload.Data))
+			}
+			var streamData StreamData
+			if err := json.Unmarshal(eventPayload.Data, &streamData); err == nil {
+				if streamData.Text != "" {
+					buffer = streamData.Text
+				} else if streamData.Delta != "" {
+					buffer += streamData.Delta
+				}
+			}
+		case "thought", "tool_call", "tool_result":
+			if onProgress != nil {
+				// Non-blocking call
+				go onProgress(eventPayload.Stream, string(eventPayload.Data))
+			}
+		case "lifecycle":
+			var streamData StreamData
+			if err := json.Unmarshal(eventPayload.Data, &streamData); err != nil {
+				return false
+			}
+			if streamData.Phase == "end" {
+				// Forward token accounting before the run completes
+				if onProgress != nil && len(streamData.Usage) > 0 {
+					onProgress("usage", string(streamData.Usage))
+				}
+				responseChan <- buffer
+				return true
+			}
+			if streamData.Phase == "error" {
+				errMsg := "agent error"
+				if streamData.Message != "" {
+					errMsg = streamData.Message
+				}
+				errorChan <- fmt.Errorf(errMsg)
+				return true
+			}
+		}
+		return false
+	}
+
+	// Message reader goroutine
+	go func() {
+		for {
+			message, err := conn.ReadFrame()
+			if err != nil {
+				return
+			}
+
+			logging.Printf(ctx, "[Clawdbot] RECEIVED MESSAGE: %s", string(message))
+
+			var resp Response
+			if err := json.Unmarshal(message, &resp); err != nil {
+				continue
+			}
+			logging.Printf(ctx, "[Clawdbot] RECEIVED MESSAGE: type=%s, event=%s, id=%s", resp.Type, resp.Event, resp.ID)
+
+			// Step 1: Handle connect challenge
+			if resp.Type == "event" && resp.Event == "connect.challenge" {
+				connectReq := c.connectRequest()
+
+				if err := conn.WriteJSON(connectReq); err != nil {
+					errorChan <- fmt.Errorf("failed to send connect request: %w", err)
+					return
+				}
+				continue
+			}
+
+			// Step 2: Handle connect response
+			if resp.Type == "res" && resp.ID == "connect" {
+				if !resp.OK {
+					errMsg := "connect failed"
+					if resp.Error != nil {
+						errMsg = resp.Error.Message
+					}
+					errorChan <- fmt.Errorf(errMsg)
+					return
+				}
+
+				// Send agent request
+				agentReq := Request{
+					Type:   "req",
+					ID:     "agent",
+					Method: "agent",
+					Params: AgentParams{
+						Message:        text,
+						AgentID:        agentID,
+						SessionKey:     sessionKey,
+						Deliver:        c.deliver,
+						IdempotencyKey: uuid.New().String(),
+						Tools:          toolPolicy(ctx),
+					},
+				}
+
+				if err := conn.WriteJSON(agentReq); err != nil {
+					errorChan <- fmt.Errorf("failed to send agent request: %w", err)
+					return
+				}
+				continue
+			}
+
+			// Step 3: Handle agent response
+			if resp.Type == "res" && resp.ID == "agent" {
+				if !resp.OK {
+					errMsg := "agent error"
+					if resp.Error != nil {
+						errMsg = resp.Error.Message
+					}
+					errorChan <- fmt.Errorf(errMsg)
+					return
+				}
+
+				var payload AgentPayload
+				if err := json.Unmarshal(resp.Payload, &payload); err == nil {
+					runID = payload.RunID
+				}
+				logging.SetRunID(ctx, runID)
+				// Let callers correlate logs and traces with the gateway run
+				if onProgress != nil && runID != "" {
+					runData, _ := json.Marshal(map[string]string{"runId": runID})
+					onProgress("run", string(runData))
+				}
+				if runID == "" {
+					logging.Printf(ctx, "[Clawdbot] Agent response has no runId, taking every event on the connection")
+				}
+
+				// Replay the events that arrived first, keeping this run's
+				confirmed = true
+				held := early
+				early = nil
+				for _, eventPayload := range held {
+					if runID != "" && eventPayload.RunID != runID {
+						continue
+					}
+					if handleEvent(eventPayload) {
+						return
+					}
+				}
+				continue
+			}
+
+			// Step 4: Handle agent events
+			if resp.Type == "event" && resp.Event == "agent" {
+				var eventPayload EventPayload
+				if err := json.Unmarshal(resp.Payload, &eventPayload); err != nil {
+					continue
+				}
+
+				// Until the agent response names the run, events can't be
+				// told apart from another run's; hold them back
+				if !confirmed {
+					if len(early) < maxEarlyEvents {
+						early = append(early, eventPayload)
+					}
+					continue
+				}
+				if runID != "" && eventPayload.RunID != runID {
+					continue
+				}
+				if handleEvent(eventPayload) {
+					return
+				}
+			}
+		}
+	}()
+
+	// Wait for response or timeout
+	select {
+	case result := <-responseChan:
+		return result, nil
+	case err := <-errorChan:
+		return "", err
+	case <-time.After(15 * time.Minute):
+		return "", fmt.Errorf("timeout waiting for response")
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Ping checks that the gateway accepts connections. It's cheap enough to
+// poll.
+func (c *Client) Ping() error {
+	conn, err := c.dial()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUnreachable, err)
+	}
+	return conn.Close()
+}
+
+// ResetSession resets a session
+func (c *Client) ResetSession(sessionKey string) error {
+	_, err := c.call("sessions.reset", map[string]string{
+		"key": sessionKey,
+	}, 10*time.Second)
+	return err
+}
+
+// ForkSession copies the history of a session into a new session newKey,
+// which continues independently
+func (c *Client) ForkSession(sessionKey, newKey string) error {
+	_, err := c.call("sessions.fork", map[string]string{
+		"key":    sessionKey,
+		"newKey": newKey,
+	}, 30*time.Second)
+	return err
+}
+
+// CompactResult reports the size of a session's context around a
+// compaction, in tokens; zero when the gateway doesn't say
+type CompactResult struct {
+	TokensBefore int `json:"tokensBefore,omitempty"`
+	TokensAfter  int `json:"tokensAfter,omitempty"`
+}
+
+// CompactSession has the gateway summarize a session's history and
+// replace it with the summary
+func (c *Client) CompactSession(sessionKey string) (*CompactResult, error) {
+	payload, err := c.call("sessions.compact", map[string]string{
+		"key": sessionKey,
+	}, 3*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	var result CompactResult
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse compaction result: %w", err)
+		}
+	}
+	return &result, nil
+}
+
+// AgentInfo describes an agent exposed by the gateway
+type AgentInfo struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Tools       []ToolName `json:"tools,omitempty"`
+}
+
+// ToolName is a tool entry in agent discovery; the gateway may send
+// either a plain name or an object with a "name" field
+type ToolName string
+
+// UnmarshalJSON accepts "name" or {"name":"..."}
+func (t *ToolName) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = ToolName(name)
+		return nil
+	}
+	var obj struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	*t = ToolName(obj.Name)
+	return nil
+}
+
+// ListAgents queries the gateway agent discovery API
+func (c *Client) ListAgents() ([]AgentInfo, error) {
+	payload, err := c.call("agents.list", map[string]interface{}{}, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Agents []AgentInfo `json:"agents"`
+	}
+	if err := json.Unmarshal(payload, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse agent list: %w", err)
+	}
+	return result.Agents, nil
+}
+
+// connectRequest builds the handshake answering a connect challenge
+func (c *Client) connectRequest() Request {
+	return Request{
+		Type:   "req",
+		ID:     "connect",
+		Method: "connect",
+		Params: ConnectParams{
+			MinProtocol: ProtocolVersion,
+			MaxProtocol: ProtocolVersion,
+			Client: ClientInfo{
+				ID:       "gateway-client",
+				Version:  "0.2.0",
+				Platform: "linux",
+				Mode:     "backend",
+			},
+			Role:   "operator",
+			Scopes: []string{"operator.read", "operator.write", "operator.admin"},
+			Auth: AuthInfo{
+				Token: c.currentToken(),
+			},
+			Locale:    "zh-CN",
+			UserAgent: "clawdbot-bridge-go",
+		},
+	}
+}
+
+// call connects to the gateway, sends a single request and returns its payload
+func (c *Client) call(method string, params interface{}, timeout time.Duration) (json.RawMessage, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnreachable, err)
+	}
+	defer conn.Close()
+
+	errorChan := make(chan error, 1)
+	doneChan := make(chan json.RawMessage, 1)
+
+	go func() {
+		for {
+			message, err := conn.ReadFrame()
+			if err != nil {
+				return
+			}
+
+			var resp Response
+			if err := json.Unmarshal(message, &resp); err != nil {
+				continue
+			}
+
+			if resp.Type == "event" && resp.Event == "connect.challenge" {
+				if err := conn.WriteJSON(c.connectRequest()); err != nil {
+					errorChan <- fmt.Errorf("failed to send connect request: %w", err)
+					return
+				}
+				continue
+			}
+
+			if resp.Type == "res" && resp.ID == "connect" {
+				if !resp.OK {
+					errorChan <- fmt.Errorf("connect failed")
+					return
+				}
+
+				req := Request{
+					Type:   "req",
+					ID:     "call",
+					Method: method,
+					Params: params,
+				}
+				if err := conn.WriteJSON(req); err != nil {
+					errorChan <- fmt.Errorf("failed to send %s request: %w", method, err)
+					return
+				}
+				continue
+			}
+
+			if resp.Type == "res" && resp.ID == "call" {
+				if !resp.OK {
+					errMsg := method + " failed"
+					if resp.Error != nil {
+						errMsg = resp.Error.Message
+					}
+					errorChan <- fmt.Errorf("%s", errMsg)
+				} else {
+					doneChan <- resp.Payload
+				}
+				return
+			}
+		}
+	}()
+
+	select {
+	case payload := <-doneChan:
+		return payload, nil
+	case err := <-errorChan:
+		return nil, err
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("timeout waiting for %s", method)
+	}
+}
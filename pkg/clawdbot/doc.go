@@ -0,0 +1,7 @@
+// Package clawdbot is a client for the ClawdBot (OpenClaw) gateway. It
+// runs agents over the gateway's WebSocket or gRPC transport and streams
+// their progress back.
+//
+// Exported names follow semantic versioning: they only change
+// incompatibly with a new major version of the module.
+package clawdbot
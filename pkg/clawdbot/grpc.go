@@ -0,0 +1,94 @@
+package clawdbot
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// grpcConnectMethod is the bidirectional streaming method that carries
+// gateway protocol frames. Each gRPC message is one JSON frame, encoded
+// with the "json" codec (content-type application/grpc+json).
+const grpcConnectMethod = "/clawdbot.gateway.v1.Gateway/Connect"
+
+// WithGRPC makes the client reach the gateway through a gRPC front at addr
+// instead of the local WebSocket port
+func WithGRPC(addr string, useTLS bool) Option {
+	return func(c *Client) {
+		c.dial = func() (frameConn, error) {
+			return c.dialGRPC(addr, useTLS)
+		}
+	}
+}
+
+// grpcConn carries frames over a gRPC bidirectional stream
+type grpcConn struct {
+	conn   *grpc.ClientConn
+	stream grpc.ClientStream
+	cancel context.CancelFunc
+}
+
+func (g *grpcConn) WriteJSON(v interface{}) error {
+	return g.stream.SendMsg(v)
+}
+
+func (g *grpcConn) ReadFrame() ([]byte, error) {
+	var frame json.RawMessage
+	if err := g.stream.RecvMsg(&frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+func (g *grpcConn) Close() error {
+	g.stream.CloseSend()
+	g.cancel()
+	return g.conn.Close()
+}
+
+// dialGRPC opens a Connect stream on the gRPC gateway front
+func (c *Client) dialGRPC(addr string, useTLS bool) (frameConn, error) {
+	creds := insecure.NewCredentials()
+	if c.tls != nil {
+		creds = credentials.NewTLS(c.tls)
+	} else if useTLS {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(rawJSONCodec{})),
+	}
+	target := addr
+	if c.netDial != nil {
+		// Leave resolving addr to netDial too
+		target = "passthrough:///" + addr
+		opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return c.netDial(ctx, "tcp", addr)
+		}))
+	}
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create grpc client: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{
+		StreamName:    "Connect",
+		ServerStreams: true,
+		ClientStreams: true,
+	}, grpcConnectMethod)
+	if err != nil {
+		cancel()
+		conn.Close()
+		return nil, fmt.Errorf("failed to open grpc stream: %w", err)
+	}
+
+	return &grpcConn{conn: conn, stream: stream, cancel: cancel}, nil
+}
@@ -0,0 +1,129 @@
+package clawdbot
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+)
+
+// frameConn is a bidirectional stream of JSON protocol frames.
+// The gateway protocol (connect challenge, requests, events) is the same
+// regardless of the transport carrying it.
+type frameConn interface {
+	WriteJSON(v interface{}) error
+	ReadFrame() ([]byte, error)
+	Close() error
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// dialer opens a new frame connection to the gateway
+type dialer func() (frameConn, error)
+
+// wsConn carries frames over the gateway's native WebSocket endpoint
+type wsConn struct {
+	conn *websocket.Conn
+}
+
+func (w *wsConn) WriteJSON(v interface{}) error {
+	return w.conn.WriteJSON(v)
+}
+
+func (w *wsConn) ReadFrame() ([]byte, error) {
+	_, message, err := w.conn.ReadMessage()
+	return message, err
+}
+
+func (w *wsConn) Close() error {
+	return w.conn.Close()
+}
+
+// WithHost makes the client connect to the gateway WebSocket on host
+// instead of 127.0.0.1. With a proxy or SSH tunnel, host is resolved on
+// the far side.
+func WithHost(host string) Option {
+	return func(c *Client) {
+		c.host = host
+	}
+}
+
+// WithProxy connects to the gateway WebSocket through proxy, which works
+// like http.Transport.Proxy: http(s) proxies are used with CONNECT, and
+// socks5 proxies are supported too
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) Option {
+	return func(c *Client) {
+		c.ws.Proxy = proxy
+	}
+}
+
+// WithNetDial opens the network connections to the gateway, or to its
+// proxy, with dial, e.g. through an SSH tunnel
+func WithNetDial(dial func(ctx context.Context, network, addr string) (net.Conn, error)) Option {
+	return func(c *Client) {
+		c.netDial = dial
+		c.ws.NetDialContext = dial
+	}
+}
+
+// WithTLS connects to the gateway over TLS configured by cfg, e.g. with a
+// client certificate: wss for the WebSocket transport, and in place of
+// the default TLS settings for gRPC
+func WithTLS(cfg *tls.Config) Option {
+	return func(c *Client) {
+		c.tls = cfg
+		c.ws.TLSClientConfig = cfg
+	}
+}
+
+// newWebSocketDialer returns the dialer for the gateway WebSocket. It is
+// the client's own, so it ignores any proxy others set on
+// websocket.DefaultDialer.
+func newWebSocketDialer() *websocket.Dialer {
+	return &websocket.Dialer{HandshakeTimeout: websocket.DefaultDialer.HandshakeTimeout}
+}
+
+// dialWebSocket connects to the gateway WebSocket, on the current port so
+// port changes take effect
+func (c *Client) dialWebSocket() (frameConn, error) {
+	scheme := "ws"
+	if c.tls != nil {
+		scheme = "wss"
+	}
+	url := fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(c.host, strconv.Itoa(c.currentPort())))
+	conn, _, err := c.ws.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &wsConn{conn: conn}, nil
+}
+
+// rawJSONCodec is a gRPC codec that sends protocol frames as JSON
+// instead of protobuf, so no generated stubs are needed
+type rawJSONCodec struct{}
+
+func (rawJSONCodec) Marshal(v interface{}) ([]byte, error) {
+	if raw, ok := v.(*json.RawMessage); ok {
+		return *raw, nil
+	}
+	return json.Marshal(v)
+}
+
+func (rawJSONCodec) Unmarshal(data []byte, v interface{}) error {
+	if raw, ok := v.(*json.RawMessage); ok {
+		*raw = append((*raw)[:0], data...)
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (rawJSONCodec) Name() string {
+	return "json"
+}
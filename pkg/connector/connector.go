@@ -0,0 +1,48 @@
+// Package connector defines how the bridge talks to whatever answers chat
+// messages. Implement Connector to put your own agent behind Feishu and
+// hand it to the bridge package; bridge.json routes to it through a
+// backend of type "embedded" with the same name.
+//
+// The names in this package follow semantic versioning: they only change
+// incompatibly with a new major version of the module.
+package connector
+
+import "context"
+
+// Stream names passed to ProgressFunc. They mirror the ClawdBot gateway
+// agent event streams so the bridge renders every connector the same way.
+const (
+	// StreamAssistant carries {"delta":"..."} as the reply is written
+	StreamAssistant = "assistant"
+	// StreamThought carries {"delta":"..."} of the model's reasoning
+	StreamThought = "thought"
+	// StreamToolCall carries {"name":"...","id":"...","args":{...}}
+	StreamToolCall = "tool_call"
+	// StreamToolResult carries {"name":"...","id":"...","isError":false}
+	StreamToolResult = "tool_result"
+	// StreamUsage carries a Usage object once the run has finished
+	StreamUsage = "usage"
+	// StreamRun carries {"runId":"..."} once the connector has accepted the run
+	StreamRun = "run"
+)
+
+// Usage is the token accounting reported on the usage stream
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// ProgressFunc receives streaming updates during a run.
+// data is a JSON object, e.g. {"delta":"..."} for the assistant stream
+// or {"name":"..."} for tool_call.
+type ProgressFunc func(stream, data string)
+
+// Connector answers chat messages on behalf of the bridge. Ask may be
+// called concurrently for different sessions.
+type Connector interface {
+	// Ask sends text within the given session and returns the final reply.
+	// ctx carries the turn's correlation fields and cancels the run.
+	Ask(ctx context.Context, text, sessionKey string, onProgress ProgressFunc) (string, error)
+	// ResetSession clears the conversation history of a session
+	ResetSession(sessionKey string) error
+}
@@ -0,0 +1,211 @@
+package feishu
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	larkcore "github.com/larksuite/oapi-sdk-go/v3/core"
+)
+
+// Announcement block types, as numbered by the docx API
+const (
+	blockPage     = 1
+	blockText     = 2
+	blockHeading1 = 3
+	blockHeading2 = 4
+	blockBullet   = 12
+)
+
+// maxBlocksPerCreate is how many blocks one create request may add
+const maxBlocksPerCreate = 50
+
+// announcementBlock is a block of a group announcement as the docx API
+// returns it; the text sits under a key named for the block type
+type announcementBlock struct {
+	BlockID   string
+	BlockType int
+	Children  []string
+	Text      string
+}
+
+func (b *announcementBlock) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	json.Unmarshal(fields["block_id"], &b.BlockID)
+	json.Unmarshal(fields["block_type"], &b.BlockType)
+	json.Unmarshal(fields["children"], &b.Children)
+	for key, raw := range fields {
+		switch key {
+		case "block_id", "block_type", "children", "parent_id":
+			continue
+		}
+		var body struct {
+			Elements []struct {
+				TextRun *struct {
+					Content string `json:"content"`
+				} `json:"text_run"`
+			} `json:"elements"`
+		}
+		if json.Unmarshal(raw, &body) != nil {
+			continue
+		}
+		for _, e := range body.Elements {
+			if e.TextRun != nil {
+				b.Text += e.TextRun.Content
+			}
+		}
+	}
+	return nil
+}
+
+// Announcement returns the text of a group's announcement, one line per
+// block; headings start with # and bullets with -
+func (c *Client) Announcement(chatID string) (string, error) {
+	var text string
+	err := c.guard("get announcement", func() error {
+		blocks, err := c.announcementBlocks(chatID)
+		if err != nil {
+			return err
+		}
+		var lines []string
+		for _, b := range blocks {
+			switch b.BlockType {
+			case blockPage:
+				continue
+			case blockHeading1:
+				lines = append(lines, "# "+b.Text)
+			case blockHeading2:
+				lines = append(lines, "## "+b.Text)
+			case blockBullet:
+				lines = append(lines, "- "+b.Text)
+			default:
+				lines = append(lines, b.Text)
+			}
+		}
+		text = strings.TrimSpace(strings.Join(lines, "\n"))
+		return nil
+	})
+	return text, err
+}
+
+// SetAnnouncement replaces a group's announcement with text: a block per
+// non-blank line, lines starting with "# " or "## " as headings and "- "
+// as bullets
+func (c *Client) SetAnnouncement(chatID, text string) error {
+	return c.guard("set announcement", func() error {
+		return c.setAnnouncement(chatID, text)
+	})
+}
+
+// setAnnouncement calls the API directly; see SetAnnouncement
+func (c *Client) setAnnouncement(chatID, text string) error {
+	blocks, err := c.announcementBlocks(chatID)
+	if err != nil {
+		return err
+	}
+	var page *announcementBlock
+	for i := range blocks {
+		if blocks[i].BlockType == blockPage {
+			page = &blocks[i]
+			break
+		}
+	}
+	if page == nil {
+		return fmt.Errorf("failed to set announcement: the announcement has no page block")
+	}
+
+	path := "/open-apis/docx/v1/chats/:chat_id/announcement/blocks/:block_id/children"
+	params := larkcore.PathParams{"chat_id": chatID, "block_id": page.BlockID}
+	latest := larkcore.QueryParams{"revision_id": {"-1"}}
+	if n := len(page.Children); n > 0 {
+		req := &larkcore.ApiReq{
+			HttpMethod:  http.MethodDelete,
+			ApiPath:     path + "/batch_delete",
+			PathParams:  params,
+			QueryParams: latest,
+			Body:        map[string]interface{}{"start_index": 0, "end_index": n},
+		}
+		if err := c.rawCall("clear announcement", req, nil); err != nil {
+			return err
+		}
+	}
+
+	children := announcementChildren(text)
+	for start := 0; start < len(children); start += maxBlocksPerCreate {
+		end := min(start+maxBlocksPerCreate, len(children))
+		req := &larkcore.ApiReq{
+			HttpMethod:  http.MethodPost,
+			ApiPath:     path,
+			PathParams:  params,
+			QueryParams: latest,
+			Body:        map[string]interface{}{"index": start, "children": children[start:end]},
+		}
+		if err := c.rawCall("set announcement", req, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// announcementBlocks lists a group announcement's blocks in document
+// order
+func (c *Client) announcementBlocks(chatID string) ([]announcementBlock, error) {
+	var blocks []announcementBlock
+	pageToken := ""
+	for {
+		query := larkcore.QueryParams{"page_size": {"500"}, "document_revision_id": {"-1"}}
+		if pageToken != "" {
+			query["page_token"] = []string{pageToken}
+		}
+		var data struct {
+			Items     []announcementBlock `json:"items"`
+			HasMore   bool                `json:"has_more"`
+			PageToken string              `json:"page_token"`
+		}
+		req := &larkcore.ApiReq{
+			HttpMethod:  http.MethodGet,
+			ApiPath:     "/open-apis/docx/v1/chats/:chat_id/announcement/blocks",
+			PathParams:  larkcore.PathParams{"chat_id": chatID},
+			QueryParams: query,
+		}
+		if err := c.rawCall("get announcement", req, &data); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, data.Items...)
+		if !data.HasMore || data.PageToken == "" {
+			return blocks, nil
+		}
+		pageToken = data.PageToken
+	}
+}
+
+// announcementChildren converts text to the blocks SetAnnouncement writes
+func announcementChildren(text string) []map[string]interface{} {
+	var children []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			continue
+		}
+		typ, key := blockText, "text"
+		switch {
+		case strings.HasPrefix(line, "## "):
+			typ, key, line = blockHeading2, "heading2", line[3:]
+		case strings.HasPrefix(line, "# "):
+			typ, key, line = blockHeading1, "heading1", line[2:]
+		case strings.HasPrefix(line, "- "):
+			typ, key, line = blockBullet, "bullet", line[2:]
+		}
+		children = append(children, map[string]interface{}{
+			"block_type": typ,
+			key: map[string]interface{}{
+				"elements": []interface{}{map[string]interface{}{"text_run": map[string]interface{}{"content": line}}},
+			},
+		})
+	}
+	return children
+}
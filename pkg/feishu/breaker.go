@@ -0,0 +1,292 @@
+package feishu
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// breakerThreshold consecutive outage errors open the circuit
+	breakerThreshold = 5
+	// breakerCooldown is how long the circuit stays open before a probe
+	breakerCooldown = 30 * time.Second
+	// maxPending caps replies buffered while the circuit is open
+	maxPending = 500
+)
+
+// ErrCircuitOpen is returned without calling Feishu while the API is
+// considered down
+var ErrCircuitOpen = errors.New("feishu API unavailable (circuit open)")
+
+// Circuit states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// CircuitStatus describes the breaker for health reporting
+type CircuitStatus struct {
+	State string    `json:"state"`
+	Since time.Time `json:"since"`
+	// Pending counts final replies waiting for the API to recover
+	Pending int `json:"pending"`
+}
+
+// pendingReply is a final reply held back while the circuit is open.
+// With a MessageID it replaces that message's text, otherwise it's sent
+// to ChatID as a new message. Post replies are sent as rich text.
+type pendingReply struct {
+	ChatID    string
+	MessageID string
+	Text      string
+	Post      bool
+}
+
+// breaker stops calls to the Feishu API after repeated outage errors.
+// After the cooldown one call is let through as a probe; success closes
+// the circuit again.
+type breaker struct {
+	mu       sync.Mutex
+	state    string
+	since    time.Time
+	failures int
+	pending  []pendingReply
+	onChange func(CircuitStatus)
+	onDrop   func(DroppedReply)
+}
+
+// DroppedReply is a held final reply that was given up on, because the
+// buffer overflowed or Feishu rejected it once it was back
+type DroppedReply struct {
+	ChatID string
+	Text   string
+	Post   bool
+	Err    error
+}
+
+func newBreaker() *breaker {
+	return &breaker{state: CircuitClosed, since: time.Now()}
+}
+
+// allow reports whether a call may go ahead, moving an open circuit whose
+// cooldown has passed to half-open for a single probe
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitClosed:
+		return true
+	case CircuitOpen:
+		if time.Since(b.since) < breakerCooldown {
+			return false
+		}
+		b.setState(CircuitHalfOpen)
+		return true
+	default:
+		// A probe is already in flight
+		return false
+	}
+}
+
+// record updates the breaker with the outcome of an allowed call and
+// reports whether the circuit just closed
+func (b *breaker) record(err error) (recovered bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !isOutage(err) {
+		b.failures = 0
+		if b.state != CircuitClosed {
+			b.setState(CircuitClosed)
+			return true
+		}
+		return false
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || (b.state == CircuitClosed && b.failures >= breakerThreshold) {
+		b.setState(CircuitOpen)
+	}
+	return false
+}
+
+// setState changes state and notifies; callers must hold mu
+func (b *breaker) setState(state string) {
+	b.state = state
+	b.since = time.Now()
+	log.Printf("[Feishu] API circuit %s (%d replies pending)", state, len(b.pending))
+	if b.onChange != nil {
+		st := b.statusLocked()
+		go b.onChange(st)
+	}
+}
+
+func (b *breaker) status() CircuitStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.statusLocked()
+}
+
+func (b *breaker) statusLocked() CircuitStatus {
+	return CircuitStatus{State: b.state, Since: b.since, Pending: len(b.pending)}
+}
+
+// hold buffers a reply, dropping the oldest once the buffer is full
+func (b *breaker) hold(r pendingReply) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.pending) >= maxPending {
+		oldest := b.pending[0]
+		log.Printf("[Feishu] Pending reply buffer full, dropping reply for %s", oldest.ChatID)
+		b.pending = b.pending[1:]
+		b.droppedLocked(oldest, fmt.Errorf("pending reply buffer full (%d replies)", maxPending))
+	}
+	b.pending = append(b.pending, r)
+}
+
+// droppedLocked reports a dropped reply to onDrop in its own goroutine
+func (b *breaker) droppedLocked(r pendingReply, err error) {
+	if fn := b.onDrop; fn != nil {
+		go fn(DroppedReply{ChatID: r.ChatID, Text: r.Text, Post: r.Post, Err: err})
+	}
+}
+
+// next returns the oldest pending reply without removing it
+func (b *breaker) next() (pendingReply, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.pending) == 0 {
+		return pendingReply{}, false
+	}
+	return b.pending[0], true
+}
+
+// done removes the oldest pending reply
+func (b *breaker) done() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.pending) > 0 {
+		b.pending = b.pending[1:]
+	}
+}
+
+// isOutage reports whether err suggests the API as a whole is failing,
+// as opposed to a rejection of one request
+func isOutage(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrAuth) {
+		return true
+	}
+	var rejected *apiFailure
+	return !errors.As(err, &rejected)
+}
+
+// IsUnavailable reports whether err means Feishu couldn't be reached, so a
+// final reply is worth holding with DeferReply
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrCircuitOpen) || (isOutage(err) && !errors.Is(err, ErrAuth))
+}
+
+// guard runs call through the breaker
+func (c *Client) guard(action string, call func() error) error {
+	if !c.breaker.allow() {
+		return fmt.Errorf("failed to %s: %w", action, ErrCircuitOpen)
+	}
+	err := call()
+	if c.breaker.record(err) {
+		go c.flushPending()
+	}
+	return err
+}
+
+// Circuit returns the API breaker state for health reporting
+func (c *Client) Circuit() CircuitStatus {
+	return c.breaker.status()
+}
+
+// OnCircuitChange registers fn to be called (in its own goroutine) when
+// the API breaker changes state
+func (c *Client) OnCircuitChange(fn func(CircuitStatus)) {
+	c.breaker.mu.Lock()
+	defer c.breaker.mu.Unlock()
+	c.breaker.onChange = fn
+}
+
+// OnReplyDropped registers fn to be called (in its own goroutine) for
+// each reply held by DeferReply that ends up not delivered
+func (c *Client) OnReplyDropped(fn func(DroppedReply)) {
+	c.breaker.mu.Lock()
+	defer c.breaker.mu.Unlock()
+	c.breaker.onDrop = fn
+}
+
+// DeferReply holds a final reply until the API recovers. With a messageID
+// the reply replaces that message's text, otherwise it's sent to chatID.
+// post selects rich text, as for SendPost and UpdatePost.
+func (c *Client) DeferReply(chatID, messageID, text string, post bool) {
+	c.breaker.hold(pendingReply{ChatID: chatID, MessageID: messageID, Text: text, Post: post})
+	c.scheduleProbe()
+}
+
+// scheduleProbe delivers pending replies once the cooldown has passed,
+// using the first one as the probe. Without pending replies the next
+// ordinary call probes instead.
+func (c *Client) scheduleProbe() {
+	c.probeOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(breakerCooldown / 3)
+			defer ticker.Stop()
+			for range ticker.C {
+				if _, ok := c.breaker.next(); ok {
+					c.flushPending()
+				}
+			}
+		}()
+	})
+}
+
+// flushPending delivers buffered replies in order, stopping at the first
+// failure so the rest wait for the next probe
+func (c *Client) flushPending() {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	for {
+		r, ok := c.breaker.next()
+		if !ok {
+			return
+		}
+
+		var err error
+		switch {
+		case r.MessageID != "" && r.Post:
+			err = c.UpdatePost(r.MessageID, r.Text)
+		case r.MessageID != "":
+			err = c.UpdateMessage(r.MessageID, r.Text)
+		case r.Post:
+			_, err = c.SendPost(r.ChatID, r.Text)
+		default:
+			_, err = c.SendMessage(r.ChatID, r.Text)
+		}
+		if IsUnavailable(err) {
+			return
+		}
+		if err != nil {
+			log.Printf("[Feishu] Dropping pending reply for %s: %v", r.ChatID, err)
+			c.breaker.mu.Lock()
+			c.breaker.droppedLocked(r, err)
+			c.breaker.mu.Unlock()
+		} else {
+			log.Printf("[Feishu] Delivered pending reply to %s", r.ChatID)
+		}
+		c.breaker.done()
+	}
+}
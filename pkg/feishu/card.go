@@ -0,0 +1,111 @@
+package feishu
+
+// Card is an interactive message card (message card JSON 1.0)
+type Card struct {
+	Config   CardConfig    `json:"config"`
+	Header   *CardHeader   `json:"header,omitempty"`
+	Elements []interface{} `json:"elements"`
+}
+
+// CardConfig contains card display options
+type CardConfig struct {
+	WideScreenMode bool `json:"wide_screen_mode"`
+	UpdateMulti    bool `json:"update_multi"`
+}
+
+// CardHeader is the card title bar
+type CardHeader struct {
+	Title    CardText `json:"title"`
+	Template string   `json:"template,omitempty"`
+}
+
+// CardText is a text object
+type CardText struct {
+	Tag     string `json:"tag"`
+	Content string `json:"content"`
+}
+
+// CardButton is a button inside an action block.
+// Value is passed back unchanged in the card action callback.
+type CardButton struct {
+	Text  string
+	Type  string // default, primary or danger
+	Value map[string]interface{}
+}
+
+// NewCard creates a card with a title; template is the header color
+// (blue, green, orange, red, grey...) and may be empty
+func NewCard(title, template string) *Card {
+	card := &Card{
+		Config:   CardConfig{WideScreenMode: true, UpdateMulti: true},
+		Elements: []interface{}{},
+	}
+	if title != "" {
+		card.Header = &CardHeader{
+			Title:    CardText{Tag: "plain_text", Content: title},
+			Template: template,
+		}
+	}
+	return card
+}
+
+// AddMarkdown appends a lark_md text block
+func (c *Card) AddMarkdown(text string) *Card {
+	c.Elements = append(c.Elements, map[string]interface{}{
+		"tag":  "div",
+		"text": CardText{Tag: "lark_md", Content: text},
+	})
+	return c
+}
+
+// AddDivider appends a horizontal rule
+func (c *Card) AddDivider() *Card {
+	c.Elements = append(c.Elements, map[string]interface{}{"tag": "hr"})
+	return c
+}
+
+// AddNote appends small grey footnote text
+func (c *Card) AddNote(text string) *Card {
+	c.Elements = append(c.Elements, map[string]interface{}{
+		"tag":      "note",
+		"elements": []CardText{{Tag: "lark_md", Content: text}},
+	})
+	return c
+}
+
+// AddCollapsible appends a panel showing title that opens to reveal
+// text. Clients too old for panels show it expanded.
+func (c *Card) AddCollapsible(title, text string, expanded bool) *Card {
+	c.Elements = append(c.Elements, map[string]interface{}{
+		"tag":      "collapsible_panel",
+		"expanded": expanded,
+		"header": map[string]interface{}{
+			"title": CardText{Tag: "markdown", Content: title},
+		},
+		"border":   map[string]interface{}{"color": "grey"},
+		"elements": []interface{}{map[string]interface{}{"tag": "markdown", "content": text}},
+	})
+	return c
+}
+
+// AddButtons appends a row of buttons
+func (c *Card) AddButtons(buttons ...CardButton) *Card {
+	actions := make([]interface{}, 0, len(buttons))
+	for _, b := range buttons {
+		typ := b.Type
+		if typ == "" {
+			typ = "default"
+		}
+		actions = append(actions, map[string]interface{}{
+			"tag":   "button",
+			"text":  CardText{Tag: "plain_text", Content: b.Text},
+			"type":  typ,
+			"value": b.Value,
+		})
+	}
+	c.Elements = append(c.Elements, map[string]interface{}{
+		"tag":     "action",
+		"actions": actions,
+	})
+	return c
+}
@@ -0,0 +1,160 @@
+package feishu
+
+import (
+	"context"
+	"fmt"
+
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+)
+
+// ChatInput describes a group to create
+type ChatInput struct {
+	Name        string
+	Description string
+	// Members are open_ids
+	Members []string
+	// UUID makes retries with the same UUID create one group
+	UUID string
+}
+
+// Chat is a created group
+type Chat struct {
+	ChatID string
+	Name   string
+}
+
+// CreateChat creates a group owned by the bot, which is also made a group
+// admin, with input.Members in it
+func (c *Client) CreateChat(input ChatInput) (*Chat, error) {
+	var chat *Chat
+	err := c.guard("create chat", func() (err error) {
+		chat, err = c.createChat(input)
+		return err
+	})
+	return chat, err
+}
+
+// createChat calls the API directly; see CreateChat
+func (c *Client) createChat(input ChatInput) (*Chat, error) {
+	body := larkim.NewCreateChatReqBodyBuilder().
+		Name(input.Name).
+		Description(input.Description).
+		UserIdList(input.Members).
+		ChatMode("group").
+		ChatType("private").
+		Build()
+	req := larkim.NewCreateChatReqBuilder().
+		UserIdType("open_id").
+		SetBotManager(true).
+		Body(body)
+	if input.UUID != "" {
+		req.Uuid(input.UUID)
+	}
+
+	resp, err := c.api().Im.Chat.Create(context.Background(), req.Build())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat: %w", err)
+	}
+	if !resp.Success() {
+		return nil, apiError("create chat", resp.Code, resp.Msg)
+	}
+	if resp.Data == nil || resp.Data.ChatId == nil {
+		return nil, fmt.Errorf("failed to create chat: no chat returned")
+	}
+	return &Chat{ChatID: *resp.Data.ChatId, Name: getStringValue(resp.Data.Name)}, nil
+}
+
+// AddChatMembers adds users to a group and returns the ones that weren't
+// added, such as users who left the company or who wait for the owner's
+// approval to join
+func (c *Client) AddChatMembers(chatID string, ids []string) ([]string, error) {
+	var skipped []string
+	err := c.guard("add chat members", func() error {
+		req := larkim.NewCreateChatMembersReqBuilder().
+			ChatId(chatID).
+			MemberIdType("open_id").
+			SucceedType(1).
+			Body(larkim.NewCreateChatMembersReqBodyBuilder().IdList(ids).Build()).
+			Build()
+		resp, err := c.api().Im.ChatMembers.Create(context.Background(), req)
+		if err != nil {
+			return fmt.Errorf("failed to add chat members: %w", err)
+		}
+		if !resp.Success() {
+			return apiError("add chat members", resp.Code, resp.Msg)
+		}
+		if resp.Data != nil {
+			skipped = append(skipped, resp.Data.InvalidIdList...)
+			skipped = append(skipped, resp.Data.NotExistedIdList...)
+			skipped = append(skipped, resp.Data.PendingApprovalIdList...)
+		}
+		return nil
+	})
+	return skipped, err
+}
+
+// RemoveChatMembers removes users from a group and returns the ones that
+// couldn't be removed
+func (c *Client) RemoveChatMembers(chatID string, ids []string) ([]string, error) {
+	var skipped []string
+	err := c.guard("remove chat members", func() error {
+		req := larkim.NewDeleteChatMembersReqBuilder().
+			ChatId(chatID).
+			MemberIdType("open_id").
+			Body(larkim.NewDeleteChatMembersReqBodyBuilder().IdList(ids).Build()).
+			Build()
+		resp, err := c.api().Im.ChatMembers.Delete(context.Background(), req)
+		if err != nil {
+			return fmt.Errorf("failed to remove chat members: %w", err)
+		}
+		if !resp.Success() {
+			return apiError("remove chat members", resp.Code, resp.Msg)
+		}
+		if resp.Data != nil {
+			skipped = resp.Data.InvalidIdList
+		}
+		return nil
+	})
+	return skipped, err
+}
+
+// SetChatName renames a group
+func (c *Client) SetChatName(chatID, name string) error {
+	return c.guard("set chat name", func() error {
+		req := larkim.NewUpdateChatReqBuilder().
+			ChatId(chatID).
+			Body(larkim.NewUpdateChatReqBodyBuilder().Name(name).Build()).
+			Build()
+		resp, err := c.api().Im.Chat.Update(context.Background(), req)
+		if err != nil {
+			return fmt.Errorf("failed to set chat name: %w", err)
+		}
+		if !resp.Success() {
+			return apiError("set chat name", resp.Code, resp.Msg)
+		}
+		return nil
+	})
+}
+
+// ChatLink returns a link to join a group, valid for a week
+func (c *Client) ChatLink(chatID string) (string, error) {
+	var link string
+	err := c.guard("get chat link", func() error {
+		req := larkim.NewLinkChatReqBuilder().
+			ChatId(chatID).
+			Body(larkim.NewLinkChatReqBodyBuilder().ValidityPeriod("week").Build()).
+			Build()
+		resp, err := c.api().Im.Chat.Link(context.Background(), req)
+		if err != nil {
+			return fmt.Errorf("failed to get chat link: %w", err)
+		}
+		if !resp.Success() {
+			return apiError("get chat link", resp.Code, resp.Msg)
+		}
+		if resp.Data != nil {
+			link = getStringValue(resp.Data.ShareLink)
+		}
+		return nil
+	})
+	return link, err
+}
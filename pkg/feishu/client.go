@@ -0,0 +1,674 @@
+package feishu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	lark "github.com/larksuite/oapi-sdk-go/v3"
+	larkcore "github.com/larksuite/oapi-sdk-go/v3/core"
+	"github.com/larksuite/oapi-sdk-go/v3/event/dispatcher"
+	"github.com/larksuite/oapi-sdk-go/v3/event/dispatcher/callback"
+	larkws "github.com/larksuite/oapi-sdk-go/v3/ws"
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+)
+
+// MessageHandler is called when a message is received.
+// ctx carries the message's correlation ID for logging.
+type MessageHandler func(ctx context.Context, msg *Message) error
+
+// CardActionHandler is called when a user clicks a card button.
+// The returned text, if any, is shown to the user as a toast.
+type CardActionHandler func(ctx context.Context, action *CardAction) (string, error)
+
+// BotAddedHandler is called when the bot is added to a group
+type BotAddedHandler func(ctx context.Context, event *BotAdded)
+
+// BotAdded describes the bot joining a group
+type BotAdded struct {
+	ChatID   string
+	ChatName string
+	// OperatorID is the open_id of who added the bot
+	OperatorID string
+	External   bool
+}
+
+// CardAction represents a card button click
+type CardAction struct {
+	OperatorID string // clicker open_id
+	ChatID     string
+	MessageID  string
+	Value      map[string]interface{}
+	Option     string
+}
+
+// Message represents a received message
+type Message struct {
+	MessageID   string
+	ChatID      string
+	ChatType    string
+	SenderID    string // sender open_id
+	Content     string
+	Mentions    []Mention
+}
+
+// Mention represents a user mention
+type Mention struct {
+	Key       string
+	ID        string
+	Name      string
+	TenantKey string
+}
+
+// Client is a Feishu WebSocket client
+type Client struct {
+	appID string
+	// credMu guards appSecret, client, wsCtx and ws, which change when
+	// the app secret is rotated or the long connection replaced
+	credMu    sync.RWMutex
+	appSecret string
+	client    *lark.Client
+	wsCtx     context.Context
+	// ws is the current long connection
+	ws        *wsConn
+	handler   MessageHandler
+	onCard    CardActionHandler
+	onAdded   BotAddedHandler
+	onTask    TaskUpdateHandler
+	wsLog     *wsLogger
+	watchdog  *watchdog
+	breaker   *breaker
+	probeOnce sync.Once
+	flushMu   sync.Mutex
+	// domain overrides the Feishu open platform URL when set
+	domain string
+	// httpClient carries API calls when set, e.g. through a proxy
+	httpClient *http.Client
+	// helpdeskID and helpdeskToken authenticate Helpdesk API calls
+	helpdeskID    string
+	helpdeskToken string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithDomain sends API calls and the long connection handshake to domain
+// (e.g. https://open.larksuite.com, or a fake server in tests) instead of
+// https://open.feishu.cn
+func WithDomain(domain string) Option {
+	return func(c *Client) {
+		c.domain = domain
+	}
+}
+
+// WithHelpdesk authenticates Helpdesk (服务台) API calls as the helpdesk
+// with the given ID and token
+func WithHelpdesk(id, token string) Option {
+	return func(c *Client) {
+		c.helpdeskID, c.helpdeskToken = id, token
+	}
+}
+
+// NewClient creates a new Feishu client
+func NewClient(appID, appSecret string, handler MessageHandler, opts ...Option) *Client {
+	w := &watchdog{timeout: defaultSilenceTimeout}
+	c := &Client{
+		appID:     appID,
+		appSecret: appSecret,
+		handler:   handler,
+		wsLog:     newWSLogger(w),
+		watchdog:  w,
+		breaker:   newBreaker(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.client = c.newAPIClient()
+	return c
+}
+
+// newAPIClient builds an API client for the current app secret; callers
+// must hold credMu or own c exclusively. SDK logs go through the standard
+// logger so they're redacted like the bridge's own.
+func (c *Client) newAPIClient() *lark.Client {
+	opts := []lark.ClientOptionFunc{
+		lark.WithLogLevel(larkcore.LogLevelInfo),
+		lark.WithLogger(c.wsLog),
+	}
+	if c.domain != "" {
+		opts = append(opts, lark.WithOpenBaseUrl(c.domain))
+	}
+	if c.helpdeskID != "" {
+		opts = append(opts, lark.WithHelpdeskCredential(c.helpdeskID, c.helpdeskToken))
+	}
+	if c.httpClient != nil {
+		opts = append(opts, lark.WithHttpClient(c.httpClient))
+	}
+	return lark.NewClient(c.appID, c.appSecret, opts...)
+}
+
+// Connected is closed once the long connection is first established
+func (c *Client) Connected() <-chan struct{} {
+	return c.wsLog.connected
+}
+
+// SetCardActionHandler sets the handler for card button clicks.
+// Must be called before Start.
+func (c *Client) SetCardActionHandler(handler CardActionHandler) {
+	c.onCard = handler
+}
+
+// SetBotAddedHandler sets the handler for the bot joining a group.
+// Must be called before Start.
+func (c *Client) SetBotAddedHandler(handler BotAddedHandler) {
+	c.onAdded = handler
+}
+
+// Start starts the WebSocket client. It only returns if the first
+// connection can't be made; replacements made later by the watchdog or a
+// secret rotation log their failures instead.
+func (c *Client) Start(ctx context.Context) error {
+	c.credMu.Lock()
+	c.wsCtx = ctx
+	wsClient, wsCtx := c.replaceConnection()
+	c.credMu.Unlock()
+
+	if c.watchdog.timeout > 0 {
+		go c.watchConnection(ctx)
+	}
+	log.Printf("[Feishu] Starting WebSocket client (appId=%s)", c.appID)
+	return wsClient.Start(wsCtx)
+}
+
+// wsConn is a long connection started by Start or reconnect
+type wsConn struct {
+	log *wsLogger
+	// cancel stops the SDK from reconnecting it once it's retired
+	cancel context.CancelFunc
+	// prev is the connection it replaces, retired once this one is up
+	prev *wsConn
+}
+
+// retire drops what w and the connections before it log from now on and
+// keeps them from reconnecting; callers must hold credMu. The SDK can't
+// close a connection, so a retired one lingers until its socket fails.
+func (w *wsConn) retire() {
+	for w != nil {
+		w.log.retire()
+		w.cancel()
+		next := w.prev
+		w.prev = nil
+		w = next
+	}
+}
+
+// replaceConnection builds a long connection client with the current
+// secret to replace the current one, which is retired once the new one
+// is up; callers must hold credMu. If the new one never comes up, e.g.
+// because a rotated secret is wrong, the current one keeps serving.
+func (c *Client) replaceConnection() (*larkws.Client, context.Context) {
+	ctx, cancel := context.WithCancel(c.wsCtx)
+	conn := &wsConn{log: c.wsLog.forConnection(), cancel: cancel, prev: c.ws}
+	conn.log.onConnect = func() {
+		c.credMu.Lock()
+		defer c.credMu.Unlock()
+		conn.prev.retire()
+		conn.prev = nil
+	}
+	c.ws = conn
+	return c.newWSClient(conn.log), ctx
+}
+
+// reconnect opens a new long connection with the current secret beside
+// the current one, which is retired once the new one is up. It does
+// nothing before Start.
+func (c *Client) reconnect() {
+	c.credMu.Lock()
+	if c.wsCtx == nil {
+		c.credMu.Unlock()
+		return
+	}
+	wsClient, ctx := c.replaceConnection()
+	c.credMu.Unlock()
+
+	go func() {
+		if err := wsClient.Start(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("[Feishu] Replacement WebSocket client stopped: %v", err)
+		}
+	}()
+}
+
+// newWSClient builds a long connection client logging to l; callers must
+// hold credMu
+func (c *Client) newWSClient(l *wsLogger) *larkws.Client {
+	eventHandler := dispatcher.NewEventDispatcher("", "").
+		OnP2MessageReceiveV1(c.handleMessage).
+		OnP2CardActionTrigger(c.handleCardAction).
+		OnP2ChatMemberBotAddedV1(c.handleBotAdded).
+		OnP2TaskUpdatedV1(c.handleTaskUpdated)
+
+	opts := []larkws.ClientOption{
+		larkws.WithEventHandler(eventHandler),
+		larkws.WithLogLevel(larkcore.LogLevelInfo),
+		larkws.WithLogger(l),
+	}
+	if c.domain != "" {
+		opts = append(opts, larkws.WithDomain(c.domain))
+	}
+	return larkws.NewClient(c.appID, c.appSecret, opts...)
+}
+
+// UpdateSecret switches to a rotated app secret. API calls fetch a new
+// tenant token with it right away. Once started, a long connection is
+// opened with it too, and the one opened with the old secret is retired
+// as soon as the new one is up, so nothing reconnects with the old one.
+func (c *Client) UpdateSecret(appSecret string) {
+	c.credMu.Lock()
+	c.appSecret = appSecret
+	c.client = c.newAPIClient()
+	c.credMu.Unlock()
+
+	c.reconnect()
+}
+
+// api returns the API client for the current credentials
+func (c *Client) api() *lark.Client {
+	c.credMu.RLock()
+	defer c.credMu.RUnlock()
+	return c.client
+}
+
+// eventContext starts the logging context for an event. It drops the
+// connection's cancellation, which fires when the connection is retired
+// rather than when the event is done with.
+func eventContext(ctx context.Context) context.Context {
+	return logging.NewContext(context.WithoutCancel(ctx))
+}
+
+// handleMessage handles incoming messages
+func (c *Client) handleMessage(ctx context.Context, event *larkim.P2MessageReceiveV1) error {
+	msg := event.Event.Message
+	ctx = eventContext(ctx)
+	logging.SetChatID(ctx, getStringValue(msg.ChatId))
+
+	// Only handle text messages
+	if msg.MessageType == nil || *msg.MessageType != "text" {
+		return nil
+	}
+
+	if msg.Content == nil {
+		return nil
+	}
+
+	// Parse message content
+	var content struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal([]byte(*msg.Content), &content); err != nil {
+		logging.Printf(ctx, "[Feishu] Failed to parse message content: %v", err)
+		return nil
+	}
+
+	// Build message
+	message := &Message{
+		MessageID: getStringValue(msg.MessageId),
+		ChatID:    getStringValue(msg.ChatId),
+		ChatType:  getStringValue(msg.ChatType),
+		Content:   content.Text,
+	}
+
+	// Parse sender
+	if sender := event.Event.Sender; sender != nil && sender.SenderId != nil {
+		message.SenderID = getStringValue(sender.SenderId.OpenId)
+	}
+
+	// Parse mentions
+	if msg.Mentions != nil {
+		for _, mention := range msg.Mentions {
+			mentionID := ""
+			if mention.Id != nil && mention.Id.UserId != nil {
+				mentionID = *mention.Id.UserId
+			}
+			message.Mentions = append(message.Mentions, Mention{
+				Key:       getStringValue(mention.Key),
+				ID:        mentionID,
+				Name:      getStringValue(mention.Name),
+				TenantKey: getStringValue(mention.TenantKey),
+			})
+		}
+	}
+
+	logging.Printf(ctx, "[Feishu] Received message %s (%s) from %s", message.MessageID, message.ChatType, message.SenderID)
+
+	// Call handler
+	if c.handler != nil {
+		return c.handler(ctx, message)
+	}
+
+	return nil
+}
+
+// handleBotAdded handles the bot being added to a group
+func (c *Client) handleBotAdded(ctx context.Context, event *larkim.P2ChatMemberBotAddedV1) error {
+	if c.onAdded == nil || event.Event == nil {
+		return nil
+	}
+	added := &BotAdded{
+		ChatID:   getStringValue(event.Event.ChatId),
+		ChatName: getStringValue(event.Event.Name),
+		External: event.Event.External != nil && *event.Event.External,
+	}
+	if event.Event.OperatorId != nil {
+		added.OperatorID = getStringValue(event.Event.OperatorId.OpenId)
+	}
+	ctx = eventContext(ctx)
+	logging.SetChatID(ctx, added.ChatID)
+	logging.Printf(ctx, "[Feishu] Bot added to %s (%s) by %s", added.ChatID, added.ChatName, added.OperatorID)
+
+	c.onAdded(ctx, added)
+	return nil
+}
+
+// handleCardAction handles card button callbacks
+func (c *Client) handleCardAction(ctx context.Context, event *callback.CardActionTriggerEvent) (*callback.CardActionTriggerResponse, error) {
+	if c.onCard == nil || event.Event == nil || event.Event.Action == nil {
+		return nil, nil
+	}
+
+	action := &CardAction{
+		Value:  event.Event.Action.Value,
+		Option: event.Event.Action.Option,
+	}
+	if event.Event.Operator != nil {
+		action.OperatorID = event.Event.Operator.OpenID
+	}
+	if event.Event.Context != nil {
+		action.ChatID = event.Event.Context.OpenChatID
+		action.MessageID = event.Event.Context.OpenMessageID
+	}
+	ctx = eventContext(ctx)
+	logging.SetChatID(ctx, action.ChatID)
+
+	toast, err := c.onCard(ctx, action)
+	if err != nil {
+		logging.Printf(ctx, "[Feishu] Card action failed: %v", err)
+		return &callback.CardActionTriggerResponse{
+			Toast: &callback.Toast{Type: "error", Content: err.Error()},
+		}, nil
+	}
+	if toast == "" {
+		return nil, nil
+	}
+	return &callback.CardActionTriggerResponse{
+		Toast: &callback.Toast{Type: "success", Content: toast},
+	}, nil
+}
+
+// SendCard sends an interactive card to a chat
+func (c *Client) SendCard(chatID string, card *Card) (string, error) {
+	var messageID string
+	err := c.guard("send card", func() (err error) {
+		messageID, err = c.sendCard(chatID, card)
+		return err
+	})
+	return messageID, err
+}
+
+// sendCard calls the API directly; see SendCard
+func (c *Client) sendCard(chatID string, card *Card) (string, error) {
+	content, err := json.Marshal(card)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode card: %w", err)
+	}
+
+	req := larkim.NewCreateMessageReqBuilder().
+		ReceiveIdType("chat_id").
+		Body(larkim.NewCreateMessageReqBodyBuilder().
+			ReceiveId(chatID).
+			MsgType("interactive").
+			Content(string(content)).
+			Build()).
+		Build()
+
+	resp, err := c.api().Im.Message.Create(context.Background(), req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send card: %w", err)
+	}
+
+	if !resp.Success() {
+		return "", apiError("send card", resp.Code, resp.Msg)
+	}
+
+	messageID := ""
+	if resp.Data != nil && resp.Data.MessageId != nil {
+		messageID = *resp.Data.MessageId
+	}
+
+	return messageID, nil
+}
+
+// UpdateCard replaces the content of a previously sent card
+func (c *Client) UpdateCard(messageID string, card *Card) error {
+	return c.guard("update card", func() error { return c.updateCard(messageID, card) })
+}
+
+// updateCard calls the API directly; see UpdateCard
+func (c *Client) updateCard(messageID string, card *Card) error {
+	content, err := json.Marshal(card)
+	if err != nil {
+		return fmt.Errorf("failed to encode card: %w", err)
+	}
+
+	req := larkim.NewPatchMessageReqBuilder().
+		MessageId(messageID).
+		Body(larkim.NewPatchMessageReqBodyBuilder().
+			Content(string(content)).
+			Build()).
+		Build()
+
+	resp, err := c.api().Im.Message.Patch(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("failed to update card: %w", err)
+	}
+
+	if !resp.Success() {
+		return apiError("update card", resp.Code, resp.Msg)
+	}
+
+	return nil
+}
+
+// SendMessage sends a text message to a chat
+func (c *Client) SendMessage(chatID, text string) (string, error) {
+	var messageID string
+	err := c.guard("send message", func() (err error) {
+		messageID, err = c.sendMessage(chatID, text)
+		return err
+	})
+	return messageID, err
+}
+
+// sendMessage calls the API directly; see SendMessage
+func (c *Client) sendMessage(chatID, text string) (string, error) {
+	req := larkim.NewCreateMessageReqBuilder().
+		ReceiveIdType("chat_id").
+		Body(larkim.NewCreateMessageReqBodyBuilder().
+			ReceiveId(chatID).
+			MsgType("text").
+			Content(fmt.Sprintf(`{"text":"%s"}`, escapeJSON(text))).
+			Build()).
+		Build()
+
+	resp, err := c.api().Im.Message.Create(context.Background(), req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send message: %w", err)
+	}
+
+	if !resp.Success() {
+		return "", apiError("send message", resp.Code, resp.Msg)
+	}
+
+	messageID := ""
+	if resp.Data != nil && resp.Data.MessageId != nil {
+		messageID = *resp.Data.MessageId
+	}
+
+	return messageID, nil
+}
+
+// UpdateMessage updates an existing message
+func (c *Client) UpdateMessage(messageID, text string) error {
+	return c.guard("update message", func() error { return c.updateMessage(messageID, text) })
+}
+
+// updateMessage calls the API directly; see UpdateMessage
+func (c *Client) updateMessage(messageID, text string) error {
+	req := larkim.NewUpdateMessageReqBuilder().
+		MessageId(messageID).
+		Body(larkim.NewUpdateMessageReqBodyBuilder().
+			MsgType("text").
+			Content(fmt.Sprintf(`{"text":"%s"}`, escapeJSON(text))).
+			Build()).
+		Build()
+
+	resp, err := c.api().Im.Message.Update(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("failed to update message: %w", err)
+	}
+
+	if !resp.Success() {
+		return statusError("update message", resp.StatusCode, resp.Code, resp.Msg)
+	}
+
+	return nil
+}
+
+// DeleteMessage deletes a message
+func (c *Client) DeleteMessage(messageID string) error {
+	return c.guard("delete message", func() error { return c.deleteMessage(messageID) })
+}
+
+// deleteMessage calls the API directly; see DeleteMessage
+func (c *Client) deleteMessage(messageID string) error {
+	req := larkim.NewDeleteMessageReqBuilder().
+		MessageId(messageID).
+		Build()
+
+	resp, err := c.api().Im.Message.Delete(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+
+	if !resp.Success() {
+		return apiError("delete message", resp.Code, resp.Msg)
+	}
+
+	return nil
+}
+
+// ErrAuth is wrapped by API errors caused by invalid app credentials or
+// access tokens, so callers can tell them apart from per-message failures
+var ErrAuth = errors.New("feishu auth failed")
+
+// authCodes are Feishu API codes meaning the app couldn't authenticate
+var authCodes = map[int]bool{
+	10003:    true, // invalid app_id
+	10014:    true, // invalid app_secret
+	99991661: true, // missing access token
+	99991663: true, // invalid tenant access token
+	99991664: true, // invalid app access token
+	99991668: true, // invalid user access token
+	99991671: true, // token format error
+}
+
+// ErrRateLimited is wrapped by API errors caused by Feishu frequency limits
+var ErrRateLimited = errors.New("feishu rate limit")
+
+// rateLimitCodes are response codes for exceeded frequency limits
+var rateLimitCodes = map[int]bool{
+	99991400: true, // app request frequency limit
+	230020:   true, // message operation frequency limit
+}
+
+// ErrNotEditable is wrapped by API errors for edits of a message Feishu
+// no longer lets the bot change, such as one edited too often or too long
+// ago; a fresh message is needed instead
+var ErrNotEditable = errors.New("feishu message not editable")
+
+// notEditableCodes are response codes for edits that can't succeed on
+// that message any more
+var notEditableCodes = map[int]bool{
+	230011: true, // message recalled
+	230072: true, // message edited too many times
+	230075: true, // message too old to edit
+}
+
+// ErrServer is wrapped by API errors caused by a Feishu server error,
+// which may succeed when retried
+var ErrServer = errors.New("feishu server error")
+
+// IsRetryable reports whether err is a rate limit or server error, so the
+// same call is worth retrying after a pause
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrServer)
+}
+
+// apiFailure is an unsuccessful API response, as opposed to a failure to
+// reach the API at all
+type apiFailure struct {
+	msg  string
+	kind error
+}
+
+func (e *apiFailure) Error() string { return e.msg }
+func (e *apiFailure) Unwrap() error { return e.kind }
+
+// apiError builds the error for an unsuccessful API response
+func apiError(action string, code int, msg string) error {
+	if authCodes[code] {
+		return &apiFailure{fmt.Sprintf("failed to %s: %v: %s (code %d)", action, ErrAuth, msg, code), ErrAuth}
+	}
+	if rateLimitCodes[code] {
+		return &apiFailure{fmt.Sprintf("failed to %s: %v: %s (code %d)", action, ErrRateLimited, msg, code), ErrRateLimited}
+	}
+	if notEditableCodes[code] {
+		return &apiFailure{fmt.Sprintf("failed to %s: %v: %s (code %d)", action, ErrNotEditable, msg, code), ErrNotEditable}
+	}
+	return &apiFailure{msg: fmt.Sprintf("failed to %s: %s", action, msg)}
+}
+
+// statusError builds the error for an unsuccessful response that also
+// tells HTTP 429 and 5xx apart, for calls worth retrying
+func statusError(action string, status, code int, msg string) error {
+	switch {
+	case status == http.StatusTooManyRequests && !rateLimitCodes[code]:
+		return &apiFailure{fmt.Sprintf("failed to %s: %v: %s (status %d)", action, ErrRateLimited, msg, status), ErrRateLimited}
+	case status >= 500:
+		return &apiFailure{fmt.Sprintf("failed to %s: %v: %s (status %d, code %d)", action, ErrServer, msg, status, code), ErrServer}
+	}
+	return apiError(action, code, msg)
+}
+
+// Helper functions
+
+func getStringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func escapeJSON(s string) string {
+	b, _ := json.Marshal(s)
+	// Remove surrounding quotes
+	if len(b) >= 2 && b[0] == '"' && b[len(b)-1] == '"' {
+		return string(b[1 : len(b)-1])
+	}
+	return string(b)
+}
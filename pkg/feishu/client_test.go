@@ -0,0 +1,57 @@
+package feishu_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/testutil"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// TestUpdateSecretReconnects rotates the app secret of a running client
+// and checks a long connection is opened with the new secret and used
+func TestUpdateSecretReconnects(t *testing.T) {
+	server := testutil.NewFeishuServer()
+	defer server.Close()
+
+	received := make(chan string, 10)
+	client := feishu.NewClient("cli_test", "old-secret", func(ctx context.Context, msg *feishu.Message) error {
+		received <- msg.Content
+		return nil
+	}, feishu.WithDomain(server.URL))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.Start(ctx)
+	select {
+	case <-server.Connected():
+	case <-time.After(10 * time.Second):
+		t.Fatal("client didn't open the long connection")
+	}
+
+	client.UpdateSecret("new-secret")
+	deadline := time.Now().Add(10 * time.Second)
+	for client.Connection().Connects < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no new long connection after rotation: %+v", client.Connection())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	secrets := server.HandshakeSecrets()
+	if len(secrets) != 2 || secrets[0] != "old-secret" || secrets[1] != "new-secret" {
+		t.Errorf("handshake secrets = %q, want old-secret then new-secret", secrets)
+	}
+
+	if err := server.PushText("oc_p2p", "p2p", "ou_alice", "轮换后"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case text := <-received:
+		if text != "轮换后" {
+			t.Errorf("received %q, want 轮换后", text)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no message received on the new connection")
+	}
+}
@@ -0,0 +1,10 @@
+// Package feishu is a Feishu (Lark) bot client: it receives messages,
+// card clicks, group joins and task changes over the long connection and
+// sends text, rich text, cards, images and files, creates tasks,
+// approval instances, helpdesk tickets and groups, manages group
+// members, reads and appends to spreadsheets, reads wiki spaces and
+// meeting minutes, and edits group announcements.
+//
+// Exported names follow semantic versioning: they only change
+// incompatibly with a new major version of the module.
+package feishu
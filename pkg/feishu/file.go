@@ -0,0 +1,122 @@
+package feishu
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+)
+
+// SendFile uploads data as a file named name and sends it to a chat
+func (c *Client) SendFile(chatID, name string, data []byte) (string, error) {
+	var messageID string
+	err := c.guard("send file", func() (err error) {
+		messageID, err = c.sendFile(chatID, name, data)
+		return err
+	})
+	return messageID, err
+}
+
+// sendFile calls the API directly; see SendFile
+func (c *Client) sendFile(chatID, name string, data []byte) (string, error) {
+	uploadReq := larkim.NewCreateFileReqBuilder().
+		Body(larkim.NewCreateFileReqBodyBuilder().
+			FileType(larkim.FileTypeStream).
+			FileName(name).
+			File(bytes.NewReader(data)).
+			Build()).
+		Build()
+
+	uploadResp, err := c.api().Im.File.Create(context.Background(), uploadReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %w", err)
+	}
+	if !uploadResp.Success() {
+		return "", apiError("upload file", uploadResp.Code, uploadResp.Msg)
+	}
+	if uploadResp.Data == nil || uploadResp.Data.FileKey == nil {
+		return "", fmt.Errorf("failed to upload file: no file key returned")
+	}
+
+	return c.sendResource(chatID, "file", fmt.Sprintf(`{"file_key":"%s"}`, escapeJSON(*uploadResp.Data.FileKey)))
+}
+
+// SendImage uploads a PNG or JPEG image and sends it to a chat
+func (c *Client) SendImage(chatID string, data []byte) (string, error) {
+	var messageID string
+	err := c.guard("send image", func() (err error) {
+		messageID, err = c.sendImage(chatID, data)
+		return err
+	})
+	return messageID, err
+}
+
+// sendImage calls the API directly; see SendImage
+func (c *Client) sendImage(chatID string, data []byte) (string, error) {
+	imageKey, err := c.uploadImage(data)
+	if err != nil {
+		return "", err
+	}
+	return c.sendResource(chatID, "image", fmt.Sprintf(`{"image_key":"%s"}`, escapeJSON(imageKey)))
+}
+
+// UploadImage uploads a PNG or JPEG image for use in messages and
+// returns its image key
+func (c *Client) UploadImage(data []byte) (string, error) {
+	var imageKey string
+	err := c.guard("upload image", func() (err error) {
+		imageKey, err = c.uploadImage(data)
+		return err
+	})
+	return imageKey, err
+}
+
+// uploadImage calls the API directly; see UploadImage
+func (c *Client) uploadImage(data []byte) (string, error) {
+	uploadReq := larkim.NewCreateImageReqBuilder().
+		Body(larkim.NewCreateImageReqBodyBuilder().
+			ImageType(larkim.ImageTypeMessage).
+			Image(bytes.NewReader(data)).
+			Build()).
+		Build()
+
+	uploadResp, err := c.api().Im.Image.Create(context.Background(), uploadReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload image: %w", err)
+	}
+	if !uploadResp.Success() {
+		return "", apiError("upload image", uploadResp.Code, uploadResp.Msg)
+	}
+	if uploadResp.Data == nil || uploadResp.Data.ImageKey == nil {
+		return "", fmt.Errorf("failed to upload image: no image key returned")
+	}
+
+	return *uploadResp.Data.ImageKey, nil
+}
+
+// sendResource sends a message referring to an uploaded file or image
+func (c *Client) sendResource(chatID, msgType, content string) (string, error) {
+	req := larkim.NewCreateMessageReqBuilder().
+		ReceiveIdType("chat_id").
+		Body(larkim.NewCreateMessageReqBodyBuilder().
+			ReceiveId(chatID).
+			MsgType(msgType).
+			Content(content).
+			Build()).
+		Build()
+
+	resp, err := c.api().Im.Message.Create(context.Background(), req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send %s: %w", msgType, err)
+	}
+	if !resp.Success() {
+		return "", apiError("send "+msgType, resp.Code, resp.Msg)
+	}
+
+	messageID := ""
+	if resp.Data != nil && resp.Data.MessageId != nil {
+		messageID = *resp.Data.MessageId
+	}
+	return messageID, nil
+}
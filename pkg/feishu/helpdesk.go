@@ -0,0 +1,98 @@
+package feishu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	larkcore "github.com/larksuite/oapi-sdk-go/v3/core"
+	larkhelpdesk "github.com/larksuite/oapi-sdk-go/v3/service/helpdesk/v1"
+)
+
+// maxTicketInfo is the longest customized info a ticket accepts
+const maxTicketInfo = 1024
+
+// TicketInput describes a Helpdesk (服务台) ticket to open for a user
+type TicketInput struct {
+	// User is the open_id of the user the ticket is for
+	User string
+	// Agents are the open_ids of the helpdesk agents to assign; empty
+	// lets the helpdesk assign one
+	Agents []string
+	// Summary describes the problem; it's kept as the ticket's source
+	// information and posted into the ticket
+	Summary string
+}
+
+// Ticket is an opened Helpdesk ticket
+type Ticket struct {
+	ID string
+	// ChatID is the helpdesk chat where the user talks to the agents
+	ChatID string
+	// URL opens the helpdesk chat in Feishu
+	URL string
+}
+
+// StartTicket opens a ticket handled by human agents for input.User and
+// posts input.Summary into it, if it can. The client must be created
+// WithHelpdesk.
+func (c *Client) StartTicket(input TicketInput) (*Ticket, error) {
+	var ticket *Ticket
+	err := c.guard("start ticket", func() (err error) {
+		ticket, err = c.startTicket(input)
+		return err
+	})
+	return ticket, err
+}
+
+// startTicket calls the API directly; see StartTicket
+func (c *Client) startTicket(input TicketInput) (*Ticket, error) {
+	info := []rune(input.Summary)
+	if len(info) > maxTicketInfo {
+		info = info[:maxTicketInfo]
+	}
+	body := larkhelpdesk.NewStartServiceTicketReqBodyBuilder().
+		HumanService(true).
+		OpenId(input.User).
+		CustomizedInfo(string(info))
+	if len(input.Agents) > 0 {
+		body.AppointedAgents(input.Agents)
+	}
+	req := larkhelpdesk.NewStartServiceTicketReqBuilder().Body(body.Build()).Build()
+
+	ctx := context.Background()
+	resp, err := c.api().Helpdesk.Ticket.StartService(ctx, req, larkcore.WithNeedHelpDeskAuth())
+	if err != nil {
+		return nil, fmt.Errorf("failed to start ticket: %w", err)
+	}
+	if !resp.Success() {
+		return nil, apiError("start ticket", resp.Code, resp.Msg)
+	}
+	if resp.Data == nil || resp.Data.ChatId == nil {
+		return nil, fmt.Errorf("failed to start ticket: no chat returned")
+	}
+	ticket := &Ticket{
+		ID:     getStringValue(resp.Data.TicketId),
+		ChatID: *resp.Data.ChatId,
+		URL:    "https://applink.feishu.cn/client/chat/open?openChatId=" + *resp.Data.ChatId,
+	}
+	if ticket.ID == "" || input.Summary == "" {
+		return ticket, nil
+	}
+
+	content, _ := json.Marshal(map[string]string{"text": input.Summary})
+	msgReq := larkhelpdesk.NewCreateTicketMessageReqBuilder().
+		TicketId(ticket.ID).
+		Body(larkhelpdesk.NewCreateTicketMessageReqBodyBuilder().MsgType("text").Content(string(content)).Build()).
+		Build()
+	// The ticket is open either way; the summary is also in its info
+	msgResp, err := c.api().Helpdesk.TicketMessage.Create(ctx, msgReq, larkcore.WithNeedHelpDeskAuth())
+	if err == nil && !msgResp.Success() {
+		err = apiError("post ticket summary", msgResp.Code, msgResp.Msg)
+	}
+	if err != nil {
+		log.Printf("[Feishu] Failed to post summary to ticket %s: %v", ticket.ID, err)
+	}
+	return ticket, nil
+}
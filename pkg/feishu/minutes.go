@@ -0,0 +1,90 @@
+package feishu
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+
+	larkminutes "github.com/larksuite/oapi-sdk-go/v3/service/minutes/v1"
+)
+
+// minutesURL matches links to Feishu Minutes (妙记)
+var minutesURL = regexp.MustCompile(`https?://[^\s/]+/minutes/([A-Za-z0-9]+)`)
+
+// Minutes is a recorded meeting and its transcript
+type Minutes struct {
+	Token    string
+	Title    string
+	URL      string
+	Duration time.Duration
+	// Transcript is the text of the meeting, one paragraph per speaker
+	// turn
+	Transcript string
+}
+
+// FindMinutes returns the tokens of the Minutes linked in text, each once
+func FindMinutes(text string) []string {
+	var tokens []string
+	seen := make(map[string]bool)
+	for _, m := range minutesURL.FindAllStringSubmatch(text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			tokens = append(tokens, m[1])
+		}
+	}
+	return tokens
+}
+
+// GetMinutes returns a Minutes' title and transcript
+func (c *Client) GetMinutes(token string) (*Minutes, error) {
+	var minutes *Minutes
+	err := c.guard("get minutes", func() (err error) {
+		minutes, err = c.getMinutes(token)
+		return err
+	})
+	return minutes, err
+}
+
+// getMinutes calls the API directly; see GetMinutes
+func (c *Client) getMinutes(token string) (*Minutes, error) {
+	ctx := context.Background()
+	resp, err := c.api().Minutes.V1.Minute.Get(ctx, larkminutes.NewGetMinuteReqBuilder().MinuteToken(token).Build())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get minutes: %w", err)
+	}
+	if !resp.Success() {
+		return nil, apiError("get minutes", resp.Code, resp.Msg)
+	}
+	minutes := &Minutes{Token: token}
+	if resp.Data != nil && resp.Data.Minute != nil {
+		m := resp.Data.Minute
+		minutes.Title = getStringValue(m.Title)
+		minutes.URL = getStringValue(m.Url)
+		if ms, err := strconv.ParseInt(getStringValue(m.Duration), 10, 64); err == nil {
+			minutes.Duration = time.Duration(ms) * time.Millisecond
+		}
+	}
+
+	req := larkminutes.NewGetMinuteTranscriptReqBuilder().
+		MinuteToken(token).
+		NeedSpeaker(true).
+		NeedTimestamp(false).
+		FileFormat("txt").
+		Build()
+	transcript, err := c.api().Minutes.V1.MinuteTranscript.Get(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get minutes transcript: %w", err)
+	}
+	if !transcript.Success() || transcript.File == nil {
+		return nil, apiError("get minutes transcript", transcript.Code, transcript.Msg)
+	}
+	text, err := io.ReadAll(transcript.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read minutes transcript: %w", err)
+	}
+	minutes.Transcript = string(text)
+	return minutes, nil
+}
@@ -0,0 +1,56 @@
+package feishu
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/websocket"
+)
+
+// The SDK opens the long connection with http.DefaultClient and
+// websocket.DefaultDialer, so the long connection settings below are made
+// on those for the whole process.
+
+// WithProxy routes API calls through apiProxy and the long connection
+// through connProxy. Both work like http.Transport.Proxy, so http(s) and
+// socks5 proxies are supported and nil connects directly. Without this
+// option both follow HTTPS_PROXY and NO_PROXY.
+func WithProxy(apiProxy, connProxy func(*http.Request) (*url.URL, error)) Option {
+	return func(c *Client) {
+		c.apiTransport().Proxy = apiProxy
+		connTransport().Proxy = connProxy
+		websocket.DefaultDialer.Proxy = connProxy
+	}
+}
+
+// WithDialer opens the network connections of API calls and the long
+// connection, including those to a proxy, with dial
+func WithDialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) Option {
+	return func(c *Client) {
+		c.apiTransport().DialContext = dial
+		connTransport().DialContext = dial
+		websocket.DefaultDialer.NetDialContext = dial
+	}
+}
+
+// apiTransport returns the transport of API calls, replacing the SDK's
+// default client on first use
+func (c *Client) apiTransport() *http.Transport {
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+	}
+	return c.httpClient.Transport.(*http.Transport)
+}
+
+// connTransport returns the transport of http.DefaultClient, giving it one
+// of its own on first use
+func connTransport() *http.Transport {
+	if t, ok := http.DefaultClient.Transport.(*http.Transport); ok && t != http.DefaultTransport {
+		return t
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	http.DefaultClient.Transport = t
+	return t
+}
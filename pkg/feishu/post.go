@@ -0,0 +1,238 @@
+package feishu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+)
+
+// codeLanguages maps common fence labels to Feishu code_block languages.
+// Other labels are upper-cased as they are.
+var codeLanguages = map[string]string{
+	"":           "PLAIN_TEXT",
+	"text":       "PLAIN_TEXT",
+	"txt":        "PLAIN_TEXT",
+	"plaintext":  "PLAIN_TEXT",
+	"golang":     "GO",
+	"js":         "JAVASCRIPT",
+	"jsx":        "JAVASCRIPT",
+	"ts":         "TYPESCRIPT",
+	"tsx":        "TYPESCRIPT",
+	"py":         "PYTHON",
+	"python3":    "PYTHON",
+	"sh":         "SHELL",
+	"bash":       "SHELL",
+	"zsh":        "SHELL",
+	"console":    "SHELL",
+	"ps1":        "POWERSHELL",
+	"yml":        "YAML",
+	"c++":        "CPP",
+	"cc":         "CPP",
+	"c#":         "CSHARP",
+	"cs":         "CSHARP",
+	"kt":         "KOTLIN",
+	"rb":         "RUBY",
+	"rs":         "RUST",
+	"md":         "MARKDOWN",
+	"dockerfile": "DOCKERFILE",
+	"objc":       "OBJECTIVE_C",
+}
+
+// imageRefPattern matches image references made with ImageRef. Only
+// uploaded image keys count; Markdown images with URLs stay text.
+var imageRefPattern = regexp.MustCompile(`!\[([^\]]*)\]\((img_[A-Za-z0-9_-]+)\)`)
+
+// ImageRef returns Markdown-style markup that shows an uploaded image
+// (see UploadImage) inside a rich text message
+func ImageRef(alt, imageKey string) string {
+	return "![" + alt + "](" + imageKey + ")"
+}
+
+// NeedsPost reports whether text has code blocks or images, which only
+// render in a rich text message; see SendPost
+func NeedsPost(text string) bool {
+	return HasCodeBlock(text) || imageRefPattern.MatchString(text)
+}
+
+// HasCodeBlock reports whether text contains a fenced code block
+func HasCodeBlock(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			return true
+		}
+	}
+	return false
+}
+
+// postElement is a text or code element of a rich text paragraph
+type postElement struct {
+	Tag      string `json:"tag"`
+	Text     string `json:"text"`
+	Language string `json:"language,omitempty"`
+}
+
+// postImage is an image element of a rich text paragraph
+type postImage struct {
+	Tag      string `json:"tag"`
+	ImageKey string `json:"image_key"`
+}
+
+// postContent renders text as a rich text ("post") message body. Fenced
+// code blocks become code_block elements, which Feishu shows with the
+// language label, monospace layout and a copy button; other lines stay
+// plain text, except for image references, which show the image on its
+// own line. An unclosed fence, as in a reply still streaming, runs to the
+// end of the text.
+func postContent(text string) (string, error) {
+	var paragraphs [][]interface{}
+	var code []string
+	lang, inCode := "", false
+
+	flushCode := func() {
+		paragraphs = append(paragraphs, []interface{}{postElement{
+			Tag:      "code_block",
+			Language: codeLanguage(lang),
+			Text:     strings.Join(code, "\n"),
+		}})
+		code = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		fence := strings.TrimSpace(line)
+		if strings.HasPrefix(fence, "```") {
+			if inCode {
+				flushCode()
+				inCode = false
+			} else {
+				lang, inCode = strings.TrimSpace(strings.TrimPrefix(fence, "```")), true
+			}
+			continue
+		}
+		if inCode {
+			code = append(code, line)
+			continue
+		}
+		paragraphs = append(paragraphs, textParagraphs(line)...)
+	}
+	if inCode {
+		flushCode()
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"zh_cn": map[string]interface{}{"content": paragraphs},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode post: %w", err)
+	}
+	return string(body), nil
+}
+
+// textParagraphs renders a line of text, splitting it around any image
+// references
+func textParagraphs(line string) [][]interface{} {
+	refs := imageRefPattern.FindAllStringSubmatchIndex(line, -1)
+	if len(refs) == 0 {
+		return [][]interface{}{{postElement{Tag: "text", Text: line}}}
+	}
+	var paragraphs [][]interface{}
+	start := 0
+	for _, ref := range refs {
+		if before := strings.TrimSpace(line[start:ref[0]]); before != "" {
+			paragraphs = append(paragraphs, []interface{}{postElement{Tag: "text", Text: before}})
+		}
+		paragraphs = append(paragraphs, []interface{}{postImage{Tag: "img", ImageKey: line[ref[4]:ref[5]]}})
+		start = ref[1]
+	}
+	if after := strings.TrimSpace(line[start:]); after != "" {
+		paragraphs = append(paragraphs, []interface{}{postElement{Tag: "text", Text: after}})
+	}
+	return paragraphs
+}
+
+// codeLanguage maps a fence label such as "go" or "bash" to the language
+// name Feishu expects
+func codeLanguage(label string) string {
+	// Fences may carry more than the language, e.g. "go title=main.go"
+	if fields := strings.Fields(label); len(fields) > 0 {
+		label = fields[0]
+	}
+	label = strings.ToLower(label)
+	if lang, ok := codeLanguages[label]; ok {
+		return lang
+	}
+	return strings.ToUpper(label)
+}
+
+// SendPost sends text as a rich text message, rendering fenced code
+// blocks; see HasCodeBlock
+func (c *Client) SendPost(chatID, text string) (string, error) {
+	var messageID string
+	err := c.guard("send post", func() (err error) {
+		messageID, err = c.sendPost(chatID, text)
+		return err
+	})
+	return messageID, err
+}
+
+// sendPost calls the API directly; see SendPost
+func (c *Client) sendPost(chatID, text string) (string, error) {
+	content, err := postContent(text)
+	if err != nil {
+		return "", err
+	}
+	req := larkim.NewCreateMessageReqBuilder().
+		ReceiveIdType("chat_id").
+		Body(larkim.NewCreateMessageReqBodyBuilder().
+			ReceiveId(chatID).
+			MsgType("post").
+			Content(content).
+			Build()).
+		Build()
+
+	resp, err := c.api().Im.Message.Create(context.Background(), req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send post: %w", err)
+	}
+	if !resp.Success() {
+		return "", apiError("send post", resp.Code, resp.Msg)
+	}
+
+	messageID := ""
+	if resp.Data != nil && resp.Data.MessageId != nil {
+		messageID = *resp.Data.MessageId
+	}
+	return messageID, nil
+}
+
+// UpdatePost replaces the text of a message sent with SendPost
+func (c *Client) UpdatePost(messageID, text string) error {
+	return c.guard("update post", func() error { return c.updatePost(messageID, text) })
+}
+
+// updatePost calls the API directly; see UpdatePost
+func (c *Client) updatePost(messageID, text string) error {
+	content, err := postContent(text)
+	if err != nil {
+		return err
+	}
+	req := larkim.NewUpdateMessageReqBuilder().
+		MessageId(messageID).
+		Body(larkim.NewUpdateMessageReqBodyBuilder().
+			MsgType("post").
+			Content(content).
+			Build()).
+		Build()
+
+	resp, err := c.api().Im.Message.Update(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("failed to update post: %w", err)
+	}
+	if !resp.Success() {
+		return statusError("update post", resp.StatusCode, resp.Code, resp.Msg)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package feishu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	larkcore "github.com/larksuite/oapi-sdk-go/v3/core"
+	larksheets "github.com/larksuite/oapi-sdk-go/v3/service/sheets/v3"
+)
+
+// sheetURL matches links to Feishu spreadsheets
+var sheetURL = regexp.MustCompile(`https?://[^\s/]+/sheets/([A-Za-z0-9]+)(\?[^\s)\]]*)?`)
+
+// SheetRef names a spreadsheet and, optionally, one of its sheets
+type SheetRef struct {
+	Token string
+	// SheetID is the sheet the link opened to; empty means the first
+	SheetID string
+	// URL is the link the reference was found in
+	URL string
+}
+
+// FindSheets returns the spreadsheets linked in text, each once
+func FindSheets(text string) []SheetRef {
+	var refs []SheetRef
+	seen := make(map[string]bool)
+	for _, m := range sheetURL.FindAllStringSubmatch(text, -1) {
+		ref := SheetRef{Token: m[1], URL: m[0]}
+		if q, err := url.ParseQuery(trimQuery(m[2])); err == nil {
+			ref.SheetID = q.Get("sheet")
+		}
+		if key := ref.Token + "!" + ref.SheetID; !seen[key] {
+			seen[key] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+func trimQuery(q string) string {
+	if len(q) > 0 && q[0] == '?' {
+		return q[1:]
+	}
+	return q
+}
+
+// FirstSheet returns the ID of a spreadsheet's first sheet
+func (c *Client) FirstSheet(token string) (string, error) {
+	var sheetID string
+	err := c.guard("query sheets", func() (err error) {
+		sheetID, err = c.firstSheet(token)
+		return err
+	})
+	return sheetID, err
+}
+
+// firstSheet calls the API directly; see FirstSheet
+func (c *Client) firstSheet(token string) (string, error) {
+	req := larksheets.NewQuerySpreadsheetSheetReqBuilder().SpreadsheetToken(token).Build()
+	resp, err := c.api().Sheets.SpreadsheetSheet.Query(context.Background(), req)
+	if err != nil {
+		return "", fmt.Errorf("failed to query sheets: %w", err)
+	}
+	if !resp.Success() {
+		return "", apiError("query sheets", resp.Code, resp.Msg)
+	}
+	if resp.Data == nil {
+		return "", fmt.Errorf("failed to query sheets: no sheets returned")
+	}
+	for _, sheet := range resp.Data.Sheets {
+		if sheet.SheetId != nil && (sheet.Hidden == nil || !*sheet.Hidden) {
+			return *sheet.SheetId, nil
+		}
+	}
+	return "", fmt.Errorf("failed to query sheets: the spreadsheet has no visible sheet")
+}
+
+// ReadRange returns the cells of rng, e.g. "0b1234!A1:D20", as text
+func (c *Client) ReadRange(token, rng string) ([][]string, error) {
+	var rows [][]string
+	err := c.guard("read sheet", func() (err error) {
+		rows, err = c.readRange(token, rng)
+		return err
+	})
+	return rows, err
+}
+
+// readRange calls the API directly; see ReadRange
+func (c *Client) readRange(token, rng string) ([][]string, error) {
+	var data struct {
+		ValueRange struct {
+			Values [][]interface{} `json:"values"`
+		} `json:"valueRange"`
+	}
+	req := &larkcore.ApiReq{
+		HttpMethod:  http.MethodGet,
+		ApiPath:     "/open-apis/sheets/v2/spreadsheets/:spreadsheet_token/values/:range",
+		PathParams:  larkcore.PathParams{"spreadsheet_token": token, "range": rng},
+		QueryParams: larkcore.QueryParams{"valueRenderOption": {"ToString"}, "dateTimeRenderOption": {"FormattedString"}},
+	}
+	if err := c.rawCall("read sheet", req, &data); err != nil {
+		return nil, err
+	}
+	rows := make([][]string, 0, len(data.ValueRange.Values))
+	for _, values := range data.ValueRange.Values {
+		row := make([]string, len(values))
+		for i, v := range values {
+			row[i] = cellText(v)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// AppendRows adds rows after the table that starts in rng and returns
+// the range written. Cells that look like numbers are written as numbers.
+func (c *Client) AppendRows(token, rng string, rows [][]string) (string, error) {
+	var written string
+	err := c.guard("append rows", func() (err error) {
+		written, err = c.appendRows(token, rng, rows)
+		return err
+	})
+	return written, err
+}
+
+// appendRows calls the API directly; see AppendRows
+func (c *Client) appendRows(token, rng string, rows [][]string) (string, error) {
+	values := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		values[i] = make([]interface{}, len(row))
+		for j, cell := range row {
+			values[i][j] = cellValue(cell)
+		}
+	}
+	var data struct {
+		Updates struct {
+			UpdatedRange string `json:"updatedRange"`
+		} `json:"updates"`
+	}
+	req := &larkcore.ApiReq{
+		HttpMethod:  http.MethodPost,
+		ApiPath:     "/open-apis/sheets/v2/spreadsheets/:spreadsheet_token/values_append",
+		PathParams:  larkcore.PathParams{"spreadsheet_token": token},
+		QueryParams: larkcore.QueryParams{"insertDataOption": {"INSERT_ROWS"}},
+		Body: map[string]interface{}{
+			"valueRange": map[string]interface{}{"range": rng, "values": values},
+		},
+	}
+	if err := c.rawCall("append rows", req, &data); err != nil {
+		return "", err
+	}
+	return data.Updates.UpdatedRange, nil
+}
+
+// rawCall makes a request the SDK has no typed method for, such as
+// Sheets v2, and decodes its data into out
+func (c *Client) rawCall(action string, req *larkcore.ApiReq, out interface{}) error {
+	req.SupportedAccessTokenTypes = []larkcore.AccessTokenType{larkcore.AccessTokenTypeTenant}
+	resp, err := c.api().Do(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	var body struct {
+		larkcore.CodeError
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(resp.RawBody, &body); err != nil {
+		return fmt.Errorf("failed to %s: HTTP %d: %w", action, resp.StatusCode, err)
+	}
+	if body.Code != 0 {
+		return apiError(action, body.Code, body.Msg)
+	}
+	if out == nil || len(body.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body.Data, out); err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	return nil
+}
+
+// cellText renders a cell read with valueRenderOption=ToString; links and
+// mentions still come back as segments
+func cellText(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		var text string
+		for _, seg := range v {
+			if m, ok := seg.(map[string]interface{}); ok {
+				if s, ok := m["text"].(string); ok {
+					text += s
+				}
+			}
+		}
+		return text
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// cellValue converts text to what the cell should hold: numbers as
+// numbers, except codes like 007 whose leading zeros would be lost
+func cellValue(cell string) interface{} {
+	if len(cell) > 1 && cell[0] == '0' && cell[1] != '.' {
+		return cell
+	}
+	f, err := strconv.ParseFloat(cell, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return cell
+	}
+	return f
+}
@@ -0,0 +1,131 @@
+package feishu
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	larktaskv1 "github.com/larksuite/oapi-sdk-go/v3/service/task/v1"
+	larktask "github.com/larksuite/oapi-sdk-go/v3/service/task/v2"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/logging"
+)
+
+// TaskInput describes a task to create
+type TaskInput struct {
+	Summary     string
+	Description string
+	// Assignees are open_ids
+	Assignees []string
+	// Due is when the task is due; zero means no due date
+	Due time.Time
+	// AllDay makes Due a date rather than a time
+	AllDay bool
+	// Remind alerts the assignees at Due, unless it's AllDay
+	Remind bool
+	// ClientToken makes retries with the same token create one task
+	ClientToken string
+}
+
+// Task is a created task
+type Task struct {
+	GUID    string
+	Summary string
+	// URL opens the task in Feishu
+	URL string
+}
+
+// TaskEvent kinds, as numbered by Feishu
+const (
+	TaskChanged     = 1
+	TaskCompleted   = 5
+	TaskUncompleted = 6
+	TaskDeleted     = 7
+)
+
+// TaskUpdate describes a change to a task the app can see
+type TaskUpdate struct {
+	GUID string
+	// Kind is one of the TaskEvent kinds, e.g. TaskCompleted
+	Kind int
+}
+
+// TaskUpdateHandler is called when a task changes
+type TaskUpdateHandler func(ctx context.Context, update *TaskUpdate)
+
+// SetTaskUpdateHandler sets the handler for task changes.
+// Must be called before Start.
+func (c *Client) SetTaskUpdateHandler(handler TaskUpdateHandler) {
+	c.onTask = handler
+}
+
+// CreateTask creates a task assigned to input.Assignees
+func (c *Client) CreateTask(input TaskInput) (*Task, error) {
+	var task *Task
+	err := c.guard("create task", func() (err error) {
+		task, err = c.createTask(input)
+		return err
+	})
+	return task, err
+}
+
+// createTask calls the API directly; see CreateTask
+func (c *Client) createTask(input TaskInput) (*Task, error) {
+	members := make([]*larktask.Member, len(input.Assignees))
+	for i, id := range input.Assignees {
+		members[i] = larktask.NewMemberBuilder().Id(id).Type("user").Role("assignee").Build()
+	}
+	body := larktask.NewInputTaskBuilder().
+		Summary(input.Summary).
+		Description(input.Description).
+		Members(members)
+	if !input.Due.IsZero() {
+		body.Due(larktask.NewDueBuilder().
+			Timestamp(strconv.FormatInt(input.Due.UnixMilli(), 10)).
+			IsAllDay(input.AllDay).
+			Build())
+		if input.Remind && !input.AllDay {
+			body.Reminders([]*larktask.Reminder{larktask.NewReminderBuilder().RelativeFireMinute(0).Build()})
+		}
+	}
+	if input.ClientToken != "" {
+		body.ClientToken(input.ClientToken)
+	}
+	req := larktask.NewCreateTaskReqBuilder().
+		UserIdType("open_id").
+		InputTask(body.Build()).
+		Build()
+
+	resp, err := c.api().Task.V2.Task.Create(context.Background(), req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+	if !resp.Success() {
+		return nil, apiError("create task", resp.Code, resp.Msg)
+	}
+	if resp.Data == nil || resp.Data.Task == nil || resp.Data.Task.Guid == nil {
+		return nil, fmt.Errorf("failed to create task: no task returned")
+	}
+	return &Task{
+		GUID:    *resp.Data.Task.Guid,
+		Summary: getStringValue(resp.Data.Task.Summary),
+		URL:     getStringValue(resp.Data.Task.Url),
+	}, nil
+}
+
+// handleTaskUpdated handles a change to a task
+func (c *Client) handleTaskUpdated(ctx context.Context, event *larktaskv1.P2TaskUpdatedV1) error {
+	if c.onTask == nil || event.Event == nil {
+		return nil
+	}
+	update := &TaskUpdate{GUID: getStringValue(event.Event.TaskId)}
+	if event.Event.ObjType != nil {
+		update.Kind = *event.Event.ObjType
+	}
+	ctx = eventContext(ctx)
+	logging.Printf(ctx, "[Feishu] Task %s updated (kind %d)", update.GUID, update.Kind)
+
+	c.onTask(ctx, update)
+	return nil
+}
@@ -0,0 +1,29 @@
+package feishu
+
+import (
+	"context"
+	"fmt"
+
+	larkcontact "github.com/larksuite/oapi-sdk-go/v3/service/contact/v3"
+)
+
+// UserTimezone returns the timezone in a user's Feishu profile as an IANA
+// name such as Asia/Shanghai, or "" when the profile doesn't have one
+func (c *Client) UserTimezone(openID string) (string, error) {
+	var tz string
+	err := c.guard("get user", func() error {
+		req := larkcontact.NewGetUserReqBuilder().UserId(openID).UserIdType("open_id").Build()
+		resp, err := c.api().Contact.User.Get(context.Background(), req)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+		if !resp.Success() {
+			return apiError("get user", resp.Code, resp.Msg)
+		}
+		if resp.Data != nil && resp.Data.User != nil {
+			tz = getStringValue(resp.Data.User.TimeZone)
+		}
+		return nil
+	})
+	return tz, err
+}
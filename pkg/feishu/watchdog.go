@@ -0,0 +1,130 @@
+package feishu
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// ConnectionStatus describes the long connection for health reporting
+type ConnectionStatus struct {
+	// LastActivity is when anything, heartbeats included, last arrived
+	LastActivity time.Time `json:"last_activity"`
+	// Silent is set from when the watchdog finds the connection silent
+	// until something arrives again
+	Silent bool `json:"silent"`
+	// Reconnects counts connections the watchdog replaced
+	Reconnects int `json:"reconnects"`
+	// ConnectedAt is when the current connection came up
+	ConnectedAt time.Time `json:"connected_at,omitempty"`
+	// Connects counts the connections made, the SDK's own reconnects
+	// included
+	Connects int `json:"connects"`
+}
+
+// defaultSilenceTimeout is how long the long connection may deliver
+// nothing before it's replaced
+const defaultSilenceTimeout = 5 * time.Minute
+
+// watchdog notices a long connection that stopped delivering anything.
+// The SDK pings every couple of minutes and Feishu answers each ping, so
+// a healthy connection is never silent for long even without events.
+type watchdog struct {
+	timeout  time.Duration
+	mu       sync.Mutex
+	status   ConnectionStatus
+	onChange func(ConnectionStatus)
+}
+
+// WithSilenceTimeout replaces the long connection with a new one when
+// nothing, not even a heartbeat, arrives on it for d. The default is 5
+// minutes; 0 disables the watchdog.
+func WithSilenceTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.watchdog.timeout = d
+	}
+}
+
+// Connection returns the long connection state for health reporting
+func (c *Client) Connection() ConnectionStatus {
+	c.watchdog.mu.Lock()
+	defer c.watchdog.mu.Unlock()
+	return c.watchdog.status
+}
+
+// OnConnectionChange registers fn to be called (in its own goroutine)
+// when the long connection goes silent or recovers
+func (c *Client) OnConnectionChange(fn func(ConnectionStatus)) {
+	c.watchdog.mu.Lock()
+	defer c.watchdog.mu.Unlock()
+	c.watchdog.onChange = fn
+}
+
+// seen records that something arrived on the long connection
+func (w *watchdog) seen() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status.LastActivity = time.Now()
+	if w.status.Silent {
+		w.status.Silent = false
+		w.notify()
+	}
+}
+
+// connected records that a long connection came up
+func (w *watchdog) connected() {
+	w.mu.Lock()
+	w.status.ConnectedAt = time.Now()
+	w.status.Connects++
+	w.mu.Unlock()
+	w.seen()
+}
+
+// notify reports the status to the change callback; callers hold mu
+func (w *watchdog) notify() {
+	if w.onChange != nil {
+		go w.onChange(w.status)
+	}
+}
+
+// check reports whether the connection has been silent for longer than
+// the timeout at now, marking it silent and counting the reconnect. The
+// silence is measured afresh from now, so the new connection gets a full
+// timeout to deliver something.
+func (w *watchdog) check(now time.Time) (silence time.Duration, stalled bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	silence = now.Sub(w.status.LastActivity)
+	if w.status.LastActivity.IsZero() || silence < w.timeout {
+		return silence, false
+	}
+	w.status.LastActivity = now
+	w.status.Silent = true
+	w.status.Reconnects++
+	w.notify()
+	return silence, true
+}
+
+// watchConnection replaces the long connection whenever it goes silent,
+// until ctx is done. Checks start once the first connection is up.
+func (c *Client) watchConnection(ctx context.Context) {
+	select {
+	case <-c.wsLog.connected:
+	case <-ctx.Done():
+		return
+	}
+	ticker := time.NewTicker(c.watchdog.timeout / 10)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if silence, stalled := c.watchdog.check(now); stalled {
+				log.Printf("[Feishu] Nothing received on the long connection for %s, reconnecting (appId=%s)", silence.Round(time.Second), c.appID)
+				c.reconnect()
+			}
+		}
+	}
+}
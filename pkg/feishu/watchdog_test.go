@@ -0,0 +1,56 @@
+package feishu_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/wy51ai/moltbotCNAPP/internal/testutil"
+	"github.com/wy51ai/moltbotCNAPP/pkg/feishu"
+)
+
+// TestWatchdogReconnects lets the fake server's long connection go quiet
+// (it only answers pings, sent every two minutes) and checks the client
+// replaces it in-process and still receives events afterwards
+func TestWatchdogReconnects(t *testing.T) {
+	server := testutil.NewFeishuServer()
+	defer server.Close()
+
+	received := make(chan string, 10)
+	client := feishu.NewClient("cli_test", "secret", func(ctx context.Context, msg *feishu.Message) error {
+		received <- msg.Content
+		return nil
+	}, feishu.WithDomain(server.URL), feishu.WithSilenceTimeout(500*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.Start(ctx)
+	select {
+	case <-server.Connected():
+	case <-time.After(10 * time.Second):
+		t.Fatal("client didn't open the long connection")
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for client.Connection().Reconnects < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("watchdog didn't reconnect: %+v", client.Connection())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if st := client.Connection(); st.Connects < 2 {
+		t.Errorf("Connects = %d after %d reconnects, want a new connection each time", st.Connects, st.Reconnects)
+	}
+
+	if err := server.PushText("oc_p2p", "p2p", "ou_alice", "还在吗"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case text := <-received:
+		if text != "还在吗" {
+			t.Errorf("received %q, want 还在吗", text)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no message received after reconnecting")
+	}
+}
@@ -0,0 +1,124 @@
+package feishu
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	larkdocx "github.com/larksuite/oapi-sdk-go/v3/service/docx/v1"
+	larkwiki "github.com/larksuite/oapi-sdk-go/v3/service/wiki/v2"
+)
+
+// maxWikiPages bounds the pages listed from one wiki space
+const maxWikiPages = 2000
+
+// WikiPage is a node of a wiki space
+type WikiPage struct {
+	NodeToken string
+	// ObjToken and ObjType name the document the node holds, e.g. a
+	// "docx" document
+	ObjToken string
+	ObjType  string
+	Title    string
+	// Edited is when the document was last edited
+	Edited time.Time
+}
+
+// WikiPages lists the pages of a wiki space, parents before children
+func (c *Client) WikiPages(spaceID string) ([]WikiPage, error) {
+	var pages []WikiPage
+	parents := []string{""}
+	for len(parents) > 0 && len(pages) < maxWikiPages {
+		parent := parents[0]
+		parents = parents[1:]
+		var children []WikiPage
+		var more []string
+		err := c.guard("list wiki pages", func() (err error) {
+			children, more, err = c.listWikiNodes(spaceID, parent)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, children...)
+		parents = append(parents, more...)
+	}
+	if len(pages) > maxWikiPages {
+		pages = pages[:maxWikiPages]
+	}
+	return pages, nil
+}
+
+// listWikiNodes calls the API directly for the children of parent, the
+// space's top level when empty, and returns them and those that have
+// children of their own
+func (c *Client) listWikiNodes(spaceID, parent string) ([]WikiPage, []string, error) {
+	var pages []WikiPage
+	var parents []string
+	pageToken := ""
+	for {
+		req := larkwiki.NewListSpaceNodeReqBuilder().SpaceId(spaceID).PageSize(50)
+		if parent != "" {
+			req.ParentNodeToken(parent)
+		}
+		if pageToken != "" {
+			req.PageToken(pageToken)
+		}
+		resp, err := c.api().Wiki.SpaceNode.List(context.Background(), req.Build())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list wiki pages: %w", err)
+		}
+		if !resp.Success() {
+			return nil, nil, apiError("list wiki pages", resp.Code, resp.Msg)
+		}
+		if resp.Data == nil {
+			return pages, parents, nil
+		}
+		for _, node := range resp.Data.Items {
+			page := WikiPage{
+				NodeToken: getStringValue(node.NodeToken),
+				ObjToken:  getStringValue(node.ObjToken),
+				ObjType:   getStringValue(node.ObjType),
+				Title:     getStringValue(node.Title),
+			}
+			if sec, err := strconv.ParseInt(getStringValue(node.ObjEditTime), 10, 64); err == nil {
+				page.Edited = time.Unix(sec, 0)
+			}
+			pages = append(pages, page)
+			if node.HasChild != nil && *node.HasChild {
+				parents = append(parents, page.NodeToken)
+			}
+		}
+		if resp.Data.HasMore == nil || !*resp.Data.HasMore || resp.Data.PageToken == nil {
+			return pages, parents, nil
+		}
+		pageToken = *resp.Data.PageToken
+	}
+}
+
+// DocumentText returns the plain text of a docx document
+func (c *Client) DocumentText(token string) (string, error) {
+	var text string
+	err := c.guard("read document", func() (err error) {
+		text, err = c.documentText(token)
+		return err
+	})
+	return text, err
+}
+
+// documentText calls the API directly; see DocumentText
+func (c *Client) documentText(token string) (string, error) {
+	req := larkdocx.NewRawContentDocumentReqBuilder().DocumentId(token).Build()
+	resp, err := c.api().Docx.Document.RawContent(context.Background(), req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read document: %w", err)
+	}
+	if !resp.Success() {
+		return "", apiError("read document", resp.Code, resp.Msg)
+	}
+	if resp.Data == nil {
+		return "", nil
+	}
+	return getStringValue(resp.Data.Content), nil
+}
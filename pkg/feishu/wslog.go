@@ -0,0 +1,81 @@
+package feishu
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// wsLogger routes the SDK's logs to the standard logger and notices when
+// the long connection comes up and when anything arrives on it, which the
+// SDK doesn't otherwise expose
+type wsLogger struct {
+	connected chan struct{}
+	once      *sync.Once
+	watchdog  *watchdog
+	// retired is set once the connection logging here was replaced;
+	// whatever it still logs is dropped and isn't activity
+	retired atomic.Bool
+	// onConnect, if set, is called each time the connection comes up
+	onConnect func()
+}
+
+func newWSLogger(w *watchdog) *wsLogger {
+	return &wsLogger{connected: make(chan struct{}), once: new(sync.Once), watchdog: w}
+}
+
+// forConnection returns a logger for one long connection, sharing l's
+// first-connection signal and watchdog
+func (l *wsLogger) forConnection() *wsLogger {
+	return &wsLogger{connected: l.connected, once: l.once, watchdog: l.watchdog}
+}
+
+// retire drops what the connection logs from now on
+func (l *wsLogger) retire() {
+	l.retired.Store(true)
+}
+
+// Debug is dropped, matching the Info level used elsewhere, but pongs and
+// events it reports count as activity for the watchdog
+func (l *wsLogger) Debug(ctx context.Context, args ...interface{}) {
+	if l.retired.Load() {
+		return
+	}
+	if len(args) > 0 {
+		if msg, ok := args[0].(string); ok && strings.HasPrefix(msg, "receive ") {
+			l.watchdog.seen()
+		}
+	}
+}
+
+func (l *wsLogger) Info(ctx context.Context, args ...interface{}) {
+	if l.retired.Load() {
+		return
+	}
+	log.Printf("[Feishu] [Info] %v", args)
+	if len(args) > 0 {
+		if msg, ok := args[0].(string); ok && strings.HasPrefix(msg, "connected to ") {
+			l.once.Do(func() { close(l.connected) })
+			l.watchdog.connected()
+			if l.onConnect != nil {
+				l.onConnect()
+			}
+		}
+	}
+}
+
+func (l *wsLogger) Warn(ctx context.Context, args ...interface{}) {
+	if l.retired.Load() {
+		return
+	}
+	log.Printf("[Feishu] [Warn] %v", args)
+}
+
+func (l *wsLogger) Error(ctx context.Context, args ...interface{}) {
+	if l.retired.Load() {
+		return
+	}
+	log.Printf("[Feishu] [Error] %v", args)
+}